@@ -0,0 +1,112 @@
+package exporter
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// aclLogEntry is an entry of ACL LOG, Redis groups repeated events into one entry and increments its count
+type aclLogEntry struct {
+	id       string
+	count    int64
+	reason   string
+	context  string
+	object   string
+	username string
+}
+
+// key identifies the entry across scrapes, entries have an id since Redis 7.2 which
+// starts over on restarts, older versions group events with the same reason, context,
+// object and username
+func (a aclLogEntry) key() string {
+	return strings.Join([]string{a.id, a.reason, a.context, a.object, a.username}, "\x00")
+}
+
+func parseACLLogEntries(values []interface{}) []aclLogEntry {
+	var entries []aclLogEntry
+	for _, v := range values {
+		fields, err := redis.Values(v, nil)
+		if err != nil || len(fields)%2 != 0 {
+			continue
+		}
+
+		entry := aclLogEntry{}
+		for i := 0; i < len(fields); i += 2 {
+			name, _ := redis.String(fields[i], nil)
+			switch name {
+			case "count":
+				entry.count, _ = redis.Int64(fields[i+1], nil)
+			case "entry-id":
+				if id, err := redis.Int64(fields[i+1], nil); err == nil {
+					entry.id = strconv.FormatInt(id, 10)
+				}
+			case "reason":
+				entry.reason, _ = redis.String(fields[i+1], nil)
+			case "context":
+				entry.context, _ = redis.String(fields[i+1], nil)
+			case "object":
+				entry.object, _ = redis.String(fields[i+1], nil)
+			case "username":
+				entry.username, _ = redis.String(fields[i+1], nil)
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// aclLogState keeps the counts of the ACL LOG entries of the last scrape per target
+type aclLogState struct {
+	sync.Mutex
+	counts map[string]map[string]int64
+}
+
+func newACLLogState() *aclLogState {
+	return &aclLogState{counts: map[string]map[string]int64{}}
+}
+
+// newEvents returns the number of events per username and reason since the last
+// update for target, the first update of a target only records the counts
+func (s *aclLogState) newEvents(target string, entries []aclLogEntry) map[[2]string]int64 {
+	s.Lock()
+	defer s.Unlock()
+
+	prev, seen := s.counts[target]
+	counts := make(map[string]int64, len(entries))
+	res := map[[2]string]int64{}
+	for _, entry := range entries {
+		k := entry.key()
+		counts[k] = entry.count
+
+		// groups without new events are exported as 0
+		group := [2]string{entry.username, entry.reason}
+		res[group] += 0
+		if !seen {
+			continue
+		}
+		// the count of a known entry only grows unless the log was reset
+		if last, ok := prev[k]; ok && entry.count >= last {
+			res[group] += entry.count - last
+		} else {
+			res[group] += entry.count
+		}
+	}
+	s.counts[target] = counts
+	return res
+}
+
+func (e *Exporter) extractACLLogMetrics(ch chan<- prometheus.Metric, c redis.Conn) {
+	values, err := redis.Values(doRedisCmd(c, "ACL", "LOG", e.options.ACLLogCount))
+	if err != nil {
+		e.logger().Errorf("ACL LOG err: %s", err)
+		return
+	}
+
+	for group, count := range e.aclLog.newEvents(e.redisAddr, parseACLLogEntries(values)) {
+		e.registerConstMetricGauge(ch, "acl_log_new_events", float64(count), group[0], group[1])
+	}
+}
@@ -0,0 +1,111 @@
+package exporter
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func aclLogReply(entries ...[]interface{}) []interface{} {
+	res := make([]interface{}, 0, len(entries))
+	for _, entry := range entries {
+		res = append(res, entry)
+	}
+	return res
+}
+
+func aclLogEntryReply(id int64, count int64, reason, username string) []interface{} {
+	return []interface{}{
+		[]byte("count"), count,
+		[]byte("reason"), []byte(reason),
+		[]byte("context"), []byte("toplevel"),
+		[]byte("object"), []byte("AUTH"),
+		[]byte("username"), []byte(username),
+		[]byte("age-seconds"), []byte("1.5"),
+		[]byte("client-info"), []byte("id=3 addr=127.0.0.1:51234"),
+		[]byte("entry-id"), id,
+		[]byte("timestamp-created"), int64(1700000000000),
+		[]byte("timestamp-last-updated"), int64(1700000001000),
+	}
+}
+
+func TestACLLogMetrics(t *testing.T) {
+	e, _ := NewRedisExporter("redis://localhost:6379", Options{Namespace: "test", InclACLLogMetrics: true, ACLLogCount: 128})
+
+	scrape := func(reply []interface{}) map[[2]string]float64 {
+		c := &fakeRedisConn{replies: map[string]interface{}{"ACL LOG 128": reply}}
+		chM := make(chan prometheus.Metric)
+		go func() {
+			e.extractACLLogMetrics(chM, c)
+			close(chM)
+		}()
+
+		res := map[[2]string]float64{}
+		for m := range chM {
+			d := &dto.Metric{}
+			m.Write(d)
+			labels := map[string]string{}
+			for _, l := range d.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			res[[2]string{labels["username"], labels["reason"]}] = d.GetGauge().GetValue()
+		}
+		return res
+	}
+
+	for _, tst := range []struct {
+		name  string
+		reply []interface{}
+		want  map[[2]string]float64
+	}{
+		{
+			name:  "first scrape is the baseline",
+			reply: aclLogReply(aclLogEntryReply(1, 5, "auth", "default"), aclLogEntryReply(0, 2, "command", "app")),
+			want:  map[[2]string]float64{{"default", "auth"}: 0, {"app", "command"}: 0},
+		},
+		{
+			name: "new events of existing and new entries",
+			reply: aclLogReply(
+				aclLogEntryReply(3, 1, "auth", "default"),
+				aclLogEntryReply(2, 4, "key", "app"),
+				aclLogEntryReply(1, 8, "auth", "default"),
+				aclLogEntryReply(0, 2, "command", "app"),
+			),
+			want: map[[2]string]float64{{"default", "auth"}: 4, {"app", "key"}: 4, {"app", "command"}: 0},
+		},
+		{
+			name:  "log was reset",
+			reply: aclLogReply(aclLogEntryReply(0, 3, "auth", "default")),
+			want:  map[[2]string]float64{{"default", "auth"}: 3},
+		},
+	} {
+		if got := scrape(tst.reply); !reflect.DeepEqual(got, tst.want) {
+			t.Errorf("%s: got: %v, want: %v", tst.name, got, tst.want)
+		}
+	}
+}
+
+func TestACLLogEntryKeyWithoutID(t *testing.T) {
+	entries := parseACLLogEntries(aclLogReply([]interface{}{
+		[]byte("count"), int64(2),
+		[]byte("reason"), []byte("auth"),
+		[]byte("context"), []byte("toplevel"),
+		[]byte("object"), []byte("AUTH"),
+		[]byte("username"), []byte("default"),
+	}))
+	if len(entries) != 1 || entries[0].count != 2 || entries[0].key() == "" {
+		t.Fatalf("unexpected entries: %#v", entries)
+	}
+
+	s := newACLLogState()
+	s.newEvents("target", entries)
+	entries[0].count = 5
+	if got := s.newEvents("target", entries); got[[2]string{"default", "auth"}] != 3 {
+		t.Errorf("expected 3 new events, got: %v", got)
+	}
+	if got := s.newEvents("other", entries); got[[2]string{"default", "auth"}] != 0 {
+		t.Errorf("expected the first scrape of another target to be the baseline, got: %v", got)
+	}
+}
@@ -0,0 +1,44 @@
+package exporter
+
+import (
+	"net/http"
+	"net/http/pprof"
+)
+
+// registerAdminHandlers registers the operational endpoints, they're served on
+// AdminListenAddress instead of the main address when it's set
+func (e *Exporter) registerAdminHandlers(mux *http.ServeMux) {
+	mux.HandleFunc("/health", e.healthHandler)
+	mux.HandleFunc("/healthz", e.healthzHandler)
+	mux.HandleFunc("/targets", e.targetsHandler)
+	mux.HandleFunc("/-/reload", e.reloadPwdFile)
+	if e.checkKeysAPI != nil {
+		mux.HandleFunc("/api/v1/check-keys", e.checkKeysAPIHandler)
+	}
+	if e.options.Events {
+		mux.HandleFunc("/events", e.eventsHandler)
+	}
+}
+
+// registerDebugHandlers registers the debug endpoints, only on the admin address as
+// they connect to any target and expose the internals of the exporter. The command
+// line isn't served, it can hold passwords
+func (e *Exporter) registerDebugHandlers(mux *http.ServeMux) {
+	mux.HandleFunc("/debug/permissions", e.permissionsHandler)
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+}
+
+// AdminHandler returns the handler of the operational endpoints for AdminListenAddress,
+// with the same basic auth and audit log as the main address
+func (e *Exporter) AdminHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if e.adminMux == nil {
+			http.NotFound(w, r)
+			return
+		}
+		e.serveHTTP(w, r, e.adminMux)
+	})
+}
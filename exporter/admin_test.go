@@ -0,0 +1,52 @@
+package exporter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestAdminHandler(t *testing.T) {
+	for _, tst := range []struct {
+		name      string
+		adminAddr string
+		path      string
+		wantMain  int
+		wantAdmin int
+	}{
+		{name: "health on the main address", path: "/health", wantMain: http.StatusOK, wantAdmin: http.StatusNotFound},
+		{name: "no pprof without admin address", path: "/debug/pprof/", wantMain: http.StatusNotFound, wantAdmin: http.StatusNotFound},
+		{name: "health on the admin address", adminAddr: "localhost:9122", path: "/health", wantMain: http.StatusNotFound, wantAdmin: http.StatusOK},
+		{name: "targets on the admin address", adminAddr: "localhost:9122", path: "/targets", wantMain: http.StatusNotFound, wantAdmin: http.StatusOK},
+		{name: "pprof on the admin address", adminAddr: "localhost:9122", path: "/debug/pprof/", wantMain: http.StatusNotFound, wantAdmin: http.StatusOK},
+		{name: "no pprof command line", adminAddr: "localhost:9122", path: "/debug/pprof/cmdline", wantMain: http.StatusNotFound, wantAdmin: http.StatusNotFound},
+		{name: "metrics stay on the main address", adminAddr: "localhost:9122", path: "/metrics", wantMain: http.StatusOK, wantAdmin: http.StatusNotFound},
+	} {
+		t.Run(tst.name, func(t *testing.T) {
+			e, _ := NewRedisExporter("", Options{Namespace: "test", Registry: prometheus.NewRegistry(), AdminListenAddress: tst.adminAddr, DisableLandingPage: true})
+
+			for _, h := range []struct {
+				handler http.Handler
+				want    int
+			}{
+				{e, tst.wantMain},
+				{e.AdminHandler(), tst.wantAdmin},
+			} {
+				w := httptest.NewRecorder()
+				h.handler.ServeHTTP(w, httptest.NewRequest("GET", tst.path, nil))
+				if w.Code != h.want {
+					t.Errorf("%s: expected status %d, got: %d", tst.path, h.want, w.Code)
+				}
+			}
+		})
+	}
+
+	e, _ := NewRedisExporter("", Options{Namespace: "test", AdminListenAddress: "localhost:9122", BasicAuthUsername: "user", BasicAuthPassword: "pass"})
+	w := httptest.NewRecorder()
+	e.AdminHandler().ServeHTTP(w, httptest.NewRequest("GET", "/health", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected the admin address to require basic auth, got: %d", w.Code)
+	}
+}
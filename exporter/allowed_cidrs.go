@@ -0,0 +1,60 @@
+package exporter
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// parseAllowedCIDRs parses a comma separated list of CIDRs, single addresses are
+// allowed as a CIDR of only that address
+func parseAllowedCIDRs(s string) ([]netip.Prefix, error) {
+	var res []netip.Prefix
+	for _, c := range strings.Split(s, ",") {
+		c = strings.TrimSpace(c)
+		if c == "" {
+			continue
+		}
+		if !strings.Contains(c, "/") {
+			addr, err := netip.ParseAddr(c)
+			if err != nil {
+				return nil, fmt.Errorf("invalid address %q: %s", c, err)
+			}
+			res = append(res, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		p, err := netip.ParsePrefix(c)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %s", c, err)
+		}
+		res = append(res, p.Masked())
+	}
+	return res, nil
+}
+
+// allowedRemoteAddr returns whether the client of r is in one of the allowed CIDRs, all
+// clients are allowed without CIDRs. Only the address of the connection is checked,
+// X-Forwarded-For headers are ignored as they can be set by the client
+func (e *Exporter) allowedRemoteAddr(r *http.Request) bool {
+	if len(e.allowedCIDRs) == 0 {
+		return true
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, p := range e.allowedCIDRs {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package exporter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAllowedCIDRs(t *testing.T) {
+	e, err := NewRedisExporter("", Options{Namespace: "test", WebAllowedCIDRs: "10.0.0.0/8, 192.168.1.7,::1", AdminListenAddress: "localhost:9122"})
+	if err != nil {
+		t.Fatalf("NewRedisExporter() err: %s", err)
+	}
+
+	for _, tst := range []struct {
+		remoteAddr string
+		forwarded  string
+		want       int
+	}{
+		{remoteAddr: "10.1.2.3:5000", want: http.StatusOK},
+		{remoteAddr: "192.168.1.7:5000", want: http.StatusOK},
+		{remoteAddr: "[::1]:5000", want: http.StatusOK},
+		{remoteAddr: "[::ffff:10.0.0.1]:5000", want: http.StatusOK},
+		{remoteAddr: "192.168.1.8:5000", want: http.StatusForbidden},
+		{remoteAddr: "172.16.0.1:5000", forwarded: "10.0.0.1", want: http.StatusForbidden},
+	} {
+		for _, h := range []struct {
+			handler http.Handler
+			path    string
+		}{
+			{e, "/"},
+			{e.AdminHandler(), "/health"},
+		} {
+			r := httptest.NewRequest("GET", h.path, nil)
+			r.RemoteAddr = tst.remoteAddr
+			if tst.forwarded != "" {
+				r.Header.Set("X-Forwarded-For", tst.forwarded)
+			}
+			w := httptest.NewRecorder()
+			h.handler.ServeHTTP(w, r)
+			if w.Code != tst.want {
+				t.Errorf("%s %s: expected status %d, got: %d", tst.remoteAddr, h.path, tst.want, w.Code)
+			}
+		}
+	}
+
+	for _, cidrs := range []string{"10.0.0.0/33", "not-an-ip", "10.0.0.0/8,"} {
+		_, err := NewRedisExporter("", Options{WebAllowedCIDRs: cidrs})
+		if (err != nil) != (cidrs != "10.0.0.0/8,") {
+			t.Errorf("web.allowed-cidrs %q: unexpected err: %v", cidrs, err)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package exporter
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// statusRecorder keeps track of the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// isAuditedPath returns true for the endpoints serving Redis metrics
+func (e *Exporter) isAuditedPath(path string) bool {
+	return path == e.options.MetricsPath || path == "/scrape"
+}
+
+// auditTarget returns the redacted address of the instance a request pulls metrics from
+func (e *Exporter) auditTarget(r *http.Request) string {
+	if r.URL.Path != "/scrape" {
+		return redactedAddr(e.redisAddr)
+	}
+
+	target := r.URL.Query().Get("target")
+	if target == "" {
+		return ""
+	}
+	if t, _, err := parseTarget(target); err == nil {
+		return t
+	}
+	return "<invalid>"
+}
+
+// auditRequest writes a structured access log line for a request to /metrics or /scrape
+func (e *Exporter) auditRequest(r *http.Request, status int, took time.Duration) {
+	clientIP, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		clientIP = r.RemoteAddr
+	}
+
+	outcome := "success"
+	switch {
+	case status == http.StatusUnauthorized:
+		outcome = "unauthorized"
+	case status == http.StatusTooManyRequests:
+		outcome = "rate_limited"
+	case status >= 400:
+		outcome = "error"
+	}
+
+	fields := log.Fields{
+		"audit":            true,
+		"client_ip":        clientIP,
+		"path":             r.URL.Path,
+		"target":           e.auditTarget(r),
+		"status":           status,
+		"outcome":          outcome,
+		"duration_seconds": took.Seconds(),
+	}
+	if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+		fields["forwarded_for"] = fwd
+	}
+	if user, _, ok := r.BasicAuth(); ok {
+		fields["user"] = user
+	}
+
+	auditLogger().WithFields(fields).Info("scrape request")
+}
+
+var (
+	auditLogOnce sync.Once
+	auditLog     *log.Logger
+)
+
+// lockedWriter serializes the writes of the standard and the audit logger to their shared output
+type lockedWriter struct {
+	sync.Mutex
+	w io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.Lock()
+	defer l.Unlock()
+	return l.w.Write(p)
+}
+
+// ConfigureAuditLog creates the audit logger from the output, format and hooks of the standard logger,
+// it has to be called once the standard logger is set up, later changes to it aren't picked up
+func ConfigureAuditLog() {
+	auditLogOnce.Do(func() {
+		std := log.StandardLogger()
+		out := &lockedWriter{w: std.Out}
+		std.SetOutput(out)
+		auditLog = &log.Logger{Out: out, Formatter: std.Formatter, Hooks: std.Hooks, Level: log.InfoLevel, ExitFunc: std.ExitFunc}
+	})
+}
+
+// auditLogger returns the audit logger, it logs at info level so the audit log doesn't depend on the log level
+func auditLogger() *log.Logger {
+	ConfigureAuditLog()
+	return auditLog
+}
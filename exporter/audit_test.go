@@ -0,0 +1,76 @@
+package exporter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestAuditLog(t *testing.T) {
+	hook := logtest.NewGlobal()
+	defer hook.Reset()
+
+	e, _ := NewRedisExporter("unix:///tmp/doesnt.exist.sock", Options{
+		Namespace:         "test",
+		AuditLog:          true,
+		BasicAuthUsername: "user",
+		BasicAuthPassword: "pwd",
+	})
+
+	for _, tst := range []struct {
+		path      string
+		user      string
+		wantAudit bool
+		outcome   string
+		target    string
+		// the log level of the request, the audit log is written regardless of it
+		level log.Level
+	}{
+		{path: "/metrics", wantAudit: true, outcome: "unauthorized", target: "unix:///tmp/doesnt.exist.sock"},
+		{path: "/metrics", user: "user", wantAudit: true, outcome: "success", target: "unix:///tmp/doesnt.exist.sock"},
+		{path: "/scrape", user: "user", wantAudit: true, outcome: "error", target: ""},
+		{path: "/scrape?target=redis://:secret@localhost:6379", user: "user", wantAudit: true, target: "redis://localhost:6379"},
+		{path: "/health", user: "user", wantAudit: false},
+		{path: "/metrics", user: "user", wantAudit: true, outcome: "success", target: "unix:///tmp/doesnt.exist.sock", level: log.ErrorLevel},
+	} {
+		hook.Reset()
+		if tst.level != 0 {
+			defer log.SetLevel(log.GetLevel())
+			log.SetLevel(tst.level)
+		}
+
+		req := httptest.NewRequest(http.MethodGet, tst.path, nil)
+		req.RemoteAddr = "10.1.2.3:4567"
+		if tst.user != "" {
+			req.SetBasicAuth(tst.user, "pwd")
+		}
+		e.ServeHTTP(httptest.NewRecorder(), req)
+
+		var audit map[string]interface{}
+		for _, entry := range hook.AllEntries() {
+			if entry.Data["audit"] == true {
+				audit = entry.Data
+			}
+		}
+
+		if !tst.wantAudit {
+			if audit != nil {
+				t.Errorf("%s: unexpected audit log line: %#v", tst.path, audit)
+			}
+			continue
+		}
+		if audit == nil {
+			t.Errorf("%s: missing audit log line", tst.path)
+			continue
+		}
+		if audit["client_ip"] != "10.1.2.3" || audit["target"] != tst.target {
+			t.Errorf("%s: unexpected audit log fields: %#v", tst.path, audit)
+		}
+		if tst.outcome != "" && audit["outcome"] != tst.outcome {
+			t.Errorf("%s: expected outcome %s, got: %#v", tst.path, tst.outcome, audit)
+		}
+	}
+}
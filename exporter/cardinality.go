@@ -0,0 +1,84 @@
+package exporter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// overflowMetricName returns the name of the unlabeled metric that sums up the series of
+// metric dropped by the cardinality guard, e.g. key_size_overflow or commands_overflow_total.
+// A separate family keeps label values like "overflow" from being mistaken for real ones
+func overflowMetricName(metric string, valType prometheus.ValueType) string {
+	if valType == prometheus.CounterValue {
+		if base, ok := strings.CutSuffix(metric, "_total"); ok {
+			return base + "_overflow_total"
+		}
+	}
+	return metric + "_overflow"
+}
+
+type overflowBucket struct {
+	valType prometheus.ValueType
+	dropped int
+	sum     float64
+}
+
+// seriesGuard caps the number of labeled series per metric family during a
+// single scrape, it's not safe for concurrent use
+type seriesGuard struct {
+	limit    int
+	counts   map[string]int
+	overflow map[string]*overflowBucket
+}
+
+func newSeriesGuard(limit int) *seriesGuard {
+	return &seriesGuard{
+		limit:    limit,
+		counts:   map[string]int{},
+		overflow: map[string]*overflowBucket{},
+	}
+}
+
+// admit returns false if the series exceeds the limit of its family,
+// its value is then added to the overflow bucket of the family
+func (g *seriesGuard) admit(metric string, val float64, valType prometheus.ValueType) bool {
+	if g.counts[metric] < g.limit {
+		g.counts[metric]++
+		return true
+	}
+
+	b, ok := g.overflow[metric]
+	if !ok {
+		b = &overflowBucket{valType: valType}
+		g.overflow[metric] = b
+	}
+	b.dropped++
+	b.sum += val
+	return false
+}
+
+// flushSeriesGuard emits the overflow buckets and the number of truncated
+// series per family and resets the guard for the next scrape
+func (e *Exporter) flushSeriesGuard(ch chan<- prometheus.Metric) {
+	g := e.seriesGuard
+	if g == nil {
+		return
+	}
+	e.seriesGuard = nil
+	defer func() {
+		e.seriesGuard = newSeriesGuard(g.limit)
+	}()
+
+	for metric, b := range g.overflow {
+		name := overflowMetricName(metric, b.valType)
+		e.logger().Warnf("metric %s exceeded %d series, %d series were aggregated into %s", metric, g.limit, b.dropped, name)
+
+		if _, ok := e.addMetricDescription(name, metric, fmt.Sprintf("Sum of the series of %s over max-series-per-family", metric), nil); !ok {
+			continue
+		}
+		e.registerConstMetric(ch, name, b.sum, b.valType)
+		e.registerConstMetricGauge(ch, "exporter_truncated_series", float64(b.dropped), metric)
+	}
+}
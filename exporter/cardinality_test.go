@@ -0,0 +1,74 @@
+package exporter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestSeriesGuard(t *testing.T) {
+	e, _ := NewRedisExporter("", Options{Namespace: "test", MaxSeriesPerFamily: 2})
+
+	for scrape := 0; scrape < 2; scrape++ {
+		chM := make(chan prometheus.Metric)
+		go func() {
+			for _, key := range []string{"a", "b", "c", "d"} {
+				e.registerConstMetricGauge(chM, "key_size", 1.5, "db0", key)
+			}
+			e.registerConstMetricGauge(chM, "connected_clients", 10)
+			e.flushSeriesGuard(chM)
+			close(chM)
+		}()
+
+		keys := map[string]float64{}
+		truncated := map[string]float64{}
+		overflow := -1.0
+		unlabeled := 0
+		for m := range chM {
+			d := &dto.Metric{}
+			m.Write(d)
+			desc := m.Desc().String()
+			switch {
+			case strings.Contains(desc, `"test_key_size_overflow"`):
+				overflow = d.GetGauge().GetValue()
+			case strings.Contains(desc, "test_key_size"):
+				keys[d.GetLabel()[1].GetValue()] = d.GetGauge().GetValue()
+			case strings.Contains(desc, "test_exporter_truncated_series"):
+				truncated[d.GetLabel()[0].GetValue()] = d.GetGauge().GetValue()
+			default:
+				unlabeled++
+			}
+		}
+
+		if len(keys) != 2 || keys["a"] != 1.5 || keys["b"] != 1.5 {
+			t.Errorf("scrape %d: unexpected key series: %#v", scrape, keys)
+		}
+		if overflow != 3 {
+			t.Errorf("scrape %d: expected the dropped series summed up in key_size_overflow, got: %f", scrape, overflow)
+		}
+		if len(truncated) != 1 || truncated["key_size"] != 2 {
+			t.Errorf("scrape %d: unexpected truncated series: %#v", scrape, truncated)
+		}
+		if unlabeled != 1 {
+			t.Errorf("scrape %d: expected unlabeled metrics to not be limited", scrape)
+		}
+	}
+}
+
+func TestOverflowMetricName(t *testing.T) {
+	for _, tst := range []struct {
+		metric  string
+		valType prometheus.ValueType
+		want    string
+	}{
+		{"key_size", prometheus.GaugeValue, "key_size_overflow"},
+		{"commands_total", prometheus.CounterValue, "commands_overflow_total"},
+		{"client_output_buffer_bytes", prometheus.CounterValue, "client_output_buffer_bytes_overflow"},
+	} {
+		if got := overflowMetricName(tst.metric, tst.valType); got != tst.want {
+			t.Errorf("overflowMetricName(%s): expected %s, got: %s", tst.metric, tst.want, got)
+		}
+	}
+}
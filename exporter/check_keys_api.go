@@ -0,0 +1,197 @@
+package exporter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// CheckKeysConfig are the key and stream checks that can be changed at runtime
+// with the /api/v1/check-keys endpoint, persisted as JSON in CheckKeysAPIFile
+type CheckKeysConfig struct {
+	CheckKeys          string `json:"check_keys"`
+	CheckSingleKeys    string `json:"check_single_keys"`
+	CountKeys          string `json:"count_keys"`
+	CheckStreams       string `json:"check_streams"`
+	CheckSingleStreams string `json:"check_single_streams"`
+}
+
+// checkKeysAPI keeps the checks of the command line to reset to and serializes the API calls,
+// the checks in effect are swapped atomically so scrapes read them without locking
+type checkKeysAPI struct {
+	sync.Mutex
+	defaults CheckKeysConfig
+	current  atomic.Pointer[CheckKeysConfig]
+}
+
+func checkKeysConfigFromOptions(opts Options) CheckKeysConfig {
+	return CheckKeysConfig{
+		CheckKeys:          opts.CheckKeys,
+		CheckSingleKeys:    opts.CheckSingleKeys,
+		CountKeys:          opts.CountKeys,
+		CheckStreams:       opts.CheckStreams,
+		CheckSingleStreams: opts.CheckSingleStreams,
+	}
+}
+
+// checkKeysConfig returns the checks in effect, the ones of the options unless the API is enabled
+func (e *Exporter) checkKeysConfig() CheckKeysConfig {
+	if e.checkKeysAPI != nil {
+		return *e.checkKeysAPI.current.Load()
+	}
+	return checkKeysConfigFromOptions(e.options)
+}
+
+func (cfg CheckKeysConfig) apply(opts *Options) {
+	opts.CheckKeys = cfg.CheckKeys
+	opts.CheckSingleKeys = cfg.CheckSingleKeys
+	opts.CountKeys = cfg.CountKeys
+	opts.CheckStreams = cfg.CheckStreams
+	opts.CheckSingleStreams = cfg.CheckSingleStreams
+}
+
+func (cfg CheckKeysConfig) validate() error {
+	for _, f := range []struct {
+		name string
+		val  string
+	}{
+		{"check_keys", cfg.CheckKeys},
+		{"check_single_keys", cfg.CheckSingleKeys},
+		{"count_keys", cfg.CountKeys},
+		{"check_streams", cfg.CheckStreams},
+		{"check_single_streams", cfg.CheckSingleStreams},
+	} {
+		if _, err := parseKeyArg(f.val); err != nil {
+			return fmt.Errorf("couldn't parse %s: %s", f.name, err)
+		}
+	}
+	return nil
+}
+
+// loadCheckKeysFile reads the checks persisted by the API, a missing file isn't an error
+func loadCheckKeysFile(file string) (*CheckKeysConfig, error) {
+	bytes, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var cfg CheckKeysConfig
+	if err := json.Unmarshal(bytes, &cfg); err != nil {
+		return nil, fmt.Errorf("check-keys file format error: %s", err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// saveCheckKeysFile writes cfg to a temporary file that replaces file so a crash
+// never leaves a partially written file behind
+func saveCheckKeysFile(file string, cfg CheckKeysConfig) error {
+	bytes, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(bytes, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+// initCheckKeysAPI applies the checks persisted in CheckKeysAPIFile over the ones of the
+// command line. The API can change what's read from Redis so it requires basic auth
+func (e *Exporter) initCheckKeysAPI() error {
+	if !e.isBasicAuthConfigured() {
+		return errors.New("the check-keys API requires basic auth to be configured")
+	}
+
+	api := &checkKeysAPI{defaults: checkKeysConfigFromOptions(e.options)}
+
+	cfg, err := loadCheckKeysFile(e.options.CheckKeysAPIFile)
+	if err != nil {
+		return fmt.Errorf("couldn't load check-keys file %s: %s", e.options.CheckKeysAPIFile, err)
+	}
+	if cfg != nil {
+		log.Infof("Loaded the checks from %s", e.options.CheckKeysAPIFile)
+	} else {
+		cfg = &api.defaults
+	}
+	api.current.Store(cfg)
+	e.checkKeysAPI = api
+	return nil
+}
+
+// checkKeysAPIHandler serves /api/v1/check-keys: GET returns the current checks, PUT changes
+// the checks set in the JSON body and DELETE resets them to the ones of the command line
+func (e *Exporter) checkKeysAPIHandler(w http.ResponseWriter, r *http.Request) {
+	e.checkKeysAPI.Lock()
+	defer e.checkKeysAPI.Unlock()
+
+	cfg := e.checkKeysConfig()
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		// fields that are omitted keep their current value
+		dec := json.NewDecoder(io.LimitReader(r.Body, 1<<20))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&cfg); err != nil {
+			http.Error(w, "invalid check-keys config: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := cfg.validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := saveCheckKeysFile(e.options.CheckKeysAPIFile, cfg); err != nil {
+			e.logger().Errorf("Couldn't save the checks to %s, err: %s", e.options.CheckKeysAPIFile, err)
+			http.Error(w, "failed to save check-keys file: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		e.setCheckKeysConfig(r, cfg)
+	case http.MethodDelete:
+		if err := os.Remove(e.options.CheckKeysAPIFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+			e.logger().Errorf("Couldn't remove %s, err: %s", e.options.CheckKeysAPIFile, err)
+			http.Error(w, "failed to remove check-keys file: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		cfg = e.checkKeysAPI.defaults
+		e.setCheckKeysConfig(r, cfg)
+	default:
+		w.Header().Set("Allow", strings.Join([]string{http.MethodGet, http.MethodPut, http.MethodDelete}, ", "))
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(cfg)
+}
+
+// setCheckKeysConfig applies cfg from the next collection on
+func (e *Exporter) setCheckKeysConfig(r *http.Request, cfg CheckKeysConfig) {
+	e.checkKeysAPI.current.Store(&cfg)
+
+	user, _, _ := r.BasicAuth()
+	e.logger().Infof("Checks changed by %q with %s %s: %+v", user, r.Method, r.URL.Path, cfg)
+}
@@ -0,0 +1,86 @@
+package exporter
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckKeysAPI(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "check-keys.json")
+	opts := Options{
+		Namespace:         "test",
+		CheckKeys:         "db0=flag:*",
+		CountKeys:         "db0=count:*",
+		CheckKeysAPIFile:  file,
+		BasicAuthUsername: "admin",
+		BasicAuthPassword: "pwd",
+	}
+
+	if _, err := NewRedisExporter("redis://localhost:6379", Options{CheckKeysAPIFile: file}); err == nil {
+		t.Fatalf("expected an error without basic auth")
+	}
+
+	e, err := NewRedisExporter("redis://localhost:6379", opts)
+	if err != nil {
+		t.Fatalf("NewRedisExporter() err: %s", err)
+	}
+
+	do := func(method string, body string, user string) (int, CheckKeysConfig) {
+		req := httptest.NewRequest(method, "/api/v1/check-keys", strings.NewReader(body))
+		req.SetBasicAuth(user, "pwd")
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, req)
+		var cfg CheckKeysConfig
+		if w.Code == http.StatusOK {
+			if err := json.Unmarshal(w.Body.Bytes(), &cfg); err != nil {
+				t.Errorf("%s: couldn't parse the response %q, err: %s", method, w.Body.String(), err)
+			}
+		}
+		return w.Code, cfg
+	}
+
+	if code, _ := do(http.MethodGet, "", "other"); code != http.StatusUnauthorized {
+		t.Errorf("expected %d without valid credentials, got: %d", http.StatusUnauthorized, code)
+	}
+	if code, cfg := do(http.MethodGet, "", "admin"); code != http.StatusOK || cfg.CheckKeys != "db0=flag:*" || cfg.CountKeys != "db0=count:*" {
+		t.Errorf("expected the checks of the options, got: %d %+v", code, cfg)
+	}
+
+	for _, body := range []string{`{"check_keys": "db0=a=b"}`, `{"unknown": "x"}`, `not json`} {
+		if code, _ := do(http.MethodPut, body, "admin"); code != http.StatusBadRequest {
+			t.Errorf("PUT %s: expected %d, got: %d", body, http.StatusBadRequest, code)
+		}
+	}
+	if code, _ := do(http.MethodPost, "", "admin"); code != http.StatusMethodNotAllowed {
+		t.Errorf("expected %d for POST, got: %d", http.StatusMethodNotAllowed, code)
+	}
+
+	code, cfg := do(http.MethodPut, `{"check_keys": "db1=api:*", "check_streams": "db0=events"}`, "admin")
+	if code != http.StatusOK || cfg.CheckKeys != "db1=api:*" || cfg.CheckStreams != "db0=events" || cfg.CountKeys != "db0=count:*" {
+		t.Errorf("expected the changed checks, got: %d %+v", code, cfg)
+	}
+	if checks := e.checkKeysConfig(); checks.CheckKeys != "db1=api:*" || checks.CheckStreams != "db0=events" {
+		t.Errorf("expected the checks to be applied, got: %+v", checks)
+	}
+
+	// a restart keeps the checks set with the API
+	restarted, err := NewRedisExporter("redis://localhost:6379", opts)
+	if err != nil {
+		t.Fatalf("NewRedisExporter() err: %s", err)
+	}
+	if checks := restarted.checkKeysConfig(); checks.CheckKeys != "db1=api:*" || checks.CountKeys != "db0=count:*" {
+		t.Errorf("expected the persisted checks after a restart, got: %+v", checks)
+	}
+
+	if code, cfg := do(http.MethodDelete, "", "admin"); code != http.StatusOK || cfg.CheckKeys != "db0=flag:*" || cfg.CheckStreams != "" {
+		t.Errorf("expected the checks of the options after DELETE, got: %d %+v", code, cfg)
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Errorf("expected %s to be removed, err: %v", file, err)
+	}
+}
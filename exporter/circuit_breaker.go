@@ -0,0 +1,104 @@
+package exporter
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	log "github.com/sirupsen/logrus"
+)
+
+var errCircuitOpen = errors.New("circuit breaker open, skipping connection attempt")
+
+// circuitBreaker tracks consecutive connection failures per target and
+// short-circuits connection attempts to targets that keep failing
+type circuitBreaker struct {
+	sync.Mutex
+
+	threshold int
+	cooldown  time.Duration
+	targets   map[string]*circuitBreakerState
+}
+
+type circuitBreakerState struct {
+	failures  int
+	openUntil time.Time
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		targets:   map[string]*circuitBreakerState{},
+	}
+}
+
+// allow returns false while the breaker for target is open
+func (cb *circuitBreaker) allow(target string) bool {
+	cb.Lock()
+	defer cb.Unlock()
+
+	s, ok := cb.targets[target]
+	if !ok {
+		return true
+	}
+	return !time.Now().Before(s.openUntil)
+}
+
+func (cb *circuitBreaker) recordSuccess(target string) {
+	cb.Lock()
+	defer cb.Unlock()
+	delete(cb.targets, target)
+}
+
+func (cb *circuitBreaker) recordFailure(target string) {
+	cb.Lock()
+	defer cb.Unlock()
+
+	s, ok := cb.targets[target]
+	if !ok {
+		s = &circuitBreakerState{}
+		cb.targets[target] = s
+	}
+	s.failures++
+	if s.failures >= cb.threshold {
+		log.Debugf("circuit breaker for %s open for %s after %d failures", target, cb.cooldown, s.failures)
+		s.openUntil = time.Now().Add(cb.cooldown)
+	}
+}
+
+// connectToRedisWithRetry connects to the redis instance, retrying with exponential backoff
+// as long as the retries fit into the connection timeout budget.
+func (e *Exporter) connectToRedisWithRetry() (redis.Conn, error) {
+	if e.circuitBreaker != nil && !e.circuitBreaker.allow(e.redisAddr) {
+		return nil, errCircuitOpen
+	}
+
+	start := time.Now()
+	backoff := e.options.ConnectionRetryBackoff
+	c, err := e.connectToRedis()
+	for attempt := 1; err != nil && attempt <= e.options.ConnectionRetries; attempt++ {
+		if e.options.ConnectionTimeouts > 0 && time.Since(start)+backoff > e.options.ConnectionTimeouts {
+			e.logger().Debugf("not retrying connection to %s, out of time budget", e.redisAddr)
+			break
+		}
+		e.logger().Debugf("connection attempt %d to %s failed, retrying in %s, err: %s", attempt, e.redisAddr, backoff, err)
+		select {
+		case <-e.scrapeContext().Done():
+			return nil, errScrapeCancelled
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		c, err = e.connectToRedis()
+	}
+
+	if e.circuitBreaker != nil {
+		if err != nil {
+			e.circuitBreaker.recordFailure(e.redisAddr)
+		} else {
+			e.circuitBreaker.recordSuccess(e.redisAddr)
+		}
+	}
+	return c, err
+}
@@ -0,0 +1,110 @@
+package exporter
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	cb := newCircuitBreaker(2, time.Hour)
+	target := "redis://localhost:6379"
+
+	if !cb.allow(target) {
+		t.Fatalf("expected unknown target to be allowed")
+	}
+
+	cb.recordFailure(target)
+	if !cb.allow(target) {
+		t.Fatalf("expected target to be allowed below the threshold")
+	}
+
+	cb.recordFailure(target)
+	if cb.allow(target) {
+		t.Fatalf("expected circuit to be open after reaching the threshold")
+	}
+
+	if !cb.allow("redis://other-host:6379") {
+		t.Fatalf("expected other targets to be unaffected")
+	}
+
+	cb.recordSuccess(target)
+	if !cb.allow(target) {
+		t.Fatalf("expected circuit to be closed after a success")
+	}
+
+	cb = newCircuitBreaker(1, time.Millisecond)
+	cb.recordFailure(target)
+	time.Sleep(5 * time.Millisecond)
+	if !cb.allow(target) {
+		t.Fatalf("expected circuit to be half-open after the cooldown")
+	}
+}
+
+func TestConnectToRedisWithRetry(t *testing.T) {
+	e, _ := NewRedisExporter("unix:///tmp/doesnt.exist", Options{
+		Namespace:              "test",
+		ConnectionRetries:      2,
+		ConnectionRetryBackoff: 10 * time.Millisecond,
+		ConnectionTimeouts:     time.Second,
+	})
+
+	start := time.Now()
+	if _, err := e.connectToRedisWithRetry(); err == nil {
+		t.Fatalf("expected connection error")
+	}
+	// 10ms + 20ms of backoff
+	if took := time.Since(start); took < 30*time.Millisecond {
+		t.Errorf("expected retries with backoff, took only %s", took)
+	}
+
+	e, _ = NewRedisExporter("unix:///tmp/doesnt.exist", Options{
+		Namespace:              "test",
+		ConnectionRetries:      5,
+		ConnectionRetryBackoff: time.Second,
+		ConnectionTimeouts:     100 * time.Millisecond,
+	})
+
+	start = time.Now()
+	if _, err := e.connectToRedisWithRetry(); err == nil {
+		t.Fatalf("expected connection error")
+	}
+	if took := time.Since(start); took > 500*time.Millisecond {
+		t.Errorf("expected retries to respect the connection timeout budget, took %s", took)
+	}
+}
+
+func TestCircuitBreakerOpenMetric(t *testing.T) {
+	e, _ := NewRedisExporter("unix:///tmp/doesnt.exist", Options{
+		Namespace:               "test",
+		CircuitBreakerThreshold: 1,
+		CircuitBreakerCooldown:  time.Hour,
+	})
+
+	for _, wantOpen := range []float64{0, 1} {
+		chM := make(chan prometheus.Metric)
+		go func() {
+			e.Collect(chM)
+			close(chM)
+		}()
+
+		found := false
+		for m := range chM {
+			if !strings.Contains(m.Desc().String(), "test_exporter_circuit_breaker_open") {
+				continue
+			}
+			found = true
+			got := &dto.Metric{}
+			m.Write(got)
+			if got.GetGauge().GetValue() != wantOpen {
+				t.Errorf("exporter_circuit_breaker_open: got %f, want %f", got.GetGauge().GetValue(), wantOpen)
+			}
+		}
+		if !found {
+			t.Errorf("didn't find test_exporter_circuit_breaker_open")
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package exporter
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// counterSample is a value of a counter and the time it was read
+type counterSample struct {
+	val float64
+	ts  time.Time
+}
+
+// counterRates keeps the last sample of a counter per target to compute its rate between scrapes
+type counterRates struct {
+	sync.Mutex
+	last map[string]counterSample
+}
+
+func newCounterRates() *counterRates {
+	return &counterRates{last: map[string]counterSample{}}
+}
+
+// rate records val and returns the per-second rate since the previous sample of target,
+// there's no rate for the first sample and after the counter was reset
+func (r *counterRates) rate(target string, val float64, now time.Time) (float64, bool) {
+	r.Lock()
+	defer r.Unlock()
+
+	prev, ok := r.last[target]
+	r.last[target] = counterSample{val: val, ts: now}
+	if !ok || val < prev.val || !now.After(prev.ts) {
+		return 0, false
+	}
+	return (val - prev.val) / now.Sub(prev.ts).Seconds(), true
+}
+
+// extractClientHeadroomMetrics exports how close the instance is to maxclients
+// and how fast connections are rejected, so alerts don't need to join metrics
+func (e *Exporter) extractClientHeadroomMetrics(ch chan<- prometheus.Metric, fields *infoFieldTable) {
+	if maxClients, ok := fields.float("maxclients"); ok && maxClients > 0 {
+		if connected, ok := fields.float("connected_clients"); ok {
+			e.registerConstMetricGauge(ch, "clients_headroom_ratio", (maxClients-connected)/maxClients)
+		}
+	}
+
+	if rejected, ok := fields.float("rejected_connections"); ok {
+		if rate, ok := e.rejectedConnections.rate(e.redisAddr, rejected, time.Now()); ok {
+			e.registerConstMetricGauge(ch, "rejected_connections_per_second", rate)
+		}
+	}
+}
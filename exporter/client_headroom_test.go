@@ -0,0 +1,78 @@
+package exporter
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestCounterRates(t *testing.T) {
+	r := newCounterRates()
+	now := time.Now()
+
+	if _, ok := r.rate("a", 10, now); ok {
+		t.Errorf("expected no rate for the first sample")
+	}
+	if rate, ok := r.rate("a", 30, now.Add(10*time.Second)); !ok || rate != 2 {
+		t.Errorf("expected a rate of 2, got: %f %t", rate, ok)
+	}
+	if _, ok := r.rate("b", 30, now.Add(10*time.Second)); ok {
+		t.Errorf("expected no rate for the first sample of another target")
+	}
+	if _, ok := r.rate("a", 5, now.Add(20*time.Second)); ok {
+		t.Errorf("expected no rate after a counter reset")
+	}
+	if rate, ok := r.rate("a", 5, now.Add(30*time.Second)); !ok || rate != 0 {
+		t.Errorf("expected a rate of 0, got: %f %t", rate, ok)
+	}
+}
+
+func TestClientHeadroomMetrics(t *testing.T) {
+	e, _ := NewRedisExporter("redis://localhost:6379", Options{Namespace: "test"})
+
+	scrape := func(info string) map[string]float64 {
+		chM := make(chan prometheus.Metric)
+		go func() {
+			e.extractInfoMetrics(chM, info, 0)
+			close(chM)
+		}()
+
+		got := map[string]float64{}
+		for m := range chM {
+			desc := m.Desc().String()
+			for _, name := range []string{"test_clients_headroom_ratio", "test_rejected_connections_per_second"} {
+				if strings.Contains(desc, `"`+name+`"`) {
+					d := &dto.Metric{}
+					m.Write(d)
+					got[name] = d.GetGauge().GetValue()
+				}
+			}
+		}
+		return got
+	}
+
+	got := scrape("# Clients\r\nconnected_clients:900\r\nmaxclients:1000\r\n\r\n# Stats\r\nrejected_connections:10\r\n")
+	if val, ok := got["test_clients_headroom_ratio"]; !ok || math.Abs(val-0.1) > 0.0001 {
+		t.Errorf("expected a headroom ratio of 0.1, got: %f (found: %t)", val, ok)
+	}
+	if _, ok := got["test_rejected_connections_per_second"]; ok {
+		t.Errorf("expected no rejected connections rate for the first scrape")
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	got = scrape("# Clients\r\nconnected_clients:1000\r\nmaxclients:1000\r\n\r\n# Stats\r\nrejected_connections:20\r\n")
+	if val := got["test_clients_headroom_ratio"]; val != 0 {
+		t.Errorf("expected a headroom ratio of 0, got: %f", val)
+	}
+	if val, ok := got["test_rejected_connections_per_second"]; !ok || val <= 0 {
+		t.Errorf("expected a positive rejected connections rate, got: %f (found: %t)", val, ok)
+	}
+
+	if got := scrape("# Clients\r\nconnected_clients:10\r\nmaxclients:0\r\n"); len(got) != 0 {
+		t.Errorf("expected no metrics without maxclients, got: %v", got)
+	}
+}
@@ -0,0 +1,85 @@
+package exporter
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// clientIdleBuckets are the upper bounds in seconds of the connected_clients_idle_seconds buckets
+var clientIdleBuckets = []float64{1, 10, 60, 300, 900, 3600, 4 * 3600, 24 * 3600, 7 * 24 * 3600}
+
+// clientIdleFields returns the idle seconds and flags of a CLIENT LIST line
+func clientIdleFields(line string) (idle float64, flags string, ok bool) {
+	foundIdle := false
+	for rest := line; rest != ""; {
+		var kvPart string
+		kvPart, rest, _ = strings.Cut(rest, " ")
+		k, v, found := strings.Cut(kvPart, "=")
+		if !found {
+			continue
+		}
+		switch k {
+		case "idle":
+			val, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return 0, "", false
+			}
+			idle, foundIdle = val, true
+		case "flags":
+			flags = v
+		}
+	}
+	return idle, flags, foundIdle
+}
+
+// parseClientIdleMetrics aggregates the idle times of all clients of the CLIENT LIST reply into
+// a histogram and counts the clients idle for longer than ClientIdleThreshold, replicas, masters and
+// pub/sub clients are idle by design and not counted as stale
+func (e *Exporter) parseClientIdleMetrics(input []byte, ch chan<- prometheus.Metric) {
+	threshold := e.options.ClientIdleThreshold.Seconds()
+	buckets := make(map[float64]uint64, len(clientIdleBuckets))
+	for _, b := range clientIdleBuckets {
+		buckets[b] = 0
+	}
+
+	count, sum, stale := uint64(0), float64(0), 0
+	for len(input) > 0 {
+		line := input
+		if idx := bytes.IndexByte(input, '\n'); idx >= 0 {
+			line, input = input[:idx], input[idx+1:]
+		} else {
+			input = nil
+		}
+
+		line = bytes.TrimSuffix(line, []byte("\r"))
+		if len(line) == 0 {
+			continue
+		}
+
+		idle, flags, ok := clientIdleFields(string(line))
+		if !ok {
+			e.logger().Debugf("no idle time in CLIENT LIST line: %s", line)
+			continue
+		}
+
+		count++
+		sum += idle
+		for _, b := range clientIdleBuckets {
+			if idle <= b {
+				buckets[b]++
+			}
+		}
+
+		if threshold > 0 && idle > threshold && !strings.ContainsAny(flags, "SMP") {
+			stale++
+		}
+	}
+
+	e.registerConstHistogram(ch, "connected_clients_idle_seconds", count, sum, buckets)
+	if threshold > 0 {
+		e.registerConstMetricGauge(ch, "connected_clients_idle_over_threshold", float64(stale))
+	}
+}
@@ -0,0 +1,79 @@
+package exporter
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestClientIdleMetrics(t *testing.T) {
+	clientList := strings.Join([]string{
+		"id=11 addr=127.0.0.1:63508 fd=8 name= age=7300 idle=7200 flags=N db=0 sub=0 psub=0 multi=-1 qbuf=0 qbuf-free=0 obl=0 oll=0 omem=0 events=r cmd=setex user=default resp=2",
+		"id=12 addr=127.0.0.1:63509 fd=9 name= age=7300 idle=7200 flags=P db=0 sub=1 psub=0 multi=-1 qbuf=0 qbuf-free=0 obl=0 oll=0 omem=0 events=r cmd=subscribe user=default resp=2",
+		"id=13 addr=127.0.0.1:63510 fd=10 name= age=100 idle=30 flags=N db=0 sub=0 psub=0 multi=-1 qbuf=0 qbuf-free=0 obl=0 oll=0 omem=0 events=r cmd=get user=default resp=2",
+		"id=14 addr=127.0.0.1:64958 fd=11 name= age=5 idle=0 flags=N db=0 sub=0 psub=0 multi=-1 qbuf=26 qbuf-free=32742 obl=0 oll=0 omem=0 events=r cmd=client user=default resp=3",
+		"",
+	}, "\r\n")
+
+	for _, tst := range []struct {
+		threshold time.Duration
+		wantStale float64
+		wantFound bool
+	}{
+		{threshold: time.Hour, wantStale: 1, wantFound: true},
+		{threshold: 3 * time.Hour, wantStale: 0, wantFound: true},
+		{threshold: 0},
+	} {
+		e, _ := NewRedisExporter("redis://localhost:6379", Options{Namespace: "test", InclClientIdleMetrics: true, ClientIdleThreshold: tst.threshold})
+
+		chM := make(chan prometheus.Metric)
+		go func() {
+			e.parseClientIdleMetrics([]byte(clientList), chM)
+			close(chM)
+		}()
+
+		var histogram *dto.Histogram
+		stale, found := 0.0, false
+		for m := range chM {
+			d := &dto.Metric{}
+			m.Write(d)
+			switch desc := m.Desc().String(); {
+			case strings.Contains(desc, `"test_connected_clients_idle_seconds"`):
+				histogram = d.GetHistogram()
+			case strings.Contains(desc, `"test_connected_clients_idle_over_threshold"`):
+				stale, found = d.GetGauge().GetValue(), true
+			}
+		}
+
+		if histogram == nil {
+			t.Fatalf("expected the idle histogram")
+		}
+		if histogram.GetSampleCount() != 4 || histogram.GetSampleSum() != 14430 {
+			t.Errorf("expected 4 clients idle for 14430s, got: %d %f", histogram.GetSampleCount(), histogram.GetSampleSum())
+		}
+		for _, b := range histogram.GetBucket() {
+			want := map[float64]uint64{1: 1, 10: 1, 60: 2, 300: 2, 900: 2, 3600: 2}[b.GetUpperBound()]
+			if b.GetUpperBound() > 3600 {
+				want = 4
+			}
+			if b.GetCumulativeCount() != want {
+				t.Errorf("expected %d clients in bucket %f, got: %d", want, b.GetUpperBound(), b.GetCumulativeCount())
+			}
+		}
+		if found != tst.wantFound || stale != tst.wantStale {
+			t.Errorf("threshold %s: expected %f stale clients (found: %t), got: %f (found: %t)", tst.threshold, tst.wantStale, tst.wantFound, stale, found)
+		}
+	}
+}
+
+func TestClientIdleFields(t *testing.T) {
+	if idle, flags, ok := clientIdleFields("id=11 addr=127.0.0.1:63508 fd=8 name= age=6321 idle=6320 flags=N db=0"); !ok || idle != 6320 || flags != "N" {
+		t.Errorf("unexpected fields: %f %q %t", idle, flags, ok)
+	}
+	if _, _, ok := clientIdleFields("id=11 addr=127.0.0.1:63508 fd=8"); ok {
+		t.Errorf("expected no idle field")
+	}
+}
@@ -1,6 +1,7 @@
 package exporter
 
 import (
+	"bytes"
 	"regexp"
 	"strconv"
 	"strings"
@@ -19,6 +20,9 @@ type ClientInfo struct {
 	Db,
 	Host,
 	Port,
+	Cmd,
+	LibName,
+	LibVer,
 	Resp string
 	CreatedAt,
 	IdleSince,
@@ -40,75 +44,85 @@ id=11 addr=127.0.0.1:63508 fd=8 name= age=6321 idle=6320 flags=N db=0 sub=0 psub
 id=14 addr=127.0.0.1:64958 fd=9 name= age=5 idle=0 flags=N db=0 sub=0 psub=0 multi=-1 qbuf=26 qbuf-free=32742 obl=0 oll=0 omem=0 events=r cmd=client user=default resp=3
 id=40253233 addr=fd40:1481:21:dbe0:7021:300:a03:1a06:44426 fd=19 name= age=782 idle=0 flags=N db=0 sub=0 psub=0 multi=-1 qbuf=26 qbuf-free=32742 argv-mem=10 obl=0 oll=0 omem=0 tot-mem=61466 ow=0 owmem=0 events=r cmd=client user=default lib-name=redis-py lib-ver=5.0.1 numops=9
 */
+var clientListLineRE = regexp.MustCompile(`^id=\d+ addr=\S+`)
+
 func parseClientListString(clientInfo string) (*ClientInfo, bool) {
-	if matched, _ := regexp.MatchString(`^id=\d+ addr=\S+`, clientInfo); !matched {
+	if !clientListLineRE.MatchString(clientInfo) {
 		return nil, false
 	}
 	connectedClient := ClientInfo{}
 	connectedClient.Ssub = -1  // mark it as missing - introduced in Redis 7.0.3
 	connectedClient.Watch = -1 // mark it as missing - introduced in Redis 7.4
-	for _, kvPart := range strings.Split(clientInfo, " ") {
-		vPart := strings.Split(kvPart, "=")
-		if len(vPart) != 2 {
+	for rest := clientInfo; rest != ""; {
+		var kvPart string
+		kvPart, rest, _ = strings.Cut(rest, " ")
+		k, v, ok := cutInfoPair(kvPart)
+		if !ok {
 			log.Debugf("Invalid format for client list string, got: %s", kvPart)
 			return nil, false
 		}
 
-		switch vPart[0] {
+		switch k {
 		case "id":
-			connectedClient.Id = vPart[1]
+			connectedClient.Id = v
 		case "name":
-			connectedClient.Name = vPart[1]
+			connectedClient.Name = v
 		case "user":
-			connectedClient.User = vPart[1]
+			connectedClient.User = v
 		case "age":
-			createdAt, err := durationFieldToTimestamp(vPart[1])
+			createdAt, err := durationFieldToTimestamp(v)
 			if err != nil {
-				log.Debugf("could not parse 'age' field(%s): %s", vPart[1], err.Error())
+				log.Debugf("could not parse 'age' field(%s): %s", v, err.Error())
 				return nil, false
 			}
 			connectedClient.CreatedAt = createdAt
 		case "idle":
-			idleSinceTs, err := durationFieldToTimestamp(vPart[1])
+			idleSinceTs, err := durationFieldToTimestamp(v)
 			if err != nil {
-				log.Debugf("could not parse 'idle' field(%s): %s", vPart[1], err.Error())
+				log.Debugf("could not parse 'idle' field(%s): %s", v, err.Error())
 				return nil, false
 			}
 			connectedClient.IdleSince = idleSinceTs
 		case "flags":
-			connectedClient.Flags = vPart[1]
+			connectedClient.Flags = v
 		case "db":
-			connectedClient.Db = vPart[1]
+			connectedClient.Db = v
 		case "sub":
-			connectedClient.Sub, _ = strconv.ParseInt(vPart[1], 10, 64)
+			connectedClient.Sub, _ = strconv.ParseInt(v, 10, 64)
 		case "psub":
-			connectedClient.Psub, _ = strconv.ParseInt(vPart[1], 10, 64)
+			connectedClient.Psub, _ = strconv.ParseInt(v, 10, 64)
 		case "ssub":
-			connectedClient.Ssub, _ = strconv.ParseInt(vPart[1], 10, 64)
+			connectedClient.Ssub, _ = strconv.ParseInt(v, 10, 64)
 		case "watch":
-			connectedClient.Watch, _ = strconv.ParseInt(vPart[1], 10, 64)
+			connectedClient.Watch, _ = strconv.ParseInt(v, 10, 64)
 		case "qbuf":
-			connectedClient.Qbuf, _ = strconv.ParseInt(vPart[1], 10, 64)
+			connectedClient.Qbuf, _ = strconv.ParseInt(v, 10, 64)
 		case "qbuf-free":
-			connectedClient.QbufFree, _ = strconv.ParseInt(vPart[1], 10, 64)
+			connectedClient.QbufFree, _ = strconv.ParseInt(v, 10, 64)
 		case "obl":
-			connectedClient.Obl, _ = strconv.ParseInt(vPart[1], 10, 64)
+			connectedClient.Obl, _ = strconv.ParseInt(v, 10, 64)
 		case "oll":
-			connectedClient.Oll, _ = strconv.ParseInt(vPart[1], 10, 64)
+			connectedClient.Oll, _ = strconv.ParseInt(v, 10, 64)
 		case "omem":
-			connectedClient.OMem, _ = strconv.ParseInt(vPart[1], 10, 64)
+			connectedClient.OMem, _ = strconv.ParseInt(v, 10, 64)
 		case "tot-mem":
-			connectedClient.TotMem, _ = strconv.ParseInt(vPart[1], 10, 64)
+			connectedClient.TotMem, _ = strconv.ParseInt(v, 10, 64)
 		case "addr":
-			hostPortString := strings.Split(vPart[1], ":")
-			if len(hostPortString) < 2 {
+			idx := strings.LastIndexByte(v, ':')
+			if idx < 0 {
 				log.Debug("Invalid value for 'addr' found in client info")
 				return nil, false
 			}
-			connectedClient.Host = strings.Join(hostPortString[:len(hostPortString)-1], ":")
-			connectedClient.Port = hostPortString[len(hostPortString)-1]
+			connectedClient.Host = v[:idx]
+			connectedClient.Port = v[idx+1:]
+		case "cmd":
+			connectedClient.Cmd = v
+		case "lib-name":
+			connectedClient.LibName = v
+		case "lib-ver":
+			connectedClient.LibVer = v
 		case "resp":
-			connectedClient.Resp = vPart[1]
+			connectedClient.Resp = v
 		}
 	}
 
@@ -123,21 +137,77 @@ func durationFieldToTimestamp(field string) (int64, error) {
 	return time.Now().Unix() - parsed, nil
 }
 
-func (e *Exporter) extractConnectedClientMetrics(ch chan<- prometheus.Metric, c redis.Conn) {
-	reply, err := redis.String(doRedisCmd(c, "CLIENT", "LIST"))
+// extractConnectedClientMetrics runs CLIENT LIST unless the server has more than
+// ClientListMaxConnectedClients clients, as the whole reply is buffered. It isn't paged,
+// CLIENT LIST ID takes single client IDs rather than ranges so paging would have to
+// enumerate every ID ever assigned by the server
+func (e *Exporter) extractConnectedClientMetrics(ch chan<- prometheus.Metric, c redis.Conn, fields *infoFieldTable) {
+	if limit := e.options.ClientListMaxConnectedClients; limit > 0 {
+		connected, _ := strconv.ParseInt(fields.value("connected_clients"), 10, 64)
+		if connected > limit {
+			e.logger().Debugf("skipping CLIENT LIST, %d connected clients are more than %d", connected, limit)
+			e.registerConstMetricGauge(ch, "exporter_client_list_skipped", 1)
+			if e.options.ExportClientList {
+				e.registerConstMetricGauge(ch, "connected_clients_skipped", float64(connected))
+			}
+			return
+		}
+		e.registerConstMetricGauge(ch, "exporter_client_list_skipped", 0)
+	}
+
+	reply, err := redis.Bytes(doRedisCmd(c, "CLIENT", "LIST"))
 	if err != nil {
-		log.Errorf("CLIENT LIST err: %s", err)
+		e.logger().Errorf("CLIENT LIST err: %s", err)
 		return
 	}
-	e.parseConnectedClientMetrics(reply, ch)
+	if e.options.ExportClientList {
+		e.parseConnectedClientMetrics(reply, ch)
+	}
+	if e.options.InclClientIdleMetrics {
+		e.parseClientIdleMetrics(reply, ch)
+	}
 }
 
-func (e *Exporter) parseConnectedClientMetrics(input string, ch chan<- prometheus.Metric) {
+// parseConnectedClientMetrics walks the buffered CLIENT LIST reply line by line instead
+// of splitting it up front, which saves a copy of the reply but doesn't bound the memory
+// of a scrape, see extractConnectedClientMetrics(). At most ClientListMaxClients clients
+// are exported
+func (e *Exporter) parseConnectedClientMetrics(input []byte, ch chan<- prometheus.Metric) {
+	exported, skipped := int64(0), int64(0)
+	blockedByCmd := map[string]int64{}
+	byLibrary := map[[2]string]int64{}
+	for len(input) > 0 {
+		line := input
+		if idx := bytes.IndexByte(input, '\n'); idx >= 0 {
+			line, input = input[:idx], input[idx+1:]
+		} else {
+			input = nil
+		}
+
+		line = bytes.TrimSuffix(line, []byte("\r"))
+		if len(line) == 0 {
+			continue
+		}
 
-	for _, s := range strings.Split(input, "\n") {
+		s := string(line)
 		info, ok := parseClientListString(s)
 		if !ok {
-			log.Debugf("parseClientListString( %s ) - couldn';t parse input", s)
+			e.logger().Debugf("parseClientListString( %s ) - couldn';t parse input", s)
+			continue
+		}
+
+		// the "b" flag marks clients waiting in a blocking command (BLPOP, XREAD, WAIT, ...)
+		if strings.Contains(info.Flags, "b") {
+			blockedByCmd[info.Cmd]++
+		}
+
+		// introduced in Redis 7.2, set by the client library via CLIENT SETINFO
+		if info.LibName != "" || info.LibVer != "" {
+			byLibrary[[2]string{info.LibName, info.LibVer}]++
+		}
+
+		if limit := e.options.ClientListMaxClients; limit > 0 && exported >= limit {
+			skipped++
 			continue
 		}
 		clientInfoLabels := []string{"id", "name", "flags", "db", "host"}
@@ -159,6 +229,7 @@ func (e *Exporter) parseConnectedClientMetrics(input string, ch chan<- prometheu
 			clientInfoLabelValues = append(clientInfoLabelValues, resp)
 		}
 
+		exported++
 		e.createMetricDescription("connected_client_info", clientInfoLabels)
 		e.registerConstMetricGauge(
 			ch, "connected_client_info", 1.0,
@@ -248,4 +319,19 @@ func (e *Exporter) parseConnectedClientMetrics(input string, ch chan<- prometheu
 			)
 		}
 	}
+
+	for cmd, count := range blockedByCmd {
+		e.registerConstMetricGauge(ch, "blocked_clients_by_command", float64(count), cmd)
+	}
+
+	for lib, count := range byLibrary {
+		e.registerConstMetricGauge(ch, "clients_by_library", float64(count), lib[0], lib[1])
+	}
+
+	if limit := e.options.ClientListMaxClients; limit > 0 {
+		if skipped > 0 {
+			e.logger().Debugf("CLIENT LIST has more than %d clients, skipped %d clients", limit, skipped)
+		}
+		e.registerConstMetricGauge(ch, "connected_clients_skipped", float64(skipped))
+	}
 }
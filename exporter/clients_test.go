@@ -1,12 +1,14 @@
 package exporter
 
 import (
+	"fmt"
 	"os"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 )
 
 func TestDurationFieldToTimestamp(t *testing.T) {
@@ -62,19 +64,19 @@ func TestParseClientListString(t *testing.T) {
 		{
 			in:           "id=11 addr=127.0.0.1:63508 fd=8 name= age=6321 idle=6320 flags=N db=0 sub=0 psub=0 multi=-1 qbuf=0 qbuf-free=0 obl=3 oll=8 omem=0 tot-mem=0 events=r cmd=setex",
 			expectedOk:   true,
-			expectedInfo: ClientInfo{Id: "11", CreatedAt: convertDurationToTimestampInt64("6321"), IdleSince: convertDurationToTimestampInt64("6320"), Flags: "N", Db: "0", Ssub: -1, Watch: -1, Obl: 3, Oll: 8, OMem: 0, TotMem: 0, Host: "127.0.0.1", Port: "63508"},
+			expectedInfo: ClientInfo{Id: "11", CreatedAt: convertDurationToTimestampInt64("6321"), IdleSince: convertDurationToTimestampInt64("6320"), Flags: "N", Db: "0", Cmd: "setex", Ssub: -1, Watch: -1, Obl: 3, Oll: 8, OMem: 0, TotMem: 0, Host: "127.0.0.1", Port: "63508"},
 		}, {
 			in:           "id=14 addr=127.0.0.1:64958 fd=9 name=foo age=5 idle=0 flags=N db=1 sub=0 psub=0 multi=-1 qbuf=26 qbuf-free=32742 obl=0 oll=0 omem=0 tot-mem=0 events=r cmd=client",
 			expectedOk:   true,
-			expectedInfo: ClientInfo{Id: "14", Name: "foo", CreatedAt: convertDurationToTimestampInt64("5"), IdleSince: convertDurationToTimestampInt64("0"), Flags: "N", Db: "1", Ssub: -1, Watch: -1, Qbuf: 26, QbufFree: 32742, OMem: 0, TotMem: 0, Host: "127.0.0.1", Port: "64958"},
+			expectedInfo: ClientInfo{Id: "14", Name: "foo", CreatedAt: convertDurationToTimestampInt64("5"), IdleSince: convertDurationToTimestampInt64("0"), Flags: "N", Db: "1", Ssub: -1, Watch: -1, Qbuf: 26, QbufFree: 32742, OMem: 0, TotMem: 0, Host: "127.0.0.1", Port: "64958", Cmd: "client"},
 		}, {
 			in:           "id=14 addr=127.0.0.1:64959 fd=9 name= age=5 idle=0 flags=N db=0 sub=0 psub=0 multi=-1 qbuf=26 qbuf-free=32742 obl=0 oll=0 omem=0 tot-mem=0 events=r cmd=client user=default resp=3",
 			expectedOk:   true,
-			expectedInfo: ClientInfo{Id: "14", CreatedAt: convertDurationToTimestampInt64("5"), IdleSince: convertDurationToTimestampInt64("0"), Flags: "N", Db: "0", Ssub: -1, Watch: -1, Qbuf: 26, QbufFree: 32742, OMem: 0, TotMem: 0, Host: "127.0.0.1", Port: "64959", User: "default", Resp: "3"},
+			expectedInfo: ClientInfo{Id: "14", CreatedAt: convertDurationToTimestampInt64("5"), IdleSince: convertDurationToTimestampInt64("0"), Flags: "N", Db: "0", Ssub: -1, Watch: -1, Qbuf: 26, QbufFree: 32742, OMem: 0, TotMem: 0, Host: "127.0.0.1", Port: "64959", Cmd: "client", User: "default", Resp: "3"},
 		}, {
 			in:           "id=40253233 addr=fd40:1481:21:dbe0:7021:300:a03:1a06:44426 fd=19 name= age=782 idle=0 flags=N db=0 sub=896 psub=18 ssub=17 watch=3 multi=-1 qbuf=26 qbuf-free=32742 argv-mem=10 obl=0 oll=555 omem=0 tot-mem=61466 ow=0 owmem=0 events=r cmd=client user=default lib-name=redis-py lib-ver=5.0.1 numops=9",
 			expectedOk:   true,
-			expectedInfo: ClientInfo{Id: "40253233", CreatedAt: convertDurationToTimestampInt64("782"), IdleSince: convertDurationToTimestampInt64("0"), Flags: "N", Db: "0", Sub: 896, Psub: 18, Ssub: 17, Watch: 3, Qbuf: 26, QbufFree: 32742, Oll: 555, OMem: 0, TotMem: 61466, Host: "fd40:1481:21:dbe0:7021:300:a03:1a06", Port: "44426", User: "default"},
+			expectedInfo: ClientInfo{Id: "40253233", CreatedAt: convertDurationToTimestampInt64("782"), IdleSince: convertDurationToTimestampInt64("0"), Flags: "N", Db: "0", Sub: 896, Psub: 18, Ssub: 17, Watch: 3, Qbuf: 26, QbufFree: 32742, Oll: 555, OMem: 0, TotMem: 61466, Host: "fd40:1481:21:dbe0:7021:300:a03:1a06", Port: "44426", Cmd: "client", LibName: "redis-py", LibVer: "5.0.1", User: "default"},
 		}, {
 			in:         "id=14 addr=127.0.0.1:64958 fd=9 name=foo age=ABCDE idle=0 flags=N db=1 sub=0 psub=0 multi=-1 qbuf=26 qbuf-free=32742 obl=0 oll=0 omem=0 tot-mem=0 events=r cmd=client",
 			expectedOk: false,
@@ -270,3 +272,186 @@ func TestExportClientListInclPort(t *testing.T) {
 		}
 	}
 }
+
+func TestParseConnectedClientMetricsMaxClients(t *testing.T) {
+	input := []byte("id=11 addr=127.0.0.1:63508 fd=8 name= age=6321 idle=6320 flags=N db=0 sub=0 psub=0 multi=-1 qbuf=0 qbuf-free=0 obl=0 oll=0 omem=0 events=r cmd=setex\n" +
+		"id=12 addr=127.0.0.1:63509 fd=9 name= age=6321 idle=6320 flags=N db=0 sub=0 psub=0 multi=-1 qbuf=0 qbuf-free=0 obl=0 oll=0 omem=0 events=r cmd=get\n" +
+		"id=13 addr=127.0.0.1:63510 fd=10 name= age=6321 idle=6320 flags=N db=0 sub=0 psub=0 multi=-1 qbuf=0 qbuf-free=0 obl=0 oll=0 omem=0 events=r cmd=client\n")
+
+	for _, tst := range []struct {
+		maxClients      int64
+		expectedClients int
+		expectedSkipped float64
+	}{
+		{maxClients: 0, expectedClients: 3, expectedSkipped: -1},
+		{maxClients: 2, expectedClients: 2, expectedSkipped: 1},
+		{maxClients: 5, expectedClients: 3, expectedSkipped: 0},
+	} {
+		e, _ := NewRedisExporter("", Options{Namespace: "test", ClientListMaxClients: tst.maxClients})
+
+		chM := make(chan prometheus.Metric)
+		go func() {
+			e.parseConnectedClientMetrics(input, chM)
+			close(chM)
+		}()
+
+		clients, skipped := 0, float64(-1)
+		for m := range chM {
+			desc := m.Desc().String()
+			if strings.Contains(desc, "connected_client_info") {
+				clients++
+			}
+			if strings.Contains(desc, "connected_clients_skipped") {
+				got := &dto.Metric{}
+				m.Write(got)
+				skipped = got.GetGauge().GetValue()
+			}
+		}
+
+		if clients != tst.expectedClients {
+			t.Errorf("max clients %d: expected %d clients, got: %d", tst.maxClients, tst.expectedClients, clients)
+		}
+		if skipped != tst.expectedSkipped {
+			t.Errorf("max clients %d: expected %.0f skipped, got: %.0f", tst.maxClients, tst.expectedSkipped, skipped)
+		}
+	}
+}
+
+func TestClientListMaxConnectedClients(t *testing.T) {
+	for _, tst := range []struct {
+		connected       string
+		wantClientList  bool
+		expectedSkipped float64
+	}{
+		{connected: "3", wantClientList: true, expectedSkipped: 0},
+		{connected: "60000", wantClientList: false, expectedSkipped: 1},
+	} {
+		e, _ := NewRedisExporter("", Options{Namespace: "test", ExportClientList: true, ClientListMaxConnectedClients: 50000})
+		c := &fakeRedisConn{replies: map[string]interface{}{"CLIENT LIST": []byte("id=11 addr=127.0.0.1:63508 fd=8 name= age=6321 idle=6320 flags=N db=0 cmd=get\n")}}
+		var fields infoFieldTable
+		fields.set("connected_clients", tst.connected)
+
+		chM := make(chan prometheus.Metric)
+		go func() {
+			e.extractConnectedClientMetrics(chM, c, &fields)
+			close(chM)
+		}()
+
+		clientList, skipped, skippedClients := false, float64(-1), float64(-1)
+		for m := range chM {
+			got := &dto.Metric{}
+			m.Write(got)
+			desc := m.Desc().String()
+			switch {
+			case strings.Contains(desc, "connected_client_info"):
+				clientList = true
+			case strings.Contains(desc, "exporter_client_list_skipped"):
+				skipped = got.GetGauge().GetValue()
+			case strings.Contains(desc, "connected_clients_skipped"):
+				skippedClients = got.GetGauge().GetValue()
+			}
+		}
+
+		if clientList != tst.wantClientList || skipped != tst.expectedSkipped {
+			t.Errorf("%s clients: expected client list %t and skipped %.0f, got: %t %.0f", tst.connected, tst.wantClientList, tst.expectedSkipped, clientList, skipped)
+		}
+		if !tst.wantClientList && skippedClients != 60000 {
+			t.Errorf("expected all clients to be reported as skipped, got: %.0f", skippedClients)
+		}
+	}
+}
+
+func TestParseConnectedClientMetricsBlockedByCommand(t *testing.T) {
+	input := []byte("id=11 addr=127.0.0.1:63508 fd=8 name= age=10 idle=10 flags=b db=0 sub=0 psub=0 multi=-1 qbuf=0 qbuf-free=0 obl=0 oll=0 omem=0 events=r cmd=blpop\n" +
+		"id=12 addr=127.0.0.1:63509 fd=9 name= age=10 idle=10 flags=b db=0 sub=0 psub=0 multi=-1 qbuf=0 qbuf-free=0 obl=0 oll=0 omem=0 events=r cmd=blpop\n" +
+		"id=13 addr=127.0.0.1:63510 fd=10 name= age=10 idle=10 flags=b db=0 sub=0 psub=0 multi=-1 qbuf=0 qbuf-free=0 obl=0 oll=0 omem=0 events=r cmd=xread\n" +
+		"id=14 addr=127.0.0.1:63511 fd=11 name= age=10 idle=0 flags=N db=0 sub=0 psub=0 multi=-1 qbuf=0 qbuf-free=0 obl=0 oll=0 omem=0 events=r cmd=get\n")
+
+	// blocked clients are counted even if they're not exported
+	e, _ := NewRedisExporter("", Options{Namespace: "test", ClientListMaxClients: 1})
+
+	chM := make(chan prometheus.Metric)
+	go func() {
+		e.parseConnectedClientMetrics(input, chM)
+		close(chM)
+	}()
+
+	blocked := map[string]float64{}
+	for m := range chM {
+		if !strings.Contains(m.Desc().String(), "blocked_clients_by_command") {
+			continue
+		}
+		got := &dto.Metric{}
+		m.Write(got)
+		blocked[got.GetLabel()[0].GetValue()] = got.GetGauge().GetValue()
+	}
+
+	if len(blocked) != 2 || blocked["blpop"] != 2 || blocked["xread"] != 1 {
+		t.Errorf("unexpected blocked clients by command: %#v", blocked)
+	}
+}
+
+func TestParseConnectedClientMetricsByLibrary(t *testing.T) {
+	input := []byte("id=11 addr=127.0.0.1:63508 fd=8 name= age=10 idle=0 flags=N db=0 cmd=get user=default lib-name=redis-py lib-ver=5.0.1\n" +
+		"id=12 addr=127.0.0.1:63509 fd=9 name= age=10 idle=0 flags=N db=0 cmd=get user=default lib-name=redis-py lib-ver=5.0.1\n" +
+		"id=13 addr=127.0.0.1:63510 fd=10 name= age=10 idle=0 flags=N db=0 cmd=get user=default lib-name=go-redis(,go1.22.1) lib-ver=9.5.1\n" +
+		"id=14 addr=127.0.0.1:63511 fd=11 name= age=10 idle=0 flags=N db=0 cmd=get user=default lib-name= lib-ver=\n")
+
+	e, _ := NewRedisExporter("", Options{Namespace: "test"})
+
+	chM := make(chan prometheus.Metric)
+	go func() {
+		e.parseConnectedClientMetrics(input, chM)
+		close(chM)
+	}()
+
+	libs := map[string]float64{}
+	for m := range chM {
+		if !strings.Contains(m.Desc().String(), "clients_by_library") {
+			continue
+		}
+		got := &dto.Metric{}
+		m.Write(got)
+		libs[got.GetLabel()[0].GetValue()+"/"+got.GetLabel()[1].GetValue()] = got.GetGauge().GetValue()
+	}
+
+	if len(libs) != 2 || libs["redis-py/5.0.1"] != 2 || libs["go-redis(,go1.22.1)/9.5.1"] != 1 {
+		t.Errorf("unexpected clients by library: %#v", libs)
+	}
+}
+
+func benchmarkClientList(n int) []byte {
+	var sb strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&sb, "id=%d addr=10.0.0.%d:%d laddr=10.0.1.1:6379 fd=%d name=app-%d age=6321 idle=%d flags=N db=0 sub=0 psub=0 ssub=0 multi=-1 watch=0 qbuf=0 qbuf-free=20474 argv-mem=0 multi-mem=0 rbs=1024 rbp=0 obl=0 oll=0 omem=0 tot-mem=22400 events=r cmd=get user=default redir=-1 resp=2 lib-name=redis-py lib-ver=5.0.1\n",
+			i, i%250, 40000+i, i+8, i%10, i%60)
+	}
+	return []byte(sb.String())
+}
+
+func BenchmarkParseClientListString(b *testing.B) {
+	line := strings.TrimSpace(string(benchmarkClientList(1)))
+	b.ReportAllocs()
+	for b.Loop() {
+		parseClientListString(line)
+	}
+}
+
+func BenchmarkParseConnectedClientMetrics(b *testing.B) {
+	input := benchmarkClientList(1000)
+	e, _ := NewRedisExporter("", Options{Namespace: "test", ExportClientList: true})
+	ch := make(chan prometheus.Metric, 1024)
+	done := make(chan struct{})
+	go func() {
+		for range ch {
+		}
+		close(done)
+	}()
+
+	b.ReportAllocs()
+	for b.Loop() {
+		e.parseConnectedClientMetrics(input, ch)
+	}
+	close(ch)
+	<-done
+}
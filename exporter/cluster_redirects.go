@@ -0,0 +1,182 @@
+package exporter
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/mna/redisc"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// clusterRedirectTypes are the values of the type label of cluster_key_redirects_total
+var clusterRedirectTypes = []string{"moved", "ask", "tryagain"}
+
+// clusterRedirects counts the redirects and TRYAGAIN replies of the key collectors per target.
+// ASK and TRYAGAIN are only returned for the keys of slots that are being migrated, MOVED once
+// a slot was moved to another node, e.g. by rebalancing, until the slot mapping is refreshed
+type clusterRedirects struct {
+	sync.Mutex
+	counts    map[string]map[string]int64
+	exhausted map[string]int64
+}
+
+func newClusterRedirects() *clusterRedirects {
+	return &clusterRedirects{counts: map[string]map[string]int64{}, exhausted: map[string]int64{}}
+}
+
+func (r *clusterRedirects) add(target, redirectType string) {
+	r.Lock()
+	defer r.Unlock()
+	if r.counts[target] == nil {
+		r.counts[target] = map[string]int64{}
+	}
+	r.counts[target][strings.ToLower(redirectType)]++
+}
+
+func (r *clusterRedirects) addExhausted(target string) {
+	r.Lock()
+	defer r.Unlock()
+	r.exhausted[target]++
+}
+
+func (r *clusterRedirects) get(target string) (map[string]int64, int64) {
+	r.Lock()
+	defer r.Unlock()
+	counts := map[string]int64{}
+	for t, n := range r.counts[target] {
+		counts[t] = n
+	}
+	return counts, r.exhausted[target]
+}
+
+// redirectConn follows the MOVED and ASK redirects of a cluster connection for at most
+// maxRedirects hops, a MOVED redirect rebinds the connection to the node of the reply that
+// now serves the slot, an ASK redirect sends the command once to the importing node.
+// TRYAGAIN replies are retried after tryAgainDelay
+type redirectConn struct {
+	conn          redis.Conn
+	maxRedirects  int
+	tryAgainDelay time.Duration
+
+	// dialAddr returns a connection to a single node
+	dialAddr func(addr string) (redis.Conn, error)
+
+	// called for every redirect and TRYAGAIN reply and when the redirect limit is reached
+	onRedirect  func(redirectType string)
+	onExhausted func()
+}
+
+func (c *redirectConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	askAddr := ""
+	for hops := 0; ; hops++ {
+		var v interface{}
+		var err error
+		if askAddr != "" {
+			v, err = c.doAsking(askAddr, cmd, args...)
+			askAddr = ""
+		} else {
+			v, err = c.conn.Do(cmd, args...)
+		}
+
+		// TRYAGAIN is returned for multi-key commands while their slot is migrated
+		if redisc.IsTryAgain(err) && hops < c.maxRedirects {
+			c.onRedirect("TRYAGAIN")
+			time.Sleep(c.tryAgainDelay)
+			continue
+		}
+
+		re := redisc.ParseRedir(err)
+		if re == nil {
+			return v, err
+		}
+		c.onRedirect(re.Type)
+		if hops >= c.maxRedirects {
+			c.onExhausted()
+			return nil, &ClusterRedirectError{Type: re.Type, Addr: re.Addr, Err: fmt.Errorf("cluster redirect limit of %d reached: %w", c.maxRedirects, err)}
+		}
+
+		if re.Type == "ASK" {
+			askAddr = re.Addr
+			continue
+		}
+
+		// an unbound cluster connection would pick the node by the first argument again,
+		// which isn't the key of e.g. MEMORY USAGE or EVALSHA, so it's bound to the node
+		// of the reply like redisc.RetryConn does
+		conn, err := c.dialAddr(re.Addr)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't follow MOVED redirect to %s: %w", re.Addr, err)
+		}
+		c.conn.Close()
+		c.conn = conn
+	}
+}
+
+// bind binds the connection to the node serving the slot of key, it has to be called
+// before the first command
+func (c *redirectConn) bind(key string) error {
+	return redisc.BindConn(c.conn, key)
+}
+
+func (c *redirectConn) doAsking(addr string, cmd string, args ...interface{}) (interface{}, error) {
+	conn, err := c.dialAddr(addr)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't follow ASK redirect to %s: %w", addr, err)
+	}
+	defer conn.Close()
+
+	if err := conn.Send("ASKING"); err != nil {
+		return nil, err
+	}
+	return conn.Do(cmd, args...)
+}
+
+func (c *redirectConn) Send(cmd string, args ...interface{}) error {
+	return c.conn.Send(cmd, args...)
+}
+
+func (c *redirectConn) Flush() error {
+	return c.conn.Flush()
+}
+
+func (c *redirectConn) Receive() (interface{}, error) {
+	return c.conn.Receive()
+}
+
+func (c *redirectConn) Err() error {
+	return c.conn.Err()
+}
+
+func (c *redirectConn) Close() error {
+	return c.conn.Close()
+}
+
+// newRedirectConn wraps conn, a connection of cluster, into a redirectConn that counts the redirects
+func (e *Exporter) newRedirectConn(cluster *redisc.Cluster, conn redis.Conn) *redirectConn {
+	return &redirectConn{
+		conn:          conn,
+		maxRedirects:  e.options.ClusterMaxRedirects,
+		tryAgainDelay: 100 * time.Millisecond,
+		dialAddr: func(addr string) (redis.Conn, error) {
+			return e.dialClusterNode(addr, cluster.DialOptions...)
+		},
+		onRedirect: func(redirectType string) {
+			e.logger().Debugf("cluster redirect: %s", redirectType)
+			e.clusterRedirects.add(e.redisAddr, redirectType)
+		},
+		onExhausted: func() {
+			e.clusterRedirects.addExhausted(e.redisAddr)
+		},
+	}
+}
+
+func (e *Exporter) registerClusterRedirectMetrics(ch chan<- prometheus.Metric) {
+	counts, exhausted := e.clusterRedirects.get(e.redisAddr)
+	for _, t := range clusterRedirectTypes {
+		e.registerConstMetric(ch, "cluster_key_redirects_total", float64(counts[t]), prometheus.CounterValue, t)
+	}
+	e.registerConstMetric(ch, "cluster_key_redirects_exhausted_total", float64(exhausted), prometheus.CounterValue)
+}
@@ -0,0 +1,224 @@
+package exporter
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/oliver006/redis_exporter/exporter/exportertest"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// nodeConn is the connection to a single node that replies with reply or err and records the sent commands
+type nodeConn struct {
+	reply  interface{}
+	err    error
+	sent   []string
+	closed bool
+}
+
+func (c *nodeConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	c.sent = append(c.sent, cmd)
+	return c.reply, c.err
+}
+func (c *nodeConn) Send(cmd string, args ...interface{}) error {
+	c.sent = append(c.sent, cmd)
+	return nil
+}
+func (c *nodeConn) Flush() error                  { return nil }
+func (c *nodeConn) Receive() (interface{}, error) { return nil, nil }
+func (c *nodeConn) Err() error                    { return nil }
+func (c *nodeConn) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestRedirectConn(t *testing.T) {
+	moved := redis.Error("MOVED 3999 127.0.0.1:6381")
+	ask := redis.Error("ASK 3999 127.0.0.1:6382")
+
+	newConn := func(first redis.Conn, nodes map[string]redis.Conn) (*redirectConn, map[string]int, *int) {
+		counts := map[string]int{}
+		exhausted := 0
+		return &redirectConn{
+			conn:         first,
+			maxRedirects: 2,
+			dialAddr: func(addr string) (redis.Conn, error) {
+				c, ok := nodes[addr]
+				if !ok {
+					return nil, errors.New("unknown node")
+				}
+				return c, nil
+			},
+			onRedirect:  func(redirectType string) { counts[redirectType]++ },
+			onExhausted: func() { exhausted++ },
+		}, counts, &exhausted
+	}
+
+	t.Run("moved", func(t *testing.T) {
+		first := &nodeConn{err: moved}
+		c, counts, _ := newConn(first, map[string]redis.Conn{"127.0.0.1:6381": &nodeConn{reply: "value"}})
+		if v, err := redis.String(c.Do("GET", "key")); err != nil || v != "value" {
+			t.Fatalf("expected value, got: %q %v", v, err)
+		}
+		if !first.closed || counts["MOVED"] != 1 {
+			t.Errorf("expected the first connection to be replaced after one MOVED, got: %v", counts)
+		}
+	})
+
+	t.Run("ask", func(t *testing.T) {
+		importing := &nodeConn{reply: "value"}
+		first := &nodeConn{err: ask}
+		c, counts, _ := newConn(first, map[string]redis.Conn{"127.0.0.1:6382": importing})
+		if v, err := redis.String(c.Do("GET", "key")); err != nil || v != "value" {
+			t.Fatalf("expected value, got: %q %v", v, err)
+		}
+		if strings.Join(importing.sent, " ") != "ASKING GET" || !importing.closed {
+			t.Errorf("expected ASKING before the command on the importing node, got: %v", importing.sent)
+		}
+		if first.closed || counts["ASK"] != 1 {
+			t.Errorf("expected the connection to be kept after an ASK, got: %v", counts)
+		}
+	})
+
+	t.Run("limit", func(t *testing.T) {
+		c, counts, exhausted := newConn(&nodeConn{err: moved}, map[string]redis.Conn{"127.0.0.1:6381": &nodeConn{err: moved}})
+		_, err := c.Do("GET", "key")
+		if err == nil || !strings.Contains(err.Error(), "redirect limit of 2") {
+			t.Fatalf("expected the redirect limit error, got: %v", err)
+		}
+		var redirectErr *ClusterRedirectError
+		if !errors.As(err, &redirectErr) || redirectErr.Type != "MOVED" {
+			t.Errorf("expected a ClusterRedirectError, got: %#v", err)
+		}
+		if counts["MOVED"] != 3 || *exhausted != 1 {
+			t.Errorf("expected the limit to be reached once, got: %v %d", counts, *exhausted)
+		}
+	})
+
+	t.Run("tryagain", func(t *testing.T) {
+		// TRYAGAIN twice before it succeeds
+		srv := exportertest.NewServer(t)
+		failures := 2
+		srv.Set("MGET", exportertest.Func(func([]string) interface{} {
+			if failures > 0 {
+				failures--
+				return exportertest.Error("TRYAGAIN Multiple keys request during rehashing of slot")
+			}
+			return "value"
+		}))
+		c, counts, _ := newConn(dialServer(t, srv), nil)
+		if v, err := redis.String(c.Do("MGET", "{a}1", "{a}2")); err != nil || v != "value" {
+			t.Fatalf("expected value, got: %q %v", v, err)
+		}
+		if counts["TRYAGAIN"] != 2 {
+			t.Errorf("expected 2 TRYAGAIN replies, got: %v", counts)
+		}
+	})
+
+	t.Run("no redirect", func(t *testing.T) {
+		c, counts, _ := newConn(&nodeConn{err: redis.Error("ERR wrong")}, nil)
+		if _, err := c.Do("GET", "key"); err == nil || err.Error() != "ERR wrong" {
+			t.Fatalf("expected the error of the command, got: %v", err)
+		}
+		if len(counts) != 0 {
+			t.Errorf("unexpected redirects: %v", counts)
+		}
+	})
+}
+
+func TestClusterRedirectMetrics(t *testing.T) {
+	e, _ := NewRedisExporter("redis://localhost:7000", Options{Namespace: "test", IsCluster: true})
+	e.clusterRedirects.add(e.redisAddr, "ASK")
+	e.clusterRedirects.add(e.redisAddr, "ASK")
+	e.clusterRedirects.add("redis://localhost:7001", "TRYAGAIN")
+	e.clusterRedirects.addExhausted(e.redisAddr)
+
+	chM := make(chan prometheus.Metric)
+	go func() {
+		e.registerClusterRedirectMetrics(chM)
+		close(chM)
+	}()
+
+	got := map[string]float64{}
+	for m := range chM {
+		d := &dto.Metric{}
+		m.Write(d)
+		name := "exhausted"
+		if len(d.GetLabel()) > 0 {
+			name = d.GetLabel()[0].GetValue()
+		}
+		got[name] = d.GetCounter().GetValue()
+	}
+	want := map[string]float64{"moved": 0, "ask": 2, "tryagain": 0, "exhausted": 1}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("expected %s = %.0f, got: %v", k, v, got)
+		}
+	}
+}
+
+func TestClusterMovedRebind(t *testing.T) {
+	// all slots are served by the first node until they're moved to the second one
+	first, second := exportertest.NewServer(t), exportertest.NewServer(t)
+	host, port, _ := net.SplitHostPort(strings.TrimPrefix(first.Addr(), "redis://"))
+	p, _ := strconv.Atoi(port)
+	first.Set("CLUSTER SLOTS", []interface{}{[]interface{}{0, 16383, []interface{}{host, p}}})
+	moved := exportertest.Error("MOVED 12539 " + strings.TrimPrefix(second.Addr(), "redis://"))
+	for _, s := range []*exportertest.Server{first, second} {
+		s.Set("CLIENT NO-TOUCH", exportertest.Status("OK"))
+	}
+	first.Set("MEMORY USAGE", moved)
+	first.Set("EVALSHA", moved)
+	second.Set("MEMORY USAGE", 128)
+	second.Set("EVALSHA", "locked")
+
+	e, _ := NewRedisExporter(first.Addr(), Options{Namespace: "test", IsCluster: true})
+	for _, args := range [][]interface{}{
+		{"MEMORY", "USAGE", "key"},
+		{"EVALSHA", "e0e1f9fabfc9d4800c877a703b823ac0578ff8db", 1, "key"},
+	} {
+		// the first argument isn't the key, an unbound connection would pick the first node again
+		c, err := e.connectToRedisCluster()
+		if err != nil {
+			t.Fatalf("connectToRedisCluster() err: %s", err)
+		}
+		if _, err := c.Do(args[0].(string), args[1:]...); err != nil {
+			t.Errorf("expected %v to follow the MOVED redirect to the second node, got: %s", args, err)
+		}
+		c.Close()
+	}
+
+	if counts, _ := e.clusterRedirects.get(e.redisAddr); counts["moved"] != 2 {
+		t.Errorf("expected 2 MOVED redirects, got: %v", counts)
+	}
+}
+
+func TestClusterNodeConnSetup(t *testing.T) {
+	srv := exportertest.NewServer(t)
+	host, port, _ := net.SplitHostPort(strings.TrimPrefix(srv.Addr(), "redis://"))
+	p, _ := strconv.Atoi(port)
+	srv.Set("CLUSTER SLOTS", []interface{}{[]interface{}{0, 16383, []interface{}{host, p}}})
+	srv.Set("CLIENT NO-TOUCH", exportertest.Status("OK"))
+	srv.Set("GET", "value")
+
+	e, _ := NewRedisExporter(srv.Addr(), Options{Namespace: "test", IsCluster: true, SetClientName: true})
+	c, err := e.connectToRedisCluster()
+	if err != nil {
+		t.Fatalf("connectToRedisCluster() err: %s", err)
+	}
+	defer c.Close()
+	if v, err := redis.String(c.Do("GET", "key")); err != nil || v != "value" {
+		t.Fatalf("expected value, got: %q %v", v, err)
+	}
+
+	cmds := strings.Join(srv.Commands(), "\n")
+	if !strings.Contains(cmds, "CLIENT SETNAME redis_exporter\nCLIENT SETINFO LIB-NAME redis_exporter\n") ||
+		!strings.Contains(cmds, "CLIENT NO-TOUCH ON\nGET key") {
+		t.Errorf("expected the node connection to be set up before the command, got: %s", cmds)
+	}
+}
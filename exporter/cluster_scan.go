@@ -0,0 +1,238 @@
+package exporter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/mna/redisc"
+)
+
+// clusterMaster is a master node of a cluster and the slot ranges it serves
+type clusterMaster struct {
+	addr  string
+	slots [][2]int
+}
+
+func (m clusterMaster) servesSlot(slot int) bool {
+	for _, r := range m.slots {
+		if slot >= r[0] && slot <= r[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// slotRanges are the hash slots the key collectors are limited to in cluster mode, all slots if empty
+type slotRanges [][2]int
+
+// parseSlotRanges parses a comma separated list of slots, slot ranges like 0-5460 and
+// hash tags like {user1} which stand for the slot of the keys with that hash tag
+func parseSlotRanges(s string) (slotRanges, error) {
+	var res slotRanges
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if len(part) > 2 && strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			slot := redisc.Slot(part)
+			res = append(res, [2]int{slot, slot})
+			continue
+		}
+
+		from, to, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(from)
+		if err != nil {
+			return nil, fmt.Errorf("invalid slot range %q", part)
+		}
+		end := start
+		if isRange {
+			if end, err = strconv.Atoi(to); err != nil {
+				return nil, fmt.Errorf("invalid slot range %q", part)
+			}
+		}
+		if start < 0 || end >= redisc.HashSlots || start > end {
+			return nil, fmt.Errorf("invalid slot range %q, slots are between 0 and %d", part, redisc.HashSlots-1)
+		}
+		res = append(res, [2]int{start, end})
+	}
+	return res, nil
+}
+
+func (r slotRanges) contains(slot int) bool {
+	if len(r) == 0 {
+		return true
+	}
+	for _, sr := range r {
+		if slot >= sr[0] && slot <= sr[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// overlaps returns whether m serves any of the slots of r
+func (r slotRanges) overlaps(m clusterMaster) bool {
+	if len(r) == 0 {
+		return true
+	}
+	for _, sr := range r {
+		for _, ms := range m.slots {
+			if sr[0] <= ms[1] && ms[0] <= sr[1] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// keysInSlotRanges returns the keys of the slot ranges of check-keys-slot-range
+func (e *Exporter) keysInSlotRanges(keys []dbKeyPair) []dbKeyPair {
+	if len(e.checkKeysSlots) == 0 {
+		return keys
+	}
+	var res []dbKeyPair
+	for _, k := range keys {
+		if e.checkKeysSlots.contains(redisc.Slot(k.key)) {
+			res = append(res, k)
+		}
+	}
+	return res
+}
+
+// scanMasters returns the masters to SCAN for pattern, all masters serving one of
+// the configured slot ranges or only the one serving the slot of the pattern's hash tag
+func (e *Exporter) scanMasters(masters []clusterMaster, pattern string) []clusterMaster {
+	slot, hasSlot := patternSlot(pattern)
+	var res []clusterMaster
+	for _, m := range masters {
+		if hasSlot && !m.servesSlot(slot) {
+			continue
+		}
+		if !e.checkKeysSlots.overlaps(m) {
+			continue
+		}
+		res = append(res, m)
+	}
+	return res
+}
+
+// parseClusterMasters returns the healthy master nodes from the output of CLUSTER NODES
+func parseClusterMasters(nodes string) []clusterMaster {
+	var masters []clusterMaster
+	for line := range strings.Lines(nodes) {
+		fields := strings.Fields(line)
+		if len(fields) < 8 {
+			continue
+		}
+		flags := "," + fields[2] + ","
+		if !strings.Contains(flags, ",master,") || strings.Contains(flags, ",fail,") || strings.Contains(flags, ",noaddr,") {
+			continue
+		}
+		addr, ok := parseClusterNodeString(line)
+		if !ok {
+			continue
+		}
+
+		m := clusterMaster{addr: addr}
+		for _, s := range fields[8:] {
+			// skip importing/migrating slots like [42->-<node id>]
+			if strings.HasPrefix(s, "[") {
+				continue
+			}
+			from, to, isRange := strings.Cut(s, "-")
+			start, err := strconv.Atoi(from)
+			if err != nil {
+				continue
+			}
+			end := start
+			if isRange {
+				if end, err = strconv.Atoi(to); err != nil {
+					continue
+				}
+			}
+			m.slots = append(m.slots, [2]int{start, end})
+		}
+		if len(m.slots) > 0 {
+			masters = append(masters, m)
+		}
+	}
+	return masters
+}
+
+// patternSlot returns the slot of all keys matching pattern if it has a hash tag without glob characters
+func patternSlot(pattern string) (int, bool) {
+	start := strings.IndexByte(pattern, '{')
+	if start < 0 {
+		return 0, false
+	}
+	end := strings.IndexByte(pattern[start+1:], '}')
+	if end <= 0 {
+		return 0, false
+	}
+	if tag := pattern[start+1 : start+1+end]; globPattern.MatchString(tag) || strings.ContainsRune(tag, '\\') {
+		return 0, false
+	}
+	// a glob character before the hash tag could match another '{'
+	if globPattern.MatchString(pattern[:start]) {
+		return 0, false
+	}
+	return redisc.Slot(pattern), true
+}
+
+// clusterScanKeysFunc fans out the SCAN for pattern to the master nodes of the cluster (see
+// scanMasters) and calls fn with every page of keys in the configured slot ranges as it
+// arrives, a key that is migrated while scanning can be reported by both nodes
+func (e *Exporter) clusterScanKeysFunc(c redis.Conn, pattern string, count int64, fn func(keys []string) error) error {
+	nodes, err := redis.String(doRedisCmd(c, "CLUSTER", "NODES"))
+	if err != nil {
+		return fmt.Errorf("couldn't get cluster nodes: %w", err)
+	}
+
+	if slot, ok := patternSlot(pattern); ok && !e.checkKeysSlots.contains(slot) {
+		e.logger().Debugf("clusterScanKeysFunc() pattern: %s slot %d is outside of check-keys-slot-range", pattern, slot)
+		return nil
+	}
+
+	masters := e.scanMasters(parseClusterMasters(nodes), pattern)
+	if len(masters) == 0 {
+		return fmt.Errorf("no master nodes found to scan for pattern %s", pattern)
+	}
+
+	options, err := e.configureOptions(e.redisAddr)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range masters {
+		nodeConn, err := e.dialClusterNode(m.addr, options...)
+		if err != nil {
+			return fmt.Errorf("couldn't connect to cluster node %s: %w", m.addr, err)
+		}
+		nodeConn = e.withCommandLogging(e.withScrapeContext(nodeConn))
+		err = scanKeysFunc(nodeConn, pattern, count, func(page []interface{}) error {
+			keys, err := redis.Strings(page, nil)
+			if err != nil {
+				return err
+			}
+			if len(e.checkKeysSlots) > 0 {
+				inRange := keys[:0]
+				for _, k := range keys {
+					if e.checkKeysSlots.contains(redisc.Slot(k)) {
+						inRange = append(inRange, k)
+					}
+				}
+				keys = inRange
+			}
+			return fn(keys)
+		})
+		nodeConn.Close()
+		if err != nil {
+			return fmt.Errorf("error scanning cluster node %s: %w", m.addr, err)
+		}
+	}
+	e.logger().Debugf("clusterScanKeysFunc() pattern: %s scanned %d master nodes", pattern, len(masters))
+	return nil
+}
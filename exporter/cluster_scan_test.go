@@ -0,0 +1,188 @@
+package exporter
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/oliver006/redis_exporter/exporter/exportertest"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestParseClusterMasters(t *testing.T) {
+	nodes := `07c37dfeb235213a872192d90877d0cd55635b91 127.0.0.1:30004@31004 slave e7d1eecce10fd6bb5eb35b9f99a514335d9ba9ca 0 1426238317239 4 connected
+67ed2db8d677e59ec4a4cefb06858cf2a1a89fa1 127.0.0.1:30002@31002 master - 0 1426238316232 2 connected 5461-10922
+292f8b365bb7edb5e285caf0b7e6ddc7265d2f4f 127.0.0.1:30003@31003 master - 0 1426238318243 3 connected 10923-16383 [16000->-e7d1eecce10fd6bb5eb35b9f99a514335d9ba9ca]
+e7d1eecce10fd6bb5eb35b9f99a514335d9ba9ca 127.0.0.1:30001@31001 myself,master - 0 0 1 connected 0-5460 16000
+824fe116063bc5fcf9f4ffd895bc17aee7731ac3 127.0.0.1:30006@31006 master,fail - 1426238316232 1426238315225 6 connected 42
+6ec23923021cf3ffec47632106199cb7f496ce01 127.0.0.1:30005@31005 master - 0 1426238316232 5 connected
+`
+	want := []clusterMaster{
+		{addr: "127.0.0.1:30002", slots: [][2]int{{5461, 10922}}},
+		{addr: "127.0.0.1:30003", slots: [][2]int{{10923, 16383}}},
+		{addr: "127.0.0.1:30001", slots: [][2]int{{0, 5460}, {16000, 16000}}},
+	}
+	if got := parseClusterMasters(nodes); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseClusterMasters() mismatch.\nActual: %#v\nExpected: %#v", got, want)
+	}
+
+	if !want[2].servesSlot(16000) || want[2].servesSlot(16001) || !want[0].servesSlot(5461) {
+		t.Errorf("unexpected servesSlot() result")
+	}
+}
+
+func TestPatternSlot(t *testing.T) {
+	for _, tst := range []struct {
+		pattern string
+		wantOk  bool
+	}{
+		{pattern: "{user1000}:*", wantOk: true},
+		{pattern: "sessions:{user1000}:*", wantOk: true},
+		{pattern: "user:*", wantOk: false},
+		{pattern: "{user*}:sessions", wantOk: false},
+		{pattern: "*{user1000}", wantOk: false},
+		{pattern: "{}:*", wantOk: false},
+		{pattern: "{user1000:*", wantOk: false},
+	} {
+		slot, ok := patternSlot(tst.pattern)
+		if ok != tst.wantOk {
+			t.Errorf("patternSlot(%q) expected ok: %t, got: %t", tst.pattern, tst.wantOk, ok)
+		}
+		if ok {
+			if want, _ := patternSlot("{user1000}"); slot != want {
+				t.Errorf("patternSlot(%q) expected slot %d of the hash tag, got: %d", tst.pattern, want, slot)
+			}
+		}
+	}
+}
+
+func TestParseSlotRanges(t *testing.T) {
+	for _, tst := range []struct {
+		in      string
+		want    slotRanges
+		wantErr bool
+	}{
+		{in: "", want: nil},
+		{in: "0-5460", want: slotRanges{{0, 5460}}},
+		{in: "0-100, 200 ,16383", want: slotRanges{{0, 100}, {200, 200}, {16383, 16383}}},
+		{in: "{user1000}", want: slotRanges{{3443, 3443}}},
+		{in: "100-50", wantErr: true},
+		{in: "0-16384", wantErr: true},
+		{in: "a-b", wantErr: true},
+	} {
+		got, err := parseSlotRanges(tst.in)
+		if (err != nil) != tst.wantErr {
+			t.Errorf("parseSlotRanges(%q) err: %v", tst.in, err)
+			continue
+		}
+		if !tst.wantErr && !reflect.DeepEqual(got, tst.want) {
+			t.Errorf("parseSlotRanges(%q) = %v, want: %v", tst.in, got, tst.want)
+		}
+	}
+}
+
+func TestCheckKeysSlotRange(t *testing.T) {
+	masters := []clusterMaster{
+		{addr: "127.0.0.1:30001", slots: [][2]int{{0, 5460}}},
+		{addr: "127.0.0.1:30002", slots: [][2]int{{5461, 10922}}},
+		{addr: "127.0.0.1:30003", slots: [][2]int{{10923, 16383}}},
+	}
+	addrs := func(masters []clusterMaster) []string {
+		var res []string
+		for _, m := range masters {
+			res = append(res, m.addr)
+		}
+		return res
+	}
+
+	e, err := NewRedisExporter("redis://127.0.0.1:30001", Options{Namespace: "test", IsCluster: true, CheckKeysSlotRange: "5000-6000"})
+	if err != nil {
+		t.Fatalf("NewRedisExporter() err: %s", err)
+	}
+	if got := addrs(e.scanMasters(masters, "user:*")); !reflect.DeepEqual(got, []string{"127.0.0.1:30001", "127.0.0.1:30002"}) {
+		t.Errorf("unexpected masters to scan: %v", got)
+	}
+	// {user1000} is in slot 3443, the scan is skipped by clusterScanKeysFunc
+	if got := addrs(e.scanMasters(masters, "{user1000}:*")); !reflect.DeepEqual(got, []string{"127.0.0.1:30001"}) {
+		t.Errorf("unexpected masters to scan for a hash tag pattern: %v", got)
+	}
+
+	// "foo" is in slot 12182, "{user1000}:a" in 3443 and "bar" in 5061
+	keys := e.keysInSlotRanges([]dbKeyPair{{db: "0", key: "foo"}, {db: "0", key: "{user1000}:a"}, {db: "0", key: "bar"}})
+	if !reflect.DeepEqual(keys, []dbKeyPair{{db: "0", key: "bar"}}) {
+		t.Errorf("unexpected keys in the slot range: %v", keys)
+	}
+
+	e, _ = NewRedisExporter("redis://127.0.0.1:30001", Options{Namespace: "test", IsCluster: true, CheckKeysSlotRange: "{user1000},12182"})
+	keys = e.keysInSlotRanges([]dbKeyPair{{db: "0", key: "foo"}, {db: "0", key: "{user1000}:a"}, {db: "0", key: "bar"}})
+	if !reflect.DeepEqual(keys, []dbKeyPair{{db: "0", key: "foo"}, {db: "0", key: "{user1000}:a"}}) {
+		t.Errorf("unexpected keys in the slot range: %v", keys)
+	}
+	if got := addrs(e.scanMasters(masters, "*")); !reflect.DeepEqual(got, []string{"127.0.0.1:30001", "127.0.0.1:30003"}) {
+		t.Errorf("unexpected masters to scan: %v", got)
+	}
+
+	if _, err := NewRedisExporter("redis://127.0.0.1:30001", Options{CheckKeysSlotRange: "0-99999"}); err == nil {
+		t.Errorf("expected an error for an invalid slot range")
+	}
+}
+
+func TestClusterCountKeys(t *testing.T) {
+	clusterUri := os.Getenv("TEST_REDIS_CLUSTER_MASTER_URI")
+	if clusterUri == "" {
+		t.Skipf("Skipping TestClusterCountKeys, don't have env var TEST_REDIS_CLUSTER_MASTER_URI")
+	}
+	setupTestKeysCluster(t, clusterUri)
+	defer deleteTestKeysCluster(t, clusterUri)
+
+	e, _ := NewRedisExporter(clusterUri, Options{Namespace: "test", CountKeys: "*", IsCluster: true})
+
+	chM := make(chan prometheus.Metric)
+	go func() {
+		e.Collect(chM)
+		close(chM)
+	}()
+
+	found := false
+	for m := range chM {
+		d := &dto.Metric{}
+		m.Write(d)
+		if m.Desc() == e.mustFindMetricDescription("keys_count") {
+			found = true
+			// the test keys are spread over the slots of all master nodes
+			if got := d.GetGauge().GetValue(); got < float64(len(testKeys)) {
+				t.Errorf("expected at least %d keys counted across all masters, got: %f", len(testKeys), got)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("keys_count metric not found")
+	}
+}
+
+func TestClusterScanNodeConnNoTouch(t *testing.T) {
+	srv := exportertest.NewServer(t)
+	addr := strings.TrimPrefix(srv.Addr(), "redis://")
+	srv.Set("CLUSTER NODES", "e7d1eecce10fd6bb5eb35b9f99a514335d9ba9ca "+addr+"@16379 myself,master - 0 0 1 connected 0-16383\n")
+	srv.Set("CLIENT NO-TOUCH", exportertest.Status("OK"))
+	srv.Set("SCAN", []interface{}{"0", []string{"key"}})
+
+	e, _ := NewRedisExporter(srv.Addr(), Options{Namespace: "test", IsCluster: true})
+	c := dialServer(t, srv)
+
+	var keys []string
+	if err := e.clusterScanKeysFunc(c, "*", 10, func(page []string) error {
+		keys = append(keys, page...)
+		return nil
+	}); err != nil {
+		t.Fatalf("clusterScanKeysFunc() err: %s", err)
+	}
+	if !reflect.DeepEqual(keys, []string{"key"}) {
+		t.Errorf("expected the key of the node, got: %v", keys)
+	}
+	if cmds := strings.Join(srv.Commands(), "\n"); !strings.Contains(cmds, "CLIENT NO-TOUCH ON\nSCAN 0") {
+		t.Errorf("expected CLIENT NO-TOUCH on the node connection before SCAN, got: %s", cmds)
+	}
+}
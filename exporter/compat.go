@@ -0,0 +1,91 @@
+package exporter
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// compat levels of the scraped server, each level disables the collectors of the level below,
+// exported as exporter_compat_level so legacy servers can be told apart from failing collectors
+const (
+	// all collectors run
+	compatLevelFull = iota
+	// before Redis 7.0: LATENCY HISTOGRAM isn't available
+	compatLevelNoLatencyHistogram
+	// before Redis 5.0: XINFO isn't available so the streams collector is skipped
+	compatLevelNoXinfo
+	// before Redis 4.0: MEMORY USAGE isn't available so the key memory usage isn't exported
+	compatLevelNoMemory
+	// before Redis 2.8.13: LATENCY isn't available so the latency collector is skipped
+	compatLevelNoLatency
+)
+
+// compatVersions are the versions that introduced the commands of the compat levels,
+// a server older than compatVersions[i] has at least compat level i+1
+var compatVersions = [][3]int{
+	{7, 0, 0},
+	{5, 0, 0},
+	{4, 0, 0},
+	{2, 8, 13},
+}
+
+// parseRedisVersion parses a version like 3.2.12, missing parts are 0
+func parseRedisVersion(s string) ([3]int, bool) {
+	var v [3]int
+	parts := strings.SplitN(s, ".", 3)
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return v, false
+		}
+		v[i] = n
+	}
+	return v, true
+}
+
+func versionLess(a, b [3]int) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return false
+}
+
+// compatLevel returns the compat level of a server with the redis_version of INFO, servers
+// without a parsable version are assumed to support all collectors
+func compatLevel(redisVersion string) int {
+	v, ok := parseRedisVersion(redisVersion)
+	if !ok {
+		return compatLevelFull
+	}
+	level := compatLevelFull
+	for _, introduced := range compatVersions {
+		if versionLess(v, introduced) {
+			level++
+		}
+	}
+	return level
+}
+
+// detectCompatLevel sets the compat level of the current scrape from the fields of INFO
+func (e *Exporter) detectCompatLevel(ch chan<- prometheus.Metric, fields *infoFieldTable) {
+	version := fields.value("redis_version")
+	e.compatLevel = compatLevel(version)
+	if e.compatLevel >= compatLevelNoXinfo {
+		e.logger().Debugf("legacy server version %s, compat level %d", version, e.compatLevel)
+	}
+	e.registerConstMetricGauge(ch, "exporter_compat_level", float64(e.compatLevel))
+}
+
+// supportsCompat returns true if the server supports the commands disabled at level, it logs
+// which collector is skipped otherwise
+func (e *Exporter) supportsCompat(level int, collector string) bool {
+	if e.compatLevel < level {
+		return true
+	}
+	e.logger().Debugf("skipping %s, not supported at compat level %d", collector, e.compatLevel)
+	return false
+}
@@ -0,0 +1,83 @@
+package exporter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestCompatLevel(t *testing.T) {
+	for version, want := range map[string]int{
+		"7.2.4":   compatLevelFull,
+		"7.0":     compatLevelFull,
+		"6.2.14":  compatLevelNoLatencyHistogram,
+		"5.0.0":   compatLevelNoLatencyHistogram,
+		"4.0.14":  compatLevelNoXinfo,
+		"3.2.12":  compatLevelNoMemory,
+		"2.8.13":  compatLevelNoMemory,
+		"2.8.12":  compatLevelNoLatency,
+		"2.6.17":  compatLevelNoLatency,
+		"":        compatLevelFull,
+		"unknown": compatLevelFull,
+	} {
+		if got := compatLevel(version); got != want {
+			t.Errorf("compatLevel(%q) = %d, want: %d", version, got, want)
+		}
+	}
+}
+
+func TestDetectCompatLevel(t *testing.T) {
+	e, _ := NewRedisExporter("redis://localhost:6379", Options{Namespace: "test"})
+
+	var fields infoFieldTable
+	fields.set("redis_version", "3.0.7")
+	fields.set("redis_mode", "standalone")
+
+	chM := make(chan prometheus.Metric, 1)
+	e.detectCompatLevel(chM, &fields)
+	close(chM)
+
+	if e.compatLevel != compatLevelNoMemory {
+		t.Errorf("expected compat level %d, got: %d", compatLevelNoMemory, e.compatLevel)
+	}
+	m := <-chM
+	d := &dto.Metric{}
+	m.Write(d)
+	if !strings.Contains(m.Desc().String(), `"test_exporter_compat_level"`) || d.GetGauge().GetValue() != compatLevelNoMemory {
+		t.Errorf("unexpected metric: %s %v", m.Desc(), d)
+	}
+}
+
+func TestLegacyServerCollectors(t *testing.T) {
+	c := &pipelineConn{fakeRedisConn: fakeRedisConn{replies: map[string]interface{}{
+		"SELECT 0":  []byte("OK"),
+		"TYPE list": []byte("list"),
+		"LLEN list": int64(3),
+	}}}
+	e, _ := NewRedisExporter("redis://localhost:6379", Options{Namespace: "test", CheckSingleKeys: "db0=list"})
+	e.compatLevel = compatLevelNoLatency
+
+	chM := make(chan prometheus.Metric)
+	go func() {
+		e.extractLatencyMetrics(chM, "", c)
+		if err := e.extractCheckKeyMetrics(chM, c); err != nil {
+			t.Errorf("extractCheckKeyMetrics() err: %s", err)
+		}
+		close(chM)
+	}()
+
+	var got []string
+	for m := range chM {
+		got = append(got, m.Desc().String())
+	}
+	if len(got) != 1 || !strings.Contains(got[0], `"test_key_size"`) {
+		t.Errorf("expected only the key size, got: %v", got)
+	}
+	for _, cmd := range c.commands {
+		if strings.HasPrefix(cmd, "LATENCY") || strings.HasPrefix(cmd, "MEMORY") {
+			t.Errorf("unexpected command on a legacy server: %s", cmd)
+		}
+	}
+}
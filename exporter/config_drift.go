@@ -0,0 +1,52 @@
+package exporter
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+// LoadExpectedConfigFile reads a JSON file with the expected config values
+// (as returned by CONFIG GET) keyed by parameter name
+func LoadExpectedConfigFile(configFile string) (map[string]string, error) {
+	res := make(map[string]string)
+
+	log.Debugf("start load expected config file: %s", configFile)
+	bytes, err := os.ReadFile(configFile)
+	if err != nil {
+		log.Warnf("load expected config file failed: %s", err)
+		return nil, err
+	}
+	err = json.Unmarshal(bytes, &res)
+	if err != nil {
+		log.Warnf("expected config file format error: %s", err)
+		return nil, err
+	}
+
+	log.Infof("Loaded %d expected config values from %s", len(res), configFile)
+	return res, nil
+}
+
+// extractConfigDriftMetrics compares the config values of the instance with the expected
+// ones, parameters missing from CONFIG GET, e.g. unknown to the version of the instance
+// or misspelled in the file, are drifted with missing="true"
+func (e *Exporter) extractConfigDriftMetrics(ch chan<- prometheus.Metric, config map[string]string) {
+	for param, expected := range e.options.ExpectedConfig {
+		actual, ok := config[param]
+		if !ok {
+			e.logger().Debugf("expected config parameter %s not found", param)
+			e.registerConstMetricGauge(ch, "config_drift", 1, param, "true")
+			continue
+		}
+
+		drift := 0.0
+		if strings.TrimSpace(actual) != strings.TrimSpace(expected) {
+			e.logger().Debugf("config drift for %s, expected: %q, got: %q", param, expected, actual)
+			drift = 1
+		}
+		e.registerConstMetricGauge(ch, "config_drift", drift, param, "false")
+	}
+}
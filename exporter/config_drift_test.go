@@ -0,0 +1,82 @@
+package exporter
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestLoadExpectedConfigFile(t *testing.T) {
+	expected, err := LoadExpectedConfigFile("../contrib/sample-expected-config.json")
+	if err != nil {
+		t.Fatalf("LoadExpectedConfigFile() err: %s", err)
+	}
+	if expected["maxmemory-policy"] != "allkeys-lru" {
+		t.Errorf("unexpected config: %#v", expected)
+	}
+
+	if _, err := LoadExpectedConfigFile("non-existent.json"); err == nil {
+		t.Errorf("expected error for missing file")
+	}
+
+	if _, err := LoadExpectedConfigFile("../contrib/sample-pwd-file.json-malformed"); err == nil {
+		t.Errorf("expected error for malformed file")
+	}
+}
+
+func TestConfigDriftMetrics(t *testing.T) {
+	e, _ := NewRedisExporter("", Options{
+		Namespace: "test",
+		ExpectedConfig: map[string]string{
+			"maxmemory-policy": "allkeys-lru",
+			"appendonly":       "yes",
+			"unknown-param":    "1",
+		},
+	})
+
+	config := []interface{}{
+		[]byte("maxmemory-policy"), []byte("allkeys-lru"),
+		[]byte("appendonly"), []byte("no"),
+		[]byte("databases"), []byte("16"),
+	}
+
+	chM := make(chan prometheus.Metric)
+	go func() {
+		if _, err := e.extractConfigMetrics(chM, config); err != nil {
+			t.Errorf("extractConfigMetrics() err: %s", err)
+		}
+		close(chM)
+	}()
+
+	drift := map[string]float64{}
+	missing := map[string]string{}
+	for m := range chM {
+		if m.Desc() != e.mustFindMetricDescription("config_drift") {
+			continue
+		}
+		got := &dto.Metric{}
+		m.Write(got)
+		labels := map[string]string{}
+		for _, l := range got.GetLabel() {
+			labels[l.GetName()] = l.GetValue()
+		}
+		drift[labels["parameter"]] = got.GetGauge().GetValue()
+		missing[labels["parameter"]] = labels["missing"]
+	}
+
+	want := map[string]float64{"maxmemory-policy": 0, "appendonly": 1, "unknown-param": 1}
+	if len(drift) != len(want) {
+		t.Fatalf("expected %#v, got: %#v", want, drift)
+	}
+	for param, val := range want {
+		if drift[param] != val {
+			t.Errorf("expected drift %.0f for %s, got: %.0f", val, param, drift[param])
+		}
+	}
+	for param, want := range map[string]string{"maxmemory-policy": "false", "appendonly": "false", "unknown-param": "true"} {
+		if missing[param] != want {
+			t.Errorf("expected missing=%q for %s, got: %q", want, param, missing[param])
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package exporter
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// processStartTime is the time the exporter process started
+var processStartTime = time.Now()
+
+// configHashKey keys the hash of the password entries, without it the passwords
+// could be brute-forced from the exported hash
+var configHashKey = rand.Text()
+
+// configReloads is the state of the config reloaded by /-/reload (the password file)
+// exported like the config reload metrics of Prometheus and Alertmanager
+type configReloads struct {
+	sync.Mutex
+	hash           float64
+	lastSuccessful bool
+	lastSuccessAt  time.Time
+	reloads        int64
+}
+
+// newConfigReloads returns the state after the password file was loaded at startup,
+// which isn't counted as a reload
+func newConfigReloads(entries map[string]PasswordEntry, now time.Time) *configReloads {
+	return &configReloads{hash: configHash(entries), lastSuccessful: true, lastSuccessAt: now}
+}
+
+func (c *configReloads) succeeded(entries map[string]PasswordEntry, now time.Time) {
+	c.Lock()
+	defer c.Unlock()
+	c.hash = configHash(entries)
+	c.lastSuccessful = true
+	c.lastSuccessAt = now
+	c.reloads++
+}
+
+func (c *configReloads) failed() {
+	c.Lock()
+	defer c.Unlock()
+	c.lastSuccessful = false
+}
+
+// configHash returns the first 48 bits of the HMAC-SHA-256 of the password entries, as
+// many as a float64 can hold without losing precision. The key is random per process
+// so hashes can only be compared between reloads of the same process
+func configHash(entries map[string]PasswordEntry) float64 {
+	// maps are marshaled with sorted keys so the same entries have the same hash
+	data, _ := json.Marshal(entries)
+	mac := hmac.New(sha256.New, []byte(configHashKey))
+	mac.Write(data)
+	sum := mac.Sum(nil)
+	return float64(binary.BigEndian.Uint64(sum[:8]) >> 16)
+}
+
+// registerConfigReloadMetrics exports the start time of the exporter and, if a password
+// file is configured, its hash and the result of the last reload
+func (e *Exporter) registerConfigReloadMetrics(ch chan<- prometheus.Metric) {
+	if e.targetExporter {
+		return
+	}
+	e.registerConstMetricGauge(ch, "exporter_start_time_seconds", float64(processStartTime.UnixNano())/1e9)
+
+	if e.options.RedisPwdFile == "" {
+		return
+	}
+	e.configReloads.Lock()
+	defer e.configReloads.Unlock()
+
+	successful := 0.0
+	if e.configReloads.lastSuccessful {
+		successful = 1
+	}
+	e.registerConstMetricGauge(ch, "exporter_config_hash", e.configReloads.hash)
+	e.registerConstMetricGauge(ch, "exporter_config_last_reload_successful", successful)
+	e.registerConstMetricGauge(ch, "exporter_config_last_reload_success_timestamp_seconds", float64(e.configReloads.lastSuccessAt.Unix()))
+	e.registerConstMetric(ch, "exporter_config_reloads_total", float64(e.configReloads.reloads), prometheus.CounterValue)
+}
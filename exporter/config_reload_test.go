@@ -0,0 +1,113 @@
+package exporter
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestConfigReloadMetrics(t *testing.T) {
+	pwdFile := filepath.Join(t.TempDir(), "pwd.json")
+	if err := os.WriteFile(pwdFile, []byte(`{"redis://localhost:6379": "pwd1"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := LoadPwdFileEntries(pwdFile, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, _ := NewRedisExporter("", Options{Namespace: "test", RedisPwdFile: pwdFile, PasswordEntries: entries})
+
+	metrics := func() map[string]float64 {
+		chM := make(chan prometheus.Metric)
+		go func() {
+			e.registerConfigReloadMetrics(chM)
+			close(chM)
+		}()
+		got := map[string]float64{}
+		for m := range chM {
+			desc := m.Desc().String()
+			name := desc[strings.Index(desc, `"`)+1:]
+			name = name[:strings.Index(name, `"`)]
+			d := &dto.Metric{}
+			m.Write(d)
+			got[name] = d.GetGauge().GetValue() + d.GetCounter().GetValue()
+		}
+		return got
+	}
+	reload := func() {
+		e.reloadPwdFile(httptest.NewRecorder(), httptest.NewRequest("GET", "/-/reload", nil))
+	}
+
+	initial := metrics()
+	if initial["test_exporter_start_time_seconds"] == 0 || initial["test_exporter_config_hash"] == 0 {
+		t.Fatalf("expected the start time and config hash, got: %v", initial)
+	}
+	if initial["test_exporter_config_last_reload_successful"] != 1 || initial["test_exporter_config_reloads_total"] != 0 {
+		t.Errorf("expected the initial load to be successful and not counted, got: %v", initial)
+	}
+
+	reload()
+	got := metrics()
+	if got["test_exporter_config_reloads_total"] != 1 || got["test_exporter_config_hash"] != initial["test_exporter_config_hash"] {
+		t.Errorf("expected one reload with an unchanged hash, got: %v", got)
+	}
+
+	if err := os.WriteFile(pwdFile, []byte(`{"redis://localhost:6379": "pwd2"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	reload()
+	got = metrics()
+	if got["test_exporter_config_reloads_total"] != 2 || got["test_exporter_config_hash"] == initial["test_exporter_config_hash"] {
+		t.Errorf("expected a second reload with a new hash, got: %v", got)
+	}
+
+	if err := os.WriteFile(pwdFile, []byte(`{`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	reload()
+	failed := metrics()
+	if failed["test_exporter_config_last_reload_successful"] != 0 || failed["test_exporter_config_reloads_total"] != 2 || failed["test_exporter_config_hash"] != got["test_exporter_config_hash"] {
+		t.Errorf("expected a failed reload to keep the previous config, got: %v", failed)
+	}
+}
+
+func TestConfigReloadMetricsWithoutPwdFile(t *testing.T) {
+	e, _ := NewRedisExporter("", Options{Namespace: "test"})
+	chM := make(chan prometheus.Metric)
+	go func() {
+		e.registerConfigReloadMetrics(chM)
+		close(chM)
+	}()
+
+	n := 0
+	for m := range chM {
+		n++
+		if !strings.Contains(m.Desc().String(), `"test_exporter_start_time_seconds"`) {
+			t.Errorf("unexpected metric without a password file: %s", m.Desc())
+		}
+	}
+	if n != 1 {
+		t.Errorf("expected only the start time, got %d metrics", n)
+	}
+}
+
+func TestConfigHashIsKeyed(t *testing.T) {
+	entries := map[string]PasswordEntry{"redis://localhost:6379": {Password: "pwd1"}}
+	if configHash(entries) != configHash(entries) {
+		t.Errorf("expected the same entries to have the same hash")
+	}
+
+	data, _ := json.Marshal(entries)
+	sum := sha256.Sum256(data)
+	if configHash(entries) == float64(binary.BigEndian.Uint64(sum[:8])>>16) {
+		t.Errorf("expected the hash to be keyed, got the plain SHA-256")
+	}
+}
@@ -0,0 +1,254 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	log "github.com/sirupsen/logrus"
+)
+
+// counterStateSaveInterval is how often the counter state is saved besides on shutdown
+const counterStateSaveInterval = 30 * time.Second
+
+// counterStateFile is the content of CounterStateFile, targets are keyed by their
+// redacted address so no credentials end up in the file
+type counterStateFile struct {
+	SavedAt             time.Time                     `json:"saved_at"`
+	Scrapes             float64                       `json:"scrapes_total"`
+	ScrapeRequestErrors float64                       `json:"target_scrape_request_errors_total"`
+	Targets             map[string]targetCounterState `json:"targets"`
+}
+
+// targetCounterState are the exporter side counters of a target
+type targetCounterState struct {
+	Role                      string            `json:"role,omitempty"`
+	RoleChanges               int64             `json:"role_changes,omitempty"`
+	RoleLastChange            *time.Time        `json:"role_last_change,omitempty"`
+	EventStates               map[string]string `json:"event_states,omitempty"`
+	EventCounts               map[string]int64  `json:"event_counts,omitempty"`
+	ClusterRedirects          map[string]int64  `json:"cluster_redirects,omitempty"`
+	ClusterRedirectsExhausted int64             `json:"cluster_redirects_exhausted,omitempty"`
+}
+
+// counterState keeps the loaded state of the targets that haven't been scraped since the
+// exporter started, it's restored on their first scrape
+type counterState struct {
+	sync.Mutex
+	file    string
+	pending map[string]targetCounterState
+}
+
+func loadCounterStateFile(file string) (*counterStateFile, error) {
+	bytes, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var state counterStateFile
+	if err := json.Unmarshal(bytes, &state); err != nil {
+		return nil, fmt.Errorf("counter state file format error: %s", err)
+	}
+	return &state, nil
+}
+
+// initCounterState loads CounterStateFile, the global counters continue from the
+// saved values and the counters of the targets are restored on their first scrape
+func (e *Exporter) initCounterState() error {
+	e.counterState = &counterState{file: e.options.CounterStateFile, pending: map[string]targetCounterState{}}
+
+	state, err := loadCounterStateFile(e.options.CounterStateFile)
+	if err != nil {
+		return fmt.Errorf("couldn't load counter state file %s: %s", e.options.CounterStateFile, err)
+	}
+	if state == nil {
+		return nil
+	}
+
+	e.totalScrapes.Add(state.Scrapes)
+	e.targetScrapeRequestErrors.Add(state.ScrapeRequestErrors)
+	for target, s := range state.Targets {
+		e.counterState.pending[target] = s
+	}
+	log.Infof("Loaded the counters of %d targets saved at %s from %s", len(state.Targets), state.SavedAt.Format(time.RFC3339), e.options.CounterStateFile)
+	return nil
+}
+
+// restoreCounterState restores the saved counters of the target of the
+// exporter if it's the first scrape of the target since the exporter started
+func (e *Exporter) restoreCounterState() {
+	if e.counterState == nil {
+		return
+	}
+	key := redactedAddr(e.redisAddr)
+	e.counterState.Lock()
+	s, ok := e.counterState.pending[key]
+	delete(e.counterState.pending, key)
+	e.counterState.Unlock()
+	if !ok {
+		return
+	}
+
+	if s.Role != "" {
+		e.roleChanges.Lock()
+		rs := &roleState{role: s.Role, changes: s.RoleChanges}
+		if s.RoleLastChange != nil {
+			rs.lastChange = *s.RoleLastChange
+		}
+		e.roleChanges.targets[e.redisAddr] = rs
+		e.roleChanges.Unlock()
+	}
+
+	if len(s.EventStates) > 0 || len(s.EventCounts) > 0 {
+		e.events.Lock()
+		e.events.states[e.redisAddr] = map[string]string{}
+		e.events.counts[e.redisAddr] = map[string]int64{}
+		for t, v := range s.EventStates {
+			e.events.states[e.redisAddr][t] = v
+		}
+		for t, v := range s.EventCounts {
+			e.events.counts[e.redisAddr][t] = v
+		}
+		e.events.Unlock()
+	}
+
+	if len(s.ClusterRedirects) > 0 || s.ClusterRedirectsExhausted > 0 {
+		e.clusterRedirects.Lock()
+		e.clusterRedirects.counts[e.redisAddr] = map[string]int64{}
+		for t, v := range s.ClusterRedirects {
+			e.clusterRedirects.counts[e.redisAddr][t] = v
+		}
+		e.clusterRedirects.exhausted[e.redisAddr] = s.ClusterRedirectsExhausted
+		e.clusterRedirects.Unlock()
+	}
+	e.logger().Debugf("restored the saved counters of %s", key)
+}
+
+func counterValue(c prometheus.Counter) float64 {
+	m := &dto.Metric{}
+	if err := c.Write(m); err != nil {
+		return 0
+	}
+	return m.GetCounter().GetValue()
+}
+
+// counterStateSnapshot returns the current counters and those of the targets not scraped yet
+func (e *Exporter) counterStateSnapshot(now time.Time) counterStateFile {
+	state := counterStateFile{
+		SavedAt:             now,
+		Scrapes:             counterValue(e.totalScrapes),
+		ScrapeRequestErrors: counterValue(e.targetScrapeRequestErrors),
+		Targets:             map[string]targetCounterState{},
+	}
+	target := func(addr string) targetCounterState {
+		return state.Targets[redactedAddr(addr)]
+	}
+
+	e.roleChanges.Lock()
+	for addr, rs := range e.roleChanges.targets {
+		s := target(addr)
+		s.Role, s.RoleChanges = rs.role, rs.changes
+		if !rs.lastChange.IsZero() {
+			t := rs.lastChange
+			s.RoleLastChange = &t
+		}
+		state.Targets[redactedAddr(addr)] = s
+	}
+	e.roleChanges.Unlock()
+
+	e.events.Lock()
+	for addr, states := range e.events.states {
+		s := target(addr)
+		s.EventStates = map[string]string{}
+		s.EventCounts = map[string]int64{}
+		for t, v := range states {
+			s.EventStates[t] = v
+		}
+		for t, v := range e.events.counts[addr] {
+			s.EventCounts[t] = v
+		}
+		state.Targets[redactedAddr(addr)] = s
+	}
+	e.events.Unlock()
+
+	e.clusterRedirects.Lock()
+	for addr, counts := range e.clusterRedirects.counts {
+		s := target(addr)
+		s.ClusterRedirects = map[string]int64{}
+		for t, v := range counts {
+			s.ClusterRedirects[t] = v
+		}
+		state.Targets[redactedAddr(addr)] = s
+	}
+	for addr, n := range e.clusterRedirects.exhausted {
+		s := target(addr)
+		s.ClusterRedirectsExhausted = n
+		state.Targets[redactedAddr(addr)] = s
+	}
+	e.clusterRedirects.Unlock()
+
+	e.counterState.Lock()
+	for key, s := range e.counterState.pending {
+		state.Targets[key] = s
+	}
+	e.counterState.Unlock()
+
+	return state
+}
+
+// SaveCounterState writes the exporter side counters to CounterStateFile
+func (e *Exporter) SaveCounterState() error {
+	if e.counterState == nil {
+		return nil
+	}
+
+	bytes, err := json.MarshalIndent(e.counterStateSnapshot(time.Now()), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// written to a temporary file that replaces the state file so a crash never leaves a partial file behind
+	tmp, err := os.CreateTemp(filepath.Dir(e.counterState.file), filepath.Base(e.counterState.file)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(bytes, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), e.counterState.file)
+}
+
+// StartCounterStatePersistence saves the counters every counterStateSaveInterval until ctx is done
+func (e *Exporter) StartCounterStatePersistence(ctx context.Context) {
+	if e.counterState == nil {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(counterStateSaveInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := e.SaveCounterState(); err != nil {
+					e.logger().Errorf("Couldn't save the counters to %s, err: %s", e.counterState.file, err)
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,72 @@
+package exporter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCounterState(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "counters.json")
+	addr := "redis://:secret@localhost:6379"
+	opts := Options{Namespace: "test", Events: true, CounterStateFile: file}
+
+	e, err := NewRedisExporter(addr, opts)
+	if err != nil {
+		t.Fatalf("NewRedisExporter() err: %s", err)
+	}
+	now := time.Unix(1700000000, 0)
+	e.roleChanges.observe(addr, "master", now)
+	e.roleChanges.observe(addr, "slave", now)
+	e.events.observe(addr, "master_link", "up", now)
+	e.events.observe(addr, "master_link", "down", now)
+	e.clusterRedirects.add(addr, "ASK")
+	e.totalScrapes.Add(5)
+	e.targetScrapeRequestErrors.Inc()
+
+	if err := e.SaveCounterState(); err != nil {
+		t.Fatalf("SaveCounterState() err: %s", err)
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("ReadFile() err: %s", err)
+	}
+	if strings.Contains(string(content), "secret") {
+		t.Errorf("expected no credentials in the state file, got: %s", content)
+	}
+
+	restarted, err := NewRedisExporter(addr, opts)
+	if err != nil {
+		t.Fatalf("NewRedisExporter() err: %s", err)
+	}
+	if got := counterValue(restarted.totalScrapes); got != 5 {
+		t.Errorf("expected exporter_scrapes_total to continue from 5, got: %f", got)
+	}
+	if got := counterValue(restarted.targetScrapeRequestErrors); got != 1 {
+		t.Errorf("expected target_scrape_request_errors_total to continue from 1, got: %f", got)
+	}
+
+	// the state of targets that weren't scraped yet is kept when saving
+	if err := restarted.SaveCounterState(); err != nil {
+		t.Fatalf("SaveCounterState() err: %s", err)
+	}
+	if saved, _ := os.ReadFile(file); !strings.Contains(string(saved), `"role_changes": 1`) {
+		t.Errorf("expected the pending state to be saved again, got: %s", saved)
+	}
+
+	restarted.restoreCounterState()
+	if s := restarted.roleChanges.observe(addr, "slave", now.Add(time.Minute)); s.changes != 1 || !s.lastChange.Equal(now) {
+		t.Errorf("expected 1 role change at %s, got: %+v", now, s)
+	}
+	if n := restarted.events.count(addr, "master_link"); n != 1 {
+		t.Errorf("expected 1 master_link event, got: %d", n)
+	}
+	if _, ok := restarted.events.observe(addr, "master_link", "down", now); ok {
+		t.Errorf("expected the restored master_link state, got a change")
+	}
+	if counts, _ := restarted.clusterRedirects.get(addr); counts["ask"] != 1 {
+		t.Errorf("expected 1 ASK redirect, got: %v", counts)
+	}
+}
@@ -0,0 +1,93 @@
+package exporter
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// forkDurationBuckets are the upper bounds of fork_duration_seconds, forks of large
+// instances take from a few milliseconds to seconds
+var forkDurationBuckets = []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5}
+
+// cpuForkSample are the fork fields of an INFO reply
+type cpuForkSample struct {
+	totalForks float64
+	forksKnown bool
+}
+
+// forkHistogram are the durations of the forks seen by the exporter
+type forkHistogram struct {
+	count   uint64
+	sum     float64
+	buckets map[float64]uint64
+}
+
+func (h *forkHistogram) observe(seconds float64) {
+	h.count++
+	h.sum += seconds
+	for _, b := range forkDurationBuckets {
+		if seconds <= b {
+			h.buckets[b]++
+		}
+	}
+}
+
+// cpuForkStats keeps the last sample and the fork durations per target
+type cpuForkStats struct {
+	sync.Mutex
+	last  map[string]cpuForkSample
+	forks map[string]*forkHistogram
+}
+
+func newCPUForkStats() *cpuForkStats {
+	return &cpuForkStats{last: map[string]cpuForkSample{}, forks: map[string]*forkHistogram{}}
+}
+
+// record stores cur as the last sample of target, observes latestForkUsec if total_forks went
+// up since the previous sample and returns the fork histogram
+func (s *cpuForkStats) record(target string, cur cpuForkSample, latestForkUsec float64, latestForkKnown bool) forkHistogram {
+	s.Lock()
+	defer s.Unlock()
+
+	prev, hasPrev := s.last[target]
+	s.last[target] = cur
+
+	h, ok := s.forks[target]
+	if !ok {
+		h = &forkHistogram{buckets: make(map[float64]uint64, len(forkDurationBuckets))}
+		for _, b := range forkDurationBuckets {
+			h.buckets[b] = 0
+		}
+		s.forks[target] = h
+	}
+	// only the latest of several forks between two scrapes is known
+	if hasPrev && prev.forksKnown && cur.forksKnown && cur.totalForks > prev.totalForks && latestForkKnown {
+		h.observe(latestForkUsec / 1e6)
+	}
+
+	res := *h
+	res.buckets = make(map[float64]uint64, len(h.buckets))
+	for b, c := range h.buckets {
+		res.buckets[b] = c
+	}
+	return res
+}
+
+// extractCPUForkMetrics exports the durations of the forks seen since the exporter started,
+// the CPU usage is exported by INFO as counters like cpu_sys_children_seconds_total
+func (e *Exporter) extractCPUForkMetrics(ch chan<- prometheus.Metric, fields *infoFieldTable) {
+	if !e.options.InclCPUForkMetrics {
+		return
+	}
+
+	var cur cpuForkSample
+	cur.totalForks, cur.forksKnown = fields.float("total_forks")
+	latestForkUsec, latestForkKnown := fields.float("latest_fork_usec")
+
+	forks := e.cpuFork.record(e.redisAddr, cur, latestForkUsec, latestForkKnown)
+
+	if cur.forksKnown {
+		e.registerConstHistogram(ch, "fork_duration_seconds", forks.count, forks.sum, forks.buckets)
+	}
+}
@@ -0,0 +1,73 @@
+package exporter
+
+import (
+	"math"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestCPUForkMetrics(t *testing.T) {
+	e, _ := NewRedisExporter("redis://localhost:6379", Options{Namespace: "test", InclCPUForkMetrics: true})
+
+	type scrape struct {
+		fields    map[string]string
+		wantCount uint64
+		wantSum   float64
+	}
+	for i, s := range []scrape{
+		{
+			fields:    map[string]string{"used_cpu_sys": "10", "total_forks": "3", "latest_fork_usec": "1500"},
+			wantCount: 0,
+		},
+		{
+			// a fork of 30ms since the last scrape
+			fields:    map[string]string{"used_cpu_sys": "11", "total_forks": "4", "latest_fork_usec": "30000"},
+			wantCount: 1,
+			wantSum:   0.03,
+		},
+		{
+			// no new fork
+			fields:    map[string]string{"used_cpu_sys": "12", "total_forks": "4", "latest_fork_usec": "30000"},
+			wantCount: 1,
+			wantSum:   0.03,
+		},
+	} {
+		var fields infoFieldTable
+		for k, v := range s.fields {
+			fields.set(k, v)
+		}
+
+		chM := make(chan prometheus.Metric)
+		go func() {
+			e.extractCPUForkMetrics(chM, &fields)
+			close(chM)
+		}()
+
+		var hist *dto.Histogram
+		for m := range chM {
+			d := &dto.Metric{}
+			m.Write(d)
+			if d.GetHistogram() == nil {
+				t.Errorf("scrape %d: unexpected metric %s", i, m.Desc())
+				continue
+			}
+			hist = d.GetHistogram()
+		}
+
+		if hist == nil {
+			t.Fatalf("scrape %d: expected fork_duration_seconds", i)
+		}
+		if hist.GetSampleCount() != s.wantCount || math.Abs(hist.GetSampleSum()-s.wantSum) > 1e-9 {
+			t.Errorf("scrape %d: expected %d forks with a sum of %f, got: %d %f", i, s.wantCount, s.wantSum, hist.GetSampleCount(), hist.GetSampleSum())
+		}
+		for _, b := range hist.GetBucket() {
+			if want := uint64(0); b.GetUpperBound() < 0.03 && b.GetCumulativeCount() != want {
+				t.Errorf("scrape %d: expected no forks up to %f, got: %d", i, b.GetUpperBound(), b.GetCumulativeCount())
+			} else if b.GetUpperBound() >= 0.05 && b.GetCumulativeCount() != s.wantCount {
+				t.Errorf("scrape %d: expected %d forks up to %f, got: %d", i, s.wantCount, b.GetUpperBound(), b.GetCumulativeCount())
+			}
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package exporter
+
+import (
+	"slices"
+	"strings"
+)
+
+// dbLabelFormats are the values of DbLabelFormat: db="db0", db="0" or db="db0" with db_number="0"
+var dbLabelFormats = []string{"prefixed", "numeric", "both"}
+
+// recordDbLabel remembers the position of the db label of metricName so registerConstMetric can
+// rewrite it for DbLabelFormat and returns the labels of the metric, with "both" db_number is added
+func (e *Exporter) recordDbLabel(metricName string, labels []string) []string {
+	if e.options.DbLabelFormat == "" || e.options.DbLabelFormat == "prefixed" {
+		return labels
+	}
+	idx := slices.Index(labels, "db")
+	if idx < 0 {
+		return labels
+	}
+	e.dbLabelIndexes[metricName] = idx
+	if e.options.DbLabelFormat == "both" {
+		labels = append(slices.Clip(labels), "db_number")
+	}
+	return labels
+}
+
+// dbLabelValues rewrites the db label of metric in labelValues, "db0" and "0" alike, for DbLabelFormat
+func (e *Exporter) dbLabelValues(metric string, labelValues []string) []string {
+	idx, ok := e.dbLabelIndexes[metric]
+	if !ok || idx >= len(labelValues) {
+		return labelValues
+	}
+
+	num := strings.TrimPrefix(labelValues[idx], "db")
+	res := slices.Clone(labelValues)
+	switch e.options.DbLabelFormat {
+	case "numeric":
+		res[idx] = num
+	case "both":
+		res[idx] = "db" + num
+		res = append(res, num)
+	}
+	return res
+}
@@ -0,0 +1,68 @@
+package exporter
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestDbLabelFormat(t *testing.T) {
+	for _, tst := range []struct {
+		format     string
+		wantKeys   map[string]string
+		wantClient map[string]string
+	}{
+		{format: "", wantKeys: map[string]string{"db": "db3"}, wantClient: map[string]string{"db": "3"}},
+		{format: "prefixed", wantKeys: map[string]string{"db": "db3"}, wantClient: map[string]string{"db": "3"}},
+		{format: "numeric", wantKeys: map[string]string{"db": "3"}, wantClient: map[string]string{"db": "3"}},
+		{format: "both", wantKeys: map[string]string{"db": "db3", "db_number": "3"}, wantClient: map[string]string{"db": "db3", "db_number": "3"}},
+	} {
+		t.Run(tst.format, func(t *testing.T) {
+			e, err := NewRedisExporter("redis://localhost:6379", Options{Namespace: "test", DbLabelFormat: tst.format})
+			if err != nil {
+				t.Fatalf("NewRedisExporter() err: %s", err)
+			}
+
+			chM := make(chan prometheus.Metric, 10)
+			e.registerConstMetricGauge(chM, "db_keys", 5, "db3")
+			e.createMetricDescription("connected_client_info", []string{"id", "db"})
+			e.registerConstMetricGauge(chM, "connected_client_info", 1, "7", "3")
+			e.registerConstMetricGauge(chM, "uptime_in_seconds", 10)
+			close(chM)
+
+			var got []map[string]string
+			for m := range chM {
+				d := &dto.Metric{}
+				if err := m.Write(d); err != nil {
+					t.Fatalf("Write() err: %s", err)
+				}
+				lbls := map[string]string{}
+				for _, l := range d.GetLabel() {
+					if l.GetName() == "db" || l.GetName() == "db_number" {
+						lbls[l.GetName()] = l.GetValue()
+					}
+				}
+				got = append(got, lbls)
+			}
+
+			if len(got) != 3 {
+				t.Fatalf("expected 3 metrics, got: %v", got)
+			}
+			for i, want := range []map[string]string{tst.wantKeys, tst.wantClient, {}} {
+				if len(got[i]) != len(want) {
+					t.Errorf("metric %d: expected %v, got: %v", i, want, got[i])
+				}
+				for k, v := range want {
+					if got[i][k] != v {
+						t.Errorf("metric %d: expected %s=%q, got: %v", i, k, v, got[i])
+					}
+				}
+			}
+		})
+	}
+
+	if _, err := NewRedisExporter("", Options{DbLabelFormat: "index"}); err == nil {
+		t.Errorf("expected an error for an unknown db-label-format")
+	}
+}
@@ -0,0 +1,106 @@
+package exporter
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// parseDbsizeFallbackDbs parses the comma separated database numbers of DbsizeFallbackDbs
+func parseDbsizeFallbackDbs(s string) ([]int, error) {
+	var dbs []int
+	for _, db := range strings.Split(s, ",") {
+		if db = strings.TrimPrefix(strings.TrimSpace(db), "db"); db == "" {
+			continue
+		}
+		n, err := strconv.Atoi(db)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid database %q", db)
+		}
+		dbs = append(dbs, n)
+	}
+	return dbs, nil
+}
+
+// addrDB returns the database selected by the path of a redis:// address, e.g. 3 for
+// redis://localhost:6379/3, and 0 if there's none, as DialURL() does
+func addrDB(addr string) int {
+	if isUnixSocketAddr(addr) || !strings.Contains(addr, "://") {
+		return 0
+	}
+	u, err := url.Parse(addr)
+	if err != nil {
+		return 0
+	}
+	db, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(u.Path, "/")))
+	if err != nil {
+		return 0
+	}
+	return db
+}
+
+// hasInfoSection returns true if info has the section, even if it has no fields
+// like the Keyspace section of an empty server
+func hasInfoSection(info string, section string) bool {
+	for line := range strings.Lines(info) {
+		if strings.TrimSpace(line) == "# "+section {
+			return true
+		}
+	}
+	return false
+}
+
+// useDbsizeFallback returns true if the keys of the databases of DbsizeFallbackDbs
+// are counted with DBSIZE as info has no Keyspace section, e.g. because of a proxy or a
+// provider that restricts INFO. Empty databases aren't padded with 0 then
+func (e *Exporter) useDbsizeFallback(info string) bool {
+	return len(e.dbsizeFallbackDbs) > 0 && !hasInfoSection(info, "Keyspace")
+}
+
+// extractDbsizeFallbackMetrics exports db_keys of the databases of DbsizeFallbackDbs with
+// DBSIZE if info has no Keyspace section, the expiring keys and the TTLs aren't known
+func (e *Exporter) extractDbsizeFallbackMetrics(ch chan<- prometheus.Metric, c redis.Conn, info string) {
+	if len(e.dbsizeFallbackDbs) == 0 {
+		return
+	}
+	if !e.useDbsizeFallback(info) {
+		e.registerConstMetricGauge(ch, "exporter_dbsize_fallback", 0)
+		return
+	}
+	e.startCollector("dbsize_fallback")
+	e.registerConstMetricGauge(ch, "exporter_dbsize_fallback", 1)
+
+	dbs := e.dbsizeFallbackDbs
+	if e.options.IsCluster {
+		// cluster nodes only have db0
+		dbs = []int{0}
+	} else {
+		// the collectors that follow expect the database of the connection
+		db := addrDB(e.redisAddr)
+		defer func() {
+			if _, err := doRedisCmd(c, "SELECT", db); err != nil {
+				e.logger().Errorf("Couldn't select database %d after DBSIZE, err: %s", db, err)
+			}
+		}()
+	}
+	for _, db := range dbs {
+		if !e.options.IsCluster {
+			if _, err := doRedisCmd(c, "SELECT", db); err != nil {
+				e.logger().Errorf("Couldn't select database %d for DBSIZE, err: %s", db, err)
+				continue
+			}
+		}
+		keys, err := redis.Int64(doRedisCmd(c, "DBSIZE"))
+		if err != nil {
+			e.logger().Errorf("DBSIZE of database %d err: %s", db, err)
+			continue
+		}
+		dbName := "db" + strconv.Itoa(db)
+		e.registerConstMetricGauge(ch, "db_keys", float64(keys), dbName)
+		e.registerGrowth(ch, "db_keys_growth_per_hour", dbName, float64(keys), dbName)
+	}
+}
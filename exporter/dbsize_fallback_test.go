@@ -0,0 +1,127 @@
+package exporter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/oliver006/redis_exporter/exporter/exportertest"
+)
+
+func TestParseDbsizeFallbackDbs(t *testing.T) {
+	if dbs, err := parseDbsizeFallbackDbs(" 0, db3,,12"); err != nil || len(dbs) != 3 || dbs[0] != 0 || dbs[1] != 3 || dbs[2] != 12 {
+		t.Errorf("unexpected result: %v %v", dbs, err)
+	}
+	for _, s := range []string{"x", "-1", "1,two"} {
+		if _, err := parseDbsizeFallbackDbs(s); err == nil {
+			t.Errorf("expected an error for %q", s)
+		}
+	}
+	if _, err := NewRedisExporter("", Options{Namespace: "test", DbsizeFallbackDbs: "a"}); err == nil {
+		t.Errorf("expected NewRedisExporter() to fail for an invalid database")
+	}
+}
+
+func TestDbsizeFallback(t *testing.T) {
+	restricted := "# Server\r\nredis_version:7.2.4\r\n\r\n# Replication\r\nrole:master\r\n"
+
+	for _, tst := range []struct {
+		name         string
+		info         string
+		dbs          string
+		wantFallback float64
+		wantKeys     map[string]float64
+	}{
+		{name: "restricted info", info: restricted, dbs: "0,1", wantFallback: 1, wantKeys: map[string]float64{"db0": 42, "db1": 42}},
+		{name: "keyspace section", info: exportertest.DefaultInfo + "db0:keys=7,expires=0,avg_ttl=0\r\n", dbs: "0,1", wantFallback: 0, wantKeys: map[string]float64{"db0": 7, "db1": 0}},
+		{name: "empty keyspace section", info: exportertest.DefaultInfo, dbs: "0", wantFallback: 0, wantKeys: map[string]float64{"db0": 0, "db1": 0}},
+		{name: "disabled", info: restricted, wantFallback: -1, wantKeys: map[string]float64{"db0": 0, "db1": 0}},
+	} {
+		t.Run(tst.name, func(t *testing.T) {
+			s := exportertest.NewServer(t)
+			s.SetInfo(tst.info)
+			s.Set("DBSIZE", int64(42))
+			s.Set("CONFIG GET", []interface{}{"databases", "2"})
+
+			e, _ := NewRedisExporter(s.Addr(), Options{Namespace: "test", DbsizeFallbackDbs: tst.dbs, InclMetricsForEmptyDatabases: true})
+			chM := make(chan prometheus.Metric)
+			go func() {
+				e.Collect(chM)
+				close(chM)
+			}()
+
+			fallback := float64(-1)
+			keys := map[string]float64{}
+			for m := range chM {
+				desc := m.Desc().String()
+				d := &dto.Metric{}
+				m.Write(d)
+				switch {
+				case strings.Contains(desc, `"test_exporter_dbsize_fallback"`):
+					fallback = d.GetGauge().GetValue()
+				case strings.Contains(desc, `"test_db_keys"`):
+					for _, l := range d.GetLabel() {
+						if l.GetName() == "db" {
+							if _, dup := keys[l.GetValue()]; dup {
+								t.Errorf("duplicate db_keys of %s", l.GetValue())
+							}
+							keys[l.GetValue()] = d.GetGauge().GetValue()
+						}
+					}
+				}
+			}
+
+			if fallback != tst.wantFallback {
+				t.Errorf("expected exporter_dbsize_fallback %.0f, got: %.0f", tst.wantFallback, fallback)
+			}
+			if len(keys) != len(tst.wantKeys) {
+				t.Errorf("expected db_keys %v, got: %v", tst.wantKeys, keys)
+			}
+			for db, want := range tst.wantKeys {
+				if got, ok := keys[db]; !ok || got != want {
+					t.Errorf("expected db_keys of %s to be %.0f, got: %.0f (found: %t)", db, want, got, ok)
+				}
+			}
+
+			dbsize := strings.Contains(strings.Join(s.Commands(), "\n"), "DBSIZE")
+			if dbsize != (tst.wantFallback == 1) {
+				t.Errorf("expected DBSIZE to be sent only for the fallback, commands: %v", s.Commands())
+			}
+		})
+	}
+}
+
+func TestDbsizeFallbackRestoresDB(t *testing.T) {
+	s := exportertest.NewServer(t)
+	s.SetInfo("# Server\r\nredis_version:7.2.4\r\n")
+	s.Set("DBSIZE", int64(1))
+
+	e, _ := NewRedisExporter(s.Addr()+"/3", Options{Namespace: "test", DbsizeFallbackDbs: "0,1"})
+	chM := make(chan prometheus.Metric)
+	go func() {
+		e.Collect(chM)
+		close(chM)
+	}()
+	for range chM {
+	}
+
+	var selects []string
+	for _, cmd := range s.Commands() {
+		if strings.HasPrefix(cmd, "SELECT") || cmd == "DBSIZE" {
+			selects = append(selects, cmd)
+		}
+	}
+	// the first SELECT 0 is the SELECT-free probe
+	want := "SELECT 3,SELECT 0,SELECT 0,DBSIZE,SELECT 1,DBSIZE,SELECT 3"
+	if got := strings.Join(selects, ","); got != want {
+		t.Errorf("expected the database of the address to be selected again, want: %s got: %s", want, got)
+	}
+
+	for addr, want := range map[string]int{"redis://localhost:6379/3": 3, "redis://localhost:6379": 0, "localhost:6379": 0, "unix:///tmp/redis.sock": 0} {
+		if got := addrDB(addr); got != want {
+			t.Errorf("addrDB(%s): expected %d, got: %d", addr, want, got)
+		}
+	}
+}
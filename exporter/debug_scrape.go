@@ -0,0 +1,98 @@
+package exporter
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+	log "github.com/sirupsen/logrus"
+)
+
+// maxDebugScrapeWarningHeaders limits the number of warning headers of a debug scrape,
+// all warnings are appended as comments to the exposition
+const maxDebugScrapeWarningHeaders = 20
+
+// scrapeWarnings is a logrus hook that collects the warnings and errors logged during a scrape
+type scrapeWarnings struct {
+	sync.Mutex
+	lines []string
+}
+
+func (s *scrapeWarnings) Levels() []log.Level {
+	return []log.Level{log.PanicLevel, log.FatalLevel, log.ErrorLevel, log.WarnLevel}
+}
+
+func (s *scrapeWarnings) Fire(entry *log.Entry) error {
+	line := entry.Level.String() + ": " + entry.Message
+	if collector, ok := entry.Data["collector"]; ok {
+		line = fmt.Sprintf("%s: collector %v: %s", entry.Level, collector, entry.Message)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	// header values and exposition comments are single lines
+	s.lines = append(s.lines, strings.Join(strings.Fields(line), " "))
+	return nil
+}
+
+func (s *scrapeWarnings) all() []string {
+	s.Lock()
+	defer s.Unlock()
+	return append([]string(nil), s.lines...)
+}
+
+// newDebugLogger returns a logger with the output and format of the standard logger
+// that logs at debug level and passes the warnings to warnings
+func newDebugLogger(warnings *scrapeWarnings) *log.Logger {
+	std := log.StandardLogger()
+	l := log.New()
+	l.SetOutput(std.Out)
+	l.SetFormatter(std.Formatter)
+	l.SetLevel(log.DebugLevel)
+	l.AddHook(warnings)
+	return l
+}
+
+// debugScrapeHandler scrapes a single target with debug logging and returns the
+// warnings of the scrape as X-Redis-Exporter-Warning headers and as comments
+// after the metrics
+func (e *Exporter) debugScrapeHandler(w http.ResponseWriter, r *http.Request, target string, opts Options) {
+	exp, err := e.newTargetExporter(target, opts)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("NewRedisExporter() error: %v", err), http.StatusBadRequest)
+		e.targetScrapeRequestErrors.Inc()
+		return
+	}
+
+	warnings := &scrapeWarnings{}
+	exp.debugLogger = newDebugLogger(warnings)
+	exp.logger().Infof("Debug scrape requested by %s", r.RemoteAddr)
+
+	mfs, gatherErr := exp.options.Registry.Gather()
+
+	lines := warnings.all()
+	w.Header().Set("X-Redis-Exporter-Warning-Count", fmt.Sprint(len(lines)))
+	for i, line := range lines {
+		if i == maxDebugScrapeWarningHeaders {
+			break
+		}
+		w.Header().Add("X-Redis-Exporter-Warning", line)
+	}
+
+	// comments can only be appended to the uncompressed text format
+	r.Header.Del("Accept")
+	r.Header.Del("Accept-Encoding")
+	promhttp.HandlerFor(
+		prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) { return mfs, gatherErr }),
+		promhttp.HandlerOpts{ErrorHandling: promhttp.ContinueOnError},
+	).ServeHTTP(w, r)
+
+	for _, line := range lines {
+		_, _ = io.WriteString(w, "# "+line+"\n")
+	}
+}
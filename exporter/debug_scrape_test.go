@@ -0,0 +1,69 @@
+package exporter
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestDebugScrape(t *testing.T) {
+	for _, tst := range []struct {
+		name       string
+		allow      bool
+		wantStatus int
+	}{
+		{name: "disabled", allow: false, wantStatus: http.StatusForbidden},
+		{name: "enabled", allow: true, wantStatus: http.StatusOK},
+	} {
+		t.Run(tst.name, func(t *testing.T) {
+			e, _ := NewRedisExporter("", Options{Namespace: "test", AllowDebugScrape: tst.allow})
+
+			// nothing listens on port 1, the scrape logs the failed connection
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/scrape?target=redis://127.0.0.1:1&debug=true", nil)
+			r.Header.Set("Accept-Encoding", "gzip")
+			e.ServeHTTP(w, r)
+
+			if w.Code != tst.wantStatus {
+				t.Fatalf("expected status %d, got: %d", tst.wantStatus, w.Code)
+			}
+			if !tst.allow {
+				return
+			}
+
+			warnings := w.Header().Values("X-Redis-Exporter-Warning")
+			if len(warnings) == 0 || !strings.Contains(warnings[0], "collector connection: Couldn't connect") {
+				t.Errorf("expected the connection error as warning header, got: %v", warnings)
+			}
+			if w.Header().Get("X-Redis-Exporter-Warning-Count") != "1" {
+				t.Errorf("unexpected warning count: %s", w.Header().Get("X-Redis-Exporter-Warning-Count"))
+			}
+
+			body, _ := io.ReadAll(w.Body)
+			if !strings.Contains(string(body), "test_up 0") {
+				t.Errorf("expected the metrics of the target, got: %s", body)
+			}
+			if !strings.HasSuffix(string(body), "# "+warnings[0]+"\n") {
+				t.Errorf("expected the warnings as comments after the metrics, got: %s", body)
+			}
+		})
+	}
+}
+
+func TestDebugScrapeLogLevel(t *testing.T) {
+	warnings := &scrapeWarnings{}
+	l := newDebugLogger(warnings)
+	if l.GetLevel() != log.DebugLevel {
+		t.Errorf("expected debug level, got: %s", l.GetLevel())
+	}
+
+	l.WithField("collector", "keys").Warnf("multi\nline")
+	l.Debugf("not a warning")
+	if got := warnings.all(); len(got) != 1 || got[0] != "warning: collector keys: multi line" {
+		t.Errorf("unexpected warnings: %#v", got)
+	}
+}
@@ -0,0 +1,72 @@
+package exporter
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// extractDerivedMetrics computes metrics that INFO doesn't provide directly
+// from the fields of a single INFO reply, all of them are exported as redis_derived_*
+func (e *Exporter) extractDerivedMetrics(ch chan<- prometheus.Metric, keyValues map[string]string) {
+	field := func(name string) (float64, bool) {
+		s, ok := keyValues[name]
+		if !ok {
+			return 0, false
+		}
+		val, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			e.logger().Debugf("couldn't parse INFO field %s for derived metrics: %s", name, err)
+			return 0, false
+		}
+		return val, true
+	}
+
+	if hits, ok := field("keyspace_hits"); ok {
+		if misses, ok := field("keyspace_misses"); ok && hits+misses > 0 {
+			e.registerConstMetricGauge(ch, "derived_keyspace_hit_ratio", hits/(hits+misses))
+		}
+	}
+
+	// Lua (and functions) memory is part of the RSS but not of used_memory,
+	// subtracting it keeps scripts from showing up as fragmentation
+	if rss, ok := field("used_memory_rss"); ok {
+		if used, ok := field("used_memory"); ok && used > 0 {
+			lua, ok := field("used_memory_vm_total")
+			if !ok {
+				lua, _ = field("used_memory_lua")
+			}
+			e.registerConstMetricGauge(ch, "derived_mem_fragmentation_ratio", (rss-lua)/used)
+		}
+	}
+
+	if maxMemory, ok := field("maxmemory"); ok && maxMemory > 0 {
+		if used, ok := field("used_memory"); ok {
+			e.registerConstMetricGauge(ch, "derived_memory_headroom_ratio", (maxMemory-used)/maxMemory)
+		}
+	}
+
+	// replication lag in seconds estimated from the offset difference and the
+	// current replication output rate of the master
+	masterOffset, ok := field("master_repl_offset")
+	if !ok {
+		return
+	}
+	outputKbps, _ := field("instantaneous_output_repl_kbps")
+	for fieldKey, fieldValue := range keyValues {
+		slaveOffset, slaveIP, slavePort, slaveState, _, ok := parseConnectedSlaveString(fieldKey, fieldValue)
+		if !ok {
+			continue
+		}
+
+		behindBytes := masterOffset - slaveOffset
+		if behindBytes <= 0 {
+			e.registerConstMetricGauge(ch, "derived_replication_lag_seconds", 0, slaveIP, slavePort, slaveState)
+			continue
+		}
+		if outputKbps <= 0 {
+			continue
+		}
+		e.registerConstMetricGauge(ch, "derived_replication_lag_seconds", behindBytes/(outputKbps*1024), slaveIP, slavePort, slaveState)
+	}
+}
@@ -0,0 +1,78 @@
+package exporter
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestDerivedMetrics(t *testing.T) {
+	infoStr := `# Memory
+used_memory:600
+used_memory_rss:1100
+used_memory_vm_total:100
+maxmemory:1000
+
+# Stats
+keyspace_hits:75
+keyspace_misses:25
+instantaneous_output_repl_kbps:2.00
+
+# Replication
+role:master
+connected_slaves:2
+slave0:ip=10.0.0.1,port=6379,state=online,offset=10240,lag=0
+slave1:ip=10.0.0.2,port=6379,state=online,offset=6144,lag=1
+master_repl_offset:10240
+`
+
+	for _, incl := range []bool{true, false} {
+		e, _ := NewRedisExporter("", Options{Namespace: "test", InclDerivedMetrics: incl})
+
+		chM := make(chan prometheus.Metric)
+		go func() {
+			e.extractInfoMetrics(chM, infoStr, 0)
+			close(chM)
+		}()
+
+		got := map[string]float64{}
+		for m := range chM {
+			desc := m.Desc().String()
+			if !strings.Contains(desc, "test_derived_") {
+				continue
+			}
+			d := &dto.Metric{}
+			m.Write(d)
+			name := desc[strings.Index(desc, "test_derived_"):]
+			name = name[:strings.Index(name, `"`)]
+			for _, l := range d.GetLabel() {
+				if l.GetName() == "slave_ip" {
+					name += "/" + l.GetValue()
+				}
+			}
+			got[name] = d.GetGauge().GetValue()
+		}
+
+		if !incl {
+			if len(got) != 0 {
+				t.Errorf("expected no derived metrics, got: %#v", got)
+			}
+			continue
+		}
+
+		for name, want := range map[string]float64{
+			"test_derived_keyspace_hit_ratio":               0.75,
+			"test_derived_mem_fragmentation_ratio":          1.6666,
+			"test_derived_memory_headroom_ratio":            0.4,
+			"test_derived_replication_lag_seconds/10.0.0.1": 0,
+			"test_derived_replication_lag_seconds/10.0.0.2": 2,
+		} {
+			if val, ok := got[name]; !ok || math.Abs(val-want) > 0.001 {
+				t.Errorf("%s: expected %f, got: %f (found: %t)", name, want, val, ok)
+			}
+		}
+	}
+}
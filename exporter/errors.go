@@ -0,0 +1,184 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/mna/redisc"
+)
+
+// AuthError is returned when the server rejects the credentials of the exporter
+// or requires authentication, e.g. NOAUTH and WRONGPASS replies
+type AuthError struct {
+	Err error
+}
+
+func (e *AuthError) Error() string { return e.Err.Error() }
+func (e *AuthError) Unwrap() error { return e.Err }
+
+// PermissionError is returned for NOPERM replies, the ACL user of the exporter isn't
+// allowed to run the command or to access its keys or channels
+type PermissionError struct {
+	// Command is the command and, for commands with subcommands, the subcommand
+	Command string
+	Err     error
+}
+
+func (e *PermissionError) Error() string { return e.Err.Error() }
+func (e *PermissionError) Unwrap() error { return e.Err }
+
+// TimeoutError is returned when connecting to the server or running a command timed out
+type TimeoutError struct {
+	// Command is empty if connecting timed out
+	Command string
+	Err     error
+}
+
+func (e *TimeoutError) Error() string { return e.Err.Error() }
+func (e *TimeoutError) Unwrap() error { return e.Err }
+
+// UnsupportedCommandError is returned for commands the server doesn't know, e.g. those
+// of newer Redis versions, of modules that aren't loaded or renamed commands
+type UnsupportedCommandError struct {
+	// Command is the command and, for "unknown subcommand" errors, the subcommand
+	Command string
+	Err     error
+}
+
+func (e *UnsupportedCommandError) Error() string { return e.Err.Error() }
+func (e *UnsupportedCommandError) Unwrap() error { return e.Err }
+
+// ClusterRedirectError is returned for MOVED and ASK replies that weren't followed,
+// either on a connection that doesn't follow redirects or once the redirect limit is reached
+type ClusterRedirectError struct {
+	// Type is MOVED or ASK
+	Type string
+	// Addr is the address of the node the command was redirected to
+	Addr string
+	Err  error
+}
+
+func (e *ClusterRedirectError) Error() string { return e.Err.Error() }
+func (e *ClusterRedirectError) Unwrap() error { return e.Err }
+
+// error kinds of the exporter_last_scrape_error_kind metric, see errorKind()
+const (
+	errorKindAuth               = "auth"
+	errorKindPermission         = "permission"
+	errorKindTimeout            = "timeout"
+	errorKindUnsupportedCommand = "unsupported_command"
+	errorKindClusterRedirect    = "cluster_redirect"
+	errorKindOther              = "other"
+)
+
+var authErrorMessages = []string{
+	"noauth",
+	"wrongpass",
+	"invalid password",
+	"invalid username-password pair",
+	"no password is set",
+	"without any password configured",
+}
+
+var unsupportedCommandMessages = []string{
+	"unknown command",
+	"unknown subcommand",
+	"cluster support disabled",
+}
+
+// classifyError wraps err in the error type of its failure mode, err is returned as is if it
+// doesn't match any of them or is already classified. The message of err isn't changed
+func classifyError(err error, cmd string, args ...interface{}) error {
+	if err == nil || errorKind(err) != errorKindOther {
+		return err
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return &TimeoutError{Command: cmd, Err: err}
+	}
+
+	var redisErr redis.Error
+	if !errors.As(err, &redisErr) {
+		return err
+	}
+
+	if re := redisc.ParseRedir(redisErr); re != nil {
+		return &ClusterRedirectError{Type: re.Type, Addr: re.Addr, Err: err}
+	}
+
+	msg := strings.ToLower(string(redisErr))
+	if strings.HasPrefix(msg, "noperm") {
+		return &PermissionError{Command: deniedCommand(msg, cmd), Err: err}
+	}
+	for _, m := range authErrorMessages {
+		if strings.Contains(msg, m) {
+			return &AuthError{Err: err}
+		}
+	}
+	for _, m := range unsupportedCommandMessages {
+		if !strings.Contains(msg, m) {
+			continue
+		}
+		command := strings.ToUpper(cmd)
+		if m == "unknown subcommand" && len(args) > 0 {
+			command = fmt.Sprintf("%s %v", command, args[0])
+		}
+		return &UnsupportedCommandError{Command: command, Err: err}
+	}
+	return err
+}
+
+// deniedCommand returns the command of a NOPERM reply like "NOPERM User default has no
+// permissions to run the 'acl|dryrun' command", cmd if the reply doesn't name it
+func deniedCommand(msg string, cmd string) string {
+	if _, rest, ok := strings.Cut(msg, "'"); ok {
+		if name, _, ok := strings.Cut(rest, "'"); ok && strings.Contains(msg, "command") {
+			return strings.ToUpper(strings.ReplaceAll(name, "|", " "))
+		}
+	}
+	return strings.ToUpper(cmd)
+}
+
+// errorKind returns the kind of a classified error, "other" if it isn't classified
+func errorKind(err error) string {
+	var (
+		authErr        *AuthError
+		permissionErr  *PermissionError
+		timeoutErr     *TimeoutError
+		unsupportedErr *UnsupportedCommandError
+		redirectErr    *ClusterRedirectError
+	)
+	switch {
+	case errors.As(err, &authErr):
+		return errorKindAuth
+	case errors.As(err, &permissionErr):
+		return errorKindPermission
+	case errors.As(err, &timeoutErr):
+		return errorKindTimeout
+	case errors.As(err, &unsupportedErr):
+		return errorKindUnsupportedCommand
+	case errors.As(err, &redirectErr):
+		return errorKindClusterRedirect
+	}
+	return errorKindOther
+}
+
+// errorStatusCode returns the HTTP status of handlers that failed talking to Redis with err
+func errorStatusCode(err error) int {
+	switch errorKind(err) {
+	case errorKindAuth, errorKindPermission, errorKindClusterRedirect:
+		return http.StatusBadGateway
+	case errorKindTimeout:
+		return http.StatusGatewayTimeout
+	case errorKindUnsupportedCommand:
+		return http.StatusNotImplemented
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,136 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/oliver006/redis_exporter/exporter/exportertest"
+)
+
+// dialServer connects to s, the connection is closed when the test ends
+func dialServer(t *testing.T, s *exportertest.Server) redis.Conn {
+	t.Helper()
+	c, err := redis.DialURL(s.Addr())
+	if err != nil {
+		t.Fatalf("couldn't connect to %s: %s", s.Addr(), err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestClassifyError(t *testing.T) {
+	for _, tst := range []struct {
+		err     error
+		cmd     string
+		args    []interface{}
+		kind    string
+		command string
+	}{
+		{err: redis.Error("NOAUTH Authentication required."), kind: errorKindAuth},
+		{err: redis.Error("WRONGPASS invalid username-password pair or user is disabled."), kind: errorKindAuth},
+		{err: redis.Error("ERR invalid password"), kind: errorKindAuth},
+		{err: redis.Error("ERR AUTH <password> called without any password configured for the default user."), kind: errorKindAuth},
+		{err: fmt.Errorf("dial failed: %w", redis.Error("NOAUTH Authentication required.")), kind: errorKindAuth},
+		{err: redis.Error("NOPERM User exporter has no permissions to run the 'acl|dryrun' command"), cmd: "ACL", args: []interface{}{"DRYRUN", "exporter"}, kind: errorKindPermission, command: "ACL DRYRUN"},
+		{err: redis.Error("NOPERM No permissions to access a key"), cmd: "get", kind: errorKindPermission, command: "GET"},
+		{err: os.ErrDeadlineExceeded, cmd: "INFO", kind: errorKindTimeout, command: "INFO"},
+		{err: fmt.Errorf("read: %w", context.DeadlineExceeded), kind: errorKindTimeout},
+		{err: redis.Error("ERR unknown command 'LATENCY', with args beginning with: 'HISTOGRAM' "), cmd: "latency", args: []interface{}{"HISTOGRAM"}, kind: errorKindUnsupportedCommand, command: "LATENCY"},
+		{err: redis.Error("ERR unknown subcommand 'DRYRUN'. Try ACL HELP."), cmd: "ACL", args: []interface{}{"DRYRUN", "default"}, kind: errorKindUnsupportedCommand, command: "ACL DRYRUN"},
+		{err: redis.Error("ERR This instance has cluster support disabled"), cmd: "CLUSTER", args: []interface{}{"INFO"}, kind: errorKindUnsupportedCommand, command: "CLUSTER"},
+		{err: redis.Error("MOVED 3999 127.0.0.1:6381"), cmd: "GET", kind: errorKindClusterRedirect},
+		{err: redis.Error("ASK 3999 127.0.0.1:6382"), cmd: "GET", kind: errorKindClusterRedirect},
+		{err: redis.Error("WRONGTYPE Operation against a key holding the wrong kind of value"), cmd: "GET", kind: errorKindOther},
+		{err: redis.ErrNil, cmd: "GET", kind: errorKindOther},
+	} {
+		err := classifyError(tst.err, tst.cmd, tst.args...)
+		if err.Error() != tst.err.Error() {
+			t.Errorf("%s: the message changed to %q", tst.err, err)
+		}
+		if !errors.Is(err, tst.err) {
+			t.Errorf("%s: the error isn't wrapped", tst.err)
+		}
+		if kind := errorKind(err); kind != tst.kind {
+			t.Errorf("%s: expected kind %s, got: %s", tst.err, tst.kind, kind)
+		}
+
+		var unsupportedErr *UnsupportedCommandError
+		if errors.As(err, &unsupportedErr) && unsupportedErr.Command != tst.command {
+			t.Errorf("%s: expected command %q, got: %q", tst.err, tst.command, unsupportedErr.Command)
+		}
+		var permissionErr *PermissionError
+		if errors.As(err, &permissionErr) && permissionErr.Command != tst.command {
+			t.Errorf("%s: expected command %q, got: %q", tst.err, tst.command, permissionErr.Command)
+		}
+		var timeoutErr *TimeoutError
+		if errors.As(err, &timeoutErr) && timeoutErr.Command != tst.command {
+			t.Errorf("%s: expected command %q, got: %q", tst.err, tst.command, timeoutErr.Command)
+		}
+		// classifying twice doesn't wrap again
+		if again := classifyError(err, tst.cmd, tst.args...); again != err {
+			t.Errorf("%s: classified twice", tst.err)
+		}
+	}
+
+	if classifyError(nil, "GET") != nil {
+		t.Errorf("expected nil")
+	}
+
+	var redirectErr *ClusterRedirectError
+	if err := classifyError(redis.Error("ASK 3999 127.0.0.1:6382"), "GET"); !errors.As(err, &redirectErr) || redirectErr.Type != "ASK" || redirectErr.Addr != "127.0.0.1:6382" {
+		t.Errorf("unexpected redirect error: %#v", err)
+	}
+}
+
+func TestDoRedisCmdClassifiesErrors(t *testing.T) {
+	s := exportertest.NewServer(t)
+	s.Set("MEMORY", exportertest.Error("ERR unknown command 'MEMORY'"))
+	_, err := redis.Int64(doRedisCmd(dialServer(t, s), "MEMORY", "USAGE", "key"))
+
+	var unsupportedErr *UnsupportedCommandError
+	if !errors.As(err, &unsupportedErr) || unsupportedErr.Command != "MEMORY" {
+		t.Fatalf("expected an UnsupportedCommandError, got: %#v", err)
+	}
+	var redisErr redis.Error
+	if !errors.As(err, &redisErr) {
+		t.Errorf("the redis.Error isn't wrapped: %#v", err)
+	}
+}
+
+func TestConnectAuthError(t *testing.T) {
+	s := exportertest.NewServer(t)
+	s.Set("AUTH", exportertest.Error("WRONGPASS invalid username-password pair or user is disabled."))
+
+	e, _ := NewRedisExporter(s.Addr(), Options{Namespace: "test", Password: "wrong"})
+	_, err := e.connectToRedis()
+
+	var authErr *AuthError
+	if !errors.As(err, &authErr) {
+		t.Fatalf("expected an AuthError, got: %#v", err)
+	}
+	if code := errorStatusCode(err); code != http.StatusBadGateway {
+		t.Errorf("expected status %d, got: %d", http.StatusBadGateway, code)
+	}
+}
+
+func TestErrorStatusCode(t *testing.T) {
+	for _, tst := range []struct {
+		err  error
+		code int
+	}{
+		{err: &TimeoutError{Err: os.ErrDeadlineExceeded}, code: http.StatusGatewayTimeout},
+		{err: &UnsupportedCommandError{Command: "ACL DRYRUN", Err: redis.Error("ERR unknown subcommand")}, code: http.StatusNotImplemented},
+		{err: fmt.Errorf("scrape: %w", &ClusterRedirectError{Type: "MOVED", Err: redis.Error("MOVED 1 a:1")}), code: http.StatusBadGateway},
+		{err: errors.New("boom"), code: http.StatusInternalServerError},
+	} {
+		if code := errorStatusCode(tst.err); code != tst.code {
+			t.Errorf("%s: expected %d, got: %d", tst.err, tst.code, code)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package exporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// maxEvents is the number of events kept for /events, older ones are dropped
+const maxEvents = 1000
+
+// Event is a state transition of a target observed by a scrape
+type Event struct {
+	Time   time.Time `json:"time"`
+	Target string    `json:"target"`
+	Type   string    `json:"type"`
+	From   string    `json:"from"`
+	To     string    `json:"to"`
+}
+
+// eventLog keeps the last state per target and event type and the most recent transitions
+type eventLog struct {
+	sync.Mutex
+	states map[string]map[string]string
+	counts map[string]map[string]int64
+	events []Event
+}
+
+func newEventLog() *eventLog {
+	return &eventLog{states: map[string]map[string]string{}, counts: map[string]map[string]int64{}}
+}
+
+// observe records state as the state of eventType of target, a change is recorded as
+// event and returned, the first state of a target isn't a change
+func (l *eventLog) observe(target string, eventType string, state string, now time.Time) (Event, bool) {
+	l.Lock()
+	defer l.Unlock()
+
+	states, ok := l.states[target]
+	if !ok {
+		states = map[string]string{}
+		l.states[target] = states
+		l.counts[target] = map[string]int64{}
+	}
+	prev, seen := states[eventType]
+	states[eventType] = state
+	if !seen || prev == state {
+		return Event{}, false
+	}
+
+	ev := Event{Time: now, Target: target, Type: eventType, From: prev, To: state}
+	l.counts[target][eventType]++
+	l.events = append(l.events, ev)
+	if len(l.events) > maxEvents {
+		l.events = l.events[len(l.events)-maxEvents:]
+	}
+	return ev, true
+}
+
+// list returns the recorded events of target, of all targets if it's empty, oldest first,
+// the credentials of the target addresses are redacted
+func (l *eventLog) list(target string) []Event {
+	l.Lock()
+	defer l.Unlock()
+
+	res := []Event{}
+	for _, ev := range l.events {
+		ev.Target = redactedAddr(ev.Target)
+		if target == "" || ev.Target == target {
+			res = append(res, ev)
+		}
+	}
+	return res
+}
+
+func (l *eventLog) count(target string, eventType string) int64 {
+	l.Lock()
+	defer l.Unlock()
+	return l.counts[target][eventType]
+}
+
+// eventTypes are the state transitions recorded with Events
+var eventTypes = []string{"role_change", "master_link", "cluster_state", "aof_write_status"}
+
+// observeEvent records state for eventType of the target of the current scrape, empty
+// states (the field isn't reported) are ignored
+func (e *Exporter) observeEvent(eventType string, state string) {
+	if !e.options.Events || state == "" {
+		return
+	}
+	if ev, ok := e.events.observe(e.redisAddr, eventType, state, time.Now()); ok {
+		e.logger().Infof("%s of %s: %s -> %s", ev.Type, redactedAddr(ev.Target), ev.From, ev.To)
+	}
+}
+
+// registerEventMetrics exports events_total for all event types of the current target
+func (e *Exporter) registerEventMetrics(ch chan<- prometheus.Metric) {
+	if !e.options.Events {
+		return
+	}
+	for _, t := range eventTypes {
+		e.registerConstMetric(ch, "events_total", float64(e.events.count(e.redisAddr, t)), prometheus.CounterValue, t)
+	}
+}
+
+// eventsHandler serves the recorded events as JSON, optionally of a single target
+func (e *Exporter) eventsHandler(w http.ResponseWriter, r *http.Request) {
+	events := e.events.list(r.URL.Query().Get("target"))
+	data, err := json.MarshalIndent(events, "", "  ")
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to marshal events: %s", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
@@ -0,0 +1,95 @@
+package exporter
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestEventLog(t *testing.T) {
+	l := newEventLog()
+	now := time.Now()
+
+	if _, ok := l.observe("redis://a:6379", "role_change", "master", now); ok {
+		t.Errorf("the first state isn't a change")
+	}
+	if _, ok := l.observe("redis://a:6379", "role_change", "master", now); ok {
+		t.Errorf("the same state isn't a change")
+	}
+	ev, ok := l.observe("redis://a:6379", "role_change", "slave", now)
+	if !ok || ev.From != "master" || ev.To != "slave" {
+		t.Errorf("expected a change from master to slave, got: %+v", ev)
+	}
+	l.observe("redis://:secret@b:6379", "master_link", "up", now)
+	l.observe("redis://:secret@b:6379", "master_link", "down", now)
+
+	if got := l.count("redis://a:6379", "role_change"); got != 1 {
+		t.Errorf("expected 1 role change, got: %d", got)
+	}
+	if got := l.list(""); len(got) != 2 {
+		t.Errorf("expected 2 events, got: %v", got)
+	}
+	got := l.list("redis://:xxxxx@b:6379")
+	if len(got) != 1 || got[0].Type != "master_link" || got[0].Target != "redis://:xxxxx@b:6379" {
+		t.Errorf("expected the master_link event with a redacted target, got: %v", got)
+	}
+
+	for i := 0; i < maxEvents+10; i++ {
+		l.observe("redis://c:6379", "cluster_state", []string{"ok", "fail"}[i%2], now)
+	}
+	if got := l.list(""); len(got) != maxEvents {
+		t.Errorf("expected at most %d events, got: %d", maxEvents, len(got))
+	}
+}
+
+func TestEvents(t *testing.T) {
+	e, _ := NewRedisExporter("redis://localhost:6379", Options{Namespace: "test", Events: true, Registry: prometheus.NewRegistry()})
+
+	var counts map[string]float64
+	for _, info := range []string{
+		"# Replication\r\nrole:slave\r\nmaster_link_status:up\r\n\r\n# Persistence\r\naof_last_write_status:ok\r\n",
+		"# Replication\r\nrole:slave\r\nmaster_link_status:down\r\n\r\n# Persistence\r\naof_last_write_status:err\r\n",
+	} {
+		chM := make(chan prometheus.Metric)
+		go func() {
+			e.extractInfoMetrics(chM, info, 16)
+			close(chM)
+		}()
+		counts = map[string]float64{}
+		for m := range chM {
+			if m.Desc() != e.mustFindMetricDescription("events_total") {
+				continue
+			}
+			d := &dto.Metric{}
+			m.Write(d)
+			counts[d.GetLabel()[0].GetValue()] = d.GetCounter().GetValue()
+		}
+	}
+	for eventType, want := range map[string]float64{"role_change": 0, "master_link": 1, "aof_write_status": 1, "cluster_state": 0} {
+		if counts[eventType] != want {
+			t.Errorf("expected %s = %f, got: %v", eventType, want, counts)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest("GET", "/events", nil))
+	var events []Event
+	if err := json.Unmarshal(w.Body.Bytes(), &events); err != nil {
+		t.Fatalf("couldn't parse /events: %s, body: %s", err, w.Body.String())
+	}
+	if len(events) != 2 || events[0].Type != "master_link" || events[0].To != "down" || events[1].Type != "aof_write_status" {
+		t.Errorf("expected the master_link and aof_write_status events, got: %+v", events)
+	}
+
+	disabled, _ := NewRedisExporter("redis://localhost:6379", Options{Namespace: "test", DisableLandingPage: true, Registry: prometheus.NewRegistry()})
+	w = httptest.NewRecorder()
+	disabled.ServeHTTP(w, httptest.NewRequest("GET", "/events", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected /events to be disabled by default, got: %d", w.Code)
+	}
+}
@@ -0,0 +1,70 @@
+package exporter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// expectedModule is a module every scraped node must have loaded, in at least minVer if it's set
+type expectedModule struct {
+	name   string
+	minVer int64
+}
+
+// parseExpectedModules parses a list like "search>=2.10.0,ReJSON", versions are the numeric
+// ver of INFO MODULES (21000) or dotted (2.10.0), which modules encode as major*10000+minor*100+patch
+func parseExpectedModules(s string) ([]expectedModule, error) {
+	var res []expectedModule
+	for _, m := range strings.Split(s, ",") {
+		m = strings.TrimSpace(m)
+		if m == "" {
+			continue
+		}
+		name, ver, hasVer := strings.Cut(m, ">=")
+		em := expectedModule{name: strings.TrimSpace(name)}
+		if em.name == "" {
+			return nil, fmt.Errorf("invalid expected module %q: missing name", m)
+		}
+		if hasVer {
+			v, err := parseModuleVersion(strings.TrimSpace(ver))
+			if err != nil {
+				return nil, fmt.Errorf("invalid expected module %q: %s", m, err)
+			}
+			em.minVer = v
+		}
+		res = append(res, em)
+	}
+	return res, nil
+}
+
+// parseModuleVersion parses a module version like 21005 or 2.10.5
+func parseModuleVersion(s string) (int64, error) {
+	if !strings.Contains(s, ".") {
+		return strconv.ParseInt(s, 10, 64)
+	}
+	v, ok := parseRedisVersion(s)
+	if !ok {
+		return 0, fmt.Errorf("invalid version %q", s)
+	}
+	return int64(v[0]*10000 + v[1]*100 + v[2]), nil
+}
+
+// registerExpectedModules exports module_missing and module_version_outdated for every
+// expected module from the versions of the loaded modules by lowercase name
+func (e *Exporter) registerExpectedModules(ch chan<- prometheus.Metric, loaded map[string]int64) {
+	for _, m := range e.expectedModules {
+		missing, outdated := 0.0, 0.0
+		if ver, ok := loaded[strings.ToLower(m.name)]; !ok {
+			missing = 1
+			e.logger().Debugf("expected module %s isn't loaded", m.name)
+		} else if ver < m.minVer {
+			outdated = 1
+			e.logger().Debugf("module %s has version %d, expected at least %d", m.name, ver, m.minVer)
+		}
+		e.registerConstMetricGauge(ch, "module_missing", missing, m.name)
+		e.registerConstMetricGauge(ch, "module_version_outdated", outdated, m.name)
+	}
+}
@@ -0,0 +1,120 @@
+package exporter
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestParseExpectedModules(t *testing.T) {
+	got, err := parseExpectedModules("search>=2.10.5, ReJSON ,timeseries>=11202,")
+	if err != nil {
+		t.Fatalf("parseExpectedModules() err: %s", err)
+	}
+	want := []expectedModule{{name: "search", minVer: 21005}, {name: "ReJSON"}, {name: "timeseries", minVer: 11202}}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got: %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("expected %v, got: %v", want[i], got[i])
+		}
+	}
+
+	for _, s := range []string{">=1.0", "search>=x", "search>=1.a.0"} {
+		if _, err := parseExpectedModules(s); err == nil {
+			t.Errorf("expected an error for %q", s)
+		}
+	}
+	if _, err := NewRedisExporter("", Options{ExpectedModules: "search>=x"}); err == nil {
+		t.Errorf("expected NewRedisExporter() to fail for an invalid expected-modules")
+	}
+}
+
+func TestExpectedModules(t *testing.T) {
+	c := &fakeRedisConn{replies: map[string]interface{}{
+		"INFO MODULES": "# Modules\r\n" +
+			"module:name=search,ver=20804,api=1,filters=0,usedby=[],using=[],options=[]\r\n" +
+			"module:name=ReJSON,ver=20803,api=1,filters=0,usedby=[search],using=[],options=[]\r\n" +
+			"\r\n# search_version\r\nsearch_number_of_indexes:3\r\n",
+	}}
+
+	for _, tst := range []struct {
+		name        string
+		inclModules bool
+		wantInfo    bool
+	}{
+		{name: "only expected modules", inclModules: false, wantInfo: false},
+		{name: "with modules metrics", inclModules: true, wantInfo: true},
+	} {
+		t.Run(tst.name, func(t *testing.T) {
+			e, _ := NewRedisExporter("redis://localhost:6379", Options{
+				Namespace:          "test",
+				InclModulesMetrics: tst.inclModules,
+				ExpectedModules:    "search>=2.10.0,rejson>=2.8.0,timeseries",
+			})
+
+			chM := make(chan prometheus.Metric)
+			go func() {
+				e.extractModulesMetrics(chM, c)
+				close(chM)
+			}()
+
+			missing := map[string]float64{}
+			outdated := map[string]float64{}
+			infos := 0
+			for m := range chM {
+				d := &dto.Metric{}
+				m.Write(d)
+				switch m.Desc() {
+				case e.mustFindMetricDescription("module_missing"):
+					missing[d.GetLabel()[0].GetValue()] = d.GetGauge().GetValue()
+				case e.mustFindMetricDescription("module_version_outdated"):
+					outdated[d.GetLabel()[0].GetValue()] = d.GetGauge().GetValue()
+				case e.mustFindMetricDescription("module_info"):
+					infos++
+				}
+			}
+
+			for name, want := range map[string]float64{"search": 0, "rejson": 0, "timeseries": 1} {
+				if missing[name] != want {
+					t.Errorf("expected module_missing{name=%q} = %f, got: %v", name, want, missing)
+				}
+			}
+			for name, want := range map[string]float64{"search": 1, "rejson": 0, "timeseries": 0} {
+				if outdated[name] != want {
+					t.Errorf("expected module_version_outdated{name=%q} = %f, got: %v", name, want, outdated)
+				}
+			}
+			if (infos == 2) != tst.wantInfo {
+				t.Errorf("expected module_info: %t, got %d", tst.wantInfo, infos)
+			}
+		})
+	}
+}
+
+func TestExpectedModulesInfoError(t *testing.T) {
+	e, _ := NewRedisExporter("redis://localhost:6379", Options{Namespace: "test", ExpectedModules: "search,rejson"})
+
+	chM := make(chan prometheus.Metric)
+	go func() {
+		// INFO MODULES fails, the fake conn has no replies
+		e.extractModulesMetrics(chM, &fakeRedisConn{})
+		close(chM)
+	}()
+
+	missing := map[string]float64{}
+	for m := range chM {
+		d := &dto.Metric{}
+		m.Write(d)
+		if m.Desc() == e.mustFindMetricDescription("module_missing") {
+			missing[d.GetLabel()[0].GetValue()] = d.GetGauge().GetValue()
+		}
+	}
+	for _, name := range []string{"search", "rejson"} {
+		if missing[name] != 1 {
+			t.Errorf("expected module_missing{name=%q} = 1, got: %v", name, missing)
+		}
+	}
+}
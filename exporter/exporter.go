@@ -1,13 +1,17 @@
 package exporter
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
-	"net/url"
+	"net/netip"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	// see https://github.com/prometheus/client_golang/releases/tag/v1.22.0
@@ -15,8 +19,8 @@ import (
 
 	"github.com/gomodule/redigo/redis"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type BuildInfo struct {
@@ -32,66 +36,267 @@ type Exporter struct {
 	redisAddr string
 
 	totalScrapes              prometheus.Counter
-	scrapeDuration            prometheus.Summary
+	scrapeDuration            scrapeDurationMetric
 	targetScrapeRequestErrors prometheus.Counter
 
-	metricDescriptions map[string]*prometheus.Desc
+	targetScrapeRequestsRateLimited *prometheus.CounterVec
+
+	// metricDescriptions is written while scraping by collectors adding descriptions on
+	// first use, see addMetricDescription()
+	metricDescriptionsMtx sync.RWMutex
+	metricDescriptions    map[string]*prometheus.Desc
+	// source of the descriptions added while scraping, e.g. the key of key_size_<key>
+	metricDescriptionSources map[string]string
+	// names of the descriptions that weren't added, logged once
+	droppedMetricDescriptions map[string]bool
+	// names of the INFO fields seen with export-unknown-info-fields, false if the name is taken
+	unknownInfoFields map[string]bool
 
 	options Options
 
 	metricMapCounters map[string]string
 	metricMapGauges   map[string]string
 
+	configMetricsInclude map[string]bool
+
+	streamConsumerFilter streamConsumerFilter
+	infoFieldFilter      infoFieldFilter
+
+	// hash slots of check-keys-slot-range
+	checkKeysSlots slotRanges
+
+	// set while the keys of a check-keys pattern are aggregated
+	keyAggregate *keyPatternAggregate
+	// set while the keys of a check-keys pattern are ranked for check-keys-top-n
+	keyTopN *keyTopN
+	// number of keys that may still be DUMPed in the current scrape
+	keyDumpBudget int64
+	// hashed label values whose label_value_hash_info was exported in the current collection
+	labelValueHashes map[string]bool
+	// position of the db label per metric for db-label-format, see db_labels.go
+	dbLabelIndexes map[string]int
+	// keys checked in the current scrape, see max-checked-keys
+	keyChecks *safetyCap
+	// compat level of the server of the current scrape, see compat.go
+	compatLevel int
+	// modules every scraped node must have loaded, see expected_modules.go
+	expectedModules []expectedModule
+	// percentiles of LATENCYSTATS to export, see latency_percentiles.go
+	latencyPercentiles []float64
+	// databases counted with DBSIZE if INFO has no Keyspace section, see dbsize_fallback.go
+	dbsizeFallbackDbs []int
+	// flavor of the server of the current scrape, see server_flavor.go
+	serverFlavor string
+
+	seriesGuard *seriesGuard
+
+	// phases of the current scrape and its connection attempt, see scrape_phases.go
+	phaseTimer *scrapePhaseTimer
+	dialTimer  *dialTimer
+
+	// spans of the current scrape if tracing is enabled, see tracing.go
+	tracer trace.Tracer
+	trace  *scrapeTrace
+
+	// log context of the current scrape, see logging.go
+	logBase  *log.Entry
+	logEntry atomic.Pointer[log.Entry]
+	// debug level logger of the debug scrapes of /scrape, see debug_scrape.go
+	debugLogger *log.Logger
+
 	mux *http.ServeMux
+	// operational endpoints served on AdminListenAddress, nil if they're served by mux
+	adminMux *http.ServeMux
+	// clients allowed to use the web endpoints, all if empty
+	allowedCIDRs []netip.Prefix
 
 	buildInfo BuildInfo
+
+	// shared with the per-target exporters created by the /scrape handler
+	circuitBreaker      *circuitBreaker
+	lastScrapes         *lastScrapes
+	aclLog              *aclLogState
+	rejectedConnections *counterRates
+	replOffsets         *counterRates
+	roleChanges         *roleChanges
+	latencyMonitorHints *onceSet
+	clusterRedirects    *clusterRedirects
+	inflight            *inflightCollections
+	leader              *leaderElection
+	scanCheckpoints     *scanCheckpoints
+	staleSnapshots      *staleSnapshots
+	responseStats       *responseStats
+	growth              *growthWindows
+	events              *eventLog
+	cpuFork             *cpuForkStats
+
+	scrapeRateLimiter *scrapeRateLimiter
+
+	// requests waiting for a collection and the context of the current one, see scrape_context.go
+	scrapeRequests *scrapeRequests
+	scrapeCtx      context.Context
+
+	scheduler *targetScheduler
+	watchdog  *watchdog
+
+	configReloads *configReloads
+	checkKeysAPI  *checkKeysAPI
+	counterState  *counterState
+
+	// exporters of the tenants by metrics path, see tenants.go
+	tenants map[string]*Exporter
+
+	// set for the exporters of single targets created by newTargetExporter
+	targetExporter bool
 }
 
 type Options struct {
 	User                           string
 	Password                       string
 	Namespace                      string
+	SubsystemNamespaces            map[string]string
 	PasswordMap                    map[string]string
+	PasswordEntries                map[string]PasswordEntry
 	ConfigCommandName              string
 	CheckKeys                      string
 	CheckSingleKeys                string
+	CheckKeysFromKey               string
+	CheckKeysAPIFile               string
+	CounterStateFile               string
 	CheckStreams                   string
 	CheckSingleStreams             string
 	StreamsExcludeConsumerMetrics  bool
+	StreamsConsumerFilter          string
+	StreamsConsumerMinPending      int64
+	StreamsConsumerMinIdle         time.Duration
+	StreamsUseXinfoFull            bool
+	StreamsXinfoFullCount          int64
 	CheckKeysBatchSize             int64
+	CheckKeysPipelineSize          int64
+	CheckKeysAsMetricNames         bool
+	CheckKeysAggregate             bool
+	CheckKeysTopN                  int64
+	CheckKeysSort                  string
+	CheckKeysScanBudget            int64
+	MaxCheckedKeys                 int64
+	MaxCheckedStreams              int64
+	MaxStreamConsumers             int64
+	CheckKeysDumpSize              bool
+	CheckKeysDumpMaxKeys           int64
+	CheckKeysDumpSampleRatio       float64
+	CheckKeysHexLabels             bool
+	KeySampleCount                 int64
+	KeySampleDbs                   string
+	DbsizeFallbackDbs              string
+	KeyMetricNames                 map[string]string
 	CheckKeyGroups                 string
+	KeyGroups                      []KeyGroup
 	MaxDistinctKeyGroups           int64
+	KeyGroupsMemorySamples         int64
+	KeyGroupsMemorySampleRatio     float64
+	KeyGroupsMinKeys               int64
 	CountKeys                      string
 	LuaScript                      map[string][]byte
+	Probes                         []Probe
+	Tenants                        []Tenant
+	WaitProbeKey                   string
+	WaitProbeReplicas              int
+	WaitProbeTimeout               time.Duration
 	ClientCertFile                 string
 	ClientKeyFile                  string
 	CaCertFile                     string
 	InclConfigMetrics              bool
+	ConfigMetricsInclude           string
 	InclModulesMetrics             bool
+	ExpectedModules                string
+	InclPubSubShardMetrics         bool
+	InclPubSubShardChannels        bool
+	InclACLLogMetrics              bool
+	ACLLogCount                    int64
 	InclSearchIndexesMetrics       bool
 	CheckSearchIndexes             string
 	DisableExportingKeyValues      bool
 	ExcludeLatencyHistogramMetrics bool
+	LatencyPercentiles             string
+	LatencyPercentilesFormat       string
 	RedactConfigMetrics            bool
+	ExpectedConfig                 map[string]string
 	InclSystemMetrics              bool
+	InclDerivedMetrics             bool
+	InclCPUForkMetrics             bool
+	InclKernelWarnings             bool
+	ExportUnknownInfoFields        bool
+	InfoFieldsInclude              string
+	InfoFieldsExclude              string
 	SkipTLSVerification            bool
 	SetClientName                  bool
 	IsTile38                       bool
+	IsGarnet                       bool
+	ViaProxy                       bool
 	IsCluster                      bool
+	ClusterMaxRedirects            int
+	CheckKeysSlotRange             string
 	ExportClientList               bool
 	ExportClientsInclPort          bool
+	ClientListMaxClients           int64
+	ClientListMaxConnectedClients  int64
+	InclClientIdleMetrics          bool
+	ClientIdleThreshold            time.Duration
+	WatchdogInterval               time.Duration
+	WatchdogStuckAfter             time.Duration
+	WatchdogExitOnStuck            bool
 	ConnectionTimeouts             time.Duration
+	ConnectionRetries              int
+	ConnectionRetryBackoff         time.Duration
+	CircuitBreakerThreshold        int
+	CircuitBreakerCooldown         time.Duration
+	ServeStaleOnError              time.Duration
+	TargetsFile                    string
+	TargetsScrapeInterval          time.Duration
+	TargetsScrapeConcurrency       int
+	TargetsScrapeJitter            time.Duration
+	ConstLabels                    map[string]string
+	HAReplicaName                  string
+	Zone                           string
+	LeaderElectionKey              string
+	LeaderElectionTTL              time.Duration
+	MetricMapping                  map[string]MetricMapping
+	MaxSeriesPerFamily             int
+	ValidateSeries                 bool
+	LabelValuesMaxBytes            int
+	LabelValuesTooLong             string
+	LabelValuesStripNonUTF8        bool
+	DbLabelFormat                  string
 	MetricsPath                    string
+	AdminListenAddress             string
+	GRPCHealthListenAddress        string
+	WebAllowedCIDRs                string
+	ExpositionFormat               string
+	LandingPageTitle               string
+	DisableLandingPage             bool
 	RedisMetricsOnly               bool
 	PingOnConnect                  bool
 	RedisPwdFile                   string
+	RedisPwdFileKey                []byte
 	Registry                       *prometheus.Registry
 	BuildInfo                      BuildInfo
 	BasicAuthUsername              string
 	BasicAuthPassword              string
 	BasicAuthHashPassword          string
+	AuditLog                       bool
+	ScrapeRateLimit                float64
+	ScrapeRateBurst                int
+	ScrapeRateLimitPerClient       float64
+	ScrapeRateBurstPerClient       int
 	SkipCheckKeysForRoleMaster     bool
+	AssumeReadonlyReplica          bool
 	InclMetricsForEmptyDatabases   bool
+	GrowthWindow                   time.Duration
+	Events                         bool
+	DisableSelect                  bool
+	AllowDebugScrape               bool
+	LogLatencyMonitorHint          bool
+	TracerProvider                 trace.TracerProvider
 }
 
 // NewRedisExporter returns a new exporter of Redis metrics.
@@ -103,6 +308,9 @@ func NewRedisExporter(uri string, opts Options) (*Exporter, error) {
 		uri = strings.Replace(uri, "valkey://", "redis://", 1)
 	case strings.HasPrefix(uri, "valkeys://"):
 		uri = strings.Replace(uri, "valkeys://", "rediss://", 1)
+	case strings.HasPrefix(uri, "/"):
+		// plain path, e.g. "/run/redis/redis.sock"
+		uri = "unix://" + uri
 	}
 
 	log.Debugf("NewRedisExporter = using redis uri: %s", uri)
@@ -117,24 +325,52 @@ func NewRedisExporter(uri string, opts Options) (*Exporter, error) {
 
 		buildInfo: opts.BuildInfo,
 
+		lastScrapes:         newLastScrapes(),
+		aclLog:              newACLLogState(),
+		rejectedConnections: newCounterRates(),
+		replOffsets:         newCounterRates(),
+		roleChanges:         newRoleChanges(),
+		latencyMonitorHints: newOnceSet(),
+		clusterRedirects:    newClusterRedirects(),
+		inflight:            newInflightCollections(),
+		scanCheckpoints:     newScanCheckpoints(),
+		staleSnapshots:      newStaleSnapshots(opts.ServeStaleOnError),
+		responseStats:       newResponseStats(),
+		growth:              newGrowthWindows(),
+		events:              newEventLog(),
+		cpuFork:             newCPUForkStats(),
+
+		configReloads:  newConfigReloads(opts.PasswordEntries, time.Now()),
+		scrapeRequests: newScrapeRequests(),
+
 		totalScrapes: prometheus.NewCounter(prometheus.CounterOpts{
-			Namespace: opts.Namespace,
-			Name:      "exporter_scrapes_total",
-			Help:      "Current total redis scrapes.",
+			Namespace:   opts.Namespace,
+			Name:        "exporter_scrapes_total",
+			Help:        "Current total redis scrapes.",
+			ConstLabels: constLabels(opts.ConstLabels, haReplicaLabels(opts.HAReplicaName), nil),
 		}),
 
 		scrapeDuration: prometheus.NewSummary(prometheus.SummaryOpts{
-			Namespace: opts.Namespace,
-			Name:      "exporter_scrape_duration_seconds",
-			Help:      "Durations of scrapes by the exporter",
+			Namespace:   opts.Namespace,
+			Name:        "exporter_scrape_duration_seconds",
+			Help:        "Durations of scrapes by the exporter",
+			ConstLabels: constLabels(opts.ConstLabels, haReplicaLabels(opts.HAReplicaName), nil),
 		}),
 
 		targetScrapeRequestErrors: prometheus.NewCounter(prometheus.CounterOpts{
-			Namespace: opts.Namespace,
-			Name:      "target_scrape_request_errors_total",
-			Help:      "Errors in requests to the exporter",
+			Namespace:   opts.Namespace,
+			Name:        "target_scrape_request_errors_total",
+			Help:        "Errors in requests to the exporter",
+			ConstLabels: constLabels(opts.ConstLabels, haReplicaLabels(opts.HAReplicaName), nil),
 		}),
 
+		targetScrapeRequestsRateLimited: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   opts.Namespace,
+			Name:        "target_scrape_requests_rate_limited_total",
+			Help:        "Requests to /scrape rejected by the global or per client rate limit",
+			ConstLabels: constLabels(opts.ConstLabels, haReplicaLabels(opts.HAReplicaName), []string{"scope"}),
+		}, []string{"scope"}),
+
 		metricMapGauges: map[string]string{
 			// # Server
 			"uptime_in_seconds": "uptime_in_seconds",
@@ -282,6 +518,14 @@ func NewRedisExporter(uri string, opts Options) (*Exporter, error) {
 			"sync_partial_ok":                "replica_partial_resync_accepted",
 			"sync_partial_err":               "replica_partial_resync_denied",
 
+			// diskless / dual-channel replication, Added in Redis 8.0 and Valkey 8.0
+			"replicas_waiting_psync":        "replicas_waiting_psync",
+			"replicas_repl_buffer_size":     "replicas_repl_buffer_size_bytes",
+			"replicas_repl_buffer_peak":     "replicas_repl_buffer_peak_bytes",
+			"replica_full_sync_buffer_size": "replica_full_sync_buffer_size_bytes",
+			"replica_full_sync_buffer_peak": "replica_full_sync_buffer_peak_bytes",
+			"master_current_sync_attempts":  "master_current_sync_attempts",
+
 			// # Cluster
 			"cluster_stats_messages_sent":     "cluster_messages_sent_total",
 			"cluster_stats_messages_received": "cluster_messages_received_total",
@@ -360,6 +604,7 @@ func NewRedisExporter(uri string, opts Options) (*Exporter, error) {
 
 		metricMapCounters: map[string]string{
 			"total_connections_received": "connections_received_total",
+			"total_forks":                "forks_total",
 			"total_commands_processed":   "commands_processed_total",
 
 			"rejected_connections":   "rejected_connections_total",
@@ -369,6 +614,8 @@ func NewRedisExporter(uri string, opts Options) (*Exporter, error) {
 			"total_net_repl_input_bytes":  "net_repl_input_bytes_total",
 			"total_net_repl_output_bytes": "net_repl_output_bytes_total",
 
+			"master_total_sync_attempts": "master_sync_attempts_total", // Added in Redis 8.0 and Valkey 8.0
+
 			"expired_subkeys":                "expired_subkeys_total",
 			"expired_keys":                   "expired_keys_total",
 			"expired_time_cap_reached_count": "expired_time_cap_reached_total",
@@ -455,6 +702,46 @@ func NewRedisExporter(uri string, opts Options) (*Exporter, error) {
 		e.options.ConfigCommandName = "CONFIG"
 	}
 
+	if e.options.ConnectionRetryBackoff <= 0 {
+		e.options.ConnectionRetryBackoff = 100 * time.Millisecond
+	}
+
+	if e.options.StreamsXinfoFullCount <= 0 {
+		e.options.StreamsXinfoFullCount = 1
+	}
+
+	if opts.CircuitBreakerThreshold > 0 {
+		if e.options.CircuitBreakerCooldown <= 0 {
+			e.options.CircuitBreakerCooldown = 30 * time.Second
+		}
+		e.circuitBreaker = newCircuitBreaker(opts.CircuitBreakerThreshold, e.options.CircuitBreakerCooldown)
+	}
+
+	if e.options.ClusterMaxRedirects <= 0 {
+		e.options.ClusterMaxRedirects = 10
+	}
+
+	if opts.ScrapeRateLimit > 0 || opts.ScrapeRateLimitPerClient > 0 {
+		e.scrapeRateLimiter = newScrapeRateLimiter(opts.ScrapeRateLimit, opts.ScrapeRateBurst, opts.ScrapeRateLimitPerClient, opts.ScrapeRateBurstPerClient)
+	}
+
+	if opts.TracerProvider != nil {
+		e.tracer = opts.TracerProvider.Tracer(tracerName)
+		e.scrapeDuration = newScrapeDurationHistogram(opts.Namespace, constLabels(opts.ConstLabels, haReplicaLabels(opts.HAReplicaName), nil))
+	}
+
+	if opts.CheckKeysAPIFile != "" {
+		if err := e.initCheckKeysAPI(); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.CounterStateFile != "" {
+		if err := e.initCounterState(); err != nil {
+			return nil, err
+		}
+	}
+
 	if keys, err := parseKeyArg(opts.CheckKeys); err != nil {
 		return nil, fmt.Errorf("couldn't parse check-keys: %s", err)
 	} else {
@@ -467,6 +754,10 @@ func NewRedisExporter(uri string, opts Options) (*Exporter, error) {
 		log.Debugf("singleKeys: %#v", singleKeys)
 	}
 
+	if _, err := parseKeyWatchlist(opts.CheckKeysFromKey); err != nil {
+		return nil, fmt.Errorf("couldn't parse check-keys-from-key: %s", err)
+	}
+
 	if streams, err := parseKeyArg(opts.CheckStreams); err != nil {
 		return nil, fmt.Errorf("couldn't parse check-streams: %s", err)
 	} else {
@@ -479,22 +770,139 @@ func NewRedisExporter(uri string, opts Options) (*Exporter, error) {
 		log.Debugf("singleStreams: %#v", singleStreams)
 	}
 
+	if filter, err := parseStreamConsumerFilter(opts.StreamsConsumerFilter); err != nil {
+		return nil, fmt.Errorf("couldn't parse check-streams-consumer-filter: %s", err)
+	} else {
+		e.streamConsumerFilter = filter
+	}
+
+	if cidrs, err := parseAllowedCIDRs(opts.WebAllowedCIDRs); err != nil {
+		return nil, fmt.Errorf("couldn't parse web.allowed-cidrs: %s", err)
+	} else {
+		e.allowedCIDRs = cidrs
+	}
+
+	if filter, err := parseInfoFieldFilter(opts.InfoFieldsInclude, opts.InfoFieldsExclude); err != nil {
+		return nil, fmt.Errorf("couldn't parse info-fields-include/exclude: %s", err)
+	} else {
+		e.infoFieldFilter = filter
+	}
+
 	if countKeys, err := parseKeyArg(opts.CountKeys); err != nil {
 		return nil, fmt.Errorf("couldn't parse count-keys: %s", err)
 	} else {
 		log.Debugf("countKeys: %#v", countKeys)
 	}
 
+	if slots, err := parseSlotRanges(opts.CheckKeysSlotRange); err != nil {
+		return nil, fmt.Errorf("couldn't parse check-keys-slot-range: %s", err)
+	} else {
+		e.checkKeysSlots = slots
+	}
+
+	if opts.CheckKeyGroups != "" && len(opts.KeyGroups) > 0 {
+		return nil, fmt.Errorf("check-key-groups and key-groups-file are mutually exclusive")
+	}
+
+	if _, ok := expositionFormats[opts.ExpositionFormat]; !ok && opts.ExpositionFormat != "" {
+		return nil, fmt.Errorf("unknown exposition format %q, expected one of %s", opts.ExpositionFormat, strings.Join(expositionFormatNames(), ", "))
+	}
+
+	if opts.CheckKeysTopN > 0 {
+		if opts.CheckKeysAggregate {
+			return nil, fmt.Errorf("check-keys-top-n and check-keys-aggregate are mutually exclusive")
+		}
+		if e.options.CheckKeysSort == "" {
+			e.options.CheckKeysSort = "size"
+		}
+		if !slices.Contains(checkKeysSortOrders, e.options.CheckKeysSort) {
+			return nil, fmt.Errorf("unknown check-keys-sort %q, expected one of %s", e.options.CheckKeysSort, strings.Join(checkKeysSortOrders, ", "))
+		}
+	}
+
+	if e.options.LabelValuesTooLong == "" {
+		e.options.LabelValuesTooLong = "truncate"
+	}
+	if !slices.Contains(labelValuesTooLongPolicies, e.options.LabelValuesTooLong) {
+		return nil, fmt.Errorf("unknown label-values-too-long %q, expected one of %s", e.options.LabelValuesTooLong, strings.Join(labelValuesTooLongPolicies, ", "))
+	}
+
+	if e.options.DbLabelFormat != "" && !slices.Contains(dbLabelFormats, e.options.DbLabelFormat) {
+		return nil, fmt.Errorf("unknown db-label-format %q, expected one of %s", e.options.DbLabelFormat, strings.Join(dbLabelFormats, ", "))
+	}
+
+	if opts.CheckKeysScanBudget > 0 && opts.IsCluster {
+		return nil, fmt.Errorf("check-keys-scan-budget isn't supported in cluster mode, the SCAN cursors of the nodes can't be resumed")
+	}
+
+	if opts.WaitProbeKey != "" && opts.IsCluster {
+		return nil, fmt.Errorf("wait-probe-key isn't supported in cluster mode, the canary key can't be routed to the node that's scraped")
+	}
+
+	if opts.CheckKeysDumpSampleRatio < 0 || opts.CheckKeysDumpSampleRatio > 1 {
+		return nil, fmt.Errorf("check-keys-dump-sample-ratio must be between 0 and 1, got: %f", opts.CheckKeysDumpSampleRatio)
+	}
+
+	if opts.KeyGroupsMemorySampleRatio < 0 || opts.KeyGroupsMemorySampleRatio > 1 {
+		return nil, fmt.Errorf("key-groups-memory-sample-ratio must be between 0 and 1, got: %f", opts.KeyGroupsMemorySampleRatio)
+	}
+
+	if opts.MaxSeriesPerFamily > 0 {
+		e.seriesGuard = newSeriesGuard(opts.MaxSeriesPerFamily)
+	}
+
+	if err := validateConstLabels(opts.ConstLabels); err != nil {
+		return nil, fmt.Errorf("invalid const labels: %w", err)
+	}
+
+	expectedModules, err := parseExpectedModules(opts.ExpectedModules)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse expected-modules: %w", err)
+	}
+	e.expectedModules = expectedModules
+
+	switch opts.LatencyPercentilesFormat {
+	case "":
+		e.options.LatencyPercentilesFormat = latencyPercentilesSummary
+	case latencyPercentilesSummary, latencyPercentilesGauges:
+	default:
+		return nil, fmt.Errorf("invalid latency-percentiles-format %q, expected summary or gauges", opts.LatencyPercentilesFormat)
+	}
+	if e.latencyPercentiles, err = parseLatencyPercentiles(opts.LatencyPercentiles); err != nil {
+		return nil, fmt.Errorf("couldn't parse latency-percentiles: %w", err)
+	}
+	if e.dbsizeFallbackDbs, err = parseDbsizeFallbackDbs(opts.DbsizeFallbackDbs); err != nil {
+		return nil, fmt.Errorf("couldn't parse dbsize-fallback-dbs: %w", err)
+	}
+
+	e.configMetricsInclude = map[string]bool{}
+	for _, param := range strings.Split(opts.ConfigMetricsInclude, ",") {
+		if param = strings.TrimSpace(param); param != "" {
+			e.configMetricsInclude[strings.ToLower(param)] = true
+		}
+	}
+
 	if opts.InclSystemMetrics {
-		e.metricMapGauges["total_system_memory"] = "total_system_memory_bytes"
+		for field, name := range systemInfoFields {
+			e.metricMapGauges[field] = name
+		}
 	}
 
 	e.metricDescriptions = map[string]*prometheus.Desc{}
+	e.metricDescriptionSources = map[string]string{}
+	e.droppedMetricDescriptions = map[string]bool{}
+	e.unknownInfoFields = map[string]bool{}
+	e.labelValueHashes = map[string]bool{}
+	e.dbLabelIndexes = map[string]int{}
 
 	for k, desc := range map[string]struct {
 		txt  string
 		lbls []string
 	}{
+		"acl_log_new_events":                                 {txt: `Number of new ACL LOG events since the last scrape by username and reason`, lbls: []string{"username", "reason"}},
+		"blocked_clients_by_command":                         {txt: `Number of clients blocked per blocking command (from CLIENT LIST)`, lbls: []string{"cmd"}},
+		"clients_by_library":                                 {txt: `Number of clients per client library name and version (from CLIENT LIST)`, lbls: []string{"lib_name", "lib_ver"}},
+		"cluster_key_redirects_total":                        {txt: `Number of MOVED and ASK redirects and TRYAGAIN replies the key collectors ran into`, lbls: []string{"type"}},
 		"commands_duration_seconds_total":                    {txt: `Total amount of time in seconds spent per command`, lbls: []string{"cmd"}},
 		"commands_failed_calls_total":                        {txt: `Total number of errors prior command execution per command`, lbls: []string{"cmd"}},
 		"commands_latencies_usec":                            {txt: `A histogram of latencies per command`, lbls: []string{"cmd"}},
@@ -502,25 +910,60 @@ func NewRedisExporter(uri string, opts Options) (*Exporter, error) {
 		"commands_total":                                     {txt: `Total number of calls per command`, lbls: []string{"cmd"}},
 		"config_client_output_buffer_limit_bytes":            {txt: `The configured buffer limits per class`, lbls: []string{"class", "limit"}},
 		"config_client_output_buffer_limit_overcome_seconds": {txt: `How long for buffer limits per class to be exceeded before replicas are dropped`, lbls: []string{"class", "limit"}},
+		"config_drift":                                       {txt: `Whether the config parameter differs from the expected value or is missing (1) or not (0)`, lbls: []string{"parameter", "missing"}},
 		"config_key_value":                                   {txt: `Config key and value`, lbls: []string{"key", "value"}},
 		"config_value":                                       {txt: `Config key and value as metric`, lbls: []string{"key"}},
+		"connected_clients_idle_seconds":                     {txt: "A histogram of the idle time of the connected clients (from CLIENT LIST)", lbls: []string{}},
 		"connected_slave_lag_seconds":                        {txt: "Lag of connected slave", lbls: []string{"slave_ip", "slave_port", "slave_state"}},
 		"connected_slave_offset_bytes":                       {txt: "Offset of connected slave", lbls: []string{"slave_ip", "slave_port", "slave_state"}},
 		"db_avg_ttl_seconds":                                 {txt: "Avg TTL in seconds", lbls: []string{"db"}},
 		"db_keys":                                            {txt: "Total number of keys by DB", lbls: []string{"db"}},
 		"db_keys_cached":                                     {txt: "Total number of cached keys by DB", lbls: []string{"db"}},
 		"db_keys_expiring":                                   {txt: "Total number of expiring keys by DB", lbls: []string{"db"}},
+		"db_keys_growth_per_hour":                            {txt: "Growth of the number of keys per hour by DB, the slope of a linear regression over growth-window", lbls: []string{"db"}},
+		"db_keys_subexpiry":                                  {txt: "Total number of keys with hash fields that have an expire by DB", lbls: []string{"db"}},
+		"derived_replication_lag_seconds":                    {txt: "Replication lag of connected slave estimated from its offset and the replication output rate", lbls: []string{"slave_ip", "slave_port", "slave_state"}},
 		"errors_total":                                       {txt: `Total number of errors per error type`, lbls: []string{"err"}},
+		"events_total":                                       {txt: `Number of state transitions of the instance recorded for /events by type`, lbls: []string{"type"}},
 		"exporter_last_scrape_error":                         {txt: "The last scrape error status.", lbls: []string{"err"}},
+		"exporter_last_scrape_error_kind":                    {txt: "The kind of the last scrape error (auth, timeout, unsupported_command, cluster_redirect or other), only exported if the scrape failed", lbls: []string{"kind"}},
+		"exporter_last_successful_scrape_timestamp_seconds":  {txt: "Timestamp of the last successful scrape of the target", lbls: []string{"target"}},
+		"exporter_readonly_skipped_collector":                {txt: `Collectors skipped because they aren't safe on read-only replicas`, lbls: []string{"collector"}},
+		"exporter_via_proxy_skipped_collector":               {txt: `Collectors skipped because the proxy in front of the server rejects their commands`, lbls: []string{"collector"}},
+		"exporter_scrape_cache_age_seconds":                  {txt: "Age in seconds of the cached metrics of a target of the targets file", lbls: []string{"target"}},
+		"exporter_scrape_phase_duration_seconds":             {txt: "Duration of the phases of the last scrape in seconds", lbls: []string{"phase"}},
+		"exporter_invalid_series":                            {txt: "Number of series of the last collection dropped as they can't be exposed, e.g. duplicates produced by a Lua script", lbls: []string{"reason"}},
+		"exporter_truncated_series":                          {txt: "Number of series per metric that exceeded max-series-per-family and were aggregated into the overflow series", lbls: []string{"metric"}},
+		"fork_duration_seconds":                              {txt: `Durations of the forks for RDB saves and AOF rewrites seen by the exporter, from latest_fork_usec`},
+		"kernel_warning":                                     {txt: `Kernel misconfigurations Redis warns about, 1 if the setting needs to be changed`, lbls: []string{"warning"}},
+		"key_dump_size_bytes":                                {txt: `The length of the DUMP serialization of "key" in bytes`, lbls: []string{"db", "key"}},
+		"key_group_avg_ttl_seconds":                          {txt: `Average TTL in seconds of the keys with a TTL in key group`, lbls: []string{"db", "group", "key_group"}},
 		"key_group_count":                                    {txt: `Count of keys in key group`, lbls: []string{"db", "key_group"}},
+		"key_group_keys_with_ttl":                            {txt: `Count of keys with a TTL in key group`, lbls: []string{"db", "group", "key_group"}},
 		"key_group_memory_usage_bytes":                       {txt: `Total memory usage of key group in bytes`, lbls: []string{"db", "key_group"}},
 		"key_memory_usage_bytes":                             {txt: `The memory usage of "key" in bytes`, lbls: []string{"db", "key"}},
+		"key_pattern_keys":                                   {txt: `Number of keys matching the check-keys pattern`, lbls: []string{"db", "pattern"}},
+		"key_pattern_memory_usage_bytes":                     {txt: `Total memory usage in bytes of the keys matching the check-keys pattern`, lbls: []string{"db", "pattern"}},
+		"key_pattern_size_avg":                               {txt: `Average length or size of the keys matching the check-keys pattern`, lbls: []string{"db", "pattern"}},
+		"key_pattern_size_max":                               {txt: `Largest length or size of the keys matching the check-keys pattern`, lbls: []string{"db", "pattern"}},
+		"key_pattern_size_min":                               {txt: `Smallest length or size of the keys matching the check-keys pattern`, lbls: []string{"db", "pattern"}},
+		"key_pattern_size_sum":                               {txt: `Total length or size of the keys matching the check-keys pattern`, lbls: []string{"db", "pattern"}},
+		"key_sample_expiring_ratio":                          {txt: `Fraction of the sampled keys of type that have a TTL`, lbls: []string{"db", "type"}},
+		"key_sample_keys":                                    {txt: `Number of keys sampled with RANDOMKEY`, lbls: []string{"db"}},
+		"key_sample_memory_usage_avg_bytes":                  {txt: `Average memory usage in bytes of the sampled keys of type`, lbls: []string{"db", "type"}},
+		"key_sample_type_ratio":                              {txt: `Fraction of the sampled keys that are of type`, lbls: []string{"db", "type"}},
+		"key_scan_in_progress":                               {txt: `Whether a SCAN pass over the keys matching the pattern is split over several scrapes and not complete yet`, lbls: []string{"db", "pattern", "collector"}},
+		"key_scan_last_pass_duration_seconds":                {txt: `Duration of the last complete SCAN pass over the keys matching the pattern in seconds`, lbls: []string{"db", "pattern", "collector"}},
+		"key_scan_pass_keys":                                 {txt: `Number of keys matching the pattern found so far in the current SCAN pass`, lbls: []string{"db", "pattern", "collector"}},
+		"key_scan_passes_total":                              {txt: `Total number of complete SCAN passes over the keys matching the pattern`, lbls: []string{"db", "pattern", "collector"}},
 		"key_size":                                           {txt: `The length or size of "key"`, lbls: []string{"db", "key"}},
 		"key_value":                                          {txt: `The value of "key"`, lbls: []string{"db", "key"}},
 		"key_value_as_string":                                {txt: `The value of "key" as a string`, lbls: []string{"db", "key", "val"}},
 		"keys_count":                                         {txt: `Count of keys`, lbls: []string{"db", "key"}},
+		"label_value_hash_info":                              {txt: `Maps the hashes used as label values for key, stream, group and consumer names longer than label-values-max-bytes to the names`, lbls: []string{"hash", "value"}},
 		"last_key_groups_scrape_duration_milliseconds":       {txt: `Duration of the last key group metrics scrape in milliseconds`},
 		"last_slow_execution_duration_seconds":               {txt: `The amount of time needed for last slow execution, in seconds`},
+		"latency_percentile_usec":                            {txt: `Latency percentile per command in microseconds`, lbls: []string{"cmd", "percentile"}},
 		"latency_percentiles_usec":                           {txt: `A summary of latency percentile distribution per command`, lbls: []string{"cmd"}},
 		"latency_spike_duration_seconds":                     {txt: `Length of the last latency spike in seconds`, lbls: []string{"event_name"}},
 		"latency_spike_last":                                 {txt: `When the latency spike last occurred`, lbls: []string{"event_name"}},
@@ -528,7 +971,13 @@ func NewRedisExporter(uri string, opts Options) (*Exporter, error) {
 		"master_link_up":                                     {txt: "Master link status on Redis slave", lbls: []string{"master_host", "master_port"}},
 		"master_sync_in_progress":                            {txt: "Master sync in progress", lbls: []string{"master_host", "master_port"}},
 		"module_info":                                        {txt: "Information about loaded Redis module", lbls: []string{"name", "ver", "api", "filters", "usedby", "using"}},
+		"module_missing":                                     {txt: "Whether the module of expected-modules isn't loaded", lbls: []string{"name"}},
+		"module_version_outdated":                            {txt: "Whether the module of expected-modules is loaded in an older version than expected", lbls: []string{"name"}},
 		"number_of_distinct_key_groups":                      {txt: `Number of distinct key groups`, lbls: []string{"db"}},
+		"probe_duration_seconds":                             {txt: `How long the probe took in seconds`, lbls: []string{"probe"}},
+		"probe_success":                                      {txt: `Whether the probe succeeded (1) or not (0)`, lbls: []string{"probe"}},
+		"probe_value":                                        {txt: `The numeric reply of the probe`, lbls: []string{"probe"}},
+		"pubsub_shard_channel_subscribers":                   {txt: `Number of subscribers of the shard channel (from PUBSUB SHARDNUMSUB)`, lbls: []string{"channel"}},
 		"script_result":                                      {txt: "Result of the collect script evaluation", lbls: []string{"filename"}},
 		"script_values":                                      {txt: "Values returned by the collect script", lbls: []string{"key", "filename"}},
 		"search_index_num_docs":                              {txt: "Number of documents in search index", lbls: []string{"index_name"}},
@@ -572,11 +1021,14 @@ func NewRedisExporter(uri string, opts Options) (*Exporter, error) {
 		"sentinel_tilt":                                      {txt: "Sentinel is in TILT mode"},
 		"sentinel_config_key_value":                          {txt: `Sentinel global config key and value`, lbls: []string{"key", "value"}},
 		"sentinel_config_value":                              {txt: `Sentinel global config key and value as metric`, lbls: []string{"key"}},
+		"server_flavor_info":                                 {txt: "Flavor of the server (redis, valkey, keydb, dragonfly or garnet) and its version", lbls: []string{"flavor", "version"}},
 		"slave_info":                                         {txt: "Information about the Redis slave", lbls: []string{"master_host", "master_port", "read_only"}},
 		"slave_repl_offset":                                  {txt: "Slave replication offset", lbls: []string{"master_host", "master_port"}},
 		"slowlog_last_id":                                    {txt: `Last id of slowlog`},
 		"slowlog_length":                                     {txt: `Total slowlog`},
 		"start_time_seconds":                                 {txt: "Start time of the Redis instance since unix epoch in seconds."},
+		"stream_backlog_age_seconds":                         {txt: `Age of the oldest entry of the stream in seconds, based on its ID`, lbls: []string{"db", "stream"}},
+		"stream_entries_added_total":                         {txt: `The number of entries added to the stream over its lifetime`, lbls: []string{"db", "stream"}},
 		"stream_first_entry_id":                              {txt: `The epoch timestamp (ms) of the first message in the stream`, lbls: []string{"db", "stream"}},
 		"stream_group_consumer_idle_seconds":                 {txt: `Consumer idle time in seconds`, lbls: []string{"db", "stream", "group", "consumer"}},
 		"stream_group_consumer_messages_pending":             {txt: `Pending number of messages for this specific consumer`, lbls: []string{"db", "stream", "group", "consumer"}},
@@ -592,9 +1044,22 @@ func NewRedisExporter(uri string, opts Options) (*Exporter, error) {
 		"stream_max_deleted_entry_id":                        {txt: `The epoch timestamp (ms) of last message was deleted from the stream`, lbls: []string{"db", "stream"}},
 		"stream_radix_tree_keys":                             {txt: `Radix tree keys count"`, lbls: []string{"db", "stream"}},
 		"stream_radix_tree_nodes":                            {txt: `Radix tree nodes count`, lbls: []string{"db", "stream"}},
+		"stream_trimmed_entries":                             {txt: `The number of entries added to the stream that have been trimmed or deleted since`, lbls: []string{"db", "stream"}},
+		"transparent_hugepages_enabled":                      {txt: "Whether transparent huge pages are enabled for the server, they cause latency spikes and memory usage on forks"},
 		"up":                                                 {txt: "Information about the Redis instance"},
 	} {
-		e.metricDescriptions[k] = newMetricDescr(opts.Namespace, k, desc.txt, desc.lbls)
+		e.metricDescriptions[k] = e.newMetricDescr(k, desc.txt, desc.lbls)
+	}
+
+	// named key groups from the key groups file add the group label
+	if len(e.options.KeyGroups) > 0 {
+		e.metricDescriptions["key_group_count"] = e.newMetricDescr("key_group_count", `Count of keys in key group`, []string{"db", "group", "key_group"})
+		e.metricDescriptions["key_group_memory_usage_bytes"] = e.newMetricDescr("key_group_memory_usage_bytes", `Total memory usage of key group in bytes`, []string{"db", "group", "key_group"})
+		e.metricDescriptions["number_of_distinct_key_groups"] = e.newMetricDescr("number_of_distinct_key_groups", `Number of distinct key groups`, []string{"db", "group"})
+	}
+
+	if err := e.checkMetricMappingCollisions(); err != nil {
+		return nil, fmt.Errorf("invalid metric mapping: %w", err)
 	}
 
 	if e.options.MetricsPath == "" {
@@ -604,17 +1069,19 @@ func NewRedisExporter(uri string, opts Options) (*Exporter, error) {
 	e.mux = http.NewServeMux()
 
 	if e.options.Registry != nil {
+		// static labels (e.g. from the targets file) are const labels of all metrics, see constLabels()
 		e.options.Registry.MustRegister(e)
-		e.mux.Handle(e.options.MetricsPath, promhttp.HandlerFor(
-			e.options.Registry, promhttp.HandlerOpts{ErrorHandling: promhttp.ContinueOnError},
-		))
+		// exemplars are only exposed in the OpenMetrics format
+		e.mux.Handle(e.options.MetricsPath, e.cancelOnDisconnect(e.metricsHandler(e.options.Registry, e.tracer != nil, e.redisAddr)))
 
 		if !e.options.RedisMetricsOnly {
+			buildInfoLabels := []string{"version", "commit_sha", "build_date", "golang_version"}
 			buildInfoCollector := prometheus.NewGaugeVec(prometheus.GaugeOpts{
-				Namespace: opts.Namespace,
-				Name:      "exporter_build_info",
-				Help:      "redis exporter build_info",
-			}, []string{"version", "commit_sha", "build_date", "golang_version"})
+				Namespace:   opts.Namespace,
+				Name:        "exporter_build_info",
+				Help:        "redis exporter build_info",
+				ConstLabels: constLabels(opts.ConstLabels, haReplicaLabels(opts.HAReplicaName), buildInfoLabels),
+			}, buildInfoLabels)
 			buildInfoCollector.WithLabelValues(e.buildInfo.Version, e.buildInfo.CommitSha, e.buildInfo.Date, runtime.Version()).Set(1)
 			e.options.Registry.MustRegister(buildInfoCollector)
 		}
@@ -623,57 +1090,119 @@ func NewRedisExporter(uri string, opts Options) (*Exporter, error) {
 	e.mux.HandleFunc("/", e.indexHandler)
 	e.mux.HandleFunc("/scrape", e.scrapeHandler)
 	e.mux.HandleFunc("/discover-cluster-nodes", e.discoverClusterNodesHandler)
-	e.mux.HandleFunc("/health", e.healthHandler)
-	e.mux.HandleFunc("/-/reload", e.reloadPwdFile)
+	if opts.AdminListenAddress != "" {
+		e.adminMux = http.NewServeMux()
+		e.registerAdminHandlers(e.adminMux)
+		e.registerDebugHandlers(e.adminMux)
+	} else {
+		e.registerAdminHandlers(e.mux)
+	}
+
+	if len(opts.Tenants) > 0 {
+		if err := e.initTenants(); err != nil {
+			return nil, err
+		}
+	}
 
 	return e, nil
 }
 
 // Describe outputs Redis metric descriptions.
 func (e *Exporter) Describe(ch chan<- *prometheus.Desc) {
+	e.metricDescriptionsMtx.RLock()
+	descs := make([]*prometheus.Desc, 0, len(e.metricDescriptions))
 	for _, desc := range e.metricDescriptions {
+		descs = append(descs, desc)
+	}
+	e.metricDescriptionsMtx.RUnlock()
+	for _, desc := range descs {
 		ch <- desc
 	}
 
 	for _, v := range e.metricMapGauges {
-		ch <- newMetricDescr(e.options.Namespace, v, v+" metric", nil)
+		ch <- e.newMetricDescr(v, v+" metric", nil)
 	}
 
 	for _, v := range e.metricMapCounters {
-		ch <- newMetricDescr(e.options.Namespace, v, v+" metric", nil)
+		ch <- e.newMetricDescr(v, v+" metric", nil)
 	}
 
 	ch <- e.totalScrapes.Desc()
 	ch <- e.scrapeDuration.Desc()
 	ch <- e.targetScrapeRequestErrors.Desc()
+	e.targetScrapeRequestsRateLimited.Describe(ch)
 }
 
 // Collect fetches new metrics from the RedisHost and updates the appropriate metrics.
 func (e *Exporter) Collect(ch chan<- prometheus.Metric) {
+	if e.validateSeriesEnabled() {
+		e.collectValidated(ch)
+		return
+	}
+	e.collect(ch)
+}
+
+func (e *Exporter) collect(ch chan<- prometheus.Metric) {
+	// started before taking the lock so the watchdog notices collections waiting for it
+	defer e.inflight.begin(e.redisAddr, time.Now())()
+
 	e.Lock()
 	defer e.Unlock()
 	e.totalScrapes.Inc()
 
+	e.startScrapeLogging()
+	defer e.endScrapeLogging()
+
 	if e.redisAddr != "" {
 		startTime := time.Now()
 		var up float64
-		if err := e.scrapeRedisHost(ch); err != nil {
+		e.phaseTimer = newScrapePhaseTimer()
+		clear(e.labelValueHashes)
+		ctx, cancel := e.scrapeRequests.context()
+		e.startScrapeTrace(ctx)
+		e.restoreCounterState()
+		e.scrapeCtx = ctx
+		err := e.scrapeRedisHostOrStale(ch)
+		cancel()
+		e.scrapeCtx = nil
+		if errors.Is(err, errScrapeCancelled) {
+			e.logger().Infof("scrape of %s cancelled, all scrapers disconnected", redactedAddr(e.redisAddr))
+		}
+		e.flushSeriesGuard(ch)
+		e.registerScrapePhaseMetrics(ch)
+		e.phaseTimer = nil
+		if err != nil {
 			e.registerConstMetricGauge(ch, "exporter_last_scrape_error", 1.0, fmt.Sprintf("%s", err))
+			e.registerConstMetricGauge(ch, "exporter_last_scrape_error_kind", 1.0, errorKind(err))
 		} else {
 			up = 1
 			e.registerConstMetricGauge(ch, "exporter_last_scrape_error", 0, "")
+			e.lastScrapes.record(e.redisAddr, time.Now())
 		}
+		e.registerLastSuccessfulScrape(ch)
+		e.registerResponseStats(ch)
 
 		e.registerConstMetricGauge(ch, "up", up)
 
+		spanContext := e.endScrapeTrace(err)
+
 		took := time.Since(startTime).Seconds()
-		e.scrapeDuration.Observe(took)
+		e.observeScrapeDuration(took, spanContext)
 		e.registerConstMetricGauge(ch, "exporter_last_scrape_duration_seconds", took)
 	}
 
+	if e.scheduler != nil {
+		e.scheduler.collectMetrics(ch)
+	}
+	e.registerTargetInventory(ch)
+	e.registerWatchdogMetrics(ch)
+	e.registerConfigReloadMetrics(ch)
+	e.registerLeaderElectionMetrics(ch)
+
 	ch <- e.totalScrapes
 	ch <- e.scrapeDuration
 	ch <- e.targetScrapeRequestErrors
+	e.targetScrapeRequestsRateLimited.Collect(ch)
 }
 
 func (e *Exporter) extractConfigMetrics(ch chan<- prometheus.Metric, config []interface{}) (dbCount int, err error) {
@@ -681,18 +1210,20 @@ func (e *Exporter) extractConfigMetrics(ch chan<- prometheus.Metric, config []in
 		return 0, fmt.Errorf("invalid config: %#v", config)
 	}
 
+	configValues := make(map[string]string, len(config)/2)
 	for pos := 0; pos < len(config)/2; pos++ {
 		strKey, err := redis.String(config[pos*2], nil)
 		if err != nil {
-			log.Errorf("invalid config key name, err: %s, skipped", err)
+			e.logger().Errorf("invalid config key name, err: %s, skipped", err)
 			continue
 		}
 
 		strVal, err := redis.String(config[pos*2+1], nil)
 		if err != nil {
-			log.Debugf("invalid config value for key name %s, err: %s, skipped", strKey, err)
+			e.logger().Debugf("invalid config value for key name %s, err: %s, skipped", strKey, err)
 			continue
 		}
+		configValues[strKey] = strVal
 
 		if strKey == "databases" {
 			if dbCount, err = strconv.Atoi(strVal); err != nil {
@@ -700,7 +1231,7 @@ func (e *Exporter) extractConfigMetrics(ch chan<- prometheus.Metric, config []in
 			}
 		}
 
-		if e.options.InclConfigMetrics {
+		if e.options.InclConfigMetrics || e.configMetricsInclude[strKey] {
 			if redact := map[string]bool{
 				"masterauth":               true,
 				"requirepass":              true,
@@ -742,86 +1273,135 @@ func (e *Exporter) extractConfigMetrics(ch chan<- prometheus.Metric, config []in
 			}
 		}
 	}
+
+	if len(e.options.ExpectedConfig) > 0 {
+		e.extractConfigDriftMetrics(ch, configValues)
+	}
+	e.extractLatencyMonitorMetrics(ch, configValues)
+	e.extractRDBChannelMetrics(ch, configValues)
 	return
 }
 
 // getKeyOperationConnection returns the appropriate Redis connection for key-based operations.
 // For cluster mode, it returns a cluster connection; otherwise, it returns the provided connection.
+// Unix sockets can't be used to bootstrap a cluster client so they fall back to the provided connection.
 func (e *Exporter) getKeyOperationConnection(defaultConn redis.Conn) (redis.Conn, error) {
 	if e.options.IsCluster {
-		return e.connectToRedisCluster()
+		if isUnixSocketAddr(e.redisAddr) {
+			e.logger().Debugf("unix socket address %s, using node connection for key operations", e.redisAddr)
+			return defaultConn, nil
+		}
+		c, err := e.connectToRedisCluster()
+		if err != nil {
+			return nil, err
+		}
+		return e.withScrapeContext(c), nil
 	}
 	return defaultConn, nil
 }
 
 func (e *Exporter) scrapeRedisHost(ch chan<- prometheus.Metric) error {
-	defer log.Debugf("scrapeRedisHost() done")
+	defer e.logger().Debugf("scrapeRedisHost() done")
 
+	e.startCollector("connection")
 	startTime := time.Now()
-	c, err := e.connectToRedis()
+	e.dialTimer = &dialTimer{}
+	c, err := e.connectToRedisWithRetry()
+	e.phaseTimer.addDial(e.dialTimer)
+	e.traceDial(e.dialTimer)
+	e.dialTimer = nil
 	connectTookSeconds := time.Since(startTime).Seconds()
 	e.registerConstMetricGauge(ch, "exporter_last_scrape_connect_time_seconds", connectTookSeconds)
 
-	if err != nil {
-		var redactedAddr string
-		if redisURL, err2 := url.Parse(e.redisAddr); err2 != nil {
-			log.Debugf("url.Parse( %s ) err: %s", e.redisAddr, err2)
-			redactedAddr = "<redacted>"
-		} else {
-			redactedAddr = redisURL.Redacted()
+	if e.circuitBreaker != nil {
+		circuitOpen := 0.0
+		if errors.Is(err, errCircuitOpen) {
+			circuitOpen = 1
 		}
-		log.Errorf("Couldn't connect to redis instance (%s)", redactedAddr)
-		log.Debugf("connectToRedis( %s ) err: %s", e.redisAddr, err)
+		e.registerConstMetricGauge(ch, "exporter_circuit_breaker_open", circuitOpen)
+	}
+
+	if errors.Is(err, errCircuitOpen) {
+		e.logger().Debugf("circuit breaker open for %s, skipping scrape", e.redisAddr)
+		return err
+	}
+
+	if err != nil {
+		e.logger().Errorf("Couldn't connect to redis instance (%s)", redactedAddr(e.redisAddr))
+		e.logger().Debugf("connectToRedis( %s ) err: %s", e.redisAddr, err)
 		return err
 	}
+	c = e.withCommandLogging(e.withScrapeContext(c))
 	defer c.Close()
 
-	log.Debugf("connected to: %s", e.redisAddr)
-	log.Debugf("connecting took %f seconds", connectTookSeconds)
+	e.logger().Debugf("connected to: %s", e.redisAddr)
+	e.logger().Debugf("connecting took %f seconds", connectTookSeconds)
+
+	if e.options.ViaProxy {
+		return e.scrapeViaProxy(ch, c)
+	}
 
 	if e.options.PingOnConnect {
 		startTime := time.Now()
 
 		if _, err := doRedisCmd(c, "PING"); err != nil {
-			log.Errorf("Couldn't PING server, err: %s", err)
+			e.logger().Errorf("Couldn't PING server, err: %s", err)
 		} else {
 			pingTookSeconds := time.Since(startTime).Seconds()
 			e.registerConstMetricGauge(ch, "exporter_last_scrape_ping_time_seconds", pingTookSeconds)
-			log.Debugf("PING took %f seconds", pingTookSeconds)
+			e.logger().Debugf("PING took %f seconds", pingTookSeconds)
 		}
 	}
 
 	if e.options.SetClientName {
-		if _, err := doRedisCmd(c, "CLIENT", "SETNAME", "redis_exporter"); err != nil {
-			log.Errorf("Couldn't set client name, err: %s", err)
-		}
+		e.setClientName(c)
 	}
 
+	// providers that forbid SELECT only get db0 scraped instead of failing the key collectors
+	selectFree := e.selectFree(c)
+	if selectFree {
+		c = &selectFreeConn{Conn: c}
+		e.registerConstMetricGauge(ch, "exporter_select_free_mode", 1)
+	} else {
+		e.registerConstMetricGauge(ch, "exporter_select_free_mode", 0)
+	}
+
+	if e.clientNoTouch(c) {
+		e.registerConstMetricGauge(ch, "exporter_client_no_touch", 1)
+	} else {
+		e.registerConstMetricGauge(ch, "exporter_client_no_touch", 0)
+	}
+
+	e.startCollector("config")
 	dbCount := 0
 	if e.options.ConfigCommandName == "-" {
-		log.Debugf("Skipping extractConfigMetrics()")
+		e.logger().Debugf("Skipping extractConfigMetrics()")
 	} else {
 		if config, err := redis.Values(doRedisCmd(c, e.options.ConfigCommandName, "GET", "*")); err == nil {
 			dbCount, err = e.extractConfigMetrics(ch, config)
 			if err != nil {
-				log.Errorf("Redis extractConfigMetrics() err: %s", err)
+				e.logger().Errorf("Redis extractConfigMetrics() err: %s", err)
 				return err
 			}
 		} else {
-			log.Debugf("Redis CONFIG err: %s", err)
+			e.logger().Debugf("Redis CONFIG err: %s", err)
 		}
 	}
 
+	e.startCollector("info")
 	infoAll, err := redis.String(doRedisCmd(c, "INFO", "ALL"))
 	if err != nil || infoAll == "" {
-		log.Debugf("Redis INFO ALL err: %s", err)
+		e.logger().Debugf("Redis INFO ALL err: %s", err)
 		infoAll, err = redis.String(doRedisCmd(c, "INFO"))
 		if err != nil {
-			log.Errorf("Redis INFO err: %s", err)
+			e.logger().Errorf("Redis INFO err: %s", err)
 			return err
 		}
 	}
-	log.Debugf("Redis INFO ALL result: [%#v]", infoAll)
+	e.logger().Debugf("Redis INFO ALL result: [%#v]", infoAll)
+	if e.isGarnet(infoAll) {
+		infoAll = garnetInfo(infoAll)
+	}
 
 	if strings.Contains(infoAll, "cluster_enabled:1") {
 		if clusterInfo, err := redis.String(doRedisCmd(c, "CLUSTER", "INFO")); err == nil {
@@ -829,8 +1409,12 @@ func (e *Exporter) scrapeRedisHost(ch chan<- prometheus.Metric) error {
 
 			// in cluster mode Redis only supports one database, so no extra DB number padding needed
 			dbCount = 1
+
+			if e.options.AssumeReadonlyReplica {
+				e.enableReadonly(c)
+			}
 		} else {
-			log.Errorf("Redis CLUSTER INFO err: %s", err)
+			e.logger().Errorf("Redis CLUSTER INFO err: %s", err)
 		}
 	} else if dbCount == 0 {
 		// in non-cluster mode, if dbCount is zero, then "CONFIG" failed to retrieve a valid
@@ -839,22 +1423,39 @@ func (e *Exporter) scrapeRedisHost(ch chan<- prometheus.Metric) error {
 		dbCount = 16
 	}
 
-	log.Debugf("dbCount: %d", dbCount)
+	e.logger().Debugf("dbCount: %d", dbCount)
 
-	role := e.extractInfoMetrics(ch, infoAll, dbCount)
+	fields := e.extractInfoMetrics(ch, infoAll, dbCount)
+	role := fields.value("role")
+	e.detectCompatLevel(ch, fields)
+	e.extractDbsizeFallbackMetrics(ch, c, infoAll)
 
-	if !e.options.ExcludeLatencyHistogramMetrics {
+	if e.options.WaitProbeKey != "" && role == "master" && !e.skipReadonlyUnsafe(ch, "wait_probe") {
+		e.startCollector("wait_probe")
+		e.extractWaitProbeMetrics(ch, c)
+	}
+
+	if !e.options.ExcludeLatencyHistogramMetrics && e.supportsFlavor("latency") {
+		e.startCollector("latency")
 		e.extractLatencyMetrics(ch, infoAll, c)
 	}
 
+	if e.options.InclKernelWarnings {
+		e.startCollector("kernel_warnings")
+		e.extractKernelWarningMetrics(ch, c)
+	}
+
+	e.startCollector("keys")
 	// skip these metrics for master if SkipCheckKeysForRoleMaster is set
 	// (can help with reducing workload on the master node)
-	log.Debugf("checkKeys metric collection for role: %s  SkipCheckKeysForRoleMaster flag: %#v", role, e.options.SkipCheckKeysForRoleMaster)
-	if role == InstanceRoleSlave || !e.options.SkipCheckKeysForRoleMaster {
+	e.logger().Debugf("checkKeys metric collection for role: %s  SkipCheckKeysForRoleMaster flag: %#v", role, e.options.SkipCheckKeysForRoleMaster)
+	if e.skipNotLeader("keys") {
+		// the leader of the exporters sharing the leader election key runs them
+	} else if role == InstanceRoleSlave || !e.options.SkipCheckKeysForRoleMaster {
 		// For key-based operations, use cluster connection if in cluster mode
 		keyConn, err := e.getKeyOperationConnection(c)
 		if err != nil {
-			log.Errorf("failed to get key operation connection: %s", err)
+			e.logger().Errorf("failed to get key operation connection: %s", err)
 		} else {
 			defer func() {
 				if keyConn != c {
@@ -863,55 +1464,95 @@ func (e *Exporter) scrapeRedisHost(ch chan<- prometheus.Metric) error {
 			}()
 
 			if err := e.extractCheckKeyMetrics(ch, keyConn); err != nil {
-				log.Errorf("extractCheckKeyMetrics() err: %s", err)
+				e.logger().Errorf("extractCheckKeyMetrics() err: %s", err)
 			}
 
 			e.extractCountKeysMetrics(ch, keyConn)
 
-			e.extractStreamMetrics(ch, keyConn)
+			e.startCollector("key_sample")
+			e.extractKeySampleMetrics(ch, keyConn)
+
+			e.startCollector("streams")
+			if e.supportsCompat(compatLevelNoXinfo, "streams") && e.supportsFlavor("streams") {
+				e.extractStreamMetrics(ch, keyConn)
+			}
 		}
 	} else {
-		log.Infof("skipping checkKeys metrics, role: %s  flag: %#v", role, e.options.SkipCheckKeysForRoleMaster)
+		e.logger().Infof("skipping checkKeys metrics, role: %s  flag: %#v", role, e.options.SkipCheckKeysForRoleMaster)
 	}
 
+	e.startCollector("slowlog")
 	e.extractSlowLogMetrics(ch, c)
 
+	e.startCollector("key_groups")
 	// Key groups also need cluster connection for key operations
-	keyGroupConn, err := e.getKeyOperationConnection(c)
-	if err != nil {
-		log.Errorf("failed to get key operation connection for key groups: %s", err)
+	if e.keyGroupsConfigured() && e.skipReadonlyUnsafe(ch, "key_groups") {
+		// the key group collectors run a Lua script
+	} else if e.keyGroupsConfigured() && e.skipNotLeader("key_groups") {
+		// the leader of the exporters sharing the leader election key runs them
+	} else if keyGroupConn, err := e.getKeyOperationConnection(c); err != nil {
+		e.logger().Errorf("failed to get key operation connection for key groups: %s", err)
 	} else {
 		defer func() {
 			if keyGroupConn != c {
 				keyGroupConn.Close()
 			}
 		}()
-		e.extractKeyGroupMetrics(ch, keyGroupConn, dbCount)
+		keyGroupsDbCount := dbCount
+		if selectFree {
+			keyGroupsDbCount = min(dbCount, 1)
+		}
+		e.extractKeyGroupMetrics(ch, keyGroupConn, keyGroupsDbCount)
+	}
+
+	if e.options.IsCluster {
+		e.registerClusterRedirectMetrics(ch)
 	}
 
 	if strings.Contains(infoAll, "# Sentinel") {
+		e.startCollector("sentinel")
 		e.extractSentinelMetrics(ch, c)
 
 		e.extractSentinelConfig(ch, c)
 	}
 
-	if e.options.ExportClientList {
-		e.extractConnectedClientMetrics(ch, c)
+	if e.options.ExportClientList || e.options.InclClientIdleMetrics {
+		e.startCollector("clients")
+		e.extractConnectedClientMetrics(ch, c, fields)
 	}
 
 	if e.options.IsTile38 {
+		e.startCollector("tile38")
 		e.extractTile38Metrics(ch, c)
 	}
 
-	if e.options.InclModulesMetrics {
+	if (e.options.InclModulesMetrics || len(e.expectedModules) > 0) && e.supportsFlavor("modules") {
+		e.startCollector("modules")
 		e.extractModulesMetrics(ch, c)
 	}
 
-	if e.options.InclSearchIndexesMetrics {
+	if e.options.InclPubSubShardMetrics && e.supportsFlavor("pubsub_shard") {
+		e.startCollector("pubsub_shard")
+		e.extractPubSubShardMetrics(ch, c)
+	}
+
+	if e.options.InclACLLogMetrics && e.supportsFlavor("acl_log") {
+		e.startCollector("acl_log")
+		e.extractACLLogMetrics(ch, c)
+	}
+
+	if e.options.InclSearchIndexesMetrics && e.supportsFlavor("search_indexes") {
+		e.startCollector("search_indexes")
 		e.extractSearchIndexesMetrics(ch, c)
 	}
 
-	if len(e.options.LuaScript) > 0 {
+	if len(e.options.Probes) > 0 {
+		e.startCollector("probes")
+		e.extractProbeMetrics(ch, c)
+	}
+
+	if len(e.options.LuaScript) > 0 && !e.skipReadonlyUnsafe(ch, "lua") {
+		e.startCollector("lua")
 		for filename, script := range e.options.LuaScript {
 			if err := e.extractLuaScriptMetrics(ch, c, filename, script); err != nil {
 				return err
@@ -21,6 +21,8 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	dto "github.com/prometheus/client_model/go"
 	log "github.com/sirupsen/logrus"
+
+	"github.com/oliver006/redis_exporter/exporter/exportertest"
 )
 
 const (
@@ -342,6 +344,40 @@ func TestIncludeConfigMetrics(t *testing.T) {
 	}
 }
 
+func TestConfigMetricsInclude(t *testing.T) {
+	e, _ := NewRedisExporter("", Options{Namespace: "test", ConfigMetricsInclude: "maxmemory-policy, appendonly"})
+
+	config := []interface{}{
+		[]byte("maxmemory-policy"), []byte("allkeys-lru"),
+		[]byte("appendonly"), []byte("no"),
+		[]byte("save"), []byte("3600 1"),
+	}
+
+	chM := make(chan prometheus.Metric)
+	go func() {
+		e.extractConfigMetrics(chM, config)
+		close(chM)
+	}()
+
+	keys := map[string]bool{}
+	for m := range chM {
+		if m.Desc() != e.mustFindMetricDescription("config_key_value") {
+			continue
+		}
+		got := &dto.Metric{}
+		m.Write(got)
+		for _, l := range got.GetLabel() {
+			if l.GetName() == "key" {
+				keys[l.GetValue()] = true
+			}
+		}
+	}
+
+	if len(keys) != 2 || !keys["maxmemory-policy"] || !keys["appendonly"] {
+		t.Errorf("unexpected config metrics: %#v", keys)
+	}
+}
+
 func TestClientOutputBufferLimitMetrics(t *testing.T) {
 	for _, class := range []string{
 		`normal`,
@@ -963,6 +999,42 @@ db1:keys=18,expires=13,avg_ttl=145372776312,subexpiry=0
 	}
 }
 
+func benchmarkScrape(b *testing.B, addr string) {
+	e, err := NewRedisExporter(addr, Options{Namespace: "test"})
+	if err != nil {
+		b.Fatalf("NewRedisExporter() err: %s", err)
+	}
+	ch := make(chan prometheus.Metric, 1024)
+	done := make(chan struct{})
+	go func() {
+		for range ch {
+		}
+		close(done)
+	}()
+
+	b.ReportAllocs()
+	for b.Loop() {
+		e.Collect(ch)
+	}
+	close(ch)
+	<-done
+}
+
+func BenchmarkScrape(b *testing.B) {
+	addr := os.Getenv("TEST_REDIS_URI")
+	if addr == "" {
+		b.Skipf("TEST_REDIS_URI not set - skipping")
+	}
+	benchmarkScrape(b, addr)
+}
+
+// BenchmarkScrapeFakeRedis measures the exporter side of a scrape, without the work of a real server
+func BenchmarkScrapeFakeRedis(b *testing.B) {
+	s := exportertest.NewServer(b)
+	s.SetInfo(benchmarkInfo)
+	benchmarkScrape(b, s.Addr())
+}
+
 func init() {
 	ll := strings.ToLower(os.Getenv("LOG_LEVEL"))
 	if pl, err := log.ParseLevel(ll); err == nil {
@@ -0,0 +1,287 @@
+// Package exportertest provides a fake Redis server for testing collectors and
+// programs embedding the exporter without running Redis
+package exportertest
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Status is a simple string reply like OK
+type Status string
+
+// Error is an error reply like "ERR unknown command"
+type Error string
+
+// Func computes the reply of a command from its arguments, the command name excluded
+type Func func(args []string) interface{}
+
+// DefaultInfo is the reply to INFO of a new server, a standalone master without keys
+const DefaultInfo = "# Server\r\nredis_version:7.2.4\r\nredis_mode:standalone\r\nos:Linux\r\ntcp_port:6379\r\nuptime_in_seconds:100\r\n\r\n" +
+	"# Clients\r\nconnected_clients:1\r\nmaxclients:10000\r\n\r\n" +
+	"# Memory\r\nused_memory:1048576\r\nmaxmemory:0\r\nmaxmemory_policy:noeviction\r\n\r\n" +
+	"# Stats\r\ntotal_connections_received:1\r\ntotal_commands_processed:1\r\n\r\n" +
+	"# Replication\r\nrole:master\r\nconnected_slaves:0\r\nmaster_repl_offset:0\r\n\r\n" +
+	"# Keyspace\r\n"
+
+// Server is a fake Redis server speaking RESP2 on a local TCP port. Replies are looked
+// up by the command with its arguments joined by spaces (e.g. "CONFIG GET maxmemory"),
+// then by the command name, commands without reply get an "unknown command" error.
+// Replies can be strings (bulk strings), []byte, Status, Error, int, int64, float64,
+// nil (null bulk string), []string, []interface{} of these or a Func
+type Server struct {
+	lis net.Listener
+
+	mu       sync.Mutex
+	replies  map[string]interface{}
+	commands []string
+	conns    map[net.Conn]struct{}
+	wg       sync.WaitGroup
+}
+
+// NewServer starts a server that replies to PING, ECHO, SELECT, AUTH, CLIENT SETNAME,
+// CLIENT SETINFO, INFO, CONFIG GET and LATENCY, it's closed when the test ends
+func NewServer(tb testing.TB) *Server {
+	tb.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		tb.Fatalf("exportertest: couldn't listen, err: %s", err)
+	}
+
+	s := &Server{lis: lis, replies: map[string]interface{}{}, conns: map[net.Conn]struct{}{}}
+	s.Set("PING", Status("PONG"))
+	s.Set("ECHO", Func(func(args []string) interface{} {
+		if len(args) != 1 {
+			return Error("ERR wrong number of arguments for 'echo' command")
+		}
+		return args[0]
+	}))
+	s.Set("SELECT", Status("OK"))
+	s.Set("AUTH", Status("OK"))
+	s.Set("CLIENT SETNAME", Func(func([]string) interface{} { return Status("OK") }))
+	s.Set("CLIENT SETINFO", Func(func([]string) interface{} { return Status("OK") }))
+	s.SetInfo(DefaultInfo)
+	s.Set("CONFIG GET", Func(func([]string) interface{} { return []interface{}{} }))
+	s.Set("LATENCY LATEST", []interface{}{})
+	s.Set("LATENCY HISTOGRAM", []interface{}{})
+
+	s.wg.Add(1)
+	go s.serve()
+	tb.Cleanup(s.Close)
+	return s
+}
+
+// Addr returns the redis:// URI of the server
+func (s *Server) Addr() string {
+	return "redis://" + s.lis.Addr().String()
+}
+
+// Set sets the reply to cmd, either a command with all its arguments or a command name,
+// names of commands with subcommands (e.g. CLIENT LIST) include the subcommand
+func (s *Server) Set(cmd string, reply interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.replies[normalize(strings.Fields(cmd))] = reply
+}
+
+// SetInfo sets the reply to INFO, regardless of the sections asked for
+func (s *Server) SetInfo(info string) {
+	s.Set("INFO", Func(func([]string) interface{} { return info }))
+}
+
+// Commands returns the commands received so far with their arguments joined by spaces
+func (s *Server) Commands() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.commands...)
+}
+
+// Close stops the server and closes all connections
+func (s *Server) Close() {
+	_ = s.lis.Close()
+	s.mu.Lock()
+	for c := range s.conns {
+		_ = c.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		c, err := s.lis.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns[c] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(c)
+			s.mu.Lock()
+			delete(s.conns, c)
+			s.mu.Unlock()
+			_ = c.Close()
+		}()
+	}
+}
+
+func (s *Server) handle(c net.Conn) {
+	r := bufio.NewReader(c)
+	w := bufio.NewWriter(c)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+		writeReply(w, s.reply(args))
+		// pipelined commands are answered together
+		if r.Buffered() == 0 {
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// reply looks up the reply for args, the longest matching prefix of the
+// command wins so "CONFIG GET maxmemory" is preferred over "CONFIG GET"
+func (s *Server) reply(args []string) interface{} {
+	s.mu.Lock()
+	s.commands = append(s.commands, strings.Join(args, " "))
+	var reply interface{}
+	n := len(args)
+	for ; n > 0; n-- {
+		if r, ok := s.replies[normalize(args[:n])]; ok {
+			reply = r
+			break
+		}
+	}
+	s.mu.Unlock()
+
+	if n == 0 {
+		return Error(fmt.Sprintf("ERR unknown command '%s'", args[0]))
+	}
+	if fn, ok := reply.(Func); ok {
+		return fn(args[n:])
+	}
+	return reply
+}
+
+// containerCommands are the commands whose first argument is a subcommand
+var containerCommands = map[string]bool{
+	"ACL": true, "CLIENT": true, "CLUSTER": true, "COMMAND": true, "CONFIG": true,
+	"FUNCTION": true, "LATENCY": true, "MEMORY": true, "MODULE": true, "OBJECT": true,
+	"PUBSUB": true, "SCRIPT": true, "SLOWLOG": true, "XINFO": true,
+}
+
+// normalize upper cases the command name and the subcommand of containerCommands,
+// the other arguments like keys are case-sensitive
+func normalize(args []string) string {
+	res := append([]string(nil), args...)
+	if len(res) > 0 {
+		res[0] = strings.ToUpper(res[0])
+		if len(res) > 1 && containerCommands[res[0]] {
+			res[1] = strings.ToUpper(res[1])
+		}
+	}
+	return strings.Join(res, " ")
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		// inline command e.g. from redis-cli or telnet
+		return strings.Fields(line), nil
+	}
+
+	n, err := strconv.Atoi(line[1:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid multibulk length %q", line)
+	}
+	args := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		line, err := readLine(r)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(line, "$") {
+			return nil, fmt.Errorf("expected a bulk string, got %q", line)
+		}
+		size, err := strconv.Atoi(line[1:])
+		if err != nil || size < 0 {
+			return nil, fmt.Errorf("invalid bulk length %q", line)
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args = append(args, string(buf[:size]))
+	}
+	return args, nil
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasSuffix(line, "\r\n") {
+		return "", errors.New("line not terminated by CRLF")
+	}
+	return line[:len(line)-2], nil
+}
+
+func writeReply(w *bufio.Writer, reply interface{}) {
+	switch r := reply.(type) {
+	case nil:
+		w.WriteString("$-1\r\n")
+	case Status:
+		w.WriteString("+" + string(r) + "\r\n")
+	case Error:
+		w.WriteString("-" + string(r) + "\r\n")
+	case int:
+		w.WriteString(":" + strconv.Itoa(r) + "\r\n")
+	case int64:
+		w.WriteString(":" + strconv.FormatInt(r, 10) + "\r\n")
+	case float64:
+		writeBulk(w, strconv.FormatFloat(r, 'f', -1, 64))
+	case string:
+		writeBulk(w, r)
+	case []byte:
+		writeBulk(w, string(r))
+	case []string:
+		w.WriteString("*" + strconv.Itoa(len(r)) + "\r\n")
+		for _, s := range r {
+			writeBulk(w, s)
+		}
+	case []interface{}:
+		w.WriteString("*" + strconv.Itoa(len(r)) + "\r\n")
+		for _, v := range r {
+			writeReply(w, v)
+		}
+	default:
+		w.WriteString(fmt.Sprintf("-ERR exportertest: unsupported reply type %T\r\n", reply))
+	}
+}
+
+func writeBulk(w *bufio.Writer, s string) {
+	w.WriteString("$" + strconv.Itoa(len(s)) + "\r\n" + s + "\r\n")
+}
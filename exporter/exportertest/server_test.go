@@ -0,0 +1,118 @@
+package exportertest_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/oliver006/redis_exporter/exporter"
+	"github.com/oliver006/redis_exporter/exporter/exportertest"
+)
+
+func TestServerReplies(t *testing.T) {
+	s := exportertest.NewServer(t)
+	s.Set("GET foo", "bar")
+	s.Set("HLEN", 3)
+	s.Set("CONFIG GET maxmemory", []string{"maxmemory", "100"})
+	s.Set("LRANGE", []interface{}{"a", nil, exportertest.Status("OK")})
+	s.Set("FAIL", exportertest.Error("ERR broken"))
+	s.Set("INCRBY", exportertest.Func(func(args []string) interface{} { return int64(len(args)) }))
+
+	c, err := redis.DialURL(s.Addr())
+	if err != nil {
+		t.Fatalf("couldn't connect, err: %s", err)
+	}
+	defer c.Close()
+
+	if v, err := redis.String(c.Do("PING")); err != nil || v != "PONG" {
+		t.Errorf("PING = %q, %v", v, err)
+	}
+	if v, err := redis.String(c.Do("get", "foo")); err != nil || v != "bar" {
+		t.Errorf("GET foo = %q, %v", v, err)
+	}
+	if _, err := c.Do("GET", "other"); err == nil || !strings.Contains(err.Error(), "unknown command") {
+		t.Errorf("GET other err = %v, expected an unknown command error", err)
+	}
+	// keys are case-sensitive, subcommands aren't
+	if _, err := c.Do("GET", "FOO"); err == nil {
+		t.Errorf("GET FOO err = %v, expected an unknown command error", err)
+	}
+	if v, err := redis.Int(c.Do("HLEN", "h")); err != nil || v != 3 {
+		t.Errorf("HLEN = %d, %v", v, err)
+	}
+	if v, err := redis.Strings(c.Do("CONFIG", "GET", "maxmemory")); err != nil || len(v) != 2 || v[1] != "100" {
+		t.Errorf("CONFIG GET maxmemory = %v, %v", v, err)
+	}
+	if v, err := redis.Strings(c.Do("config", "get", "maxmemory")); err != nil || len(v) != 2 {
+		t.Errorf("config get maxmemory = %v, %v", v, err)
+	}
+	if v, err := redis.Strings(c.Do("CONFIG", "GET", "maxclients")); err != nil || len(v) != 0 {
+		t.Errorf("CONFIG GET maxclients = %v, %v", v, err)
+	}
+	if v, err := redis.Values(c.Do("LRANGE", "l", 0, -1)); err != nil || len(v) != 3 || v[1] != nil || v[2] != "OK" {
+		t.Errorf("LRANGE = %v, %v", v, err)
+	}
+	if _, err := c.Do("FAIL"); err == nil || err.Error() != "ERR broken" {
+		t.Errorf("FAIL err = %v", err)
+	}
+	if v, err := redis.Int(c.Do("INCRBY", "k", 5)); err != nil || v != 2 {
+		t.Errorf("INCRBY = %d, %v", v, err)
+	}
+
+	// pipelined
+	c.Send("GET", "foo")
+	c.Send("HLEN", "h")
+	c.Flush()
+	if v, err := redis.String(c.Receive()); err != nil || v != "bar" {
+		t.Errorf("pipelined GET foo = %q, %v", v, err)
+	}
+	if v, err := redis.Int(c.Receive()); err != nil || v != 3 {
+		t.Errorf("pipelined HLEN = %d, %v", v, err)
+	}
+
+	cmds := s.Commands()
+	if len(cmds) == 0 || cmds[len(cmds)-1] != "HLEN h" {
+		t.Errorf("unexpected commands: %v", cmds)
+	}
+}
+
+func TestServerScrape(t *testing.T) {
+	s := exportertest.NewServer(t)
+	s.SetInfo(exportertest.DefaultInfo + "db0:keys=11,expires=1,avg_ttl=0\r\n")
+
+	e, err := exporter.NewRedisExporter(s.Addr(), exporter.Options{Namespace: "test"})
+	if err != nil {
+		t.Fatalf("NewRedisExporter() err: %s", err)
+	}
+
+	ch := make(chan prometheus.Metric)
+	go func() {
+		e.Collect(ch)
+		close(ch)
+	}()
+
+	found := map[string]bool{}
+	for m := range ch {
+		desc := m.Desc().String()
+		for _, name := range []string{"test_up", "test_db_keys", "test_connected_clients"} {
+			if strings.Contains(desc, `"`+name+`"`) {
+				found[name] = true
+			}
+		}
+		if strings.Contains(desc, `"test_up"`) {
+			d := &dto.Metric{}
+			m.Write(d)
+			if v := d.GetGauge().GetValue(); v != 1 {
+				t.Errorf("test_up = %f, expected 1", v)
+			}
+		}
+	}
+	for _, name := range []string{"test_up", "test_db_keys", "test_connected_clients"} {
+		if !found[name] {
+			t.Errorf("metric %s not found", name)
+		}
+	}
+}
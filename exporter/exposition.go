@@ -0,0 +1,53 @@
+package exporter
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/common/expfmt"
+)
+
+// expositionFormats are the formats the metrics can be forced to with ExpositionFormat, by
+// default the format is negotiated with the Accept header of the scraper, e.g. Prometheus
+// asks for protobuf when it scrapes native histograms
+var expositionFormats = map[string]expfmt.Format{
+	"text":        expfmt.NewFormat(expfmt.TypeTextPlain),
+	"protobuf":    expfmt.NewFormat(expfmt.TypeProtoDelim),
+	"openmetrics": expfmt.NewFormat(expfmt.TypeOpenMetrics),
+}
+
+// expositionFormatNames returns the names of the formats ExpositionFormat accepts
+func expositionFormatNames() []string {
+	names := make([]string, 0, len(expositionFormats))
+	for name := range expositionFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// metricsHandler serves the metrics of g in the format negotiated with the scraper or the
+// one forced by ExpositionFormat, OpenMetrics is only offered if enableOpenMetrics is set
+// (for the exemplars of the tracing) or it's forced. The size of the response is recorded
+// for the next collection of target, see response_stats.go
+func (e *Exporter) metricsHandler(g prometheus.Gatherer, enableOpenMetrics bool, target string) http.Handler {
+	opts := promhttp.HandlerOpts{
+		ErrorHandling:     promhttp.ContinueOnError,
+		EnableOpenMetrics: enableOpenMetrics || e.options.ExpositionFormat == "openmetrics",
+	}
+	format, forced := expositionFormats[e.options.ExpositionFormat]
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if forced {
+			r = r.Clone(r.Context())
+			r.Header.Set("Accept", string(format))
+		}
+
+		series := 0
+		cw := &countingResponseWriter{ResponseWriter: w}
+		promhttp.HandlerFor(countingGatherer(g, &series), opts).ServeHTTP(cw, r)
+		e.responseStats.record(target, responseStat{bytes: cw.bytes, series: series})
+	})
+}
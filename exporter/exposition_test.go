@@ -0,0 +1,60 @@
+package exporter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const (
+	acceptProtobuf    = "application/vnd.google.protobuf;proto=io.prometheus.client.MetricFamily;encoding=delimited;q=0.7,text/plain;version=0.0.4;q=0.3"
+	acceptOpenMetrics = "application/openmetrics-text;version=1.0.0,text/plain;version=0.0.4;q=0.5"
+	acceptText        = "text/plain;version=0.0.4"
+)
+
+func TestExpositionFormatNegotiation(t *testing.T) {
+	for _, tst := range []struct {
+		name   string
+		format string
+		accept string
+		want   string
+	}{
+		{name: "default text", accept: acceptText, want: "text/plain"},
+		{name: "no accept header", want: "text/plain"},
+		{name: "negotiated protobuf", accept: acceptProtobuf, want: "application/vnd.google.protobuf"},
+		{name: "openmetrics not offered", accept: acceptOpenMetrics, want: "text/plain"},
+		{name: "forced text", format: "text", accept: acceptProtobuf, want: "text/plain"},
+		{name: "forced protobuf", format: "protobuf", accept: acceptText, want: "application/vnd.google.protobuf"},
+		{name: "forced openmetrics", format: "openmetrics", accept: acceptText, want: "application/openmetrics-text"},
+	} {
+		t.Run(tst.name, func(t *testing.T) {
+			e, err := NewRedisExporter("", Options{Namespace: "test", ExpositionFormat: tst.format})
+			if err != nil {
+				t.Fatalf("NewRedisExporter() err: %s", err)
+			}
+			ts := httptest.NewServer(e)
+			defer ts.Close()
+
+			req, _ := http.NewRequest("GET", ts.URL+"/metrics", nil)
+			if tst.accept != "" {
+				req.Header.Set("Accept", tst.accept)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("request err: %s", err)
+			}
+			resp.Body.Close()
+
+			if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, tst.want) {
+				t.Errorf("expected content type %s, got: %s", tst.want, ct)
+			}
+		})
+	}
+}
+
+func TestExpositionFormatInvalid(t *testing.T) {
+	if _, err := NewRedisExporter("", Options{Namespace: "test", ExpositionFormat: "json"}); err == nil || !strings.Contains(err.Error(), "openmetrics, protobuf, text") {
+		t.Errorf("expected an error for an unknown exposition format, got: %v", err)
+	}
+}
@@ -0,0 +1,52 @@
+package exporter
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// lastScrapes keeps the time of the last successful scrape per target
+type lastScrapes struct {
+	sync.Mutex
+	ts map[string]time.Time
+}
+
+func newLastScrapes() *lastScrapes {
+	return &lastScrapes{ts: map[string]time.Time{}}
+}
+
+func (l *lastScrapes) record(target string, t time.Time) {
+	l.Lock()
+	defer l.Unlock()
+	l.ts[target] = t
+}
+
+func (l *lastScrapes) get(target string) (time.Time, bool) {
+	l.Lock()
+	defer l.Unlock()
+	t, ok := l.ts[target]
+	return t, ok
+}
+
+func (e *Exporter) registerLastSuccessfulScrape(ch chan<- prometheus.Metric) {
+	if t, ok := e.lastScrapes.get(e.redisAddr); ok {
+		e.registerConstMetricGauge(ch, "exporter_last_successful_scrape_timestamp_seconds", float64(t.UnixMilli())/1e3, redactedAddr(e.redisAddr))
+	}
+}
+
+// withCacheAge adds the age of cached metrics to mfs without modifying it
+func (e *Exporter) withCacheAge(mfs []*dto.MetricFamily, collectedAt time.Time) []*dto.MetricFamily {
+	name := prometheus.BuildFQName(e.options.Namespace, "", "exporter_cached_data_age_seconds")
+	help := "Age in seconds of metrics served from the background collection"
+	age := time.Since(collectedAt).Seconds()
+
+	return append(append([]*dto.MetricFamily{}, mfs...), &dto.MetricFamily{
+		Name:   &name,
+		Help:   &help,
+		Type:   dto.MetricType_GAUGE.Enum(),
+		Metric: []*dto.Metric{{Gauge: &dto.Gauge{Value: &age}}},
+	})
+}
@@ -0,0 +1,52 @@
+package exporter
+
+import (
+	"slices"
+	"strings"
+)
+
+// flavorUnsupportedCollectors are the collectors skipped per server flavor because the
+// flavor doesn't implement their commands or answers them in an incompatible format
+var flavorUnsupportedCollectors = map[string][]string{
+	// Garnet has no XINFO, MODULE LIST, ACL LOG, PUBSUB SHARDCHANNELS or FT._LIST and
+	// its LATENCY command only has its own HISTOGRAM subcommand
+	flavorGarnet: {"latency", "streams", "modules", "pubsub_shard", "acl_log", "search_indexes"},
+}
+
+// garnetInfoFields are the INFO fields of Garnet that mean the same as a Redis field,
+// they're exported under the Redis name unless Garnet reports that one as well
+var garnetInfoFields = map[string]string{
+	"total_found":               "keyspace_hits",
+	"total_notfound":            "keyspace_misses",
+	"proc_physical_memory_size": "used_memory_rss",
+}
+
+// isGarnet returns whether the INFO reply is from a Garnet server or is-garnet is set
+func (e *Exporter) isGarnet(info string) bool {
+	return e.options.IsGarnet || strings.Contains(info, "garnet_version:")
+}
+
+// garnetInfo renames the fields of garnetInfoFields in the INFO reply of a Garnet server
+func garnetInfo(info string) string {
+	lines := strings.Split(info, "\n")
+	for i, line := range lines {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		if name, ok := garnetInfoFields[key]; ok && !strings.Contains(info, "\n"+name+":") {
+			lines[i] = name + ":" + value
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// supportsFlavor returns true unless the collector is unsupported by the flavor of the
+// server of the current scrape, it logs which collector is skipped otherwise
+func (e *Exporter) supportsFlavor(collector string) bool {
+	if slices.Contains(flavorUnsupportedCollectors[e.serverFlavor], collector) {
+		e.logger().Debugf("skipping %s, not supported by %s", collector, e.serverFlavor)
+		return false
+	}
+	return true
+}
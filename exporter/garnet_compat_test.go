@@ -0,0 +1,75 @@
+package exporter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestGarnetInfo(t *testing.T) {
+	info := "# Server\r\nredis_version:7.4.0\r\ngarnet_version:1.0.60\r\n\r\n" +
+		"# Memory\r\nproc_physical_memory_size:4096\r\n\r\n" +
+		"# Stats\r\ntotal_found:42\r\ntotal_notfound:7\r\nkeyspace_misses:9\r\n"
+
+	e, _ := NewRedisExporter("redis://localhost:6379", Options{Namespace: "test"})
+	if !e.isGarnet(info) {
+		t.Fatalf("expected the INFO to be detected as Garnet")
+	}
+	if e.isGarnet("# Server\r\nredis_version:7.4.0\r\n") {
+		t.Errorf("didn't expect Redis to be detected as Garnet")
+	}
+
+	mapped := garnetInfo(info)
+	for _, want := range []string{"\nkeyspace_hits:42\r", "\nused_memory_rss:4096\r", "\ntotal_notfound:7\r", "\nkeyspace_misses:9\r"} {
+		if !strings.Contains(mapped, want) {
+			t.Errorf("expected %q in the mapped INFO, got: %q", want, mapped)
+		}
+	}
+
+	chM := make(chan prometheus.Metric)
+	go func() {
+		e.extractInfoMetrics(chM, mapped, 16)
+		close(chM)
+	}()
+	got := map[string]float64{}
+	for m := range chM {
+		d := &dto.Metric{}
+		m.Write(d)
+		desc := m.Desc().String()
+		name := desc[strings.Index(desc, `"`)+1:]
+		name = name[:strings.Index(name, `"`)]
+		got[name] = d.GetGauge().GetValue() + d.GetCounter().GetValue()
+	}
+	for name, want := range map[string]float64{"test_keyspace_hits_total": 42, "test_keyspace_misses_total": 9, "test_memory_used_rss_bytes": 4096} {
+		if got[name] != want {
+			t.Errorf("expected %s = %f, got: %f", name, want, got[name])
+		}
+	}
+
+	for collector, want := range map[string]bool{"streams": false, "latency": false, "modules": false, "slowlog": true, "clients": true} {
+		if got := e.supportsFlavor(collector); got != want {
+			t.Errorf("supportsFlavor(%s) = %t, want: %t", collector, got, want)
+		}
+	}
+}
+
+func TestIsGarnetOption(t *testing.T) {
+	e, _ := NewRedisExporter("redis://localhost:6379", Options{Namespace: "test", IsGarnet: true})
+	info := "# Server\r\nredis_version:7.4.0\r\n"
+	if !e.isGarnet(info) {
+		t.Fatalf("expected is-garnet to force the Garnet compatibility mode")
+	}
+
+	chM := make(chan prometheus.Metric)
+	go func() {
+		e.extractInfoMetrics(chM, info, 16)
+		close(chM)
+	}()
+	for range chM {
+	}
+	if e.serverFlavor != flavorGarnet || e.supportsFlavor("streams") {
+		t.Errorf("expected the garnet flavor, got: %q", e.serverFlavor)
+	}
+}
@@ -0,0 +1,75 @@
+package exporter
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// growthWindows keeps the samples of the last GrowthWindow per target and series
+// to compute their growth rates without predict_linear()
+type growthWindows struct {
+	sync.Mutex
+	samples map[string][]counterSample
+}
+
+func newGrowthWindows() *growthWindows {
+	return &growthWindows{samples: map[string][]counterSample{}}
+}
+
+// slope records val and returns the slope per second of the linear regression over the
+// samples of key within window, there's no slope until there are two samples
+func (g *growthWindows) slope(key string, val float64, now time.Time, window time.Duration) (float64, bool) {
+	g.Lock()
+	defer g.Unlock()
+
+	samples := g.samples[key]
+	start := 0
+	for start < len(samples) && now.Sub(samples[start].ts) > window {
+		start++
+	}
+	samples = append(samples[start:], counterSample{val: val, ts: now})
+	g.samples[key] = samples
+	if len(samples) < 2 {
+		return 0, false
+	}
+
+	// least squares with the seconds since the first sample as x
+	var sumX, sumY, sumXY, sumXX float64
+	for _, s := range samples {
+		x := s.ts.Sub(samples[0].ts).Seconds()
+		sumX += x
+		sumY += s.val
+		sumXY += x * s.val
+		sumXX += x * x
+	}
+	n := float64(len(samples))
+	denom := n*sumXX - sumX*sumX
+	if denom == 0 {
+		return 0, false
+	}
+	return (n*sumXY - sumX*sumY) / denom, true
+}
+
+// registerGrowth exports metric with the growth per hour of val over GrowthWindow, series
+// tells the values of a target apart
+func (e *Exporter) registerGrowth(ch chan<- prometheus.Metric, metric string, series string, val float64, labelValues ...string) {
+	if e.options.GrowthWindow <= 0 {
+		return
+	}
+	if slope, ok := e.growth.slope(e.redisAddr+"/"+series, val, time.Now(), e.options.GrowthWindow); ok {
+		e.registerConstMetricGauge(ch, metric, slope*3600, labelValues...)
+	}
+}
+
+// registerMemoryGrowth exports memory_growth_bytes_per_hour from the used_memory field of INFO
+func (e *Exporter) registerMemoryGrowth(ch chan<- prometheus.Metric, usedMemory string) {
+	if e.options.GrowthWindow <= 0 {
+		return
+	}
+	if val, err := strconv.ParseFloat(usedMemory, 64); err == nil {
+		e.registerGrowth(ch, "memory_growth_bytes_per_hour", "used_memory", val)
+	}
+}
@@ -0,0 +1,80 @@
+package exporter
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestGrowthWindowsSlope(t *testing.T) {
+	g := newGrowthWindows()
+	now := time.Now()
+
+	if _, ok := g.slope("t/used_memory", 1000, now, time.Hour); ok {
+		t.Errorf("didn't expect a slope for the first sample")
+	}
+	// +100 per minute with some noise
+	for i, v := range []float64{1110, 1190, 1300, 1400} {
+		slope, ok := g.slope("t/used_memory", v, now.Add(time.Duration(i+1)*time.Minute), time.Hour)
+		if !ok {
+			t.Fatalf("expected a slope for sample %d", i+2)
+		}
+		if i == 3 && math.Abs(slope*60-100) > 5 {
+			t.Errorf("expected about 100 per minute, got: %f", slope*60)
+		}
+	}
+
+	// the samples older than the window are dropped
+	slope, _ := g.slope("t/used_memory", 1400, now.Add(2*time.Hour), time.Hour)
+	if len(g.samples["t/used_memory"]) != 1 || slope != 0 {
+		t.Errorf("expected only the last sample within the window, got: %v", g.samples["t/used_memory"])
+	}
+}
+
+func TestGrowthMetrics(t *testing.T) {
+	for _, tst := range []struct {
+		name   string
+		window time.Duration
+		want   bool
+	}{
+		{name: "enabled", window: time.Hour, want: true},
+		{name: "disabled", want: false},
+	} {
+		t.Run(tst.name, func(t *testing.T) {
+			e, _ := NewRedisExporter("redis://localhost:6379", Options{Namespace: "test", GrowthWindow: tst.window})
+
+			got := map[string]bool{}
+			for _, info := range []string{
+				"# Memory\r\nused_memory:1000\r\n\r\n# Keyspace\r\ndb0:keys=10,expires=0,avg_ttl=0\r\n",
+				"# Memory\r\nused_memory:2000\r\n\r\n# Keyspace\r\ndb0:keys=20,expires=0,avg_ttl=0\r\n",
+			} {
+				chM := make(chan prometheus.Metric)
+				go func() {
+					e.extractInfoMetrics(chM, info, 16)
+					close(chM)
+				}()
+				for m := range chM {
+					desc := m.Desc().String()
+					if strings.Contains(desc, "growth") {
+						d := &dto.Metric{}
+						m.Write(d)
+						if d.GetGauge().GetValue() <= 0 {
+							t.Errorf("expected a positive growth, got: %s %f", desc, d.GetGauge().GetValue())
+						}
+						got[desc[strings.Index(desc, `"`)+1:strings.Index(desc, `", help`)]] = true
+					}
+				}
+			}
+
+			for _, name := range []string{"test_memory_growth_bytes_per_hour", "test_db_keys_growth_per_hour"} {
+				if got[name] != tst.want {
+					t.Errorf("expected %s to be exported: %t, got: %v", name, tst.want, got)
+				}
+			}
+		})
+	}
+}
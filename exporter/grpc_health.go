@@ -0,0 +1,88 @@
+package exporter
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// grpcHealthService is the service name of the exporter for the gRPC health checks,
+// the empty name is the overall health of the server and has the same status
+const grpcHealthService = "redis_exporter"
+
+// isHealthy returns false when the watchdog found a stuck collection
+func (e *Exporter) isHealthy() bool {
+	return e.watchdog == nil || e.watchdog.healthy.Load()
+}
+
+// healthzHandler is /health with the status of the watchdog, 503 while a collection is stuck
+func (e *Exporter) healthzHandler(w http.ResponseWriter, r *http.Request) {
+	if !e.isHealthy() {
+		http.Error(w, "unhealthy", http.StatusServiceUnavailable)
+		return
+	}
+	_, _ = w.Write([]byte(`ok`))
+}
+
+func (e *Exporter) grpcServingStatus() healthpb.HealthCheckResponse_ServingStatus {
+	if e.isHealthy() {
+		return healthpb.HealthCheckResponse_SERVING
+	}
+	return healthpb.HealthCheckResponse_NOT_SERVING
+}
+
+// StartGRPCHealthServer serves the standard gRPC health checking service on
+// GRPCHealthListenAddress until ctx is done, the status follows /healthz
+func (e *Exporter) StartGRPCHealthServer(ctx context.Context) error {
+	if e.options.GRPCHealthListenAddress == "" {
+		return nil
+	}
+
+	lis, err := net.Listen("tcp", e.options.GRPCHealthListenAddress)
+	if err != nil {
+		return err
+	}
+	e.serveGRPCHealth(ctx, lis)
+	return nil
+}
+
+// serveGRPCHealth serves the health checking service on lis and updates
+// the status every second until ctx is done
+func (e *Exporter) serveGRPCHealth(ctx context.Context, lis net.Listener) {
+	server := grpc.NewServer()
+	hs := health.NewServer()
+	healthpb.RegisterHealthServer(server, hs)
+	setStatus := func(status healthpb.HealthCheckResponse_ServingStatus) {
+		hs.SetServingStatus("", status)
+		hs.SetServingStatus(grpcHealthService, status)
+	}
+	setStatus(e.grpcServingStatus())
+
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				// Watch streams get NOT_SERVING before the server goes away
+				hs.Shutdown()
+				server.GracefulStop()
+				return
+			case <-ticker.C:
+				setStatus(e.grpcServingStatus())
+			}
+		}
+	}()
+
+	e.logger().Infof("Providing the gRPC health checking service at %s", lis.Addr())
+	go func() {
+		if err := server.Serve(lis); err != nil {
+			e.logger().Errorf("gRPC health server err: %s", err)
+		}
+	}()
+}
@@ -0,0 +1,87 @@
+package exporter
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestHealthz(t *testing.T) {
+	e, _ := NewRedisExporter("redis://localhost:6379", Options{Namespace: "test"})
+
+	for _, tst := range []struct {
+		name     string
+		watchdog *watchdog
+		want     int
+	}{
+		{name: "no watchdog", want: http.StatusOK},
+		{name: "healthy", watchdog: &watchdog{}, want: http.StatusOK},
+		{name: "stuck", watchdog: &watchdog{}, want: http.StatusServiceUnavailable},
+	} {
+		t.Run(tst.name, func(t *testing.T) {
+			if tst.watchdog != nil {
+				tst.watchdog.healthy.Store(tst.want == http.StatusOK)
+			}
+			e.watchdog = tst.watchdog
+
+			w := httptest.NewRecorder()
+			e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+			if w.Code != tst.want {
+				t.Errorf("expected %d, got: %d", tst.want, w.Code)
+			}
+		})
+	}
+}
+
+func TestGRPCHealth(t *testing.T) {
+	e, _ := NewRedisExporter("redis://localhost:6379", Options{Namespace: "test"})
+	e.watchdog = &watchdog{}
+	e.watchdog.healthy.Store(true)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() err: %s", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	e.serveGRPCHealth(ctx, lis)
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("grpc.NewClient() err: %s", err)
+	}
+	defer conn.Close()
+	client := healthpb.NewHealthClient(conn)
+
+	check := func(service string) healthpb.HealthCheckResponse_ServingStatus {
+		reqCtx, reqCancel := context.WithTimeout(ctx, 5*time.Second)
+		defer reqCancel()
+		resp, err := client.Check(reqCtx, &healthpb.HealthCheckRequest{Service: service})
+		if err != nil {
+			t.Fatalf("Check(%q) err: %s", service, err)
+		}
+		return resp.GetStatus()
+	}
+
+	for _, service := range []string{"", grpcHealthService} {
+		if got := check(service); got != healthpb.HealthCheckResponse_SERVING {
+			t.Errorf("Check(%q): expected SERVING, got: %s", service, got)
+		}
+	}
+
+	e.watchdog.healthy.Store(false)
+	deadline := time.Now().Add(5 * time.Second)
+	for check("") != healthpb.HealthCheckResponse_NOT_SERVING {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected NOT_SERVING once the watchdog found a stuck collection")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
@@ -0,0 +1,22 @@
+package exporter
+
+import (
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// isSelfMetric returns whether metricName is about the exporter itself instead of a redis instance
+func isSelfMetric(metricName string) bool {
+	return strings.HasPrefix(metricName, "exporter_") || strings.HasPrefix(metricName, "target_scrape_")
+}
+
+// haReplicaLabels returns the replica label of the self metrics of an exporter of an HA
+// pair, the metrics of the redis instances don't get it so they're identical for both
+// exporters and can be deduplicated
+func haReplicaLabels(replicaName string) prometheus.Labels {
+	if replicaName == "" {
+		return nil
+	}
+	return prometheus.Labels{"replica": replicaName}
+}
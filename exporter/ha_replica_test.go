@@ -0,0 +1,66 @@
+package exporter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestHAReplicaLabel(t *testing.T) {
+	e, _ := NewRedisExporter("", Options{Namespace: "test", HAReplicaName: "exporter-a", Registry: prometheus.NewRegistry()})
+
+	mfs, err := e.options.Registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mfs) == 0 {
+		t.Fatalf("expected the exporter's own metrics")
+	}
+	for _, mf := range mfs {
+		for _, m := range mf.GetMetric() {
+			if !hasLabel(m, "replica", "exporter-a") {
+				t.Errorf("expected the replica label on %s", mf.GetName())
+			}
+		}
+	}
+
+	chM := make(chan prometheus.Metric)
+	go func() {
+		e.registerConstMetricGauge(chM, "connected_clients", 3)
+		e.registerConstMetricGauge(chM, "exporter_last_scrape_duration_seconds", 1)
+		close(chM)
+	}()
+	for m := range chM {
+		d := &dto.Metric{}
+		m.Write(d)
+		self := strings.Contains(m.Desc().String(), "exporter_")
+		if hasLabel(d, "replica", "exporter-a") != self {
+			t.Errorf("expected the replica label only on the exporter's own metrics, got: %s", m.Desc())
+		}
+	}
+}
+
+func TestHAReplicaLabelNotSet(t *testing.T) {
+	e, _ := NewRedisExporter("", Options{Namespace: "test", Registry: prometheus.NewRegistry()})
+	mfs, _ := e.options.Registry.Gather()
+	for _, mf := range mfs {
+		for _, m := range mf.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "replica" {
+					t.Errorf("unexpected replica label on %s", mf.GetName())
+				}
+			}
+		}
+	}
+}
+
+func hasLabel(m *dto.Metric, name, value string) bool {
+	for _, l := range m.GetLabel() {
+		if l.GetName() == name && l.GetValue() == value {
+			return true
+		}
+	}
+	return false
+}
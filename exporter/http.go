@@ -5,24 +5,56 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"html"
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
+	"github.com/gomodule/redigo/redis"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/crypto/bcrypt"
 )
 
 func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// tenants check their own credentials instead of the ones of the main exporter
+	if t, ok := e.tenants[r.URL.Path]; ok {
+		t.ServeHTTP(w, r)
+		return
+	}
+	e.serveHTTP(w, r, e.mux)
+}
+
+// serveHTTP serves r with mux after checking the basic auth
+func (e *Exporter) serveHTTP(w http.ResponseWriter, r *http.Request, mux *http.ServeMux) {
+	if e.options.AuditLog && e.isAuditedPath(r.URL.Path) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		defer func() {
+			status := rec.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			e.auditRequest(r, status, time.Since(start))
+		}()
+		w = rec
+	}
+
+	if !e.allowedRemoteAddr(r) {
+		e.logger().Debugf("rejected request for %s from %s, not in web.allowed-cidrs", r.URL.Path, r.RemoteAddr)
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
 	if err := e.verifyBasicAuth(r.BasicAuth()); err != nil {
 		w.Header().Set("WWW-Authenticate", `Basic realm="redis-exporter, charset=UTF-8"`)
 		http.Error(w, err.Error(), http.StatusUnauthorized)
 		return
 	}
 
-	e.mux.ServeHTTP(w, r)
+	mux.ServeHTTP(w, r)
 }
 
 func (e *Exporter) healthHandler(w http.ResponseWriter, r *http.Request) {
@@ -30,17 +62,79 @@ func (e *Exporter) healthHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func (e *Exporter) indexHandler(w http.ResponseWriter, r *http.Request) {
+	if e.options.DisableLandingPage {
+		http.NotFound(w, r)
+		return
+	}
+
+	title := "Redis Exporter " + e.buildInfo.Version
+	if e.options.LandingPageTitle != "" {
+		title = e.options.LandingPageTitle
+	}
+	title = html.EscapeString(title)
+
+	links := `<p><a href='` + e.options.MetricsPath + `'>Metrics</a></p>
+`
+	if e.adminMux == nil {
+		links += `<p><a href='/health'>Health</a></p>
+`
+	}
+	if e.options.TargetsFile != "" && e.adminMux == nil {
+		links += `<p><a href='/targets'>Targets</a></p>
+`
+	}
+	if e.options.Events && e.adminMux == nil {
+		links += `<p><a href='/events'>Events</a></p>
+`
+	}
+
 	_, _ = w.Write([]byte(`<html>
-<head><title>Redis Exporter ` + e.buildInfo.Version + `</title></head>
+<head><title>` + title + `</title></head>
 <body>
-<h1>Redis Exporter ` + e.buildInfo.Version + `</h1>
-<p><a href='` + e.options.MetricsPath + `'>Metrics</a></p>
-</body>
+<h1>` + title + `</h1>
+` + links + `</body>
 </html>
 `))
 }
 
+// targetsHandler lists the targets of the targets file in the Prometheus http_sd format
+func (e *Exporter) targetsHandler(w http.ResponseWriter, r *http.Request) {
+	type targetGroup struct {
+		Targets []string          `json:"targets"`
+		Labels  map[string]string `json:"labels"`
+	}
+
+	groups := []targetGroup{}
+	if e.scheduler != nil {
+		for _, t := range e.scheduler.targetList() {
+			// strips the user info so credentials from the targets file aren't exposed
+			addr, _, err := parseTarget(t.Addr)
+			if err != nil {
+				continue
+			}
+			labels := t.Labels
+			if labels == nil {
+				labels = map[string]string{}
+			}
+			groups = append(groups, targetGroup{Targets: []string{addr}, Labels: labels})
+		}
+	}
+
+	data, err := json.MarshalIndent(groups, "", "  ")
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to marshal targets: %s", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
+
 func (e *Exporter) scrapeHandler(w http.ResponseWriter, r *http.Request) {
+	if !e.rateLimitScrape(w, r) {
+		return
+	}
+
 	target := r.URL.Query().Get("target")
 	if target == "" {
 		http.Error(w, "'target' parameter must be specified", http.StatusBadRequest)
@@ -48,11 +142,7 @@ func (e *Exporter) scrapeHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !strings.Contains(target, "://") {
-		target = "redis://" + target
-	}
-
-	u, err := url.Parse(target)
+	target, user, err := parseTarget(target)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Invalid 'target' parameter, parse err: %ck ", err), http.StatusBadRequest)
 		e.targetScrapeRequestErrors.Inc()
@@ -60,48 +150,127 @@ func (e *Exporter) scrapeHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	opts := e.options
-
-	// get rid of username/password info in "target" so users don't send them in plain text via http
-	// and save "user" in options so we can use it later when connecting to the redis instance
-	// the password will be looked up from the password file
-	if u.User != nil {
-		opts.User = u.User.Username()
-		u.User = nil
+	e.checkKeysConfig().apply(&opts)
+	if user != "" {
+		opts.User = user
+	}
+	if e.scheduler != nil {
+		opts.ConstLabels = e.scheduler.targetLabels(target)
 	}
-	target = u.String()
 
+	overridden := false
 	if ck := r.URL.Query().Get("check-keys"); ck != "" {
 		opts.CheckKeys = ck
+		overridden = true
 	}
 
 	if csk := r.URL.Query().Get("check-single-keys"); csk != "" {
 		opts.CheckSingleKeys = csk
+		overridden = true
 	}
 
 	if cs := r.URL.Query().Get("check-streams"); cs != "" {
 		opts.CheckStreams = cs
+		overridden = true
 	}
 
 	if css := r.URL.Query().Get("check-single-streams"); css != "" {
 		opts.CheckSingleStreams = css
+		overridden = true
 	}
 
 	if cntk := r.URL.Query().Get("count-keys"); cntk != "" {
 		opts.CountKeys = cntk
+		overridden = true
 	}
 
-	opts.Registry = prometheus.NewRegistry()
+	if r.URL.Query().Get("debug") == "true" {
+		if !e.options.AllowDebugScrape {
+			http.Error(w, "Debug scrapes are disabled, start the exporter with --allow-debug-scrape", http.StatusForbidden)
+			e.targetScrapeRequestErrors.Inc()
+			return
+		}
+		e.debugScrapeHandler(w, r, target, opts)
+		return
+	}
 
-	_, err = NewRedisExporter(target, opts)
+	// targets from the targets file are collected in the background,
+	// serve the latest result unless the request asks for different keys/streams
+	if e.scheduler != nil && !overridden {
+		if mfs, collectedAt, ok := e.scheduler.result(target); ok {
+			e.metricsHandler(
+				prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) { return e.withCacheAge(mfs, collectedAt), nil }),
+				false,
+				target,
+			).ServeHTTP(w, r)
+			return
+		}
+	}
+
+	exp, err := e.newTargetExporter(target, opts)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("NewRedisExporter() error: %v", err), http.StatusBadRequest)
 		e.targetScrapeRequestErrors.Inc()
 		return
 	}
 
-	promhttp.HandlerFor(
-		opts.Registry, promhttp.HandlerOpts{ErrorHandling: promhttp.ContinueOnError},
-	).ServeHTTP(w, r)
+	exp.cancelOnDisconnect(e.metricsHandler(exp.options.Registry, exp.tracer != nil, target)).ServeHTTP(w, r)
+}
+
+// parseTarget normalizes a scrape target and strips username/password info from it
+// so users don't send them in plain text via http. The username is returned so it can
+// be used when connecting to the redis instance, the password will be looked up from the password file.
+func parseTarget(target string) (string, string, error) {
+	if strings.HasPrefix(target, "/") {
+		target = "unix://" + target
+	} else if !strings.Contains(target, "://") {
+		target = "redis://" + target
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", "", err
+	}
+
+	user := ""
+	if u.User != nil {
+		user = u.User.Username()
+		u.User = nil
+	}
+	return u.String(), user, nil
+}
+
+// newTargetExporter returns an exporter with its own registry for a single target,
+// sharing the per-target state (like the circuit breaker) with e
+func (e *Exporter) newTargetExporter(target string, opts Options) (*Exporter, error) {
+	opts.Registry = prometheus.NewRegistry()
+	// only the main exporter serves the check-keys API, opts already has its checks
+	opts.CheckKeysAPIFile = ""
+	opts.CounterStateFile = ""
+
+	exp, err := NewRedisExporter(target, opts)
+	if err != nil {
+		return nil, err
+	}
+	exp.targetExporter = true
+	exp.circuitBreaker = e.circuitBreaker
+	exp.lastScrapes = e.lastScrapes
+	exp.aclLog = e.aclLog
+	exp.rejectedConnections = e.rejectedConnections
+	exp.replOffsets = e.replOffsets
+	exp.roleChanges = e.roleChanges
+	exp.latencyMonitorHints = e.latencyMonitorHints
+	exp.clusterRedirects = e.clusterRedirects
+	exp.inflight = e.inflight
+	exp.leader = e.leader
+	exp.scanCheckpoints = e.scanCheckpoints
+	exp.staleSnapshots = e.staleSnapshots
+	exp.responseStats = e.responseStats
+	exp.growth = e.growth
+	exp.events = e.events
+	exp.cpuFork = e.cpuFork
+	exp.counterState = e.counterState
+	return exp, nil
 }
 
 func (e *Exporter) discoverClusterNodesHandler(w http.ResponseWriter, r *http.Request) {
@@ -110,16 +279,24 @@ func (e *Exporter) discoverClusterNodesHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	c, err := e.connectToRedisCluster()
+	var c redis.Conn
+	var err error
+	if isUnixSocketAddr(e.redisAddr) {
+		// the cluster client can't bootstrap via a unix socket,
+		// ask the node behind the socket for the cluster topology instead
+		c, err = e.connectToRedis()
+	} else {
+		c, err = e.connectToRedisCluster()
+	}
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Couldn't connect to redis cluster: %s", err), http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("Couldn't connect to redis cluster: %s", err), errorStatusCode(err))
 		return
 	}
 	defer c.Close()
 
 	nodes, err := e.getClusterNodes(c)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to fetch cluster nodes: %s", err), http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("Failed to fetch cluster nodes: %s", err), errorStatusCode(err))
 		return
 	}
 
@@ -158,15 +335,17 @@ func (e *Exporter) reloadPwdFile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	log.Debugf("Reload redisPwdFile")
-	passwordMap, err := LoadPwdFile(e.options.RedisPwdFile)
+	passwordEntries, err := LoadPwdFileEntries(e.options.RedisPwdFile, e.options.RedisPwdFileKey)
 	if err != nil {
 		log.Errorf("Error reloading redis passwords from file %s, err: %s", e.options.RedisPwdFile, err)
+		e.configReloads.failed()
 		http.Error(w, "failed to reload passwords file: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 	e.Lock()
-	e.options.PasswordMap = passwordMap
+	e.options.PasswordEntries = passwordEntries
 	e.Unlock()
+	e.configReloads.succeeded(passwordEntries, time.Now())
 	_, _ = w.Write([]byte(`ok`))
 }
 
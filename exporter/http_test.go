@@ -828,3 +828,80 @@ func TestValidateBasicAuthPassword(t *testing.T) {
 		})
 	}
 }
+
+func TestLandingPage(t *testing.T) {
+	for _, tst := range []struct {
+		name     string
+		opts     Options
+		path     string
+		wantCode int
+		want     []string
+		notWant  []string
+	}{
+		{
+			name:     "default",
+			opts:     Options{Namespace: "test"},
+			path:     "/",
+			wantCode: http.StatusOK,
+			want:     []string{"<title>Redis Exporter ", "href='/metrics'", "href='/health'"},
+			notWant:  []string{"href='/targets'"},
+		},
+		{
+			name:     "custom title and targets link",
+			opts:     Options{Namespace: "test", LandingPageTitle: "Shard <A>", TargetsFile: "targets.json", MetricsPath: "/m"},
+			path:     "/",
+			wantCode: http.StatusOK,
+			want:     []string{"<title>Shard &lt;A&gt;</title>", "href='/m'", "href='/targets'"},
+		},
+		{
+			name:     "admin endpoints on the admin address",
+			opts:     Options{Namespace: "test", TargetsFile: "targets.json", AdminListenAddress: "localhost:9122"},
+			path:     "/",
+			wantCode: http.StatusOK,
+			want:     []string{"href='/metrics'"},
+			notWant:  []string{"href='/health'", "href='/targets'"},
+		},
+		{
+			name:     "disabled",
+			opts:     Options{Namespace: "test", DisableLandingPage: true},
+			path:     "/",
+			wantCode: http.StatusNotFound,
+			notWant:  []string{"<html>"},
+		},
+		{
+			name:     "disabled unknown path",
+			opts:     Options{Namespace: "test", DisableLandingPage: true},
+			path:     "/foo",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "targets without targets file",
+			opts:     Options{Namespace: "test"},
+			path:     "/targets",
+			wantCode: http.StatusOK,
+			want:     []string{"[]"},
+		},
+	} {
+		t.Run(tst.name, func(t *testing.T) {
+			e, _ := NewRedisExporter("", tst.opts)
+
+			w := httptest.NewRecorder()
+			e.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tst.path, nil))
+
+			if w.Code != tst.wantCode {
+				t.Errorf("expected status %d, got: %d", tst.wantCode, w.Code)
+			}
+			body := w.Body.String()
+			for _, want := range tst.want {
+				if !strings.Contains(body, want) {
+					t.Errorf("expected %q in body, got: %s", want, body)
+				}
+			}
+			for _, notWant := range tst.notWant {
+				if strings.Contains(body, notWant) {
+					t.Errorf("didn't expect %q in body, got: %s", notWant, body)
+				}
+			}
+		})
+	}
+}
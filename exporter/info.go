@@ -40,72 +40,88 @@ const (
 	InstanceRoleSlave = "slave"
 )
 
+var errInvalidVal = errors.New("nope")
+
 func extractVal(s string) (val float64, err error) {
-	split := strings.Split(s, "=")
-	if len(split) != 2 {
-		return 0, fmt.Errorf("nope")
+	_, v, ok := cutInfoPair(s)
+	if !ok {
+		return 0, errInvalidVal
 	}
-	val, err = strconv.ParseFloat(split[1], 64)
+	val, err = strconv.ParseFloat(v, 64)
 	if err != nil {
-		return 0, fmt.Errorf("nope")
+		return 0, errInvalidVal
 	}
 	return
 }
 
 func extractPercentileVal(s string) (percentile float64, val float64, err error) {
-	split := strings.Split(s, "=")
-	if len(split) != 2 {
+	k, v, ok := cutInfoPair(s)
+	if !ok {
 		return
 	}
-	percentile, err = strconv.ParseFloat(split[0][1:], 64)
+	percentile, err = strconv.ParseFloat(k[1:], 64)
 	if err != nil {
 		return
 	}
-	val, err = strconv.ParseFloat(split[1], 64)
+	val, err = strconv.ParseFloat(v, 64)
 	return
 }
 
-// returns the role of the instance we're scraping (master or slave)
-func (e *Exporter) extractInfoMetrics(ch chan<- prometheus.Metric, info string, dbCount int) string {
-	keyValues := map[string]string{}
+// returns the fields of infoTableFields, e.g. the role of the instance we're scraping (master or slave)
+func (e *Exporter) extractInfoMetrics(ch chan<- prometheus.Metric, info string, dbCount int) *infoFieldTable {
+	var fields infoFieldTable
+	// all fields are only needed for the derived metrics
+	var keyValues map[string]string
+	if e.options.InclDerivedMetrics {
+		keyValues = map[string]string{}
+	}
 	handledDBs := map[string]bool{}
+	// metrics of the keyspace fields of newer servers, exported as 0 for empty databases too
+	keyspaceExtraFields := map[string]bool{}
 	cmdCount := map[string]uint64{}
 	cmdSum := map[string]float64{}
 	cmdLatencyMap := map[string]map[float64]float64{}
 
-	fieldClass := ""
-	lines := strings.Split(info, "\n")
+	// boxing every line for Debugf() allocates even if debug logging is off
+	debug := log.IsLevelEnabled(log.DebugLevel)
+
 	masterHost := ""
 	masterPort := ""
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		log.Debugf("info: %s", line)
-		if len(line) > 0 && strings.HasPrefix(line, "# ") {
-			fieldClass = line[2:]
-			log.Debugf("set fieldClass: %s", fieldClass)
-			continue
+	scanner := newInfoScanner(info)
+	for scanner.next() {
+		fieldClass := scanner.section
+		fieldKey := scanner.key
+		fieldValue := scanner.value
+		if debug {
+			e.logger().Debugf("info: %s", scanner.line)
 		}
 
-		if (len(line) < 2) || (!strings.Contains(line, ":")) {
-			continue
+		fields.set(fieldKey, fieldValue)
+		if keyValues != nil {
+			keyValues[fieldKey] = fieldValue
 		}
 
-		split := strings.SplitN(line, ":", 2)
-		fieldKey := split[0]
-		fieldValue := split[1]
-
-		keyValues[fieldKey] = fieldValue
+		if strings.HasPrefix(fieldKey, "master") {
+			if reMasterHost.MatchString(fieldKey) {
+				masterHost = fieldValue
+			}
 
-		if reMasterHost.MatchString(fieldKey) {
-			masterHost = fieldValue
+			if reMasterPort.MatchString(fieldKey) {
+				masterPort = fieldValue
+			}
 		}
 
-		if reMasterPort.MatchString(fieldKey) {
-			masterPort = fieldValue
+		if !e.includeInfoField(fieldKey) {
+			continue
 		}
 
 		switch fieldClass {
 
+		case "Memory":
+			if fieldKey == "used_memory" {
+				e.registerMemoryGrowth(ch, fieldValue)
+			}
+
 		case "Replication":
 			if ok := e.handleMetricsReplication(ch, masterHost, masterPort, fieldKey, fieldValue); ok {
 				continue
@@ -140,6 +156,8 @@ func (e *Exporter) extractInfoMetrics(ch chan<- prometheus.Metric, info string,
 				if avgTTL > -1 {
 					e.registerConstMetricGauge(ch, "db_avg_ttl_seconds", avgTTL, dbName)
 				}
+				e.registerKeyspaceExtraFields(ch, dbName, fieldValue, keyspaceExtraFields)
+				e.registerGrowth(ch, "db_keys_growth_per_hour", dbName, keysTotal, dbName)
 				handledDBs[dbName] = true
 				continue
 			}
@@ -149,6 +167,7 @@ func (e *Exporter) extractInfoMetrics(ch chan<- prometheus.Metric, info string,
 		}
 
 		if !e.includeMetric(fieldKey) {
+			e.registerUnknownInfoField(ch, fieldClass, fieldKey, fieldValue)
 			continue
 		}
 
@@ -159,55 +178,86 @@ func (e *Exporter) extractInfoMetrics(ch chan<- prometheus.Metric, info string,
 	// from #Commandstats processing and the percentile info that we get from the #Latencystats processing
 	e.generateCommandLatencySummaries(ch, cmdLatencyMap, cmdCount, cmdSum)
 
-	if e.options.InclMetricsForEmptyDatabases {
+	if e.options.InclMetricsForEmptyDatabases && !e.useDbsizeFallback(info) {
 		for dbIndex := 0; dbIndex < dbCount; dbIndex++ {
 			dbName := "db" + strconv.Itoa(dbIndex)
 			if _, exists := handledDBs[dbName]; !exists {
 				e.registerConstMetricGauge(ch, "db_keys", 0, dbName)
 				e.registerConstMetricGauge(ch, "db_keys_expiring", 0, dbName)
+				for name := range keyspaceExtraFields {
+					e.registerConstMetricGauge(ch, name, 0, dbName)
+				}
+				e.registerGrowth(ch, "db_keys_growth_per_hour", dbName, 0, dbName)
 			}
 		}
 	}
 
-	instanceRole := keyValues["role"]
+	if e.options.InclDerivedMetrics {
+		e.extractDerivedMetrics(ch, keyValues)
+	}
+
+	e.extractClientHeadroomMetrics(ch, &fields)
+	e.extractReplBacklogCoverage(ch, &fields)
+	e.extractCPUForkMetrics(ch, &fields)
+
+	instanceRole := fields.value("role")
 
 	lbls := []string{"role", "redis_version", "redis_build_id", "redis_mode", "os", "maxmemory_policy", "tcp_port", "run_id", "process_id", "master_replid"}
 	lblVals := []string{
 		instanceRole,
-		keyValues["redis_version"],
-		keyValues["redis_build_id"],
-		keyValues["redis_mode"],
-		keyValues["os"],
-		keyValues["maxmemory_policy"],
-		keyValues["tcp_port"],
-		keyValues["run_id"],
-		keyValues["process_id"],
-		keyValues["master_replid"],
-	}
-	if valkeyVersion, ok := keyValues["valkey_version"]; ok {
+		fields.value("redis_version"),
+		fields.value("redis_build_id"),
+		fields.value("redis_mode"),
+		fields.value("os"),
+		fields.value("maxmemory_policy"),
+		fields.value("tcp_port"),
+		fields.value("run_id"),
+		fields.value("process_id"),
+		fields.value("master_replid"),
+	}
+	if valkeyVersion, ok := fields.get("valkey_version"); ok {
 		lbls = append(lbls, "valkey_version")
 		lblVals = append(lblVals, valkeyVersion)
 	}
-	if valkeyReleaseStage, ok := keyValues["valkey_release_stage"]; ok {
+	if valkeyReleaseStage, ok := fields.get("valkey_release_stage"); ok {
 		lbls = append(lbls, "valkey_release_stage")
 		lblVals = append(lblVals, valkeyReleaseStage)
 	}
 
+	if zone := e.instanceZone(&fields); zone != "" {
+		lbls = append(lbls, "zone")
+		lblVals = append(lblVals, zone)
+	}
+
 	e.createMetricDescription("instance_info", lbls)
 	e.registerConstMetricGauge(ch, "instance_info", 1, lblVals...)
+	e.registerServerFlavor(ch, &fields)
 
 	if instanceRole == InstanceRoleSlave {
 		e.registerConstMetricGauge(ch, "slave_info", 1,
-			keyValues["master_host"],
-			keyValues["master_port"],
-			keyValues["slave_read_only"])
+			fields.value("master_host"),
+			fields.value("master_port"),
+			fields.value("slave_read_only"))
 	}
 
-	return instanceRole
+	e.registerRoleChangeMetrics(ch, instanceRole)
+
+	e.observeEvent("role_change", instanceRole)
+	e.observeEvent("master_link", fields.value("master_link_status"))
+	e.observeEvent("aof_write_status", fields.value("aof_last_write_status"))
+	e.registerEventMetrics(ch)
+
+	return &fields
 }
 
 func (e *Exporter) generateCommandLatencySummaries(ch chan<- prometheus.Metric, cmdLatencyMap map[string]map[float64]float64, cmdCount map[string]uint64, cmdSum map[string]float64) {
 	for cmd, latencyMap := range cmdLatencyMap {
+		latencyMap = e.selectLatencyPercentiles(latencyMap)
+		if e.options.LatencyPercentilesFormat == latencyPercentilesGauges {
+			e.registerLatencyPercentileGauges(ch, cmd, latencyMap)
+			continue
+		}
+
 		count, okCount := cmdCount[cmd]
 		sum, okSum := cmdSum[cmd]
 		if okCount && okSum {
@@ -221,7 +271,7 @@ func (e *Exporter) extractClusterInfoMetrics(ch chan<- prometheus.Metric, info s
 	lines := strings.Split(info, "\r\n")
 
 	for _, line := range lines {
-		log.Debugf("info: %s", line)
+		e.logger().Debugf("info: %s", line)
 
 		split := strings.Split(line, ":")
 		if len(split) != 2 {
@@ -229,6 +279,9 @@ func (e *Exporter) extractClusterInfoMetrics(ch chan<- prometheus.Metric, info s
 		}
 		fieldKey := split[0]
 		fieldValue := split[1]
+		if fieldKey == "cluster_state" {
+			e.observeEvent("cluster_state", fieldValue)
+		}
 
 		if !e.includeMetric(fieldKey) {
 			continue
@@ -239,46 +292,60 @@ func (e *Exporter) extractClusterInfoMetrics(ch chan<- prometheus.Metric, info s
 
 /*
 valid example: db0:keys=1,expires=0,avg_ttl=0,cached_keys=0
+the fields are matched by name, the ones besides these (subexpiry, ...) are
+exported by registerKeyspaceExtraFields
 */
 func parseDBKeyspaceString(inputKey string, inputVal string) (keysTotal float64, keysExpiringTotal float64, avgTTL float64, keysCachedTotal float64, ok bool) {
-	log.Debugf("parseDBKeyspaceString inputKey: [%s] inputVal: [%s]", inputKey, inputVal)
-
 	if !strings.HasPrefix(inputKey, "db") {
 		log.Debugf("parseDBKeyspaceString inputKey not starting with 'db': [%s]", inputKey)
 		return
 	}
-
-	split := strings.Split(inputVal, ",")
-	if len(split) < 2 || len(split) > 4 {
-		log.Debugf("parseDBKeyspaceString strings.Split(inputVal) invalid: %#v", split)
-		return
+	if log.IsLevelEnabled(log.DebugLevel) {
+		log.Debugf("parseDBKeyspaceString inputKey: [%s] inputVal: [%s]", inputKey, inputVal)
 	}
 
-	var err error
-	if keysTotal, err = extractVal(split[0]); err != nil {
-		log.Debugf("parseDBKeyspaceString extractVal(split[0]) invalid, err: %s", err)
-		return
-	}
-	if keysExpiringTotal, err = extractVal(split[1]); err != nil {
-		log.Debugf("parseDBKeyspaceString extractVal(split[1]) invalid, err: %s", err)
-		return
-	}
-
-	avgTTL = -1
-	if len(split) > 2 {
-		if avgTTL, err = extractVal(split[2]); err != nil {
-			log.Debugf("parseDBKeyspaceString extractVal(split[2]) invalid, err: %s", err)
+	avgTTL, keysCachedTotal = -1, -1
+	hasKeys, hasExpires := false, false
+	for value := inputVal; ; {
+		kvPart, rest, more := strings.Cut(value, ",")
+		k, v, valid := cutInfoPair(kvPart)
+		if !valid {
+			log.Debugf("parseDBKeyspaceString invalid field [%s]", kvPart)
 			return
 		}
-		avgTTL /= 1000
-	}
 
-	keysCachedTotal = -1
-	if len(split) > 3 {
-		if keysCachedTotal, err = extractVal(split[3]); err != nil {
-			log.Debugf("parseDBKeyspaceString extractVal(split[3]) invalid, err: %s", err)
-			return
+		var dst *float64
+		switch k {
+		case "keys":
+			dst, hasKeys = &keysTotal, true
+		case "expires":
+			dst, hasExpires = &keysExpiringTotal, true
+		case "avg_ttl":
+			dst = &avgTTL
+		case "cached_keys":
+			dst = &keysCachedTotal
 		}
+		if dst != nil {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				log.Debugf("parseDBKeyspaceString field [%s] invalid, err: %s", kvPart, err)
+				return
+			}
+			*dst = f
+		}
+
+		if !more {
+			break
+		}
+		value = rest
+	}
+
+	if !hasKeys || !hasExpires {
+		log.Debugf("parseDBKeyspaceString keys or expires missing in [%s]", inputVal)
+		return
+	}
+	if avgTTL > -1 {
+		avgTTL /= 1000
 	}
 
 	ok = true
@@ -294,22 +361,39 @@ func parseConnectedSlaveString(slaveName string, keyValues string) (offset float
 	if !reSlave.MatchString(slaveName) {
 		return
 	}
-	connectedkeyValues := make(map[string]string)
-	for _, kvPart := range strings.Split(keyValues, ",") {
-		x := strings.Split(kvPart, "=")
-		if len(x) != 2 {
+	var ipStr, portStr, stateStr, offsetStr, lagStr string
+	lagExists := false
+	for value := keyValues; ; {
+		kvPart, rest, more := strings.Cut(value, ",")
+		k, v, valid := cutInfoPair(kvPart)
+		if !valid {
 			log.Debugf("Invalid format for connected slave string, got: %s", kvPart)
 			return
 		}
-		connectedkeyValues[x[0]] = x[1]
+		switch k {
+		case "ip":
+			ipStr = v
+		case "port":
+			portStr = v
+		case "state":
+			stateStr = v
+		case "offset":
+			offsetStr = v
+		case "lag":
+			lagStr, lagExists = v, true
+		}
+		if !more {
+			break
+		}
+		value = rest
 	}
-	offset, err := strconv.ParseFloat(connectedkeyValues["offset"], 64)
+	offset, err := strconv.ParseFloat(offsetStr, 64)
 	if err != nil {
-		log.Debugf("Can not parse connected slave offset, got: %s", connectedkeyValues["offset"])
+		log.Debugf("Can not parse connected slave offset, got: %s", offsetStr)
 		return
 	}
 
-	if lagStr, exists := connectedkeyValues["lag"]; !exists {
+	if !lagExists {
 		// Prior to Redis 3.0, "lag" property does not exist
 		lag = -1
 	} else {
@@ -321,9 +405,9 @@ func parseConnectedSlaveString(slaveName string, keyValues string) (offset float
 	}
 
 	ok = true
-	ip = connectedkeyValues["ip"]
-	port = connectedkeyValues["port"]
-	state = connectedkeyValues["state"]
+	ip = ipStr
+	port = portStr
+	state = stateStr
 
 	return
 }
@@ -419,8 +503,8 @@ func parseMetricsCommandStats(fieldKey string, fieldValue string) (cmd string, c
 	}
 	cmd = strings.TrimPrefix(fieldKey, cmdPrefix)
 
-	splitValue := strings.Split(fieldValue, ",")
-	splitLen := len(splitValue)
+	var splitValue [5]string
+	splitLen := splitInfoValue(fieldValue, splitValue[:])
 	if splitLen < 3 {
 		errorOut = errors.New("invalid fieldValue")
 		return
@@ -540,7 +624,7 @@ func parseMetricsErrorStats(fieldKey string, fieldValue string) (errorType strin
 func (e *Exporter) handleMetricsCommandStats(ch chan<- prometheus.Metric, fieldKey string, fieldValue string) (cmd string, calls float64, usecTotal float64) {
 	cmd, calls, rejectedCalls, failedCalls, usecTotal, extendedStats, err := parseMetricsCommandStats(fieldKey, fieldValue)
 	if err != nil {
-		log.Debugf("parseMetricsCommandStats( %s , %s ) err: %s", fieldKey, fieldValue, err)
+		e.logger().Debugf("parseMetricsCommandStats( %s , %s ) err: %s", fieldKey, fieldValue, err)
 		return
 	}
 	e.createMetricDescription("commands_total", []string{"cmd"})
@@ -0,0 +1,102 @@
+package exporter
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// infoFieldFilter selects the INFO fields that metrics are produced from, patterns are
+// globs matched against the field name and the names of the metrics produced from it
+type infoFieldFilter struct {
+	include []string
+	exclude []string
+}
+
+func parseInfoFieldFilter(include string, exclude string) (infoFieldFilter, error) {
+	var res infoFieldFilter
+	for _, l := range []struct {
+		patterns string
+		res      *[]string
+	}{
+		{include, &res.include},
+		{exclude, &res.exclude},
+	} {
+		for _, p := range strings.Split(l.patterns, ",") {
+			p = strings.TrimSpace(p)
+			if p == "" {
+				continue
+			}
+			if _, err := path.Match(p, ""); err != nil {
+				return res, fmt.Errorf("invalid INFO field pattern %q: %s", p, err)
+			}
+			*l.res = append(*l.res, p)
+		}
+	}
+	return res, nil
+}
+
+// infoFieldMetricNames are the metrics produced from the fields of the INFO sections that
+// don't map fields one by one, by the prefix of their fields
+var infoFieldMetricNames = []struct {
+	prefix string
+	names  []string
+}{
+	{"cmdstat_", []string{"commands_total", "commands_duration_seconds_total", "commands_rejected_calls_total", "commands_failed_calls_total"}},
+	{"errorstat_", []string{"errors_total"}},
+	{"latency_percentiles_usec_", []string{"latency_percentiles_usec"}},
+}
+
+// keyspaceInfoFieldMetricNames are the metrics produced from the db<n> fields of the Keyspace
+// section, the ones of fields added by newer servers come with them
+var keyspaceInfoFieldMetricNames = []string{"db_keys", "db_keys_expiring", "db_keys_cached", "db_avg_ttl_seconds", "db_keys_growth_per_hour", "db_keys_subexpiry"}
+
+// isKeyspaceInfoField returns whether fieldKey is a db<n> field of the Keyspace section
+func isKeyspaceInfoField(fieldKey string) bool {
+	n := strings.TrimPrefix(fieldKey, "db")
+	if n == fieldKey || n == "" {
+		return false
+	}
+	_, err := strconv.Atoi(n)
+	return err == nil
+}
+
+func (f infoFieldFilter) matchAny(patterns []string, names ...string) bool {
+	for _, p := range patterns {
+		for _, n := range names {
+			if ok, _ := path.Match(p, n); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// includeInfoField returns whether metrics are produced from the INFO field fieldKey
+func (e *Exporter) includeInfoField(fieldKey string) bool {
+	f := e.infoFieldFilter
+	if len(f.include) == 0 && len(f.exclude) == 0 {
+		return true
+	}
+
+	names := []string{fieldKey}
+	if m, ok := e.metricMapGauges[fieldKey]; ok {
+		names = append(names, m)
+	} else if m, ok := e.metricMapCounters[fieldKey]; ok {
+		names = append(names, m)
+	} else if isKeyspaceInfoField(fieldKey) {
+		names = append(names, keyspaceInfoFieldMetricNames...)
+	} else {
+		for _, m := range infoFieldMetricNames {
+			if strings.HasPrefix(fieldKey, m.prefix) {
+				names = append(names, m.names...)
+			}
+		}
+	}
+
+	if f.matchAny(f.exclude, names...) {
+		return false
+	}
+	return len(f.include) == 0 || f.matchAny(f.include, names...)
+}
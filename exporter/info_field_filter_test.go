@@ -0,0 +1,91 @@
+package exporter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestInfoFieldFilter(t *testing.T) {
+	info := "# Server\r\nredis_version:8.2.0\r\nuptime_in_seconds:100\r\n\r\n" +
+		"# Clients\r\nconnected_clients:5\r\nblocked_clients:1\r\n\r\n" +
+		"# Memory\r\nused_memory:1024\r\nused_memory_rss:2048\r\n\r\n" +
+		"# Commandstats\r\ncmdstat_get:calls=10,usec=20,usec_per_call=2.00\r\n\r\n" +
+		"# Errorstats\r\nerrorstat_ERR:count=3\r\n\r\n" +
+		"# Keyspace\r\ndb0:keys=5,expires=1,avg_ttl=0\r\n"
+
+	for _, tst := range []struct {
+		name      string
+		include   string
+		exclude   string
+		want      []string
+		wantNot   []string
+		wantError bool
+	}{
+		{
+			name:    "all fields",
+			want:    []string{"test_connected_clients", "test_blocked_clients", "test_memory_used_bytes", "test_errors_total", "test_uptime_in_seconds"},
+			wantNot: []string{},
+		},
+		{
+			name:    "include field and metric names",
+			include: "connected_*,memory_used_bytes",
+			want:    []string{"test_connected_clients", "test_memory_used_bytes"},
+			wantNot: []string{"test_blocked_clients", "test_memory_used_rss_bytes", "test_errors_total", "test_uptime_in_seconds"},
+		},
+		{
+			name:    "include metric names of sections",
+			include: "db_keys,commands_total,errors_total",
+			want:    []string{"test_db_keys", "test_commands_total", "test_errors_total"},
+			wantNot: []string{"test_connected_clients", "test_memory_used_bytes"},
+		},
+		{
+			name:    "exclude",
+			exclude: "errorstat_*, used_memory*",
+			want:    []string{"test_connected_clients", "test_blocked_clients", "test_uptime_in_seconds"},
+			wantNot: []string{"test_memory_used_bytes", "test_memory_used_rss_bytes", "test_errors_total"},
+		},
+		{
+			name:      "invalid pattern",
+			include:   "used_[memory",
+			wantError: true,
+		},
+	} {
+		t.Run(tst.name, func(t *testing.T) {
+			e, err := NewRedisExporter("redis://localhost:6379", Options{Namespace: "test", InfoFieldsInclude: tst.include, InfoFieldsExclude: tst.exclude})
+			if tst.wantError {
+				if err == nil {
+					t.Errorf("expected an error for the pattern %q", tst.include)
+				}
+				return
+			}
+
+			chM := make(chan prometheus.Metric)
+			go func() {
+				e.extractInfoMetrics(chM, info, 16)
+				close(chM)
+			}()
+			got := map[string]bool{}
+			for m := range chM {
+				desc := m.Desc().String()
+				name := desc[strings.Index(desc, `"`)+1:]
+				got[name[:strings.Index(name, `"`)]] = true
+			}
+
+			for _, name := range tst.want {
+				if !got[name] {
+					t.Errorf("expected %s, got: %v", name, got)
+				}
+			}
+			for _, name := range tst.wantNot {
+				if got[name] {
+					t.Errorf("didn't expect %s", name)
+				}
+			}
+			if !got["test_instance_info"] {
+				t.Errorf("expected instance_info to be exported with any filter")
+			}
+		})
+	}
+}
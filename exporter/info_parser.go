@@ -0,0 +1,168 @@
+package exporter
+
+import (
+	"strconv"
+	"strings"
+)
+
+/*
+	INFO is parsed once per scrape for every target, with hundreds of targets it's the
+	top allocation site of the exporter. The helpers below walk the INFO response with
+	byte offsets instead of splitting it, all strings they return are substrings of the
+	response so parsing doesn't allocate.
+*/
+
+// infoScanner iterates over the "key:value" fields of an INFO response
+type infoScanner struct {
+	info    string
+	section string
+	line    string
+	key     string
+	value   string
+}
+
+func newInfoScanner(info string) infoScanner {
+	return infoScanner{info: info}
+}
+
+// next advances to the next field, section headers ("# Server") update the
+// section of the fields that follow them, it returns false at the end of the response
+func (s *infoScanner) next() bool {
+	for len(s.info) > 0 {
+		line := s.info
+		if idx := strings.IndexByte(s.info, '\n'); idx >= 0 {
+			line, s.info = s.info[:idx], s.info[idx+1:]
+		} else {
+			s.info = ""
+		}
+
+		line = strings.TrimSpace(line)
+		s.line = line
+		if strings.HasPrefix(line, "# ") {
+			s.section = line[2:]
+			continue
+		}
+
+		if len(line) < 2 {
+			continue
+		}
+		idx := strings.IndexByte(line, ':')
+		if idx < 0 {
+			continue
+		}
+		s.key, s.value = line[:idx], line[idx+1:]
+		return true
+	}
+	return false
+}
+
+// splitInfoValue splits a comma separated INFO value like "keys=1,expires=0" into parts
+// and returns the number of elements, elements that don't fit into parts are counted but dropped
+func splitInfoValue(value string, parts []string) int {
+	n := 0
+	for {
+		elem, rest, found := strings.Cut(value, ",")
+		if n < len(parts) {
+			parts[n] = elem
+		}
+		n++
+		if !found {
+			return n
+		}
+		value = rest
+	}
+}
+
+// cutInfoPair splits "key=value", ok is false unless there is exactly one "="
+func cutInfoPair(s string) (key string, value string, ok bool) {
+	key, value, ok = strings.Cut(s, "=")
+	if ok && strings.IndexByte(value, '=') >= 0 {
+		return "", "", false
+	}
+	return
+}
+
+// infoTableFields are the INFO fields needed after all lines have been processed
+var infoTableFields = [...]string{
+	"role",
+	"redis_version",
+	"redis_build_id",
+	"redis_mode",
+	"os",
+	"maxmemory_policy",
+	"tcp_port",
+	"run_id",
+	"process_id",
+	"master_replid",
+	"valkey_version",
+	"valkey_release_stage",
+	"server_name",
+	"dragonfly_version",
+	"garnet_version",
+	"server_threads",
+	"master_link_status",
+	"aof_last_write_status",
+	"availability_zone",
+	"master_host",
+	"master_port",
+	"slave_read_only",
+	"connected_clients",
+	"maxclients",
+	"rejected_connections",
+	"master_repl_offset",
+	"repl_backlog_active",
+	"repl_backlog_histlen",
+	"used_cpu_sys",
+	"used_cpu_user",
+	"used_cpu_sys_children",
+	"used_cpu_user_children",
+	"used_cpu_sys_main_thread",
+	"used_cpu_user_main_thread",
+	"total_forks",
+	"latest_fork_usec",
+}
+
+var infoTableIndex = func() map[string]int {
+	idx := make(map[string]int, len(infoTableFields))
+	for i, f := range infoTableFields {
+		idx[f] = i
+	}
+	return idx
+}()
+
+// infoFieldTable keeps the values of infoTableFields in a fixed size table,
+// unlike a map of all the INFO fields it doesn't grow while parsing
+type infoFieldTable struct {
+	values  [len(infoTableFields)]string
+	present [len(infoTableFields)]bool
+}
+
+func (t *infoFieldTable) set(key string, value string) {
+	if i, ok := infoTableIndex[key]; ok {
+		t.values[i] = value
+		t.present[i] = true
+	}
+}
+
+func (t *infoFieldTable) get(key string) (string, bool) {
+	i, ok := infoTableIndex[key]
+	if !ok {
+		return "", false
+	}
+	return t.values[i], t.present[i]
+}
+
+func (t *infoFieldTable) value(key string) string {
+	v, _ := t.get(key)
+	return v
+}
+
+// float returns the value of key as number, false if it's missing or not a number
+func (t *infoFieldTable) float(key string) (float64, bool) {
+	s, ok := t.get(key)
+	if !ok {
+		return 0, false
+	}
+	val, err := strconv.ParseFloat(s, 64)
+	return val, err == nil
+}
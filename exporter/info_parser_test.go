@@ -0,0 +1,179 @@
+package exporter
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// benchmarkInfo is an INFO ALL response of a master with two replicas
+var benchmarkInfo = func() string {
+	var b strings.Builder
+	b.WriteString("# Server\r\nredis_version:7.2.4\r\nredis_git_sha1:00000000\r\nredis_git_dirty:0\r\nredis_build_id:7fbe6b5e3d3a8bd1\r\nredis_mode:standalone\r\nos:Linux 6.1.0 x86_64\r\narch_bits:64\r\nmultiplexing_api:epoll\r\ngcc_version:12.2.0\r\nprocess_id:1\r\nrun_id:5b8c5ec8b0ef2e1a0c6a7a2f0e3fa1cbf0a6cf40\r\ntcp_port:6379\r\nuptime_in_seconds:864000\r\nuptime_in_days:10\r\nhz:10\r\nconfigured_hz:10\r\nlru_clock:1234567\r\n\r\n")
+	b.WriteString("# Clients\r\nconnected_clients:120\r\ncluster_connections:0\r\nmaxclients:10000\r\nclient_recent_max_input_buffer:20480\r\nclient_recent_max_output_buffer:0\r\nblocked_clients:0\r\ntracking_clients:0\r\nclients_in_timeout_table:0\r\n\r\n")
+	b.WriteString("# Memory\r\nused_memory:1073741824\r\nused_memory_human:1.00G\r\nused_memory_rss:1288490188\r\nused_memory_peak:1181116006\r\nused_memory_lua:31744\r\nused_memory_scripts:184\r\nmaxmemory:2147483648\r\nmaxmemory_policy:allkeys-lru\r\nmem_fragmentation_ratio:1.20\r\nmem_allocator:jemalloc-5.3.0\r\nactive_defrag_running:0\r\nlazyfree_pending_objects:0\r\n\r\n")
+	b.WriteString("# Persistence\r\nloading:0\r\nrdb_changes_since_last_save:1234\r\nrdb_bgsave_in_progress:0\r\nrdb_last_save_time:1700000000\r\nrdb_last_bgsave_status:ok\r\naof_enabled:0\r\naof_rewrite_in_progress:0\r\naof_last_bgrewrite_status:ok\r\n\r\n")
+	b.WriteString("# Stats\r\ntotal_connections_received:123456\r\ntotal_commands_processed:987654321\r\ninstantaneous_ops_per_sec:12000\r\ntotal_net_input_bytes:123456789012\r\ntotal_net_output_bytes:234567890123\r\ninstantaneous_input_kbps:1024.50\r\ninstantaneous_output_kbps:2048.25\r\nrejected_connections:0\r\nexpired_keys:12345\r\nevicted_keys:0\r\nkeyspace_hits:876543210\r\nkeyspace_misses:12345678\r\npubsub_channels:2\r\nlatest_fork_usec:1234\r\n\r\n")
+	b.WriteString("# Replication\r\nrole:master\r\nconnected_slaves:2\r\nslave0:ip=10.0.0.2,port=6379,state=online,offset=123456789,lag=0\r\nslave1:ip=10.0.0.3,port=6379,state=online,offset=123456700,lag=1\r\nmaster_replid:5b8c5ec8b0ef2e1a0c6a7a2f0e3fa1cbf0a6cf41\r\nmaster_repl_offset:123456789\r\nrepl_backlog_active:1\r\nrepl_backlog_size:1048576\r\n\r\n")
+	b.WriteString("# CPU\r\nused_cpu_sys:1234.56\r\nused_cpu_user:2345.67\r\nused_cpu_sys_children:1.23\r\nused_cpu_user_children:2.34\r\n\r\n")
+	b.WriteString("# Commandstats\r\n")
+	for _, cmd := range []string{"get", "set", "del", "expire", "hget", "hset", "lpush", "rpop", "zadd", "zrange", "scan", "info", "ping", "eval", "config|get"} {
+		fmt.Fprintf(&b, "cmdstat_%s:calls=123456,usec=234567,usec_per_call=1.90,rejected_calls=0,failed_calls=0\r\n", cmd)
+	}
+	b.WriteString("\r\n# Errorstats\r\nerrorstat_ERR:count=12\r\nerrorstat_WRONGTYPE:count=3\r\n\r\n")
+	b.WriteString("# Cluster\r\ncluster_enabled:0\r\n\r\n")
+	b.WriteString("# Keyspace\r\n")
+	for db := 0; db < 4; db++ {
+		fmt.Fprintf(&b, "db%d:keys=1234567,expires=123456,avg_ttl=3600000\r\n", db)
+	}
+	return b.String()
+}()
+
+func TestInfoScanner(t *testing.T) {
+	type field struct{ section, key, value string }
+
+	var got []field
+	scanner := newInfoScanner("# Server\r\nredis_version:7.2.4\r\n\r\n# Replication\r\nrole:master\r\nno_colon\r\nslave0:ip=::1,port=6379\r\n# Keyspace\ndb0:keys=1,expires=0")
+	for scanner.next() {
+		got = append(got, field{scanner.section, scanner.key, scanner.value})
+	}
+
+	want := []field{
+		{"Server", "redis_version", "7.2.4"},
+		{"Replication", "role", "master"},
+		{"Replication", "slave0", "ip=::1,port=6379"},
+		{"Keyspace", "db0", "keys=1,expires=0"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+}
+
+func TestSplitInfoValue(t *testing.T) {
+	var parts [3]string
+	for _, tst := range []struct {
+		value string
+		n     int
+		parts [3]string
+	}{
+		{value: "keys=1", n: 1, parts: [3]string{"keys=1"}},
+		{value: "keys=1,expires=0", n: 2, parts: [3]string{"keys=1", "expires=0"}},
+		{value: "a,b,c,d,e", n: 5, parts: [3]string{"a", "b", "c"}},
+		{value: "", n: 1},
+	} {
+		parts = [3]string{}
+		if n := splitInfoValue(tst.value, parts[:]); n != tst.n || parts != tst.parts {
+			t.Errorf("splitInfoValue(%q) = %d %q, want: %d %q", tst.value, n, parts, tst.n, tst.parts)
+		}
+	}
+}
+
+func TestInfoFieldTable(t *testing.T) {
+	var fields infoFieldTable
+	fields.set("role", "slave")
+	fields.set("used_memory", "1024")
+	fields.set("master_port", "")
+
+	if v, ok := fields.get("role"); !ok || v != "slave" {
+		t.Errorf("role = %q %t, want: slave", v, ok)
+	}
+	if _, ok := fields.get("master_port"); !ok {
+		t.Errorf("empty master_port should be present")
+	}
+	if _, ok := fields.get("valkey_version"); ok {
+		t.Errorf("valkey_version shouldn't be present")
+	}
+	if _, ok := fields.get("used_memory"); ok {
+		t.Errorf("used_memory isn't part of the table and shouldn't be present")
+	}
+}
+
+func TestInfoParsingDoesNotAllocate(t *testing.T) {
+	allocs := testing.AllocsPerRun(100, func() {
+		scanner := newInfoScanner(benchmarkInfo)
+		var fields infoFieldTable
+		for scanner.next() {
+			fields.set(scanner.key, scanner.value)
+			switch scanner.section {
+			case "Keyspace":
+				parseDBKeyspaceString(scanner.key, scanner.value)
+			case "Commandstats":
+				parseMetricsCommandStats(scanner.key, scanner.value)
+			case "Replication":
+				parseConnectedSlaveString(scanner.key, scanner.value)
+			default:
+				sanitizeMetricName(scanner.key)
+			}
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("parsing INFO allocated %.0f times, want: 0", allocs)
+	}
+}
+
+func BenchmarkInfoScanner(b *testing.B) {
+	b.ReportAllocs()
+	for b.Loop() {
+		scanner := newInfoScanner(benchmarkInfo)
+		for scanner.next() {
+		}
+	}
+}
+
+func BenchmarkParseDBKeyspaceString(b *testing.B) {
+	b.ReportAllocs()
+	for b.Loop() {
+		parseDBKeyspaceString("db0", "keys=1234567,expires=123456,avg_ttl=3600000,subexpiry=0")
+	}
+}
+
+func BenchmarkParseMetricsCommandStats(b *testing.B) {
+	b.ReportAllocs()
+	for b.Loop() {
+		parseMetricsCommandStats("cmdstat_get", "calls=123456,usec=234567,usec_per_call=1.90,rejected_calls=0,failed_calls=0")
+	}
+}
+
+func BenchmarkParseConnectedSlaveString(b *testing.B) {
+	b.ReportAllocs()
+	for b.Loop() {
+		parseConnectedSlaveString("slave0", "ip=10.0.0.2,port=6379,state=online,offset=123456789,lag=0")
+	}
+}
+
+func BenchmarkExtractInfoMetrics(b *testing.B) {
+	e, _ := NewRedisExporter("unix:///tmp/doesnt.matter", Options{Namespace: "test"})
+	ch := make(chan prometheus.Metric, 1024)
+	done := make(chan struct{})
+	go func() {
+		for range ch {
+		}
+		close(done)
+	}()
+
+	b.ReportAllocs()
+	for b.Loop() {
+		e.extractInfoMetrics(ch, benchmarkInfo, 16)
+	}
+	close(ch)
+	<-done
+}
+
+func TestInfoFieldTableFloat(t *testing.T) {
+	var fields infoFieldTable
+	fields.set("used_cpu_sys", "1.5")
+	fields.set("role", "master")
+
+	if v, ok := fields.float("used_cpu_sys"); !ok || v != 1.5 {
+		t.Errorf("expected 1.5, got: %f %t", v, ok)
+	}
+	if _, ok := fields.float("role"); ok {
+		t.Errorf("expected no number for role")
+	}
+	if _, ok := fields.float("total_forks"); ok {
+		t.Errorf("expected no number for a missing field")
+	}
+}
@@ -31,14 +31,16 @@ func TestKeyspaceStringParser(t *testing.T) {
 		{db: "db3", stats: "keys=123,expires=0,avg_ttl=zzz", ok: false},
 		{db: "db3", stats: "keys=1,expires=0,avg_ttl=zzz,cached_keys=0", ok: false},
 		{db: "db3", stats: "keys=1,expires=0,avg_ttl=0,cached_keys=zzz", ok: false},
-		{db: "db3", stats: "keys=1,expires=0,avg_ttl=0,cached_keys=0,extra=0", ok: false},
+		{db: "db3", stats: "keys=1,expires=0", keysTotal: 1, keysEx: 0, avgTTL: -1, keysCached: -1, ok: true},
+		{db: "db3", stats: "keys=1,expires=0,avg_ttl=0,cached_keys=0,extra=0", keysTotal: 1, keysEx: 0, avgTTL: 0, keysCached: 0, ok: true},
+		{db: "db3", stats: "expires=0,avg_ttl=0", ok: false},
 
 		{db: "db0", stats: "keys=1,expires=0,avg_ttl=0", keysTotal: 1, keysEx: 0, avgTTL: 0, keysCached: -1, ok: true},
 		{db: "db0", stats: "keys=1,expires=0,avg_ttl=0,cached_keys=0", keysTotal: 1, keysEx: 0, avgTTL: 0, keysCached: 0, ok: true},
 
 		{
 			db: "db0", stats: "keys=25714011,expires=25091314,avg_ttl=685620459,subexpiry=0",
-			keysTotal: 25714011, keysEx: 25091314, keysCached: 0, avgTTL: 685620.459000,
+			keysTotal: 25714011, keysEx: 25091314, keysCached: -1, avgTTL: 685620.459000,
 			ok: true,
 		},
 	}
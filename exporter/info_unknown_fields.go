@@ -0,0 +1,61 @@
+package exporter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// systemInfoFields are the INFO fields that are only mapped with InclSystemMetrics,
+// they aren't unknown if it's turned off
+var systemInfoFields = map[string]string{
+	"total_system_memory": "total_system_memory_bytes",
+}
+
+// registerUnknownInfoField exports a numeric INFO field the exporter doesn't map with
+// ExportUnknownInfoFields, under its sanitized name and with the lowercase section as label.
+// Fields whose name is taken by another metric are skipped
+func (e *Exporter) registerUnknownInfoField(ch chan<- prometheus.Metric, section string, fieldKey string, fieldValue string) {
+	if !e.options.ExportUnknownInfoFields {
+		return
+	}
+	if _, ok := systemInfoFields[fieldKey]; ok {
+		return
+	}
+	val, err := strconv.ParseFloat(fieldValue, 64)
+	if err != nil {
+		return
+	}
+
+	name := sanitizeMetricName(fieldKey)
+	exported, seen := e.unknownInfoFields[name]
+	if !seen {
+		exported = !e.isKnownMetricName(name)
+		if exported {
+			_, exported = e.addMetricDescription(name, fieldKey, fmt.Sprintf("Value of the INFO field %s, not mapped by the exporter", fieldKey), []string{"section"})
+		}
+		e.unknownInfoFields[name] = exported
+	}
+	if !exported {
+		return
+	}
+
+	e.registerConstMetricGauge(ch, name, val, strings.ToLower(section))
+}
+
+// isKnownMetricName returns whether name is exported by another collector
+func (e *Exporter) isKnownMetricName(name string) bool {
+	if _, ok := e.findMetricDescription(name); ok {
+		return true
+	}
+	for _, m := range []map[string]string{e.metricMapGauges, e.metricMapCounters} {
+		for _, v := range m {
+			if v == name {
+				return true
+			}
+		}
+	}
+	return false
+}
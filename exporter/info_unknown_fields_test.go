@@ -0,0 +1,67 @@
+package exporter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestExportUnknownInfoFields(t *testing.T) {
+	// total_system_memory is only exported with InclSystemMetrics but it isn't unknown
+	info := "# Server\r\nredis_version:8.2.0\r\nhz:10\r\nnew_server_mode:fast\r\n\r\n" +
+		"# Memory\r\nused_memory:1024\r\ntotal_system_memory:8589934592\r\nnew_memory_gauge:42\r\nnew-dashed.field:7\r\n"
+
+	for _, tst := range []struct {
+		name string
+		opts Options
+		want map[string]string
+	}{
+		{
+			name: "enabled",
+			opts: Options{Namespace: "test", ExportUnknownInfoFields: true},
+			want: map[string]string{"test_new_memory_gauge": "memory", "test_new_dashed_field": "memory"},
+		},
+		{
+			name: "disabled",
+			opts: Options{Namespace: "test"},
+			want: map[string]string{},
+		},
+	} {
+		t.Run(tst.name, func(t *testing.T) {
+			e, _ := NewRedisExporter("redis://localhost:6379", tst.opts)
+
+			// the second scrape uses the cached names
+			for scrape := 1; scrape <= 2; scrape++ {
+				chM := make(chan prometheus.Metric)
+				go func() {
+					e.extractInfoMetrics(chM, info, 16)
+					close(chM)
+				}()
+
+				got := map[string]string{}
+				for m := range chM {
+					d := &dto.Metric{}
+					m.Write(d)
+					for _, l := range d.GetLabel() {
+						if l.GetName() == "section" {
+							desc := m.Desc().String()
+							name := desc[strings.Index(desc, `"`)+1:]
+							got[name[:strings.Index(name, `"`)]] = l.GetValue()
+						}
+					}
+				}
+
+				if len(got) != len(tst.want) {
+					t.Errorf("scrape %d: expected %v, got: %v", scrape, tst.want, got)
+				}
+				for name, section := range tst.want {
+					if got[name] != section {
+						t.Errorf("scrape %d: expected %s with section %q, got: %v", scrape, name, section, got)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package exporter
+
+import (
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// kernelFilesRoot is the root the kernel settings are read from, replaced in the tests
+var kernelFilesRoot = "/"
+
+// defaultTCPBacklog is the tcp-backlog of Redis, used if CONFIG GET isn't allowed
+const defaultTCPBacklog = 511
+
+// isLocalTarget returns true for unix sockets and loopback addresses, the
+// kernel settings of the exporter's host are only the ones of these servers
+func isLocalTarget(addr string) bool {
+	if isUnixSocketAddr(addr) {
+		return true
+	}
+	if !strings.Contains(addr, "://") {
+		addr = "redis://" + addr
+	}
+	u, err := url.Parse(addr)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func readKernelFile(name string) (string, bool) {
+	b, err := os.ReadFile(filepath.Join(kernelFilesRoot, name))
+	if err != nil {
+		return "", false
+	}
+	return strings.TrimSpace(string(b)), true
+}
+
+// extractKernelWarningMetrics exports the kernel misconfigurations Redis warns about at
+// startup as kernel_warning{warning}. Transparent huge pages are detected with LATENCY DOCTOR
+// which reports the huge pages used by the process, overcommit_memory and somaxconn can only
+// be read from the host of the exporter and are checked for local targets on Linux
+func (e *Exporter) extractKernelWarningMetrics(ch chan<- prometheus.Metric, c redis.Conn) {
+	warnings := map[string]bool{}
+	// whether THP are known to be enabled or disabled, LATENCY DOCTOR only reports them if used
+	thpKnown := false
+
+	if report, err := redis.String(doRedisCmd(c, "LATENCY", "DOCTOR")); err != nil {
+		e.logger().Debugf("LATENCY DOCTOR err: %s", err)
+	} else {
+		warnings["transparent_hugepages"] = strings.Contains(strings.ToLower(report), "huge pages")
+		thpKnown = warnings["transparent_hugepages"]
+	}
+
+	if runtime.GOOS == "linux" && isLocalTarget(e.redisAddr) {
+		if thp, ok := readKernelFile("sys/kernel/mm/transparent_hugepage/enabled"); ok {
+			// e.g. "always [madvise] never"
+			warnings["transparent_hugepages"] = warnings["transparent_hugepages"] || strings.Contains(thp, "[always]")
+			thpKnown = true
+		}
+		if overcommit, ok := readKernelFile("proc/sys/vm/overcommit_memory"); ok {
+			warnings["overcommit_memory"] = overcommit != "1"
+		}
+		if somaxconn, ok := readKernelFile("proc/sys/net/core/somaxconn"); ok {
+			if n, err := strconv.Atoi(somaxconn); err == nil {
+				warnings["somaxconn"] = n < e.tcpBacklog(c)
+			}
+		}
+	}
+
+	for warning, found := range warnings {
+		val := 0.0
+		if found {
+			val = 1
+		}
+		e.registerConstMetricGauge(ch, "kernel_warning", val, warning)
+	}
+
+	if thpKnown {
+		val := 0.0
+		if warnings["transparent_hugepages"] {
+			val = 1
+		}
+		e.registerConstMetricGauge(ch, "transparent_hugepages_enabled", val)
+	}
+}
+
+// tcpBacklog returns the tcp-backlog config of the server
+func (e *Exporter) tcpBacklog(c redis.Conn) int {
+	values, err := redis.Strings(doRedisCmd(c, e.options.ConfigCommandName, "GET", "tcp-backlog"))
+	if err != nil || len(values) != 2 {
+		return defaultTCPBacklog
+	}
+	n, err := strconv.Atoi(values[1])
+	if err != nil {
+		return defaultTCPBacklog
+	}
+	return n
+}
@@ -0,0 +1,118 @@
+package exporter
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestIsLocalTarget(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"redis://localhost:6379":  true,
+		"127.0.0.1:6379":          true,
+		"redis://[::1]:6379":      true,
+		"unix:///tmp/redis.sock":  true,
+		"/tmp/redis.sock":         true,
+		"redis://10.0.0.1:6379":   false,
+		"rediss://redis.internal": false,
+	} {
+		if got := isLocalTarget(addr); got != want {
+			t.Errorf("isLocalTarget(%q) = %t, expected: %t", addr, got, want)
+		}
+	}
+}
+
+func TestKernelWarnings(t *testing.T) {
+	root := t.TempDir()
+	for name, content := range map[string]string{
+		"sys/kernel/mm/transparent_hugepage/enabled": "always madvise [never]\n",
+		"proc/sys/vm/overcommit_memory":              "0\n",
+		"proc/sys/net/core/somaxconn":                "128\n",
+	} {
+		if err := os.MkdirAll(filepath.Join(root, filepath.Dir(name)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer func(r string) { kernelFilesRoot = r }(kernelFilesRoot)
+	kernelFilesRoot = root
+
+	thpReport := "Dave, I have observed latency spikes in this Redis instance.\n" +
+		"- I detected a non zero amount of anonymous huge pages used by your process. This creates very serious latency events.\n"
+	for _, tst := range []struct {
+		name   string
+		addr   string
+		doctor string
+		want   map[string]float64
+		// -1 if transparent_hugepages_enabled isn't exported
+		wantTHPEnabled float64
+	}{
+		{
+			name:   "local target",
+			addr:   "redis://localhost:6379",
+			doctor: "Dave, no latency spike was observed during the lifetime of this Redis instance.\n",
+			want:   map[string]float64{"transparent_hugepages": 0, "overcommit_memory": 1, "somaxconn": 1},
+		},
+		{
+			name:           "remote target",
+			addr:           "redis://10.0.0.1:6379",
+			doctor:         thpReport,
+			want:           map[string]float64{"transparent_hugepages": 1},
+			wantTHPEnabled: 1,
+		},
+		{
+			name:           "remote target without huge pages report",
+			addr:           "redis://10.0.0.1:6379",
+			doctor:         "Dave, no latency spike was observed during the lifetime of this Redis instance.\n",
+			want:           map[string]float64{"transparent_hugepages": 0},
+			wantTHPEnabled: -1,
+		},
+	} {
+		t.Run(tst.name, func(t *testing.T) {
+			if tst.addr == "redis://localhost:6379" && runtime.GOOS != "linux" {
+				t.Skip("the kernel settings are only read on Linux")
+			}
+			e, _ := NewRedisExporter(tst.addr, Options{Namespace: "test", InclKernelWarnings: true})
+			c := &fakeRedisConn{replies: map[string]interface{}{
+				"LATENCY DOCTOR":         []byte(tst.doctor),
+				"CONFIG GET tcp-backlog": []interface{}{[]byte("tcp-backlog"), []byte("511")},
+			}}
+
+			chM := make(chan prometheus.Metric)
+			go func() {
+				e.extractKernelWarningMetrics(chM, c)
+				close(chM)
+			}()
+
+			got := map[string]float64{}
+			thpEnabled := -1.0
+			for m := range chM {
+				d := &dto.Metric{}
+				m.Write(d)
+				if len(d.GetLabel()) == 0 {
+					thpEnabled = d.GetGauge().GetValue()
+					continue
+				}
+				got[d.GetLabel()[0].GetValue()] = d.GetGauge().GetValue()
+			}
+
+			if len(got) != len(tst.want) {
+				t.Errorf("expected %v, got: %v", tst.want, got)
+			}
+			for warning, want := range tst.want {
+				if v, ok := got[warning]; !ok || v != want {
+					t.Errorf("expected kernel_warning{warning=%q} = %f, got: %v", warning, want, got)
+				}
+			}
+			if thpEnabled != tst.wantTHPEnabled {
+				t.Errorf("expected transparent_hugepages_enabled = %f, got: %f", tst.wantTHPEnabled, thpEnabled)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package exporter
+
+import (
+	"hash/fnv"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// keyDumpSampled returns true if the serialized size of keyName is sampled, the same keys
+// are picked on every scrape so their key_dump_size_bytes series don't come and go
+func keyDumpSampled(keyName string, ratio float64) bool {
+	if ratio >= 1 {
+		return true
+	}
+	h := fnv.New32a()
+	h.Write([]byte(keyName))
+	return float64(h.Sum32()%10000) < ratio*10000
+}
+
+// extractKeyDumpSizeMetrics exports the length of the DUMP serialization of the sampled keys of
+// a batch of check-keys as key_dump_size_bytes, it approximates the network transfer cost of a
+// key and works on versions without MEMORY USAGE. DUMP transfers the whole value, so at most
+// CheckKeysDumpMaxKeys keys are dumped per scrape
+func (e *Exporter) extractKeyDumpSizeMetrics(ch chan<- prometheus.Metric, c redis.Conn, dbLabel string, keyNames []string) {
+	var keys []string
+	for _, keyName := range keyNames {
+		if e.keyDumpBudget <= 0 {
+			break
+		}
+		if keyDumpSampled(keyName, e.options.CheckKeysDumpSampleRatio) {
+			keys = append(keys, keyName)
+			e.keyDumpBudget--
+		}
+	}
+	if len(keys) == 0 {
+		return
+	}
+
+	register := func(keyName string, reply interface{}, err error) {
+		dump, err := redis.Bytes(reply, err)
+		if err == redis.ErrNil {
+			// the key was deleted since it was checked
+			return
+		}
+		if err != nil {
+			e.logger().Errorf("DUMP %s err: %s", printableKey(keyName), err)
+			return
+		}
+		e.registerKeyMetric(ch, "key_dump_size_bytes", float64(len(dump)), dbLabel, keyName)
+	}
+
+	if e.options.IsCluster {
+		for _, keyName := range keys {
+			reply, err := doRedisCmd(c, "DUMP", keyName)
+			register(keyName, reply, err)
+		}
+		return
+	}
+
+	for _, keyName := range keys {
+		if err := c.Send("DUMP", keyName); err != nil {
+			e.logger().Errorf("c.Send() DUMP err: %s", err)
+			return
+		}
+	}
+	if err := c.Flush(); err != nil {
+		e.logger().Errorf("Flush() err: %s", err)
+		return
+	}
+	for _, keyName := range keys {
+		reply, err := c.Receive()
+		register(keyName, reply, err)
+	}
+}
@@ -0,0 +1,118 @@
+package exporter
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestKeyDumpSizeMetrics(t *testing.T) {
+	replies := map[string]interface{}{
+		"SELECT 0":  []byte("OK"),
+		"DUMP gone": nil,
+	}
+	var keys []string
+	for i := 1; i <= 4; i++ {
+		key := fmt.Sprintf("list:%d", i)
+		keys = append(keys, key)
+		replies["TYPE "+key] = []byte("list")
+		// MEMORY USAGE isn't available before Redis 4.0
+		replies["MEMORY USAGE "+key] = redis.Error("ERR unknown command 'MEMORY'")
+		replies["LLEN "+key] = int64(i)
+		replies["DUMP "+key] = []byte(strings.Repeat("x", 10*i))
+	}
+	replies["TYPE gone"] = []byte("none")
+	replies["MEMORY USAGE gone"] = nil
+
+	for _, tst := range []struct {
+		name string
+		opts Options
+		want map[string]float64
+	}{
+		{
+			name: "all keys",
+			opts: Options{CheckKeysDumpSize: true, CheckKeysDumpSampleRatio: 1},
+			want: map[string]float64{"list:1": 10, "list:2": 20, "list:3": 30, "list:4": 40},
+		},
+		{
+			name: "max keys",
+			opts: Options{CheckKeysDumpSize: true, CheckKeysDumpMaxKeys: 2, CheckKeysDumpSampleRatio: 1},
+			want: map[string]float64{"list:1": 10, "list:2": 20},
+		},
+		{
+			name: "disabled",
+			opts: Options{},
+			want: map[string]float64{},
+		},
+	} {
+		t.Run(tst.name, func(t *testing.T) {
+			c := &pipelineConn{fakeRedisConn: fakeRedisConn{replies: replies}}
+			tst.opts.Namespace = "test"
+			tst.opts.CheckSingleKeys = "db0=" + strings.Join(append(keys, "gone"), ",db0=")
+			e, _ := NewRedisExporter("redis://localhost:6379", tst.opts)
+
+			chM := make(chan prometheus.Metric)
+			go func() {
+				if err := e.extractCheckKeyMetrics(chM, c); err != nil {
+					t.Errorf("extractCheckKeyMetrics() err: %s", err)
+				}
+				close(chM)
+			}()
+
+			got := map[string]float64{}
+			sizes := 0
+			for m := range chM {
+				d := &dto.Metric{}
+				m.Write(d)
+				desc := m.Desc().String()
+				if strings.Contains(desc, `"test_key_size"`) {
+					sizes++
+				}
+				if !strings.Contains(desc, `"test_key_dump_size_bytes"`) {
+					continue
+				}
+				for _, l := range d.GetLabel() {
+					if l.GetName() == "key" {
+						got[l.GetValue()] = d.GetGauge().GetValue()
+					}
+				}
+			}
+
+			// the size of the missing key defaults to 0
+			if sizes != len(keys)+1 {
+				t.Errorf("expected the sizes of %d keys without MEMORY USAGE, got: %d", len(keys)+1, sizes)
+			}
+			if len(got) != len(tst.want) {
+				t.Errorf("expected %v, got: %v", tst.want, got)
+			}
+			for key, val := range tst.want {
+				if got[key] != val {
+					t.Errorf("expected the dump size of %s to be %f, got: %f", key, val, got[key])
+				}
+			}
+		})
+	}
+}
+
+func TestKeyDumpSampled(t *testing.T) {
+	sampled := 0
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("key:%d", i)
+		if keyDumpSampled(key, 0.25) {
+			sampled++
+		}
+		if keyDumpSampled(key, 0.25) != keyDumpSampled(key, 0.25) {
+			t.Fatalf("expected the same keys to be sampled every time")
+		}
+		if !keyDumpSampled(key, 1) {
+			t.Fatalf("expected all keys to be sampled with a ratio of 1")
+		}
+	}
+	if sampled < 200 || sampled > 300 {
+		t.Errorf("expected about 250 of 1000 keys to be sampled, got: %d", sampled)
+	}
+}
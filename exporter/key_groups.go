@@ -4,18 +4,52 @@ import (
 	"encoding/csv"
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gomodule/redigo/redis"
 	"github.com/prometheus/client_golang/prometheus"
-	log "github.com/sirupsen/logrus"
 )
 
 type keyGroupMetrics struct {
 	keyGroup    string
 	count       int64
 	memoryUsage int64
+	// number of keys whose memory usage was sampled
+	memorySampled int64
+}
+
+// keyGroupSampling controls how MEMORY USAGE is called for the keys of key groups
+type keyGroupSampling struct {
+	// SAMPLES argument of MEMORY USAGE, empty for the Redis default
+	samples string
+	// fraction of keys whose memory usage is sampled, the memory usage of the
+	// other keys is extrapolated from the sampled ones
+	ratio float64
+}
+
+func (e *Exporter) keyGroupSampling() keyGroupSampling {
+	s := keyGroupSampling{ratio: e.options.KeyGroupsMemorySampleRatio}
+	switch {
+	case e.options.KeyGroupsMemorySamples > 0:
+		s.samples = strconv.FormatInt(e.options.KeyGroupsMemorySamples, 10)
+	case e.options.KeyGroupsMemorySamples < 0:
+		// SAMPLES 0 samples all nested values
+		s.samples = "0"
+	}
+	if s.ratio <= 0 || s.ratio > 1 {
+		s.ratio = 1
+	}
+	return s
+}
+
+// extrapolateMemoryUsage estimates the memory usage of all keys from the sampled ones
+func extrapolateMemoryUsage(memoryUsage, sampled, count int64) int64 {
+	if sampled == 0 || sampled >= count {
+		return memoryUsage
+	}
+	return int64(float64(memoryUsage) / float64(sampled) * float64(count))
 }
 
 type overflowedKeyGroupMetrics struct {
@@ -31,6 +65,11 @@ type keyGroupsScrapeResult struct {
 }
 
 func (e *Exporter) extractKeyGroupMetrics(ch chan<- prometheus.Metric, c redis.Conn, dbCount int) {
+	if len(e.options.KeyGroups) > 0 {
+		e.extractNamedKeyGroupMetrics(ch, c, dbCount)
+		return
+	}
+
 	allDbKeyGroupMetrics := e.gatherKeyGroupsMetricsForAllDatabases(c, dbCount)
 	if allDbKeyGroupMetrics == nil {
 		return
@@ -86,7 +125,7 @@ func (e *Exporter) gatherKeyGroupsMetricsForAllDatabases(c redis.Conn, dbCount i
 		strings.NewReader(e.options.CheckKeyGroups),
 	).Read()
 	if err != nil {
-		log.Errorf("Failed to parse key groups as csv: %s", err)
+		e.logger().Errorf("Failed to parse key groups as csv: %s", err)
 		return allMetrics
 	}
 	for i, v := range keyGroups {
@@ -104,20 +143,30 @@ func (e *Exporter) gatherKeyGroupsMetricsForAllDatabases(c redis.Conn, dbCount i
 	}
 	for db := 0; db < dbCount; db++ {
 		if _, err := doRedisCmd(c, "SELECT", db); err != nil {
-			log.Errorf("Couldn't select database %d when getting key info.", db)
+			e.logger().Errorf("Couldn't select database %d when getting key info.", db)
 			continue
 		}
-		allGroups, err := gatherKeyGroupMetrics(c, e.options.CheckKeysBatchSize, keyGroupsNoEmptyStrings)
+		allGroups, err := gatherKeyGroupMetrics(c, e.options.CheckKeysBatchSize, keyGroupsNoEmptyStrings, e.keyGroupSampling())
 		if err != nil {
-			log.Error(err)
+			e.logger().Error(err)
 			continue
 		}
 		allMetrics.metrics[db] = allGroups
-		if int64(len(allGroups)) > e.options.MaxDistinctKeyGroups {
-			metricsSlice := make([]*keyGroupMetrics, 0, len(allGroups))
-			for _, v := range allGroups {
-				metricsSlice = append(metricsSlice, v)
+
+		// key groups with less than KeyGroupsMinKeys keys are reported in the overflow group
+		metricsSlice := make([]*keyGroupMetrics, 0, len(allGroups))
+		var smallCount, smallMemoryUsage int64
+		for _, v := range allGroups {
+			if v.count < e.options.KeyGroupsMinKeys {
+				smallCount += v.count
+				smallMemoryUsage += v.memoryUsage
+				continue
 			}
+			metricsSlice = append(metricsSlice, v)
+		}
+
+		if int64(len(metricsSlice)) > e.options.MaxDistinctKeyGroups || len(metricsSlice) < len(allGroups) {
+			maxDistinct := min(e.options.MaxDistinctKeyGroups, int64(len(metricsSlice)))
 			sort.Slice(metricsSlice, func(i, j int) bool {
 				if metricsSlice[i].memoryUsage == metricsSlice[j].memoryUsage {
 					if metricsSlice[i].count == metricsSlice[j].count {
@@ -127,13 +176,13 @@ func (e *Exporter) gatherKeyGroupsMetricsForAllDatabases(c redis.Conn, dbCount i
 				}
 				return metricsSlice[i].memoryUsage > metricsSlice[j].memoryUsage
 			})
-			var overflowedCount, overflowedMemoryUsage int64
-			for _, v := range metricsSlice[e.options.MaxDistinctKeyGroups:] {
+			overflowedCount, overflowedMemoryUsage := smallCount, smallMemoryUsage
+			for _, v := range metricsSlice[maxDistinct:] {
 				overflowedCount += v.count
 				overflowedMemoryUsage += v.memoryUsage
 			}
 			allMetrics.overflowedMetrics[db] = &overflowedKeyGroupMetrics{
-				topMemoryUsageKeyGroups: metricsSlice[:e.options.MaxDistinctKeyGroups],
+				topMemoryUsageKeyGroups: metricsSlice[:maxDistinct],
 				overflowKeyGroupAggregate: keyGroupMetrics{
 					keyGroup:    "overflow",
 					count:       overflowedCount,
@@ -146,9 +195,9 @@ func (e *Exporter) gatherKeyGroupsMetricsForAllDatabases(c redis.Conn, dbCount i
 	return allMetrics
 }
 
-func gatherKeyGroupMetrics(c redis.Conn, batchSize int64, keyGroups []string) (map[string]*keyGroupMetrics, error) {
+func gatherKeyGroupMetrics(c redis.Conn, batchSize int64, keyGroups []string, sampling keyGroupSampling) (map[string]*keyGroupMetrics, error) {
 	allGroups := make(map[string]*keyGroupMetrics)
-	keysAndArgs := []interface{}{0, batchSize}
+	keysAndArgs := []interface{}{0, batchSize, sampling.samples, sampling.ratio}
 	for _, keyGroup := range keyGroups {
 		keysAndArgs = append(keysAndArgs, keyGroup)
 	}
@@ -159,26 +208,22 @@ func gatherKeyGroupMetrics(c redis.Conn, batchSize int64, keyGroups []string) (m
 local result = {}
 local batch = redis.call("SCAN", ARGV[1], "COUNT", ARGV[2])
 local groups = {}
-local usage = 0
 local group_index = 0
 local group = nil
 local value = {}
 local key_match_result = {}
 local status = false
 local err = nil
-for i=3,#ARGV do
+local ratio = tonumber(ARGV[4])
+for i=5,#ARGV do
   status, err = pcall(string.find, " ", ARGV[i])
   if not status then
     error(err .. ARGV[i])
   end
 end
 for i,key in ipairs(batch[2]) do
-  local reply = redis.pcall("MEMORY", "USAGE", key)  
-  if type(reply) == "number" then
-    usage = reply;
-  end
   group = nil
-  for i=3,#ARGV do
+  for i=5,#ARGV do
     key_match_result = {string.find(key, ARGV[i])}
     if key_match_result[1] ~= nil then
       group = table.concat({unpack(key_match_result, 3,  #key_match_result)},  "")
@@ -190,13 +235,26 @@ for i,key in ipairs(batch[2]) do
   end
   value = groups[group]
   if value == nil then
-     groups[group] = {1, usage}
-  else
-     groups[group] = {value[1] + 1, value[2] + usage}
+     value = {0, 0, 0}
+     groups[group] = value
+  end
+  value[1] = value[1] + 1
+  -- sample the memory usage of ratio of the keys of each group
+  if math.ceil(value[1] * ratio) > math.ceil((value[1] - 1) * ratio) then
+    local reply
+    if ARGV[3] == "" then
+      reply = redis.pcall("MEMORY", "USAGE", key)
+    else
+      reply = redis.pcall("MEMORY", "USAGE", key, "SAMPLES", ARGV[3])
+    end
+    if type(reply) == "number" then
+      value[2] = value[2] + reply
+    end
+    value[3] = value[3] + 1
   end
 end
 for group,value in pairs(groups) do
-  result[#result+1] = {group, value[1], value[2]}
+  result[#result+1] = {group, value[1], value[2], value[3]}
 end
 return {batch[1], result}`,
 	)
@@ -218,15 +276,18 @@ return {batch[1], result}`,
 			name, _ := redis.String(metricsArr[0], nil)
 			count, _ := redis.Int64(metricsArr[1], nil)
 			memoryUsage, _ := redis.Int64(metricsArr[2], nil)
+			memorySampled, _ := redis.Int64(metricsArr[3], nil)
 
 			if currentMetrics, ok := allGroups[name]; ok {
 				currentMetrics.count += count
 				currentMetrics.memoryUsage += memoryUsage
+				currentMetrics.memorySampled += memorySampled
 			} else {
 				allGroups[name] = &keyGroupMetrics{
-					keyGroup:    name,
-					count:       count,
-					memoryUsage: memoryUsage,
+					keyGroup:      name,
+					count:         count,
+					memoryUsage:   memoryUsage,
+					memorySampled: memorySampled,
 				}
 			}
 
@@ -235,5 +296,9 @@ return {batch[1], result}`,
 			break
 		}
 	}
+
+	for _, m := range allGroups {
+		m.memoryUsage = extrapolateMemoryUsage(m.memoryUsage, m.memorySampled, m.count)
+	}
 	return allGroups, nil
 }
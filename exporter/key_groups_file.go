@@ -0,0 +1,321 @@
+package exporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+// KeyGroup is a named group of keys from the key groups file, keys are assigned to the
+// first group with a matching Lua pattern, the captures of the pattern form the key_group label
+type KeyGroup struct {
+	Name         string   `json:"name"`
+	Patterns     []string `json:"patterns"`
+	Aggregations []string `json:"aggregations"`
+	MaxDistinct  int64    `json:"max_distinct"`
+}
+
+var keyGroupAggregations = map[string]string{"count": "c", "memory": "m", "ttl": "t"}
+
+func (g KeyGroup) aggregates(aggregation string) bool {
+	for _, a := range g.Aggregations {
+		if a == aggregation {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadKeyGroupsFile reads the key groups file and validates the groups
+func LoadKeyGroupsFile(keyGroupsFile string) ([]KeyGroup, error) {
+	log.Debugf("start load key groups file: %s", keyGroupsFile)
+	bytes, err := os.ReadFile(keyGroupsFile)
+	if err != nil {
+		log.Warnf("load key groups file failed: %s", err)
+		return nil, err
+	}
+
+	var groups []KeyGroup
+	if err := json.Unmarshal(bytes, &groups); err != nil {
+		log.Warnf("key groups file format error: %s", err)
+		return nil, err
+	}
+
+	names := map[string]bool{}
+	for i, g := range groups {
+		if g.Name == "" || names[g.Name] {
+			return nil, fmt.Errorf("key group names must be set and unique, got: %q", g.Name)
+		}
+		names[g.Name] = true
+
+		if len(g.Patterns) == 0 {
+			return nil, fmt.Errorf("key group %s: at least one pattern is required", g.Name)
+		}
+		if g.MaxDistinct < 0 {
+			return nil, fmt.Errorf("key group %s: max_distinct must not be negative", g.Name)
+		}
+
+		if len(g.Aggregations) == 0 {
+			groups[i].Aggregations = []string{"count", "memory"}
+		}
+		for _, a := range groups[i].Aggregations {
+			if _, ok := keyGroupAggregations[a]; !ok {
+				return nil, fmt.Errorf("key group %s: invalid aggregation %q, valid aggregations are count, memory and ttl", g.Name, a)
+			}
+		}
+	}
+
+	log.Infof("Loaded %d key groups from %s", len(groups), keyGroupsFile)
+	return groups, nil
+}
+
+type namedKeyGroupMetrics struct {
+	group       string
+	keyGroup    string
+	count       int64
+	memoryUsage int64
+	keysWithTTL int64
+	ttlSumMs    int64
+	// number of keys whose memory usage was sampled
+	memorySampled int64
+}
+
+func (m *namedKeyGroupMetrics) add(o *namedKeyGroupMetrics) {
+	m.count += o.count
+	m.memoryUsage += o.memoryUsage
+	m.keysWithTTL += o.keysWithTTL
+	m.ttlSumMs += o.ttlSumMs
+	m.memorySampled += o.memorySampled
+}
+
+var namedKeyGroupsScript = redis.NewScript(
+	0,
+	`
+local batch = redis.call("SCAN", ARGV[1], "COUNT", ARGV[2])
+local samples = ARGV[3]
+local ratio = tonumber(ARGV[4])
+local groups = {}
+local pos = 6
+for g=1,tonumber(ARGV[5]) do
+  local group = {aggregations = ARGV[pos], patterns = {}}
+  local n = tonumber(ARGV[pos+1])
+  for p=1,n do
+    local status, err = pcall(string.find, " ", ARGV[pos+1+p])
+    if not status then
+      error(err .. ARGV[pos+1+p])
+    end
+    group.patterns[p] = ARGV[pos+1+p]
+  end
+  pos = pos + 2 + n
+  groups[g] = group
+end
+local groupMetrics = {}
+for _,key in ipairs(batch[2]) do
+  for g,group in ipairs(groups) do
+    local keyGroup = nil
+    for _,pattern in ipairs(group.patterns) do
+      local match = {string.find(key, pattern)}
+      if match[1] ~= nil then
+        keyGroup = table.concat({unpack(match, 3, #match)}, "")
+        break
+      end
+    end
+    if keyGroup ~= nil then
+      local id = g .. ":" .. keyGroup
+      local value = groupMetrics[id]
+      if value == nil then
+        value = {g, keyGroup, 0, 0, 0, 0, 0}
+        groupMetrics[id] = value
+      end
+      value[3] = value[3] + 1
+      -- sample the memory usage of ratio of the keys of each key group
+      if string.find(group.aggregations, "m", 1, true) and math.ceil(value[3] * ratio) > math.ceil((value[3] - 1) * ratio) then
+        local reply
+        if samples == "" then
+          reply = redis.pcall("MEMORY", "USAGE", key)
+        else
+          reply = redis.pcall("MEMORY", "USAGE", key, "SAMPLES", samples)
+        end
+        if type(reply) == "number" then
+          value[4] = value[4] + reply
+        end
+        value[7] = value[7] + 1
+      end
+      if string.find(group.aggregations, "t", 1, true) then
+        local ttl = redis.call("PTTL", key)
+        if ttl > 0 then
+          value[5] = value[5] + 1
+          value[6] = value[6] + ttl
+        end
+      end
+      break
+    end
+  end
+end
+local result = {}
+for _,value in pairs(groupMetrics) do
+  result[#result+1] = value
+end
+return {batch[1], result}`,
+)
+
+// gatherNamedKeyGroupMetrics scans the keys of the selected database and aggregates them by group and key group
+func gatherNamedKeyGroupMetrics(c redis.Conn, batchSize int64, groups []KeyGroup, sampling keyGroupSampling) (map[string]*namedKeyGroupMetrics, error) {
+	keysAndArgs := []interface{}{0, batchSize, sampling.samples, sampling.ratio, len(groups)}
+	for _, g := range groups {
+		aggregations := ""
+		for _, a := range g.Aggregations {
+			aggregations += keyGroupAggregations[a]
+		}
+		keysAndArgs = append(keysAndArgs, aggregations, len(g.Patterns))
+		for _, p := range g.Patterns {
+			keysAndArgs = append(keysAndArgs, p)
+		}
+	}
+
+	all := map[string]*namedKeyGroupMetrics{}
+	for {
+		arr, err := redis.Values(namedKeyGroupsScript.Do(c, keysAndArgs...))
+		if err != nil {
+			return nil, err
+		}
+		if len(arr) != 2 {
+			return nil, fmt.Errorf("invalid response from named key group metrics lua script")
+		}
+
+		rows, _ := redis.Values(arr[1], nil)
+		for _, row := range rows {
+			values, _ := redis.Values(row, nil)
+			if len(values) != 7 {
+				continue
+			}
+			idx, _ := redis.Int(values[0], nil)
+			if idx < 1 || idx > len(groups) {
+				continue
+			}
+
+			m := &namedKeyGroupMetrics{group: groups[idx-1].Name}
+			m.keyGroup, _ = redis.String(values[1], nil)
+			m.count, _ = redis.Int64(values[2], nil)
+			m.memoryUsage, _ = redis.Int64(values[3], nil)
+			m.keysWithTTL, _ = redis.Int64(values[4], nil)
+			m.ttlSumMs, _ = redis.Int64(values[5], nil)
+			m.memorySampled, _ = redis.Int64(values[6], nil)
+
+			id := m.group + "\x00" + m.keyGroup
+			if current, ok := all[id]; ok {
+				current.add(m)
+			} else {
+				all[id] = m
+			}
+		}
+
+		if keysAndArgs[0], _ = redis.Int(arr[0], nil); keysAndArgs[0].(int) == 0 {
+			break
+		}
+	}
+
+	for _, m := range all {
+		m.memoryUsage = extrapolateMemoryUsage(m.memoryUsage, m.memorySampled, m.count)
+	}
+	return all, nil
+}
+
+// limitKeyGroups keeps the maxDistinct key groups with at least minKeys keys with the highest memory
+// usage (or count if memory isn't aggregated) and sums up the remaining ones in the "overflow" key group
+func limitKeyGroups(g KeyGroup, metrics []*namedKeyGroupMetrics, maxDistinct, minKeys int64) []*namedKeyGroupMetrics {
+	overflow := &namedKeyGroupMetrics{group: g.Name, keyGroup: "overflow"}
+	kept := make([]*namedKeyGroupMetrics, 0, len(metrics))
+	for _, m := range metrics {
+		if m.count < minKeys {
+			overflow.add(m)
+			continue
+		}
+		kept = append(kept, m)
+	}
+	metrics = kept
+
+	if maxDistinct <= 0 || int64(len(metrics)) <= maxDistinct {
+		if overflow.count > 0 {
+			metrics = append(metrics, overflow)
+		}
+		return metrics
+	}
+
+	byMemory := g.aggregates("memory")
+	sort.Slice(metrics, func(i, j int) bool {
+		if byMemory && metrics[i].memoryUsage != metrics[j].memoryUsage {
+			return metrics[i].memoryUsage > metrics[j].memoryUsage
+		}
+		if metrics[i].count != metrics[j].count {
+			return metrics[i].count > metrics[j].count
+		}
+		return metrics[i].keyGroup < metrics[j].keyGroup
+	})
+
+	for _, m := range metrics[maxDistinct:] {
+		overflow.add(m)
+	}
+	return append(metrics[:maxDistinct:maxDistinct], overflow)
+}
+
+func (e *Exporter) extractNamedKeyGroupMetrics(ch chan<- prometheus.Metric, c redis.Conn, dbCount int) {
+	start := time.Now()
+
+	for db := 0; db < dbCount; db++ {
+		if _, err := doRedisCmd(c, "SELECT", db); err != nil {
+			e.logger().Errorf("Couldn't select database %d when getting key info.", db)
+			continue
+		}
+		all, err := gatherNamedKeyGroupMetrics(c, e.options.CheckKeysBatchSize, e.options.KeyGroups, e.keyGroupSampling())
+		if err != nil {
+			e.logger().Error(err)
+			continue
+		}
+
+		dbLabel := fmt.Sprintf("db%d", db)
+		for _, g := range e.options.KeyGroups {
+			var metrics []*namedKeyGroupMetrics
+			for _, m := range all {
+				if m.group == g.Name {
+					metrics = append(metrics, m)
+				}
+			}
+			if len(metrics) == 0 {
+				continue
+			}
+			e.registerConstMetricGauge(ch, "number_of_distinct_key_groups", float64(len(metrics)), dbLabel, g.Name)
+
+			maxDistinct := g.MaxDistinct
+			if maxDistinct == 0 {
+				maxDistinct = e.options.MaxDistinctKeyGroups
+			}
+			for _, m := range limitKeyGroups(g, metrics, maxDistinct, e.options.KeyGroupsMinKeys) {
+				e.registerNamedKeyGroupMetrics(ch, g, m, dbLabel)
+			}
+		}
+	}
+
+	e.registerConstMetricGauge(ch, "last_key_groups_scrape_duration_milliseconds", float64(time.Since(start).Milliseconds()))
+}
+
+func (e *Exporter) registerNamedKeyGroupMetrics(ch chan<- prometheus.Metric, g KeyGroup, m *namedKeyGroupMetrics, dbLabel string) {
+	if g.aggregates("count") {
+		e.registerConstMetricGauge(ch, "key_group_count", float64(m.count), dbLabel, g.Name, m.keyGroup)
+	}
+	if g.aggregates("memory") {
+		e.registerConstMetricGauge(ch, "key_group_memory_usage_bytes", float64(m.memoryUsage), dbLabel, g.Name, m.keyGroup)
+	}
+	if g.aggregates("ttl") {
+		e.registerConstMetricGauge(ch, "key_group_keys_with_ttl", float64(m.keysWithTTL), dbLabel, g.Name, m.keyGroup)
+		if m.keysWithTTL > 0 {
+			e.registerConstMetricGauge(ch, "key_group_avg_ttl_seconds", float64(m.ttlSumMs)/float64(m.keysWithTTL)/1000, dbLabel, g.Name, m.keyGroup)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package exporter
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestLoadKeyGroupsFile(t *testing.T) {
+	groups, err := LoadKeyGroupsFile("../contrib/sample-key-groups.json")
+	if err != nil {
+		t.Fatalf("LoadKeyGroupsFile() err: %s", err)
+	}
+	if len(groups) != 3 || groups[0].Name != "sessions" || !groups[0].aggregates("ttl") || groups[1].MaxDistinct != 20 {
+		t.Errorf("unexpected key groups: %#v", groups)
+	}
+
+	if _, err := LoadKeyGroupsFile("non-existent.json"); err == nil {
+		t.Errorf("expected error for missing file")
+	}
+
+	for _, content := range []string{
+		`[{"patterns": ["^a"]}]`,
+		`[{"name": "a", "patterns": []}]`,
+		`[{"name": "a", "patterns": ["^a"]}, {"name": "a", "patterns": ["^b"]}]`,
+		`[{"name": "a", "patterns": ["^a"], "aggregations": ["size"]}]`,
+		`[{"name": "a", "patterns": ["^a"], "max_distinct": -1}]`,
+	} {
+		f := filepath.Join(t.TempDir(), "key-groups.json")
+		if err := os.WriteFile(f, []byte(content), 0o600); err != nil {
+			t.Fatalf("WriteFile() err: %s", err)
+		}
+		if _, err := LoadKeyGroupsFile(f); err == nil {
+			t.Errorf("expected error for key groups: %s", content)
+		}
+	}
+
+	f := filepath.Join(t.TempDir(), "key-groups.json")
+	if err := os.WriteFile(f, []byte(`[{"name": "a", "patterns": ["^a"]}]`), 0o600); err != nil {
+		t.Fatalf("WriteFile() err: %s", err)
+	}
+	if groups, err := LoadKeyGroupsFile(f); err != nil || !groups[0].aggregates("count") || !groups[0].aggregates("memory") || groups[0].aggregates("ttl") {
+		t.Errorf("expected count and memory aggregations by default, got: %#v err: %v", groups, err)
+	}
+}
+
+func TestNamedKeyGroupMetrics(t *testing.T) {
+	row := func(group int64, keyGroup string, count, memory, withTTL, ttlSumMs int64) []interface{} {
+		return []interface{}{group, []byte(keyGroup), count, memory, withTTL, ttlSumMs, count}
+	}
+
+	c := &fakeRedisConn{
+		replies: map[string]interface{}{"SELECT 0": "OK"},
+		prefixReplies: map[string]interface{}{
+			"EVALSHA": []interface{}{[]byte("0"), []interface{}{
+				row(1, "", 10, 1000, 4, 8000),
+				row(2, "users", 5, 500, 0, 0),
+				row(2, "items", 3, 900, 0, 0),
+				row(2, "orders", 1, 100, 0, 0),
+			}},
+		},
+	}
+
+	e, _ := NewRedisExporter("", Options{
+		Namespace:            "test",
+		MaxDistinctKeyGroups: 100,
+		KeyGroups: []KeyGroup{
+			{Name: "sessions", Patterns: []string{"^session:"}, Aggregations: []string{"count", "ttl"}},
+			{Name: "cache", Patterns: []string{"^cache:([^:]+):"}, Aggregations: []string{"count", "memory"}, MaxDistinct: 2},
+		},
+	})
+
+	chM := make(chan prometheus.Metric)
+	go func() {
+		e.extractKeyGroupMetrics(chM, c, 1)
+		close(chM)
+	}()
+
+	got := map[string]float64{}
+	for m := range chM {
+		d := &dto.Metric{}
+		m.Write(d)
+		desc := m.Desc().String()
+		name := desc[strings.Index(desc, `"`)+1:]
+		name = name[:strings.Index(name, `"`)]
+
+		lbls := []string{}
+		for _, l := range d.GetLabel() {
+			lbls = append(lbls, l.GetName()+"="+l.GetValue())
+		}
+		got[name+"{"+strings.Join(lbls, ",")+"}"] = d.GetGauge().GetValue()
+	}
+
+	for series, want := range map[string]float64{
+		"test_key_group_count{db=db0,group=sessions,key_group=}":                   10,
+		"test_key_group_keys_with_ttl{db=db0,group=sessions,key_group=}":           4,
+		"test_key_group_avg_ttl_seconds{db=db0,group=sessions,key_group=}":         2,
+		"test_key_group_count{db=db0,group=cache,key_group=items}":                 3,
+		"test_key_group_memory_usage_bytes{db=db0,group=cache,key_group=items}":    900,
+		"test_key_group_count{db=db0,group=cache,key_group=users}":                 5,
+		"test_key_group_count{db=db0,group=cache,key_group=overflow}":              1,
+		"test_key_group_memory_usage_bytes{db=db0,group=cache,key_group=overflow}": 100,
+		"test_number_of_distinct_key_groups{db=db0,group=cache}":                   3,
+	} {
+		if v, ok := got[series]; !ok || v != want {
+			t.Errorf("expected %s = %f, got: %f (found: %t)", series, want, v, ok)
+		}
+	}
+
+	for _, series := range []string{
+		"test_key_group_memory_usage_bytes{db=db0,group=sessions,key_group=}",
+		"test_key_group_count{db=db0,group=cache,key_group=orders}",
+	} {
+		if _, ok := got[series]; ok {
+			t.Errorf("unexpected series %s", series)
+		}
+	}
+}
+
+func TestKeyGroupsMutuallyExclusive(t *testing.T) {
+	_, err := NewRedisExporter("", Options{
+		Namespace:      "test",
+		CheckKeyGroups: "^(a)",
+		KeyGroups:      []KeyGroup{{Name: "a", Patterns: []string{"^a"}}},
+	})
+	if err == nil {
+		t.Errorf("expected error when both check-key-groups and key groups file are set")
+	}
+}
+
+func TestLimitKeyGroups(t *testing.T) {
+	g := KeyGroup{Name: "cache", Aggregations: []string{"count", "memory"}}
+	metrics := func() []*namedKeyGroupMetrics {
+		return []*namedKeyGroupMetrics{
+			{group: "cache", keyGroup: "a", count: 100, memoryUsage: 1000},
+			{group: "cache", keyGroup: "b", count: 2, memoryUsage: 5000},
+			{group: "cache", keyGroup: "c", count: 50, memoryUsage: 500},
+		}
+	}
+
+	for _, tst := range []struct {
+		name        string
+		maxDistinct int64
+		minKeys     int64
+		want        map[string]int64
+	}{
+		{name: "no limits", want: map[string]int64{"a": 100, "b": 2, "c": 50}},
+		{name: "max distinct", maxDistinct: 2, want: map[string]int64{"b": 2, "a": 100, "overflow": 50}},
+		{name: "min keys", minKeys: 10, want: map[string]int64{"a": 100, "c": 50, "overflow": 2}},
+		{name: "min keys and max distinct", maxDistinct: 1, minKeys: 10, want: map[string]int64{"a": 100, "overflow": 52}},
+	} {
+		t.Run(tst.name, func(t *testing.T) {
+			got := map[string]int64{}
+			for _, m := range limitKeyGroups(g, metrics(), tst.maxDistinct, tst.minKeys) {
+				got[m.keyGroup] = m.count
+			}
+			if !reflect.DeepEqual(got, tst.want) {
+				t.Errorf("expected %v, got: %v", tst.want, got)
+			}
+		})
+	}
+}
+
+func TestKeyGroupMemorySampling(t *testing.T) {
+	c := &fakeRedisConn{
+		prefixReplies: map[string]interface{}{
+			// 10 keys of which 2 were sampled with 300 bytes in total
+			"EVALSHA": []interface{}{[]byte("0"), []interface{}{
+				[]interface{}{[]byte("users"), int64(10), int64(300), int64(2)},
+			}},
+		},
+	}
+
+	groups, err := gatherKeyGroupMetrics(c, 1000, []string{"^(users):"}, keyGroupSampling{ratio: 0.2})
+	if err != nil {
+		t.Fatalf("gatherKeyGroupMetrics() err: %s", err)
+	}
+	if m := groups["users"]; m == nil || m.count != 10 || m.memoryUsage != 1500 {
+		t.Errorf("expected 10 keys with an extrapolated memory usage of 1500, got: %#v", m)
+	}
+
+	for _, tst := range []struct {
+		samples int64
+		ratio   float64
+		want    keyGroupSampling
+	}{
+		{want: keyGroupSampling{samples: "", ratio: 1}},
+		{samples: 10, ratio: 0.5, want: keyGroupSampling{samples: "10", ratio: 0.5}},
+		{samples: -1, want: keyGroupSampling{samples: "0", ratio: 1}},
+	} {
+		e, _ := NewRedisExporter("", Options{Namespace: "test", KeyGroupsMemorySamples: tst.samples, KeyGroupsMemorySampleRatio: tst.ratio})
+		if got := e.keyGroupSampling(); got != tst.want {
+			t.Errorf("expected sampling %#v, got: %#v", tst.want, got)
+		}
+	}
+
+	if _, err := NewRedisExporter("", Options{Namespace: "test", KeyGroupsMemorySampleRatio: 1.5}); err == nil {
+		t.Errorf("expected error for sample ratio > 1")
+	}
+}
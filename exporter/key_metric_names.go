@@ -0,0 +1,84 @@
+package exporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+var keyMetricNameRE = regexp.MustCompile(`^[a-zA-Z0-9_:]+$`)
+
+// LoadKeyMetricNamesFile reads a JSON file mapping key names to the suffix of
+// their metric names when check-keys metrics are exported with the key in the name
+func LoadKeyMetricNamesFile(namesFile string) (map[string]string, error) {
+	res := make(map[string]string)
+
+	log.Debugf("start load key metric names file: %s", namesFile)
+	bytes, err := os.ReadFile(namesFile)
+	if err != nil {
+		log.Warnf("load key metric names file failed: %s", err)
+		return nil, err
+	}
+	err = json.Unmarshal(bytes, &res)
+	if err != nil {
+		log.Warnf("key metric names file format error: %s", err)
+		return nil, err
+	}
+
+	names := map[string]string{}
+	for key, name := range res {
+		if !keyMetricNameRE.MatchString(name) {
+			return nil, fmt.Errorf("invalid metric name %q for key %s", name, key)
+		}
+		if other, ok := names[name]; ok {
+			return nil, fmt.Errorf("keys %s and %s both use the metric name %s", other, key, name)
+		}
+		names[name] = key
+	}
+
+	log.Infof("Loaded %d key metric names from %s", len(res), namesFile)
+	return res, nil
+}
+
+// keyMetricName returns the metric name suffix of keyName, either from the
+// key metric names file or the sanitized key name
+func (e *Exporter) keyMetricName(keyName string) string {
+	if name, ok := e.options.KeyMetricNames[keyName]; ok {
+		return name
+	}
+	return sanitizeMetricName(keyName)
+}
+
+// registerKeyMetric exports a check-keys metric like key_size of keyName, either with
+// the key as label (key_size{db, key}) or with the key in the metric name (key_size_<key>{db}),
+// while the keys of a pattern are aggregated it's only added to the aggregate and while
+// they're ranked for check-keys-top-n it's buffered until the top keys are known
+func (e *Exporter) registerKeyMetric(ch chan<- prometheus.Metric, metric string, val float64, dbLabel string, keyName string, labelValues ...string) {
+	if e.keyAggregate != nil {
+		e.keyAggregate.add(metric, val)
+		return
+	}
+	if e.keyTopN != nil {
+		e.keyTopN.add(metric, val, dbLabel, keyName, labelValues)
+		return
+	}
+
+	if !e.options.CheckKeysAsMetricNames {
+		e.registerConstMetricGauge(ch, metric, val, append([]string{dbLabel, e.labelValue(ch, keyName)}, labelValues...)...)
+		return
+	}
+
+	name := metric + "_" + e.keyMetricName(keyName)
+	labels := []string{"db"}
+	if metric == "key_value_as_string" {
+		labels = append(labels, "val")
+	}
+	if _, ok := e.addMetricDescription(name, keyName, fmt.Sprintf("%s metric of key %q", metric, keyName), labels); !ok {
+		return
+	}
+	e.registerConstMetricGauge(ch, name, val, append([]string{dbLabel}, labelValues...)...)
+}
@@ -0,0 +1,139 @@
+package exporter
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestLoadKeyMetricNamesFile(t *testing.T) {
+	names, err := LoadKeyMetricNamesFile("../contrib/sample-key-metric-names.json")
+	if err != nil {
+		t.Fatalf("LoadKeyMetricNamesFile() err: %s", err)
+	}
+	if names["queue:jobs:pending"] != "jobs_pending" {
+		t.Errorf("unexpected names: %#v", names)
+	}
+
+	if _, err := LoadKeyMetricNamesFile("non-existent.json"); err == nil {
+		t.Errorf("expected error for missing file")
+	}
+
+	for _, content := range []string{
+		`{"a": "invalid-name"}`,
+		`{"a": ""}`,
+		`{"a": "same", "b": "same"}`,
+		`["a"]`,
+	} {
+		f := filepath.Join(t.TempDir(), "names.json")
+		if err := os.WriteFile(f, []byte(content), 0o600); err != nil {
+			t.Fatalf("WriteFile() err: %s", err)
+		}
+		if _, err := LoadKeyMetricNamesFile(f); err == nil {
+			t.Errorf("expected error for names: %s", content)
+		}
+	}
+}
+
+func TestCheckKeysAsMetricNames(t *testing.T) {
+	e, _ := NewRedisExporter("", Options{
+		Namespace:              "test",
+		CheckKeysAsMetricNames: true,
+		KeyMetricNames:         map[string]string{"queue:jobs:pending": "jobs_pending"},
+	})
+
+	chM := make(chan prometheus.Metric, 10)
+	e.registerKeyMetric(chM, "key_size", 3, "db0", "queue:jobs:pending")
+	e.registerKeyMetric(chM, "key_memory_usage_bytes", 64, "db0", "my.queue")
+	e.registerKeyMetric(chM, "key_value_as_string", 1, "db1", "my.queue", "hello")
+	close(chM)
+
+	want := map[string]map[string]string{
+		`"test_key_size_jobs_pending"`:           {"db": "db0"},
+		`"test_key_memory_usage_bytes_my_queue"`: {"db": "db0"},
+		`"test_key_value_as_string_my_queue"`:    {"db": "db1", "val": "hello"},
+	}
+	for m := range chM {
+		d := &dto.Metric{}
+		m.Write(d)
+		labels := map[string]string{}
+		for _, l := range d.GetLabel() {
+			labels[l.GetName()] = l.GetValue()
+		}
+
+		found := false
+		for name, wantLabels := range want {
+			if strings.Contains(m.Desc().String(), name) {
+				found = true
+				if !reflect.DeepEqual(labels, wantLabels) {
+					t.Errorf("%s: got labels %v, want: %v", name, labels, wantLabels)
+				}
+				delete(want, name)
+			}
+		}
+		if !found {
+			t.Errorf("unexpected metric: %s", m.Desc())
+		}
+	}
+	if len(want) > 0 {
+		t.Errorf("missing metrics: %v", want)
+	}
+}
+
+func TestCheckKeysAsMetricNamesCollision(t *testing.T) {
+	e, _ := NewRedisExporter("", Options{Namespace: "test", CheckKeysAsMetricNames: true})
+
+	chM := make(chan prometheus.Metric, 10)
+	e.registerKeyMetric(chM, "key_size", 3, "db0", "my.queue")
+	e.registerKeyMetric(chM, "key_size", 5, "db0", "my-queue")
+	e.registerKeyMetric(chM, "key_size", 7, "db1", "my.queue")
+	close(chM)
+
+	var got []float64
+	for m := range chM {
+		d := &dto.Metric{}
+		m.Write(d)
+		got = append(got, d.GetGauge().GetValue())
+	}
+	if !reflect.DeepEqual(got, []float64{3, 7}) {
+		t.Errorf("expected only the series of the first key of test_key_size_my_queue, got: %v", got)
+	}
+}
+
+func TestMaxMetricDescriptions(t *testing.T) {
+	e, _ := NewRedisExporter("", Options{Namespace: "test", CheckKeysAsMetricNames: true})
+
+	chM := make(chan prometheus.Metric, maxMetricDescriptions)
+	for i := 0; i < maxMetricDescriptions; i++ {
+		e.registerKeyMetric(chM, "key_size", 1, "db0", fmt.Sprintf("key%d", i))
+	}
+	close(chM)
+
+	if n := len(e.metricDescriptions); n != maxMetricDescriptions {
+		t.Errorf("expected %d metric descriptions, got: %d", maxMetricDescriptions, n)
+	}
+	if n := len(chM); n >= maxMetricDescriptions {
+		t.Errorf("expected the keys over the limit to be dropped, got %d metrics", n)
+	}
+}
+
+func TestCheckKeysAsLabels(t *testing.T) {
+	e, _ := NewRedisExporter("", Options{Namespace: "test"})
+
+	chM := make(chan prometheus.Metric, 1)
+	e.registerKeyMetric(chM, "key_size", 3, "db0", "my.queue")
+	close(chM)
+
+	m := <-chM
+	d := &dto.Metric{}
+	m.Write(d)
+	if !strings.Contains(m.Desc().String(), `"test_key_size"`) || len(d.GetLabel()) != 2 {
+		t.Errorf("expected key_size with db and key labels, got: %s %s", m.Desc(), d)
+	}
+}
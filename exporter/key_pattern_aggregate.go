@@ -0,0 +1,63 @@
+package exporter
+
+import (
+	"math"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// keyPatternAggregate sums up the key metrics of the keys matching a check-keys pattern
+type keyPatternAggregate struct {
+	keys        int64
+	sizeSum     float64
+	sizeMin     float64
+	sizeMax     float64
+	memoryBytes float64
+}
+
+func newKeyPatternAggregate() *keyPatternAggregate {
+	return &keyPatternAggregate{sizeMin: math.Inf(1), sizeMax: math.Inf(-1)}
+}
+
+// add records a key metric, the values of the keys aren't aggregated
+func (a *keyPatternAggregate) add(metric string, val float64) {
+	switch metric {
+	case "key_size":
+		a.keys++
+		a.sizeSum += val
+		a.sizeMin = math.Min(a.sizeMin, val)
+		a.sizeMax = math.Max(a.sizeMax, val)
+	case "key_memory_usage_bytes":
+		a.memoryBytes += val
+	}
+}
+
+// extractAggregatedCheckKeyMetrics exports the number, sizes and memory usage of the keys
+// matching the pattern of k instead of the metrics of each key
+func (e *Exporter) extractAggregatedCheckKeyMetrics(ch chan<- prometheus.Metric, c redis.Conn, k dbKeyPair) error {
+	agg := newKeyPatternAggregate()
+	e.keyAggregate = agg
+	err := e.extractScannedCheckKeyMetrics(ch, c, k)
+	e.keyAggregate = nil
+	if err != nil {
+		return err
+	}
+
+	// cluster mode only has one db
+	dbLabel := "db" + k.db
+	if e.options.IsCluster {
+		dbLabel = "db0"
+	}
+
+	pattern := e.labelValue(ch, k.key)
+	e.registerConstMetricGauge(ch, "key_pattern_keys", float64(agg.keys), dbLabel, pattern)
+	e.registerConstMetricGauge(ch, "key_pattern_size_sum", agg.sizeSum, dbLabel, pattern)
+	e.registerConstMetricGauge(ch, "key_pattern_memory_usage_bytes", agg.memoryBytes, dbLabel, pattern)
+	if agg.keys > 0 {
+		e.registerConstMetricGauge(ch, "key_pattern_size_min", agg.sizeMin, dbLabel, pattern)
+		e.registerConstMetricGauge(ch, "key_pattern_size_max", agg.sizeMax, dbLabel, pattern)
+		e.registerConstMetricGauge(ch, "key_pattern_size_avg", agg.sizeSum/float64(agg.keys), dbLabel, pattern)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package exporter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestAggregatedCheckKeyMetrics(t *testing.T) {
+	c := &pipelineConn{fakeRedisConn: fakeRedisConn{replies: map[string]interface{}{
+		"SELECT 0":                               []byte("OK"),
+		"SCAN 0 MATCH session:* COUNT 1000":      []interface{}{[]byte("0"), []interface{}{[]byte("session:1"), []byte("session:2"), []byte("session:3")}},
+		"SCAN 0 MATCH missing:* COUNT 1000":      []interface{}{[]byte("0"), []interface{}{}},
+		"TYPE session:1":                         []byte("list"),
+		"TYPE session:2":                         []byte("hash"),
+		"TYPE session:3":                         []byte("string"),
+		"MEMORY USAGE session:1":                 int64(100),
+		"MEMORY USAGE session:2":                 int64(200),
+		"MEMORY USAGE session:3":                 int64(50),
+		"LLEN session:1":                         int64(4),
+		"HLEN session:2":                         int64(10),
+		"PFCOUNT session:3":                      redis.Error("WRONGTYPE Key is not a valid HyperLogLog string value."),
+		"STRLEN session:3":                       int64(1),
+		"GET session:3":                          []byte("5"),
+		"CONFIG GET check-keys-aggregate-unused": nil,
+	}}}
+
+	e, _ := NewRedisExporter("redis://localhost:6379", Options{Namespace: "test", CheckKeys: "db0=session:*,db0=missing:*", CheckKeysAggregate: true, CheckKeysBatchSize: 1000})
+
+	chM := make(chan prometheus.Metric)
+	go func() {
+		if err := e.extractCheckKeyMetrics(chM, c); err != nil {
+			t.Errorf("extractCheckKeyMetrics() err: %s", err)
+		}
+		close(chM)
+	}()
+
+	got := map[string]float64{}
+	for m := range chM {
+		desc := m.Desc().String()
+		name := desc[strings.Index(desc, `"`)+1:]
+		name = name[:strings.Index(name, `"`)]
+		d := &dto.Metric{}
+		m.Write(d)
+		for _, l := range d.GetLabel() {
+			if l.GetName() == "pattern" {
+				name += "/" + l.GetValue()
+			}
+		}
+		got[name] = d.GetGauge().GetValue()
+	}
+
+	want := map[string]float64{
+		"test_key_pattern_keys/session:*":               3,
+		"test_key_pattern_size_sum/session:*":           15,
+		"test_key_pattern_size_min/session:*":           1,
+		"test_key_pattern_size_max/session:*":           10,
+		"test_key_pattern_size_avg/session:*":           5,
+		"test_key_pattern_memory_usage_bytes/session:*": 350,
+		"test_key_pattern_keys/missing:*":               0,
+		"test_key_pattern_size_sum/missing:*":           0,
+		"test_key_pattern_memory_usage_bytes/missing:*": 0,
+	}
+	if len(got) != len(want) {
+		t.Errorf("expected only the aggregated metrics, got: %v", got)
+	}
+	for name, val := range want {
+		if v, ok := got[name]; !ok || v != val {
+			t.Errorf("expected %s = %f, got: %f (found: %t)", name, val, v, ok)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package exporter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// keySampleStats sums up the sampled keys of one type
+type keySampleStats struct {
+	keys        int64
+	expiring    int64
+	memoryBytes float64
+}
+
+// extractKeySampleMetrics picks KeySampleCount random keys of each database of KeySampleDbs
+// with RANDOMKEY and exports the approximate composition of the keyspace by type, it's a
+// cheap alternative to SCANning all keys as it costs a fixed number of commands per scrape.
+// RANDOMKEY picks a random hash table bucket and then a key of it, so the sample is biased
+// towards keys alone in their bucket and larger samples don't remove the bias.
+// In cluster mode KeySampleCount keys of every master are sampled, see sampleClusterKeys()
+func (e *Exporter) extractKeySampleMetrics(ch chan<- prometheus.Metric, c redis.Conn) {
+	if e.options.KeySampleCount <= 0 {
+		return
+	}
+	hasMemoryUsage := e.compatLevel < compatLevelNoMemory
+
+	if e.options.IsCluster {
+		stats, sampled, err := e.sampleClusterKeys(c, hasMemoryUsage)
+		if err != nil {
+			e.logger().Errorf("Couldn't sample the keys of the cluster, err: %s", err)
+			return
+		}
+		e.registerKeySampleMetrics(ch, "db0", stats, sampled, hasMemoryUsage)
+		return
+	}
+
+	dbs := []string{"0"}
+	if e.options.KeySampleDbs != "" {
+		dbs = strings.Split(e.options.KeySampleDbs, ",")
+	}
+	for _, db := range dbs {
+		db = strings.TrimSpace(db)
+		if _, err := doRedisCmd(c, "SELECT", db); err != nil {
+			e.logger().Errorf("Couldn't select database %s when sampling keys, err: %s", db, err)
+			continue
+		}
+
+		stats, sampled, err := e.sampleNodeKeys(c, hasMemoryUsage)
+		if err != nil {
+			e.logger().Errorf("Couldn't sample the keys of database %s, err: %s", db, err)
+			continue
+		}
+		e.registerKeySampleMetrics(ch, "db"+db, stats, sampled, hasMemoryUsage)
+	}
+}
+
+// sampleNodeKeys samples KeySampleCount keys of the selected database of c
+func (e *Exporter) sampleNodeKeys(c redis.Conn, withMemory bool) (map[string]*keySampleStats, int64, error) {
+	keys, err := randomKeys(c, e.options.KeySampleCount)
+	if err != nil {
+		return nil, 0, fmt.Errorf("RANDOMKEY failed: %w", err)
+	}
+	return sampleKeys(c, keys, withMemory)
+}
+
+// sampleClusterKeys samples KeySampleCount keys of every master node of the cluster of c and
+// merges them, every master contributes the same number of keys whatever its share of the keys
+func (e *Exporter) sampleClusterKeys(c redis.Conn, withMemory bool) (map[string]*keySampleStats, int64, error) {
+	nodes, err := redis.String(doRedisCmd(c, "CLUSTER", "NODES"))
+	if err != nil {
+		return nil, 0, fmt.Errorf("couldn't get cluster nodes: %w", err)
+	}
+	options, err := e.configureOptions(e.redisAddr)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	stats := map[string]*keySampleStats{}
+	total := int64(0)
+	for _, m := range parseClusterMasters(nodes) {
+		nodeConn, err := e.dialClusterNode(m.addr, options...)
+		if err != nil {
+			return nil, 0, fmt.Errorf("couldn't connect to cluster node %s: %w", m.addr, err)
+		}
+		nodeConn = e.withCommandLogging(e.withScrapeContext(nodeConn))
+		nodeStats, sampled, err := e.sampleNodeKeys(nodeConn, withMemory)
+		nodeConn.Close()
+		if err != nil {
+			return nil, 0, fmt.Errorf("couldn't sample the keys of cluster node %s: %w", m.addr, err)
+		}
+
+		total += sampled
+		for keyType, ns := range nodeStats {
+			s, ok := stats[keyType]
+			if !ok {
+				s = &keySampleStats{}
+				stats[keyType] = s
+			}
+			s.keys += ns.keys
+			s.expiring += ns.expiring
+			s.memoryBytes += ns.memoryBytes
+		}
+	}
+	return stats, total, nil
+}
+
+// registerKeySampleMetrics exports the sampled keys of the database dbLabel
+func (e *Exporter) registerKeySampleMetrics(ch chan<- prometheus.Metric, dbLabel string, stats map[string]*keySampleStats, sampled int64, hasMemoryUsage bool) {
+	e.registerConstMetricGauge(ch, "key_sample_keys", float64(sampled), dbLabel)
+
+	types := make([]string, 0, len(stats))
+	for keyType := range stats {
+		types = append(types, keyType)
+	}
+	sort.Strings(types)
+	for _, keyType := range types {
+		s := stats[keyType]
+		e.registerConstMetricGauge(ch, "key_sample_type_ratio", float64(s.keys)/float64(sampled), dbLabel, keyType)
+		e.registerConstMetricGauge(ch, "key_sample_expiring_ratio", float64(s.expiring)/float64(s.keys), dbLabel, keyType)
+		if hasMemoryUsage {
+			e.registerConstMetricGauge(ch, "key_sample_memory_usage_avg_bytes", s.memoryBytes/float64(s.keys), dbLabel, keyType)
+		}
+	}
+}
+
+// randomKeys pipelines count RANDOMKEY calls, the same key can be returned more than once
+// and no keys are returned if the database is empty
+func randomKeys(c redis.Conn, count int64) ([]string, error) {
+	for i := int64(0); i < count; i++ {
+		if err := c.Send("RANDOMKEY"); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.Flush(); err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, count)
+	for i := int64(0); i < count; i++ {
+		key, err := redis.String(c.Receive())
+		if err == redis.ErrNil {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// sampleKeys pipelines the TYPE, TTL and, if withMemory is set, MEMORY USAGE calls of keys and
+// sums them up by type, keys that were deleted since they were picked aren't counted
+func sampleKeys(c redis.Conn, keys []string, withMemory bool) (map[string]*keySampleStats, int64, error) {
+	for _, key := range keys {
+		cmds := [][]interface{}{{"TYPE", key}, {"TTL", key}}
+		if withMemory {
+			cmds = append(cmds, []interface{}{"MEMORY", "USAGE", key})
+		}
+		for _, args := range cmds {
+			if err := c.Send(args[0].(string), args[1:]...); err != nil {
+				return nil, 0, err
+			}
+		}
+	}
+	if err := c.Flush(); err != nil {
+		return nil, 0, err
+	}
+
+	stats := map[string]*keySampleStats{}
+	sampled := int64(0)
+	for range keys {
+		keyType, typeErr := redis.String(c.Receive())
+		ttl, ttlErr := redis.Int64(c.Receive())
+		var memoryBytes int64
+		memErr := redis.ErrNil
+		if withMemory {
+			memoryBytes, memErr = redis.Int64(c.Receive())
+		}
+		if typeErr != nil || ttlErr != nil || keyType == "none" || ttl == -2 {
+			continue
+		}
+
+		s, ok := stats[keyType]
+		if !ok {
+			s = &keySampleStats{}
+			stats[keyType] = s
+		}
+		sampled++
+		s.keys++
+		if ttl >= 0 {
+			s.expiring++
+		}
+		if memErr == nil {
+			s.memoryBytes += float64(memoryBytes)
+		}
+	}
+	return stats, sampled, nil
+}
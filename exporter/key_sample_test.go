@@ -0,0 +1,130 @@
+package exporter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/oliver006/redis_exporter/exporter/exportertest"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// randomKeyConn replies to RANDOMKEY with the next of randomKeys
+type randomKeyConn struct {
+	*pipelineConn
+	randomKeys []interface{}
+}
+
+func (c *randomKeyConn) Send(cmd string, args ...interface{}) error {
+	if cmd != "RANDOMKEY" {
+		return c.pipelineConn.Send(cmd, args...)
+	}
+	var key interface{}
+	if len(c.randomKeys) > 0 {
+		key, c.randomKeys = c.randomKeys[0], c.randomKeys[1:]
+	}
+	c.pending = append(c.pending, key)
+	return nil
+}
+
+func TestKeySampleMetrics(t *testing.T) {
+	c := &randomKeyConn{
+		pipelineConn: &pipelineConn{fakeRedisConn: fakeRedisConn{replies: map[string]interface{}{
+			"SELECT 0":               []byte("OK"),
+			"SELECT 1":               []byte("OK"),
+			"TYPE user:1":            []byte("hash"),
+			"TYPE user:2":            []byte("hash"),
+			"TYPE session:1":         []byte("string"),
+			"TYPE gone":              []byte("none"),
+			"TTL user:1":             int64(-1),
+			"TTL user:2":             int64(60),
+			"TTL session:1":          int64(300),
+			"TTL gone":               int64(-2),
+			"MEMORY USAGE user:1":    int64(100),
+			"MEMORY USAGE user:2":    int64(300),
+			"MEMORY USAGE session:1": int64(50),
+			"MEMORY USAGE gone":      nil,
+		}}},
+		// db0 has 5 samples of which one was deleted, db1 is empty
+		randomKeys: []interface{}{[]byte("user:1"), []byte("session:1"), []byte("user:2"), []byte("gone"), []byte("user:1"), nil, nil, nil, nil, nil},
+	}
+
+	e, _ := NewRedisExporter("redis://localhost:6379", Options{Namespace: "test", KeySampleCount: 5, KeySampleDbs: "0, 1"})
+
+	chM := make(chan prometheus.Metric)
+	go func() {
+		e.extractKeySampleMetrics(chM, c)
+		close(chM)
+	}()
+
+	got := map[string]float64{}
+	for m := range chM {
+		d := &dto.Metric{}
+		m.Write(d)
+		name := ""
+		for _, l := range d.GetLabel() {
+			name += l.GetValue() + "/"
+		}
+		desc := m.Desc().String()
+		for _, metric := range []string{"key_sample_keys", "key_sample_type_ratio", "key_sample_expiring_ratio", "key_sample_memory_usage_avg_bytes"} {
+			if strings.Contains(desc, `"test_`+metric+`"`) {
+				name += metric
+			}
+		}
+		got[name] = d.GetGauge().GetValue()
+	}
+
+	want := map[string]float64{
+		"db0/key_sample_keys":                          4,
+		"db0/hash/key_sample_type_ratio":               0.75,
+		"db0/string/key_sample_type_ratio":             0.25,
+		"db0/hash/key_sample_expiring_ratio":           1.0 / 3,
+		"db0/string/key_sample_expiring_ratio":         1,
+		"db0/hash/key_sample_memory_usage_avg_bytes":   500.0 / 3,
+		"db0/string/key_sample_memory_usage_avg_bytes": 50,
+		"db1/key_sample_keys":                          0,
+	}
+	if len(got) != len(want) {
+		t.Errorf("expected %d metrics, got: %v", len(want), got)
+	}
+	for name, val := range want {
+		if v, ok := got[name]; !ok || v != val {
+			t.Errorf("expected %s = %f, got: %f (found: %t)", name, val, v, ok)
+		}
+	}
+}
+
+func TestKeySampleMetricsCluster(t *testing.T) {
+	srv := exportertest.NewServer(t)
+	addr := strings.TrimPrefix(srv.Addr(), "redis://")
+	// both masters are served by srv
+	srv.Set("CLUSTER NODES", "e7d1eecce10fd6bb5eb35b9f99a514335d9ba9ca "+addr+"@16379 myself,master - 0 0 1 connected 0-8191\n"+
+		"67ed2db8d677e59ec4a4cefb06858cf2a1a89fa1 "+addr+"@16379 master - 0 0 2 connected 8192-16383\n")
+	srv.Set("RANDOMKEY", "user:1")
+	srv.Set("TYPE user:1", exportertest.Status("hash"))
+	srv.Set("TTL user:1", 60)
+	srv.Set("MEMORY USAGE user:1", 100)
+
+	e, _ := NewRedisExporter(srv.Addr(), Options{Namespace: "test", IsCluster: true, KeySampleCount: 2})
+	c := dialServer(t, srv)
+
+	chM := make(chan prometheus.Metric)
+	go func() {
+		e.extractKeySampleMetrics(chM, c)
+		close(chM)
+	}()
+	sampled := -1.0
+	for m := range chM {
+		if strings.Contains(m.Desc().String(), `"test_key_sample_keys"`) {
+			d := &dto.Metric{}
+			m.Write(d)
+			sampled = d.GetGauge().GetValue()
+		}
+	}
+	if sampled != 4 {
+		t.Errorf("expected 2 keys sampled of each of the 2 masters, got: %f", sampled)
+	}
+	if n := strings.Count(strings.Join(srv.Commands(), "\n"), "RANDOMKEY"); n != 4 {
+		t.Errorf("expected 4 RANDOMKEY calls, got: %d", n)
+	}
+}
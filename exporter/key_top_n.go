@@ -0,0 +1,170 @@
+package exporter
+
+import (
+	"math"
+	"sort"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// checkKeysSortOrders are the values of check-keys-sort
+var checkKeysSortOrders = []string{"size", "memory", "ttl"}
+
+// keyTopN keeps the metrics of the N most significant keys matching a check-keys pattern,
+// the others are dropped at the end of each batch of keys so at most N + a batch are buffered
+type keyTopN struct {
+	n      int
+	sortBy string
+	keys   map[string]*topNKey
+}
+
+// topNKey are the buffered key metrics of a key and the values it's ranked by
+type topNKey struct {
+	name    string
+	dbLabel string
+	size    float64
+	memory  float64
+	// remaining TTL in ms, +Inf for keys without expire, only set if sorted by ttl
+	ttl     float64
+	hasTTL  bool
+	metrics []topNKeyMetric
+}
+
+type topNKeyMetric struct {
+	metric      string
+	val         float64
+	labelValues []string
+}
+
+func newKeyTopN(n int, sortBy string) *keyTopN {
+	return &keyTopN{n: n, sortBy: sortBy, keys: map[string]*topNKey{}}
+}
+
+func (t *keyTopN) add(metric string, val float64, dbLabel string, keyName string, labelValues []string) {
+	k, ok := t.keys[keyName]
+	if !ok {
+		k = &topNKey{name: keyName, dbLabel: dbLabel}
+		t.keys[keyName] = k
+	}
+	switch metric {
+	case "key_size":
+		k.size = val
+	case "key_memory_usage_bytes":
+		k.memory = val
+	}
+	k.metrics = append(k.metrics, topNKeyMetric{metric: metric, val: val, labelValues: labelValues})
+}
+
+func (t *keyTopN) score(k *topNKey) float64 {
+	switch t.sortBy {
+	case "memory":
+		return k.memory
+	case "ttl":
+		return k.ttl
+	default:
+		return k.size
+	}
+}
+
+// sorted returns the keys ordered by their score, descending, and by name if the scores are
+// equal so the same keys are exported in the same order on every scrape
+func (t *keyTopN) sorted() []*topNKey {
+	keys := make([]*topNKey, 0, len(t.keys))
+	for _, k := range t.keys {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		si, sj := t.score(keys[i]), t.score(keys[j])
+		if si != sj {
+			return si > sj
+		}
+		return keys[i].name < keys[j].name
+	})
+	return keys
+}
+
+// prune drops all but the N most significant keys
+func (t *keyTopN) prune() {
+	if len(t.keys) <= t.n {
+		return
+	}
+	for _, k := range t.sorted()[t.n:] {
+		delete(t.keys, k.name)
+	}
+}
+
+// fetchTopNKeyTTLs gets the PTTL of the buffered keys that don't have one yet, pipelined
+// unless in cluster mode where the keys of a batch can be on different nodes
+func (e *Exporter) fetchTopNKeyTTLs(c redis.Conn) {
+	var keys []*topNKey
+	for _, k := range e.keyTopN.keys {
+		if !k.hasTTL {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return
+	}
+
+	pttl := func(k *topNKey, reply interface{}, err error) {
+		ms, err := redis.Int64(reply, err)
+		if err != nil {
+			e.logger().Errorf("PTTL %s err: %s", k.name, err)
+			return
+		}
+		k.hasTTL = true
+		switch {
+		case ms == -1:
+			k.ttl = math.Inf(1)
+		case ms < 0:
+			// the key expired or was deleted since it was checked
+			k.ttl = math.Inf(-1)
+		default:
+			k.ttl = float64(ms)
+		}
+	}
+
+	if e.options.IsCluster {
+		for _, k := range keys {
+			reply, err := doRedisCmd(c, "PTTL", k.name)
+			pttl(k, reply, err)
+		}
+		return
+	}
+
+	for _, k := range keys {
+		if err := c.Send("PTTL", k.name); err != nil {
+			e.logger().Errorf("c.Send() PTTL err: %s", err)
+			return
+		}
+	}
+	if err := c.Flush(); err != nil {
+		e.logger().Errorf("Flush() err: %s", err)
+		return
+	}
+	for _, k := range keys {
+		reply, err := c.Receive()
+		pttl(k, reply, err)
+	}
+}
+
+// extractTopNCheckKeyMetrics exports the metrics of the CheckKeysTopN keys matching the pattern
+// of k with the largest size, memory usage or TTL, ordered by it
+func (e *Exporter) extractTopNCheckKeyMetrics(ch chan<- prometheus.Metric, c redis.Conn, k dbKeyPair) error {
+	topN := newKeyTopN(int(e.options.CheckKeysTopN), e.options.CheckKeysSort)
+	e.keyTopN = topN
+	err := e.extractScannedCheckKeyMetrics(ch, c, k)
+	e.keyTopN = nil
+	if err != nil {
+		return err
+	}
+
+	topN.prune()
+	for _, key := range topN.sorted() {
+		for _, m := range key.metrics {
+			e.registerKeyMetric(ch, m.metric, m.val, key.dbLabel, key.name, m.labelValues...)
+		}
+	}
+	return nil
+}
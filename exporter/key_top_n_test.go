@@ -0,0 +1,95 @@
+package exporter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestTopNCheckKeyMetrics(t *testing.T) {
+	replies := map[string]interface{}{
+		"SELECT 0":                      []byte("OK"),
+		"SCAN 0 MATCH queue:* COUNT 10": []interface{}{[]byte("0"), []interface{}{[]byte("queue:e"), []byte("queue:d"), []byte("queue:c"), []byte("queue:b"), []byte("queue:a")}},
+	}
+	// name: LLEN, MEMORY USAGE, PTTL
+	for name, v := range map[string][3]int64{
+		"a": {5, 300, 1000},
+		"b": {7, 100, -1},
+		"c": {7, 200, 5000},
+		"d": {1, 500, 2000},
+		"e": {3, 400, -2},
+	} {
+		replies["TYPE queue:"+name] = []byte("list")
+		replies["LLEN queue:"+name] = v[0]
+		replies["MEMORY USAGE queue:"+name] = v[1]
+		replies["PTTL queue:"+name] = v[2]
+	}
+
+	for _, tst := range []struct {
+		sortBy string
+		want   []string
+	}{
+		{sortBy: "size", want: []string{"queue:b", "queue:c", "queue:a"}},
+		{sortBy: "memory", want: []string{"queue:d", "queue:e", "queue:a"}},
+		{sortBy: "ttl", want: []string{"queue:b", "queue:c", "queue:d"}},
+	} {
+		t.Run(tst.sortBy, func(t *testing.T) {
+			c := &pipelineConn{fakeRedisConn: fakeRedisConn{replies: replies}}
+			e, err := NewRedisExporter("redis://localhost:6379", Options{Namespace: "test", CheckKeys: "db0=queue:*", CheckKeysBatchSize: 10, CheckKeysPipelineSize: 2, CheckKeysTopN: 3, CheckKeysSort: tst.sortBy})
+			if err != nil {
+				t.Fatalf("NewRedisExporter() err: %s", err)
+			}
+
+			chM := make(chan prometheus.Metric)
+			go func() {
+				if err := e.extractCheckKeyMetrics(chM, c); err != nil {
+					t.Errorf("extractCheckKeyMetrics() err: %s", err)
+				}
+				close(chM)
+			}()
+
+			var got []string
+			for m := range chM {
+				if !strings.Contains(m.Desc().String(), `"test_key_size"`) {
+					continue
+				}
+				d := &dto.Metric{}
+				m.Write(d)
+				for _, l := range d.GetLabel() {
+					if l.GetName() == "key" {
+						got = append(got, l.GetValue())
+					}
+				}
+			}
+			if !reflect.DeepEqual(got, tst.want) {
+				t.Errorf("expected the keys %v in this order, got: %v", tst.want, got)
+			}
+		})
+	}
+}
+
+func TestTopNCheckKeyOptions(t *testing.T) {
+	for _, tst := range []struct {
+		name    string
+		opts    Options
+		wantErr string
+	}{
+		{name: "default sort", opts: Options{CheckKeysTopN: 10}},
+		{name: "ttl", opts: Options{CheckKeysTopN: 10, CheckKeysSort: "ttl"}},
+		{name: "unknown sort", opts: Options{CheckKeysTopN: 10, CheckKeysSort: "name"}, wantErr: "unknown check-keys-sort"},
+		{name: "aggregate", opts: Options{CheckKeysTopN: 10, CheckKeysAggregate: true}, wantErr: "mutually exclusive"},
+	} {
+		t.Run(tst.name, func(t *testing.T) {
+			_, err := NewRedisExporter("redis://localhost:6379", tst.opts)
+			if tst.wantErr == "" && err != nil {
+				t.Errorf("unexpected err: %s", err)
+			}
+			if tst.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tst.wantErr)) {
+				t.Errorf("expected err %q, got: %v", tst.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package exporter
+
+import (
+	"fmt"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// parseKeyWatchlist parses the CheckKeysFromKey option, a single key in the check-keys
+// syntax e.g. db0=monitoring:watched_keys
+func parseKeyWatchlist(arg string) (*dbKeyPair, error) {
+	keys, err := parseKeyArg(arg)
+	if err != nil {
+		return nil, err
+	}
+	switch len(keys) {
+	case 0:
+		return nil, nil
+	case 1:
+		if globPattern.MatchString(keys[0].key) {
+			return nil, fmt.Errorf("the watchlist key %q can't be a pattern", keys[0].key)
+		}
+		return &keys[0], nil
+	default:
+		return nil, fmt.Errorf("expected a single watchlist key, got %d", len(keys))
+	}
+}
+
+// watchlistKeys reads the keys and patterns to check from the set or list of the
+// CheckKeysFromKey option, each member uses the check-keys syntax. Invalid members are
+// logged and skipped so one bad entry doesn't disable the watchlist
+func (e *Exporter) watchlistKeys(ch chan<- prometheus.Metric, c redis.Conn) []dbKeyPair {
+	wl, err := parseKeyWatchlist(e.options.CheckKeysFromKey)
+	if err != nil || wl == nil {
+		return nil
+	}
+
+	if !e.options.IsCluster {
+		if _, err := doRedisCmd(c, "SELECT", wl.db); err != nil {
+			e.logger().Errorf("Couldn't select database %s when reading the key watchlist, err: %s", wl.db, err)
+			return nil
+		}
+	}
+
+	keyType, err := redis.String(doRedisCmd(c, "TYPE", wl.key))
+	if err != nil {
+		e.logger().Errorf("TYPE %s err: %s", printableKey(wl.key), err)
+		return nil
+	}
+
+	var members []string
+	switch keyType {
+	case "set":
+		members, err = redis.Strings(doRedisCmd(c, "SMEMBERS", wl.key))
+	case "list":
+		members, err = redis.Strings(doRedisCmd(c, "LRANGE", wl.key, 0, -1))
+	case "none":
+		e.logger().Debugf("key watchlist %s doesn't exist", printableKey(wl.key))
+	default:
+		err = fmt.Errorf("unsupported type %s, expected a set or list", keyType)
+	}
+	if err != nil {
+		e.logger().Errorf("Couldn't read the key watchlist %s, err: %s", printableKey(wl.key), err)
+		return nil
+	}
+
+	var keys []dbKeyPair
+	for _, m := range members {
+		parsed, err := parseKeyArg(m)
+		if err != nil {
+			e.logger().Warnf("Skipping invalid key watchlist entry %q, err: %s", m, err)
+			continue
+		}
+		keys = append(keys, parsed...)
+	}
+	e.logger().Debugf("key watchlist %s: %#v", printableKey(wl.key), keys)
+	e.registerConstMetricGauge(ch, "key_watchlist_entries", float64(len(keys)))
+	return keys
+}
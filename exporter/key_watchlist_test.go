@@ -0,0 +1,102 @@
+package exporter
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestParseKeyWatchlist(t *testing.T) {
+	for _, tst := range []struct {
+		arg     string
+		want    *dbKeyPair
+		wantErr bool
+	}{
+		{arg: "", want: nil},
+		{arg: "monitoring:watched_keys", want: &dbKeyPair{db: "0", key: "monitoring:watched_keys"}},
+		{arg: "db3=watched", want: &dbKeyPair{db: "3", key: "watched"}},
+		{arg: "db0=a,db0=b", wantErr: true},
+		{arg: "db0=watched:*", wantErr: true},
+	} {
+		got, err := parseKeyWatchlist(tst.arg)
+		if (err != nil) != tst.wantErr {
+			t.Errorf("parseKeyWatchlist(%q) err: %v, expected an error: %t", tst.arg, err, tst.wantErr)
+			continue
+		}
+		if (got == nil) != (tst.want == nil) || (got != nil && *got != *tst.want) {
+			t.Errorf("parseKeyWatchlist(%q) = %v, expected: %v", tst.arg, got, tst.want)
+		}
+	}
+
+	if _, err := NewRedisExporter("redis://localhost:6379", Options{CheckKeysFromKey: "db0=a,db1=b"}); err == nil {
+		t.Errorf("expected an error for more than one watchlist key")
+	}
+}
+
+func TestCheckKeysFromKey(t *testing.T) {
+	for _, tst := range []struct {
+		name        string
+		watchlist   map[string]interface{}
+		wantKeys    []string
+		wantEntries float64
+	}{
+		{
+			name: "set",
+			watchlist: map[string]interface{}{
+				"TYPE watched":     []byte("set"),
+				"SMEMBERS watched": []interface{}{[]byte("single"), []byte("db0=k:*"), []byte("db0=bad=entry")},
+			},
+			wantKeys:    []string{"single", "k:1", "k:2", "k:3", "k:4"},
+			wantEntries: 2,
+		},
+		{
+			name: "list",
+			watchlist: map[string]interface{}{
+				"TYPE watched":        []byte("list"),
+				"LRANGE watched 0 -1": []interface{}{[]byte("single")},
+			},
+			wantKeys:    []string{"single"},
+			wantEntries: 1,
+		},
+		{
+			name:        "missing",
+			watchlist:   map[string]interface{}{"TYPE watched": []byte("none")},
+			wantEntries: 0,
+		},
+	} {
+		t.Run(tst.name, func(t *testing.T) {
+			replies := scanCheckpointReplies()
+			replies["TYPE single"] = []byte("list")
+			replies["MEMORY USAGE single"] = int64(10)
+			replies["LLEN single"] = int64(1)
+			for cmd, reply := range tst.watchlist {
+				replies[cmd] = reply
+			}
+			c := &pipelineConn{fakeRedisConn: fakeRedisConn{replies: replies}}
+			e, _ := NewRedisExporter("redis://localhost:6379", Options{Namespace: "test", CheckKeysFromKey: "db0=watched", CheckKeysBatchSize: 10})
+
+			got := collectScanMetrics(t, func(ch chan<- prometheus.Metric) {
+				if err := e.extractCheckKeyMetrics(ch, c); err != nil {
+					t.Errorf("extractCheckKeyMetrics() err: %s", err)
+				}
+			})
+
+			var keys []string
+			for name := range got {
+				if key, ok := strings.CutPrefix(name, "test_key_size/"); ok {
+					keys = append(keys, key)
+				}
+			}
+			slices.Sort(keys)
+			slices.Sort(tst.wantKeys)
+			if !slices.Equal(keys, tst.wantKeys) {
+				t.Errorf("expected the keys %v, got: %v", tst.wantKeys, keys)
+			}
+			if v, ok := got["test_key_watchlist_entries"]; !ok || v != tst.wantEntries {
+				t.Errorf("expected key_watchlist_entries = %f, got: %f (found: %t)", tst.wantEntries, v, ok)
+			}
+		})
+	}
+}
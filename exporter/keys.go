@@ -1,7 +1,9 @@
 package exporter
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"net/url"
 	"regexp"
 	"strconv"
@@ -19,7 +21,7 @@ type dbKeyPair struct {
 
 func getStringInfoNotPipelined(c redis.Conn, key string) (strVal string, keyType string, size int64, err error) {
 	if strVal, err = redis.String(doRedisCmd(c, "GET", key)); err != nil {
-		log.Errorf("GET %s err: %s", key, err)
+		log.Errorf("GET %s err: %s", printableKey(key), err)
 	}
 
 	// Check PFCOUNT first because STRLEN on HyperLogLog strings returns the wrong length
@@ -44,8 +46,8 @@ func (e *Exporter) getKeyInfo(ch chan<- prometheus.Metric, c redis.Conn, dbLabel
 
 	switch keyType {
 	case "none":
-		log.Debugf("Key '%s' not found when trying to get type and size: using default '0.0'", keyName)
-		e.registerConstMetricGauge(ch, "key_size", 0.0, dbLabel, keyName)
+		e.logger().Debugf("Key '%s' not found when trying to get type and size: using default '0.0'", printableKey(keyName))
+		e.registerKeyMetric(ch, "key_size", 0.0, dbLabel, keyName)
 		return
 
 	case "string":
@@ -61,66 +63,159 @@ func (e *Exporter) getKeyInfo(ch chan<- prometheus.Metric, c redis.Conn, dbLabel
 	case "stream":
 		size, err = redis.Int64(doRedisCmd(c, "XLEN", keyName))
 	default:
-		err = fmt.Errorf("unknown type: %v for key: %v", keyType, keyName)
+		err = fmt.Errorf("unknown type: %v for key: %v", keyType, printableKey(keyName))
 	}
 
 	if err != nil {
-		log.Errorf("getKeyInfo() err: %s", err)
+		e.logger().Errorf("getKeyInfo() err: %s", err)
 		return
 	}
 
-	e.registerConstMetricGauge(ch, "key_size", float64(size), dbLabel, keyName)
+	e.registerKeyMetric(ch, "key_size", float64(size), dbLabel, keyName)
 
 	// Only run on single value strings
 	if keyType == "string" && !e.options.DisableExportingKeyValues && strVal != "" {
 		if val, err := strconv.ParseFloat(strVal, 64); err == nil {
 			// Only record value metric if value is float-y
-			e.registerConstMetricGauge(ch, "key_value", val, dbLabel, keyName)
+			e.registerKeyMetric(ch, "key_value", val, dbLabel, keyName)
 		} else {
 			// if it's not float-y then we'll record the value as a string label
-			e.registerConstMetricGauge(ch, "key_value_as_string", 1.0, dbLabel, keyName, strVal)
+			e.registerKeyMetric(ch, "key_value_as_string", 1.0, dbLabel, keyName, strVal)
 		}
 	}
 }
 
 func (e *Exporter) extractCheckKeyMetrics(ch chan<- prometheus.Metric, c redis.Conn) error {
 
-	keys, err := parseKeyArg(e.options.CheckKeys)
+	checks := e.checkKeysConfig()
+	keys, err := parseKeyArg(checks.CheckKeys)
 	if err != nil {
 		return fmt.Errorf("couldn't parse check-keys: %w", err)
 	}
-	log.Debugf("keys: %#v", keys)
+	e.logger().Debugf("keys: %#v", keys)
 
-	singleKeys, err := parseKeyArg(e.options.CheckSingleKeys)
+	e.keyDumpBudget = e.options.CheckKeysDumpMaxKeys
+	if e.keyDumpBudget <= 0 {
+		e.keyDumpBudget = math.MaxInt64
+	}
+	e.keyChecks = newSafetyCap(e.options.MaxCheckedKeys)
+
+	singleKeys, err := parseKeyArg(checks.CheckSingleKeys)
 	if err != nil {
 		return fmt.Errorf("couldn't parse check-single-keys: %w", err)
 	}
-	log.Debugf("e.singleKeys: %#v", singleKeys)
-
-	allKeys := append([]dbKeyPair{}, singleKeys...)
+	e.logger().Debugf("e.singleKeys: %#v", singleKeys)
 
-	log.Debugf("e.keys: %#v", keys)
+	keys = append(keys, e.watchlistKeys(ch, c)...)
 
-	if scannedKeys, err := getKeysFromPatterns(c, keys, e.options.CheckKeysBatchSize); err == nil {
-		allKeys = append(allKeys, scannedKeys...)
-	} else {
-		log.Errorf("Error expanding key patterns: %#v", err)
+	// keys without glob characters are checked like single keys, the keys matching
+	// a pattern are exported page by page while SCANning so they're never all in memory
+	allKeys := append([]dbKeyPair{}, singleKeys...)
+	var patterns []dbKeyPair
+	for _, k := range keys {
+		if globPattern.MatchString(k.key) {
+			patterns = append(patterns, k)
+		} else {
+			allKeys = append(allKeys, k)
+		}
 	}
 
-	log.Debugf("allKeys: %#v", allKeys)
+	e.logger().Debugf("allKeys: %#v patterns: %#v", allKeys, patterns)
 
 	/*
 		important: when adding, modifying, removing metrics both paths here
 		(pipelined/non-pipelined) need to be modified
 	*/
 	if e.options.IsCluster {
-		e.extractCheckKeyMetricsNotPipelined(ch, c, allKeys)
+		e.extractCheckKeyMetricsNotPipelined(ch, c, capEntries(e.keyChecks, e.keysInSlotRanges(allKeys)))
 	} else {
-		e.extractCheckKeyMetricsPipelined(ch, c, allKeys)
+		e.extractCheckKeyMetricsPipelined(ch, c, capEntries(e.keyChecks, allKeys))
+	}
+
+	for _, k := range patterns {
+		if e.keyChecks.truncated {
+			break
+		}
+		extract := e.extractScannedCheckKeyMetrics
+		if e.options.CheckKeysAggregate {
+			extract = e.extractAggregatedCheckKeyMetrics
+		} else if e.options.CheckKeysTopN > 0 {
+			extract = e.extractTopNCheckKeyMetrics
+		}
+		if err := extract(ch, c, k); err != nil {
+			e.logger().Errorf("Error expanding key pattern %#v: %s", k.key, err)
+		}
+	}
+
+	if e.options.MaxCheckedKeys > 0 {
+		if e.keyChecks.truncated {
+			e.logger().Warnf("Stopped checking keys after max-checked-keys=%d keys", e.options.MaxCheckedKeys)
+		}
+		e.registerConstMetricGauge(ch, "key_checks_truncated", e.keyChecks.truncatedValue())
 	}
 	return nil
 }
 
+// extractScannedCheckKeyMetrics SCANs for the keys matching the pattern of k and exports their
+// metrics as the pages of keys arrive, at most CheckKeysPipelineSize keys are buffered. The
+// SCAN stops once max-checked-keys is reached
+func (e *Exporter) extractScannedCheckKeyMetrics(ch chan<- prometheus.Metric, c redis.Conn, k dbKeyPair) error {
+	if e.options.IsCluster {
+		err := e.clusterScanKeysFunc(c, k.key, e.options.CheckKeysBatchSize, func(keys []string) error {
+			keys = capEntries(e.keyChecks, keys)
+			pairs := make([]dbKeyPair, len(keys))
+			for i, key := range keys {
+				pairs[i] = dbKeyPair{db: k.db, key: key}
+			}
+			e.extractCheckKeyMetricsNotPipelined(ch, c, pairs)
+			if e.keyChecks.truncated {
+				return errCapReached
+			}
+			return nil
+		})
+		if errors.Is(err, errCapReached) {
+			return nil
+		}
+		return err
+	}
+
+	if _, err := doRedisCmd(c, "SELECT", k.db); err != nil {
+		return err
+	}
+
+	size := int(e.options.CheckKeysPipelineSize)
+	var pending []string
+	err := scanKeysFunc(c, k.key, e.options.CheckKeysBatchSize, func(page []interface{}) error {
+		keyNames, err := redis.Strings(page, nil)
+		if err != nil {
+			return err
+		}
+		keyNames = capEntries(e.keyChecks, keyNames)
+		if size <= 0 {
+			if len(keyNames) > 0 {
+				e.extractCheckKeyMetricsPipelinedBatch(ch, c, k.db, keyNames)
+			}
+		} else {
+			pending = append(pending, keyNames...)
+			for len(pending) >= size {
+				e.extractCheckKeyMetricsPipelinedBatch(ch, c, k.db, pending[:size])
+				pending = pending[size:]
+			}
+		}
+		if e.keyChecks.truncated {
+			return errCapReached
+		}
+		return nil
+	})
+	if len(pending) > 0 {
+		e.extractCheckKeyMetricsPipelinedBatch(ch, c, k.db, pending)
+	}
+	if errors.Is(err, errCapReached) {
+		return nil
+	}
+	return err
+}
+
 func (e *Exporter) extractCheckKeyMetricsPipelined(ch chan<- prometheus.Metric, c redis.Conn, allKeys []dbKeyPair) {
 	//
 	// the following commands are all pipelined/batched to improve performance
@@ -145,74 +240,125 @@ func (e *Exporter) extractCheckKeyMetricsPipelined(ch chan<- prometheus.Metric,
 	}
 
 	for dbNum, arrayOfKeys := range keysByDb {
-		dbLabel := "db" + dbNum
+		// bound the size of the pipelines so huge check-keys sets don't buffer all replies at once
+		for _, batch := range batchKeys(arrayOfKeys, e.options.CheckKeysPipelineSize) {
+			e.extractCheckKeyMetricsPipelinedBatch(ch, c, dbNum, batch)
+		}
+	}
+}
+
+// batchKeys splits keys into batches of at most size keys, size <= 0 means a single batch
+func batchKeys(keys []string, size int64) [][]string {
+	if size <= 0 || int64(len(keys)) <= size {
+		return [][]string{keys}
+	}
 
-		log.Debugf("c.Send() SELECT [%s]", dbNum)
-		if err := c.Send("SELECT", dbNum); err != nil {
-			log.Errorf("Couldn't select database [%s] when getting key info.", dbNum)
+	batches := make([][]string, 0, (int64(len(keys))+size-1)/size)
+	for int64(len(keys)) > size {
+		batches = append(batches, keys[:size])
+		keys = keys[size:]
+	}
+	return append(batches, keys)
+}
+
+// extractCheckKeyMetricsPipelinedBatch pipelines the SELECT, TYPE and MEMORY USAGE calls of a
+// batch of keys of database dbNum and then the calls to get the size and value of each key
+func (e *Exporter) extractCheckKeyMetricsPipelinedBatch(ch chan<- prometheus.Metric, c redis.Conn, dbNum string, arrayOfKeys []string) {
+	dbLabel := "db" + dbNum
+
+	e.logger().Debugf("c.Send() SELECT [%s]", dbNum)
+	if err := c.Send("SELECT", dbNum); err != nil {
+		e.logger().Errorf("Couldn't select database [%s] when getting key info.", dbNum)
+		return
+	}
+	/*
+		first pipeline (batch) all the TYPE & MEMORY USAGE calls and ship them to the redis instance
+		everything else is dependent on the TYPE of the key
+	*/
+
+	hasMemoryUsage := e.compatLevel < compatLevelNoMemory
+	for _, keyName := range arrayOfKeys {
+		e.logger().Debugf("c.Send() TYPE [%v]", printableKey(keyName))
+		if err := c.Send("TYPE", keyName); err != nil {
+			e.logger().Errorf("c.Send() TYPE err: %s", err)
+			return
+		}
+		if !hasMemoryUsage {
 			continue
 		}
-		/*
-			first pipeline (batch) all the TYPE & MEMORY USAGE calls and ship them to the redis instance
-			everything else is dependent on the TYPE of the key
-		*/
-
-		for _, keyName := range arrayOfKeys {
-			log.Debugf("c.Send() TYPE [%v]", keyName)
-			if err := c.Send("TYPE", keyName); err != nil {
-				log.Errorf("c.Send() TYPE err: %s", err)
-				return
-			}
-			log.Debugf("c.Send() MEMORY USAGE [%v]", keyName)
-			if err := c.Send("MEMORY", "USAGE", keyName); err != nil {
-				log.Errorf("c.Send() MEMORY USAGE err: %s", err)
-				return
-			}
+		e.logger().Debugf("c.Send() MEMORY USAGE [%v]", printableKey(keyName))
+		if err := c.Send("MEMORY", "USAGE", keyName); err != nil {
+			e.logger().Errorf("c.Send() MEMORY USAGE err: %s", err)
+			return
 		}
+	}
+
+	e.logger().Debugf("c.Flush()")
+	if err := c.Flush(); err != nil {
+		e.logger().Errorf("FLUSH err: %s", err)
+		return
+	}
 
-		log.Debugf("c.Flush()")
-		if err := c.Flush(); err != nil {
-			log.Errorf("FLUSH err: %s", err)
+	// throwaway Receive() call for the response of the SELECT() call
+	if _, err := redis.String(c.Receive()); err != nil {
+		e.logger().Errorf("Receive() err: %s", err)
+		return
+	}
+
+	/*
+		populate "keyTypes" with the batched TYPE responses from the redis instance
+		and collect MEMORY USAGE responses and immediately emmit that metric
+	*/
+	keyTypes := make([]string, len(arrayOfKeys))
+	for idx, keyName := range arrayOfKeys {
+		var err error
+		keyTypes[idx], err = redis.String(c.Receive())
+		if err != nil {
+			e.logger().Errorf("key: [%s] - Receive err: %s", printableKey(keyName), err)
 			return
 		}
-
-		// throwaway Receive() call for the response of the SELECT() call
-		if _, err := redis.String(c.Receive()); err != nil {
-			log.Errorf("Receive() err: %s", err)
+		if !hasMemoryUsage {
 			continue
 		}
-
-		/*
-			populate "keyTypes" with the batched TYPE responses from the redis instance
-			and collect MEMORY USAGE responses and immediately emmit that metric
-		*/
-		keyTypes := make([]string, len(arrayOfKeys))
-		for idx, keyName := range arrayOfKeys {
-			var err error
-			keyTypes[idx], err = redis.String(c.Receive())
-			if err != nil {
-				log.Errorf("key: [%s] - Receive err: %s", keyName, err)
-				continue
-			}
-			memUsageInBytes, err := redis.Int64(c.Receive())
-			if err != nil {
-				// log.Errorf("key: [%s] - memUsageInBytes Receive() err: %s", keyName, err)
+		memUsageInBytes, err := redis.Int64(c.Receive())
+		if err != nil {
+			// the key was deleted or MEMORY USAGE isn't available (before Redis 4.0), the other
+			// replies of the pipeline can still be read unless the connection failed
+			if _, isRedisErr := err.(redis.Error); isRedisErr || err == redis.ErrNil {
 				continue
 			}
-
-			e.registerConstMetricGauge(ch,
-				"key_memory_usage_bytes",
-				float64(memUsageInBytes),
-				dbLabel,
-				keyName)
+			return
 		}
 
-		/*
-			now that we have the types for all the keys we can gather information about
-			each key like size & length and value (redis cmd used is dependent on TYPE)
-		*/
-		e.getKeyInfoPipelined(ch, c, dbLabel, arrayOfKeys, keyTypes)
+		e.registerKeyMetric(ch,
+			"key_memory_usage_bytes",
+			float64(memUsageInBytes),
+			dbLabel,
+			keyName)
 	}
+
+	/*
+		now that we have the types for all the keys we can gather information about
+		each key like size & length and value (redis cmd used is dependent on TYPE)
+	*/
+	e.getKeyInfoPipelined(ch, c, dbLabel, arrayOfKeys, keyTypes)
+	e.endKeyBatch(ch, c, dbLabel, arrayOfKeys)
+}
+
+// endKeyBatch is called after the metrics of a batch of keys were exported, it exports their
+// serialized sizes if enabled and, if the keys of a pattern are ranked for check-keys-top-n,
+// fetches their TTLs if needed and drops the keys not in the top N
+func (e *Exporter) endKeyBatch(ch chan<- prometheus.Metric, c redis.Conn, dbLabel string, keyNames []string) {
+	if e.options.CheckKeysDumpSize {
+		e.extractKeyDumpSizeMetrics(ch, c, dbLabel, keyNames)
+	}
+	if e.keyTopN == nil {
+		return
+	}
+	if e.keyTopN.sortBy == "ttl" {
+		e.fetchTopNKeyTTLs(c)
+	}
+	e.keyTopN.prune()
 }
 
 func (e *Exporter) getKeyInfoPipelined(ch chan<- prometheus.Metric, c redis.Conn, dbLabel string, arrayOfKeys []string, keyTypes []string) {
@@ -223,66 +369,66 @@ func (e *Exporter) getKeyInfoPipelined(ch chan<- prometheus.Metric, c redis.Conn
 			continue
 
 		case "string":
-			log.Debugf("c.Send() PFCOUNT  args: [%v]", keyName)
+			e.logger().Debugf("c.Send() PFCOUNT  args: [%v]", printableKey(keyName))
 			if err := c.Send("PFCOUNT", keyName); err != nil {
-				log.Errorf("PFCOUNT err: %s", err)
+				e.logger().Errorf("PFCOUNT err: %s", err)
 				return
 			}
 
-			log.Debugf("c.Send() STRLEN  args: [%v]", keyName)
+			e.logger().Debugf("c.Send() STRLEN  args: [%v]", printableKey(keyName))
 			if err := c.Send("STRLEN", keyName); err != nil {
-				log.Errorf("STRLEN err: %s", err)
+				e.logger().Errorf("STRLEN err: %s", err)
 				return
 			}
 
-			log.Debugf("c.Send() GET  args: [%v]", keyName)
+			e.logger().Debugf("c.Send() GET  args: [%v]", printableKey(keyName))
 			if err := c.Send("GET", keyName); err != nil {
-				log.Errorf("GET err: %s", err)
+				e.logger().Errorf("GET err: %s", err)
 				return
 			}
 
 		case "list":
-			log.Debugf("c.Send() LLEN  args: [%v]", keyName)
+			e.logger().Debugf("c.Send() LLEN  args: [%v]", printableKey(keyName))
 			if err := c.Send("LLEN", keyName); err != nil {
-				log.Errorf("LLEN err: %s", err)
+				e.logger().Errorf("LLEN err: %s", err)
 				return
 			}
 
 		case "set":
-			log.Debugf("c.Send() SCARD  args: [%v]", keyName)
+			e.logger().Debugf("c.Send() SCARD  args: [%v]", printableKey(keyName))
 			if err := c.Send("SCARD", keyName); err != nil {
-				log.Errorf("SCARD err: %s", err)
+				e.logger().Errorf("SCARD err: %s", err)
 				return
 			}
 		case "zset":
-			log.Debugf("c.Send() ZCARD  args: [%v]", keyName)
+			e.logger().Debugf("c.Send() ZCARD  args: [%v]", printableKey(keyName))
 			if err := c.Send("ZCARD", keyName); err != nil {
-				log.Errorf("ZCARD err: %s", err)
+				e.logger().Errorf("ZCARD err: %s", err)
 				return
 			}
 
 		case "hash":
-			log.Debugf("c.Send() HLEN  args: [%v]", keyName)
+			e.logger().Debugf("c.Send() HLEN  args: [%v]", printableKey(keyName))
 			if err := c.Send("HLEN", keyName); err != nil {
-				log.Errorf("HLEN err: %s", err)
+				e.logger().Errorf("HLEN err: %s", err)
 				return
 			}
 
 		case "stream":
-			log.Debugf("c.Send() XLEN  args: [%v]", keyName)
+			e.logger().Debugf("c.Send() XLEN  args: [%v]", printableKey(keyName))
 			if err := c.Send("XLEN", keyName); err != nil {
-				log.Errorf("XLEN err: %s", err)
+				e.logger().Errorf("XLEN err: %s", err)
 				return
 			}
 		default:
-			log.Errorf("unknown type: %v for key: %v", keyType, keyName)
+			e.logger().Errorf("unknown type: %v for key: %v", keyType, printableKey(keyName))
 			continue
 		}
 	}
 
-	log.Debugf("c.Flush()")
+	e.logger().Debugf("c.Flush()")
 	if err := c.Flush(); err != nil {
-		log.Errorf("Flush() err: %s", err)
+		e.logger().Errorf("Flush() err: %s", err)
 		return
 	}
 
@@ -295,7 +441,7 @@ func (e *Exporter) getKeyInfoPipelined(ch chan<- prometheus.Metric, c redis.Conn
 
 		switch keyType {
 		case "none":
-			log.Debugf("Key '%s' not found, skipping", keyName)
+			e.logger().Debugf("Key '%s' not found, skipping", printableKey(keyName))
 
 		case "string":
 			hllSize, hllErr := redis.Int64(c.Receive())
@@ -303,10 +449,10 @@ func (e *Exporter) getKeyInfoPipelined(ch chan<- prometheus.Metric, c redis.Conn
 
 			var strValErr error
 			if strVal, strValErr = redis.String(c.Receive()); strValErr != nil {
-				log.Errorf("c.Receive() for GET %s err: %s", keyName, strValErr)
+				e.logger().Errorf("c.Receive() for GET %s err: %s", printableKey(keyName), strValErr)
 			}
 
-			log.Debugf("Done with c.Receive() x 3")
+			e.logger().Debugf("Done with c.Receive() x 3")
 
 			if hllErr == nil {
 				// hyperloglog
@@ -326,81 +472,107 @@ func (e *Exporter) getKeyInfoPipelined(ch chan<- prometheus.Metric, c redis.Conn
 		case "hash", "list", "set", "stream", "zset":
 			size, err = redis.Int64(c.Receive())
 		default:
-			err = fmt.Errorf("unknown type: %v for key: %v", keyType, keyName)
+			err = fmt.Errorf("unknown type: %v for key: %v", keyType, printableKey(keyName))
 		}
 
 		if err != nil {
-			log.Errorf("getKeyInfo() err: %s", err)
+			e.logger().Errorf("getKeyInfo() err: %s", err)
 			continue
 		}
 
 		if keyType == "string" && !e.options.DisableExportingKeyValues && strVal != "" {
 			if val, err := strconv.ParseFloat(strVal, 64); err == nil {
 				// Only record value metric if value is float-y
-				e.registerConstMetricGauge(ch, "key_value", val, dbLabel, keyName)
+				e.registerKeyMetric(ch, "key_value", val, dbLabel, keyName)
 			} else {
 				// if it's not float-y then we'll record the value as a string label
-				e.registerConstMetricGauge(ch, "key_value_as_string", 1.0, dbLabel, keyName, strVal)
+				e.registerKeyMetric(ch, "key_value_as_string", 1.0, dbLabel, keyName, strVal)
 			}
 		}
 
-		e.registerConstMetricGauge(ch, "key_size", float64(size), dbLabel, keyName)
+		e.registerKeyMetric(ch, "key_size", float64(size), dbLabel, keyName)
 	}
 }
 
 func (e *Exporter) extractCheckKeyMetricsNotPipelined(ch chan<- prometheus.Metric, c redis.Conn, allKeys []dbKeyPair) {
 	// Cluster mode only has one db
 	// no need to run `SELECT" but got to set it to "0" in the loop because it's used as the label
+	keyNames := make([]string, 0, len(allKeys))
 	for _, k := range allKeys {
+		keyNames = append(keyNames, k.key)
 		k.db = "0"
 
 		keyType, err := redis.String(doRedisCmd(c, "TYPE", k.key))
 		if err != nil {
-			log.Errorf("TYPE err: %s", keyType)
+			e.logger().Errorf("TYPE err: %s", keyType)
 			continue
 		}
 
-		if memUsageInBytes, err := redis.Int64(doRedisCmd(c, "MEMORY", "USAGE", k.key)); err == nil {
-			e.registerConstMetricGauge(ch, "key_memory_usage_bytes", float64(memUsageInBytes), "db"+k.db, k.key)
+		if e.compatLevel >= compatLevelNoMemory {
+			// MEMORY USAGE isn't available
+		} else if memUsageInBytes, err := redis.Int64(doRedisCmd(c, "MEMORY", "USAGE", k.key)); err == nil {
+			e.registerKeyMetric(ch, "key_memory_usage_bytes", float64(memUsageInBytes), "db"+k.db, k.key)
 		} else {
-			log.Errorf("MEMORY USAGE %s err: %s", k.key, err)
+			e.logger().Errorf("MEMORY USAGE %s err: %s", printableKey(k.key), err)
 		}
 
 		dbLabel := "db" + k.db
 		e.getKeyInfo(ch, c, dbLabel, keyType, k.key)
 	}
+	e.endKeyBatch(ch, c, "db0", keyNames)
 }
 
 func (e *Exporter) extractCountKeysMetrics(ch chan<- prometheus.Metric, c redis.Conn) {
-	cntKeys, err := parseKeyArg(e.options.CountKeys)
+	cntKeys, err := parseKeyArg(e.checkKeysConfig().CountKeys)
 	if err != nil {
-		log.Errorf("Couldn't parse given count keys: %s", err)
+		e.logger().Errorf("Couldn't parse given count keys: %s", err)
 		return
 	}
 
 	for _, k := range cntKeys {
 		if _, err := doRedisCmd(c, "SELECT", k.db); err != nil {
-			log.Errorf("Couldn't select database '%s' when getting stream info", k.db)
+			e.logger().Errorf("Couldn't select database '%s' when getting stream info", k.db)
 			continue
 		}
-		cnt, err := getKeysCount(c, k.key, e.options.CheckKeysBatchSize)
+		dbLabel := "db" + k.db
+		var cnt int
+		if e.options.IsCluster {
+			err = e.clusterScanKeysFunc(c, k.key, e.options.CheckKeysBatchSize, func(keys []string) error {
+				cnt += len(keys)
+				return nil
+			})
+		} else if e.options.CheckKeysScanBudget > 0 {
+			var cp scanCheckpoint
+			cp, err = e.scanKeysCheckpointed(c, "count_keys", k.db, k.key, func(keys []interface{}) error { return nil })
+			if err == nil {
+				e.registerScanCheckpointMetrics(ch, "count_keys", dbLabel, k.key, cp)
+				if cp.passes == 0 {
+					// the count is exported once the first pass is complete
+					continue
+				}
+				cnt = int(cp.lastPassKeys)
+			}
+		} else {
+			cnt, err = getKeysCount(c, k.key, e.options.CheckKeysBatchSize)
+		}
 		if err != nil {
-			log.Errorf("couldn't get key count for '%s', err: %s", k.key, err)
+			e.logger().Errorf("couldn't get key count for '%s', err: %s", printableKey(k.key), err)
 			continue
 		}
-		dbLabel := "db" + k.db
-		e.registerConstMetricGauge(ch, "keys_count", float64(cnt), dbLabel, k.key)
+		e.registerConstMetricGauge(ch, "keys_count", float64(cnt), dbLabel, e.labelValue(ch, k.key))
 	}
 }
 
 func getKeysCount(c redis.Conn, pattern string, count int64) (int, error) {
 	keysCount := 0
 
-	keys, err := scanKeys(c, pattern, count)
+	err := scanKeysFunc(c, pattern, count, func(keys []interface{}) error {
+		keysCount += len(keys)
+		return nil
+	})
 	if err != nil {
 		return keysCount, fmt.Errorf("error retrieving '%s' keys err: %s", pattern, err)
 	}
-	keysCount = len(keys)
 
 	return keysCount, nil
 }
@@ -413,27 +585,7 @@ var globPattern = regexp.MustCompile(`[\?\*\[\]\^]+`)
 
 // getKeysFromPatterns does a SCAN for a key if the key contains pattern characters
 func getKeysFromPatterns(c redis.Conn, keys []dbKeyPair, count int64) (expandedKeys []dbKeyPair, err error) {
-	expandedKeys = []dbKeyPair{}
-	for _, k := range keys {
-		if globPattern.MatchString(k.key) {
-			if _, err := doRedisCmd(c, "SELECT", k.db); err != nil {
-				return expandedKeys, err
-			}
-			keyNames, err := redis.Strings(scanKeys(c, k.key, count))
-			if err != nil {
-				log.Errorf("error with SCAN for pattern: %#v err: %s", k.key, err)
-				continue
-			}
-
-			for _, keyName := range keyNames {
-				expandedKeys = append(expandedKeys, dbKeyPair{db: k.db, key: keyName})
-			}
-		} else {
-			expandedKeys = append(expandedKeys, k)
-		}
-	}
-
-	return expandedKeys, err
+	return getKeysFromPatternsCapped(c, keys, count, newSafetyCap(0))
 }
 
 // parseKeyArgs splits a command-line supplied argument into a slice of dbKeyPairs.
@@ -483,27 +635,44 @@ func parseKeyArg(keysArgString string) (keys []dbKeyPair, err error) {
 // scanForKeys returns a list of keys matching `pattern` by using `SCAN`, which is safer for production systems than using `KEYS`.
 // This function was adapted from: https://github.com/reisinger/examples-redigo
 func scanKeys(c redis.Conn, pattern string, count int64) (keys []interface{}, err error) {
+	err = scanKeysFunc(c, pattern, count, func(page []interface{}) error {
+		keys = append(keys, page...)
+		return nil
+	})
+	return keys, err
+}
+
+// scanKeysFunc is scanKeys but calls fn with every page of keys returned by `SCAN` instead of
+// collecting all of them, the SCAN stops at the first error returned by fn
+func scanKeysFunc(c redis.Conn, pattern string, count int64, fn func(keys []interface{}) error) error {
+	_, err := scanKeysFrom(c, pattern, count, 0, 0, fn)
+	return err
+}
+
+// scanKeysFrom is scanKeysFunc starting at cursor, it stops after maxCalls SCAN calls unless
+// maxCalls is 0 and returns the cursor to resume the scan from, 0 once all keys were scanned
+func scanKeysFrom(c redis.Conn, pattern string, count int64, cursor uint64, maxCalls int64, fn func(keys []interface{}) error) (uint64, error) {
 	if pattern == "" {
-		return keys, fmt.Errorf("pattern shouldn't be empty")
+		return 0, fmt.Errorf("pattern shouldn't be empty")
 	}
 
-	iter := 0
-	for {
-		arr, err := redis.Values(doRedisCmd(c, "SCAN", iter, "MATCH", pattern, "COUNT", count))
+	for calls := int64(1); ; calls++ {
+		arr, err := redis.Values(doRedisCmd(c, "SCAN", cursor, "MATCH", pattern, "COUNT", count))
 		if err != nil {
-			return keys, fmt.Errorf("error retrieving '%s' keys err: %s", pattern, err)
+			return 0, fmt.Errorf("error retrieving '%s' keys err: %s", pattern, err)
 		}
 		if len(arr) != 2 {
-			return keys, fmt.Errorf("invalid response from SCAN for pattern: %s", pattern)
+			return 0, fmt.Errorf("invalid response from SCAN for pattern: %s", pattern)
 		}
 
-		k, _ := redis.Values(arr[1], nil)
-		keys = append(keys, k...)
+		if k, _ := redis.Values(arr[1], nil); len(k) > 0 {
+			if err := fn(k); err != nil {
+				return 0, err
+			}
+		}
 
-		if iter, _ = redis.Int(arr[0], nil); iter == 0 {
-			break
+		if cursor, _ = redis.Uint64(arr[0], nil); cursor == 0 || (maxCalls > 0 && calls >= maxCalls) {
+			return cursor, nil
 		}
 	}
-
-	return keys, nil
 }
@@ -829,3 +829,224 @@ func TestGetKeysCount(t *testing.T) {
 		}
 	}
 }
+
+// pipelineConn answers pipelined commands from a fakeRedisConn, counts the round trips
+// and records the commands in the order they were issued
+type pipelineConn struct {
+	fakeRedisConn
+	pending  []interface{}
+	flushes  int
+	commands []string
+}
+
+func (c *pipelineConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	c.commands = append(c.commands, strings.TrimSpace(fmt.Sprintln(append([]interface{}{cmd}, args...)...)))
+	return c.fakeRedisConn.Do(cmd, args...)
+}
+
+func (c *pipelineConn) Send(cmd string, args ...interface{}) error {
+	reply, err := c.Do(cmd, args...)
+	if err != nil {
+		return err
+	}
+	c.pending = append(c.pending, reply)
+	return nil
+}
+
+func (c *pipelineConn) Flush() error {
+	c.flushes++
+	return nil
+}
+
+func (c *pipelineConn) Receive() (interface{}, error) {
+	if len(c.pending) == 0 {
+		return nil, fmt.Errorf("no pending replies")
+	}
+	reply := c.pending[0]
+	c.pending = c.pending[1:]
+	return reply, nil
+}
+
+func TestBatchKeys(t *testing.T) {
+	keys := []string{"a", "b", "c", "d", "e"}
+	for _, tst := range []struct {
+		size int64
+		want [][]string
+	}{
+		{size: 0, want: [][]string{keys}},
+		{size: 5, want: [][]string{keys}},
+		{size: 10, want: [][]string{keys}},
+		{size: 2, want: [][]string{{"a", "b"}, {"c", "d"}, {"e"}}},
+		{size: 1, want: [][]string{{"a"}, {"b"}, {"c"}, {"d"}, {"e"}}},
+	} {
+		if got := batchKeys(keys, tst.size); !reflect.DeepEqual(got, tst.want) {
+			t.Errorf("batchKeys(%d) = %v, want: %v", tst.size, got, tst.want)
+		}
+	}
+}
+
+func TestCheckKeysPipelineSize(t *testing.T) {
+	var allKeys []dbKeyPair
+	replies := map[string]interface{}{"SELECT 0": "OK"}
+	for i := 0; i < 5; i++ {
+		key := fmt.Sprintf("key%d", i)
+		allKeys = append(allKeys, dbKeyPair{db: "0", key: key})
+		replies["TYPE "+key] = "list"
+		replies["MEMORY USAGE "+key] = int64(100 + i)
+		replies["LLEN "+key] = int64(i)
+	}
+
+	for _, tst := range []struct {
+		size        int64
+		wantFlushes int
+	}{
+		{size: 0, wantFlushes: 2},
+		{size: 2, wantFlushes: 6},
+		{size: 5, wantFlushes: 2},
+	} {
+		t.Run(fmt.Sprintf("size_%d", tst.size), func(t *testing.T) {
+			e, _ := NewRedisExporter("", Options{Namespace: "test", CheckKeysPipelineSize: tst.size})
+			c := &pipelineConn{fakeRedisConn: fakeRedisConn{replies: replies}}
+
+			ch := make(chan prometheus.Metric, 100)
+			e.extractCheckKeyMetricsPipelined(ch, c, allKeys)
+			close(ch)
+
+			sizes := 0
+			for m := range ch {
+				if strings.Contains(m.Desc().String(), "test_key_size") {
+					sizes++
+				}
+			}
+			if sizes != len(allKeys) {
+				t.Errorf("got %d key_size metrics, want: %d", sizes, len(allKeys))
+			}
+			if c.flushes != tst.wantFlushes {
+				t.Errorf("got %d round trips, want: %d", c.flushes, tst.wantFlushes)
+			}
+			if len(c.pending) != 0 {
+				t.Errorf("%d replies were not received", len(c.pending))
+			}
+		})
+	}
+}
+
+func TestCheckKeysStreamsScannedKeys(t *testing.T) {
+	replies := map[string]interface{}{
+		"SELECT 0":                       "OK",
+		"SCAN 0 MATCH key* COUNT 10":     []interface{}{[]byte("7"), []interface{}{[]byte("key0"), []byte("key1")}},
+		"SCAN 7 MATCH key* COUNT 10":     []interface{}{[]byte("0"), []interface{}{[]byte("key2"), []byte("key3"), []byte("key4")}},
+		"SCAN 0 MATCH missing* COUNT 10": []interface{}{[]byte("0"), []interface{}{}},
+	}
+	for i := 0; i < 5; i++ {
+		key := fmt.Sprintf("key%d", i)
+		replies["TYPE "+key] = "list"
+		replies["MEMORY USAGE "+key] = int64(100)
+		replies["LLEN "+key] = int64(i)
+	}
+
+	e, _ := NewRedisExporter("", Options{
+		Namespace:             "test",
+		CheckKeys:             "db0=key*,db0=missing*",
+		CheckKeysBatchSize:    10,
+		CheckKeysPipelineSize: 2,
+	})
+	c := &pipelineConn{fakeRedisConn: fakeRedisConn{replies: replies}}
+
+	ch := make(chan prometheus.Metric, 100)
+	if err := e.extractCheckKeyMetrics(ch, c); err != nil {
+		t.Fatalf("extractCheckKeyMetrics() err: %s", err)
+	}
+	close(ch)
+
+	sizes := 0
+	for m := range ch {
+		if strings.Contains(m.Desc().String(), "test_key_size") {
+			sizes++
+		}
+	}
+	if sizes != 5 {
+		t.Errorf("got %d key_size metrics, want: 5", sizes)
+	}
+
+	// 3 batches of up to 2 keys with 2 round trips each
+	if c.flushes != 6 {
+		t.Errorf("got %d round trips, want: 6", c.flushes)
+	}
+
+	// the keys of the first page are checked before the second page is requested
+	idx := func(cmd string) int {
+		for i, c := range c.commands {
+			if c == cmd {
+				return i
+			}
+		}
+		t.Fatalf("command %q wasn't issued, got: %v", cmd, c.commands)
+		return -1
+	}
+	if idx("TYPE key0") > idx("SCAN 7 MATCH key* COUNT 10") {
+		t.Errorf("keys of the first SCAN page weren't checked before the next page, commands: %v", c.commands)
+	}
+}
+
+// setupBenchmarkKeys creates n string keys named bench_keys_<i> in dbNumStr of TEST_REDIS_URI
+func setupBenchmarkKeys(b *testing.B, n int) redis.Conn {
+	addr := os.Getenv("TEST_REDIS_URI")
+	if addr == "" {
+		b.Skipf("TEST_REDIS_URI not set - skipping")
+	}
+	c, err := redis.DialURL(addr)
+	if err != nil {
+		b.Fatalf("Couldn't connect to %#v: %#v", addr, err)
+	}
+	if _, err := c.Do("SELECT", dbNumStr); err != nil {
+		b.Fatalf("Couldn't select database %#v", dbNumStr)
+	}
+	for i := 0; i < n; i++ {
+		c.Send("SET", fmt.Sprintf("bench_keys_%d", i), "value")
+	}
+	if _, err := c.Do(""); err != nil {
+		b.Fatalf("Couldn't create keys: %s", err)
+	}
+
+	b.Cleanup(func() {
+		for i := 0; i < n; i++ {
+			c.Send("DEL", fmt.Sprintf("bench_keys_%d", i))
+		}
+		c.Do("")
+		c.Close()
+	})
+	return c
+}
+
+func BenchmarkScanKeys(b *testing.B) {
+	c := setupBenchmarkKeys(b, 10000)
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := scanKeys(c, "bench_keys_*", 1000); err != nil {
+			b.Fatalf("scanKeys() err: %s", err)
+		}
+	}
+}
+
+func BenchmarkExtractCheckKeyMetrics(b *testing.B) {
+	c := setupBenchmarkKeys(b, 1000)
+	e, _ := NewRedisExporter(os.Getenv("TEST_REDIS_URI"), Options{Namespace: "test", CheckKeys: "db" + dbNumStr + "=bench_keys_*"})
+	ch := make(chan prometheus.Metric, 1024)
+	done := make(chan struct{})
+	go func() {
+		for range ch {
+		}
+		close(done)
+	}()
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if err := e.extractCheckKeyMetrics(ch, c); err != nil {
+			b.Fatalf("extractCheckKeyMetrics() err: %s", err)
+		}
+	}
+	close(ch)
+	<-done
+}
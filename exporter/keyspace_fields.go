@@ -0,0 +1,48 @@
+package exporter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// keyspaceFieldMetrics are the metric names of the per-db fields of the Keyspace section of
+// INFO besides keys, expires, avg_ttl and cached_keys, fields added by newer servers are
+// exported as db_<field>
+var keyspaceFieldMetrics = map[string]string{
+	// keys with hash fields that have an expire, Redis 7.4
+	"subexpiry": "db_keys_subexpiry",
+}
+
+// keyspaceBaseFields are the fields returned by parseDBKeyspaceString
+var keyspaceBaseFields = map[string]bool{"keys": true, "expires": true, "avg_ttl": true, "cached_keys": true}
+
+// registerKeyspaceExtraFields exports the numeric fields of the keyspace line of dbName that
+// parseDBKeyspaceString doesn't return and adds their metric names to exported
+func (e *Exporter) registerKeyspaceExtraFields(ch chan<- prometheus.Metric, dbName string, value string, exported map[string]bool) {
+	for more := true; more; {
+		var kv string
+		kv, value, more = strings.Cut(value, ",")
+		k, v, ok := cutInfoPair(kv)
+		if !ok || keyspaceBaseFields[k] {
+			continue
+		}
+		val, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			continue
+		}
+
+		// the descriptions of the known fields are created with the others
+		name, source := keyspaceFieldMetrics[k], ""
+		if name == "" {
+			name, source = "db_"+sanitizeMetricName(k), k
+		}
+		if _, ok := e.addMetricDescription(name, source, fmt.Sprintf("Value of the keyspace field %s by DB", k), []string{"db"}); !ok {
+			continue
+		}
+		e.registerConstMetricGauge(ch, name, val, dbName)
+		exported[name] = true
+	}
+}
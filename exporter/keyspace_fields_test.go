@@ -0,0 +1,80 @@
+package exporter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestKeyspaceExtraFields(t *testing.T) {
+	info := "# Keyspace\r\n" +
+		"db0:keys=10,expires=2,avg_ttl=5000,subexpiry=3\r\n" +
+		"db2:keys=1,expires=0,avg_ttl=0,subexpiry=0,new_field=7,text_field=abc\r\n"
+
+	for _, tst := range []struct {
+		name       string
+		emptyDBs   bool
+		wantValues map[string]float64
+	}{
+		{
+			name: "without empty databases",
+			wantValues: map[string]float64{
+				"test_db_keys/db0": 10, "test_db_avg_ttl_seconds/db0": 5, "test_db_keys_subexpiry/db0": 3,
+				"test_db_keys_subexpiry/db2": 0, "test_db_new_field/db2": 7,
+			},
+		},
+		{
+			name:     "with empty databases",
+			emptyDBs: true,
+			wantValues: map[string]float64{
+				"test_db_keys_subexpiry/db0": 3, "test_db_keys/db1": 0, "test_db_keys_subexpiry/db1": 0,
+				"test_db_new_field/db1": 0, "test_db_new_field/db3": 0, "test_db_keys_subexpiry/db3": 0,
+			},
+		},
+	} {
+		t.Run(tst.name, func(t *testing.T) {
+			e, _ := NewRedisExporter("redis://localhost:6379", Options{Namespace: "test", InclMetricsForEmptyDatabases: tst.emptyDBs})
+
+			chM := make(chan prometheus.Metric)
+			go func() {
+				e.extractInfoMetrics(chM, info, 4)
+				close(chM)
+			}()
+
+			got := map[string]float64{}
+			for m := range chM {
+				d := &dto.Metric{}
+				m.Write(d)
+				desc := m.Desc().String()
+				name := desc[strings.Index(desc, `"`)+1:]
+				name = name[:strings.Index(name, `"`)]
+				if !strings.HasPrefix(name, "test_db_") {
+					continue
+				}
+				for _, l := range d.GetLabel() {
+					if l.GetName() == "db" {
+						name += "/" + l.GetValue()
+					}
+				}
+				got[name] = d.GetGauge().GetValue()
+			}
+
+			for name, want := range tst.wantValues {
+				if v, ok := got[name]; !ok || v != want {
+					t.Errorf("expected %s = %f, got: %f (found: %t)", name, want, v, ok)
+				}
+			}
+			if _, ok := got["test_db_keys_cached/db0"]; ok {
+				t.Errorf("subexpiry mustn't be exported as db_keys_cached")
+			}
+			if _, ok := got["test_db_text_field/db2"]; ok {
+				t.Errorf("non-numeric fields mustn't be exported")
+			}
+			if _, ok := got["test_db_keys/db1"]; ok != tst.emptyDBs {
+				t.Errorf("expected db1 to be exported: %t, got: %v", tst.emptyDBs, got)
+			}
+		})
+	}
+}
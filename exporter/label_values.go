@@ -0,0 +1,94 @@
+package exporter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// labelValuesTooLongPolicies are the values of LabelValuesTooLong
+var labelValuesTooLongPolicies = []string{"truncate", "hash"}
+
+// labelValue returns the key, stream, group or consumer name v sanitized for use as label
+// value: with LabelValuesStripNonUTF8 invalid UTF-8 is removed, binary values that aren't
+// valid UTF-8 (and with CheckKeysHexLabels any value with non-printable characters) are
+// hex-encoded and values longer than LabelValuesMaxBytes are truncated or hashed. A hashed
+// value is mapped back to v by label_value_hash_info, exported once per collection, a truncated
+// one ends with a short hash of v so values with the same prefix don't end up in the same series
+func (e *Exporter) labelValue(ch chan<- prometheus.Metric, v string) string {
+	if e.options.LabelValuesStripNonUTF8 && !utf8.ValidString(v) {
+		v = strings.ToValidUTF8(v, "")
+	}
+	if !utf8.ValidString(v) || (e.options.CheckKeysHexLabels && !isPrintable(v)) {
+		v = hexLabelValue(v)
+	}
+
+	maxBytes := e.options.LabelValuesMaxBytes
+	if maxBytes <= 0 || len(v) <= maxBytes {
+		return v
+	}
+
+	if e.options.LabelValuesTooLong == "hash" {
+		sum := sha256.Sum256([]byte(v))
+		hash := "sha256:" + hex.EncodeToString(sum[:8])
+		if !e.labelValueHashes[hash] {
+			e.labelValueHashes[hash] = true
+			e.registerConstMetricGauge(ch, "label_value_hash_info", 1, hash, v)
+		}
+		return hash
+	}
+	return truncateLabelValue(v, maxBytes)
+}
+
+// truncateLabelValue truncates v to at most n bytes, including "~" and the first 8 hex
+// digits of the SHA-256 of v that are appended to tell apart values with the same prefix
+func truncateLabelValue(v string, n int) string {
+	sum := sha256.Sum256([]byte(v))
+	suffix := "~" + hex.EncodeToString(sum[:4])
+	if n <= len(suffix) {
+		return suffix[len(suffix)-n:]
+	}
+	return truncateUTF8(v, n-len(suffix)) + suffix
+}
+
+// truncateUTF8 truncates s to at most n bytes without splitting a multi-byte character
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// hexLabelValue is the label value of a binary key, "0x" followed by the hex-encoded bytes
+func hexLabelValue(v string) string {
+	return "0x" + hex.EncodeToString([]byte(v))
+}
+
+// isPrintable returns whether s is valid UTF-8 without control or other non-printable characters
+func isPrintable(s string) bool {
+	if !utf8.ValidString(s) {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// printableKey returns the key name k for logging, quoted with Go escapes if it's binary
+func printableKey(k string) string {
+	if isPrintable(k) {
+		return k
+	}
+	return strconv.Quote(k)
+}
@@ -0,0 +1,138 @@
+package exporter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestTruncateUTF8(t *testing.T) {
+	for _, tst := range []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{s: "short", n: 10, want: "short"},
+		{s: "session:1234", n: 7, want: "session"},
+		// "é" takes 2 bytes and isn't split
+		{s: "caché", n: 5, want: "cach"},
+		{s: "caché", n: 6, want: "caché"},
+	} {
+		if got := truncateUTF8(tst.s, tst.n); got != tst.want {
+			t.Errorf("truncateUTF8(%q, %d) = %q, want: %q", tst.s, tst.n, got, tst.want)
+		}
+	}
+}
+
+func TestLabelValue(t *testing.T) {
+	long := "user:" + strings.Repeat("x", 100)
+	for _, tst := range []struct {
+		name     string
+		opts     Options
+		value    string
+		want     string
+		wantInfo bool
+	}{
+		{name: "defaults", opts: Options{}, value: long, want: long},
+		{name: "truncate", opts: Options{LabelValuesMaxBytes: 16}, value: long, want: "user:xx~64b4aa9f"},
+		{name: "short value", opts: Options{LabelValuesMaxBytes: 8, LabelValuesTooLong: "hash"}, value: "user:1", want: "user:1"},
+		{name: "hash", opts: Options{LabelValuesMaxBytes: 8, LabelValuesTooLong: "hash"}, value: long, want: "sha256:", wantInfo: true},
+		{name: "strip non-UTF-8", opts: Options{LabelValuesStripNonUTF8: true}, value: "key:\xff\xfe1", want: "key:1"},
+		{name: "hex non-UTF-8", opts: Options{}, value: "key:\xff", want: "0x6b65793aff"},
+		{name: "printable", opts: Options{CheckKeysHexLabels: true}, value: "user:é 1", want: "user:é 1"},
+		{name: "hex non-printable", opts: Options{CheckKeysHexLabels: true}, value: "\x00\x01k", want: "0x00016b"},
+		{name: "control characters without hex labels", opts: Options{}, value: "\x00\x01k", want: "\x00\x01k"},
+	} {
+		t.Run(tst.name, func(t *testing.T) {
+			tst.opts.Namespace = "test"
+			e, err := NewRedisExporter("", tst.opts)
+			if err != nil {
+				t.Fatalf("NewRedisExporter() err: %s", err)
+			}
+
+			chM := make(chan prometheus.Metric, 10)
+			got := e.labelValue(chM, tst.value)
+			// the mapping is only exported once per collection
+			if again := e.labelValue(chM, tst.value); again != got {
+				t.Errorf("expected the same label value, got: %q and %q", got, again)
+			}
+			close(chM)
+
+			if !strings.HasPrefix(got, tst.want) || (!tst.wantInfo && got != tst.want) {
+				t.Errorf("labelValue(%q) = %q, want: %q", tst.value, got, tst.want)
+			}
+
+			var infos []*dto.Metric
+			for m := range chM {
+				d := &dto.Metric{}
+				m.Write(d)
+				infos = append(infos, d)
+			}
+			if !tst.wantInfo {
+				if len(infos) != 0 {
+					t.Errorf("didn't expect label_value_hash_info, got: %v", infos)
+				}
+				return
+			}
+			if len(infos) != 1 || infos[0].GetLabel()[0].GetValue() != got || infos[0].GetLabel()[1].GetValue() != tst.value {
+				t.Errorf("expected the mapping of %q to %q, got: %v", got, tst.value, infos)
+			}
+		})
+	}
+
+	if _, err := NewRedisExporter("", Options{LabelValuesTooLong: "drop"}); err == nil {
+		t.Errorf("expected an error for an unknown label-values-too-long policy")
+	}
+}
+
+func TestPrintableKey(t *testing.T) {
+	for _, tst := range []struct {
+		key  string
+		want string
+	}{
+		{key: "user:1", want: "user:1"},
+		{key: "caché", want: "caché"},
+		{key: "\x00\x01k", want: `"\x00\x01k"`},
+		{key: "k\xff", want: `"k\xff"`},
+		{key: "line\nbreak", want: `"line\nbreak"`},
+	} {
+		if got := printableKey(tst.key); got != tst.want {
+			t.Errorf("printableKey(%q) = %s, want: %s", tst.key, got, tst.want)
+		}
+	}
+}
+
+func TestCheckBinaryKeys(t *testing.T) {
+	replies := map[string]interface{}{
+		"SELECT 0":               []byte("OK"),
+		"TYPE \x00\xffk":         []byte("string"),
+		"MEMORY USAGE \x00\xffk": int64(50),
+		"GET \x00\xffk":          []byte("abc"),
+		"STRLEN \x00\xffk":       int64(3),
+		"PFCOUNT \x00\xffk":      redis.Error("WRONGTYPE"),
+	}
+	c := &pipelineConn{fakeRedisConn: fakeRedisConn{replies: replies}}
+	e, _ := NewRedisExporter("redis://localhost:6379", Options{Namespace: "test", CheckSingleKeys: "db0=%00%FFk"})
+
+	got := collectScanMetrics(t, func(ch chan<- prometheus.Metric) {
+		if err := e.extractCheckKeyMetrics(ch, c); err != nil {
+			t.Errorf("extractCheckKeyMetrics() err: %s", err)
+		}
+	})
+	if v, ok := got["test_key_size/0x00ff6b"]; !ok || v != 3 {
+		t.Errorf("expected key_size of the hex-encoded key, got: %v", got)
+	}
+}
+
+func TestTruncateLabelValue(t *testing.T) {
+	a, b := truncateLabelValue("user:1:"+strings.Repeat("x", 50), 16), truncateLabelValue("user:1:"+strings.Repeat("y", 50), 16)
+	if a == b || len(a) != 16 || !strings.HasPrefix(a, "user:1:") {
+		t.Errorf("expected different truncated values of 16 bytes, got: %q and %q", a, b)
+	}
+	if got := truncateLabelValue("user:1", 4); len(got) != 4 {
+		t.Errorf("expected 4 bytes of the hash, got: %q", got)
+	}
+}
@@ -19,8 +19,13 @@ var (
 )
 
 func (e *Exporter) extractLatencyMetrics(ch chan<- prometheus.Metric, infoAll string, c redis.Conn) {
+	if !e.supportsCompat(compatLevelNoLatency, "latency") {
+		return
+	}
 	e.extractLatencyLatestMetrics(ch, c)
-	e.extractLatencyHistogramMetrics(ch, infoAll, c)
+	if e.supportsCompat(compatLevelNoLatencyHistogram, "latency histogram") {
+		e.extractLatencyHistogramMetrics(ch, infoAll, c)
+	}
 }
 
 func (e *Exporter) extractLatencyLatestMetrics(outChan chan<- prometheus.Metric, redisConn redis.Conn) {
@@ -31,9 +36,9 @@ func (e *Exporter) extractLatencyLatestMetrics(outChan chan<- prometheus.Metric,
 			we're logging this only once as an Error and always as Debugf()
 		*/
 		logLatestErrOnce.Do(func() {
-			log.Errorf("WARNING, LOGGED ONCE ONLY: cmd LATENCY LATEST, err: %s", err)
+			e.logger().Errorf("WARNING, LOGGED ONCE ONLY: cmd LATENCY LATEST, err: %s", err)
 		})
-		log.Debugf("cmd LATENCY LATEST, err: %s", err)
+		e.logger().Debugf("cmd LATENCY LATEST, err: %s", err)
 		return
 	}
 
@@ -57,9 +62,9 @@ func (e *Exporter) extractLatencyHistogramMetrics(outChan chan<- prometheus.Metr
 	reply, err := redis.Values(doRedisCmd(redisConn, "LATENCY", "HISTOGRAM"))
 	if err != nil {
 		logHistogramErrOnce.Do(func() {
-			log.Errorf("WARNING, LOGGED ONCE ONLY: cmd LATENCY HISTOGRAM, err: %s", err)
+			e.logger().Errorf("WARNING, LOGGED ONCE ONLY: cmd LATENCY HISTOGRAM, err: %s", err)
 		})
-		log.Debugf("cmd LATENCY HISTOGRAM, err: %s", err)
+		e.logger().Debugf("cmd LATENCY HISTOGRAM, err: %s", err)
 		return
 	}
 
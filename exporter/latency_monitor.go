@@ -0,0 +1,54 @@
+package exporter
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// onceSet remembers the targets a one-time log message was logged for
+type onceSet struct {
+	sync.Mutex
+	seen map[string]bool
+}
+
+func newOnceSet() *onceSet {
+	return &onceSet{seen: map[string]bool{}}
+}
+
+// first returns true the first time it's called for target
+func (o *onceSet) first(target string) bool {
+	o.Lock()
+	defer o.Unlock()
+	if o.seen[target] {
+		return false
+	}
+	o.seen[target] = true
+	return true
+}
+
+// extractLatencyMonitorMetrics exports latency-monitor-threshold of the CONFIG GET values, Redis
+// only records the latency events of LATENCY LATEST if the threshold isn't 0
+func (e *Exporter) extractLatencyMonitorMetrics(ch chan<- prometheus.Metric, configValues map[string]string) {
+	val, ok := configValues["latency-monitor-threshold"]
+	if !ok {
+		return
+	}
+	threshold, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		e.logger().Debugf("invalid latency-monitor-threshold: %s", val)
+		return
+	}
+
+	e.registerConstMetricGauge(ch, "latency_monitor_threshold_milliseconds", threshold)
+	if threshold != 0 {
+		return
+	}
+
+	e.registerConstMetricGauge(ch, "latency_monitor_disabled", 1)
+	if e.options.LogLatencyMonitorHint && e.latencyMonitorHints.first(e.redisAddr) {
+		e.logger().Warnf("Latency monitoring is disabled (latency-monitor-threshold is 0) so the latency_spike metrics stay empty, " +
+			"enable it with e.g. CONFIG SET latency-monitor-threshold 100")
+	}
+}
@@ -0,0 +1,67 @@
+package exporter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestLatencyMonitorMetrics(t *testing.T) {
+	e, _ := NewRedisExporter("redis://localhost:6379", Options{Namespace: "test", LogLatencyMonitorHint: true})
+
+	scrape := func(config []interface{}) map[string]float64 {
+		chM := make(chan prometheus.Metric)
+		go func() {
+			e.extractConfigMetrics(chM, config)
+			close(chM)
+		}()
+
+		got := map[string]float64{}
+		for m := range chM {
+			desc := m.Desc().String()
+			for _, name := range []string{"test_latency_monitor_threshold_milliseconds", "test_latency_monitor_disabled"} {
+				if strings.Contains(desc, `"`+name+`"`) {
+					d := &dto.Metric{}
+					m.Write(d)
+					got[name] = d.GetGauge().GetValue()
+				}
+			}
+		}
+		return got
+	}
+
+	got := scrape([]interface{}{[]byte("latency-monitor-threshold"), []byte("0")})
+	if val, ok := got["test_latency_monitor_threshold_milliseconds"]; !ok || val != 0 {
+		t.Errorf("expected a threshold of 0, got: %f (found: %t)", val, ok)
+	}
+	if got["test_latency_monitor_disabled"] != 1 {
+		t.Errorf("expected latency_monitor_disabled, got: %v", got)
+	}
+	if e.latencyMonitorHints.first(e.redisAddr) {
+		t.Errorf("expected the hint to be logged")
+	}
+
+	got = scrape([]interface{}{[]byte("latency-monitor-threshold"), []byte("100")})
+	if got["test_latency_monitor_threshold_milliseconds"] != 100 {
+		t.Errorf("expected a threshold of 100, got: %v", got)
+	}
+	if _, ok := got["test_latency_monitor_disabled"]; ok {
+		t.Errorf("unexpected latency_monitor_disabled for an enabled monitor")
+	}
+
+	if got := scrape([]interface{}{[]byte("maxmemory"), []byte("0")}); len(got) != 0 {
+		t.Errorf("expected no metrics without latency-monitor-threshold, got: %v", got)
+	}
+}
+
+func TestOnceSet(t *testing.T) {
+	o := newOnceSet()
+	if !o.first("a") || o.first("a") {
+		t.Errorf("expected only the first call for a to return true")
+	}
+	if !o.first("b") {
+		t.Errorf("expected the first call for b to return true")
+	}
+}
@@ -0,0 +1,56 @@
+package exporter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// formats of the LATENCYSTATS percentiles, see the LatencyPercentilesFormat option
+const (
+	latencyPercentilesSummary = "summary"
+	latencyPercentilesGauges  = "gauges"
+)
+
+// parseLatencyPercentiles parses a comma separated list of percentiles like "p50,p99,99.9"
+func parseLatencyPercentiles(s string) ([]float64, error) {
+	var res []float64
+	for _, p := range strings.Split(s, ",") {
+		p = strings.TrimPrefix(strings.TrimSpace(p), "p")
+		if p == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(p, 64)
+		if err != nil || v <= 0 || v > 100 {
+			return nil, fmt.Errorf("invalid percentile %q, expected a number between 0 and 100", p)
+		}
+		res = append(res, v)
+	}
+	return res, nil
+}
+
+// selectLatencyPercentiles returns the percentiles of latencyMap that are in the
+// LatencyPercentiles option, all of them if it's empty. Percentiles Redis doesn't
+// report (see latency-tracking-info-percentiles) are missing from the result
+func (e *Exporter) selectLatencyPercentiles(latencyMap map[float64]float64) map[float64]float64 {
+	if len(e.latencyPercentiles) == 0 {
+		return latencyMap
+	}
+	res := make(map[float64]float64, len(e.latencyPercentiles))
+	for _, p := range e.latencyPercentiles {
+		if v, ok := latencyMap[p]; ok {
+			res[p] = v
+		}
+	}
+	return res
+}
+
+// registerLatencyPercentileGauges exports the percentiles of cmd as one gauge per percentile,
+// unlike the summary they don't depend on the calls and usec of Commandstats
+func (e *Exporter) registerLatencyPercentileGauges(ch chan<- prometheus.Metric, cmd string, latencyMap map[float64]float64) {
+	for p, v := range latencyMap {
+		e.registerConstMetricGauge(ch, "latency_percentile_usec", v, cmd, "p"+strconv.FormatFloat(p, 'f', -1, 64))
+	}
+}
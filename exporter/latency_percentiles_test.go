@@ -0,0 +1,106 @@
+package exporter
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestParseLatencyPercentiles(t *testing.T) {
+	got, err := parseLatencyPercentiles("p50, 99,p99.9,")
+	if err != nil || !slices.Equal(got, []float64{50, 99, 99.9}) {
+		t.Errorf("expected [50 99 99.9], got: %v err: %v", got, err)
+	}
+	for _, s := range []string{"p0", "101", "pfoo"} {
+		if _, err := parseLatencyPercentiles(s); err == nil {
+			t.Errorf("expected an error for %q", s)
+		}
+	}
+	if _, err := NewRedisExporter("redis://localhost:6379", Options{LatencyPercentilesFormat: "histogram"}); err == nil {
+		t.Errorf("expected an error for an invalid format")
+	}
+}
+
+func TestLatencyPercentiles(t *testing.T) {
+	info := "# Commandstats\r\ncmdstat_get:calls=10,usec=50,usec_per_call=5.00,rejected_calls=0,failed_calls=0\r\n\r\n" +
+		"# Latencystats\r\nlatency_percentiles_usec_get:p50=6.015,p99=57.087,p99.9=387.071\r\n" +
+		"latency_percentiles_usec_set:p50=1.003,p99=2.007,p99.9=3.007\r\n"
+
+	for _, tst := range []struct {
+		name          string
+		opts          Options
+		wantQuantiles []float64
+		wantGauges    map[string]float64
+	}{
+		{
+			name:          "all percentiles",
+			opts:          Options{Namespace: "test"},
+			wantQuantiles: []float64{50, 99, 99.9},
+		},
+		{
+			name:          "selected percentiles",
+			opts:          Options{Namespace: "test", LatencyPercentiles: "p50,p99.9,p99.99"},
+			wantQuantiles: []float64{50, 99.9},
+		},
+		{
+			name:       "gauges",
+			opts:       Options{Namespace: "test", LatencyPercentiles: "p99", LatencyPercentilesFormat: latencyPercentilesGauges},
+			wantGauges: map[string]float64{"get/p99": 57.087, "set/p99": 2.007},
+		},
+	} {
+		t.Run(tst.name, func(t *testing.T) {
+			e, err := NewRedisExporter("redis://localhost:6379", tst.opts)
+			if err != nil {
+				t.Fatalf("NewRedisExporter() err: %s", err)
+			}
+
+			chM := make(chan prometheus.Metric)
+			go func() {
+				e.extractInfoMetrics(chM, info, 16)
+				close(chM)
+			}()
+
+			var quantiles []float64
+			summaries := 0
+			gauges := map[string]float64{}
+			for m := range chM {
+				d := &dto.Metric{}
+				m.Write(d)
+				if s := d.GetSummary(); s != nil {
+					summaries++
+					for _, q := range s.GetQuantile() {
+						quantiles = append(quantiles, q.GetQuantile())
+					}
+					continue
+				}
+				lbls := map[string]string{}
+				for _, l := range d.GetLabel() {
+					lbls[l.GetName()] = l.GetValue()
+				}
+				if p, ok := lbls["percentile"]; ok {
+					gauges[lbls["cmd"]+"/"+p] = d.GetGauge().GetValue()
+				}
+			}
+
+			slices.Sort(quantiles)
+			if tst.wantQuantiles != nil && (summaries != 1 || !slices.Equal(quantiles, tst.wantQuantiles)) {
+				t.Errorf("expected one summary with the quantiles %v, got %d summaries with %v", tst.wantQuantiles, summaries, quantiles)
+			}
+			if tst.wantGauges != nil {
+				if summaries != 0 {
+					t.Errorf("expected no summaries, got: %d", summaries)
+				}
+				if len(gauges) != len(tst.wantGauges) {
+					t.Errorf("expected %v, got: %v", tst.wantGauges, gauges)
+				}
+				for k, v := range tst.wantGauges {
+					if gauges[k] != v {
+						t.Errorf("expected %s = %f, got: %v", k, v, gauges)
+					}
+				}
+			}
+		})
+	}
+}
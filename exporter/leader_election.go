@@ -0,0 +1,212 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+// leaderAcquireScript takes the lock if it's free and extends it if it's held by ARGV[1]
+var leaderAcquireScript = redis.NewScript(1, `
+local holder = redis.call("GET", KEYS[1])
+if holder == ARGV[1] then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	return 1
+end
+if holder == false then
+	redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+	return 1
+end
+return 0
+`)
+
+// leaderReleaseScript deletes the lock if it's held by ARGV[1]
+var leaderReleaseScript = redis.NewScript(1, `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// leaderElection elects one of the exporters sharing a lock key in redis as leader, only
+// the leader runs the key collectors that scan the keyspace, the other exporters only
+// export the metrics of INFO and the other cheap collectors
+type leaderElection struct {
+	key string
+	id  string
+	ttl time.Duration
+	// dial returns the connection the lock is taken on
+	dial func() (redis.Conn, error)
+
+	// mu serializes campaign() and stop() so the lock isn't taken again after it was released
+	mu      sync.Mutex
+	stopped bool
+
+	isLeader    atomic.Bool
+	transitions atomic.Int64
+}
+
+// StartLeaderElection takes part in the leader election in the background until ctx is
+// done, it's disabled if no leader election key is set
+func (e *Exporter) StartLeaderElection(ctx context.Context) error {
+	if e.options.LeaderElectionKey == "" {
+		return nil
+	}
+	if e.redisAddr == "" {
+		return fmt.Errorf("leader election needs the redis.addr instance to hold the lock")
+	}
+
+	l := &leaderElection{
+		key:  e.options.LeaderElectionKey,
+		id:   leaderElectionID(e.options.HAReplicaName),
+		ttl:  e.options.LeaderElectionTTL,
+		dial: e.lockConnection,
+	}
+	if l.ttl <= 0 {
+		l.ttl = 15 * time.Second
+	}
+
+	e.Lock()
+	e.leader = l
+	e.Unlock()
+
+	e.logger().Infof("Taking part in the leader election for key %s as %s", l.key, l.id)
+	l.campaign()
+	go l.run(ctx)
+	return nil
+}
+
+// leaderElectionID returns the name the exporter holds the lock with
+func leaderElectionID(replicaName string) string {
+	if replicaName != "" {
+		return replicaName
+	}
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+// lockConnection returns a connection to the redis.addr instance, or to the
+// cluster so the lock key is read from the node that serves its slot
+func (e *Exporter) lockConnection() (redis.Conn, error) {
+	if e.options.IsCluster && !isUnixSocketAddr(e.redisAddr) {
+		c, err := e.connectToRedisCluster()
+		if err != nil {
+			return nil, err
+		}
+		// an unbound connection would pick the node by the script SHA, the first argument of EVALSHA
+		if err := c.(*redirectConn).bind(e.options.LeaderElectionKey); err != nil {
+			c.Close()
+			return nil, fmt.Errorf("couldn't bind to the node of the lock key %s: %w", e.options.LeaderElectionKey, err)
+		}
+		return c, nil
+	}
+	return e.connectToRedis()
+}
+
+func (l *leaderElection) run(ctx context.Context) {
+	// renewed three times per TTL so a single failed renewal doesn't lose the lock
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			l.stop()
+			return
+		case <-ticker.C:
+			l.campaign()
+		}
+	}
+}
+
+// campaign takes or extends the lock, the exporter isn't the leader if redis can't be reached
+func (l *leaderElection) campaign() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.stopped {
+		return
+	}
+
+	leader := false
+	if c, err := l.dial(); err != nil {
+		log.Errorf("Leader election: couldn't connect, err: %s", err)
+	} else {
+		res, err := redis.Int64(leaderAcquireScript.Do(c, l.key, l.id, l.ttl.Milliseconds()))
+		c.Close()
+		if err != nil {
+			log.Errorf("Leader election: couldn't take the lock %s, err: %s", l.key, err)
+		}
+		leader = res == 1
+	}
+
+	if l.isLeader.Swap(leader) != leader {
+		l.transitions.Add(1)
+		if leader {
+			log.Infof("Leader election: became the leader, running the key collectors")
+		} else {
+			log.Infof("Leader election: lost the leadership, skipping the key collectors")
+		}
+	}
+}
+
+// stop ends the campaign and releases the lock
+func (l *leaderElection) stop() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.stopped = true
+	l.release()
+}
+
+// StopLeaderElection stops taking part in the leader election and releases the lock if the
+// exporter is the leader, it returns once the lock is released so it can be called on shutdown
+func (e *Exporter) StopLeaderElection() {
+	e.Lock()
+	l := e.leader
+	e.Unlock()
+	if l != nil {
+		l.stop()
+	}
+}
+
+// release gives up the lock so the other exporter can take over without waiting for the TTL
+func (l *leaderElection) release() {
+	if !l.isLeader.Swap(false) {
+		return
+	}
+	c, err := l.dial()
+	if err != nil {
+		return
+	}
+	defer c.Close()
+	if _, err := leaderReleaseScript.Do(c, l.key, l.id); err != nil {
+		log.Errorf("Leader election: couldn't release the lock %s, err: %s", l.key, err)
+	}
+}
+
+// skipNotLeader returns true if the collector scans the keyspace and the exporter isn't the leader
+func (e *Exporter) skipNotLeader(collector string) bool {
+	if e.leader == nil || e.leader.isLeader.Load() {
+		return false
+	}
+	e.logger().Debugf("skipping collector %s, not the leader", collector)
+	return true
+}
+
+func (e *Exporter) registerLeaderElectionMetrics(ch chan<- prometheus.Metric) {
+	if e.leader == nil || e.targetExporter {
+		return
+	}
+	leader := 0.0
+	if e.leader.isLeader.Load() {
+		leader = 1
+	}
+	e.registerConstMetricGauge(ch, "exporter_leader", leader)
+	e.registerConstMetric(ch, "exporter_leader_transitions_total", float64(e.leader.transitions.Load()), prometheus.CounterValue)
+}
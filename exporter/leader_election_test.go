@@ -0,0 +1,176 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/mna/redisc"
+	"github.com/oliver006/redis_exporter/exporter/exportertest"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// lockServer emulates the lock scripts on a single key
+type lockServer struct {
+	sync.Mutex
+	holder string
+	down   bool
+}
+
+type lockConn struct {
+	nodeConn
+	s *lockServer
+}
+
+func (c *lockConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	c.s.Lock()
+	defer c.s.Unlock()
+	if cmd != "EVALSHA" {
+		return nil, errors.New("unexpected command " + cmd)
+	}
+	id := args[3].(string)
+	switch args[0] {
+	case leaderAcquireScript.Hash():
+		if c.s.holder == "" || c.s.holder == id {
+			c.s.holder = id
+			return int64(1), nil
+		}
+		return int64(0), nil
+	case leaderReleaseScript.Hash():
+		if c.s.holder == id {
+			c.s.holder = ""
+			return int64(1), nil
+		}
+		return int64(0), nil
+	}
+	return nil, errors.New("unknown script")
+}
+
+func TestLeaderElection(t *testing.T) {
+	s := &lockServer{}
+	newElection := func(id string) *leaderElection {
+		return &leaderElection{key: "exporter-lock", id: id, ttl: time.Second, dial: func() (redis.Conn, error) {
+			if s.down {
+				return nil, errors.New("connection refused")
+			}
+			return &lockConn{s: s}, nil
+		}}
+	}
+	a, b := newElection("exporter-a"), newElection("exporter-b")
+
+	a.campaign()
+	b.campaign()
+	if !a.isLeader.Load() || b.isLeader.Load() {
+		t.Fatalf("expected exporter-a to be the only leader")
+	}
+
+	a.campaign()
+	if !a.isLeader.Load() || a.transitions.Load() != 1 {
+		t.Errorf("expected exporter-a to stay the leader, transitions: %d", a.transitions.Load())
+	}
+
+	e := &Exporter{leader: a}
+	e.StopLeaderElection()
+	if s.holder != "" {
+		t.Errorf("expected the lock to be released when StopLeaderElection() returns, holder: %s", s.holder)
+	}
+	a.campaign()
+	b.campaign()
+	if a.isLeader.Load() || !b.isLeader.Load() {
+		t.Errorf("expected exporter-b to take over after exporter-a stopped")
+	}
+
+	s.down = true
+	b.campaign()
+	if b.isLeader.Load() || b.transitions.Load() != 2 {
+		t.Errorf("expected exporter-b to give up the leadership without redis, transitions: %d", b.transitions.Load())
+	}
+}
+
+func TestSkipNotLeader(t *testing.T) {
+	e, _ := NewRedisExporter("redis://localhost:6379", Options{Namespace: "test"})
+	if e.skipNotLeader("keys") {
+		t.Errorf("expected no collectors to be skipped without leader election")
+	}
+
+	e.leader = &leaderElection{}
+	if !e.skipNotLeader("keys") {
+		t.Errorf("expected the key collectors to be skipped by a follower")
+	}
+
+	chM := make(chan prometheus.Metric)
+	go func() {
+		e.registerLeaderElectionMetrics(chM)
+		close(chM)
+	}()
+	for m := range chM {
+		if strings.Contains(m.Desc().String(), `"test_exporter_leader"`) {
+			d := &dto.Metric{}
+			m.Write(d)
+			if d.GetGauge().GetValue() != 0 {
+				t.Errorf("expected exporter_leader to be 0 for a follower")
+			}
+		}
+	}
+
+	e.leader.isLeader.Store(true)
+	if e.skipNotLeader("keys") {
+		t.Errorf("expected the leader to run the key collectors")
+	}
+}
+
+func TestLeaderElectionID(t *testing.T) {
+	if id := leaderElectionID("exporter-a"); id != "exporter-a" {
+		t.Errorf("expected the replica name as id, got: %s", id)
+	}
+	if id := leaderElectionID(""); id == "" {
+		t.Errorf("expected a hostname based id")
+	}
+}
+
+func TestLeaderElectionCluster(t *testing.T) {
+	const key = "redis-exporter-leader"
+	lockSlot := redisc.Slot(key)
+
+	// the second node only serves the slot of the lock key, the first node doesn't know the scripts
+	first, second := exportertest.NewServer(t), exportertest.NewServer(t)
+	hostPort := func(s *exportertest.Server) []interface{} {
+		host, port, _ := net.SplitHostPort(strings.TrimPrefix(s.Addr(), "redis://"))
+		p, _ := strconv.Atoi(port)
+		return []interface{}{host, p}
+	}
+	slots := []interface{}{[]interface{}{lockSlot, lockSlot, hostPort(second)}}
+	if lockSlot > 0 {
+		slots = append(slots, []interface{}{0, lockSlot - 1, hostPort(first)})
+	}
+	if lockSlot < 16383 {
+		slots = append(slots, []interface{}{lockSlot + 1, 16383, hostPort(first)})
+	}
+	first.Set("CLUSTER SLOTS", slots)
+	for _, s := range []*exportertest.Server{first, second} {
+		s.Set("CLIENT NO-TOUCH", exportertest.Status("OK"))
+	}
+	second.Set("EVALSHA", exportertest.Func(func(args []string) interface{} {
+		if len(args) < 3 || args[2] != key {
+			return exportertest.Error("ERR unexpected arguments")
+		}
+		return 1
+	}))
+
+	e, _ := NewRedisExporter(first.Addr(), Options{Namespace: "test", IsCluster: true, LeaderElectionKey: key, LeaderElectionTTL: time.Minute})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := e.StartLeaderElection(ctx); err != nil {
+		t.Fatalf("StartLeaderElection() err: %s", err)
+	}
+	if !e.leader.isLeader.Load() {
+		t.Errorf("expected the exporter to take the lock on the node of its slot, first node got: %v", first.Commands())
+	}
+}
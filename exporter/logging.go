@@ -0,0 +1,69 @@
+package exporter
+
+import (
+	"sync/atomic"
+
+	"github.com/gomodule/redigo/redis"
+	log "github.com/sirupsen/logrus"
+)
+
+// scrapeIDs is used to tell apart the log lines of concurrent scrapes
+var scrapeIDs atomic.Uint64
+
+// logger returns the log entry of the current collection which carries the
+// target, scrape_id and collector fields
+func (e *Exporter) logger() *log.Entry {
+	if l := e.logEntry.Load(); l != nil {
+		return l
+	}
+	return e.baseLogger().WithField("target", redactedAddr(e.redisAddr))
+}
+
+func (e *Exporter) baseLogger() *log.Logger {
+	if e.debugLogger != nil {
+		return e.debugLogger
+	}
+	return log.StandardLogger()
+}
+
+func (e *Exporter) startScrapeLogging() {
+	e.logBase = e.baseLogger().WithFields(log.Fields{
+		"target":    redactedAddr(e.redisAddr),
+		"scrape_id": scrapeIDs.Add(1),
+	})
+	e.logEntry.Store(e.logBase)
+}
+
+// setLogCollector sets the collector field of the log lines of the current scrape
+func (e *Exporter) setLogCollector(collector string) {
+	if e.logBase != nil {
+		e.logEntry.Store(e.logBase.WithField("collector", collector))
+	}
+}
+
+func (e *Exporter) endScrapeLogging() {
+	e.logEntry.Store(nil)
+	e.logBase = nil
+}
+
+// loggingConn logs the commands of the scrape connection at debug level with the
+// fields of the scrape logger
+type loggingConn struct {
+	redis.Conn
+	e *Exporter
+}
+
+func (e *Exporter) withCommandLogging(c redis.Conn) redis.Conn {
+	return &loggingConn{Conn: c, e: e}
+}
+
+func (c *loggingConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	l := c.e.logger()
+	l.Debugf("c.Do() - running command: %s args: [%v]", cmd, args)
+	res, err := c.Conn.Do(cmd, args...)
+	if err != nil {
+		l.Debugf("c.Do() - err: %s", err)
+	}
+	l.Debugf("c.Do() - done")
+	return res, err
+}
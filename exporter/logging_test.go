@@ -0,0 +1,81 @@
+package exporter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/oliver006/redis_exporter/exporter/exportertest"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestScrapeLoggingFields(t *testing.T) {
+	hook := logtest.NewGlobal()
+	defer hook.Reset()
+
+	e, _ := NewRedisExporter("unix:///tmp/doesnt.exist.sock", Options{Namespace: "test"})
+
+	for i := 0; i < 2; i++ {
+		chM := make(chan prometheus.Metric)
+		go func() {
+			e.Collect(chM)
+			close(chM)
+		}()
+		for range chM {
+		}
+	}
+
+	scrapeIDs := map[interface{}]bool{}
+	for _, entry := range hook.AllEntries() {
+		if entry.Level > log.ErrorLevel {
+			continue
+		}
+		if entry.Data["target"] != "unix:///tmp/doesnt.exist.sock" || entry.Data["collector"] != "connection" {
+			t.Errorf("unexpected log fields: %#v", entry.Data)
+		}
+		scrapeIDs[entry.Data["scrape_id"]] = true
+	}
+	if len(scrapeIDs) != 2 {
+		t.Errorf("expected log lines of 2 scrapes with distinct scrape ids, got: %#v", scrapeIDs)
+	}
+
+	// outside of a scrape only the target is set
+	hook.Reset()
+	e.logger().Error("test")
+	if entry := hook.LastEntry(); entry == nil || entry.Data["collector"] != nil || entry.Data["target"] == nil {
+		t.Errorf("unexpected log entry outside of scrape: %#v", entry)
+	}
+}
+
+func TestCommandLoggingFields(t *testing.T) {
+	hook := logtest.NewGlobal()
+	defer hook.Reset()
+	defer log.SetLevel(log.GetLevel())
+	log.SetLevel(log.DebugLevel)
+
+	srv := exportertest.NewServer(t)
+	e, _ := NewRedisExporter(srv.Addr(), Options{Namespace: "test"})
+
+	chM := make(chan prometheus.Metric)
+	go func() {
+		e.Collect(chM)
+		close(chM)
+	}()
+	for range chM {
+	}
+
+	found := false
+	for _, entry := range hook.AllEntries() {
+		if !strings.HasPrefix(entry.Message, "c.Do() - running command: INFO") {
+			continue
+		}
+		found = true
+		if entry.Data["target"] != srv.Addr() || entry.Data["scrape_id"] == nil || entry.Data["collector"] != "info" {
+			t.Errorf("unexpected log fields of the INFO command: %#v", entry.Data)
+		}
+	}
+	if !found {
+		t.Errorf("expected the INFO command to be logged")
+	}
+}
@@ -0,0 +1,130 @@
+package exporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+// MetricMapping overrides the name, help text and/or type of an exported metric
+type MetricMapping struct {
+	Name string `json:"name"`
+	Help string `json:"help"`
+	Type string `json:"type"`
+}
+
+var validMetricNameRE = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
+var metricMappingTypes = map[string]prometheus.ValueType{
+	"counter": prometheus.CounterValue,
+	"gauge":   prometheus.GaugeValue,
+	"untyped": prometheus.UntypedValue,
+}
+
+// LoadMetricMappingFile reads the metric mapping file, the keys are the metric
+// names without the namespace (e.g. "memory_used_bytes")
+func LoadMetricMappingFile(mappingFile string) (map[string]MetricMapping, error) {
+	res := make(map[string]MetricMapping)
+
+	log.Debugf("start load metric mapping file: %s", mappingFile)
+	bytes, err := os.ReadFile(mappingFile)
+	if err != nil {
+		log.Warnf("load metric mapping file failed: %s", err)
+		return nil, err
+	}
+	err = json.Unmarshal(bytes, &res)
+	if err != nil {
+		log.Warnf("metric mapping file format error: %s", err)
+		return nil, err
+	}
+
+	names := map[string]string{}
+	for metric, m := range res {
+		if m.Name != "" {
+			if !validMetricNameRE.MatchString(m.Name) {
+				return nil, fmt.Errorf("invalid metric name %q for metric %s", m.Name, metric)
+			}
+			if other, ok := names[m.Name]; ok {
+				return nil, fmt.Errorf("metrics %s and %s are both renamed to %s", other, metric, m.Name)
+			}
+			if _, ok := res[m.Name]; ok && m.Name != metric {
+				return nil, fmt.Errorf("metric %s is renamed to %s which is mapped itself", metric, m.Name)
+			}
+			names[m.Name] = metric
+		}
+		if _, ok := metricMappingTypes[m.Type]; m.Type != "" && !ok {
+			return nil, fmt.Errorf("invalid type %q for metric %s", m.Type, metric)
+		}
+	}
+
+	log.Infof("Loaded %d metric mappings from %s", len(res), mappingFile)
+	return res, nil
+}
+
+// checkMetricMappingCollisions returns an error if a metric is renamed to the name of another
+// metric of the exporter, registering the exporter would fail on the duplicate name. Metrics
+// named after keys or INFO fields while scraping aren't known yet and aren't checked
+func (e *Exporter) checkMetricMappingCollisions() error {
+	if len(e.options.MetricMapping) == 0 {
+		return nil
+	}
+
+	metrics := []string{"exporter_scrapes_total", "exporter_scrape_duration_seconds", "exporter_build_info",
+		"target_scrape_request_errors_total", "target_scrape_requests_rate_limited_total"}
+	for metric := range e.metricDescriptions {
+		metrics = append(metrics, metric)
+	}
+	for _, metric := range e.metricMapGauges {
+		metrics = append(metrics, metric)
+	}
+	for _, metric := range e.metricMapCounters {
+		metrics = append(metrics, metric)
+	}
+	sort.Strings(metrics)
+
+	names := map[string]string{}
+	for _, metric := range metrics {
+		name := metric
+		if m, ok := e.options.MetricMapping[metric]; ok && m.Name != "" {
+			name = m.Name
+		}
+		fqName := prometheus.BuildFQName(e.metricNamespace(metric), "", name)
+		if other, ok := names[fqName]; ok && other != metric {
+			return fmt.Errorf("metrics %s and %s would both be exported as %s", other, metric, fqName)
+		}
+		names[fqName] = metric
+	}
+	return nil
+}
+
+func (e *Exporter) newMetricDescr(metricName string, docString string, labels []string) *prometheus.Desc {
+	namespace := e.metricNamespace(metricName)
+	labels = e.recordDbLabel(metricName, labels)
+	var replicaLabels prometheus.Labels
+	if isSelfMetric(metricName) {
+		replicaLabels = haReplicaLabels(e.options.HAReplicaName)
+	}
+	constLabels := constLabels(e.options.ConstLabels, replicaLabels, labels)
+	if m, ok := e.options.MetricMapping[metricName]; ok {
+		if m.Name != "" {
+			metricName = m.Name
+		}
+		if m.Help != "" {
+			docString = m.Help
+		}
+	}
+	return newMetricDescr(namespace, metricName, docString, labels, constLabels)
+}
+
+// mappedValueType returns the type forced by the metric mapping or valType
+func (e *Exporter) mappedValueType(metricName string, valType prometheus.ValueType) prometheus.ValueType {
+	if m, ok := e.options.MetricMapping[metricName]; ok && m.Type != "" {
+		return metricMappingTypes[m.Type]
+	}
+	return valType
+}
@@ -0,0 +1,97 @@
+package exporter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestLoadMetricMappingFile(t *testing.T) {
+	mapping, err := LoadMetricMappingFile("../contrib/sample-metric-mapping.json")
+	if err != nil {
+		t.Fatalf("LoadMetricMappingFile() err: %s", err)
+	}
+	if mapping["memory_used_bytes"].Name != "memory_usage_bytes" {
+		t.Errorf("unexpected mapping: %#v", mapping)
+	}
+
+	if _, err := LoadMetricMappingFile("non-existent.json"); err == nil {
+		t.Errorf("expected error for missing file")
+	}
+
+	for _, content := range []string{
+		`{"memory_used_bytes": {"name": "invalid-name"}}`,
+		`{"memory_used_bytes": {"type": "histogram"}}`,
+		`{"memory_used_bytes": {"name": "mem"}, "used_memory_rss_bytes": {"name": "mem"}}`,
+		`{"memory_used_bytes": {"name": "memory_max_bytes"}, "memory_max_bytes": {"name": "memory_used_bytes"}}`,
+		`{"memory_used_bytes": {"name": "memory_max_bytes"}, "memory_max_bytes": {"help": "max"}}`,
+	} {
+		f := filepath.Join(t.TempDir(), "mapping.json")
+		if err := os.WriteFile(f, []byte(content), 0o600); err != nil {
+			t.Fatalf("WriteFile() err: %s", err)
+		}
+		if _, err := LoadMetricMappingFile(f); err == nil {
+			t.Errorf("expected error for mapping: %s", content)
+		}
+	}
+}
+
+func TestMetricMapping(t *testing.T) {
+	e, _ := NewRedisExporter("", Options{
+		Namespace: "test",
+		MetricMapping: map[string]MetricMapping{
+			"memory_used_bytes":  {Name: "memory_usage_bytes", Help: "bytes allocated"},
+			"evicted_keys_total": {Type: "gauge"},
+		},
+	})
+
+	chM := make(chan prometheus.Metric)
+	go func() {
+		e.extractInfoMetrics(chM, "# Memory\nused_memory:600\n\n# Stats\nevicted_keys:3\n", 0)
+		close(chM)
+	}()
+
+	found := map[string]bool{}
+	for m := range chM {
+		desc := m.Desc().String()
+		d := &dto.Metric{}
+		m.Write(d)
+
+		switch {
+		case strings.Contains(desc, `"test_memory_usage_bytes"`):
+			found["renamed"] = strings.Contains(desc, `help: "bytes allocated"`)
+		case strings.Contains(desc, `"test_memory_used_bytes"`):
+			t.Errorf("expected memory_used_bytes to be renamed, got: %s", desc)
+		case strings.Contains(desc, `"test_evicted_keys_total"`):
+			found["retyped"] = d.GetGauge() != nil && d.GetCounter() == nil
+		}
+	}
+
+	if !found["renamed"] || !found["retyped"] {
+		t.Errorf("mapping not applied, got: %#v", found)
+	}
+}
+
+func TestMetricMappingCollisions(t *testing.T) {
+	for _, tst := range []struct {
+		name    string
+		mapping map[string]MetricMapping
+		wantErr bool
+	}{
+		{name: "built-in metric", mapping: map[string]MetricMapping{"memory_used_bytes": {Name: "memory_max_bytes"}}, wantErr: true},
+		{name: "self metric", mapping: map[string]MetricMapping{"up": {Name: "exporter_scrapes_total"}}, wantErr: true},
+		{name: "swapped", mapping: map[string]MetricMapping{"memory_used_bytes": {Name: "memory_max_bytes"}, "memory_max_bytes": {Name: "memory_used_bytes"}}},
+		{name: "unused name", mapping: map[string]MetricMapping{"memory_used_bytes": {Name: "memory_usage_bytes"}}},
+	} {
+		t.Run(tst.name, func(t *testing.T) {
+			_, err := NewRedisExporter("", Options{Namespace: "test", MetricMapping: tst.mapping, Registry: prometheus.NewRegistry()})
+			if (err != nil) != tst.wantErr {
+				t.Errorf("expected error: %t, got: %v", tst.wantErr, err)
+			}
+		})
+	}
+}
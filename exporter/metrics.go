@@ -7,17 +7,26 @@ import (
 	"strings"
 
 	"github.com/prometheus/client_golang/prometheus"
-	log "github.com/sirupsen/logrus"
 )
 
 var metricNameRE = regexp.MustCompile(`[^a-zA-Z0-9_]`)
 
+// maxMetricDescriptions bounds the metric descriptions of an exporter, collectors add them
+// while scraping for names derived from keys and INFO fields, which are never removed
+const maxMetricDescriptions = 10000
+
 func sanitizeMetricName(n string) string {
-	return metricNameRE.ReplaceAllString(n, "_")
+	// most INFO fields are valid metric names already, ReplaceAllString() always copies
+	for i := 0; i < len(n); i++ {
+		if c := n[i]; !(c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')) {
+			return metricNameRE.ReplaceAllString(n, "_")
+		}
+	}
+	return n
 }
 
-func newMetricDescr(namespace string, metricName string, docString string, labels []string) *prometheus.Desc {
-	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "", metricName), docString, labels, nil)
+func newMetricDescr(namespace string, metricName string, docString string, labels []string, constLabels prometheus.Labels) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "", metricName), docString, labels, constLabels)
 }
 
 func (e *Exporter) includeMetric(s string) bool {
@@ -59,7 +68,7 @@ func (e *Exporter) parseAndRegisterConstMetric(ch chan<- prometheus.Metric, fiel
 
 	}
 	if err != nil {
-		log.Debugf("couldn't parse %s, err: %s", fieldValue, err)
+		e.logger().Debugf("couldn't parse %s, err: %s", fieldValue, err)
 		return
 	}
 
@@ -84,14 +93,25 @@ func (e *Exporter) registerConstMetricGauge(ch chan<- prometheus.Metric, metric
 func (e *Exporter) registerConstMetric(ch chan<- prometheus.Metric, metric string, val float64, valType prometheus.ValueType, labelValues ...string) {
 	var desc *prometheus.Desc
 	if len(labelValues) == 0 {
-		desc = e.createMetricDescription(metric, nil)
+		if desc = e.createMetricDescription(metric, nil); desc == nil {
+			return
+		}
 	} else {
 		desc = e.mustFindMetricDescription(metric)
+		labelValues = e.dbLabelValues(metric, labelValues)
+
+		if e.seriesGuard != nil && !e.seriesGuard.admit(metric, val, valType) {
+			return
+		}
 	}
 
-	m, err := prometheus.NewConstMetric(desc, valType, val, labelValues...)
+	m, err := prometheus.NewConstMetric(desc, e.mappedValueType(metric, valType), val, labelValues...)
 	if err != nil {
-		log.Debugf("registerConstMetric( %s , %.2f) err: %s", metric, val, err)
+		e.logger().Debugf("registerConstMetric( %s , %.2f) err: %s", metric, val, err)
+		if e.validateSeriesEnabled() {
+			// reported by the seriesValidator
+			ch <- prometheus.NewInvalidMetric(desc, err)
+		}
 		return
 	}
 
@@ -120,18 +140,59 @@ func (e *Exporter) registerConstHistogram(ch chan<- prometheus.Metric, metric st
 }
 
 func (e *Exporter) mustFindMetricDescription(metricName string) *prometheus.Desc {
-	description, found := e.metricDescriptions[metricName]
+	description, found := e.findMetricDescription(metricName)
 	if !found {
 		panic(fmt.Sprintf("couldn't find metric description for %s", metricName))
 	}
 	return description
 }
 
+func (e *Exporter) findMetricDescription(metricName string) (*prometheus.Desc, bool) {
+	e.metricDescriptionsMtx.RLock()
+	defer e.metricDescriptionsMtx.RUnlock()
+	desc, found := e.metricDescriptions[metricName]
+	return desc, found
+}
+
+// createMetricDescription returns the description of metricName, it's created on first use
+// and nil if maxMetricDescriptions is reached
 func (e *Exporter) createMetricDescription(metricName string, labels []string) *prometheus.Desc {
-	if desc, found := e.metricDescriptions[metricName]; found {
+	if desc, found := e.findMetricDescription(metricName); found {
 		return desc
 	}
-	d := newMetricDescr(e.options.Namespace, metricName, metricName+" metric", labels)
-	e.metricDescriptions[metricName] = d
-	return d
+	desc, _ := e.addMetricDescription(metricName, "", metricName+" metric", labels)
+	return desc
+}
+
+// addMetricDescription returns the description of metricName, creating it with help and labels on
+// first use. source identifies what the name was derived from, e.g. the key of key_size_<key>, and
+// false is returned if the name is already taken by another source, e.g. two keys with the same
+// sanitized name, or if the exporter already has maxMetricDescriptions descriptions
+func (e *Exporter) addMetricDescription(metricName string, source string, help string, labels []string) (*prometheus.Desc, bool) {
+	e.metricDescriptionsMtx.Lock()
+	defer e.metricDescriptionsMtx.Unlock()
+
+	if desc, found := e.metricDescriptions[metricName]; found {
+		if e.metricDescriptionSources[metricName] == source {
+			return desc, true
+		}
+		if !e.droppedMetricDescriptions[metricName] {
+			e.droppedMetricDescriptions[metricName] = true
+			e.logger().Warnf("metric name %s of %q is already used by %q, not exporting it", metricName, source, e.metricDescriptionSources[metricName])
+		}
+		return nil, false
+	}
+
+	if len(e.metricDescriptions) >= maxMetricDescriptions {
+		if !e.droppedMetricDescriptions[metricName] {
+			e.droppedMetricDescriptions[metricName] = true
+			e.logger().Warnf("the exporter has %d metric descriptions, not exporting %s", maxMetricDescriptions, metricName)
+		}
+		return nil, false
+	}
+
+	desc := e.newMetricDescr(metricName, help, labels)
+	e.metricDescriptions[metricName] = desc
+	e.metricDescriptionSources[metricName] = source
+	return desc, true
 }
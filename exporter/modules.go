@@ -1,23 +1,26 @@
 package exporter
 
 import (
+	"strconv"
 	"strings"
 
 	"github.com/gomodule/redigo/redis"
 	"github.com/prometheus/client_golang/prometheus"
-	log "github.com/sirupsen/logrus"
 )
 
 func (e *Exporter) extractModulesMetrics(ch chan<- prometheus.Metric, c redis.Conn) {
 	info, err := redis.String(doRedisCmd(c, "INFO", "MODULES"))
 	if err != nil {
-		log.Errorf("extractSearchMetrics() err: %s", err)
+		e.logger().Errorf("extractModulesMetrics() err: %s", err)
+		// the loaded modules are unknown, the expected ones are reported as missing rather than not at all
+		e.registerExpectedModules(ch, nil)
 		return
 	}
 
+	loaded := map[string]int64{}
 	lines := strings.Split(info, "\r\n")
 	for _, line := range lines {
-		log.Debugf("info: %s", line)
+		e.logger().Debugf("info: %s", line)
 
 		split := strings.Split(line, ":")
 		if len(split) != 2 {
@@ -30,6 +33,12 @@ func (e *Exporter) extractModulesMetrics(ch chan<- prometheus.Metric, c redis.Co
 			if len(module) != 7 {
 				continue
 			}
+			name := strings.Split(module[0], "=")[1]
+			ver, _ := strconv.ParseInt(strings.Split(module[1], "=")[1], 10, 64)
+			loaded[strings.ToLower(name)] = ver
+			if !e.options.InclModulesMetrics {
+				continue
+			}
 			e.registerConstMetricGauge(ch, "module_info", 1,
 				strings.Split(module[0], "=")[1],
 				strings.Split(module[1], "=")[1],
@@ -44,9 +53,11 @@ func (e *Exporter) extractModulesMetrics(ch chan<- prometheus.Metric, c redis.Co
 		fieldKey := split[0]
 		fieldValue := split[1]
 
-		if !e.includeMetric(fieldKey) {
+		if !e.options.InclModulesMetrics || !e.includeMetric(fieldKey) {
 			continue
 		}
 		e.parseAndRegisterConstMetric(ch, fieldKey, fieldValue)
 	}
+
+	e.registerExpectedModules(ch, loaded)
 }
@@ -0,0 +1,16 @@
+package exporter
+
+import (
+	"github.com/gomodule/redigo/redis"
+)
+
+// clientNoTouch turns on CLIENT NO-TOUCH (Redis 7.2+) for the connection so the
+// commands of the key collectors don't change the LRU/LFU data of the inspected keys,
+// it returns false if the server doesn't support it
+func (e *Exporter) clientNoTouch(c redis.Conn) bool {
+	if _, err := doRedisCmd(c, "CLIENT", "NO-TOUCH", "ON"); err != nil {
+		e.logger().Debugf("CLIENT NO-TOUCH not applied, err: %s", err)
+		return false
+	}
+	return true
+}
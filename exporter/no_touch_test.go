@@ -0,0 +1,16 @@
+package exporter
+
+import (
+	"testing"
+)
+
+func TestClientNoTouch(t *testing.T) {
+	e, _ := NewRedisExporter("", Options{Namespace: "test"})
+
+	if !e.clientNoTouch(&fakeRedisConn{replies: map[string]interface{}{"CLIENT NO-TOUCH ON": "OK"}}) {
+		t.Errorf("expected CLIENT NO-TOUCH to be applied when the server supports it")
+	}
+	if e.clientNoTouch(&fakeRedisConn{}) {
+		t.Errorf("expected CLIENT NO-TOUCH not to be applied when the server rejects it")
+	}
+}
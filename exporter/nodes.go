@@ -13,14 +13,13 @@ var reNodeAddress = regexp.MustCompile(`^(?P<ip>.+):(?P<port>\d+)@(?P<cport>\d+)
 func (e *Exporter) getClusterNodes(c redis.Conn) ([]string, error) {
 	output, err := redis.String(doRedisCmd(c, "CLUSTER", "NODES"))
 	if err != nil {
-		log.Errorf("Error getting cluster nodes: %s", err)
+		e.logger().Errorf("Error getting cluster nodes: %s", err)
 		return nil, err
 	}
 
-	lines := strings.Split(output, "\n")
 	nodes := []string{}
 
-	for _, line := range lines {
+	for line := range strings.Lines(output) {
 		if node, ok := parseClusterNodeString(line); ok {
 			nodes = append(nodes, node)
 		}
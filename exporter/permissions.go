@@ -0,0 +1,468 @@
+package exporter
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gomodule/redigo/redis"
+	log "github.com/sirupsen/logrus"
+)
+
+// requiredCommand is a command that a collector runs, args are the arguments
+// passed to ACL DRYRUN to check that the user may run it
+type requiredCommand struct {
+	collector string
+	args      []string
+}
+
+// permissionCheck is the result of checking one required command
+type permissionCheck struct {
+	requiredCommand
+	allowed bool
+	reason  string
+	// the command doesn't exist, e.g. because of an old version or a missing module
+	unknown bool
+}
+
+// PermissionReport lists the commands of the enabled collectors the user can't run
+type PermissionReport struct {
+	User   string
+	checks []permissionCheck
+}
+
+// sampleKey returns the first key of a check-keys style list, which is used
+// as key name for checking the key permissions of the collector's commands
+func sampleKey(keysArg string) string {
+	keys, err := parseKeyArg(keysArg)
+	if err != nil || len(keys) == 0 {
+		return "key"
+	}
+	return keys[0].key
+}
+
+// placeholders of the collector tables, requiredCommands() replaces them with the
+// configured command name and keys
+const (
+	argConfigCommand = "<config-command>"
+	argCheckKey      = "<check-key>"
+	argStreamKey     = "<stream-key>"
+	argWatchlistKey  = "<watchlist-key>"
+	argWaitProbeKey  = "<wait-probe-key>"
+	argLeaderKey     = "<leader-key>"
+	argSearchIndex   = "<index>"
+	argSampledKey    = "<key>"
+)
+
+// collectorCommands are the commands a collector runs if enabled returns true for the options
+type collectorCommands struct {
+	collector string
+	enabled   func(e *Exporter, checks CheckKeysConfig) bool
+	commands  [][]string
+}
+
+func always(*Exporter, CheckKeysConfig) bool { return true }
+
+// collectorCommandTable lists the commands of all collectors in the order of a scrape, it
+// must be kept in sync with the collectors, TestRequiredCommandsCoverScrape checks it
+var collectorCommandTable = []collectorCommands{
+	{"connection", func(e *Exporter, _ CheckKeysConfig) bool { return !e.options.DisableSelect }, [][]string{
+		{"SELECT", "0"},
+	}},
+	{"connection", func(e *Exporter, _ CheckKeysConfig) bool { return e.options.PingOnConnect }, [][]string{
+		{"PING"},
+	}},
+	{"connection", func(e *Exporter, _ CheckKeysConfig) bool { return e.options.SetClientName }, [][]string{
+		{"CLIENT", "SETNAME", "redis_exporter"},
+		{"CLIENT", "SETINFO", "LIB-NAME", "redis_exporter"},
+	}},
+	{"connection", always, [][]string{
+		{"CLIENT", "NO-TOUCH", "ON"},
+	}},
+	{"config", func(e *Exporter, _ CheckKeysConfig) bool { return e.options.ConfigCommandName != "-" }, [][]string{
+		{argConfigCommand, "GET", "*"},
+	}},
+	{"info", always, [][]string{
+		{"INFO", "ALL"},
+	}},
+	{"info", func(e *Exporter, _ CheckKeysConfig) bool { return e.options.IsCluster }, [][]string{
+		{"CLUSTER", "INFO"},
+	}},
+	{"keys", func(e *Exporter, _ CheckKeysConfig) bool { return e.options.IsCluster }, [][]string{
+		{"CLUSTER", "SLOTS"},
+		{"CLUSTER", "NODES"},
+	}},
+	{"keys", func(e *Exporter, _ CheckKeysConfig) bool {
+		return e.options.IsCluster && e.options.AssumeReadonlyReplica
+	}, [][]string{
+		{"READONLY"},
+	}},
+	{"dbsize_fallback", func(e *Exporter, _ CheckKeysConfig) bool { return e.options.DbsizeFallbackDbs != "" }, [][]string{
+		{"DBSIZE"},
+	}},
+	{"wait_probe", func(e *Exporter, _ CheckKeysConfig) bool { return e.options.WaitProbeKey != "" }, [][]string{
+		{"SET", argWaitProbeKey, "0"},
+		{"WAIT", "1", "0"},
+	}},
+	{"latency", func(e *Exporter, _ CheckKeysConfig) bool { return !e.options.ExcludeLatencyHistogramMetrics }, [][]string{
+		{"LATENCY", "LATEST"},
+		{"LATENCY", "HISTOGRAM"},
+	}},
+	{"kernel_warnings", func(e *Exporter, _ CheckKeysConfig) bool { return e.options.InclKernelWarnings }, [][]string{
+		{"LATENCY", "DOCTOR"},
+	}},
+	{"kernel_warnings", func(e *Exporter, _ CheckKeysConfig) bool {
+		return e.options.InclKernelWarnings && e.options.ConfigCommandName != "-"
+	}, [][]string{
+		{argConfigCommand, "GET", "tcp-backlog"},
+	}},
+	{"keys", func(e *Exporter, checks CheckKeysConfig) bool {
+		return checks.CheckKeys != "" || checks.CheckSingleKeys != "" || checks.CountKeys != ""
+	}, [][]string{
+		{"SCAN", "0", "MATCH", argCheckKey},
+		{"TYPE", argCheckKey},
+		{"GET", argCheckKey},
+		{"STRLEN", argCheckKey},
+		{"LLEN", argCheckKey},
+		{"SCARD", argCheckKey},
+		{"ZCARD", argCheckKey},
+		{"HLEN", argCheckKey},
+		{"XLEN", argCheckKey},
+		{"PFCOUNT", argCheckKey},
+		{"MEMORY", "USAGE", argCheckKey},
+	}},
+	{"keys", func(e *Exporter, checks CheckKeysConfig) bool {
+		return e.options.CheckKeysDumpSize && (checks.CheckKeys != "" || checks.CheckSingleKeys != "")
+	}, [][]string{
+		{"DUMP", argCheckKey},
+	}},
+	{"keys", func(e *Exporter, checks CheckKeysConfig) bool {
+		return e.options.CheckKeysTopN > 0 && e.options.CheckKeysSort == "ttl" && checks.CheckKeys != ""
+	}, [][]string{
+		{"PTTL", argCheckKey},
+	}},
+	{"keys", func(e *Exporter, _ CheckKeysConfig) bool { return e.options.CheckKeysFromKey != "" }, [][]string{
+		{"TYPE", argWatchlistKey},
+		{"SMEMBERS", argWatchlistKey},
+		{"LRANGE", argWatchlistKey, "0", "-1"},
+	}},
+	{"key_sample", func(e *Exporter, _ CheckKeysConfig) bool { return e.options.KeySampleCount > 0 && e.options.IsCluster }, [][]string{
+		{"CLUSTER", "NODES"},
+	}},
+	{"key_sample", func(e *Exporter, _ CheckKeysConfig) bool { return e.options.KeySampleCount > 0 }, [][]string{
+		{"RANDOMKEY"},
+		{"TYPE", argSampledKey},
+		{"TTL", argSampledKey},
+		{"MEMORY", "USAGE", argSampledKey},
+	}},
+	{"streams", func(e *Exporter, checks CheckKeysConfig) bool {
+		return checks.CheckStreams != "" || checks.CheckSingleStreams != ""
+	}, [][]string{
+		{"SCAN", "0", "MATCH", argStreamKey},
+		{"XINFO", "STREAM", argStreamKey},
+		{"XREVRANGE", argStreamKey, "+", "-", "COUNT", "1"},
+		{"XINFO", "GROUPS", argStreamKey},
+		{"XINFO", "CONSUMERS", argStreamKey, "group"},
+	}},
+	{"slowlog", always, [][]string{
+		{"SLOWLOG", "LEN"},
+		{"SLOWLOG", "GET", "1"},
+	}},
+	{"key_groups", func(e *Exporter, _ CheckKeysConfig) bool { return e.keyGroupsConfigured() }, [][]string{
+		// the key group scripts run SCAN, MEMORY USAGE and PTTL for all keys
+		{"EVALSHA", "0000000000000000000000000000000000000000", "0"},
+		{"EVAL", "return 0", "0"},
+		{"SCAN", "0"},
+		{"MEMORY", "USAGE", argSampledKey},
+		{"PTTL", argSampledKey},
+	}},
+	{"leader_election", func(e *Exporter, _ CheckKeysConfig) bool { return e.options.LeaderElectionKey != "" }, [][]string{
+		// the lock scripts run GET, SET, PEXPIRE and DEL on the lock key
+		{"EVALSHA", "0000000000000000000000000000000000000000", "1", argLeaderKey},
+		{"EVAL", "return 0", "1", argLeaderKey},
+		{"GET", argLeaderKey},
+		{"SET", argLeaderKey, "0"},
+		{"PEXPIRE", argLeaderKey, "0"},
+		{"DEL", argLeaderKey},
+	}},
+	{"clients", func(e *Exporter, _ CheckKeysConfig) bool {
+		return e.options.ExportClientList || e.options.InclClientIdleMetrics
+	}, [][]string{
+		{"CLIENT", "LIST"},
+	}},
+	{"tile38", func(e *Exporter, _ CheckKeysConfig) bool { return e.options.IsTile38 }, [][]string{
+		{"SERVER", "EXT"},
+	}},
+	{"modules", func(e *Exporter, _ CheckKeysConfig) bool {
+		return e.options.InclModulesMetrics || len(e.expectedModules) > 0
+	}, [][]string{
+		{"INFO", "MODULES"},
+	}},
+	{"pubsub_shard", func(e *Exporter, _ CheckKeysConfig) bool { return e.options.InclPubSubShardMetrics }, [][]string{
+		{"PUBSUB", "SHARDCHANNELS"},
+		{"PUBSUB", "SHARDNUMSUB"},
+	}},
+	{"acl_log", func(e *Exporter, _ CheckKeysConfig) bool { return e.options.InclACLLogMetrics }, [][]string{
+		{"ACL", "LOG"},
+	}},
+	{"search_indexes", func(e *Exporter, _ CheckKeysConfig) bool { return e.options.InclSearchIndexesMetrics }, [][]string{
+		{"FT._LIST"},
+		{"FT.INFO", argSearchIndex},
+	}},
+	{"lua", func(e *Exporter, _ CheckKeysConfig) bool { return len(e.options.LuaScript) > 0 }, [][]string{
+		{"EVAL", "return 0", "0"},
+	}},
+}
+
+// requiredCommands returns the commands the enabled collectors need, generated from
+// collectorCommandTable and the configured probes
+func (e *Exporter) requiredCommands() []requiredCommand {
+	checks := e.checkKeysConfig()
+	watchlistKey := "key"
+	if wl, _ := parseKeyWatchlist(e.options.CheckKeysFromKey); wl != nil {
+		watchlistKey = wl.key
+	}
+	placeholders := map[string]string{
+		argConfigCommand: e.options.ConfigCommandName,
+		argCheckKey:      sampleKey(checks.CheckKeys + "," + checks.CheckSingleKeys + "," + checks.CountKeys),
+		argStreamKey:     sampleKey(checks.CheckStreams + "," + checks.CheckSingleStreams),
+		argWatchlistKey:  watchlistKey,
+		argWaitProbeKey:  e.options.WaitProbeKey,
+		argLeaderKey:     e.options.LeaderElectionKey,
+		argSearchIndex:   "index",
+		argSampledKey:    "key",
+	}
+
+	var cmds []requiredCommand
+	for _, t := range collectorCommandTable {
+		if !t.enabled(e, checks) {
+			continue
+		}
+		for _, cmd := range t.commands {
+			args := make([]string, len(cmd))
+			for i, a := range cmd {
+				if v, ok := placeholders[a]; ok {
+					a = v
+				}
+				args[i] = a
+			}
+			cmds = append(cmds, requiredCommand{collector: t.collector, args: args})
+		}
+	}
+	for _, p := range e.options.Probes {
+		if len(p.Command) > 0 {
+			cmds = append(cmds, requiredCommand{collector: "probes", args: p.Command})
+		}
+	}
+	return cmds
+}
+
+// checkPermissions uses ACL DRYRUN (Redis 7.0+) to check whether the user of c can run
+// all commands of the enabled collectors
+func (e *Exporter) checkPermissions(c redis.Conn) (*PermissionReport, error) {
+	user, err := redis.String(doRedisCmd(c, "ACL", "WHOAMI"))
+	if err != nil {
+		return nil, fmt.Errorf("ACL WHOAMI failed: %w", err)
+	}
+
+	report := &PermissionReport{User: user}
+	for _, cmd := range e.requiredCommands() {
+		args := []interface{}{"DRYRUN", user}
+		for _, a := range cmd.args {
+			args = append(args, a)
+		}
+
+		check := permissionCheck{requiredCommand: cmd, allowed: true}
+		reply, err := redis.String(doRedisCmd(c, "ACL", args...))
+		var (
+			unsupportedErr *UnsupportedCommandError
+			permissionErr  *PermissionError
+		)
+		switch {
+		case errors.As(err, &unsupportedErr):
+			return nil, fmt.Errorf("ACL DRYRUN isn't supported (Redis 7.0+ is required): %w", err)
+		case errors.As(err, &permissionErr):
+			return nil, fmt.Errorf("user %s isn't allowed to run ACL DRYRUN: %w", user, err)
+		case err != nil:
+			check.allowed, check.reason = false, err.Error()
+			check.unknown = strings.Contains(err.Error(), "not found")
+		case reply != "OK":
+			check.allowed, check.reason = false, reply
+		}
+		report.checks = append(report.checks, check)
+	}
+	return report, nil
+}
+
+// Missing returns the number of commands the user can't run
+func (r *PermissionReport) Missing() int {
+	n := 0
+	for _, c := range r.checks {
+		if !c.allowed {
+			n++
+		}
+	}
+	return n
+}
+
+// WriteTo writes a report of the missing permissions and the ACL rules to grant them
+func (r *PermissionReport) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "user %q can run %d of %d commands of the enabled collectors\n", r.User, len(r.checks)-r.Missing(), len(r.checks))
+
+	var rules []string
+	seen := map[string]bool{}
+	for _, c := range r.checks {
+		if c.allowed {
+			continue
+		}
+		fmt.Fprintf(&b, "  missing: collector %s: %s: %s\n", c.collector, strings.Join(c.args, " "), c.reason)
+		if c.unknown {
+			continue
+		}
+
+		rule := aclRule(c.requiredCommand)
+		if !seen[rule] {
+			seen[rule] = true
+			rules = append(rules, rule)
+		}
+	}
+	if len(rules) > 0 {
+		fmt.Fprintf(&b, "grant the missing commands with: ACL SETUSER %s %s\n", r.User, strings.Join(rules, " "))
+		b.WriteString("key permissions (~pattern) may also be needed for the keys of the check-keys and streams collectors\n")
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// aclRule returns the ACL rule that allows cmd, subcommands are granted as +command|subcommand
+func aclRule(cmd requiredCommand) string {
+	rule := "+" + strings.ToLower(cmd.args[0])
+	if len(cmd.args) > 1 && isContainerCommand(cmd.args[0]) {
+		rule += "|" + strings.ToLower(cmd.args[1])
+	}
+	return rule
+}
+
+func isContainerCommand(cmd string) bool {
+	switch strings.ToUpper(cmd) {
+	case "ACL", "CLIENT", "CLUSTER", "CONFIG", "LATENCY", "MEMORY", "PUBSUB", "SLOWLOG", "XINFO":
+		return true
+	}
+	return false
+}
+
+// CheckPermissions connects to the configured instance and logs the commands of the
+// enabled collectors the configured user isn't allowed to run
+func (e *Exporter) CheckPermissions() error {
+	c, err := e.connectToRedis()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	report, err := e.checkPermissions(c)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	report.WriteTo(&b)
+	if report.Missing() > 0 {
+		log.Warnf("Permission check: %s", b.String())
+	} else {
+		log.Infof("Permission check: %s", b.String())
+	}
+	return nil
+}
+
+func (e *Exporter) permissionsHandler(w http.ResponseWriter, r *http.Request) {
+	exp := e
+	if target := r.URL.Query().Get("target"); target != "" {
+		target, user, err := parseTarget(target)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid 'target' parameter, parse err: %s", err), http.StatusBadRequest)
+			return
+		}
+		opts := e.options
+		opts.Registry = nil
+		if user != "" {
+			opts.User = user
+		}
+		if exp, err = NewRedisExporter(target, opts); err != nil {
+			http.Error(w, fmt.Sprintf("NewRedisExporter() error: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	c, err := exp.connectToRedis()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Couldn't connect to redis: %s", err), errorStatusCode(err))
+		return
+	}
+	defer c.Close()
+
+	report, err := exp.checkPermissions(c)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Permission check failed: %s", err), errorStatusCode(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = report.WriteTo(w)
+}
+
+// keyPatterns returns the ACL key patterns of the keys the enabled collectors access
+func (e *Exporter) keyPatterns() []string {
+	// the key groups scan all keys and the key sample picks random ones
+	if e.keyGroupsConfigured() || e.options.KeySampleCount > 0 {
+		return []string{"~*"}
+	}
+
+	var patterns []string
+	seen := map[string]bool{}
+	add := func(key string) {
+		if key != "" && !seen[key] {
+			seen[key] = true
+			patterns = append(patterns, "~"+key)
+		}
+	}
+	checks := e.checkKeysConfig()
+	for _, arg := range []string{checks.CheckKeys, checks.CheckSingleKeys, checks.CountKeys, checks.CheckStreams, checks.CheckSingleStreams} {
+		keys, _ := parseKeyArg(arg)
+		for _, k := range keys {
+			add(k.key)
+		}
+	}
+	if wl, _ := parseKeyWatchlist(e.options.CheckKeysFromKey); wl != nil {
+		add(wl.key)
+	}
+	add(e.options.WaitProbeKey)
+	add(e.options.LeaderElectionKey)
+	for _, p := range e.options.Probes {
+		if key, ok := probeKey(p); ok {
+			add(key)
+		}
+	}
+	return patterns
+}
+
+// MinimalACL returns the ACL rules a user needs to run the enabled collectors,
+// e.g. for ACL SETUSER <user> on ><password> <rules>
+func (e *Exporter) MinimalACL() string {
+	rules := []string{"-@all"}
+	seen := map[string]bool{}
+	for _, cmd := range e.requiredCommands() {
+		if rule := aclRule(cmd); !seen[rule] {
+			seen[rule] = true
+			rules = append(rules, rule)
+		}
+	}
+	if len(e.options.LuaScript) > 0 {
+		log.Warnf("The commands and keys used by the Lua scripts aren't part of the ACL rules, add them manually")
+	}
+	return strings.Join(append(rules, e.keyPatterns()...), " ")
+}
@@ -0,0 +1,184 @@
+package exporter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/oliver006/redis_exporter/exporter/exportertest"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestRequiredCommands(t *testing.T) {
+	e, _ := NewRedisExporter("", Options{Namespace: "test", CheckSingleKeys: "db1=app:counter", ExportClientList: true, InclACLLogMetrics: true})
+
+	got := map[string]bool{}
+	for _, cmd := range e.requiredCommands() {
+		got[cmd.collector+": "+strings.Join(cmd.args, " ")] = true
+	}
+	for _, want := range []string{
+		"info: INFO ALL",
+		"config: CONFIG GET *",
+		"keys: TYPE app:counter",
+		"keys: MEMORY USAGE app:counter",
+		"clients: CLIENT LIST",
+		"acl_log: ACL LOG",
+	} {
+		if !got[want] {
+			t.Errorf("expected required command %q, got: %v", want, got)
+		}
+	}
+	if got["lua: EVAL return 0 0"] || got["streams: XINFO STREAM key"] {
+		t.Errorf("expected no commands of disabled collectors, got: %v", got)
+	}
+}
+
+func TestCheckPermissions(t *testing.T) {
+	e, _ := NewRedisExporter("", Options{Namespace: "test", ExportClientList: true})
+
+	c := &fakeRedisConn{
+		replies: map[string]interface{}{
+			"ACL WHOAMI":                            "exporter",
+			"ACL DRYRUN exporter CLIENT LIST":       "This user has no permissions to run the 'client|list' command",
+			"ACL DRYRUN exporter LATENCY HISTOGRAM": redis.Error("ERR Command 'latency|histogram' not found"),
+		},
+		prefixReplies: map[string]interface{}{"ACL DRYRUN exporter": "OK"},
+	}
+	report, err := e.checkPermissions(c)
+	if err != nil {
+		t.Fatalf("checkPermissions() err: %s", err)
+	}
+	if report.User != "exporter" || report.Missing() != 2 {
+		t.Errorf("expected 2 missing commands of user exporter, got: %d %s", report.Missing(), report.User)
+	}
+
+	var b strings.Builder
+	report.WriteTo(&b)
+	for _, want := range []string{
+		"missing: collector clients: CLIENT LIST",
+		"ACL SETUSER exporter +client|list\n",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("expected %q in report, got: %s", want, b.String())
+		}
+	}
+
+	unsupported := exportertest.NewServer(t)
+	unsupported.Set("ACL WHOAMI", "default")
+	unsupported.Set("ACL DRYRUN", exportertest.Error("ERR unknown subcommand 'DRYRUN'. Try ACL HELP."))
+	if _, err := e.checkPermissions(dialServer(t, unsupported)); err == nil || !strings.Contains(err.Error(), "isn't supported") {
+		t.Errorf("expected error if ACL DRYRUN isn't supported, got: %v", err)
+	}
+
+	denied := exportertest.NewServer(t)
+	denied.Set("ACL WHOAMI", "exporter")
+	denied.Set("ACL DRYRUN", exportertest.Error("NOPERM User exporter has no permissions to run the 'acl|dryrun' command"))
+	if _, err := e.checkPermissions(dialServer(t, denied)); err == nil || !strings.Contains(err.Error(), "isn't allowed to run ACL DRYRUN") {
+		t.Errorf("expected error if the user can't run ACL DRYRUN, got: %v", err)
+	}
+}
+
+func TestMinimalACL(t *testing.T) {
+	for _, tst := range []struct {
+		name    string
+		opts    Options
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    "check keys",
+			opts:    Options{CheckKeys: "db0=sessions:*", CheckSingleKeys: "db1=app:counter", ExportClientList: true},
+			want:    []string{"-@all", "+info", "+select", "+config|get", "+slowlog|get", "+client|list", "+memory|usage", "+strlen", "~sessions:*", "~app:counter"},
+			notWant: []string{"~*", "+eval", "+acl|log"},
+		},
+		{
+			name:    "key groups scan all keys",
+			opts:    Options{CheckKeyGroups: "^(.*?):", DisableSelect: true, ConfigCommandName: "-"},
+			want:    []string{"+evalsha", "+eval", "+scan", "+pttl", "+memory|usage", "~*"},
+			notWant: []string{"+select", "+config|get"},
+		},
+		{
+			name: "probes and wait probe",
+			opts: Options{Probes: []Probe{{Name: "queue", Command: []string{"LLEN", "jobs"}}}, WaitProbeKey: "exporter:wait"},
+			want: []string{"+llen", "+set", "+wait", "~jobs", "~exporter:wait"},
+		},
+		{
+			name:    "probe without key and watchlist",
+			opts:    Options{Probes: []Probe{{Name: "size", Command: []string{"DBSIZE"}}}, CheckKeysFromKey: "db0=monitoring:watch"},
+			want:    []string{"+dbsize", "+smembers", "+lrange", "~monitoring:watch"},
+			notWant: []string{"~*"},
+		},
+	} {
+		e, _ := NewRedisExporter("", tst.opts)
+		rules := map[string]int{}
+		for _, r := range strings.Fields(e.MinimalACL()) {
+			rules[r]++
+		}
+		for _, r := range tst.want {
+			if rules[r] != 1 {
+				t.Errorf("%s: expected rule %s once, got: %s", tst.name, r, e.MinimalACL())
+			}
+		}
+		for _, r := range tst.notWant {
+			if rules[r] != 0 {
+				t.Errorf("%s: unexpected rule %s, got: %s", tst.name, r, e.MinimalACL())
+			}
+		}
+	}
+}
+
+func TestRequiredCommandsCoverScrape(t *testing.T) {
+	srv := exportertest.NewServer(t)
+	srv.Set("SCAN", []interface{}{"0", []string{}})
+	srv.Set("TYPE app:counter", exportertest.Status("string"))
+	srv.Set("TYPE watch", exportertest.Status("set"))
+	srv.Set("SMEMBERS watch", []string{"db0=app:counter"})
+	srv.Set("RANDOMKEY", "app:counter")
+	srv.Set("FT._LIST", []string{"idx"})
+	srv.Set("PUBSUB SHARDCHANNELS", []string{"orders"})
+
+	e, err := NewRedisExporter(srv.Addr(), Options{
+		Namespace: "test", PingOnConnect: true, SetClientName: true, CheckSingleKeys: "db0=app:counter",
+		CheckSingleStreams: "db0=events", CheckKeysFromKey: "db0=watch", KeySampleCount: 1, ExportClientList: true,
+		InclModulesMetrics: true, InclSearchIndexesMetrics: true, InclPubSubShardMetrics: true, InclACLLogMetrics: true,
+		InclKernelWarnings: true, IsTile38: true, Probes: []Probe{{Name: "size", Command: []string{"DBSIZE"}}},
+	})
+	if err != nil {
+		t.Fatalf("NewRedisExporter() err: %s", err)
+	}
+	ch := make(chan prometheus.Metric)
+	go func() {
+		e.Collect(ch)
+		close(ch)
+	}()
+	for range ch {
+	}
+
+	rules := map[string]bool{}
+	for _, cmd := range e.requiredCommands() {
+		rules[aclRule(cmd)] = true
+	}
+	for _, cmd := range srv.Commands() {
+		if rule := aclRule(requiredCommand{args: strings.Fields(cmd)}); !rules[rule] {
+			t.Errorf("the scrape ran %q, its rule %s isn't part of the required commands", cmd, rule)
+		}
+	}
+}
+
+func TestPermissionsHandlerAdminOnly(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/debug/permissions", nil)
+	for _, adminAddr := range []string{"", "localhost:0"} {
+		e, _ := NewRedisExporter("", Options{Namespace: "test", AdminListenAddress: adminAddr})
+		if _, pattern := e.mux.Handler(req); pattern != "/" {
+			t.Errorf("admin address %q: expected /debug/permissions not to be served on the main address, got: %s", adminAddr, pattern)
+		}
+		if e.adminMux == nil {
+			continue
+		}
+		if _, pattern := e.adminMux.Handler(req); pattern != "/debug/permissions" {
+			t.Errorf("expected /debug/permissions to be served on the admin address, got: %q", pattern)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package exporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+// Probe is a read-only command that is run on every scrape, its reply is
+// compared with Expect and/or checked to be within Min and Max
+type Probe struct {
+	Name    string   `json:"name"`
+	DB      int      `json:"db"`
+	Command []string `json:"command"`
+	Expect  *string  `json:"expect"`
+	Min     *float64 `json:"min"`
+	Max     *float64 `json:"max"`
+}
+
+// probeCommands are the read-only commands that can be used by probes and the
+// position of their key argument, 0 for commands without key
+var probeCommands = map[string]int{
+	"dbsize": 0, "exists": 1, "get": 1, "getrange": 1, "hexists": 1,
+	"hget": 1, "hlen": 1, "hstrlen": 1, "lindex": 1, "llen": 1,
+	"pttl": 1, "scard": 1, "sismember": 1, "strlen": 1, "ttl": 1,
+	"type": 1, "xlen": 1, "zcard": 1, "zcount": 1, "zscore": 1,
+}
+
+func isProbeCommand(cmd string) bool {
+	_, ok := probeCommands[strings.ToLower(cmd)]
+	return ok
+}
+
+// probeKey returns the key the probe accesses, false for commands without key
+func probeKey(p Probe) (string, bool) {
+	if len(p.Command) == 0 {
+		return "", false
+	}
+	pos := probeCommands[strings.ToLower(p.Command[0])]
+	if pos == 0 || pos >= len(p.Command) {
+		return "", false
+	}
+	return p.Command[pos], true
+}
+
+// LoadProbeConfigFile reads the probe config file and validates the probes
+func LoadProbeConfigFile(probeFile string) ([]Probe, error) {
+	log.Debugf("start load probe config file: %s", probeFile)
+	bytes, err := os.ReadFile(probeFile)
+	if err != nil {
+		log.Warnf("load probe config file failed: %s", err)
+		return nil, err
+	}
+
+	var probes []Probe
+	if err := json.Unmarshal(bytes, &probes); err != nil {
+		log.Warnf("probe config file format error: %s", err)
+		return nil, err
+	}
+
+	names := map[string]bool{}
+	for _, p := range probes {
+		if p.Name == "" || names[p.Name] {
+			return nil, fmt.Errorf("probe names must be set and unique, got: %q", p.Name)
+		}
+		names[p.Name] = true
+
+		if len(p.Command) == 0 || !isProbeCommand(p.Command[0]) {
+			return nil, fmt.Errorf("probe %s: command %v is not an allowed read-only command", p.Name, p.Command)
+		}
+	}
+
+	log.Infof("Loaded %d probes from %s", len(probes), probeFile)
+	return probes, nil
+}
+
+func (e *Exporter) extractProbeMetrics(ch chan<- prometheus.Metric, c redis.Conn) {
+	for _, p := range e.options.Probes {
+		start := time.Now()
+		success, val, err := runProbe(c, p)
+		if err != nil {
+			e.logger().Debugf("probe %s failed: %s", p.Name, err)
+		}
+
+		e.registerConstMetricGauge(ch, "probe_duration_seconds", time.Since(start).Seconds(), p.Name)
+		e.registerConstMetricGauge(ch, "probe_success", success, p.Name)
+		if val != nil {
+			e.registerConstMetricGauge(ch, "probe_value", *val, p.Name)
+		}
+	}
+}
+
+// runProbe returns 1 if the probe succeeded and the numeric value of the reply, if any
+func runProbe(c redis.Conn, p Probe) (float64, *float64, error) {
+	if _, err := doRedisCmd(c, "SELECT", p.DB); err != nil {
+		return 0, nil, err
+	}
+
+	args := make([]interface{}, len(p.Command)-1)
+	for i, a := range p.Command[1:] {
+		args[i] = a
+	}
+	reply, err := doRedisCmd(c, p.Command[0], args...)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var strVal string
+	switch r := reply.(type) {
+	case nil:
+	case int64:
+		strVal = strconv.FormatInt(r, 10)
+	default:
+		if strVal, err = redis.String(reply, nil); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	var val *float64
+	if f, err := strconv.ParseFloat(strVal, 64); err == nil {
+		val = &f
+	}
+
+	if p.Expect != nil && strVal != *p.Expect {
+		return 0, val, fmt.Errorf("expected %q, got %q", *p.Expect, strVal)
+	}
+	if p.Min != nil || p.Max != nil {
+		if val == nil {
+			return 0, nil, fmt.Errorf("reply %q is not numeric", strVal)
+		}
+		if (p.Min != nil && *val < *p.Min) || (p.Max != nil && *val > *p.Max) {
+			return 0, val, fmt.Errorf("value %f out of range", *val)
+		}
+	}
+	return 1, val, nil
+}
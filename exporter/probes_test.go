@@ -0,0 +1,100 @@
+package exporter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestLoadProbeConfigFile(t *testing.T) {
+	probes, err := LoadProbeConfigFile("../contrib/sample-probe-config.json")
+	if err != nil {
+		t.Fatalf("LoadProbeConfigFile() err: %s", err)
+	}
+	if len(probes) != 3 || probes[0].Name != "jobs_queue_length" || *probes[0].Max != 1000 {
+		t.Errorf("unexpected probes: %#v", probes)
+	}
+
+	if _, err := LoadProbeConfigFile("non-existent.json"); err == nil {
+		t.Errorf("expected error for missing file")
+	}
+
+	for _, content := range []string{
+		`[{"name": "del", "command": ["DEL", "key"]}]`,
+		`[{"name": "empty", "command": []}]`,
+		`[{"command": ["GET", "key"]}]`,
+		`[{"name": "dup", "command": ["GET", "a"]}, {"name": "dup", "command": ["GET", "b"]}]`,
+	} {
+		f := filepath.Join(t.TempDir(), "probes.json")
+		if err := os.WriteFile(f, []byte(content), 0o600); err != nil {
+			t.Fatalf("WriteFile() err: %s", err)
+		}
+		if _, err := LoadProbeConfigFile(f); err == nil {
+			t.Errorf("expected error for probes: %s", content)
+		}
+	}
+}
+
+func TestProbeMetrics(t *testing.T) {
+	str := func(s string) *string { return &s }
+	num := func(f float64) *float64 { return &f }
+
+	c := &fakeRedisConn{replies: map[string]interface{}{
+		"SELECT 0":                "OK",
+		"SELECT 1":                "OK",
+		"LLEN jobs:queue":         int64(10),
+		"GET app:maintenance":     []byte("on"),
+		"EXISTS app:leader":       int64(1),
+		"ZSCORE scores nonnumber": []byte("abc"),
+	}}
+
+	e, _ := NewRedisExporter("", Options{Namespace: "test", Probes: []Probe{
+		{Name: "queue_ok", DB: 1, Command: []string{"LLEN", "jobs:queue"}, Max: num(100)},
+		{Name: "queue_too_long", DB: 1, Command: []string{"LLEN", "jobs:queue"}, Max: num(5)},
+		{Name: "maintenance_off", Command: []string{"GET", "app:maintenance"}, Expect: str("off")},
+		{Name: "leader", Command: []string{"EXISTS", "app:leader"}},
+		{Name: "not_numeric", Command: []string{"ZSCORE", "scores", "nonnumber"}, Min: num(1)},
+		{Name: "cmd_error", Command: []string{"GET", "missing"}},
+	}})
+
+	chM := make(chan prometheus.Metric)
+	go func() {
+		e.extractProbeMetrics(chM, c)
+		close(chM)
+	}()
+
+	success := map[string]float64{}
+	values := map[string]float64{}
+	for m := range chM {
+		d := &dto.Metric{}
+		m.Write(d)
+		desc := m.Desc().String()
+		switch {
+		case strings.Contains(desc, "test_probe_success"):
+			success[d.GetLabel()[0].GetValue()] = d.GetGauge().GetValue()
+		case strings.Contains(desc, "test_probe_value"):
+			values[d.GetLabel()[0].GetValue()] = d.GetGauge().GetValue()
+		}
+	}
+
+	for probe, want := range map[string]float64{
+		"queue_ok":        1,
+		"queue_too_long":  0,
+		"maintenance_off": 0,
+		"leader":          1,
+		"not_numeric":     0,
+		"cmd_error":       0,
+	} {
+		if got, ok := success[probe]; !ok || got != want {
+			t.Errorf("probe %s: expected success %.0f, got: %.0f (found: %t)", probe, want, got, ok)
+		}
+	}
+
+	if len(values) != 3 || values["queue_ok"] != 10 || values["leader"] != 1 {
+		t.Errorf("unexpected probe values: %#v", values)
+	}
+}
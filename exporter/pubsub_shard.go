@@ -0,0 +1,48 @@
+package exporter
+
+import (
+	"github.com/gomodule/redigo/redis"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// pubsubShardNumSubBatchSize is the number of channels per PUBSUB SHARDNUMSUB call
+const pubsubShardNumSubBatchSize = 100
+
+// extractPubSubShardMetrics exports the subscribers of the shard channels (Redis 7.0+) of the
+// node, per channel only with InclPubSubShardChannels as nodes can have many channels.
+// The number of shard channels is exported from INFO as pubsubshard_channels
+func (e *Exporter) extractPubSubShardMetrics(ch chan<- prometheus.Metric, c redis.Conn) {
+	channels, err := redis.Strings(doRedisCmd(c, "PUBSUB", "SHARDCHANNELS"))
+	if err != nil {
+		e.logger().Debugf("PUBSUB SHARDCHANNELS err: %s", err)
+		return
+	}
+
+	total := int64(0)
+	for start := 0; start < len(channels); start += pubsubShardNumSubBatchSize {
+		batch := channels[start:min(start+pubsubShardNumSubBatchSize, len(channels))]
+		args := make([]interface{}, 0, len(batch)+1)
+		args = append(args, "SHARDNUMSUB")
+		for _, channel := range batch {
+			args = append(args, channel)
+		}
+
+		values, err := redis.Values(doRedisCmd(c, "PUBSUB", args...))
+		if err != nil {
+			e.logger().Errorf("PUBSUB SHARDNUMSUB err: %s", err)
+			return
+		}
+		for i := 0; i+1 < len(values); i += 2 {
+			channel, _ := redis.String(values[i], nil)
+			subscribers, err := redis.Int64(values[i+1], nil)
+			if err != nil {
+				continue
+			}
+			total += subscribers
+			if e.options.InclPubSubShardChannels {
+				e.registerConstMetricGauge(ch, "pubsub_shard_channel_subscribers", float64(subscribers), e.labelValue(ch, channel))
+			}
+		}
+	}
+	e.registerConstMetricGauge(ch, "pubsub_shard_subscribers", float64(total))
+}
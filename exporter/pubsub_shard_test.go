@@ -0,0 +1,99 @@
+package exporter
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestPubSubShardMetrics(t *testing.T) {
+	e, _ := NewRedisExporter("redis://localhost:6379", Options{Namespace: "test", InclPubSubShardMetrics: true, InclPubSubShardChannels: true})
+
+	// more channels than fit into one SHARDNUMSUB batch
+	channels := make([]interface{}, 0, pubsubShardNumSubBatchSize+1)
+	replies := map[string]interface{}{}
+	numSub := []interface{}{}
+	args := "PUBSUB SHARDNUMSUB"
+	for i := 0; i <= pubsubShardNumSubBatchSize; i++ {
+		name := fmt.Sprintf("orders:{%d}", i)
+		channels = append(channels, []byte(name))
+		if i == pubsubShardNumSubBatchSize {
+			replies[args] = numSub
+			args, numSub = "PUBSUB SHARDNUMSUB", []interface{}{}
+		}
+		args += " " + name
+		numSub = append(numSub, []byte(name), int64(i%3))
+	}
+	replies[args] = numSub
+	replies["PUBSUB SHARDCHANNELS"] = channels
+
+	chM := make(chan prometheus.Metric)
+	go func() {
+		e.extractPubSubShardMetrics(chM, &fakeRedisConn{replies: replies})
+		close(chM)
+	}()
+
+	perChannel := map[string]float64{}
+	total := -1.0
+	for m := range chM {
+		d := &dto.Metric{}
+		m.Write(d)
+		if m.Desc() == e.mustFindMetricDescription("pubsub_shard_channel_subscribers") {
+			perChannel[d.GetLabel()[0].GetValue()] = d.GetGauge().GetValue()
+		} else {
+			total = d.GetGauge().GetValue()
+		}
+	}
+
+	if len(perChannel) != pubsubShardNumSubBatchSize+1 {
+		t.Errorf("expected %d channels, got: %d", pubsubShardNumSubBatchSize+1, len(perChannel))
+	}
+	if got := map[string]float64{"orders:{1}": perChannel["orders:{1}"], "orders:{100}": perChannel["orders:{100}"]}; !reflect.DeepEqual(got, map[string]float64{"orders:{1}": 1, "orders:{100}": 1}) {
+		t.Errorf("unexpected subscribers: %v", got)
+	}
+	// 0+1+2 for 33 groups of three channels and 0+1 for the last two
+	if total != 100 {
+		t.Errorf("expected 100 subscribers, got: %f", total)
+	}
+}
+
+func TestPubSubShardMetricsUnsupported(t *testing.T) {
+	e, _ := NewRedisExporter("redis://localhost:6379", Options{Namespace: "test", InclPubSubShardMetrics: true})
+
+	chM := make(chan prometheus.Metric)
+	go func() {
+		e.extractPubSubShardMetrics(chM, &fakeRedisConn{})
+		close(chM)
+	}()
+	for m := range chM {
+		t.Errorf("unexpected metric: %s", m.Desc())
+	}
+}
+
+func TestPubSubShardMetricsWithoutChannels(t *testing.T) {
+	e, _ := NewRedisExporter("redis://localhost:6379", Options{Namespace: "test", InclPubSubShardMetrics: true})
+
+	c := &fakeRedisConn{replies: map[string]interface{}{
+		"PUBSUB SHARDCHANNELS":   []interface{}{[]byte("a"), []byte("b")},
+		"PUBSUB SHARDNUMSUB a b": []interface{}{[]byte("a"), int64(2), []byte("b"), int64(3)},
+	}}
+	chM := make(chan prometheus.Metric)
+	go func() {
+		e.extractPubSubShardMetrics(chM, c)
+		close(chM)
+	}()
+
+	var got []string
+	for m := range chM {
+		d := &dto.Metric{}
+		m.Write(d)
+		got = append(got, fmt.Sprintf("%s %v", m.Desc(), d.GetGauge().GetValue()))
+	}
+	if len(got) != 1 || !strings.Contains(got[0], "test_pubsub_shard_subscribers") || !strings.HasSuffix(got[0], " 5") {
+		t.Errorf("expected only the total of the subscribers, got: %v", got)
+	}
+}
@@ -1,15 +1,63 @@
 package exporter
 
 import (
+	"bytes"
+	"crypto/tls"
 	"encoding/json"
+	"fmt"
 	"os"
 
 	log "github.com/sirupsen/logrus"
 )
 
-// LoadPwdFile reads the redis password file and returns the password map
-func LoadPwdFile(passwordFile string) (map[string]string, error) {
-	res := make(map[string]string)
+// PasswordEntry are the connection settings of a target of the password file,
+// entries are either just the password or an object with these fields
+type PasswordEntry struct {
+	Username   string `json:"username"`
+	Password   string `json:"password"`
+	TLS        *bool  `json:"tls"`
+	ServerName string `json:"server_name"`
+	CaCertFile string `json:"ca_file"`
+}
+
+func (p *PasswordEntry) UnmarshalJSON(data []byte) error {
+	var pwd string
+	if err := json.Unmarshal(data, &pwd); err == nil {
+		*p = PasswordEntry{Password: pwd}
+		return nil
+	}
+
+	// the alias type doesn't have UnmarshalJSON
+	type entry PasswordEntry
+	var res entry
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&res); err != nil {
+		return err
+	}
+	*p = PasswordEntry(res)
+	return nil
+}
+
+// applyTLS overrides the server name and the CAs of the TLS config of a connection
+func (p PasswordEntry) applyTLS(tlsConfig *tls.Config) error {
+	if p.ServerName != "" {
+		tlsConfig.ServerName = p.ServerName
+	}
+	if p.CaCertFile != "" {
+		certificates, err := LoadCAFile(p.CaCertFile)
+		if err != nil {
+			return err
+		}
+		tlsConfig.RootCAs = certificates
+	}
+	return nil
+}
+
+// LoadPwdFileEntries reads the redis password file and returns the connection settings per target,
+// key is needed if the file is encrypted
+func LoadPwdFileEntries(passwordFile string, key []byte) (map[string]PasswordEntry, error) {
+	res := make(map[string]PasswordEntry)
 
 	log.Debugf("start load password file: %s", passwordFile)
 	bytes, err := os.ReadFile(passwordFile)
@@ -17,12 +65,25 @@ func LoadPwdFile(passwordFile string) (map[string]string, error) {
 		log.Warnf("load password file failed: %s", err)
 		return nil, err
 	}
+	bytes, err = decryptPwdFile(bytes, key)
+	if err != nil {
+		log.Warnf("load password file failed: %s", err)
+		return nil, err
+	}
 	err = json.Unmarshal(bytes, &res)
 	if err != nil {
 		log.Warnf("password file format error: %s", err)
 		return nil, err
 	}
 
+	for k, entry := range res {
+		if entry.CaCertFile != "" {
+			if _, err := LoadCAFile(entry.CaCertFile); err != nil {
+				return nil, fmt.Errorf("invalid ca_file of %s: %w", k, err)
+			}
+		}
+	}
+
 	log.Infof("Loaded %d entries from %s", len(res), passwordFile)
 	for k := range res {
 		log.Debugf("%s", k)
@@ -30,3 +91,17 @@ func LoadPwdFile(passwordFile string) (map[string]string, error) {
 
 	return res, nil
 }
+
+// LoadPwdFile reads the redis password file and returns the password map
+func LoadPwdFile(passwordFile string) (map[string]string, error) {
+	entries, err := LoadPwdFileEntries(passwordFile, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]string, len(entries))
+	for k, entry := range entries {
+		res[k] = entry.Password
+	}
+	return res, nil
+}
@@ -0,0 +1,95 @@
+package exporter
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// encryptedPwdFileHeader is the first line of an encrypted password file, it's
+// followed by the base64 encoded nonce and AES-256-GCM ciphertext of the JSON
+const encryptedPwdFileHeader = "redis_exporter:aes-256-gcm:v1\n"
+
+var errPwdFileKeyMissing = errors.New("the password file is encrypted but no key is configured")
+
+// ParsePwdFileKey decodes a base64 encoded 32 byte key of an encrypted password file
+func ParsePwdFileKey(s string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("invalid password file key: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("invalid password file key: expected 32 bytes, got %d", len(key))
+	}
+	return key, nil
+}
+
+// LoadPwdFileKey reads the key of an encrypted password file from keyFile
+func LoadPwdFileKey(keyFile string) ([]byte, error) {
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePwdFileKey(string(data))
+}
+
+// EncryptPwdFile encrypts the content of a password file with key
+func EncryptPwdFile(plaintext []byte, key []byte) ([]byte, error) {
+	gcm, err := newPwdFileCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	sealed := gcm.Seal(nonce, nonce, plaintext, []byte(encryptedPwdFileHeader))
+	return []byte(encryptedPwdFileHeader + base64.StdEncoding.EncodeToString(sealed) + "\n"), nil
+}
+
+// decryptPwdFile returns the plain content of a password file, files without
+// the header of encrypted files are returned unchanged
+func decryptPwdFile(data []byte, key []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, []byte(encryptedPwdFileHeader)) {
+		return data, nil
+	}
+	if len(key) == 0 {
+		return nil, errPwdFileKeyMissing
+	}
+
+	gcm, err := newPwdFileCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data[len(encryptedPwdFileHeader):])))
+	if err != nil {
+		return nil, fmt.Errorf("invalid encrypted password file: %w", err)
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("invalid encrypted password file: too short")
+	}
+
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], []byte(encryptedPwdFileHeader))
+	if err != nil {
+		return nil, fmt.Errorf("couldn't decrypt password file, wrong key? err: %w", err)
+	}
+	return plaintext, nil
+}
+
+func newPwdFileCipher(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("invalid password file key: expected 32 bytes, got %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,69 @@
+package exporter
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParsePwdFileKey(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString(make([]byte, 32))
+	if got, err := ParsePwdFileKey(key + "\n"); err != nil || len(got) != 32 {
+		t.Errorf("ParsePwdFileKey() = %v, err: %v", got, err)
+	}
+
+	for _, s := range []string{"", "not base64!", base64.StdEncoding.EncodeToString(make([]byte, 16))} {
+		if _, err := ParsePwdFileKey(s); err == nil {
+			t.Errorf("expected error for key %q", s)
+		}
+	}
+}
+
+func TestEncryptedPwdFile(t *testing.T) {
+	key := make([]byte, 32)
+	for i := range key {
+		key[i] = byte(i)
+	}
+
+	plaintext, err := os.ReadFile("../contrib/sample-pwd-file.json")
+	if err != nil {
+		t.Fatalf("ReadFile() err: %s", err)
+	}
+	encrypted, err := EncryptPwdFile(plaintext, key)
+	if err != nil {
+		t.Fatalf("EncryptPwdFile() err: %s", err)
+	}
+	if strings.Contains(string(encrypted), "redis-password") {
+		t.Fatalf("encrypted password file contains a password: %s", encrypted)
+	}
+
+	pwdFile := filepath.Join(t.TempDir(), "pwd.json.enc")
+	if err := os.WriteFile(pwdFile, encrypted, 0o600); err != nil {
+		t.Fatalf("WriteFile() err: %s", err)
+	}
+
+	entries, err := LoadPwdFileEntries(pwdFile, key)
+	if err != nil {
+		t.Fatalf("LoadPwdFileEntries() err: %s", err)
+	}
+	if entries["redis://localhost:16380"].Password != "redis-password" {
+		t.Errorf("unexpected entries: %#v", entries)
+	}
+
+	if _, err := LoadPwdFileEntries(pwdFile, nil); !errors.Is(err, errPwdFileKeyMissing) {
+		t.Errorf("expected errPwdFileKeyMissing, got: %v", err)
+	}
+
+	wrongKey := make([]byte, 32)
+	if _, err := LoadPwdFileEntries(pwdFile, wrongKey); err == nil {
+		t.Errorf("expected error for the wrong key")
+	}
+
+	// plain password files can be loaded with a key
+	if _, err := LoadPwdFileEntries("../contrib/sample-pwd-file.json", key); err != nil {
+		t.Errorf("LoadPwdFileEntries() of a plain file err: %s", err)
+	}
+}
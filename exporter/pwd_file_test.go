@@ -1,11 +1,13 @@
 package exporter
 
 import (
+	"crypto/tls"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -227,3 +229,114 @@ func TestHTTPScrapeWithUsername(t *testing.T) {
 		})
 	}
 }
+
+func TestPasswordMapUnixSocket(t *testing.T) {
+	passwordMap := map[string]string{
+		"unix:///run/redis/redis.sock":          "socket-password",
+		"/run/redis/plain.sock":                 "plain-password",
+		"unix://exporter@/run/redis/redis.sock": "exporter-password",
+	}
+
+	for _, tst := range []struct {
+		name string
+		addr string
+		user string
+		want string
+	}{
+		{name: "unix-scheme", addr: "unix:///run/redis/redis.sock", want: "socket-password"},
+		{name: "plain-path-key", addr: "unix:///run/redis/plain.sock", want: "plain-password"},
+		{name: "plain-path-addr", addr: "/run/redis/plain.sock", want: "plain-password"},
+		{name: "acl-user", addr: "unix:///run/redis/redis.sock", user: "exporter", want: "exporter-password"},
+		{name: "missing", addr: "unix:///run/redis/other.sock", want: ""},
+	} {
+		t.Run(tst.name, func(t *testing.T) {
+			e, _ := NewRedisExporter(tst.addr, Options{Namespace: "test", User: tst.user, PasswordMap: passwordMap})
+			pwd, _ := e.lookupPasswordInPasswordMap(e.redisAddr)
+			if pwd != tst.want {
+				t.Errorf("addr: %s  user: %s  - got password %q, want %q", tst.addr, tst.user, pwd, tst.want)
+			}
+		})
+	}
+}
+
+func TestLoadPwdFileEntries(t *testing.T) {
+	entries, err := LoadPwdFileEntries("../contrib/sample-pwd-file.json", nil)
+	if err != nil {
+		t.Fatalf("LoadPwdFileEntries() err: %s", err)
+	}
+	if got := entries["redis://localhost:16380"]; got.Password != "redis-password" || got.TLS != nil {
+		t.Errorf("unexpected plain entry: %#v", got)
+	}
+	got := entries["redis://cache.example.com:6380"]
+	if got.Username != "exporter" || got.Password != "exporter-password" || got.TLS == nil || !*got.TLS || got.ServerName != "cache.internal" {
+		t.Errorf("unexpected structured entry: %#v", got)
+	}
+
+	passwordMap, err := LoadPwdFile("../contrib/sample-pwd-file.json")
+	if err != nil {
+		t.Fatalf("LoadPwdFile() err: %s", err)
+	}
+	if passwordMap["redis://cache.example.com:6380"] != "exporter-password" {
+		t.Errorf("expected the password of the structured entry in the password map, got: %#v", passwordMap)
+	}
+
+	for name, content := range map[string]string{
+		"unknown-field":   `{"redis://localhost:6379": {"pasword": "typo"}}`,
+		"invalid-type":    `{"redis://localhost:6379": 42}`,
+		"missing-ca-file": `{"redis://localhost:6379": {"ca_file": "non-existent.crt"}}`,
+	} {
+		pwdFile := filepath.Join(t.TempDir(), "pwd.json")
+		if err := os.WriteFile(pwdFile, []byte(content), 0o600); err != nil {
+			t.Fatalf("WriteFile() err: %s", err)
+		}
+		if _, err := LoadPwdFileEntries(pwdFile, nil); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestLookupPasswordEntry(t *testing.T) {
+	useTLS := true
+	entries := map[string]PasswordEntry{
+		"redis://cache.example.com:6380":        {Username: "exporter", Password: "pwd", TLS: &useTLS, ServerName: "cache.internal"},
+		"redis://exporter@other.example.com:63": {Password: "other-pwd"},
+	}
+
+	e, _ := NewRedisExporter("redis://cache.example.com:6380", Options{Namespace: "test", PasswordEntries: entries})
+	entry, ok := e.lookupPasswordEntry(e.redisAddr)
+	if !ok || entry.Password != "pwd" {
+		t.Fatalf("expected the entry of %s, got: %#v", e.redisAddr, entry)
+	}
+
+	tlsConfig := &tls.Config{}
+	if err := entry.applyTLS(tlsConfig); err != nil || tlsConfig.ServerName != "cache.internal" {
+		t.Errorf("expected server name cache.internal, got: %q err: %v", tlsConfig.ServerName, err)
+	}
+
+	e, _ = NewRedisExporter("redis://other.example.com:63", Options{Namespace: "test", User: "exporter", PasswordEntries: entries})
+	if entry, ok := e.lookupPasswordEntry(e.redisAddr); !ok || entry.Password != "other-pwd" {
+		t.Errorf("expected the entry of the user, got: %#v", entry)
+	}
+
+	e, _ = NewRedisExporter("redis://missing.example.com:6379", Options{Namespace: "test", PasswordEntries: entries})
+	if _, ok := e.lookupPasswordEntry(e.redisAddr); ok {
+		t.Errorf("expected no entry for a missing target")
+	}
+}
+
+func TestWithTLSScheme(t *testing.T) {
+	for _, tst := range []struct {
+		uri    string
+		useTLS bool
+		want   string
+	}{
+		{uri: "redis://localhost:6379", useTLS: true, want: "rediss://localhost:6379"},
+		{uri: "rediss://localhost:6379", useTLS: true, want: "rediss://localhost:6379"},
+		{uri: "rediss://localhost:6379", useTLS: false, want: "redis://localhost:6379"},
+		{uri: "unix:///tmp/redis.sock", useTLS: true, want: "unix:///tmp/redis.sock"},
+	} {
+		if got := withTLSScheme(tst.uri, tst.useTLS); got != tst.want {
+			t.Errorf("withTLSScheme(%s, %t) = %s, want: %s", tst.uri, tst.useTLS, got, tst.want)
+		}
+	}
+}
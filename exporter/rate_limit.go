@@ -0,0 +1,142 @@
+package exporter
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// tokenBucket allows rate events per second with bursts of up to burst events
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int, now time.Time) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: now}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	if now.Before(b.last) {
+		return
+	}
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
+
+// wait returns how long it takes until the next token is available
+func (b *tokenBucket) wait() time.Duration {
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// scrapeRateLimiter limits the requests to /scrape globally and per client IP
+type scrapeRateLimiter struct {
+	sync.Mutex
+
+	global *tokenBucket
+
+	clientRate  float64
+	clientBurst int
+	clients     map[string]*tokenBucket
+	lastPrune   time.Time
+}
+
+func newScrapeRateLimiter(rate float64, burst int, clientRate float64, clientBurst int) *scrapeRateLimiter {
+	now := time.Now()
+	l := &scrapeRateLimiter{
+		clientRate:  clientRate,
+		clientBurst: clientBurst,
+		clients:     map[string]*tokenBucket{},
+		lastPrune:   now,
+	}
+	if rate > 0 {
+		l.global = newTokenBucket(rate, burst, now)
+	}
+	return l
+}
+
+// allow takes a token from the global and the client's bucket, if either one is
+// empty the request is rejected and the scope ("global" or "client") is returned
+// along with the time until the request would be allowed
+func (l *scrapeRateLimiter) allow(clientIP string, now time.Time) (bool, string, time.Duration) {
+	l.Lock()
+	defer l.Unlock()
+
+	var client *tokenBucket
+	if l.clientRate > 0 {
+		l.pruneClients(now)
+
+		client = l.clients[clientIP]
+		if client == nil {
+			client = newTokenBucket(l.clientRate, l.clientBurst, now)
+			l.clients[clientIP] = client
+		}
+		client.refill(now)
+		if d := client.wait(); d > 0 {
+			return false, "client", d
+		}
+	}
+
+	if l.global != nil {
+		l.global.refill(now)
+		if d := l.global.wait(); d > 0 {
+			return false, "global", d
+		}
+		l.global.tokens--
+	}
+
+	if client != nil {
+		client.tokens--
+	}
+	return true, "", 0
+}
+
+// pruneClients drops the buckets of clients that have been idle long enough to be full again
+func (l *scrapeRateLimiter) pruneClients(now time.Time) {
+	if now.Sub(l.lastPrune) < time.Minute {
+		return
+	}
+	l.lastPrune = now
+
+	for ip, b := range l.clients {
+		b.refill(now)
+		if b.tokens >= b.burst {
+			delete(l.clients, ip)
+		}
+	}
+}
+
+// rateLimitScrape returns false and writes a 429 response if the request exceeds the /scrape rate limits
+func (e *Exporter) rateLimitScrape(w http.ResponseWriter, r *http.Request) bool {
+	if e.scrapeRateLimiter == nil {
+		return true
+	}
+
+	clientIP, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		clientIP = r.RemoteAddr
+	}
+
+	ok, scope, retryAfter := e.scrapeRateLimiter.allow(clientIP, time.Now())
+	if ok {
+		return true
+	}
+
+	e.targetScrapeRequestsRateLimited.WithLabelValues(scope).Inc()
+	e.logger().Debugf("rate limited /scrape request from %s, scope: %s", clientIP, scope)
+
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	http.Error(w, "too many scrape requests, rate limit exceeded ("+scope+")", http.StatusTooManyRequests)
+	return false
+}
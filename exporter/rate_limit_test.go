@@ -0,0 +1,57 @@
+package exporter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestScrapeRateLimiter(t *testing.T) {
+	now := time.Now()
+	l := newScrapeRateLimiter(10, 3, 1, 2)
+
+	for i, tst := range []struct {
+		ip     string
+		offset time.Duration
+		ok     bool
+		scope  string
+	}{
+		{ip: "10.0.0.1", ok: true},
+		{ip: "10.0.0.1", ok: true},
+		// per client burst of 2 is used up
+		{ip: "10.0.0.1", ok: false, scope: "client"},
+		{ip: "10.0.0.2", ok: true},
+		// global burst of 3 is used up
+		{ip: "10.0.0.3", ok: false, scope: "global"},
+		// 150ms refill a global token, the client's bucket needs a second
+		{ip: "10.0.0.3", offset: 150 * time.Millisecond, ok: true},
+		{ip: "10.0.0.1", offset: 200 * time.Millisecond, ok: false, scope: "client"},
+		{ip: "10.0.0.1", offset: 1100 * time.Millisecond, ok: true},
+	} {
+		ok, scope, wait := l.allow(tst.ip, now.Add(tst.offset))
+		if ok != tst.ok || scope != tst.scope {
+			t.Errorf("%d: expected ok: %t scope: %q, got ok: %t scope: %q", i, tst.ok, tst.scope, ok, scope)
+		}
+		if !ok && wait <= 0 {
+			t.Errorf("%d: expected a positive wait duration for rejected request", i)
+		}
+	}
+}
+
+func TestScrapeRateLimitHandler(t *testing.T) {
+	e, _ := NewRedisExporter("", Options{Namespace: "test", ScrapeRateLimitPerClient: 0.1, ScrapeRateBurstPerClient: 1})
+
+	for i, want := range []int{http.StatusBadRequest, http.StatusTooManyRequests} {
+		req := httptest.NewRequest(http.MethodGet, "/scrape", nil)
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, req)
+
+		if w.Code != want {
+			t.Errorf("%d: expected status %d, got: %d", i, want, w.Code)
+		}
+		if want == http.StatusTooManyRequests && w.Header().Get("Retry-After") != "10" {
+			t.Errorf("expected Retry-After: 10, got: %q", w.Header().Get("Retry-After"))
+		}
+	}
+}
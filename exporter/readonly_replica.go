@@ -0,0 +1,40 @@
+package exporter
+
+import (
+	"strings"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// readonlyUnsafeCollectors are the collectors skipped with AssumeReadonlyReplica because
+// they write (WAIT probe) or run Lua scripts (key groups, lua), probes aren't skipped as
+// they're restricted to the read-only commands of probeCommands
+var readonlyUnsafeCollectors = map[string]bool{
+	"wait_probe": true,
+	"key_groups": true,
+	"lua":        true,
+}
+
+// skipReadonlyUnsafe returns true and exports that the collector was skipped if it
+// mustn't run against a read-only endpoint
+func (e *Exporter) skipReadonlyUnsafe(ch chan<- prometheus.Metric, collector string) bool {
+	if !e.options.AssumeReadonlyReplica || !readonlyUnsafeCollectors[collector] {
+		return false
+	}
+	e.logger().Debugf("skipping collector %s on read-only replica", collector)
+	e.registerConstMetricGauge(ch, "exporter_readonly_skipped_collector", 1, collector)
+	return true
+}
+
+func (e *Exporter) keyGroupsConfigured() bool {
+	return len(e.options.KeyGroups) > 0 || strings.TrimSpace(e.options.CheckKeyGroups) != ""
+}
+
+// enableReadonly sends READONLY so a cluster replica serves the reads of the key
+// collectors instead of redirecting them to its master
+func (e *Exporter) enableReadonly(c redis.Conn) {
+	if _, err := doRedisCmd(c, "READONLY"); err != nil {
+		e.logger().Errorf("Redis READONLY err: %s", err)
+	}
+}
@@ -0,0 +1,52 @@
+package exporter
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestSkipReadonlyUnsafe(t *testing.T) {
+	chM := make(chan prometheus.Metric, 10)
+
+	e, _ := NewRedisExporter("", Options{Namespace: "test"})
+	if e.skipReadonlyUnsafe(chM, "lua") {
+		t.Errorf("collectors shouldn't be skipped without AssumeReadonlyReplica")
+	}
+
+	e, _ = NewRedisExporter("", Options{Namespace: "test", AssumeReadonlyReplica: true})
+	for collector, want := range map[string]bool{
+		"wait_probe": true,
+		"key_groups": true,
+		"probes":     false,
+		"lua":        true,
+		"keys":       false,
+		"streams":    false,
+		"slowlog":    false,
+	} {
+		if got := e.skipReadonlyUnsafe(chM, collector); got != want {
+			t.Errorf("skipReadonlyUnsafe(%s) = %t, want: %t", collector, got, want)
+		}
+	}
+	close(chM)
+
+	skipped := map[string]bool{}
+	for m := range chM {
+		d := &dto.Metric{}
+		m.Write(d)
+		skipped[d.GetLabel()[0].GetValue()] = true
+	}
+	if len(skipped) != 3 || !skipped["lua"] || !skipped["wait_probe"] {
+		t.Errorf("unexpected skipped collectors: %v", skipped)
+	}
+}
+
+func TestEnableReadonly(t *testing.T) {
+	e, _ := NewRedisExporter("", Options{Namespace: "test", AssumeReadonlyReplica: true})
+	c := &pipelineConn{fakeRedisConn: fakeRedisConn{replies: map[string]interface{}{"READONLY": "OK"}}}
+	e.enableReadonly(c)
+	if len(c.commands) != 1 || c.commands[0] != "READONLY" {
+		t.Errorf("expected READONLY to be sent, got: %v", c.commands)
+	}
+}
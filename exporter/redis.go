@@ -1,10 +1,10 @@
 package exporter
 
 import (
+	"errors"
 	"fmt"
 	"net/url"
 	"strings"
-	"time"
 
 	"github.com/gomodule/redigo/redis"
 	"github.com/mna/redisc"
@@ -12,10 +12,17 @@ import (
 )
 
 func (e *Exporter) configureOptions(uri string) ([]redis.DialOption, error) {
+	entry, hasEntry := e.lookupPasswordEntry(uri)
+
 	tlsConfig, err := e.CreateClientTLSConfig()
 	if err != nil {
 		return nil, err
 	}
+	if hasEntry {
+		if err := entry.applyTLS(tlsConfig); err != nil {
+			return nil, err
+		}
+	}
 
 	options := []redis.DialOption{
 		redis.DialConnectTimeout(e.options.ConnectionTimeouts),
@@ -37,13 +44,31 @@ func (e *Exporter) configureOptions(uri string) ([]redis.DialOption, error) {
 		options = append(options, redis.DialPassword(pwd))
 	}
 
+	if hasEntry {
+		if entry.Username != "" {
+			options = append(options, redis.DialUsername(entry.Username))
+		}
+		if entry.Password != "" {
+			options = append(options, redis.DialPassword(entry.Password))
+		}
+		if entry.TLS != nil {
+			options = append(options, redis.DialUseTLS(*entry.TLS))
+		}
+	}
+
+	if e.dialTimer != nil {
+		options = append(options, e.dialTimer.dialOptions(e.options.ConnectionTimeouts, tlsConfig)...)
+	}
+
 	return options, nil
 }
 
-func (e *Exporter) lookupPasswordInPasswordMap(uri string) (string, bool) {
+// passwordMapKeys returns the keys of the password map (and the password file) that can
+// hold the password of uri, in the order they're looked up
+func (e *Exporter) passwordMapKeys(uri string) []string {
 	u, err := url.Parse(uri)
 	if err != nil {
-		return "", false
+		return nil
 	}
 
 	if e.options.User != "" {
@@ -54,13 +79,63 @@ func (e *Exporter) lookupPasswordInPasswordMap(uri string) (string, bool) {
 	// strip solo ":" if present in uri that has a username (and no pwd)
 	uri = strings.Replace(uri, fmt.Sprintf(":@%s", u.Host), fmt.Sprintf("@%s", u.Host), 1)
 
-	log.Debugf("looking up in pwd map, uri: %s", uri)
-	if pwd, ok := e.options.PasswordMap[uri]; ok && pwd != "" {
-		return pwd, true
+	e.logger().Debugf("looking up in pwd map, uri: %s", uri)
+	keys := []string{uri}
+
+	// unix sockets can also be listed by their plain path, e.g. "/run/redis/redis.sock"
+	if u.Scheme == "unix" && e.options.User == "" {
+		keys = append(keys, u.Path)
+	}
+	return keys
+}
+
+func (e *Exporter) lookupPasswordInPasswordMap(uri string) (string, bool) {
+	for _, k := range e.passwordMapKeys(uri) {
+		if pwd, ok := e.options.PasswordMap[k]; ok && pwd != "" {
+			return pwd, true
+		}
 	}
 	return "", false
 }
 
+// lookupPasswordEntry returns the connection settings of uri from the password file
+func (e *Exporter) lookupPasswordEntry(uri string) (PasswordEntry, bool) {
+	if len(e.options.PasswordEntries) == 0 {
+		return PasswordEntry{}, false
+	}
+	for _, k := range e.passwordMapKeys(uri) {
+		if entry, ok := e.options.PasswordEntries[k]; ok {
+			return entry, true
+		}
+	}
+	return PasswordEntry{}, false
+}
+
+// withTLSScheme switches uri to the rediss:// scheme if useTLS or to redis:// otherwise
+func withTLSScheme(uri string, useTLS bool) string {
+	switch {
+	case useTLS && strings.HasPrefix(uri, "redis://"):
+		return "rediss://" + strings.TrimPrefix(uri, "redis://")
+	case !useTLS && strings.HasPrefix(uri, "rediss://"):
+		return "redis://" + strings.TrimPrefix(uri, "rediss://")
+	}
+	return uri
+}
+
+// isUnixSocketAddr returns true if addr points to a unix socket, either via the
+// "unix://" scheme or as a plain absolute path
+func isUnixSocketAddr(addr string) bool {
+	return strings.HasPrefix(addr, "unix://") || strings.HasPrefix(addr, "/")
+}
+
+// unixSocketPath returns the file system path of a unix socket address
+func unixSocketPath(addr string) string {
+	if u, err := url.Parse(addr); err == nil && u.Scheme == "unix" {
+		return u.Path
+	}
+	return strings.TrimPrefix(addr, "unix://")
+}
+
 func (e *Exporter) connectToRedis() (redis.Conn, error) {
 	uri := e.redisAddr
 	if !strings.Contains(uri, "://") {
@@ -72,22 +147,47 @@ func (e *Exporter) connectToRedis() (redis.Conn, error) {
 		return nil, err
 	}
 
-	log.Debugf("Trying DialURL(): %s", uri)
-	c, err := redis.DialURL(uri, options...)
-	if err != nil {
-		log.Debugf("DialURL() failed, err: %s", err)
+	if isUnixSocketAddr(e.redisAddr) {
+		path := unixSocketPath(e.redisAddr)
+		e.logger().Debugf("Trying: Dial(): unix %s", path)
+		c, err := redis.Dial("unix", path, options...)
+		if err == nil && e.dialTimer != nil {
+			e.dialTimer.dialed()
+		}
+		return c, classifyError(err, "")
+	}
+
+	// DialURL() enables TLS according to the scheme
+	dialURI := uri
+	if entry, ok := e.lookupPasswordEntry(uri); ok && entry.TLS != nil {
+		dialURI = withTLSScheme(uri, *entry.TLS)
+	}
+
+	e.logger().Debugf("Trying DialURL(): %s", dialURI)
+	c, err := redis.DialURL(dialURI, options...)
+	err = classifyError(err, "")
+	// the server rejected the credentials, dialing it again wouldn't help and would hide the error
+	if err != nil && errorKind(err) != errorKindAuth {
+		e.logger().Debugf("DialURL() failed, err: %s", err)
 		if frags := strings.Split(e.redisAddr, "://"); len(frags) == 2 {
-			log.Debugf("Trying: Dial(): %s %s", frags[0], frags[1])
+			e.logger().Debugf("Trying: Dial(): %s %s", frags[0], frags[1])
 			c, err = redis.Dial(frags[0], frags[1], options...)
 		} else {
-			log.Debugf("Trying: Dial(): tcp %s", e.redisAddr)
+			e.logger().Debugf("Trying: Dial(): tcp %s", e.redisAddr)
 			c, err = redis.Dial("tcp", e.redisAddr, options...)
 		}
 	}
-	return c, err
+	if err == nil && e.dialTimer != nil {
+		e.dialTimer.dialed()
+	}
+	return c, classifyError(err, "")
 }
 
 func (e *Exporter) connectToRedisCluster() (redis.Conn, error) {
+	if isUnixSocketAddr(e.redisAddr) {
+		return nil, fmt.Errorf("cluster connections are not supported for unix socket address %s", e.redisAddr)
+	}
+
 	uri := e.redisAddr
 	if !strings.Contains(uri, "://") {
 		uri = "redis://" + uri
@@ -112,39 +212,103 @@ func (e *Exporter) connectToRedisCluster() (redis.Conn, error) {
 		}
 	}
 
-	log.Debugf("Creating cluster object")
+	e.logger().Debugf("Creating cluster object")
 	cluster := redisc.Cluster{
 		StartupNodes: []string{uri},
 		DialOptions:  options,
+		// the pools don't keep idle connections, they set up the node connections like the
+		// scrape connection, including those dialed to follow redirects
+		CreatePool: func(addr string, options ...redis.DialOption) (*redis.Pool, error) {
+			return &redis.Pool{Dial: func() (redis.Conn, error) { return e.dialClusterNode(addr, options...) }}, nil
+		},
 	}
-	log.Debugf("Running refresh on cluster object")
+	e.logger().Debugf("Running refresh on cluster object")
 	if err := cluster.Refresh(); err != nil {
-		log.Errorf("Cluster refresh failed: %v", err)
-		return nil, fmt.Errorf("cluster refresh failed: %w", err)
+		e.logger().Errorf("Cluster refresh failed: %v", err)
+		return nil, classifyError(fmt.Errorf("cluster refresh failed: %w", err), "")
 	}
 
-	log.Debugf("Creating redis connection object")
-	conn, err := cluster.Dial()
-	if err != nil {
-		log.Errorf("Dial failed: %v", err)
-		return nil, fmt.Errorf("dial failed: %w", err)
+	e.logger().Debugf("Creating redis connection object")
+	conn := cluster.Get()
+	if err := conn.Err(); err != nil {
+		e.logger().Errorf("Dial failed: %v", err)
+		return nil, classifyError(fmt.Errorf("dial failed: %w", err), "")
+	}
+
+	if e.options.AssumeReadonlyReplica {
+		// key operations are routed to replicas which get READONLY sent
+		if err := redisc.ReadOnlyConn(conn); err != nil {
+			e.logger().Errorf("ReadOnlyConn failed: %v", err)
+		}
 	}
 
-	c, err := redisc.RetryConn(conn, 10, 100*time.Millisecond)
+	return e.newRedirectConn(&cluster, conn), nil
+}
+
+// dialClusterNode dials the node at addr and sets up the connection like the scrape connection
+func (e *Exporter) dialClusterNode(addr string, options ...redis.DialOption) (redis.Conn, error) {
+	c, err := redis.Dial("tcp", addr, options...)
 	if err != nil {
-		log.Errorf("RetryConn failed: %v", err)
-		return nil, fmt.Errorf("retryConn failed: %w", err)
+		return nil, err
 	}
+	if e.options.SetClientName {
+		e.setClientName(c)
+	}
+	e.clientNoTouch(c)
+	return c, nil
+}
+
+// setClientName sets the name and the library of the client of c
+func (e *Exporter) setClientName(c redis.Conn) {
+	if _, err := doRedisCmd(c, "CLIENT", "SETNAME", "redis_exporter"); err != nil {
+		e.logger().Errorf("Couldn't set client name, err: %s", err)
+	}
+	e.setClientInfo(c)
+}
 
-	return c, err
+// redactedAddr returns addr with the password (if any) redacted
+func redactedAddr(addr string) string {
+	u, err := url.Parse(addr)
+	if err != nil {
+		log.Debugf("url.Parse( %s ) err: %s", addr, err)
+		return "<redacted>"
+	}
+	return u.Redacted()
 }
 
+// doRedisCmd runs cmd on c and classifies its error, the commands of the scrape connection
+// are logged by its loggingConn
 func doRedisCmd(c redis.Conn, cmd string, args ...interface{}) (interface{}, error) {
-	log.Debugf("c.Do() - running command: %s args: [%v]", cmd, args)
 	res, err := c.Do(cmd, args...)
-	if err != nil {
-		log.Debugf("c.Do() - err: %s", err)
+	return res, classifyError(err, cmd, args...)
+}
+
+// clientLibVersion returns the version of the exporter as lib-ver of CLIENT SETINFO,
+// which doesn't allow spaces, newlines and other special characters
+func clientLibVersion(version string) string {
+	v := strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' {
+			return '-'
+		}
+		return r
+	}, version)
+	if v == "" {
+		return "unknown"
+	}
+	return v
+}
+
+// setClientInfo sets the lib-name and lib-ver shown by CLIENT LIST and CLIENT INFO to
+// the exporter and its version, servers before Redis 7.2 don't have CLIENT SETINFO
+func (e *Exporter) setClientInfo(c redis.Conn) {
+	for _, attr := range [][2]string{{"LIB-NAME", "redis_exporter"}, {"LIB-VER", clientLibVersion(e.buildInfo.Version)}} {
+		_, err := doRedisCmd(c, "CLIENT", "SETINFO", attr[0], attr[1])
+		var unsupportedErr *UnsupportedCommandError
+		if errors.As(err, &unsupportedErr) {
+			e.logger().Debugf("CLIENT SETINFO isn't supported, err: %s", err)
+			return
+		} else if err != nil {
+			e.logger().Errorf("Couldn't set client %s, err: %s", strings.ToLower(attr[0]), err)
+		}
 	}
-	log.Debugf("c.Do() - done")
-	return res, err
 }
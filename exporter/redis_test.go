@@ -1,11 +1,18 @@
 package exporter
 
 import (
+	"net"
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/oliver006/redis_exporter/exporter/exportertest"
 )
 
 func TestHostVariations(t *testing.T) {
@@ -152,3 +159,126 @@ func TestConnectToClusterUsingPasswordFile(t *testing.T) {
 		})
 	}
 }
+
+func TestConnectToRedisUnixSocket(t *testing.T) {
+	dir := t.TempDir()
+	sockPath := filepath.Join(dir, "redis.sock")
+
+	l, err := net.Listen("unix", sockPath)
+	if err != nil {
+		t.Fatalf("net.Listen() err: %s", err)
+	}
+	defer l.Close()
+
+	for _, tst := range []struct {
+		name    string
+		addr    string
+		noPerm  bool
+		wantErr string
+	}{
+		{name: "unix-scheme", addr: "unix://" + sockPath},
+		{name: "plain-path", addr: sockPath},
+		{name: "missing-socket", addr: "unix://" + filepath.Join(dir, "missing.sock"), wantErr: "no such file or directory"},
+		{name: "no-permission", addr: "unix://" + sockPath, noPerm: true, wantErr: "permission denied"},
+	} {
+		t.Run(tst.name, func(t *testing.T) {
+			if tst.noPerm {
+				if os.Geteuid() == 0 {
+					t.Skipf("running as root, socket permissions are not enforced")
+				}
+				if err := os.Chmod(sockPath, 0o000); err != nil {
+					t.Fatalf("os.Chmod() err: %s", err)
+				}
+				defer os.Chmod(sockPath, 0o777)
+			}
+
+			e, _ := NewRedisExporter(tst.addr, Options{Namespace: "test"})
+			c, err := e.connectToRedis()
+			if tst.wantErr == "" {
+				if err != nil {
+					t.Fatalf("connectToRedis() err: %s", err)
+				}
+				c.Close()
+				return
+			}
+
+			if err == nil {
+				c.Close()
+				t.Fatalf("expected error containing %q, got nil", tst.wantErr)
+			}
+			if !strings.Contains(err.Error(), tst.wantErr) {
+				t.Errorf("expected error containing %q, got: %s", tst.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestUnixSocketClusterFallback(t *testing.T) {
+	e, _ := NewRedisExporter("unix:///tmp/doesnt.matter", Options{Namespace: "test", IsCluster: true})
+
+	if _, err := e.connectToRedisCluster(); err == nil || !strings.Contains(err.Error(), "not supported for unix socket") {
+		t.Errorf("expected unix socket error from connectToRedisCluster(), got: %v", err)
+	}
+
+	var defaultConn redis.Conn
+	c, err := e.getKeyOperationConnection(defaultConn)
+	if err != nil {
+		t.Fatalf("getKeyOperationConnection() err: %s", err)
+	}
+	if c != defaultConn {
+		t.Errorf("expected the node connection to be used for key operations")
+	}
+}
+
+func TestClientLibVersion(t *testing.T) {
+	for in, want := range map[string]string{
+		"v1.74.0":                    "v1.74.0",
+		"":                           "unknown",
+		"<<< filled in by build >>>": "<<<-filled-in-by-build->>>",
+		"1.0\n":                      "1.0-",
+	} {
+		if got := clientLibVersion(in); got != want {
+			t.Errorf("clientLibVersion(%q) = %q, want: %q", in, got, want)
+		}
+	}
+}
+
+func TestSetClientInfo(t *testing.T) {
+	s := exportertest.NewServer(t)
+	e, _ := NewRedisExporter(s.Addr(), Options{Namespace: "test", SetClientName: true, BuildInfo: BuildInfo{Version: "v1.2.3"}})
+
+	chM := make(chan prometheus.Metric)
+	go func() {
+		e.Collect(chM)
+		close(chM)
+	}()
+	for range chM {
+	}
+
+	cmds := strings.Join(s.Commands(), "\n")
+	for _, want := range []string{"CLIENT SETNAME redis_exporter", "CLIENT SETINFO LIB-NAME redis_exporter", "CLIENT SETINFO LIB-VER v1.2.3"} {
+		if !strings.Contains(cmds, want) {
+			t.Errorf("expected %q, got: %s", want, cmds)
+		}
+	}
+
+	// before Redis 7.2 the first CLIENT SETINFO fails and the second one isn't sent
+	old := exportertest.NewServer(t)
+	old.Set("CLIENT SETINFO", exportertest.Error("ERR unknown subcommand 'SETINFO'. Try CLIENT HELP."))
+	c, err := redis.DialURL(old.Addr())
+	if err != nil {
+		t.Fatalf("couldn't connect, err: %s", err)
+	}
+	defer c.Close()
+	e.setClientInfo(c)
+
+	setInfo := 0
+	for _, cmd := range old.Commands() {
+		if strings.HasPrefix(cmd, "CLIENT SETINFO") {
+			setInfo++
+		}
+	}
+	if setInfo != 1 {
+		t.Errorf("expected a single CLIENT SETINFO, got: %v", old.Commands())
+	}
+}
@@ -0,0 +1,29 @@
+package exporter
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// extractReplBacklogCoverage estimates for how many seconds of writes the replication
+// backlog holds the history, a replica disconnected for less can resync partially,
+// the write throughput is the rate of master_repl_offset since the previous scrape
+func (e *Exporter) extractReplBacklogCoverage(ch chan<- prometheus.Metric, fields *infoFieldTable) {
+	offset, ok := fields.float("master_repl_offset")
+	if !ok {
+		return
+	}
+	rate, ok := e.replOffsets.rate(e.redisAddr, offset, time.Now())
+	if !ok || rate <= 0 {
+		// without writes the backlog covers any disconnect
+		return
+	}
+
+	if active, ok := fields.float("repl_backlog_active"); !ok || active != 1 {
+		return
+	}
+	if histLen, ok := fields.float("repl_backlog_histlen"); ok {
+		e.registerConstMetricGauge(ch, "repl_backlog_coverage_seconds", histLen/rate)
+	}
+}
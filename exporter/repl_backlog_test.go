@@ -0,0 +1,47 @@
+package exporter
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestReplBacklogCoverage(t *testing.T) {
+	e, _ := NewRedisExporter("redis://localhost:6379", Options{Namespace: "test"})
+
+	scrape := func(offset string, active string) (float64, bool) {
+		var fields infoFieldTable
+		fields.set("master_repl_offset", offset)
+		fields.set("repl_backlog_active", active)
+		fields.set("repl_backlog_histlen", "1000000")
+
+		chM := make(chan prometheus.Metric, 10)
+		e.extractReplBacklogCoverage(chM, &fields)
+		close(chM)
+		for m := range chM {
+			if strings.Contains(m.Desc().String(), `"test_repl_backlog_coverage_seconds"`) {
+				d := &dto.Metric{}
+				m.Write(d)
+				return d.GetGauge().GetValue(), true
+			}
+		}
+		return 0, false
+	}
+
+	if _, ok := scrape("1000", "1"); ok {
+		t.Errorf("expected no coverage for the first scrape")
+	}
+	if _, ok := scrape("1000", "1"); ok {
+		t.Errorf("expected no coverage without writes")
+	}
+	time.Sleep(10 * time.Millisecond)
+	if val, ok := scrape("100000000", "1"); !ok || val <= 0 {
+		t.Errorf("expected a positive coverage, got: %f (found: %t)", val, ok)
+	}
+	if _, ok := scrape("200000000", "0"); ok {
+		t.Errorf("expected no coverage for an inactive backlog")
+	}
+}
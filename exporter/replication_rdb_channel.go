@@ -0,0 +1,26 @@
+package exporter
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// rdbChannelConfigKeys are the config keys that enable replicating the RDB snapshot of a full
+// sync over a dedicated connection, repl-rdb-channel in Redis 8.0, dual-channel-replication-enabled in Valkey 8.0
+var rdbChannelConfigKeys = []string{"repl-rdb-channel", "dual-channel-replication-enabled"}
+
+// extractRDBChannelMetrics exports whether the rdb channel replication is enabled, the
+// buffers it uses while streaming are exported from INFO replication
+func (e *Exporter) extractRDBChannelMetrics(ch chan<- prometheus.Metric, configValues map[string]string) {
+	for _, key := range rdbChannelConfigKeys {
+		val, ok := configValues[key]
+		if !ok {
+			continue
+		}
+		enabled := 0.0
+		if val == "yes" {
+			enabled = 1
+		}
+		e.registerConstMetricGauge(ch, "config_replication_rdb_channel_enabled", enabled)
+		return
+	}
+}
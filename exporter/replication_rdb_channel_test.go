@@ -0,0 +1,84 @@
+package exporter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestRDBChannelReplicationMetrics(t *testing.T) {
+	infoStr := "# Replication\r\nrole:master\r\nconnected_slaves:1\r\n" +
+		"replicas_waiting_psync:1\r\nreplicas_repl_buffer_size:1024\r\nreplicas_repl_buffer_peak:4096\r\n" +
+		"replica_full_sync_buffer_size:512\r\nreplica_full_sync_buffer_peak:2048\r\n" +
+		"master_current_sync_attempts:2\r\nmaster_total_sync_attempts:7\r\n"
+
+	e, _ := NewRedisExporter("redis://localhost:6379", Options{Namespace: "test"})
+	chM := make(chan prometheus.Metric)
+	go func() {
+		e.extractInfoMetrics(chM, infoStr, 0)
+		e.extractConfigMetrics(chM, []interface{}{[]byte("dual-channel-replication-enabled"), []byte("yes")})
+		close(chM)
+	}()
+
+	got := map[string]float64{}
+	for m := range chM {
+		desc := m.Desc().String()
+		name := desc[strings.Index(desc, `"`)+1:]
+		name = name[:strings.Index(name, `"`)]
+		d := &dto.Metric{}
+		m.Write(d)
+		got[name] = d.GetGauge().GetValue() + d.GetCounter().GetValue()
+	}
+
+	for name, want := range map[string]float64{
+		"test_replicas_waiting_psync":                 1,
+		"test_replicas_repl_buffer_size_bytes":        1024,
+		"test_replicas_repl_buffer_peak_bytes":        4096,
+		"test_replica_full_sync_buffer_size_bytes":    512,
+		"test_replica_full_sync_buffer_peak_bytes":    2048,
+		"test_master_current_sync_attempts":           2,
+		"test_master_sync_attempts_total":             7,
+		"test_config_replication_rdb_channel_enabled": 1,
+	} {
+		if val, ok := got[name]; !ok || val != want {
+			t.Errorf("expected %s = %.0f, got: %f (found: %t)", name, want, val, ok)
+		}
+	}
+}
+
+func TestRDBChannelConfigDisabled(t *testing.T) {
+	e, _ := NewRedisExporter("redis://localhost:6379", Options{Namespace: "test"})
+
+	for _, tst := range []struct {
+		config []interface{}
+		want   float64
+		found  bool
+	}{
+		{config: []interface{}{[]byte("repl-rdb-channel"), []byte("no")}, want: 0, found: true},
+		{config: []interface{}{[]byte("repl-rdb-channel"), []byte("yes")}, want: 1, found: true},
+		{config: []interface{}{[]byte("maxmemory"), []byte("0")}},
+	} {
+		chM := make(chan prometheus.Metric)
+		go func() {
+			e.extractConfigMetrics(chM, tst.config)
+			close(chM)
+		}()
+
+		found := false
+		for m := range chM {
+			if strings.Contains(m.Desc().String(), `"test_config_replication_rdb_channel_enabled"`) {
+				d := &dto.Metric{}
+				m.Write(d)
+				found = true
+				if d.GetGauge().GetValue() != tst.want {
+					t.Errorf("expected %.0f for %v, got: %f", tst.want, tst.config, d.GetGauge().GetValue())
+				}
+			}
+		}
+		if found != tst.found {
+			t.Errorf("expected found = %t for %v", tst.found, tst.config)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package exporter
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// responseStat is the size of the last exposition of the metrics of a target
+type responseStat struct {
+	bytes  int64
+	series int
+}
+
+// responseStats keeps the size of the last exposition per target, it's exported by the
+// next collection of the target as the size is only known once the response was written
+type responseStats struct {
+	sync.Mutex
+	stats map[string]responseStat
+}
+
+func newResponseStats() *responseStats {
+	return &responseStats{stats: map[string]responseStat{}}
+}
+
+func (s *responseStats) record(target string, stat responseStat) {
+	s.Lock()
+	defer s.Unlock()
+	s.stats[target] = stat
+}
+
+func (s *responseStats) get(target string) (responseStat, bool) {
+	s.Lock()
+	defer s.Unlock()
+	stat, ok := s.stats[target]
+	return stat, ok
+}
+
+// countingResponseWriter counts the bytes written to the response
+type countingResponseWriter struct {
+	http.ResponseWriter
+	bytes int64
+}
+
+func (w *countingResponseWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// countingGatherer counts the series of the metric families gathered from g
+func countingGatherer(g prometheus.Gatherer, series *int) prometheus.Gatherer {
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		mfs, err := g.Gather()
+		for _, mf := range mfs {
+			*series += len(mf.GetMetric())
+		}
+		return mfs, err
+	})
+}
+
+// registerResponseStats exports the size of the last exposition of the metrics of the target
+func (e *Exporter) registerResponseStats(ch chan<- prometheus.Metric) {
+	if stat, ok := e.responseStats.get(e.redisAddr); ok {
+		e.registerConstMetricGauge(ch, "exporter_response_bytes", float64(stat.bytes))
+		e.registerConstMetricGauge(ch, "exporter_response_series", float64(stat.series))
+	}
+}
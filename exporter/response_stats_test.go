@@ -0,0 +1,43 @@
+package exporter
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestResponseStats(t *testing.T) {
+	e, _ := NewRedisExporter("unix:///tmp/doesnt.exist", Options{Namespace: "test", Registry: prometheus.NewRegistry()})
+
+	scrape := func() string {
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+		return w.Body.String()
+	}
+
+	first := scrape()
+	if strings.Contains(first, "test_exporter_response_bytes") {
+		t.Errorf("didn't expect the response size before the first response")
+	}
+
+	stat, ok := e.responseStats.get(e.redisAddr)
+	if !ok {
+		t.Fatalf("expected the size of the first response to be recorded")
+	}
+	if stat.bytes != int64(len(first)) {
+		t.Errorf("expected %d bytes, got: %d", len(first), stat.bytes)
+	}
+	// every series takes at least a line, histograms and summaries more
+	if lines := strings.Count(first, "\n") - strings.Count(first, "\n#"); stat.series == 0 || stat.series > lines {
+		t.Errorf("expected between 1 and %d series, got: %d", lines, stat.series)
+	}
+
+	second := scrape()
+	for _, want := range []string{"test_exporter_response_bytes", "test_exporter_response_series"} {
+		if !strings.Contains(second, want) {
+			t.Errorf("expected %s in the second response", want)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package exporter
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// roleState is the role of a target seen by the last scrape and its changes since the exporter started
+type roleState struct {
+	role       string
+	changes    int64
+	lastChange time.Time
+}
+
+// roleChanges tracks the role of each target between scrapes to count failovers
+type roleChanges struct {
+	sync.Mutex
+	targets map[string]*roleState
+}
+
+func newRoleChanges() *roleChanges {
+	return &roleChanges{targets: map[string]*roleState{}}
+}
+
+// observe records the role of target and returns its state, the first role
+// of a target isn't a change
+func (r *roleChanges) observe(target string, role string, now time.Time) roleState {
+	r.Lock()
+	defer r.Unlock()
+
+	s, ok := r.targets[target]
+	if !ok {
+		s = &roleState{role: role}
+		r.targets[target] = s
+	}
+	if s.role != role {
+		s.role = role
+		s.changes++
+		s.lastChange = now
+	}
+	return *s
+}
+
+func (e *Exporter) registerRoleChangeMetrics(ch chan<- prometheus.Metric, role string) {
+	if role == "" {
+		return
+	}
+	s := e.roleChanges.observe(e.redisAddr, role, time.Now())
+	e.registerConstMetric(ch, "role_changes_total", float64(s.changes), prometheus.CounterValue)
+	if !s.lastChange.IsZero() {
+		e.registerConstMetricGauge(ch, "role_last_change_timestamp_seconds", float64(s.lastChange.Unix()))
+	}
+}
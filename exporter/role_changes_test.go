@@ -0,0 +1,72 @@
+package exporter
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestRoleChanges(t *testing.T) {
+	r := newRoleChanges()
+	now := time.Now()
+
+	if s := r.observe("a", "master", now); s.changes != 0 || !s.lastChange.IsZero() {
+		t.Errorf("the first role shouldn't be a change, got: %#v", s)
+	}
+	if s := r.observe("a", "master", now.Add(time.Second)); s.changes != 0 {
+		t.Errorf("expected no change, got: %#v", s)
+	}
+	if s := r.observe("a", "slave", now.Add(2*time.Second)); s.changes != 1 || !s.lastChange.Equal(now.Add(2*time.Second)) {
+		t.Errorf("expected one change, got: %#v", s)
+	}
+	if s := r.observe("b", "slave", now); s.changes != 0 {
+		t.Errorf("targets should be tracked separately, got: %#v", s)
+	}
+	if s := r.observe("a", "master", now.Add(3*time.Second)); s.changes != 2 || !s.lastChange.Equal(now.Add(3*time.Second)) {
+		t.Errorf("expected two changes, got: %#v", s)
+	}
+}
+
+func TestRoleChangeMetrics(t *testing.T) {
+	e, _ := NewRedisExporter("redis://localhost:6379", Options{Namespace: "test"})
+
+	scrape := func(role string) map[string]*dto.Metric {
+		chM := make(chan prometheus.Metric)
+		go func() {
+			e.extractInfoMetrics(chM, "# Replication\r\nrole:"+role+"\r\n", 0)
+			close(chM)
+		}()
+
+		got := map[string]*dto.Metric{}
+		for m := range chM {
+			desc := m.Desc().String()
+			for _, name := range []string{"test_role_changes_total", "test_role_last_change_timestamp_seconds"} {
+				if strings.Contains(desc, `"`+name+`"`) {
+					d := &dto.Metric{}
+					m.Write(d)
+					got[name] = d
+				}
+			}
+		}
+		return got
+	}
+
+	got := scrape("master")
+	if d, ok := got["test_role_changes_total"]; !ok || d.GetCounter().GetValue() != 0 {
+		t.Errorf("expected 0 role changes, got: %v", d)
+	}
+	if _, ok := got["test_role_last_change_timestamp_seconds"]; ok {
+		t.Errorf("expected no last change timestamp before a change")
+	}
+
+	got = scrape("slave")
+	if d, ok := got["test_role_changes_total"]; !ok || d.GetCounter().GetValue() != 1 {
+		t.Errorf("expected 1 role change, got: %v", d)
+	}
+	if d, ok := got["test_role_last_change_timestamp_seconds"]; !ok || d.GetGauge().GetValue() <= 0 {
+		t.Errorf("expected a last change timestamp, got: %v", d)
+	}
+}
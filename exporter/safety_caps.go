@@ -0,0 +1,88 @@
+package exporter
+
+import (
+	"errors"
+
+	"github.com/gomodule/redigo/redis"
+	log "github.com/sirupsen/logrus"
+)
+
+// errCapReached stops a SCAN loop once a safety cap of the scrape is reached
+var errCapReached = errors.New("safety cap reached")
+
+// safetyCap counts the keys, streams or consumers collected in a scrape against one of the
+// max-checked-keys, max-checked-streams and max-stream-consumers caps
+type safetyCap struct {
+	max       int64
+	taken     int64
+	truncated bool
+}
+
+// newSafetyCap returns a cap of max entries, max <= 0 means no cap
+func newSafetyCap(max int64) *safetyCap {
+	return &safetyCap{max: max}
+}
+
+// take returns how many of n entries fit under the cap and marks the cap
+// as truncated when entries are left out
+func (s *safetyCap) take(n int) int {
+	if s.max <= 0 {
+		return n
+	}
+	if left := s.max - s.taken; int64(n) > left {
+		s.truncated = true
+		n = int(left)
+	}
+	s.taken += int64(n)
+	return n
+}
+
+// truncatedValue returns the value of the ..._truncated metric of a cap
+func (s *safetyCap) truncatedValue() float64 {
+	if s.truncated {
+		return 1
+	}
+	return 0
+}
+
+// capEntries returns the entries that fit under the cap
+func capEntries[T any](s *safetyCap, entries []T) []T {
+	return entries[:s.take(len(entries))]
+}
+
+// getKeysFromPatternsCapped is getKeysFromPatterns but stops the SCAN of a pattern once
+// the keys no longer fit under the cap
+func getKeysFromPatternsCapped(c redis.Conn, keys []dbKeyPair, count int64, limit *safetyCap) (expandedKeys []dbKeyPair, err error) {
+	expandedKeys = []dbKeyPair{}
+	for _, k := range keys {
+		if limit.truncated {
+			break
+		}
+		if !globPattern.MatchString(k.key) {
+			expandedKeys = append(expandedKeys, capEntries(limit, []dbKeyPair{k})...)
+			continue
+		}
+
+		if _, err := doRedisCmd(c, "SELECT", k.db); err != nil {
+			return expandedKeys, err
+		}
+		err := scanKeysFunc(c, k.key, count, func(page []interface{}) error {
+			keyNames, err := redis.Strings(page, nil)
+			if err != nil {
+				return err
+			}
+			for _, keyName := range capEntries(limit, keyNames) {
+				expandedKeys = append(expandedKeys, dbKeyPair{db: k.db, key: keyName})
+			}
+			if limit.truncated {
+				return errCapReached
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, errCapReached) {
+			log.Errorf("error with SCAN for pattern: %#v err: %s", k.key, err)
+		}
+	}
+
+	return expandedKeys, nil
+}
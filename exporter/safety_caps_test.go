@@ -0,0 +1,88 @@
+package exporter
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestSafetyCap(t *testing.T) {
+	s := newSafetyCap(3)
+	if got := capEntries(s, []string{"a", "b"}); len(got) != 2 || s.truncated {
+		t.Errorf("expected both entries under the cap, got: %v truncated: %t", got, s.truncated)
+	}
+	if got := capEntries(s, []string{"c", "d"}); !slices.Equal(got, []string{"c"}) || !s.truncated {
+		t.Errorf("expected only c under the cap, got: %v truncated: %t", got, s.truncated)
+	}
+	if got := capEntries(s, []string{"e"}); len(got) != 0 {
+		t.Errorf("expected no entries once the cap is reached, got: %v", got)
+	}
+
+	unlimited := newSafetyCap(0)
+	if got := capEntries(unlimited, make([]int, 1000)); len(got) != 1000 || unlimited.truncatedValue() != 0 {
+		t.Errorf("expected no cap, got %d entries", len(got))
+	}
+}
+
+func TestMaxCheckedKeys(t *testing.T) {
+	for _, tst := range []struct {
+		name          string
+		max           int64
+		wantKeys      []string
+		wantTruncated float64
+	}{
+		{name: "truncated", max: 3, wantKeys: []string{"single", "k:1", "k:2"}, wantTruncated: 1},
+		{name: "all keys", max: 5, wantKeys: []string{"single", "k:1", "k:2", "k:3", "k:4"}, wantTruncated: 0},
+	} {
+		t.Run(tst.name, func(t *testing.T) {
+			replies := scanCheckpointReplies()
+			replies["TYPE single"] = []byte("list")
+			replies["MEMORY USAGE single"] = int64(10)
+			replies["LLEN single"] = int64(1)
+			c := &pipelineConn{fakeRedisConn: fakeRedisConn{replies: replies}}
+			e, _ := NewRedisExporter("redis://localhost:6379", Options{Namespace: "test", CheckSingleKeys: "db0=single", CheckKeys: "db0=k:*", CheckKeysBatchSize: 10, MaxCheckedKeys: tst.max})
+
+			got := collectScanMetrics(t, func(ch chan<- prometheus.Metric) {
+				if err := e.extractCheckKeyMetrics(ch, c); err != nil {
+					t.Errorf("extractCheckKeyMetrics() err: %s", err)
+				}
+			})
+
+			var keys []string
+			for name := range got {
+				if key, ok := strings.CutPrefix(name, "test_key_size/"); ok {
+					keys = append(keys, key)
+				}
+			}
+			slices.Sort(keys)
+			slices.Sort(tst.wantKeys)
+			if !slices.Equal(keys, tst.wantKeys) {
+				t.Errorf("expected the keys %v, got: %v", tst.wantKeys, keys)
+			}
+			if v, ok := got["test_key_checks_truncated"]; !ok || v != tst.wantTruncated {
+				t.Errorf("expected key_checks_truncated = %f, got: %f (found: %t)", tst.wantTruncated, v, ok)
+			}
+			if tst.wantTruncated == 1 && slices.Contains(c.commands, "SCAN 9 MATCH k:* COUNT 10") {
+				t.Errorf("expected the SCAN to stop at the cap, got: %v", c.commands)
+			}
+		})
+	}
+}
+
+func TestMaxCheckedStreams(t *testing.T) {
+	c := &pipelineConn{fakeRedisConn: fakeRedisConn{replies: scanCheckpointReplies()}}
+	limit := newSafetyCap(2)
+
+	got, err := getKeysFromPatternsCapped(c, []dbKeyPair{{db: "0", key: "k:*"}, {db: "0", key: "other"}}, 10, limit)
+	if err != nil {
+		t.Fatalf("getKeysFromPatternsCapped() err: %s", err)
+	}
+	if want := []dbKeyPair{{db: "0", key: "k:1"}, {db: "0", key: "k:2"}}; !slices.Equal(got, want) || !limit.truncated {
+		t.Errorf("expected %v, got: %v truncated: %t", want, got, limit.truncated)
+	}
+	if slices.Contains(c.commands, "SCAN 9 MATCH k:* COUNT 10") {
+		t.Errorf("expected the SCAN to stop at the cap, got: %v", c.commands)
+	}
+}
@@ -0,0 +1,99 @@
+package exporter
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// scanCheckpoint is the progress of the SCAN pass over the keys of a pattern, with a scan
+// budget a pass is split over several scrapes and resumed from cursor
+type scanCheckpoint struct {
+	cursor    uint64
+	passKeys  int64
+	passStart time.Time
+
+	passes           int64
+	lastPassKeys     int64
+	lastPassDuration time.Duration
+}
+
+// scanCheckpoints are the checkpoints of the patterns of all targets, shared with the per-target exporters
+type scanCheckpoints struct {
+	sync.Mutex
+	checkpoints map[string]*scanCheckpoint
+}
+
+func newScanCheckpoints() *scanCheckpoints {
+	return &scanCheckpoints{checkpoints: map[string]*scanCheckpoint{}}
+}
+
+// get returns a copy of the checkpoint of key, or a checkpoint at the start of the keyspace
+func (s *scanCheckpoints) get(key string) scanCheckpoint {
+	s.Lock()
+	defer s.Unlock()
+	if cp, ok := s.checkpoints[key]; ok {
+		return *cp
+	}
+	return scanCheckpoint{}
+}
+
+func (s *scanCheckpoints) set(key string, cp scanCheckpoint) {
+	s.Lock()
+	defer s.Unlock()
+	s.checkpoints[key] = &cp
+}
+
+// scanKeysCheckpointed calls fn with the pages of keys matching pattern, like scanKeysFunc,
+// but with CheckKeysScanBudget set it stops after that many SCAN calls and the next scrape
+// resumes the pass from the cursor. Only count-keys uses it, the result of a pass is only
+// known once the pass is complete. It returns the checkpoint after the scan, the database
+// of the connection has to be selected
+func (e *Exporter) scanKeysCheckpointed(c redis.Conn, collector string, db string, pattern string, fn func(keys []interface{}) error) (scanCheckpoint, error) {
+	key := fmt.Sprintf("%s|%s|db%s|%s", e.redisAddr, collector, db, pattern)
+	cp := e.scanCheckpoints.get(key)
+	if cp.cursor == 0 {
+		cp.passKeys = 0
+		cp.passStart = time.Now()
+	}
+
+	cursor, err := scanKeysFrom(c, pattern, e.options.CheckKeysBatchSize, cp.cursor, e.options.CheckKeysScanBudget, func(keys []interface{}) error {
+		cp.passKeys += int64(len(keys))
+		return fn(keys)
+	})
+	if err != nil {
+		// the pass is restarted on the next scrape
+		e.scanCheckpoints.set(key, scanCheckpoint{passes: cp.passes, lastPassKeys: cp.lastPassKeys, lastPassDuration: cp.lastPassDuration})
+		return cp, err
+	}
+
+	cp.cursor = cursor
+	if cursor == 0 {
+		cp.passes++
+		cp.lastPassKeys = cp.passKeys
+		cp.lastPassDuration = time.Since(cp.passStart)
+	}
+	e.scanCheckpoints.set(key, cp)
+	return cp, nil
+}
+
+// registerScanCheckpointMetrics exports the progress of the SCAN pass over the keys of
+// pattern, only with a scan budget as every scrape does a full pass otherwise
+func (e *Exporter) registerScanCheckpointMetrics(ch chan<- prometheus.Metric, collector string, dbLabel string, pattern string, cp scanCheckpoint) {
+	if e.options.CheckKeysScanBudget <= 0 {
+		return
+	}
+	inProgress := 0.0
+	if cp.cursor != 0 {
+		inProgress = 1
+	}
+	e.registerConstMetricGauge(ch, "key_scan_in_progress", inProgress, dbLabel, pattern, collector)
+	e.registerConstMetricGauge(ch, "key_scan_pass_keys", float64(cp.passKeys), dbLabel, pattern, collector)
+	e.registerConstMetric(ch, "key_scan_passes_total", float64(cp.passes), prometheus.CounterValue, dbLabel, pattern, collector)
+	if cp.passes > 0 {
+		e.registerConstMetricGauge(ch, "key_scan_last_pass_duration_seconds", cp.lastPassDuration.Seconds(), dbLabel, pattern, collector)
+	}
+}
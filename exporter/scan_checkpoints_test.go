@@ -0,0 +1,115 @@
+package exporter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func scanCheckpointReplies() map[string]interface{} {
+	return map[string]interface{}{
+		"SELECT 0":                  []byte("OK"),
+		"SCAN 0 MATCH k:* COUNT 10": []interface{}{[]byte("5"), []interface{}{[]byte("k:1")}},
+		"SCAN 5 MATCH k:* COUNT 10": []interface{}{[]byte("9"), []interface{}{[]byte("k:2"), []byte("k:3")}},
+		"SCAN 9 MATCH k:* COUNT 10": []interface{}{[]byte("0"), []interface{}{[]byte("k:4")}},
+		"TYPE k:1":                  []byte("list"),
+		"TYPE k:2":                  []byte("list"),
+		"TYPE k:3":                  []byte("list"),
+		"TYPE k:4":                  []byte("list"),
+		"MEMORY USAGE k:1":          int64(10),
+		"MEMORY USAGE k:2":          int64(10),
+		"MEMORY USAGE k:3":          int64(10),
+		"MEMORY USAGE k:4":          int64(10),
+		"LLEN k:1":                  int64(1),
+		"LLEN k:2":                  int64(1),
+		"LLEN k:3":                  int64(1),
+		"LLEN k:4":                  int64(1),
+	}
+}
+
+// collectScanMetrics returns the values of the metrics of extract by name and key or pattern label
+func collectScanMetrics(t *testing.T, extract func(ch chan<- prometheus.Metric)) map[string]float64 {
+	chM := make(chan prometheus.Metric)
+	go func() {
+		extract(chM)
+		close(chM)
+	}()
+
+	got := map[string]float64{}
+	for m := range chM {
+		desc := m.Desc().String()
+		name := desc[strings.Index(desc, `"`)+1:]
+		name = name[:strings.Index(name, `"`)]
+		d := &dto.Metric{}
+		m.Write(d)
+		for _, l := range d.GetLabel() {
+			if l.GetName() == "key" || l.GetName() == "pattern" {
+				name += "/" + l.GetValue()
+			}
+		}
+		got[name] = d.GetGauge().GetValue() + d.GetCounter().GetValue()
+	}
+	return got
+}
+
+func TestCountKeysScanCheckpoints(t *testing.T) {
+	c := &pipelineConn{fakeRedisConn: fakeRedisConn{replies: scanCheckpointReplies()}}
+	e, _ := NewRedisExporter("redis://localhost:6379", Options{Namespace: "test", CountKeys: "db0=k:*", CheckKeysBatchSize: 10, CheckKeysScanBudget: 2})
+
+	for i, want := range []map[string]float64{
+		// the first pass is split over two scrapes
+		{"test_key_scan_in_progress/k:*": 1, "test_key_scan_pass_keys/k:*": 3, "test_key_scan_passes_total/k:*": 0},
+		{"test_key_scan_in_progress/k:*": 0, "test_key_scan_pass_keys/k:*": 4, "test_key_scan_passes_total/k:*": 1, "test_keys_count/k:*": 4},
+		// the next pass starts at cursor 0 and the count of the last complete pass is kept
+		{"test_key_scan_in_progress/k:*": 1, "test_key_scan_pass_keys/k:*": 3, "test_key_scan_passes_total/k:*": 1, "test_keys_count/k:*": 4},
+	} {
+		got := collectScanMetrics(t, func(ch chan<- prometheus.Metric) { e.extractCountKeysMetrics(ch, c) })
+		delete(got, "test_key_scan_last_pass_duration_seconds/k:*")
+		if len(got) != len(want) {
+			t.Errorf("scrape %d: expected %v, got: %v", i+1, want, got)
+		}
+		for name, val := range want {
+			if v, ok := got[name]; !ok || v != val {
+				t.Errorf("scrape %d: expected %s = %f, got: %f (found: %t)", i+1, name, val, v, ok)
+			}
+		}
+	}
+}
+
+func TestCheckKeysIgnoreScanBudget(t *testing.T) {
+	c := &pipelineConn{fakeRedisConn: fakeRedisConn{replies: scanCheckpointReplies()}}
+	e, _ := NewRedisExporter("redis://localhost:6379", Options{Namespace: "test", CheckKeys: "db0=k:*", CheckKeysBatchSize: 10, CheckKeysScanBudget: 1})
+
+	// the per key metrics are always of all keys so the series don't churn between scrapes
+	allKeys := []string{"k:1", "k:2", "k:3", "k:4"}
+	for i, wantKeys := range [][]string{allKeys, allKeys} {
+		got := collectScanMetrics(t, func(ch chan<- prometheus.Metric) {
+			if err := e.extractCheckKeyMetrics(ch, c); err != nil {
+				t.Errorf("extractCheckKeyMetrics() err: %s", err)
+			}
+		})
+		sizes := 0
+		for name := range got {
+			if strings.HasPrefix(name, "test_key_size/") {
+				sizes++
+			}
+		}
+		if sizes != len(wantKeys) {
+			t.Errorf("scrape %d: expected the keys %v, got: %v", i+1, wantKeys, got)
+		}
+		for _, key := range wantKeys {
+			if _, ok := got["test_key_size/"+key]; !ok {
+				t.Errorf("scrape %d: expected the size of %s, got: %v", i+1, key, got)
+			}
+		}
+		if _, ok := got["test_key_scan_in_progress/k:*"]; ok {
+			t.Errorf("scrape %d: expected no scan progress of check-keys patterns, got: %v", i+1, got)
+		}
+	}
+
+	if _, err := NewRedisExporter("redis://localhost:6379", Options{CheckKeysScanBudget: 1, IsCluster: true}); err == nil {
+		t.Errorf("expected an error for check-keys-scan-budget in cluster mode")
+	}
+}
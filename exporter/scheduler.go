@@ -0,0 +1,259 @@
+package exporter
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	log "github.com/sirupsen/logrus"
+)
+
+// targetScheduler periodically collects all targets of the targets file with a pool of
+// workers and caches the results so /scrape requests for these targets can be served
+// without connecting to the redis instances.
+type targetScheduler struct {
+	e *Exporter
+
+	targetsFile string
+	interval    time.Duration
+	jitter      time.Duration
+	concurrency int
+
+	queue chan Target
+	// number of targets queued or currently being collected
+	pending atomic.Int64
+
+	skippedCycles atomic.Int64
+
+	mu      sync.RWMutex
+	targets []Target
+	labels  map[string]map[string]string
+	// keys of the targets /scrape requests are served from, the first one of each address
+	keys map[string]string
+	// results by target key, see Target.key()
+	results map[string]cachedResult
+}
+
+type cachedResult struct {
+	mfs         []*dto.MetricFamily
+	collectedAt time.Time
+}
+
+// StartTargetScheduler starts collecting the targets of the configured targets file
+// in the background until ctx is done.
+func (e *Exporter) StartTargetScheduler(ctx context.Context) error {
+	if e.options.TargetsFile == "" {
+		return nil
+	}
+
+	targets, err := LoadTargetsFile(e.options.TargetsFile)
+	if err != nil {
+		return err
+	}
+
+	s := &targetScheduler{
+		e:           e,
+		targetsFile: e.options.TargetsFile,
+		interval:    e.options.TargetsScrapeInterval,
+		jitter:      e.options.TargetsScrapeJitter,
+		concurrency: e.options.TargetsScrapeConcurrency,
+		results:     map[string]cachedResult{},
+	}
+	s.setTargets(targets)
+	if s.interval <= 0 {
+		s.interval = 30 * time.Second
+	}
+	if s.concurrency <= 0 {
+		s.concurrency = 10
+	}
+	s.queue = make(chan Target)
+
+	e.Lock()
+	e.scheduler = s
+	e.Unlock()
+
+	e.logger().Infof("Collecting %d targets from %s every %s with %d workers", len(targets), s.targetsFile, s.interval, s.concurrency)
+
+	for i := 0; i < s.concurrency; i++ {
+		go s.worker(ctx)
+	}
+	s.enqueue(ctx)
+	go s.run(ctx)
+	return nil
+}
+
+func (s *targetScheduler) run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.reloadTargets()
+			s.enqueue(ctx)
+		}
+	}
+}
+
+// enqueue queues all targets in the background, unless the previous cycle hasn't finished yet
+func (s *targetScheduler) enqueue(ctx context.Context) {
+	if depth := s.pending.Load(); depth > 0 {
+		log.Warnf("previous collection cycle still has %d targets pending, skipping cycle", depth)
+		s.skippedCycles.Add(1)
+		return
+	}
+
+	s.mu.RLock()
+	targets := s.targets
+	s.mu.RUnlock()
+
+	// the workers pick up the targets as they become idle, however many targets were reloaded
+	s.pending.Add(int64(len(targets)))
+	go func() {
+		for i, t := range targets {
+			select {
+			case <-ctx.Done():
+				s.pending.Add(-int64(len(targets) - i))
+				return
+			case s.queue <- t:
+			}
+		}
+	}()
+}
+
+func (s *targetScheduler) reloadTargets() {
+	targets, err := LoadTargetsFile(s.targetsFile)
+	if err != nil {
+		log.Errorf("Error reloading targets file %s, keeping previous targets, err: %s", s.targetsFile, err)
+		return
+	}
+
+	s.setTargets(targets)
+}
+
+func (s *targetScheduler) setTargets(targets []Target) {
+	labels := map[string]map[string]string{}
+	keys := map[string]string{}
+	current := map[string]bool{}
+	for _, t := range targets {
+		current[t.key()] = true
+		addr, _, err := parseTarget(t.Addr)
+		if err != nil {
+			continue
+		}
+		// e.g. unix:///tmp/redis.sock and /tmp/redis.sock or the same address with different
+		// users, the first target is the one /scrape requests get
+		if _, ok := keys[addr]; ok {
+			log.Warnf("Target %s of the targets file has the same address as a previous target, /scrape requests get the previous one", redactedAddr(addr))
+			continue
+		}
+		labels[addr] = t.Labels
+		keys[addr] = t.key()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.targets = targets
+	s.labels = labels
+	s.keys = keys
+
+	for key := range s.results {
+		if !current[key] {
+			delete(s.results, key)
+		}
+	}
+}
+
+// targetLabels returns the static labels of target from the targets file
+func (s *targetScheduler) targetLabels(target string) map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.labels[target]
+}
+
+// targetList returns the targets of the targets file
+func (s *targetScheduler) targetList() []Target {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.targets
+}
+
+func (s *targetScheduler) worker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case t := <-s.queue:
+			if s.jitter > 0 {
+				time.Sleep(time.Duration(rand.Int63n(int64(s.jitter))))
+			}
+			s.collect(t)
+			s.pending.Add(-1)
+		}
+	}
+}
+
+func (s *targetScheduler) collect(target Target) {
+	addr, user, err := parseTarget(target.Addr)
+	if err != nil {
+		log.Errorf("Invalid target %s in targets file, err: %s", redactedAddr(target.Addr), err)
+		return
+	}
+
+	opts := s.e.options
+	s.e.checkKeysConfig().apply(&opts)
+	if user != "" {
+		opts.User = user
+	}
+
+	opts.ConstLabels = target.Labels
+
+	exp, err := s.e.newTargetExporter(addr, opts)
+	if err != nil {
+		log.Errorf("NewRedisExporter() for target %s err: %s", addr, err)
+		return
+	}
+
+	mfs, err := exp.options.Registry.Gather()
+	if err != nil {
+		log.Errorf("Gather() for target %s err: %s", addr, err)
+	}
+
+	s.mu.Lock()
+	s.results[target.key()] = cachedResult{mfs: mfs, collectedAt: time.Now()}
+	s.mu.Unlock()
+}
+
+// result returns the latest collected metrics of the target with the address target
+// and when they were collected
+func (s *targetScheduler) result(target string) ([]*dto.MetricFamily, time.Time, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	key, ok := s.keys[target]
+	if !ok {
+		return nil, time.Time{}, false
+	}
+	r, ok := s.results[key]
+	return r.mfs, r.collectedAt, ok
+}
+
+func (s *targetScheduler) collectMetrics(ch chan<- prometheus.Metric) {
+	s.mu.RLock()
+	numTargets := len(s.targets)
+	for addr, key := range s.keys {
+		if r, ok := s.results[key]; ok {
+			s.e.registerConstMetricGauge(ch, "exporter_scrape_cache_age_seconds", time.Since(r.collectedAt).Seconds(), redactedAddr(addr))
+		}
+	}
+	s.mu.RUnlock()
+
+	s.e.registerConstMetricGauge(ch, "exporter_scrape_queue_targets", float64(numTargets))
+	s.e.registerConstMetricGauge(ch, "exporter_scrape_queue_depth", float64(s.pending.Load()))
+	s.e.registerConstMetric(ch, "exporter_scrape_queue_skipped_cycles_total", float64(s.skippedCycles.Load()), prometheus.CounterValue)
+}
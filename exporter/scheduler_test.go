@@ -0,0 +1,206 @@
+package exporter
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oliver006/redis_exporter/exporter/exportertest"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestLoadTargetsFile(t *testing.T) {
+	targets, err := LoadTargetsFile("../contrib/sample-targets-file.json")
+	if err != nil {
+		t.Fatalf("LoadTargetsFile() err: %s", err)
+	}
+	if len(targets) != 3 {
+		t.Errorf("expected 3 targets, got: %#v", targets)
+	}
+	if targets[0].Labels["shard"] != "1" || targets[2].Labels != nil {
+		t.Errorf("unexpected target labels: %#v", targets)
+	}
+
+	if _, err := LoadTargetsFile("non-existent.json"); err == nil {
+		t.Errorf("expected error for missing targets file")
+	}
+
+	if _, err := LoadTargetsFile("../contrib/sample-pwd-file.json-malformed"); err == nil {
+		t.Errorf("expected error for malformed targets file")
+	}
+
+	invalidLabels := filepath.Join(t.TempDir(), "targets.json")
+	if err := os.WriteFile(invalidLabels, []byte(`[{"targets": ["localhost:6379"], "labels": {"__shard": "1"}}]`), 0o600); err != nil {
+		t.Fatalf("WriteFile() err: %s", err)
+	}
+	if _, err := LoadTargetsFile(invalidLabels); err == nil {
+		t.Errorf("expected error for invalid label name")
+	}
+}
+
+func TestTargetsFileCollidingLabels(t *testing.T) {
+	targetsFile := filepath.Join(t.TempDir(), "targets.json")
+	if err := os.WriteFile(targetsFile, []byte(`[{"targets": ["localhost:6379"], "labels": {"shard": "1", "role": "cache", "tenant": "team-a"}}]`), 0o600); err != nil {
+		t.Fatalf("WriteFile() err: %s", err)
+	}
+	targets, err := LoadTargetsFile(targetsFile)
+	if err != nil || len(targets) != 1 {
+		t.Fatalf("LoadTargetsFile() err: %v, targets: %v", err, targets)
+	}
+
+	s := exportertest.NewServer(t)
+	registry := prometheus.NewRegistry()
+	if _, err := NewRedisExporter(s.Addr(), Options{Namespace: "test", Registry: registry, ConstLabels: targets[0].Labels}); err != nil {
+		t.Fatalf("NewRedisExporter() err: %s", err)
+	}
+	mfs, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather() err: %s", err)
+	}
+
+	// role is only renamed on instance_info, which has its own role label
+	want := map[string]map[string]string{
+		"test_up":                  {"role": "cache", "shard": "1", "tenant": "team-a"},
+		"test_instance_info":       {"role": "master", "exported_role": "cache", "shard": "1"},
+		"test_exporter_build_info": {"role": "cache", "tenant": "team-a"},
+	}
+	for _, mf := range mfs {
+		labels, ok := want[mf.GetName()]
+		if !ok {
+			continue
+		}
+		delete(want, mf.GetName())
+		got := map[string]string{}
+		for _, l := range mf.GetMetric()[0].GetLabel() {
+			got[l.GetName()] = l.GetValue()
+		}
+		for name, value := range labels {
+			if got[name] != value {
+				t.Errorf("expected %s{%s=%q}, got: %v", mf.GetName(), name, value, got)
+			}
+		}
+	}
+	if len(want) > 0 {
+		t.Errorf("metrics missing: %v", want)
+	}
+}
+
+func TestTargetScheduler(t *testing.T) {
+	targetsFile := filepath.Join(t.TempDir(), "targets.json")
+	if err := os.WriteFile(targetsFile, []byte(`[{"targets": ["unix:///tmp/doesnt.exist.1"]}, {"targets": ["/tmp/doesnt.exist.2"], "labels": {"shard": "2", "alias": "cache-2"}}]`), 0o600); err != nil {
+		t.Fatalf("WriteFile() err: %s", err)
+	}
+
+	e, _ := NewRedisExporter("", Options{
+		Namespace:                "test",
+		TargetsFile:              targetsFile,
+		TargetsScrapeInterval:    time.Hour,
+		TargetsScrapeConcurrency: 2,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := e.StartTargetScheduler(ctx); err != nil {
+		t.Fatalf("StartTargetScheduler() err: %s", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for e.scheduler.pending.Load() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("targets weren't collected in time")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	for _, target := range []string{"unix:///tmp/doesnt.exist.1", "unix:///tmp/doesnt.exist.2"} {
+		if _, _, ok := e.scheduler.result(target); !ok {
+			t.Errorf("no cached result for %s", target)
+		}
+	}
+
+	ts := httptest.NewServer(e)
+	defer ts.Close()
+
+	body := downloadURL(t, ts.URL+"/scrape?target=/tmp/doesnt.exist.2")
+	if !strings.Contains(body, `test_up{alias="cache-2",shard="2"} 0`) {
+		t.Errorf("expected cached result with labeled test_up 0, got: %s", body)
+	}
+	if strings.Contains(body, "test_exporter_target_info") {
+		t.Errorf("expected no target inventory in the result of a target, got: %s", body)
+	}
+	if !strings.Contains(body, "test_exporter_cached_data_age_seconds ") {
+		t.Errorf("expected cached result with cache age, got: %s", body)
+	}
+
+	body = downloadURL(t, ts.URL+"/scrape?target=/tmp/doesnt.exist.2&check-keys=a")
+	if !strings.Contains(body, `test_up{alias="cache-2",shard="2"} 0`) {
+		t.Errorf("expected live result with labeled test_up 0, got: %s", body)
+	}
+
+	body = downloadURL(t, ts.URL+"/scrape?target=unix:///tmp/doesnt.exist.1")
+	if !strings.Contains(body, "test_up 0") {
+		t.Errorf("expected cached result with test_up 0, got: %s", body)
+	}
+
+	body = downloadURL(t, ts.URL+"/targets")
+	if !strings.Contains(body, `"unix:///tmp/doesnt.exist.2"`) || !strings.Contains(body, `"shard": "2"`) {
+		t.Errorf("expected targets with labels, got: %s", body)
+	}
+
+	body = downloadURL(t, ts.URL+"/metrics")
+	for _, want := range []string{
+		"test_exporter_scrape_queue_targets 2",
+		"test_exporter_scrape_queue_depth 0",
+		"test_exporter_scrape_queue_skipped_cycles_total 0",
+		`test_exporter_scrape_cache_age_seconds{target="unix:///tmp/doesnt.exist.2"}`,
+		`test_exporter_target_info{alias="",source="targets-file",target="unix:///tmp/doesnt.exist.1"} 1`,
+		`test_exporter_target_info{alias="cache-2",source="targets-file",target="unix:///tmp/doesnt.exist.2"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in body, got: %s", want, body)
+		}
+	}
+
+	// a cycle is skipped while targets of the previous one are still pending
+	e.scheduler.pending.Add(1)
+	e.scheduler.enqueue(ctx)
+	e.scheduler.pending.Add(-1)
+	if got := e.scheduler.skippedCycles.Load(); got != 1 {
+		t.Errorf("expected 1 skipped cycle, got: %d", got)
+	}
+
+	// a reload with more targets than before, two of them with the same address
+	if err := os.WriteFile(targetsFile, []byte(`[{"targets": ["unix:///tmp/doesnt.exist.1", "/tmp/doesnt.exist.3", "/tmp/doesnt.exist.4"]}, {"targets": ["/tmp/doesnt.exist.1"], "labels": {"shard": "1"}}]`), 0o600); err != nil {
+		t.Fatalf("WriteFile() err: %s", err)
+	}
+	e.scheduler.reloadTargets()
+	e.scheduler.enqueue(ctx)
+	for e.scheduler.pending.Load() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("reloaded targets weren't collected in time")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	e.scheduler.mu.RLock()
+	numResults := len(e.scheduler.results)
+	e.scheduler.mu.RUnlock()
+	if numResults != 4 {
+		t.Errorf("expected a result for each of the 4 targets, got: %d", numResults)
+	}
+	if _, _, ok := e.scheduler.result("unix:///tmp/doesnt.exist.2"); ok {
+		t.Errorf("expected the result of the removed target to be dropped")
+	}
+
+	body = downloadURL(t, ts.URL+"/scrape?target=/tmp/doesnt.exist.1")
+	if !strings.Contains(body, "test_up 0") {
+		t.Errorf("expected the result of the first target with the address, got: %s", body)
+	}
+	body = downloadURL(t, ts.URL+"/metrics")
+	if n := strings.Count(body, "test_exporter_scrape_cache_age_seconds{"); n != 3 {
+		t.Errorf("expected the cache age of 3 addresses, got %d in: %s", n, body)
+	}
+}
@@ -0,0 +1,163 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gomodule/redigo/redis"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// scrapeRequests are the HTTP requests waiting for a collection of the exporter, a collection
+// is cancelled once all of them are gone so an aborted scrape doesn't keep running SCAN loops
+type scrapeRequests struct {
+	sync.Mutex
+	nextID int64
+	active map[int64]context.Context
+}
+
+func newScrapeRequests() *scrapeRequests {
+	return &scrapeRequests{active: map[int64]context.Context{}}
+}
+
+// add registers the context of a request until the returned func is called
+func (s *scrapeRequests) add(ctx context.Context) func() {
+	s.Lock()
+	defer s.Unlock()
+	s.nextID++
+	id := s.nextID
+	s.active[id] = ctx
+	return func() {
+		s.Lock()
+		defer s.Unlock()
+		delete(s.active, id)
+	}
+}
+
+// context returns the context of a collection, it's cancelled once all requests waiting at
+// the time of the call are gone. Collections without a waiting request, like the ones of
+// the watchdog, are only cancelled by the returned func. The trace context sent with the
+// traceparent header of the oldest waiting request that has one is the parent of the collection
+func (s *scrapeRequests) context() (context.Context, context.CancelFunc) {
+	s.Lock()
+	defer s.Unlock()
+
+	parent := context.Background()
+	parentID := int64(0)
+	for id, reqCtx := range s.active {
+		if sc := trace.SpanContextFromContext(reqCtx); sc.IsValid() && (parentID == 0 || id < parentID) {
+			parent, parentID = trace.ContextWithRemoteSpanContext(context.Background(), sc), id
+		}
+	}
+
+	ctx, cancel := context.WithCancel(parent)
+	if len(s.active) == 0 {
+		return ctx, cancel
+	}
+
+	var remaining atomic.Int64
+	remaining.Store(int64(len(s.active)))
+	stops := make([]func() bool, 0, len(s.active))
+	for _, reqCtx := range s.active {
+		stops = append(stops, context.AfterFunc(reqCtx, func() {
+			if remaining.Add(-1) == 0 {
+				cancel()
+			}
+		}))
+	}
+	return ctx, func() {
+		for _, stop := range stops {
+			stop()
+		}
+		cancel()
+	}
+}
+
+// cancelOnDisconnect registers the requests of h so the collections they wait for are
+// cancelled when the scrapers disconnect and continue the traces of the scrapers
+func (e *Exporter) cancelOnDisconnect(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := propagation.TraceContext{}.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		defer e.scrapeRequests.add(ctx)()
+		h.ServeHTTP(w, r)
+	})
+}
+
+// errScrapeCancelled is returned by the commands of a collection whose scrapers disconnected
+var errScrapeCancelled = errors.New("scrape cancelled")
+
+// scrapeContext returns the context of the current collection
+func (e *Exporter) scrapeContext() context.Context {
+	if e.scrapeCtx == nil {
+		return context.Background()
+	}
+	return e.scrapeCtx
+}
+
+// cancelableConn closes the connection once ctx is done so a command blocked on a slow
+// server returns immediately, all following commands fail without reaching the server
+type cancelableConn struct {
+	redis.Conn
+	ctx  context.Context
+	stop func() bool
+}
+
+// withScrapeContext returns c cancelled with the context of the current collection
+func (e *Exporter) withScrapeContext(c redis.Conn) redis.Conn {
+	ctx := e.scrapeContext()
+	if ctx.Done() == nil {
+		return c
+	}
+	return &cancelableConn{Conn: c, ctx: ctx, stop: context.AfterFunc(ctx, func() { c.Close() })}
+}
+
+// err returns errScrapeCancelled instead of the error of the closed connection
+func (c *cancelableConn) err(err error) error {
+	if c.ctx.Err() != nil {
+		return fmt.Errorf("%w: %s", errScrapeCancelled, context.Cause(c.ctx))
+	}
+	return err
+}
+
+func (c *cancelableConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	if err := c.err(nil); err != nil {
+		return nil, err
+	}
+	reply, err := c.Conn.Do(cmd, args...)
+	if err != nil {
+		return reply, c.err(err)
+	}
+	return reply, nil
+}
+
+func (c *cancelableConn) Send(cmd string, args ...interface{}) error {
+	if err := c.err(nil); err != nil {
+		return err
+	}
+	return c.err(c.Conn.Send(cmd, args...))
+}
+
+func (c *cancelableConn) Flush() error {
+	if err := c.Conn.Flush(); err != nil {
+		return c.err(err)
+	}
+	return nil
+}
+
+func (c *cancelableConn) Receive() (interface{}, error) {
+	reply, err := c.Conn.Receive()
+	if err != nil {
+		return reply, c.err(err)
+	}
+	return reply, nil
+}
+
+func (c *cancelableConn) Close() error {
+	c.stop()
+	return c.Conn.Close()
+}
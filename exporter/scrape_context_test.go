@@ -0,0 +1,121 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScrapeRequestsContext(t *testing.T) {
+	s := newScrapeRequests()
+
+	ctx, cancel := s.context()
+	if ctx.Err() != nil {
+		t.Fatalf("expected the context of a collection without requests not to be cancelled")
+	}
+	cancel()
+
+	reqA, cancelA := context.WithCancel(context.Background())
+	reqB, cancelB := context.WithCancel(context.Background())
+	defer s.add(reqA)()
+	removeB := s.add(reqB)
+
+	ctx, cancel = s.context()
+	defer cancel()
+	// requests arriving later wait for the next collection
+	defer s.add(context.Background())()
+
+	cancelA()
+	select {
+	case <-ctx.Done():
+		t.Fatalf("expected the collection to continue while a scraper is waiting for it")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	cancelB()
+	removeB()
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatalf("expected the collection to be cancelled once all scrapers disconnected")
+	}
+}
+
+// blockingConn blocks SCAN until it's closed, or returns pages with a non-zero cursor
+type blockingConn struct {
+	nodeConn
+	block  bool
+	closed chan struct{}
+}
+
+func (c *blockingConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	c.sent = append(c.sent, cmd)
+	if c.block {
+		<-c.closed
+		return nil, errors.New("use of closed network connection")
+	}
+	return []interface{}{[]byte("1"), []interface{}{[]byte("key")}}, nil
+}
+
+func (c *blockingConn) Close() error {
+	select {
+	case <-c.closed:
+	default:
+		close(c.closed)
+	}
+	return nil
+}
+
+func TestCancelableConn(t *testing.T) {
+	t.Run("in-flight command", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		e := &Exporter{scrapeCtx: ctx}
+		bc := &blockingConn{block: true, closed: make(chan struct{})}
+		c := e.withScrapeContext(bc)
+		defer c.Close()
+
+		time.AfterFunc(10*time.Millisecond, cancel)
+		if _, err := c.Do("SCAN", 0); !errors.Is(err, errScrapeCancelled) {
+			t.Fatalf("expected the blocked command to be cancelled, got: %v", err)
+		}
+		if _, err := c.Do("TYPE", "key"); !errors.Is(err, errScrapeCancelled) {
+			t.Errorf("expected following commands to fail, got: %v", err)
+		}
+		if len(bc.sent) != 1 {
+			t.Errorf("expected only the first command to reach the server, got: %v", bc.sent)
+		}
+	})
+
+	t.Run("scan loop", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		e := &Exporter{scrapeCtx: ctx}
+		bc := &blockingConn{closed: make(chan struct{})}
+		c := e.withScrapeContext(bc)
+		defer c.Close()
+
+		pages := 0
+		err := scanKeysFunc(c, "*", 10, func(keys []interface{}) error {
+			pages++
+			if pages == 3 {
+				cancel()
+			}
+			return nil
+		})
+		if err == nil || !strings.Contains(err.Error(), errScrapeCancelled.Error()) {
+			t.Errorf("expected the SCAN loop to stop, got: %v", err)
+		}
+		if pages != 3 {
+			t.Errorf("expected 3 pages before the scrape was cancelled, got: %d", pages)
+		}
+	})
+
+	t.Run("no scrape context", func(t *testing.T) {
+		e := &Exporter{}
+		bc := &blockingConn{closed: make(chan struct{})}
+		if c := e.withScrapeContext(bc); c != bc {
+			t.Errorf("expected the connection not to be wrapped outside of a collection")
+		}
+	})
+}
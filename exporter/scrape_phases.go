@@ -0,0 +1,193 @@
+package exporter
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// scrapePhases are the values of the phase label of exporter_scrape_phase_duration_seconds
+var scrapePhases = []string{"dns", "connect", "tls_handshake", "auth", "info", "keys", "other"}
+
+// collectorPhases maps the collectors of scrapeRedisHost() to their phase, all others are "other"
+var collectorPhases = map[string]string{
+	"info":            "info",
+	"dbsize_fallback": "info",
+	"keys":            "keys",
+	"key_sample":      "keys",
+	"streams":         "keys",
+	"key_groups":      "keys",
+}
+
+// scrapePhaseTimer adds up the time spent in each phase of a scrape
+type scrapePhaseTimer struct {
+	durations map[string]time.Duration
+	phase     string
+	start     time.Time
+}
+
+func newScrapePhaseTimer() *scrapePhaseTimer {
+	return &scrapePhaseTimer{durations: map[string]time.Duration{}}
+}
+
+// enter ends the current phase and starts phase
+func (t *scrapePhaseTimer) enter(phase string, now time.Time) {
+	if t == nil {
+		return
+	}
+	t.finish(now)
+	t.phase, t.start = phase, now
+}
+
+func (t *scrapePhaseTimer) finish(now time.Time) {
+	if t == nil {
+		return
+	}
+	if t.phase != "" {
+		t.durations[t.phase] += now.Sub(t.start)
+	}
+	t.phase = ""
+}
+
+// addDial moves the phases of a connection attempt out of the current phase
+func (t *scrapePhaseTimer) addDial(d *dialTimer) {
+	if t == nil {
+		return
+	}
+	for phase, took := range d.durations() {
+		t.durations[phase] += took
+		if t.phase != "" {
+			t.durations[t.phase] -= took
+		}
+	}
+}
+
+// startCollector sets the collector of the log lines and the phase of the current scrape
+func (e *Exporter) startCollector(collector string) {
+	e.setLogCollector(collector)
+	phase, ok := collectorPhases[collector]
+	if !ok {
+		phase = "other"
+	}
+	e.phaseTimer.enter(phase, time.Now())
+	e.traceCollector(collector)
+}
+
+func (e *Exporter) registerScrapePhaseMetrics(ch chan<- prometheus.Metric) {
+	e.phaseTimer.finish(time.Now())
+	for _, phase := range scrapePhases {
+		e.registerConstMetricGauge(ch, "exporter_scrape_phase_duration_seconds", e.phaseTimer.durations[phase].Seconds(), phase)
+	}
+}
+
+// dialTimer measures the phases of establishing a connection: the DNS lookup ends
+// when the dialer creates the socket, the TLS handshake when the connection is
+// verified and the rest of the dial (AUTH, SELECT) is accounted as auth
+type dialTimer struct {
+	mu sync.Mutex
+
+	start, resolved, connected, handshaken, done time.Time
+}
+
+// dialOptions instruments the dialer and the TLS config of a connection
+func (d *dialTimer) dialOptions(timeout time.Duration, tlsConfig *tls.Config) []redis.DialOption {
+	dialer := &net.Dialer{
+		Timeout:   timeout,
+		KeepAlive: 5 * time.Minute,
+		ControlContext: func(ctx context.Context, network, address string, c syscall.RawConn) error {
+			d.mark(&d.resolved)
+			return nil
+		},
+	}
+
+	tlsConfig = tlsConfig.Clone()
+	verify := tlsConfig.VerifyConnection
+	tlsConfig.VerifyConnection = func(cs tls.ConnectionState) error {
+		d.mark(&d.handshaken)
+		if verify != nil {
+			return verify(cs)
+		}
+		return nil
+	}
+
+	return []redis.DialOption{
+		redis.DialContextFunc(func(ctx context.Context, network, addr string) (net.Conn, error) {
+			d.reset()
+
+			c, err := dialer.DialContext(ctx, network, addr)
+			d.mark(&d.connected)
+			return c, err
+		}),
+		redis.DialTLSConfig(tlsConfig),
+	}
+}
+
+// reset starts over for a new connection attempt
+func (d *dialTimer) reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.start = time.Now()
+	d.resolved, d.connected, d.handshaken, d.done = time.Time{}, time.Time{}, time.Time{}, time.Time{}
+}
+
+// dialed marks the end of a successful connection attempt
+func (d *dialTimer) dialed() {
+	d.mark(&d.done)
+}
+
+// mark records the first time of a phase
+func (d *dialTimer) mark(t *time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if t.IsZero() {
+		*t = time.Now()
+	}
+}
+
+// connectTimes returns the start of the last connection attempt, the time the connection
+// was established including the TLS handshake and the time it was authenticated
+func (d *dialTimer) connectTimes() (start, connected, done time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	connected = d.connected
+	if d.handshaken.After(connected) {
+		connected = d.handshaken
+	}
+	return d.start, connected, d.done
+}
+
+// durations returns the time spent in the phases reached by the last connection attempt
+func (d *dialTimer) durations() map[string]time.Duration {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	res := map[string]time.Duration{}
+	if d.start.IsZero() {
+		return res
+	}
+
+	prev := d.start
+	for _, p := range []struct {
+		phase string
+		t     time.Time
+	}{
+		{"dns", d.resolved},
+		{"connect", d.connected},
+		{"tls_handshake", d.handshaken},
+		{"auth", d.done},
+	} {
+		if p.t.IsZero() {
+			continue
+		}
+		res[p.phase] = p.t.Sub(prev)
+		prev = p.t
+	}
+	return res
+}
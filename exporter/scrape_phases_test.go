@@ -0,0 +1,123 @@
+package exporter
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestScrapePhaseTimer(t *testing.T) {
+	start := time.Now()
+	timer := newScrapePhaseTimer()
+	timer.enter("other", start)
+	timer.enter("info", start.Add(3*time.Second))
+	timer.enter("other", start.Add(5*time.Second))
+	timer.finish(start.Add(6 * time.Second))
+
+	if got := timer.durations["info"]; got != 2*time.Second {
+		t.Errorf("info: got %s, want: 2s", got)
+	}
+	if got := timer.durations["other"]; got != 4*time.Second {
+		t.Errorf("other: got %s, want: 4s", got)
+	}
+
+	// a nil timer is a no-op
+	var nilTimer *scrapePhaseTimer
+	nilTimer.enter("info", start)
+	nilTimer.finish(start)
+	nilTimer.addDial(&dialTimer{})
+}
+
+func TestDialTimer(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() err: %s", err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer c.Close()
+		}
+	}()
+
+	d := &dialTimer{}
+	c, err := redis.Dial("tcp", l.Addr().String(), d.dialOptions(time.Second, &tls.Config{})...)
+	if err != nil {
+		t.Fatalf("Dial() err: %s", err)
+	}
+	c.Close()
+	d.dialed()
+
+	got := d.durations()
+	for _, phase := range []string{"dns", "connect", "auth"} {
+		if _, ok := got[phase]; !ok {
+			t.Errorf("missing phase %s, got: %v", phase, got)
+		}
+	}
+	if _, ok := got["tls_handshake"]; ok {
+		t.Errorf("unexpected tls_handshake phase without TLS, got: %v", got)
+	}
+}
+
+func TestDialTimerTLS(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	verified := false
+	d := &dialTimer{}
+	options := append(d.dialOptions(time.Second, &tls.Config{
+		InsecureSkipVerify: true,
+		VerifyConnection: func(tls.ConnectionState) error {
+			verified = true
+			return nil
+		},
+	}), redis.DialUseTLS(true))
+	c, err := redis.Dial("tcp", ts.Listener.Addr().String(), options...)
+	if err != nil {
+		t.Fatalf("Dial() err: %s", err)
+	}
+	c.Close()
+	d.dialed()
+
+	if _, ok := d.durations()["tls_handshake"]; !ok {
+		t.Errorf("missing tls_handshake phase, got: %v", d.durations())
+	}
+	if !verified {
+		t.Errorf("the VerifyConnection func of the TLS config wasn't called")
+	}
+}
+
+func TestScrapePhaseMetrics(t *testing.T) {
+	// nothing listens on port 1, the scrape fails after the connect phase
+	e, _ := NewRedisExporter("redis://127.0.0.1:1", Options{Namespace: "test", ConnectionTimeouts: time.Second})
+
+	chM := make(chan prometheus.Metric, 100)
+	e.Collect(chM)
+	close(chM)
+
+	phases := map[string]bool{}
+	for m := range chM {
+		if m.Desc() != e.mustFindMetricDescription("exporter_scrape_phase_duration_seconds") {
+			continue
+		}
+		d := &dto.Metric{}
+		m.Write(d)
+		phases[d.GetLabel()[0].GetValue()] = true
+	}
+	for _, phase := range scrapePhases {
+		if !phases[phase] {
+			t.Errorf("missing phase %s, got: %v", phase, phases)
+		}
+	}
+}
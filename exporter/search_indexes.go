@@ -5,7 +5,6 @@ import (
 
 	"github.com/gomodule/redigo/redis"
 	"github.com/prometheus/client_golang/prometheus"
-	log "github.com/sirupsen/logrus"
 )
 
 // All fields of the searchIndexInfo struct must be exported
@@ -42,7 +41,7 @@ func (e *Exporter) extractSearchIndexesMetrics(ch chan<- prometheus.Metric, c re
 	var searchIndexes []string
 	allSearchIndexes, err := redis.Strings(doRedisCmd(c, "FT._LIST"))
 	if err != nil {
-		log.Errorf("extractSearchIndexesMetrics() err: %s", err)
+		e.logger().Errorf("extractSearchIndexesMetrics() err: %s", err)
 		return
 	}
 
@@ -57,14 +56,14 @@ func (e *Exporter) extractSearchIndexesMetrics(ch chan<- prometheus.Metric, c re
 	for _, index := range searchIndexes {
 		values, err := redis.Values(doRedisCmd(c, "FT.INFO", index))
 		if err != nil {
-			log.Errorf("extractSearchIndexesMetrics() err: %s", err)
+			e.logger().Errorf("extractSearchIndexesMetrics() err: %s", err)
 			return
 		}
 
 		// Scan slice to struct
 		var indexInfo searchIndexInfo
 		if err := redis.ScanStruct(values, &indexInfo); err != nil {
-			log.Errorf("Couldn't scan search index '%s': %s", index, err)
+			e.logger().Errorf("Couldn't scan search index '%s': %s", index, err)
 			continue
 		}
 		// Register search index metrics
@@ -0,0 +1,86 @@
+package exporter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// errSelectFree is returned for SELECT commands of databases other than db0 in SELECT-free mode
+var errSelectFree = redis.Error("ERR SELECT-free mode, only db0 is available")
+
+// selectFreeConn never sends SELECT to the server, selecting db0 succeeds while
+// selecting any other database fails, so all key collectors are restricted to db0
+type selectFreeConn struct {
+	redis.Conn
+
+	// replies of the commands queued with Send(), nil entries are replies of
+	// commands that were sent to the server
+	pending []interface{}
+}
+
+func isSelectOfDB0(args []interface{}) bool {
+	if len(args) != 1 {
+		return false
+	}
+	db, err := strconv.Atoi(fmt.Sprint(args[0]))
+	return err == nil && db == 0
+}
+
+func selectReply(args []interface{}) interface{} {
+	if isSelectOfDB0(args) {
+		return "OK"
+	}
+	return errSelectFree
+}
+
+func (c *selectFreeConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	if strings.EqualFold(cmd, "SELECT") {
+		reply := selectReply(args)
+		if err, ok := reply.(redis.Error); ok {
+			return nil, err
+		}
+		return reply, nil
+	}
+	// Do() receives all pending replies
+	c.pending = nil
+	return c.Conn.Do(cmd, args...)
+}
+
+func (c *selectFreeConn) Send(cmd string, args ...interface{}) error {
+	if strings.EqualFold(cmd, "SELECT") {
+		c.pending = append(c.pending, selectReply(args))
+		return nil
+	}
+	c.pending = append(c.pending, nil)
+	return c.Conn.Send(cmd, args...)
+}
+
+func (c *selectFreeConn) Receive() (interface{}, error) {
+	if len(c.pending) > 0 {
+		reply := c.pending[0]
+		c.pending = c.pending[1:]
+		if reply != nil {
+			if err, ok := reply.(redis.Error); ok {
+				return nil, err
+			}
+			return reply, nil
+		}
+	}
+	return c.Conn.Receive()
+}
+
+// selectFree returns true if SELECT mustn't be used, either because it's disabled
+// or because the server (e.g. a proxy) rejects it
+func (e *Exporter) selectFree(c redis.Conn) bool {
+	if e.options.DisableSelect {
+		return true
+	}
+	if _, err := doRedisCmd(c, "SELECT", 0); err != nil {
+		e.logger().Debugf("SELECT rejected, using SELECT-free mode, err: %s", err)
+		return true
+	}
+	return false
+}
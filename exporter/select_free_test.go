@@ -0,0 +1,59 @@
+package exporter
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+func TestSelectFreeConn(t *testing.T) {
+	c := &selectFreeConn{Conn: &fakeRedisConn{replies: map[string]interface{}{
+		"TYPE mykey": "string",
+	}}}
+
+	if reply, err := redis.String(c.Do("SELECT", 0)); err != nil || reply != "OK" {
+		t.Errorf("expected SELECT 0 to succeed, got: %q err: %v", reply, err)
+	}
+	if reply, err := redis.String(c.Do("SELECT", "0")); err != nil || reply != "OK" {
+		t.Errorf("expected SELECT \"0\" to succeed, got: %q err: %v", reply, err)
+	}
+	if _, err := c.Do("select", 1); !errors.Is(err, errSelectFree) {
+		t.Errorf("expected SELECT 1 to fail, got err: %v", err)
+	}
+	if reply, err := redis.String(c.Do("TYPE", "mykey")); err != nil || reply != "string" {
+		t.Errorf("expected TYPE to be sent to the server, got: %q err: %v", reply, err)
+	}
+
+	// pipelined SELECT replies are returned in order without being sent
+	_ = c.Send("SELECT", "0")
+	_ = c.Send("TYPE", "mykey")
+	_ = c.Send("SELECT", "2")
+	_ = c.Flush()
+
+	if reply, err := redis.String(c.Receive()); err != nil || reply != "OK" {
+		t.Errorf("expected OK for pipelined SELECT 0, got: %q err: %v", reply, err)
+	}
+	if reply, err := c.Receive(); err != nil || reply != nil {
+		t.Errorf("expected reply of the fake server for TYPE, got: %v err: %v", reply, err)
+	}
+	if _, err := c.Receive(); !errors.Is(err, errSelectFree) {
+		t.Errorf("expected error for pipelined SELECT 2, got err: %v", err)
+	}
+}
+
+func TestSelectFreeDetection(t *testing.T) {
+	e, _ := NewRedisExporter("", Options{Namespace: "test"})
+
+	if e.selectFree(&fakeRedisConn{replies: map[string]interface{}{"SELECT 0": "OK"}}) {
+		t.Errorf("expected SELECT to be used when the server accepts it")
+	}
+	if !e.selectFree(&fakeRedisConn{}) {
+		t.Errorf("expected SELECT-free mode when the server rejects SELECT")
+	}
+
+	e, _ = NewRedisExporter("", Options{Namespace: "test", DisableSelect: true})
+	if !e.selectFree(&fakeRedisConn{replies: map[string]interface{}{"SELECT 0": "OK"}}) {
+		t.Errorf("expected SELECT-free mode when SELECT is disabled")
+	}
+}
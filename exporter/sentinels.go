@@ -38,16 +38,16 @@ func (e *Exporter) handleMetricsSentinel(ch chan<- prometheus.Metric, fieldKey s
 func (e *Exporter) extractSentinelMetrics(ch chan<- prometheus.Metric, c redis.Conn) {
 	masterDetails, err := redis.Values(doRedisCmd(c, "SENTINEL", "MASTERS"))
 	if err != nil {
-		log.Debugf("Error getting sentinel master details %s:", err)
+		e.logger().Debugf("Error getting sentinel master details %s:", err)
 		return
 	}
 
-	log.Debugf("Sentinel master details: %#v", masterDetails)
+	e.logger().Debugf("Sentinel master details: %#v", masterDetails)
 
 	for _, masterDetail := range masterDetails {
 		masterDetailMap, err := redis.StringMap(masterDetail, nil)
 		if err != nil {
-			log.Debugf("Error getting masterDetailmap from masterDetail: %s, err: %s", masterDetail, err)
+			e.logger().Debugf("Error getting masterDetailmap from masterDetail: %s, err: %s", masterDetail, err)
 			continue
 		}
 
@@ -68,7 +68,7 @@ func (e *Exporter) extractSentinelMetrics(ch chan<- prometheus.Metric, c redis.C
 		masterAddr := masterIp + ":" + masterPort
 
 		masterCkquorumMsg, err := redis.String(doRedisCmd(c, "SENTINEL", "CKQUORUM", masterName))
-		log.Debugf("Sentinel ckquorum status for master %s: %s %s", masterName, masterCkquorumMsg, err)
+		e.logger().Debugf("Sentinel ckquorum status for master %s: %s %s", masterName, masterCkquorumMsg, err)
 		masterCkquorumStatus := 1
 		if err != nil {
 			masterCkquorumStatus = 0
@@ -87,11 +87,11 @@ func (e *Exporter) extractSentinelMetrics(ch chan<- prometheus.Metric, c redis.C
 		e.registerConstMetricGauge(ch, "sentinel_master_setting_down_after_milliseconds", masterDownAfterMs, masterName, masterAddr)
 
 		sentinelDetails, _ := redis.Values(doRedisCmd(c, "SENTINEL", "SENTINELS", masterName))
-		log.Debugf("Sentinel details for master %s: %s", masterName, sentinelDetails)
+		e.logger().Debugf("Sentinel details for master %s: %s", masterName, sentinelDetails)
 		e.processSentinelSentinels(ch, sentinelDetails, masterName, masterAddr)
 
 		slaveDetails, _ := redis.Values(doRedisCmd(c, "SENTINEL", "SLAVES", masterName))
-		log.Debugf("Slave details for master %s: %s", masterName, slaveDetails)
+		e.logger().Debugf("Slave details for master %s: %s", masterName, slaveDetails)
 		e.processSentinelSlaves(ch, slaveDetails, masterName, masterAddr)
 	}
 }
@@ -102,27 +102,27 @@ func (e *Exporter) extractSentinelConfig(ch chan<- prometheus.Metric, c redis.Co
 	}
 	sentinelConfig, err := redis.Values(doRedisCmd(c, "SENTINEL", "config", "get", "*"))
 	if err != nil {
-		log.Errorf("Error getting sentinel config: %s", err)
+		e.logger().Errorf("Error getting sentinel config: %s", err)
 		return
 	}
 
 	if len(sentinelConfig)%2 != 0 {
-		log.Errorf("Invalid sentinel config, got: %#v", sentinelConfig)
+		e.logger().Errorf("Invalid sentinel config, got: %#v", sentinelConfig)
 		return
 	}
 
-	log.Debugf("Sentinel config: %v", sentinelConfig)
+	e.logger().Debugf("Sentinel config: %v", sentinelConfig)
 
 	for pos := 0; pos < len(sentinelConfig)/2; pos++ {
 		strKey, err := redis.String(sentinelConfig[pos*2], nil)
 		if err != nil {
-			log.Errorf("invalid sentinel config key name, err: %s, skipped", err)
+			e.logger().Errorf("invalid sentinel config key name, err: %s, skipped", err)
 			continue
 		}
 
 		strVal, err := redis.String(sentinelConfig[pos*2+1], nil)
 		if err != nil {
-			log.Debugf("invalid sentinel config value for key name %s, err: %s, skipped", strKey, err)
+			e.logger().Debugf("invalid sentinel config value for key name %s, err: %s, skipped", strKey, err)
 			continue
 		}
 
@@ -141,7 +141,7 @@ func (e *Exporter) processSentinelSentinels(ch chan<- prometheus.Metric, sentine
 	for _, sentinelDetail := range sentinelDetails {
 		sentinelDetailMap, err := redis.StringMap(sentinelDetail, nil)
 		if err != nil {
-			log.Debugf("Error getting sentinelDetailMap from sentinelDetail: %s, err: %s", sentinelDetail, err)
+			e.logger().Debugf("Error getting sentinelDetailMap from sentinelDetail: %s, err: %s", sentinelDetail, err)
 			continue
 		}
 
@@ -165,7 +165,7 @@ func (e *Exporter) processSentinelSlaves(ch chan<- prometheus.Metric, slaveDetai
 	for _, slaveDetail := range slaveDetails {
 		slaveDetailMap, err := redis.StringMap(slaveDetail, nil)
 		if err != nil {
-			log.Debugf("Error getting slavedetailMap from slaveDetail: %s, err: %s", slaveDetail, err)
+			e.logger().Debugf("Error getting slavedetailMap from slaveDetail: %s, err: %s", slaveDetail, err)
 			continue
 		}
 
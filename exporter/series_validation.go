@@ -0,0 +1,132 @@
+package exporter
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	log "github.com/sirupsen/logrus"
+)
+
+// maxReportedInvalidSeries is the number of offending series listed in the log per collection
+const maxReportedInvalidSeries = 10
+
+// seriesValidator checks the metrics of a collection for the problems that make the
+// gathering of the registry fail, e.g. a Lua script or key names producing the same series
+// twice, and drops the offending series
+type seriesValidator struct {
+	seen       map[string]bool
+	labelNames map[string]string
+	invalid    map[string][]string
+}
+
+func newSeriesValidator() *seriesValidator {
+	return &seriesValidator{seen: map[string]bool{}, labelNames: map[string]string{}, invalid: map[string][]string{}}
+}
+
+// descName returns the fully-qualified name of d, Desc doesn't expose it other than in String()
+func descName(d *prometheus.Desc) string {
+	s := d.String()
+	start := strings.Index(s, `fqName: "`)
+	if start < 0 {
+		return s
+	}
+	s = s[start+len(`fqName: "`):]
+	return s[:strings.Index(s, `"`)]
+}
+
+// check returns whether m can be exposed, or records why not
+func (v *seriesValidator) check(m prometheus.Metric) bool {
+	d := &dto.Metric{}
+	if err := m.Write(d); err != nil {
+		reason := "invalid_metric"
+		if strings.Contains(err.Error(), "UTF-8") {
+			reason = "invalid_label_value"
+		}
+		v.invalid[reason] = append(v.invalid[reason], fmt.Sprintf("%s: %s", descName(m.Desc()), err))
+		return false
+	}
+
+	labels := d.GetLabel()
+	sort.Slice(labels, func(i, j int) bool { return labels[i].GetName() < labels[j].GetName() })
+	names := make([]string, 0, len(labels))
+	pairs := make([]string, 0, len(labels))
+	reason := ""
+	for _, l := range labels {
+		names = append(names, l.GetName())
+		pairs = append(pairs, fmt.Sprintf("%s=%q", l.GetName(), l.GetValue()))
+		if !utf8.ValidString(l.GetValue()) {
+			reason = "invalid_label_value"
+		}
+	}
+
+	name := descName(m.Desc())
+	series := name + "{" + strings.Join(pairs, ",") + "}"
+	if reason == "" {
+		labelNames := strings.Join(names, ",")
+		if prev, ok := v.labelNames[name]; ok && prev != labelNames {
+			reason = "inconsistent_labels"
+		} else if v.seen[series] {
+			reason = "duplicate"
+		} else {
+			v.labelNames[name] = labelNames
+			v.seen[series] = true
+			return true
+		}
+	}
+	v.invalid[reason] = append(v.invalid[reason], series)
+	return false
+}
+
+// validateSeries forwards the metrics sent to the returned channel to ch without the
+// offending series, the returned func waits for the channel to be closed and returns
+// the offending series by reason
+func validateSeries(ch chan<- prometheus.Metric) (chan<- prometheus.Metric, func() map[string][]string) {
+	v := newSeriesValidator()
+	in := make(chan prometheus.Metric)
+	done := make(chan struct{})
+	go func() {
+		for m := range in {
+			if v.check(m) {
+				ch <- m
+			}
+		}
+		close(done)
+	}()
+	return in, func() map[string][]string {
+		<-done
+		return v.invalid
+	}
+}
+
+// collectValidated is collect with the series checked by a seriesValidator, the offending
+// series are logged and their number exported as exporter_invalid_series
+func (e *Exporter) collectValidated(ch chan<- prometheus.Metric) {
+	in, wait := validateSeries(ch)
+	e.collect(in)
+	close(in)
+	invalid := wait()
+
+	reasons := make([]string, 0, len(invalid))
+	for reason := range invalid {
+		reasons = append(reasons, reason)
+	}
+	slices.Sort(reasons)
+	for _, reason := range reasons {
+		series := invalid[reason]
+		listed := series[:min(len(series), maxReportedInvalidSeries)]
+		log.Errorf("Dropped %d series of %s that can't be exposed (%s): %s", len(series), redactedAddr(e.redisAddr), reason, strings.Join(listed, ", "))
+	}
+	for _, reason := range []string{"duplicate", "inconsistent_labels", "invalid_label_value", "invalid_metric"} {
+		e.registerConstMetricGauge(ch, "exporter_invalid_series", float64(len(invalid[reason])), reason)
+	}
+}
+
+// validateSeriesEnabled returns whether the series of collections are validated
+func (e *Exporter) validateSeriesEnabled() bool {
+	return e.options.ValidateSeries || log.IsLevelEnabled(log.DebugLevel)
+}
@@ -0,0 +1,58 @@
+package exporter
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestSeriesValidator(t *testing.T) {
+	e, _ := NewRedisExporter("", Options{Namespace: "test", ValidateSeries: true})
+
+	ch := make(chan prometheus.Metric)
+	in, wait := validateSeries(ch)
+	go func() {
+		e.registerConstMetricGauge(in, "script_values", 1, "a", "script.lua")
+		e.registerConstMetricGauge(in, "script_values", 2, "b", "script.lua")
+		// a Lua script returning the same key twice
+		e.registerConstMetricGauge(in, "script_values", 3, "a", "script.lua")
+		e.registerConstMetricGauge(in, "script_values", 4, "\xff\xfe", "script.lua")
+		close(in)
+	}()
+
+	var got []float64
+	for m := range ch {
+		d := &dto.Metric{}
+		m.Write(d)
+		got = append(got, d.GetGauge().GetValue())
+		if len(got) == 2 {
+			break
+		}
+	}
+	invalid := wait()
+
+	if len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Errorf("expected the first two series, got: %v", got)
+	}
+	if len(invalid["duplicate"]) != 1 || invalid["duplicate"][0] != `test_script_values{filename="script.lua",key="a"}` {
+		t.Errorf("expected the duplicate series, got: %v", invalid)
+	}
+	if len(invalid["invalid_label_value"]) != 1 {
+		t.Errorf("expected the series with the invalid label value, got: %v", invalid)
+	}
+}
+
+func TestSeriesValidatorInconsistentLabels(t *testing.T) {
+	v := newSeriesValidator()
+	for i, lbls := range [][]string{{"key"}, {"key", "db"}} {
+		desc := prometheus.NewDesc("test_values", "", lbls, nil)
+		vals := make([]string, len(lbls))
+		if ok := v.check(prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, 1, vals...)); ok != (i == 0) {
+			t.Errorf("series %d: expected ok = %t", i, i == 0)
+		}
+	}
+	if len(v.invalid["inconsistent_labels"]) != 1 {
+		t.Errorf("expected the series with the inconsistent labels, got: %v", v.invalid)
+	}
+}
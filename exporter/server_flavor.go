@@ -0,0 +1,49 @@
+package exporter
+
+import (
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// server flavors told apart by serverFlavor, forks keep redis_version at the Redis version
+// they're compatible with and report their own version in a separate field
+const (
+	flavorRedis     = "redis"
+	flavorValkey    = "valkey"
+	flavorKeyDB     = "keydb"
+	flavorDragonfly = "dragonfly"
+	flavorGarnet    = "garnet"
+)
+
+// serverFlavor returns the flavor of the server and its own version from the fields of INFO
+func serverFlavor(fields *infoFieldTable) (string, string) {
+	if v, ok := fields.get("dragonfly_version"); ok {
+		return flavorDragonfly, strings.TrimPrefix(v, "df-v")
+	}
+	if v, ok := fields.get("garnet_version"); ok {
+		return flavorGarnet, v
+	}
+	if v, ok := fields.get("valkey_version"); ok || fields.value("server_name") == flavorValkey {
+		if !ok {
+			v = fields.value("redis_version")
+		}
+		return flavorValkey, v
+	}
+	// server_threads only exists on KeyDB, which reports its version as redis_version
+	if _, ok := fields.get("server_threads"); ok {
+		return flavorKeyDB, fields.value("redis_version")
+	}
+	return flavorRedis, fields.value("redis_version")
+}
+
+// registerServerFlavor exports server_flavor_info and keeps the flavor of the current scrape
+// so collectors can adapt to it
+func (e *Exporter) registerServerFlavor(ch chan<- prometheus.Metric, fields *infoFieldTable) {
+	flavor, version := serverFlavor(fields)
+	if e.options.IsGarnet {
+		flavor = flavorGarnet
+	}
+	e.serverFlavor = flavor
+	e.registerConstMetricGauge(ch, "server_flavor_info", 1, flavor, version)
+}
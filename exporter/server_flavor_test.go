@@ -0,0 +1,57 @@
+package exporter
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestServerFlavor(t *testing.T) {
+	for _, tst := range []struct {
+		name        string
+		info        string
+		wantFlavor  string
+		wantVersion string
+	}{
+		{name: "redis", info: "redis_version:7.4.1\r\nredis_mode:standalone\r\n", wantFlavor: "redis", wantVersion: "7.4.1"},
+		{name: "valkey", info: "redis_version:7.2.4\r\nserver_name:valkey\r\nvalkey_version:8.1.0\r\n", wantFlavor: "valkey", wantVersion: "8.1.0"},
+		{name: "valkey without valkey_version", info: "redis_version:7.2.4\r\nserver_name:valkey\r\n", wantFlavor: "valkey", wantVersion: "7.2.4"},
+		{name: "keydb", info: "redis_version:6.3.4\r\nserver_threads:2\r\n", wantFlavor: "keydb", wantVersion: "6.3.4"},
+		{name: "dragonfly", info: "redis_version:7.4.0\r\ndragonfly_version:df-v1.25.2\r\n", wantFlavor: "dragonfly", wantVersion: "1.25.2"},
+		{name: "garnet", info: "redis_version:7.4.0\r\ngarnet_version:1.0.60\r\n", wantFlavor: "garnet", wantVersion: "1.0.60"},
+	} {
+		t.Run(tst.name, func(t *testing.T) {
+			e, _ := NewRedisExporter("redis://localhost:6379", Options{Namespace: "test"})
+
+			chM := make(chan prometheus.Metric)
+			go func() {
+				e.extractInfoMetrics(chM, "# Server\r\n"+tst.info, 16)
+				close(chM)
+			}()
+
+			found := false
+			for m := range chM {
+				if m.Desc() != e.mustFindMetricDescription("server_flavor_info") {
+					continue
+				}
+				found = true
+				d := &dto.Metric{}
+				m.Write(d)
+				got := map[string]string{}
+				for _, l := range d.GetLabel() {
+					got[l.GetName()] = l.GetValue()
+				}
+				if got["flavor"] != tst.wantFlavor || got["version"] != tst.wantVersion {
+					t.Errorf("expected flavor %q version %q, got: %v", tst.wantFlavor, tst.wantVersion, got)
+				}
+			}
+			if !found {
+				t.Errorf("server_flavor_info not found")
+			}
+			if e.serverFlavor != tst.wantFlavor {
+				t.Errorf("expected the flavor %q for the scrape, got: %q", tst.wantFlavor, e.serverFlavor)
+			}
+		})
+	}
+}
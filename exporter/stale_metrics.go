@@ -0,0 +1,91 @@
+package exporter
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// staleSnapshot are the metrics of the last successful collection of a target
+type staleSnapshot struct {
+	metrics     []prometheus.Metric
+	collectedAt time.Time
+}
+
+// staleSnapshots keeps the last successful collection per target for serve-stale-on-error,
+// snapshots older than window are dropped
+type staleSnapshots struct {
+	sync.Mutex
+	window    time.Duration
+	snapshots map[string]staleSnapshot
+}
+
+func newStaleSnapshots(window time.Duration) *staleSnapshots {
+	return &staleSnapshots{window: window, snapshots: map[string]staleSnapshot{}}
+}
+
+// set stores the snapshot of target and drops the expired ones, e.g. of targets
+// that are no longer scraped
+func (s *staleSnapshots) set(target string, snap staleSnapshot) {
+	s.Lock()
+	defer s.Unlock()
+	for t, old := range s.snapshots {
+		if time.Since(old.collectedAt) > s.window {
+			delete(s.snapshots, t)
+		}
+	}
+	s.snapshots[target] = snap
+}
+
+// get returns the snapshot of target unless it expired
+func (s *staleSnapshots) get(target string) (staleSnapshot, bool) {
+	s.Lock()
+	defer s.Unlock()
+	snap, ok := s.snapshots[target]
+	if ok && time.Since(snap.collectedAt) > s.window {
+		delete(s.snapshots, target)
+		return staleSnapshot{}, false
+	}
+	return snap, ok
+}
+
+// scrapeRedisHostOrStale is scrapeRedisHost but with ServeStaleOnError set a failed scrape
+// sends the metrics of the last successful one instead, if it's at most ServeStaleOnError
+// old. It returns the error of the scrape, also when stale metrics were sent
+func (e *Exporter) scrapeRedisHostOrStale(ch chan<- prometheus.Metric) error {
+	if e.options.ServeStaleOnError <= 0 {
+		return e.scrapeRedisHost(ch)
+	}
+
+	buf := make(chan prometheus.Metric)
+	done := make(chan struct{})
+	var metrics []prometheus.Metric
+	go func() {
+		for m := range buf {
+			metrics = append(metrics, m)
+		}
+		close(done)
+	}()
+	err := e.scrapeRedisHost(buf)
+	close(buf)
+	<-done
+
+	stale := false
+	if err == nil {
+		e.staleSnapshots.set(e.redisAddr, staleSnapshot{metrics: metrics, collectedAt: time.Now()})
+	} else if snap, ok := e.staleSnapshots.get(e.redisAddr); ok {
+		e.logger().Warnf("scrape of %s failed, serving the metrics collected %s ago, err: %s", redactedAddr(e.redisAddr), time.Since(snap.collectedAt).Round(time.Millisecond), err)
+		metrics, stale = snap.metrics, true
+	}
+
+	for _, m := range metrics {
+		ch <- m
+	}
+	staleVal := 0.0
+	if stale {
+		staleVal = 1
+	}
+	e.registerConstMetricGauge(ch, "exporter_data_stale", staleVal)
+	return err
+}
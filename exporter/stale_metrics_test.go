@@ -0,0 +1,73 @@
+package exporter
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestServeStaleOnError(t *testing.T) {
+	e, _ := NewRedisExporter("unix:///tmp/doesnt.exist", Options{Namespace: "test", ServeStaleOnError: 30 * time.Second})
+
+	desc := prometheus.NewDesc("test_memory_used_bytes", "", nil, nil)
+	snapMetrics := []prometheus.Metric{prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, 1024)}
+
+	for _, tst := range []struct {
+		name      string
+		age       time.Duration
+		wantStale float64
+	}{
+		{name: "within the window", age: 10 * time.Second, wantStale: 1},
+		{name: "expired", age: time.Minute, wantStale: 0},
+	} {
+		t.Run(tst.name, func(t *testing.T) {
+			e.staleSnapshots.set(e.redisAddr, staleSnapshot{metrics: snapMetrics, collectedAt: time.Now().Add(-tst.age)})
+
+			chM := make(chan prometheus.Metric)
+			go func() {
+				e.Collect(chM)
+				close(chM)
+			}()
+
+			got := map[string]float64{}
+			for m := range chM {
+				d := m.Desc().String()
+				name := d[strings.Index(d, `"`)+1:]
+				name = name[:strings.Index(name, `"`)]
+				v := &dto.Metric{}
+				m.Write(v)
+				got[name] = v.GetGauge().GetValue()
+			}
+
+			if got["test_exporter_data_stale"] != tst.wantStale {
+				t.Errorf("expected exporter_data_stale = %f, got: %f", tst.wantStale, got["test_exporter_data_stale"])
+			}
+			if got["test_up"] != 0 {
+				t.Errorf("expected up = 0 while stale metrics are served, got: %f", got["test_up"])
+			}
+			if _, ok := got["test_memory_used_bytes"]; ok != (tst.wantStale == 1) {
+				t.Errorf("expected the stale metrics to be served: %t, got: %v", tst.wantStale == 1, got)
+			}
+		})
+	}
+}
+
+func TestStaleSnapshotsExpire(t *testing.T) {
+	s := newStaleSnapshots(time.Minute)
+	s.set("redis://old", staleSnapshot{collectedAt: time.Now().Add(-2 * time.Minute)})
+	s.set("redis://expired", staleSnapshot{collectedAt: time.Now().Add(-2 * time.Minute)})
+
+	if _, ok := s.get("redis://expired"); ok {
+		t.Errorf("expected no expired snapshot")
+	}
+	s.set("redis://new", staleSnapshot{collectedAt: time.Now()})
+	if _, ok := s.get("redis://new"); !ok {
+		t.Errorf("expected the new snapshot")
+	}
+	if len(s.snapshots) != 1 {
+		t.Errorf("expected the expired snapshots to be dropped, got: %v", s.snapshots)
+	}
+}
@@ -1,8 +1,11 @@
 package exporter
 
 import (
+	"fmt"
+	"path"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gomodule/redigo/redis"
 	"github.com/prometheus/client_golang/prometheus"
@@ -12,15 +15,17 @@ import (
 // All fields of the streamInfo struct must be exported
 // because of redis.ScanStruct (reflect) limitations
 type streamInfo struct {
-	Length            int64  `redis:"length"`
-	RadixTreeKeys     int64  `redis:"radix-tree-keys"`
-	RadixTreeNodes    int64  `redis:"radix-tree-nodes"`
-	LastGeneratedId   string `redis:"last-generated-id"`
-	Groups            int64  `redis:"groups"`
-	MaxDeletedEntryId string `redis:"max-deleted-entry-id"`
-	FirstEntryId      string
-	LastEntryId       string
-	StreamGroupsInfo  []streamGroupsInfo
+	Length               int64  `redis:"length"`
+	RadixTreeKeys        int64  `redis:"radix-tree-keys"`
+	RadixTreeNodes       int64  `redis:"radix-tree-nodes"`
+	LastGeneratedId      string `redis:"last-generated-id"`
+	Groups               int64  `redis:"groups"`
+	MaxDeletedEntryId    string `redis:"max-deleted-entry-id"`
+	EntriesAdded         int64  `redis:"entries-added"`
+	RecordedFirstEntryId string `redis:"recorded-first-entry-id"`
+	FirstEntryId         string
+	LastEntryId          string
+	StreamGroupsInfo     []streamGroupsInfo
 }
 
 type streamGroupsInfo struct {
@@ -46,7 +51,7 @@ func getStreamInfo(c redis.Conn, key string) (*streamInfo, error) {
 	}
 
 	// Scan slice to struct
-	var stream streamInfo
+	stream := streamInfo{EntriesAdded: -1}
 	if err := redis.ScanStruct(values, &stream); err != nil {
 		return nil, err
 	}
@@ -74,6 +79,54 @@ func getStreamInfo(c redis.Conn, key string) (*streamInfo, error) {
 	return &stream, nil
 }
 
+// getStreamInfoFull uses XINFO STREAM FULL, limited to count entries, and
+// XREVRANGE to get the first and last entry of the stream
+func getStreamInfoFull(c redis.Conn, key string, count int64) (*streamInfo, error) {
+	values, err := redis.Values(doRedisCmd(c, "XINFO", "STREAM", key, "FULL", "COUNT", count))
+	if err != nil {
+		return nil, err
+	}
+
+	// "entries" and "groups" are arrays in the FULL reply so they are
+	// handled separately and not passed on to ScanStruct
+	var scalars []interface{}
+	var entries, groups []interface{}
+	for i := 0; i+1 < len(values); i += 2 {
+		name, _ := redis.String(values[i], nil)
+		switch name {
+		case "entries":
+			entries, _ = redis.Values(values[i+1], nil)
+		case "groups":
+			groups, _ = redis.Values(values[i+1], nil)
+		default:
+			scalars = append(scalars, values[i], values[i+1])
+		}
+	}
+
+	stream := streamInfo{EntriesAdded: -1, Groups: int64(len(groups))}
+	if err := redis.ScanStruct(scalars, &stream); err != nil {
+		return nil, err
+	}
+
+	// recorded-first-entry-id was introduced in Redis 7.0
+	stream.FirstEntryId = stream.RecordedFirstEntryId
+	if stream.FirstEntryId == "" && len(entries) > 0 {
+		stream.FirstEntryId = getStreamEntryId(entries, 0)
+	}
+
+	if last, err := redis.Values(doRedisCmd(c, "XREVRANGE", key, "+", "-", "COUNT", 1)); err == nil && len(last) > 0 {
+		stream.LastEntryId = getStreamEntryId(last, 0)
+	}
+
+	stream.StreamGroupsInfo, err = scanStreamGroups(c, key)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debugf("getStreamInfoFull() stream: %#v", &stream)
+	return &stream, nil
+}
+
 func getStreamEntryId(redisValue []interface{}, index int) string {
 	if values, ok := redisValue[index].([]interface{}); !ok || len(values) < 2 {
 		log.Debugf("Failed to parse StreamEntryId")
@@ -103,14 +156,14 @@ func scanStreamGroups(c redis.Conn, stream string) ([]streamGroupsInfo, error) {
 	for _, g := range groups {
 		v, err := redis.Values(g, nil)
 		if err != nil {
-			log.Errorf("Couldn't convert group values for stream '%s': %s", stream, err)
+			log.Errorf("Couldn't convert group values for stream '%s': %s", printableKey(stream), err)
 			continue
 		}
 		log.Debugf("streamGroupsInfo value: %#v", v)
 
 		var group streamGroupsInfo
 		if err := redis.ScanStruct(v, &group); err != nil {
-			log.Errorf("Couldn't scan group in stream '%s': %s", stream, err)
+			log.Errorf("Couldn't scan group in stream '%s': %s", printableKey(stream), err)
 			continue
 		}
 
@@ -137,14 +190,14 @@ func scanStreamGroupConsumers(c redis.Conn, stream string, group string) ([]stre
 
 		v, err := redis.Values(c, nil)
 		if err != nil {
-			log.Errorf("Couldn't convert consumer values for group '%s' in stream '%s': %s", group, stream, err)
+			log.Errorf("Couldn't convert consumer values for group '%s' in stream '%s': %s", printableKey(group), printableKey(stream), err)
 			continue
 		}
 		log.Debugf("streamGroupConsumersInfo value: %#v", v)
 
 		var consumer streamGroupConsumersInfo
 		if err := redis.ScanStruct(v, &consumer); err != nil {
-			log.Errorf("Couldn't scan consumers for  group '%s' in stream '%s': %s", group, stream, err)
+			log.Errorf("Couldn't scan consumers for  group '%s' in stream '%s': %s", printableKey(group), printableKey(stream), err)
 			continue
 		}
 
@@ -155,6 +208,72 @@ func scanStreamGroupConsumers(c redis.Conn, stream string, group string) ([]stre
 	return result, nil
 }
 
+// streamConsumerFilter selects the stream consumers that are exported, patterns
+// have the form <group glob>:<consumer glob>, a leading "!" excludes matches
+type streamConsumerFilter struct {
+	include []string
+	exclude []string
+}
+
+func parseStreamConsumerFilter(filter string) (streamConsumerFilter, error) {
+	var res streamConsumerFilter
+	for _, p := range strings.Split(filter, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+
+		exclude := strings.HasPrefix(p, "!")
+		p = strings.TrimPrefix(p, "!")
+		if !strings.Contains(p, ":") {
+			p = p + ":*"
+		}
+		if _, err := path.Match(p, ""); err != nil {
+			return res, fmt.Errorf("invalid stream consumer filter pattern %q: %s", p, err)
+		}
+
+		if exclude {
+			res.exclude = append(res.exclude, p)
+		} else {
+			res.include = append(res.include, p)
+		}
+	}
+	return res, nil
+}
+
+func (f streamConsumerFilter) match(group, consumer string) bool {
+	name := group + ":" + consumer
+	for _, p := range f.exclude {
+		if ok, _ := path.Match(p, name); ok {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, p := range f.include {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// includeStreamConsumer returns whether the metrics of the consumer are exported
+// based on the consumer filter and the pending/idle thresholds
+func (e *Exporter) includeStreamConsumer(group string, c streamGroupConsumersInfo) bool {
+	if !e.streamConsumerFilter.match(group, c.Name) {
+		return false
+	}
+
+	minPending, minIdle := e.options.StreamsConsumerMinPending, e.options.StreamsConsumerMinIdle
+	if minPending <= 0 && minIdle <= 0 {
+		return true
+	}
+	return (minPending > 0 && c.Pending >= minPending) ||
+		(minIdle > 0 && time.Duration(c.Idle)*time.Millisecond >= minIdle)
+}
+
 func parseStreamItemId(id string) float64 {
 	if strings.TrimSpace(id) == "" {
 		return 0
@@ -172,58 +291,99 @@ func parseStreamItemId(id string) float64 {
 }
 
 func (e *Exporter) extractStreamMetrics(ch chan<- prometheus.Metric, c redis.Conn) {
-	streams, err := parseKeyArg(e.options.CheckStreams)
+	checks := e.checkKeysConfig()
+	streams, err := parseKeyArg(checks.CheckStreams)
 	if err != nil {
-		log.Errorf("Couldn't parse given stream keys: %s", err)
+		e.logger().Errorf("Couldn't parse given stream keys: %s", err)
 		return
 	}
 
-	singleStreams, err := parseKeyArg(e.options.CheckSingleStreams)
+	singleStreams, err := parseKeyArg(checks.CheckSingleStreams)
 	if err != nil {
-		log.Errorf("Couldn't parse check-single-streams: %s", err)
+		e.logger().Errorf("Couldn't parse check-single-streams: %s", err)
 		return
 	}
-	allStreams := append([]dbKeyPair{}, singleStreams...)
+	streamChecks := newSafetyCap(e.options.MaxCheckedStreams)
+	allStreams := capEntries(streamChecks, append([]dbKeyPair{}, singleStreams...))
 
-	scannedStreams, err := getKeysFromPatterns(c, streams, e.options.CheckKeysBatchSize)
+	scannedStreams, err := getKeysFromPatternsCapped(c, streams, e.options.CheckKeysBatchSize, streamChecks)
 	if err != nil {
-		log.Errorf("Error expanding key patterns: %s", err)
+		e.logger().Errorf("Error expanding key patterns: %s", err)
 	} else {
 		allStreams = append(allStreams, scannedStreams...)
 	}
+	if e.options.MaxCheckedStreams > 0 {
+		if streamChecks.truncated {
+			e.logger().Warnf("Stopped checking streams after max-checked-streams=%d streams", e.options.MaxCheckedStreams)
+		}
+		e.registerConstMetricGauge(ch, "stream_checks_truncated", streamChecks.truncatedValue())
+	}
+	consumers := newSafetyCap(e.options.MaxStreamConsumers)
+	if e.options.MaxStreamConsumers > 0 && !e.options.StreamsExcludeConsumerMetrics {
+		defer func() {
+			if consumers.truncated {
+				e.logger().Warnf("Stopped exporting stream consumers after max-stream-consumers=%d consumers", e.options.MaxStreamConsumers)
+			}
+			e.registerConstMetricGauge(ch, "stream_consumers_truncated", consumers.truncatedValue())
+		}()
+	}
 
-	log.Debugf("allStreams: %#v", allStreams)
+	e.logger().Debugf("allStreams: %#v", allStreams)
 	for _, k := range allStreams {
 		if _, err := doRedisCmd(c, "SELECT", k.db); err != nil {
-			log.Debugf("Couldn't select database '%s' when getting stream info", k.db)
+			e.logger().Debugf("Couldn't select database '%s' when getting stream info", k.db)
 			continue
 		}
-		info, err := getStreamInfo(c, k.key)
+		var info *streamInfo
+		if e.options.StreamsUseXinfoFull {
+			info, err = getStreamInfoFull(c, k.key, e.options.StreamsXinfoFullCount)
+		} else {
+			info, err = getStreamInfo(c, k.key)
+		}
 		if err != nil {
-			log.Errorf("couldn't get info for stream '%s', err: %s", k.key, err)
+			e.logger().Errorf("couldn't get info for stream '%s', err: %s", printableKey(k.key), err)
 			continue
 		}
 		dbLabel := "db" + k.db
-
-		e.registerConstMetricGauge(ch, "stream_length", float64(info.Length), dbLabel, k.key)
-		e.registerConstMetricGauge(ch, "stream_radix_tree_keys", float64(info.RadixTreeKeys), dbLabel, k.key)
-		e.registerConstMetricGauge(ch, "stream_radix_tree_nodes", float64(info.RadixTreeNodes), dbLabel, k.key)
-		e.registerConstMetricGauge(ch, "stream_last_generated_id", parseStreamItemId(info.LastGeneratedId), dbLabel, k.key)
-		e.registerConstMetricGauge(ch, "stream_groups", float64(info.Groups), dbLabel, k.key)
-		e.registerConstMetricGauge(ch, "stream_max_deleted_entry_id", parseStreamItemId(info.MaxDeletedEntryId), dbLabel, k.key)
-		e.registerConstMetricGauge(ch, "stream_first_entry_id", parseStreamItemId(info.FirstEntryId), dbLabel, k.key)
-		e.registerConstMetricGauge(ch, "stream_last_entry_id", parseStreamItemId(info.LastEntryId), dbLabel, k.key)
+		streamLabel := e.labelValue(ch, k.key)
+
+		e.registerConstMetricGauge(ch, "stream_length", float64(info.Length), dbLabel, streamLabel)
+		e.registerConstMetricGauge(ch, "stream_radix_tree_keys", float64(info.RadixTreeKeys), dbLabel, streamLabel)
+		e.registerConstMetricGauge(ch, "stream_radix_tree_nodes", float64(info.RadixTreeNodes), dbLabel, streamLabel)
+		e.registerConstMetricGauge(ch, "stream_last_generated_id", parseStreamItemId(info.LastGeneratedId), dbLabel, streamLabel)
+		e.registerConstMetricGauge(ch, "stream_groups", float64(info.Groups), dbLabel, streamLabel)
+		e.registerConstMetricGauge(ch, "stream_max_deleted_entry_id", parseStreamItemId(info.MaxDeletedEntryId), dbLabel, streamLabel)
+		e.registerConstMetricGauge(ch, "stream_first_entry_id", parseStreamItemId(info.FirstEntryId), dbLabel, streamLabel)
+		e.registerConstMetricGauge(ch, "stream_last_entry_id", parseStreamItemId(info.LastEntryId), dbLabel, streamLabel)
+		if info.EntriesAdded > -1 {
+			e.registerConstMetric(ch, "stream_entries_added_total", float64(info.EntriesAdded), prometheus.CounterValue, dbLabel, streamLabel)
+			// entries removed by XTRIM, MAXLEN/MINID or XDEL
+			e.registerConstMetricGauge(ch, "stream_trimmed_entries", float64(info.EntriesAdded-info.Length), dbLabel, streamLabel)
+		}
+		if info.Length > 0 && info.FirstEntryId != "" {
+			// stream IDs start with the millisecond timestamp of the entry
+			backlogAge := float64(time.Now().UnixMilli())/1e3 - parseStreamItemId(info.FirstEntryId)/1e3
+			e.registerConstMetricGauge(ch, "stream_backlog_age_seconds", max(backlogAge, 0), dbLabel, streamLabel)
+		}
 
 		for _, g := range info.StreamGroupsInfo {
-			e.registerConstMetricGauge(ch, "stream_group_consumers", float64(g.Consumers), dbLabel, k.key, g.Name)
-			e.registerConstMetricGauge(ch, "stream_group_messages_pending", float64(g.Pending), dbLabel, k.key, g.Name)
-			e.registerConstMetricGauge(ch, "stream_group_last_delivered_id", parseStreamItemId(g.LastDeliveredId), dbLabel, k.key, g.Name)
-			e.registerConstMetricGauge(ch, "stream_group_entries_read", float64(g.EntriesRead), dbLabel, k.key, g.Name)
-			e.registerConstMetricGauge(ch, "stream_group_lag", float64(g.Lag), dbLabel, k.key, g.Name)
+			groupLabel := e.labelValue(ch, g.Name)
+			e.registerConstMetricGauge(ch, "stream_group_consumers", float64(g.Consumers), dbLabel, streamLabel, groupLabel)
+			e.registerConstMetricGauge(ch, "stream_group_messages_pending", float64(g.Pending), dbLabel, streamLabel, groupLabel)
+			e.registerConstMetricGauge(ch, "stream_group_last_delivered_id", parseStreamItemId(g.LastDeliveredId), dbLabel, streamLabel, groupLabel)
+			e.registerConstMetricGauge(ch, "stream_group_entries_read", float64(g.EntriesRead), dbLabel, streamLabel, groupLabel)
+			e.registerConstMetricGauge(ch, "stream_group_lag", float64(g.Lag), dbLabel, streamLabel, groupLabel)
 			if !e.options.StreamsExcludeConsumerMetrics {
 				for _, c := range g.StreamGroupConsumersInfo {
-					e.registerConstMetricGauge(ch, "stream_group_consumer_messages_pending", float64(c.Pending), dbLabel, k.key, g.Name, c.Name)
-					e.registerConstMetricGauge(ch, "stream_group_consumer_idle_seconds", float64(c.Idle)/1e3, dbLabel, k.key, g.Name, c.Name)
+					if !e.includeStreamConsumer(g.Name, c) {
+						continue
+					}
+					if consumers.take(1) == 0 {
+						break
+					}
+					consumerLabel := e.labelValue(ch, c.Name)
+					e.registerConstMetricGauge(ch, "stream_group_consumer_messages_pending", float64(c.Pending), dbLabel, streamLabel, groupLabel, consumerLabel)
+					e.registerConstMetricGauge(ch, "stream_group_consumer_idle_seconds", float64(c.Idle)/1e3, dbLabel, streamLabel, groupLabel, consumerLabel)
 				}
 			}
 		}
@@ -1,13 +1,17 @@
 package exporter
 
 import (
+	"fmt"
 	"net/http/httptest"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gomodule/redigo/redis"
 	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -622,6 +626,41 @@ func TestStreamsExtractStreamMetricsExcludeConsumer(t *testing.T) {
 	}
 }
 
+func TestStreamConsumerFilter(t *testing.T) {
+	if _, err := NewRedisExporter("", Options{StreamsConsumerFilter: "orders:[a-"}); err == nil {
+		t.Errorf("expected error for invalid stream consumer filter")
+	}
+
+	for _, tst := range []struct {
+		opts     Options
+		group    string
+		consumer streamGroupConsumersInfo
+		want     bool
+	}{
+		{opts: Options{}, group: "orders", consumer: streamGroupConsumersInfo{Name: "worker-1"}, want: true},
+		{opts: Options{StreamsConsumerFilter: "orders:*"}, group: "orders", consumer: streamGroupConsumersInfo{Name: "worker-1"}, want: true},
+		{opts: Options{StreamsConsumerFilter: "orders"}, group: "orders", consumer: streamGroupConsumersInfo{Name: "worker-1"}, want: true},
+		{opts: Options{StreamsConsumerFilter: "orders:*"}, group: "billing", consumer: streamGroupConsumersInfo{Name: "worker-1"}, want: false},
+		{opts: Options{StreamsConsumerFilter: "!*:test-*"}, group: "orders", consumer: streamGroupConsumersInfo{Name: "test-1"}, want: false},
+		{opts: Options{StreamsConsumerFilter: "orders:*, !*:test-*"}, group: "orders", consumer: streamGroupConsumersInfo{Name: "worker-1"}, want: true},
+		{opts: Options{StreamsConsumerFilter: "orders:*, !*:test-*"}, group: "orders", consumer: streamGroupConsumersInfo{Name: "test-1"}, want: false},
+		{opts: Options{StreamsConsumerMinPending: 10}, group: "orders", consumer: streamGroupConsumersInfo{Name: "worker-1", Pending: 5}, want: false},
+		{opts: Options{StreamsConsumerMinPending: 10}, group: "orders", consumer: streamGroupConsumersInfo{Name: "worker-1", Pending: 10}, want: true},
+		{opts: Options{StreamsConsumerMinIdle: time.Minute}, group: "orders", consumer: streamGroupConsumersInfo{Name: "worker-1", Idle: 1000}, want: false},
+		{opts: Options{StreamsConsumerMinIdle: time.Minute}, group: "orders", consumer: streamGroupConsumersInfo{Name: "worker-1", Idle: 120000}, want: true},
+		{opts: Options{StreamsConsumerMinPending: 10, StreamsConsumerMinIdle: time.Minute}, group: "orders", consumer: streamGroupConsumersInfo{Name: "worker-1", Pending: 1, Idle: 120000}, want: true},
+		{opts: Options{StreamsConsumerFilter: "billing:*", StreamsConsumerMinPending: 10}, group: "orders", consumer: streamGroupConsumersInfo{Name: "worker-1", Pending: 100}, want: false},
+	} {
+		e, err := NewRedisExporter("", tst.opts)
+		if err != nil {
+			t.Fatalf("NewRedisExporter() err: %s", err)
+		}
+		if got := e.includeStreamConsumer(tst.group, tst.consumer); got != tst.want {
+			t.Errorf("includeStreamConsumer(%s, %#v) with options %#v: expected %t, got %t", tst.group, tst.consumer, tst.opts, tst.want, got)
+		}
+	}
+}
+
 func TestClusterStreamMetricsExtraction(t *testing.T) {
 	if os.Getenv("TEST_REDIS_CLUSTER_MASTER_URI") == "" {
 		t.Skipf("TEST_REDIS_CLUSTER_MASTER_URI not set - skipping cluster stream test")
@@ -751,3 +790,112 @@ func TestClusterStreamMetricsExtraction(t *testing.T) {
 		t.Logf("HTTP endpoint successfully returned metrics without cluster MOVED errors")
 	}
 }
+
+// fakeRedisConn returns canned replies keyed by the command and its arguments,
+// prefixReplies match commands starting with the given prefix
+type fakeRedisConn struct {
+	replies       map[string]interface{}
+	prefixReplies map[string]interface{}
+}
+
+func (c *fakeRedisConn) Close() error                      { return nil }
+func (c *fakeRedisConn) Err() error                        { return nil }
+func (c *fakeRedisConn) Send(string, ...interface{}) error { return nil }
+func (c *fakeRedisConn) Flush() error                      { return nil }
+func (c *fakeRedisConn) Receive() (interface{}, error)     { return nil, nil }
+
+func (c *fakeRedisConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	key := strings.TrimSpace(fmt.Sprintln(append([]interface{}{cmd}, args...)...))
+	if reply, ok := c.replies[key]; ok {
+		return reply, nil
+	}
+	for prefix, reply := range c.prefixReplies {
+		if strings.HasPrefix(key, prefix) {
+			return reply, nil
+		}
+	}
+	return nil, fmt.Errorf("ERR unexpected command: %s", key)
+}
+
+func TestStreamsGetStreamInfoFull(t *testing.T) {
+	entry := func(id string) []interface{} {
+		return []interface{}{[]byte(id), []interface{}{[]byte("field"), []byte("value")}}
+	}
+
+	c := &fakeRedisConn{replies: map[string]interface{}{
+		"XINFO STREAM mystream FULL COUNT 1": []interface{}{
+			[]byte("length"), int64(3),
+			[]byte("radix-tree-keys"), int64(1),
+			[]byte("radix-tree-nodes"), int64(2),
+			[]byte("last-generated-id"), []byte("1638006862418-0"),
+			[]byte("max-deleted-entry-id"), []byte("1638006862415-0"),
+			[]byte("entries-added"), int64(4),
+			[]byte("recorded-first-entry-id"), []byte("1638006862416-0"),
+			[]byte("entries"), []interface{}{entry("1638006862416-0")},
+			[]byte("groups"), []interface{}{[]interface{}{}, []interface{}{}},
+		},
+		"XREVRANGE mystream + - COUNT 1": []interface{}{entry("1638006862418-0")},
+		"XINFO GROUPS mystream":          []interface{}{},
+	}}
+
+	info, err := getStreamInfoFull(c, "mystream", 1)
+	if err != nil {
+		t.Fatalf("getStreamInfoFull() err: %s", err)
+	}
+
+	want := streamInfo{
+		Length:               3,
+		RadixTreeKeys:        1,
+		RadixTreeNodes:       2,
+		LastGeneratedId:      "1638006862418-0",
+		Groups:               2,
+		MaxDeletedEntryId:    "1638006862415-0",
+		EntriesAdded:         4,
+		RecordedFirstEntryId: "1638006862416-0",
+		FirstEntryId:         "1638006862416-0",
+		LastEntryId:          "1638006862418-0",
+	}
+	if !reflect.DeepEqual(*info, want) {
+		t.Errorf("getStreamInfoFull() mismatch.\nActual: %#v\nExpected: %#v", *info, want)
+	}
+}
+
+func TestStreamsBacklogMetrics(t *testing.T) {
+	firstEntryTs := time.Now().Add(-time.Hour).UnixMilli()
+	c := &fakeRedisConn{replies: map[string]interface{}{
+		"SELECT 0": "OK",
+		"XINFO STREAM mystream": []interface{}{
+			[]byte("length"), int64(3),
+			[]byte("last-generated-id"), []byte(fmt.Sprintf("%d-0", firstEntryTs+2)),
+			[]byte("entries-added"), int64(10),
+			[]byte("first-entry"), []interface{}{[]byte(fmt.Sprintf("%d-0", firstEntryTs)), []interface{}{}},
+		},
+		"XINFO GROUPS mystream": []interface{}{},
+	}}
+
+	e, _ := NewRedisExporter("", Options{Namespace: "test", CheckSingleStreams: "mystream"})
+
+	chM := make(chan prometheus.Metric)
+	go func() {
+		e.extractStreamMetrics(chM, c)
+		close(chM)
+	}()
+
+	got := map[string]float64{}
+	for m := range chM {
+		d := &dto.Metric{}
+		m.Write(d)
+		for _, name := range []string{"stream_backlog_age_seconds", "stream_trimmed_entries", "stream_entries_added_total"} {
+			if strings.Contains(m.Desc().String(), `"test_`+name+`"`) {
+				got[name] = d.GetGauge().GetValue() + d.GetCounter().GetValue()
+			}
+		}
+	}
+
+	if got["stream_trimmed_entries"] != 7 || got["stream_entries_added_total"] != 10 {
+		t.Errorf("unexpected stream metrics: %#v", got)
+	}
+	if age := got["stream_backlog_age_seconds"]; age < 3599 || age > 3700 {
+		t.Errorf("unexpected stream backlog age: %f", age)
+	}
+}
@@ -0,0 +1,76 @@
+package exporter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// metricSubsystems maps the collectors whose namespace can be overridden to the
+// names (or name prefixes ending in "_") of the metrics they export,
+// metrics of all other collectors always use the namespace option
+var metricSubsystems = map[string][]string{
+	"clients":    {"connected_client_", "connected_clients_skipped", "blocked_clients_by_command", "clients_by_library"},
+	"key_groups": {"key_group_", "number_of_distinct_key_groups", "last_key_groups_scrape_duration_milliseconds"},
+	"keys":       {"key_size", "key_size_", "key_value", "key_value_", "key_memory_usage_bytes", "key_memory_usage_bytes_", "key_dump_size_bytes", "key_dump_size_bytes_", "key_pattern_", "key_sample_", "key_scan_", "key_checks_truncated", "key_watchlist_entries", "keys_count"},
+	"probes":     {"probe_"},
+	"script":     {"script_"},
+	"search":     {"search_index_"},
+	"sentinel":   {"sentinel_"},
+	"slowlog":    {"slowlog_", "last_slow_execution_duration_seconds"},
+	"streams":    {"stream_"},
+}
+
+// metricSubsystem returns the subsystem of metricName or "" if its namespace can't be overridden
+func metricSubsystem(metricName string) string {
+	for subsystem, names := range metricSubsystems {
+		for _, n := range names {
+			if n == metricName || (strings.HasSuffix(n, "_") && strings.HasPrefix(metricName, n)) {
+				return subsystem
+			}
+		}
+	}
+	return ""
+}
+
+// metricNamespace returns the namespace of metricName, the namespace of its
+// subsystem if it's overridden or the namespace option otherwise
+func (e *Exporter) metricNamespace(metricName string) string {
+	if ns, ok := e.options.SubsystemNamespaces[metricSubsystem(metricName)]; ok {
+		return ns
+	}
+	return e.options.Namespace
+}
+
+// ParseSubsystemNamespaces parses a comma separated list of subsystem=namespace
+// pairs like "keys=app_keys,streams=app_streams", an empty namespace drops the prefix
+func ParseSubsystemNamespaces(s string) (map[string]string, error) {
+	res := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		subsystem, ns, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid namespace override %q, expected subsystem=namespace", pair)
+		}
+		subsystem, ns = strings.TrimSpace(subsystem), strings.TrimSpace(ns)
+		if _, ok := metricSubsystems[subsystem]; !ok {
+			return nil, fmt.Errorf("unknown subsystem %q, valid subsystems: %s", subsystem, strings.Join(subsystemNames(), ", "))
+		}
+		if ns != "" && !validMetricNameRE.MatchString(ns) {
+			return nil, fmt.Errorf("invalid namespace %q for subsystem %s", ns, subsystem)
+		}
+		res[subsystem] = ns
+	}
+	return res, nil
+}
+
+func subsystemNames() []string {
+	names := make([]string, 0, len(metricSubsystems))
+	for n := range metricSubsystems {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
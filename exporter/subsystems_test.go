@@ -0,0 +1,72 @@
+package exporter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestParseSubsystemNamespaces(t *testing.T) {
+	got, err := ParseSubsystemNamespaces(" keys=app_keys, streams=,,key_groups=app:groups")
+	if err != nil {
+		t.Fatalf("ParseSubsystemNamespaces() err: %s", err)
+	}
+	want := map[string]string{"keys": "app_keys", "streams": "", "key_groups": "app:groups"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %#v, want: %#v", got, want)
+	}
+
+	for _, s := range []string{"keys", "info=app", "keys=app-keys"} {
+		if _, err := ParseSubsystemNamespaces(s); err == nil {
+			t.Errorf("expected error for %q", s)
+		}
+	}
+}
+
+func TestMetricSubsystem(t *testing.T) {
+	for name, want := range map[string]string{
+		"key_size":                      "keys",
+		"key_group_count":               "key_groups",
+		"number_of_distinct_key_groups": "key_groups",
+		"stream_length":                 "streams",
+		"connected_client_info":         "clients",
+		"connected_clients":             "",
+		"memory_used_bytes":             "",
+		"exporter_last_scrape_error":    "",
+	} {
+		if got := metricSubsystem(name); got != want {
+			t.Errorf("metricSubsystem(%s) = %q, want: %q", name, got, want)
+		}
+	}
+}
+
+func TestSubsystemNamespaces(t *testing.T) {
+	e, _ := NewRedisExporter("", Options{
+		Namespace:           "test",
+		SubsystemNamespaces: map[string]string{"keys": "app_keys", "streams": ""},
+		MetricMapping:       map[string]MetricMapping{"key_value": {Name: "value"}},
+	})
+
+	for metric, want := range map[string]string{
+		"key_size":      `"app_keys_key_size"`,
+		"key_value":     `"app_keys_value"`,
+		"stream_length": `"stream_length"`,
+		"db_keys":       `"test_db_keys"`,
+	} {
+		if got := e.metricDescriptions[metric].String(); !strings.Contains(got, want) {
+			t.Errorf("%s: got %s, want: %s", metric, got, want)
+		}
+	}
+
+	chM := make(chan prometheus.Metric, 10)
+	e.registerConstMetricGauge(chM, "connected_clients_skipped", 1)
+	e.registerConstMetricGauge(chM, "uptime_in_seconds", 1)
+	close(chM)
+	for m := range chM {
+		if !strings.Contains(m.Desc().String(), `"test_`) {
+			t.Errorf("expected the default namespace, got: %s", m.Desc())
+		}
+	}
+}
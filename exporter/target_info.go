@@ -0,0 +1,39 @@
+package exporter
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// registerTargetInventory exports exporter_target_info for the redis-addr target and
+// all targets of the targets file, whether their scrapes succeed or not, so the
+// intended fleet can be listed and compared with the targets that have data
+func (e *Exporter) registerTargetInventory(ch chan<- prometheus.Metric) {
+	if e.targetExporter {
+		return
+	}
+
+	// created here instead of with the other descriptions as the labels of
+	// the targets file are const labels of the exporters of single targets
+	if _, ok := e.addMetricDescription("exporter_target_info", "",
+		"Info about the targets the exporter is configured to scrape, exported whether their scrapes succeed or not",
+		[]string{"target", "alias", "source"}); !ok {
+		return
+	}
+
+	if e.redisAddr != "" {
+		e.registerConstMetricGauge(ch, "exporter_target_info", 1, redactedAddr(e.redisAddr), "", "redis-addr")
+	}
+
+	if e.scheduler == nil {
+		return
+	}
+	seen := map[string]bool{}
+	for _, t := range e.scheduler.targetList() {
+		addr, _, err := parseTarget(t.Addr)
+		if err != nil || seen[addr] {
+			continue
+		}
+		seen[addr] = true
+		e.registerConstMetricGauge(ch, "exporter_target_info", 1, redactedAddr(addr), t.Labels["alias"], "targets-file")
+	}
+}
@@ -0,0 +1,113 @@
+package exporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+// targetGroup is an entry of a targets file, it uses the same format as
+// Prometheus' file_sd_configs and the /discover-cluster-nodes endpoint
+type targetGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels"`
+}
+
+// Target is a redis instance listed in a targets file together with
+// the static labels that are attached to all of its metrics
+type Target struct {
+	Addr   string
+	Labels map[string]string
+}
+
+// key identifies the target by its address, including the user, and its labels
+func (t Target) key() string {
+	names := make([]string, 0, len(t.Labels))
+	for name := range t.Labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString(t.Addr)
+	for _, name := range names {
+		fmt.Fprintf(&b, "\x00%s=%s", name, t.Labels[name])
+	}
+	return b.String()
+}
+
+var labelNameRE = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// validateConstLabels checks the names of the static labels attached to all metrics of an exporter
+func validateConstLabels(labels map[string]string) error {
+	for name := range labels {
+		if !labelNameRE.MatchString(name) || strings.HasPrefix(name, "__") {
+			return fmt.Errorf("invalid label name: %q", name)
+		}
+	}
+	return nil
+}
+
+// constLabels returns the const labels of a metric with the variable labels labels, base and
+// the static labels of the exporter (e.g. of the targets file). Static labels named like a label
+// of the metric are renamed to exported_<name> on that metric only, like Prometheus renames
+// the target labels that collide with the labels of the scraped series
+func constLabels(static map[string]string, base prometheus.Labels, labels []string) prometheus.Labels {
+	if len(static) == 0 {
+		return base
+	}
+
+	res := prometheus.Labels{}
+	for name, value := range base {
+		res[name] = value
+	}
+	for name, value := range static {
+		if _, ok := res[name]; ok || slices.Contains(labels, name) {
+			name = "exported_" + name
+		}
+		res[name] = value
+	}
+	return res
+}
+
+// LoadTargetsFile reads a targets file and returns the list of targets
+func LoadTargetsFile(targetsFile string) ([]Target, error) {
+	log.Debugf("start load targets file: %s", targetsFile)
+	bytes, err := os.ReadFile(targetsFile)
+	if err != nil {
+		log.Warnf("load targets file failed: %s", err)
+		return nil, err
+	}
+
+	var groups []targetGroup
+	if err := json.Unmarshal(bytes, &groups); err != nil {
+		log.Warnf("targets file format error: %s", err)
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	var targets []Target
+	for _, g := range groups {
+		if err := validateConstLabels(g.Labels); err != nil {
+			return nil, fmt.Errorf("invalid labels in targets file: %w", err)
+		}
+
+		for _, t := range g.Targets {
+			if t == "" || seen[t] {
+				continue
+			}
+			seen[t] = true
+			targets = append(targets, Target{Addr: t, Labels: g.Labels})
+		}
+	}
+
+	log.Debugf("Loaded %d targets from %s", len(targets), targetsFile)
+	return targets, nil
+}
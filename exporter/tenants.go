@@ -0,0 +1,147 @@
+package exporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+// Tenant is a configuration of the tenants file served at /tenants/<name>/metrics,
+// unset fields use the value of the command line
+type Tenant struct {
+	Name      string `json:"name"`
+	RedisAddr string `json:"redis_addr"`
+	User      string `json:"redis_user"`
+	Password  string `json:"redis_password"`
+	Namespace string `json:"namespace"`
+
+	// set to require other credentials than those of web.basic-auth-*
+	BasicAuthUsername     string `json:"basic_auth_username"`
+	BasicAuthPassword     string `json:"basic_auth_password"`
+	BasicAuthHashPassword string `json:"basic_auth_hash_password"`
+
+	CheckKeys          string `json:"check_keys"`
+	CheckSingleKeys    string `json:"check_single_keys"`
+	CountKeys          string `json:"count_keys"`
+	CheckStreams       string `json:"check_streams"`
+	CheckSingleStreams string `json:"check_single_streams"`
+
+	InclConfigMetrics              *bool `json:"include_config_metrics"`
+	InclModulesMetrics             *bool `json:"include_modules_metrics"`
+	InclSystemMetrics              *bool `json:"include_system_metrics"`
+	InclACLLogMetrics              *bool `json:"include_acl_log_metrics"`
+	InclSearchIndexesMetrics       *bool `json:"include_search_indexes_metrics"`
+	ExcludeLatencyHistogramMetrics *bool `json:"exclude_latency_histogram_metrics"`
+	RedisMetricsOnly               *bool `json:"redis_metrics_only"`
+}
+
+var tenantNameRE = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+// LoadTenantsFile reads the tenants file, a JSON list of tenants with unique names
+func LoadTenantsFile(tenantsFile string) ([]Tenant, error) {
+	log.Debugf("start load tenants file: %s", tenantsFile)
+	bytes, err := os.ReadFile(tenantsFile)
+	if err != nil {
+		log.Warnf("load tenants file failed: %s", err)
+		return nil, err
+	}
+
+	var tenants []Tenant
+	if err := json.Unmarshal(bytes, &tenants); err != nil {
+		log.Warnf("tenants file format error: %s", err)
+		return nil, err
+	}
+
+	names := map[string]bool{}
+	for _, t := range tenants {
+		if !tenantNameRE.MatchString(t.Name) || names[t.Name] {
+			return nil, fmt.Errorf("tenant names must be unique and only use letters, digits, _, . and -, got: %q", t.Name)
+		}
+		names[t.Name] = true
+
+		if t.RedisAddr == "" {
+			return nil, fmt.Errorf("tenant %s: redis_addr must be set", t.Name)
+		}
+		if t.BasicAuthPassword != "" && t.BasicAuthHashPassword != "" {
+			return nil, fmt.Errorf("tenant %s: only one of basic_auth_password and basic_auth_hash_password can be set", t.Name)
+		}
+	}
+
+	log.Infof("Loaded %d tenants from %s", len(tenants), tenantsFile)
+	return tenants, nil
+}
+
+func tenantMetricsPath(name string) string {
+	return "/tenants/" + name + "/metrics"
+}
+
+// options returns the options of the command line with the settings of t
+func (t Tenant) options(opts Options) Options {
+	opts.Tenants = nil
+	opts.CheckKeysAPIFile = ""
+	opts.CounterStateFile = ""
+	opts.TargetsFile = ""
+	opts.Registry = prometheus.NewRegistry()
+	opts.MetricsPath = tenantMetricsPath(t.Name)
+
+	opts.User, opts.Password = t.User, t.Password
+	if t.Namespace != "" {
+		opts.Namespace = t.Namespace
+	}
+	if t.BasicAuthUsername != "" {
+		opts.BasicAuthUsername = t.BasicAuthUsername
+		opts.BasicAuthPassword = t.BasicAuthPassword
+		opts.BasicAuthHashPassword = t.BasicAuthHashPassword
+	}
+
+	for _, s := range []struct {
+		val string
+		opt *string
+	}{
+		{t.CheckKeys, &opts.CheckKeys},
+		{t.CheckSingleKeys, &opts.CheckSingleKeys},
+		{t.CountKeys, &opts.CountKeys},
+		{t.CheckStreams, &opts.CheckStreams},
+		{t.CheckSingleStreams, &opts.CheckSingleStreams},
+	} {
+		if s.val != "" {
+			*s.opt = s.val
+		}
+	}
+
+	for _, b := range []struct {
+		val *bool
+		opt *bool
+	}{
+		{t.InclConfigMetrics, &opts.InclConfigMetrics},
+		{t.InclModulesMetrics, &opts.InclModulesMetrics},
+		{t.InclSystemMetrics, &opts.InclSystemMetrics},
+		{t.InclACLLogMetrics, &opts.InclACLLogMetrics},
+		{t.InclSearchIndexesMetrics, &opts.InclSearchIndexesMetrics},
+		{t.ExcludeLatencyHistogramMetrics, &opts.ExcludeLatencyHistogramMetrics},
+		{t.RedisMetricsOnly, &opts.RedisMetricsOnly},
+	} {
+		if b.val != nil {
+			*b.opt = *b.val
+		}
+	}
+	return opts
+}
+
+// initTenants creates an exporter with its own registry for each tenant of the Tenants option
+func (e *Exporter) initTenants() error {
+	e.tenants = make(map[string]*Exporter, len(e.options.Tenants))
+	for _, t := range e.options.Tenants {
+		exp, err := NewRedisExporter(t.RedisAddr, t.options(e.options))
+		if err != nil {
+			return fmt.Errorf("tenant %s: %w", t.Name, err)
+		}
+		e.tenants[exp.options.MetricsPath] = exp
+		e.logger().Infof("Providing the metrics of tenant %s at %s", t.Name, exp.options.MetricsPath)
+	}
+	return nil
+}
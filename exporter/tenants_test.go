@@ -0,0 +1,90 @@
+package exporter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestLoadTenantsFile(t *testing.T) {
+	for _, tst := range []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{name: "valid", content: `[{"name": "team-a", "redis_addr": "redis://a:6379"}, {"name": "team_b", "redis_addr": "redis://b:6379", "include_config_metrics": false}]`},
+		{name: "duplicate name", content: `[{"name": "a", "redis_addr": "redis://a:6379"}, {"name": "a", "redis_addr": "redis://b:6379"}]`, wantErr: true},
+		{name: "invalid name", content: `[{"name": "a/b", "redis_addr": "redis://a:6379"}]`, wantErr: true},
+		{name: "missing addr", content: `[{"name": "a"}]`, wantErr: true},
+		{name: "two passwords", content: `[{"name": "a", "redis_addr": "redis://a:6379", "basic_auth_password": "x", "basic_auth_hash_password": "y"}]`, wantErr: true},
+		{name: "invalid json", content: `{`, wantErr: true},
+	} {
+		t.Run(tst.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "tenants.json")
+			if err := os.WriteFile(file, []byte(tst.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadTenantsFile(file); (err != nil) != tst.wantErr {
+				t.Errorf("LoadTenantsFile() err: %v, expected an error: %t", err, tst.wantErr)
+			}
+		})
+	}
+}
+
+func TestTenants(t *testing.T) {
+	disabled := false
+	e, err := NewRedisExporter("redis://localhost:6379", Options{
+		Namespace:         "test",
+		Registry:          prometheus.NewRegistry(),
+		BasicAuthUsername: "admin",
+		BasicAuthPassword: "pwd",
+		InclConfigMetrics: true,
+		Tenants: []Tenant{
+			{Name: "team-a", RedisAddr: "redis://localhost:6380", Namespace: "team_a", BasicAuthUsername: "a", BasicAuthPassword: "a-pwd", InclConfigMetrics: &disabled},
+			{Name: "team-b", RedisAddr: "redis://localhost:6381", CheckKeys: "db0=b:*"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewRedisExporter() err: %s", err)
+	}
+
+	a, b := e.tenants["/tenants/team-a/metrics"], e.tenants["/tenants/team-b/metrics"]
+	if a == nil || b == nil {
+		t.Fatalf("expected both tenants, got: %v", e.tenants)
+	}
+	if a.redisAddr != "redis://localhost:6380" || a.options.Namespace != "team_a" || a.options.InclConfigMetrics {
+		t.Errorf("expected the settings of team-a, got: %s %+v", a.redisAddr, a.options)
+	}
+	if b.options.Namespace != "test" || b.options.CheckKeys != "db0=b:*" || !b.options.InclConfigMetrics || b.options.BasicAuthUsername != "admin" {
+		t.Errorf("expected team-b to use the main options except check-keys, got: %+v", b.options)
+	}
+
+	for _, tst := range []struct {
+		path     string
+		user     string
+		password string
+		want     int
+	}{
+		{path: "/tenants/team-a/metrics", user: "a", password: "a-pwd", want: http.StatusOK},
+		{path: "/tenants/team-a/metrics", user: "admin", password: "pwd", want: http.StatusUnauthorized},
+		{path: "/tenants/team-b/metrics", user: "admin", password: "pwd", want: http.StatusOK},
+		{path: "/metrics", user: "a", password: "a-pwd", want: http.StatusUnauthorized},
+		{path: "/tenants/team-c/metrics", user: "admin", password: "pwd", want: http.StatusOK}, // the landing page
+	} {
+		req := httptest.NewRequest(http.MethodGet, tst.path, nil)
+		req.SetBasicAuth(tst.user, tst.password)
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, req)
+		if w.Code != tst.want {
+			t.Errorf("%s as %s: expected %d, got: %d", tst.path, tst.user, tst.want, w.Code)
+		}
+		if tst.path == "/tenants/team-a/metrics" && w.Code == http.StatusOK && !strings.Contains(w.Body.String(), "team_a_up ") {
+			t.Errorf("expected the metrics of team-a, got: %s", w.Body.String())
+		}
+	}
+}
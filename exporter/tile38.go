@@ -5,13 +5,12 @@ import (
 
 	"github.com/gomodule/redigo/redis"
 	"github.com/prometheus/client_golang/prometheus"
-	log "github.com/sirupsen/logrus"
 )
 
 func (e *Exporter) extractTile38Metrics(ch chan<- prometheus.Metric, c redis.Conn) {
 	info, err := redis.Strings(doRedisCmd(c, "SERVER", "EXT"))
 	if err != nil {
-		log.Errorf("extractTile38Metrics() err: %s", err)
+		e.logger().Errorf("extractTile38Metrics() err: %s", err)
 		return
 	}
 
@@ -22,7 +21,7 @@ func (e *Exporter) extractTile38Metrics(ch chan<- prometheus.Metric, c redis.Con
 		}
 
 		fieldValue := info[i+1]
-		log.Debugf("tile38   key:%s   val:%s", fieldKey, fieldValue)
+		e.logger().Debugf("tile38   key:%s   val:%s", fieldKey, fieldValue)
 
 		if !e.includeMetric(fieldKey) {
 			continue
@@ -0,0 +1,144 @@
+package exporter
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/oliver006/redis_exporter/exporter"
+
+// scrapeTrace holds the spans of the current scrape, the collector span is a child
+// of the scrape span and is ended when the next collector starts
+type scrapeTrace struct {
+	ctx          context.Context
+	scrape       trace.Span
+	collectorCtx context.Context
+	collector    trace.Span
+}
+
+// scrapeDurationMetric is the exporter_scrape_duration_seconds summary, or histogram if tracing is enabled
+type scrapeDurationMetric interface {
+	prometheus.Metric
+	prometheus.Observer
+}
+
+// newScrapeDurationHistogram returns the exporter_scrape_duration_seconds histogram used while tracing
+// is enabled, it carries the trace IDs of the scrapes as exemplars which summaries don't support
+func newScrapeDurationHistogram(namespace string, constLabels prometheus.Labels) prometheus.Histogram {
+	return prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace:   namespace,
+		Name:        "exporter_scrape_duration_seconds",
+		Help:        "Durations of scrapes by the exporter",
+		Buckets:     prometheus.DefBuckets,
+		ConstLabels: constLabels,
+	})
+}
+
+// startScrapeTrace starts the span of a scrape, a child of the span of the scraper if
+// scrapeCtx carries the trace context of its request
+func (e *Exporter) startScrapeTrace(scrapeCtx context.Context) {
+	remote := trace.SpanContextFromContext(scrapeCtx)
+	if e.tracer == nil {
+		// without tracing the log lines of the scrape can still be found by the trace ID of the scraper
+		e.logTraceID(remote)
+		return
+	}
+
+	// the span doesn't end when the scrape is cancelled, only the trace context is kept
+	ctx, span := e.tracer.Start(trace.ContextWithRemoteSpanContext(context.Background(), remote), "scrape",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("redis.target", redactedAddr(e.redisAddr))),
+	)
+	e.trace = &scrapeTrace{ctx: ctx, scrape: span}
+
+	// the log lines of the scrape can be looked up by the trace ID
+	e.logTraceID(span.SpanContext())
+}
+
+func (e *Exporter) logTraceID(sc trace.SpanContext) {
+	if sc.HasTraceID() && e.logBase != nil {
+		e.logBase = e.logBase.WithField("trace_id", sc.TraceID().String())
+		e.logEntry.Store(e.logBase)
+	}
+}
+
+// traceCollector ends the span of the previous collector and starts the span of collector
+func (e *Exporter) traceCollector(collector string) {
+	if e.trace == nil {
+		return
+	}
+	if e.trace.collector != nil {
+		e.trace.collector.End()
+	}
+	e.trace.collectorCtx, e.trace.collector = e.tracer.Start(e.trace.ctx, collector,
+		trace.WithAttributes(attribute.String("collector", collector)),
+	)
+}
+
+// traceDial adds the connect and auth spans of the last connection attempt of d
+// to the span of the current collector
+func (e *Exporter) traceDial(d *dialTimer) {
+	if e.trace == nil || e.trace.collector == nil {
+		return
+	}
+
+	start, connected, done := d.connectTimes()
+	if start.IsZero() {
+		return
+	}
+	end := connected
+	if end.IsZero() {
+		end = time.Now()
+	}
+	_, span := e.tracer.Start(e.trace.collectorCtx, "connect", trace.WithTimestamp(start))
+	if connected.IsZero() {
+		span.SetStatus(codes.Error, "connection failed")
+	}
+	span.End(trace.WithTimestamp(end))
+
+	if !connected.IsZero() {
+		end = done
+		if end.IsZero() {
+			end = time.Now()
+		}
+		_, span = e.tracer.Start(e.trace.collectorCtx, "auth", trace.WithTimestamp(connected))
+		if done.IsZero() {
+			span.SetStatus(codes.Error, "connection not established")
+		}
+		span.End(trace.WithTimestamp(end))
+	}
+}
+
+// endScrapeTrace ends the spans of the scrape and returns the span context of the scrape span
+func (e *Exporter) endScrapeTrace(err error) trace.SpanContext {
+	if e.trace == nil {
+		return trace.SpanContext{}
+	}
+
+	if e.trace.collector != nil {
+		e.trace.collector.End()
+	}
+	if err != nil {
+		e.trace.scrape.RecordError(err)
+		e.trace.scrape.SetStatus(codes.Error, err.Error())
+	}
+	e.trace.scrape.End()
+
+	sc := e.trace.scrape.SpanContext()
+	e.trace = nil
+	return sc
+}
+
+// observeScrapeDuration records the duration of a scrape with the trace ID of sampled scrapes as exemplar
+func (e *Exporter) observeScrapeDuration(took float64, sc trace.SpanContext) {
+	if eo, ok := e.scrapeDuration.(prometheus.ExemplarObserver); ok && sc.IsSampled() {
+		eo.ObserveWithExemplar(took, prometheus.Labels{"trace_id": sc.TraceID().String()})
+		return
+	}
+	e.scrapeDuration.Observe(took)
+}
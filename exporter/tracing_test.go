@@ -0,0 +1,160 @@
+package exporter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestScrapeTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	// nothing listens on port 1, the connection fails
+	e, _ := NewRedisExporter("redis://127.0.0.1:1", Options{Namespace: "test", TracerProvider: tp})
+
+	chM := make(chan prometheus.Metric)
+	go func() {
+		e.Collect(chM)
+		close(chM)
+	}()
+	for range chM {
+	}
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, s := range recorder.Ended() {
+		spans[s.Name()] = s
+	}
+	for _, name := range []string{"scrape", "connection", "connect"} {
+		if _, ok := spans[name]; !ok {
+			t.Fatalf("span %s missing, got: %v", name, spans)
+		}
+	}
+
+	scrape := spans["scrape"]
+	if scrape.Status().Code != codes.Error {
+		t.Errorf("expected the scrape span to have status error, got: %v", scrape.Status())
+	}
+	if parent := spans["connection"].Parent(); parent.SpanID() != scrape.SpanContext().SpanID() {
+		t.Errorf("expected the collector span to be a child of the scrape span")
+	}
+	if parent := spans["connect"].Parent(); parent.SpanID() != spans["connection"].SpanContext().SpanID() {
+		t.Errorf("expected the connect span to be a child of the collector span")
+	}
+
+	m := &dto.Metric{}
+	if err := e.scrapeDuration.Write(m); err != nil {
+		t.Fatalf("Write() err: %s", err)
+	}
+	var traceIDs []string
+	for _, b := range m.GetHistogram().GetBucket() {
+		if ex := b.GetExemplar(); ex != nil {
+			for _, l := range ex.GetLabel() {
+				traceIDs = append(traceIDs, l.GetValue())
+			}
+		}
+	}
+	if len(traceIDs) == 0 || traceIDs[0] != scrape.SpanContext().TraceID().String() {
+		t.Errorf("expected the trace ID %s as exemplar, got: %v", scrape.SpanContext().TraceID(), traceIDs)
+	}
+}
+
+func TestScrapeTracingDisabled(t *testing.T) {
+	e, _ := NewRedisExporter("redis://127.0.0.1:1", Options{Namespace: "test"})
+	if e.tracer != nil {
+		t.Fatalf("expected tracing to be disabled without a tracer provider")
+	}
+	if _, ok := e.scrapeDuration.(prometheus.Summary); !ok {
+		t.Fatalf("expected exporter_scrape_duration_seconds to be a summary without tracing, got: %T", e.scrapeDuration)
+	}
+
+	descs := make(chan *prometheus.Desc, 1000)
+	e.Describe(descs)
+	close(descs)
+	for d := range descs {
+		if d == nil {
+			t.Fatalf("unexpected nil description")
+		}
+	}
+}
+
+func TestScrapeTraceContextPropagation(t *testing.T) {
+	const (
+		traceID     = "4bf92f3577b34da6a3ce929d0e0e4736"
+		spanID      = "00f067aa0ba902b7"
+		traceparent = "00-" + traceID + "-" + spanID + "-01"
+	)
+
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	e, _ := NewRedisExporter("redis://127.0.0.1:1", Options{Namespace: "test", TracerProvider: tp, Registry: prometheus.NewRegistry()})
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("traceparent", traceparent)
+	e.ServeHTTP(httptest.NewRecorder(), req)
+
+	var scrape sdktrace.ReadOnlySpan
+	for _, s := range recorder.Ended() {
+		if s.Name() == "scrape" {
+			scrape = s
+		}
+	}
+	if scrape == nil {
+		t.Fatalf("scrape span missing")
+	}
+	if got := scrape.SpanContext().TraceID().String(); got != traceID {
+		t.Errorf("expected the scrape to continue trace %s, got: %s", traceID, got)
+	}
+	if got := scrape.Parent(); got.SpanID().String() != spanID || !got.IsRemote() {
+		t.Errorf("expected the span of the scraper as remote parent, got: %v", got)
+	}
+
+	// without a traceparent header the scrape starts a new trace
+	recorder.Reset()
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, s := range recorder.Ended() {
+		if s.Name() == "scrape" && (s.Parent().IsValid() || s.SpanContext().TraceID().String() == traceID) {
+			t.Errorf("expected a new trace, got parent: %v", s.Parent())
+		}
+	}
+}
+
+func TestScrapeRequestsTraceContext(t *testing.T) {
+	s := newScrapeRequests()
+	extract := func(traceparent string) context.Context {
+		return propagation.TraceContext{}.Extract(context.Background(), propagation.MapCarrier{"traceparent": traceparent})
+	}
+
+	done := s.add(context.Background())
+	ctx, cancel := s.context()
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		t.Errorf("expected no trace context, got: %v", sc)
+	}
+	cancel()
+
+	doneFirst := s.add(extract("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"))
+	doneSecond := s.add(extract("00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"))
+	ctx, cancel = s.context()
+	if sc := trace.SpanContextFromContext(ctx); sc.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("expected the trace context of the oldest request, got: %v", sc)
+	}
+	cancel()
+
+	doneFirst()
+	ctx, cancel = s.context()
+	if sc := trace.SpanContextFromContext(ctx); sc.TraceID().String() != "0af7651916cd43dd8448eb211c80319c" {
+		t.Errorf("expected the trace context of the remaining request, got: %v", sc)
+	}
+	cancel()
+	doneSecond()
+	done()
+}
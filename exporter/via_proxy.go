@@ -0,0 +1,36 @@
+package exporter
+
+import (
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// viaProxyUnsupportedCollectors are the collectors skipped with ViaProxy, proxies like Twemproxy
+// and Envoy reject CONFIG, INFO, LATENCY, SLOWLOG, CLIENT, SCAN, SELECT and EVAL
+var viaProxyUnsupportedCollectors = []string{"config", "info", "latency", "keys", "streams", "slowlog", "key_groups", "clients"}
+
+// scrapeViaProxy scrapes a Redis behind a proxy that rejects admin commands: it only
+// PINGs through the proxy and runs the probes, the skipped collectors are exported as
+// exporter_via_proxy_skipped_collector instead of logging an error on every scrape
+func (e *Exporter) scrapeViaProxy(ch chan<- prometheus.Metric, c redis.Conn) error {
+	e.startCollector("ping")
+	startTime := time.Now()
+	if _, err := doRedisCmd(c, "PING"); err != nil {
+		e.logger().Errorf("Couldn't PING server via proxy, err: %s", err)
+		return err
+	}
+	e.registerConstMetricGauge(ch, "exporter_last_scrape_ping_time_seconds", time.Since(startTime).Seconds())
+
+	for _, collector := range viaProxyUnsupportedCollectors {
+		e.registerConstMetricGauge(ch, "exporter_via_proxy_skipped_collector", 1, collector)
+	}
+
+	if len(e.options.Probes) > 0 {
+		e.startCollector("probes")
+		// proxies only serve db0, probes of db0 work without SELECT
+		e.extractProbeMetrics(ch, &selectFreeConn{Conn: c})
+	}
+	return nil
+}
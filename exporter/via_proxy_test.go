@@ -0,0 +1,62 @@
+package exporter
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestScrapeViaProxy(t *testing.T) {
+	c := &pipelineConn{fakeRedisConn: fakeRedisConn{replies: map[string]interface{}{
+		"PING":          "PONG",
+		"STRLEN health": int64(2),
+	}}}
+	e, _ := NewRedisExporter("redis://localhost:6379", Options{
+		Namespace: "test",
+		ViaProxy:  true,
+		Probes:    []Probe{{Name: "health", Command: []string{"STRLEN", "health"}}},
+	})
+
+	got := collectScanMetrics(t, func(ch chan<- prometheus.Metric) {
+		if err := e.scrapeViaProxy(ch, c); err != nil {
+			t.Errorf("scrapeViaProxy() err: %s", err)
+		}
+	})
+
+	if want := []string{"PING", "STRLEN health"}; !slices.Equal(c.commands, want) {
+		t.Errorf("expected only the commands %v, got: %v", want, c.commands)
+	}
+	if got["test_probe_success"] != 1 || got["test_probe_value"] != 2 {
+		t.Errorf("expected the probe to succeed, got: %v", got)
+	}
+	if _, ok := got["test_exporter_last_scrape_ping_time_seconds"]; !ok {
+		t.Errorf("expected exporter_last_scrape_ping_time_seconds, got: %v", got)
+	}
+}
+
+func TestScrapeViaProxyHost(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "proxy.sock")
+	l := servePong(t, path)
+	defer l.Close()
+
+	e, _ := NewRedisExporter("unix://"+path, Options{Namespace: "test", ViaProxy: true})
+	chM := make(chan prometheus.Metric)
+	go func() {
+		if err := e.scrapeRedisHost(chM); err != nil {
+			t.Errorf("scrapeRedisHost() err: %s", err)
+		}
+		close(chM)
+	}()
+
+	skipped := 0
+	for m := range chM {
+		if m.Desc() == e.mustFindMetricDescription("exporter_via_proxy_skipped_collector") {
+			skipped++
+		}
+	}
+	if skipped != len(viaProxyUnsupportedCollectors) {
+		t.Errorf("expected %d skipped collectors, got: %d", len(viaProxyUnsupportedCollectors), skipped)
+	}
+}
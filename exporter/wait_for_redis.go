@@ -0,0 +1,57 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// waitForRedisMaxBackoff is the longest pause between two attempts of WaitForRedis
+const waitForRedisMaxBackoff = 5 * time.Second
+
+// WaitForRedis retries loading the TLS client config and connecting to and PINGing the
+// Redis instance of the exporter until they succeed or timeout passed, so the exporter can
+// be started before Redis and its certificates are available
+func (e *Exporter) WaitForRedis(ctx context.Context, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	backoff := 250 * time.Millisecond
+	for attempt := 1; ; attempt++ {
+		err := e.checkRedisReachable()
+		if err == nil {
+			if attempt > 1 {
+				e.logger().Infof("Redis at %s is reachable after %d attempts", redactedAddr(e.redisAddr), attempt)
+			}
+			return nil
+		}
+		if time.Now().Add(backoff).After(deadline) {
+			return fmt.Errorf("redis at %s isn't reachable after waiting %s, err: %w", redactedAddr(e.redisAddr), timeout, err)
+		}
+
+		e.logger().Infof("Waiting for Redis at %s, attempt %d failed, retrying in %s, err: %s", redactedAddr(e.redisAddr), attempt, backoff, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, waitForRedisMaxBackoff)
+	}
+}
+
+// checkRedisReachable loads the TLS client config and PINGs the Redis instance of the
+// exporter, without a Redis instance (only /scrape is used) only the TLS config is checked
+func (e *Exporter) checkRedisReachable() error {
+	if _, err := e.CreateClientTLSConfig(); err != nil {
+		return err
+	}
+	if e.redisAddr == "" {
+		return nil
+	}
+
+	c, err := e.connectToRedis()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	_, err = doRedisCmd(c, "PING")
+	return err
+}
@@ -0,0 +1,81 @@
+package exporter
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// servePong answers every command on the unix socket path with +PONG
+func servePong(t *testing.T, path string) net.Listener {
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("couldn't listen on %s: %s", path, err)
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					// skip the arguments of the command, 2 lines each
+					args, _ := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+					for i := 0; i < 2*args; i++ {
+						if _, err := r.ReadString('\n'); err != nil {
+							return
+						}
+					}
+					conn.Write([]byte("+PONG\r\n"))
+				}
+			}()
+		}
+	}()
+	return l
+}
+
+func TestWaitForRedis(t *testing.T) {
+	t.Run("redis starts later", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "redis.sock")
+		e, _ := NewRedisExporter("unix://"+path, Options{Namespace: "test"})
+
+		started := make(chan net.Listener, 1)
+		time.AfterFunc(400*time.Millisecond, func() { started <- servePong(t, path) })
+		defer func() { (<-started).Close() }()
+
+		if err := e.WaitForRedis(context.Background(), 10*time.Second); err != nil {
+			t.Errorf("WaitForRedis() err: %s", err)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		e, _ := NewRedisExporter("unix://"+filepath.Join(t.TempDir(), "redis.sock"), Options{Namespace: "test"})
+
+		start := time.Now()
+		if err := e.WaitForRedis(context.Background(), time.Second); err == nil {
+			t.Errorf("expected an error without a Redis instance")
+		}
+		if took := time.Since(start); took > 2*time.Second {
+			t.Errorf("expected WaitForRedis() to give up after about a second, took: %s", took)
+		}
+	})
+
+	t.Run("missing client certificate", func(t *testing.T) {
+		e, _ := NewRedisExporter("", Options{Namespace: "test", ClientCertFile: "/doesnt/exist.crt", ClientKeyFile: "/doesnt/exist.key"})
+		if err := e.WaitForRedis(context.Background(), 300*time.Millisecond); err == nil || !strings.Contains(err.Error(), "exist") {
+			t.Errorf("expected the error of the missing certificate, got: %v", err)
+		}
+	})
+}
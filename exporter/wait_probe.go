@@ -0,0 +1,41 @@
+package exporter
+
+import (
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// extractWaitProbeMetrics writes a canary key and uses WAIT to measure how many
+// replicas acknowledged the write and how long that took
+func (e *Exporter) extractWaitProbeMetrics(ch chan<- prometheus.Metric, c redis.Conn) {
+	timeout := e.options.WaitProbeTimeout
+	if timeout <= 0 {
+		timeout = 100 * time.Millisecond
+	}
+	// WAIT with a timeout of 0 blocks until enough replicas acknowledged the write
+	timeoutMs := max(timeout.Milliseconds(), 1)
+
+	if _, err := doRedisCmd(c, "SET", e.options.WaitProbeKey, time.Now().UnixMilli(), "PX", time.Minute.Milliseconds()); err != nil {
+		e.logger().Errorf("WAIT probe: couldn't write canary key %s, err: %s", e.options.WaitProbeKey, err)
+		e.registerConstMetricGauge(ch, "wait_probe_success", 0)
+		return
+	}
+
+	start := time.Now()
+	acked, err := redis.Int64(doRedisCmd(c, "WAIT", e.options.WaitProbeReplicas, timeoutMs))
+	if err != nil {
+		e.logger().Errorf("WAIT probe: WAIT err: %s", err)
+		e.registerConstMetricGauge(ch, "wait_probe_success", 0)
+		return
+	}
+
+	success := 0.0
+	if acked >= int64(e.options.WaitProbeReplicas) {
+		success = 1
+	}
+	e.registerConstMetricGauge(ch, "wait_probe_success", success)
+	e.registerConstMetricGauge(ch, "wait_probe_acked_replicas", float64(acked))
+	e.registerConstMetricGauge(ch, "wait_probe_duration_seconds", time.Since(start).Seconds())
+}
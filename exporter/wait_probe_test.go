@@ -0,0 +1,73 @@
+package exporter
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestWaitProbeMetrics(t *testing.T) {
+	for _, tst := range []struct {
+		name        string
+		replicas    int
+		timeout     time.Duration
+		waitTimeout int64
+		waitReply   interface{}
+		wantSuccess float64
+		wantAcked   float64
+	}{
+		{name: "all acked", replicas: 2, waitReply: int64(2), wantSuccess: 1, wantAcked: 2},
+		{name: "not enough acked", replicas: 2, waitReply: int64(1), wantSuccess: 0, wantAcked: 1},
+		{name: "wait error", replicas: 1, wantSuccess: 0, wantAcked: -1},
+		{name: "sub-millisecond timeout", replicas: 1, timeout: 500 * time.Microsecond, waitTimeout: 1, waitReply: int64(1), wantSuccess: 1, wantAcked: 1},
+	} {
+		t.Run(tst.name, func(t *testing.T) {
+			replies := map[string]interface{}{}
+			if tst.waitReply != nil {
+				waitTimeout := tst.waitTimeout
+				if waitTimeout == 0 {
+					waitTimeout = 100
+				}
+				replies[fmt.Sprintf("WAIT %d %d", tst.replicas, waitTimeout)] = tst.waitReply
+			}
+			c := &fakeRedisConn{replies: replies}
+			// the SET arguments contain the current time so every SET is accepted
+			c.prefixReplies = map[string]interface{}{"SET canary ": "OK"}
+
+			e, _ := NewRedisExporter("", Options{Namespace: "test", WaitProbeKey: "canary", WaitProbeReplicas: tst.replicas, WaitProbeTimeout: tst.timeout})
+
+			chM := make(chan prometheus.Metric)
+			go func() {
+				e.extractWaitProbeMetrics(chM, c)
+				close(chM)
+			}()
+
+			success, acked := -1.0, -1.0
+			for m := range chM {
+				d := &dto.Metric{}
+				m.Write(d)
+				desc := m.Desc().String()
+				switch {
+				case strings.Contains(desc, "test_wait_probe_success"):
+					success = d.GetGauge().GetValue()
+				case strings.Contains(desc, "test_wait_probe_acked_replicas"):
+					acked = d.GetGauge().GetValue()
+				}
+			}
+
+			if success != tst.wantSuccess || acked != tst.wantAcked {
+				t.Errorf("expected success %.0f and acked %.0f, got: %.0f and %.0f", tst.wantSuccess, tst.wantAcked, success, acked)
+			}
+		})
+	}
+}
+
+func TestWaitProbeCluster(t *testing.T) {
+	if _, err := NewRedisExporter("", Options{Namespace: "test", WaitProbeKey: "canary", IsCluster: true}); err == nil {
+		t.Errorf("expected an error for wait-probe-key in cluster mode")
+	}
+}
@@ -0,0 +1,203 @@
+package exporter
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+// inflightCollections tracks the start time of the running collections of the exporter
+// and the per-target exporters so the watchdog can find the ones that are stuck
+type inflightCollections struct {
+	sync.Mutex
+	nextID  int64
+	started map[int64]inflightCollection
+}
+
+type inflightCollection struct {
+	target  string
+	started time.Time
+	// set once the stacks were dumped for this collection
+	reported bool
+}
+
+func newInflightCollections() *inflightCollections {
+	return &inflightCollections{started: map[int64]inflightCollection{}}
+}
+
+// begin records the start of a collection of target, the returned func records its end
+func (c *inflightCollections) begin(target string, now time.Time) func() {
+	c.Lock()
+	defer c.Unlock()
+	c.nextID++
+	id := c.nextID
+	c.started[id] = inflightCollection{target: target, started: now}
+	return func() {
+		c.Lock()
+		defer c.Unlock()
+		delete(c.started, id)
+	}
+}
+
+// stuck returns the collections running for longer than maxDuration and whether
+// any of them wasn't returned by a previous call
+func (c *inflightCollections) stuck(now time.Time, maxDuration time.Duration) (stuck []inflightCollection, isNew bool) {
+	c.Lock()
+	defer c.Unlock()
+	for id, coll := range c.started {
+		if now.Sub(coll.started) <= maxDuration {
+			continue
+		}
+		stuck = append(stuck, coll)
+		if !coll.reported {
+			isNew = true
+			coll.reported = true
+			c.started[id] = coll
+		}
+	}
+	return stuck, isNew
+}
+
+// watchdogStuckTimeouts is the default stuck threshold of the watchdog in connection timeouts,
+// the commands of a collection run one after the other and each of them can take a timeout
+const watchdogStuckTimeouts = 20
+
+// watchdog periodically probes the redis.addr instance and checks that no collection runs
+// for longer than stuckAfter, e.g. because it's blocked on a lock
+type watchdog struct {
+	e *Exporter
+
+	interval    time.Duration
+	stuckAfter  time.Duration
+	exitOnStuck bool
+	// exit is os.Exit, replaced in the tests
+	exit func(code int)
+
+	probing atomic.Bool
+	healthy atomic.Bool
+}
+
+// StartWatchdog starts the watchdog in the background until ctx is done, it's
+// disabled if the watchdog interval isn't set
+func (e *Exporter) StartWatchdog(ctx context.Context) {
+	if e.options.WatchdogInterval <= 0 {
+		return
+	}
+
+	stuckAfter := e.options.WatchdogStuckAfter
+	if stuckAfter <= 0 {
+		stuckAfter = watchdogStuckTimeouts * e.options.ConnectionTimeouts
+	}
+	if stuckAfter <= 0 {
+		stuckAfter = 5 * time.Minute
+	}
+
+	w := &watchdog{
+		e:           e,
+		interval:    e.options.WatchdogInterval,
+		stuckAfter:  stuckAfter,
+		exitOnStuck: e.options.WatchdogExitOnStuck,
+		exit:        os.Exit,
+	}
+	w.healthy.Store(true)
+
+	e.Lock()
+	e.watchdog = w
+	e.Unlock()
+
+	e.logger().Infof("Watchdog probing every %s, collections running for longer than %s are considered stuck", w.interval, w.stuckAfter)
+	go w.run(ctx)
+}
+
+func (w *watchdog) run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.probe()
+			w.check(time.Now())
+		}
+	}
+}
+
+// probe sends a PING on a new connection unless a collection holds the lock of the exporter,
+// that collection is checked instead. It isn't a self-collection: a collection would count as
+// a scrape and consume the deltas of the event metrics, the collectors are checked through the
+// collections of the scrapes. It isn't started while the previous one is still running
+func (w *watchdog) probe() {
+	if w.e.redisAddr == "" || !w.probing.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer w.probing.Store(false)
+
+		if !w.e.TryLock() {
+			return
+		}
+		defer w.e.Unlock()
+		defer w.e.inflight.begin(w.e.redisAddr, time.Now())()
+
+		c, err := w.e.connectToRedis()
+		if err != nil {
+			w.e.logger().Debugf("Watchdog: couldn't connect to %s, err: %s", w.e.redisAddr, err)
+			return
+		}
+		defer c.Close()
+		if _, err := doRedisCmd(c, "PING"); err != nil {
+			w.e.logger().Debugf("Watchdog: PING %s failed, err: %s", w.e.redisAddr, err)
+		}
+	}()
+}
+
+// check updates the health of the exporter and dumps the goroutine stacks once
+// per stuck collection
+func (w *watchdog) check(now time.Time) {
+	stuck, isNew := w.e.inflight.stuck(now, w.stuckAfter)
+	w.healthy.Store(len(stuck) == 0)
+	if !isNew {
+		return
+	}
+
+	for _, coll := range stuck {
+		log.Errorf("Watchdog: collection of %q is running for %s", coll.target, now.Sub(coll.started).Round(time.Second))
+	}
+	log.Errorf("Watchdog: goroutine stacks:\n%s", goroutineStacks())
+
+	if w.exitOnStuck {
+		log.Errorf("Watchdog: exiting so the exporter can be restarted")
+		w.exit(1)
+	}
+}
+
+// goroutineStacks returns the stacks of all goroutines
+func goroutineStacks() []byte {
+	buf := make([]byte, 1<<16)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) || len(buf) >= 64<<20 {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+func (e *Exporter) registerWatchdogMetrics(ch chan<- prometheus.Metric) {
+	if e.watchdog == nil {
+		return
+	}
+	healthy := 0.0
+	if e.watchdog.healthy.Load() {
+		healthy = 1
+	}
+	e.registerConstMetricGauge(ch, "exporter_internal_healthy", healthy)
+}
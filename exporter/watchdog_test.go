@@ -0,0 +1,136 @@
+package exporter
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oliver006/redis_exporter/exporter/exportertest"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestInflightCollections(t *testing.T) {
+	c := newInflightCollections()
+	now := time.Now()
+
+	end := c.begin("redis://a", now.Add(-time.Minute))
+	c.begin("redis://b", now)
+
+	stuck, isNew := c.stuck(now, 30*time.Second)
+	if len(stuck) != 1 || stuck[0].target != "redis://a" || !isNew {
+		t.Fatalf("expected redis://a to be stuck, got: %v %t", stuck, isNew)
+	}
+	if stuck, isNew := c.stuck(now, 30*time.Second); len(stuck) != 1 || isNew {
+		t.Errorf("expected redis://a to be reported only once, got: %v %t", stuck, isNew)
+	}
+
+	end()
+	if stuck, _ := c.stuck(now, 30*time.Second); len(stuck) != 0 {
+		t.Errorf("expected no stuck collections after the end, got: %v", stuck)
+	}
+}
+
+func TestWatchdog(t *testing.T) {
+	e, _ := NewRedisExporter("", Options{Namespace: "test", WatchdogInterval: time.Hour, WatchdogExitOnStuck: true, WatchdogStuckAfter: 3 * time.Second})
+	e.StartWatchdog(context.Background())
+	if e.watchdog == nil || e.watchdog.stuckAfter != 3*time.Second {
+		t.Fatalf("expected the watchdog to consider collections stuck after 3s, got: %+v", e.watchdog)
+	}
+	exitCode := -1
+	e.watchdog.exit = func(code int) { exitCode = code }
+
+	healthy := func() float64 {
+		chM := make(chan prometheus.Metric)
+		go func() {
+			e.registerWatchdogMetrics(chM)
+			close(chM)
+		}()
+		for m := range chM {
+			if strings.Contains(m.Desc().String(), `"test_exporter_internal_healthy"`) {
+				d := &dto.Metric{}
+				m.Write(d)
+				return d.GetGauge().GetValue()
+			}
+		}
+		return -1
+	}
+
+	// a collection blocked on a lock
+	end := e.inflight.begin("redis://stuck", time.Now())
+
+	e.watchdog.check(time.Now())
+	if healthy() != 1 || exitCode != -1 {
+		t.Errorf("expected the exporter to be healthy before the collection is stuck")
+	}
+
+	e.watchdog.check(time.Now().Add(time.Minute))
+	if healthy() != 0 || exitCode != 1 {
+		t.Errorf("expected the exporter to be unhealthy and to exit, got exit code %d", exitCode)
+	}
+
+	end()
+	e.watchdog.check(time.Now().Add(time.Minute))
+	if healthy() != 1 {
+		t.Errorf("expected the exporter to be healthy again after the collection finished")
+	}
+}
+
+func TestWatchdogProbe(t *testing.T) {
+	srv := exportertest.NewServer(t)
+	e, _ := NewRedisExporter(srv.Addr(), Options{Namespace: "test", WatchdogInterval: time.Hour})
+	e.StartWatchdog(context.Background())
+
+	waitProbe := func() {
+		deadline := time.Now().Add(5 * time.Second)
+		for e.watchdog.probing.Load() && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	// a running collection holds the lock, the probe leaves Redis alone
+	e.Lock()
+	e.watchdog.probe()
+	waitProbe()
+	e.Unlock()
+	if cmds := srv.Commands(); len(cmds) != 0 {
+		t.Errorf("expected no commands while a collection is running, got: %v", cmds)
+	}
+
+	e.watchdog.probe()
+	waitProbe()
+	if cmds := srv.Commands(); len(cmds) != 1 || cmds[0] != "PING" {
+		t.Errorf("expected a PING, got: %v", cmds)
+	}
+	scrapes := &dto.Metric{}
+	e.totalScrapes.Write(scrapes)
+	if n := scrapes.GetCounter().GetValue(); n != 0 {
+		t.Errorf("expected the probe not to count as a scrape, got: %f", n)
+	}
+}
+
+func TestWatchdogDisabled(t *testing.T) {
+	e, _ := NewRedisExporter("", Options{Namespace: "test"})
+	e.StartWatchdog(context.Background())
+	if e.watchdog != nil {
+		t.Errorf("expected no watchdog without an interval")
+	}
+}
+
+func TestWatchdogStuckAfterDefault(t *testing.T) {
+	for _, tst := range []struct {
+		timeout time.Duration
+		want    time.Duration
+	}{
+		{timeout: 15 * time.Second, want: 5 * time.Minute},
+		{timeout: 3 * time.Second, want: time.Minute},
+		{timeout: 0, want: 5 * time.Minute},
+	} {
+		e, _ := NewRedisExporter("", Options{Namespace: "test", WatchdogInterval: time.Hour, ConnectionTimeouts: tst.timeout})
+		e.StartWatchdog(context.Background())
+		if e.watchdog.stuckAfter != tst.want {
+			t.Errorf("expected collections to be stuck after %s with a timeout of %s, got: %s", tst.want, tst.timeout, e.watchdog.stuckAfter)
+		}
+	}
+}
@@ -0,0 +1,15 @@
+package exporter
+
+// instanceZone returns the availability zone of the instance, the availability_zone of
+// INFO (Valkey 8.0+) or the Zone option, it's empty if the zone is already a const label
+// (e.g. a label of the targets file) as the label names of a metric have to be unique.
+// It's only a label of instance_info, other metrics get it by joining on the instance
+func (e *Exporter) instanceZone(fields *infoFieldTable) string {
+	if _, ok := e.options.ConstLabels["zone"]; ok {
+		return ""
+	}
+	if zone := fields.value("availability_zone"); zone != "" {
+		return zone
+	}
+	return e.options.Zone
+}
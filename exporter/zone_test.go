@@ -0,0 +1,54 @@
+package exporter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestInstanceZone(t *testing.T) {
+	for _, tst := range []struct {
+		name        string
+		info        string
+		zone        string
+		constLabels map[string]string
+		want        string
+	}{
+		{name: "from info", info: "availability_zone:us-east-1a\r\n", zone: "eu-west-1b", want: "us-east-1a"},
+		{name: "from option", zone: "eu-west-1b", want: "eu-west-1b"},
+		{name: "empty availability_zone", info: "availability_zone:\r\n", zone: "eu-west-1b", want: "eu-west-1b"},
+		{name: "unknown", want: ""},
+		// the zone of INFO is dropped, the const label is kept
+		{name: "const label", info: "availability_zone:us-east-1a\r\n", constLabels: map[string]string{"zone": "a"}, want: "a"},
+	} {
+		t.Run(tst.name, func(t *testing.T) {
+			e, _ := NewRedisExporter("redis://localhost:6379", Options{Namespace: "test", Zone: tst.zone, ConstLabels: tst.constLabels})
+
+			chM := make(chan prometheus.Metric)
+			go func() {
+				e.extractInfoMetrics(chM, "# Server\r\nredis_version:8.0.0\r\n"+tst.info+"\r\n# Replication\r\nrole:master\r\n", 0)
+				close(chM)
+			}()
+
+			found, zone := false, ""
+			for m := range chM {
+				if !strings.Contains(m.Desc().String(), `"test_instance_info"`) {
+					continue
+				}
+				found = true
+				d := &dto.Metric{}
+				m.Write(d)
+				for _, l := range d.GetLabel() {
+					if l.GetName() == "zone" {
+						zone = l.GetValue()
+					}
+				}
+			}
+			if !found || zone != tst.want {
+				t.Errorf("expected zone %q, got: %q (found: %t)", tst.want, zone, found)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat is the timestamp suffix of the rotated files
+const backupTimeFormat = "20060102T150405.000"
+
+var backupSuffixRE = regexp.MustCompile(`^\d{8}T\d{6}\.\d{3}$`)
+
+// renameFile renames the log file when it's rotated, replaced in the tests
+var renameFile = os.Rename
+
+// rotatingFile is a log file that is rotated once it exceeds maxSize bytes
+// or is older than maxAge, keeping at most maxBackups rotated files
+type rotatingFile struct {
+	mu sync.Mutex
+
+	path       string
+	maxSize    int64
+	maxAge     time.Duration
+	maxBackups int
+
+	file     *os.File
+	size     int64
+	openedAt time.Time
+}
+
+func newRotatingFile(path string, maxSize int64, maxAge time.Duration, maxBackups int) (*rotatingFile, error) {
+	f := &rotatingFile{
+		path:       path,
+		maxSize:    maxSize,
+		maxAge:     maxAge,
+		maxBackups: maxBackups,
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	f.file = file
+	f.size = info.Size()
+	f.openedAt = time.Now()
+	return nil
+}
+
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if (f.maxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.maxSize) ||
+		(f.maxAge > 0 && time.Since(f.openedAt) > f.maxAge) {
+		if err := f.rotate(); err != nil {
+			fmt.Fprintf(os.Stderr, "couldn't rotate log file %s: %s\n", f.path, err)
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// rotate renames the log file and opens a new one, if that fails the logs are
+// still written to the original file
+func (f *rotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return errors.Join(err, f.open())
+	}
+
+	backup := f.path + "." + time.Now().Format(backupTimeFormat)
+	if err := renameFile(f.path, backup); err != nil {
+		return errors.Join(err, f.open())
+	}
+	if err := f.open(); err != nil {
+		if renameErr := renameFile(backup, f.path); renameErr != nil {
+			return errors.Join(err, renameErr)
+		}
+		return errors.Join(err, f.open())
+	}
+	return f.removeOldBackups()
+}
+
+// removeOldBackups removes all but the newest maxBackups rotated files, other files
+// starting with the name of the log file are left alone
+func (f *rotatingFile) removeOldBackups() error {
+	entries, err := os.ReadDir(filepath.Dir(f.path))
+	if err != nil {
+		return err
+	}
+	var backups []string
+	prefix := filepath.Base(f.path) + "."
+	for _, entry := range entries {
+		if suffix, ok := strings.CutPrefix(entry.Name(), prefix); ok && backupSuffixRE.MatchString(suffix) {
+			backups = append(backups, filepath.Join(filepath.Dir(f.path), entry.Name()))
+		}
+	}
+
+	// the timestamp suffix sorts chronologically
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+	if f.maxBackups <= 0 || len(backups) <= f.maxBackups {
+		return nil
+	}
+	for _, b := range backups[f.maxBackups:] {
+		if err := os.Remove(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *rotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.file.Close()
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotatingFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "exporter.log")
+
+	f, err := newRotatingFile(path, 10, 0, 2)
+	if err != nil {
+		t.Fatalf("newRotatingFile() err: %s", err)
+	}
+	defer f.Close()
+
+	for _, line := range []string{"line-one\n", "line-two\n", "line-three\n", "line-four\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatalf("Write() err: %s", err)
+		}
+		// rotated files are named by timestamp with millisecond precision
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() err: %s", err)
+	}
+	if string(b) != "line-four\n" {
+		t.Errorf("expected current log file to only contain the last line, got: %q", b)
+	}
+
+	backups, _ := filepath.Glob(path + ".*")
+	if len(backups) != 2 {
+		t.Fatalf("expected 2 backups, got: %v", backups)
+	}
+	for _, b := range backups {
+		content, _ := os.ReadFile(b)
+		if strings.Contains(string(content), "line-one") {
+			t.Errorf("expected oldest backup to be removed, found it in %s", b)
+		}
+	}
+}
+
+func TestRotatingFileMaxAge(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "exporter.log")
+
+	f, err := newRotatingFile(path, 0, time.Millisecond, 0)
+	if err != nil {
+		t.Fatalf("newRotatingFile() err: %s", err)
+	}
+	defer f.Close()
+
+	f.Write([]byte("first\n"))
+	time.Sleep(5 * time.Millisecond)
+	f.Write([]byte("second\n"))
+
+	backups, _ := filepath.Glob(path + ".*")
+	if len(backups) != 1 {
+		t.Fatalf("expected 1 backup, got: %v", backups)
+	}
+}
+
+func TestRotatingFileRenameFails(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "exporter.log")
+
+	defer func(r func(string, string) error) { renameFile = r }(renameFile)
+	renameFile = func(string, string) error { return errors.New("rename failed") }
+
+	f, err := newRotatingFile(path, 10, 0, 2)
+	if err != nil {
+		t.Fatalf("newRotatingFile() err: %s", err)
+	}
+	defer f.Close()
+
+	for _, line := range []string{"line-one\n", "line-two\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatalf("Write() err: %s", err)
+		}
+	}
+	if b, _ := os.ReadFile(path); string(b) != "line-one\nline-two\n" {
+		t.Errorf("expected the logs to be written to the original file, got: %q", b)
+	}
+}
+
+func TestRotatingFileKeepsOtherFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "exporter.log")
+	for _, name := range []string{"exporter.log.gz", "exporter.log.old", "exporter.log.20200101T000000.000"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f, err := newRotatingFile(path, 10, 0, 1)
+	if err != nil {
+		t.Fatalf("newRotatingFile() err: %s", err)
+	}
+	defer f.Close()
+	f.Write([]byte("line-one\n"))
+	f.Write([]byte("line-two\n"))
+
+	for _, name := range []string{"exporter.log.gz", "exporter.log.old"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("expected %s to be kept, err: %s", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "exporter.log.20200101T000000.000")); err == nil {
+		t.Errorf("expected the old backup to be removed")
+	}
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/collectors"
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/crypto/bcrypt"
 
 	"github.com/oliver006/redis_exporter/exporter"
@@ -57,6 +58,44 @@ func getEnvInt64(key string, defaultVal int64) int64 {
 	return defaultVal
 }
 
+func getEnvFloat64(key string, defaultVal float64) float64 {
+	if envVal, ok := os.LookupEnv(key); ok {
+		envFloat64, err := strconv.ParseFloat(envVal, 64)
+		if err == nil {
+			return envFloat64
+		}
+	}
+	return defaultVal
+}
+
+// httpServerOptions are the hardening settings of the embedded web server
+type httpServerOptions struct {
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+	DisableHTTP2      bool
+}
+
+// newHTTPServer returns the web server serving handler on addr
+func newHTTPServer(addr string, handler http.Handler, opts httpServerOptions) *http.Server {
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadTimeout:       opts.ReadTimeout,
+		ReadHeaderTimeout: opts.ReadHeaderTimeout,
+		WriteTimeout:      opts.WriteTimeout,
+		IdleTimeout:       opts.IdleTimeout,
+		MaxHeaderBytes:    opts.MaxHeaderBytes,
+	}
+	if opts.DisableHTTP2 {
+		server.Protocols = new(http.Protocols)
+		server.Protocols.SetHTTP1(true)
+	}
+	return server
+}
+
 // parseLogLevel parses a log level string and returns the corresponding logrus level
 func parseLogLevel(level string) (log.Level, error) {
 	switch strings.ToUpper(level) {
@@ -153,26 +192,103 @@ func createPrometheusRegistry(redisMetricsOnly, inclGoRuntimeMetrics bool) *prom
 	return registry
 }
 
+// shutdownSignals receives the signals that make the exporter shut down gracefully,
+// the Windows service handler sends to it when the service is stopped
+var shutdownSignals = make(chan os.Signal, 1)
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "service" {
+		if err := runServiceCommand(os.Args[2:]); err != nil {
+			log.Fatalf("Service command failed, err: %s", err)
+		}
+		return
+	}
+
+	runExporter()
+}
+
+// runExporter parses the flags and serves metrics until a shutdown signal is received
+func runExporter() {
 	var (
 		redisAddr                      = flag.String("redis.addr", getEnv("REDIS_ADDR", "redis://localhost:6379"), "Address of the Redis instance to scrape")
 		redisUser                      = flag.String("redis.user", getEnv("REDIS_USER", ""), "User name to use for authentication (Redis ACL for Redis 6.0 and newer)")
 		redisPwd                       = flag.String("redis.password", getEnv("REDIS_PASSWORD", ""), "Password of the Redis instance to scrape")
 		redisPwdFile                   = flag.String("redis.password-file", getEnv("REDIS_PASSWORD_FILE", ""), "Password file of the Redis instance to scrape")
+		redisPwdFileKey                = flag.String("redis.password-file-key", getEnv("REDIS_PASSWORD_FILE_KEY", ""), "Base64 encoded 32 byte AES-256-GCM key of an encrypted password file")
+		redisPwdFileKeyFile            = flag.String("redis.password-file-key-file", getEnv("REDIS_PASSWORD_FILE_KEY_FILE", ""), "File with the base64 encoded key of an encrypted password file")
+		encryptPwdFile                 = flag.String("encrypt-password-file", getEnv("REDIS_EXPORTER_ENCRYPT_PASSWORD_FILE", ""), "Encrypt the given password file with the password file key, print the result to stdout and exit")
 		namespace                      = flag.String("namespace", getEnv("REDIS_EXPORTER_NAMESPACE", "redis"), "Namespace for metrics")
+		namespaceOverrides             = flag.String("namespace-overrides", getEnv("REDIS_EXPORTER_NAMESPACE_OVERRIDES", ""), "Comma separated list of subsystem=namespace pairs to override the namespace of the metrics of a collector, e.g. keys=app_keys")
 		checkKeys                      = flag.String("check-keys", getEnv("REDIS_EXPORTER_CHECK_KEYS", ""), "Comma separated list of key-patterns to export value and length/size, searched for with SCAN")
 		checkSingleKeys                = flag.String("check-single-keys", getEnv("REDIS_EXPORTER_CHECK_SINGLE_KEYS", ""), "Comma separated list of single keys to export value and length/size")
+		checkKeysFromKey               = flag.String("check-keys-from-key", getEnv("REDIS_EXPORTER_CHECK_KEYS_FROM_KEY", ""), "Set or list (e.g. db0=monitoring:watched_keys) read each scrape whose members are check-keys entries to export value and length/size of")
+		checkKeysAPIFile               = flag.String("check-keys-api-file", getEnv("REDIS_EXPORTER_CHECK_KEYS_API_FILE", ""), "Path to a JSON file the checks changed with the /api/v1/check-keys endpoint are persisted to (the exporter is configured with flags and environment variables, there is no config file to write them back to), enables the endpoint, requires basic auth")
 		checkKeyGroups                 = flag.String("check-key-groups", getEnv("REDIS_EXPORTER_CHECK_KEY_GROUPS", ""), "Comma separated list of lua regex for grouping keys")
+		keyGroupsFile                  = flag.String("key-groups-file", getEnv("REDIS_EXPORTER_KEY_GROUPS_FILE", ""), "Path to a JSON file with named key groups, each with lua patterns, aggregations (count, memory, ttl) and its own max distinct key groups, replaces check-key-groups")
 		checkStreams                   = flag.String("check-streams", getEnv("REDIS_EXPORTER_CHECK_STREAMS", ""), "Comma separated list of stream-patterns to export info about streams, groups and consumers, searched for with SCAN")
 		checkSingleStreams             = flag.String("check-single-streams", getEnv("REDIS_EXPORTER_CHECK_SINGLE_STREAMS", ""), "Comma separated list of single streams to export info about streams, groups and consumers")
+		streamsConsumerFilter          = flag.String("check-streams-consumer-filter", getEnv("REDIS_EXPORTER_CHECK_STREAMS_CONSUMER_FILTER", ""), "Comma separated list of <group>:<consumer> glob patterns of stream consumers to export, patterns starting with ! exclude consumers")
+		streamsConsumerMinPending      = flag.Int64("streams-consumer-min-pending", getEnvInt64("REDIS_EXPORTER_STREAMS_CONSUMER_MIN_PENDING", 0), "Only export stream consumers with at least this many pending messages (or exceeding streams-consumer-min-idle)")
+		streamsConsumerMinIdle         = flag.String("streams-consumer-min-idle", getEnv("REDIS_EXPORTER_STREAMS_CONSUMER_MIN_IDLE", "0s"), "Only export stream consumers idle for at least this long (or exceeding streams-consumer-min-pending)")
+		streamsUseXinfoFull            = flag.Bool("streams-xinfo-full", getEnvBool("REDIS_EXPORTER_STREAMS_XINFO_FULL", false), "Whether to use XINFO STREAM FULL (limited by streams-xinfo-full-count) to get stream info")
+		streamsXinfoFullCount          = flag.Int64("streams-xinfo-full-count", getEnvInt64("REDIS_EXPORTER_STREAMS_XINFO_FULL_COUNT", 1), "COUNT argument for XINFO STREAM FULL, limits the number of returned entries and pending entries per group")
 		streamsExcludeConsumerMetrics  = flag.Bool("streams-exclude-consumer-metrics", getEnvBool("REDIS_EXPORTER_STREAMS_EXCLUDE_CONSUMER_METRICS", false), "Don't collect per consumer metrics for streams (decreases cardinality)")
 		countKeys                      = flag.String("count-keys", getEnv("REDIS_EXPORTER_COUNT_KEYS", ""), "Comma separated list of patterns to count (eg: 'db0=production_*,db3=sessions:*'), searched for with SCAN")
 		checkKeysBatchSize             = flag.Int64("check-keys-batch-size", getEnvInt64("REDIS_EXPORTER_CHECK_KEYS_BATCH_SIZE", 1000), "Approximate number of keys to process in each execution, larger value speeds up scanning.\nWARNING: Still Redis is a single-threaded app, huge COUNT can affect production environment.")
+		checkKeysPipelineSize          = flag.Int64("check-keys-pipeline-size", getEnvInt64("REDIS_EXPORTER_CHECK_KEYS_PIPELINE_SIZE", 1000), "Maximum number of keys whose TYPE, MEMORY USAGE and size commands are sent to Redis in a single pipeline, 0 means unlimited")
+		checkKeysAggregate             = flag.Bool("check-keys-aggregate", getEnvBool("REDIS_EXPORTER_CHECK_KEYS_AGGREGATE", false), "Export the number, sizes and memory usage of the keys matching a check-keys pattern instead of the metrics of each key")
+		checkKeysTopN                  = flag.Int64("check-keys-top-n", getEnvInt64("REDIS_EXPORTER_CHECK_KEYS_TOP_N", 0), "Only export the metrics of the N keys matching a check-keys pattern that sort first by check-keys-sort, 0 exports all keys")
+		checkKeysSort                  = flag.String("check-keys-sort", getEnv("REDIS_EXPORTER_CHECK_KEYS_SORT", "size"), "Order of the keys exported with check-keys-top-n, one of size, memory or ttl (largest first, keys without expire first)")
+		checkKeysScanBudget            = flag.Int64("check-keys-scan-budget", getEnvInt64("REDIS_EXPORTER_CHECK_KEYS_SCAN_BUDGET", 0), "Maximum number of SCAN calls per count-keys pattern and scrape, the next scrape resumes the SCAN from the cursor, 0 scans all keys on every scrape, not supported with --is-cluster")
+		maxCheckedKeys                 = flag.Int64("max-checked-keys", getEnvInt64("REDIS_EXPORTER_MAX_CHECKED_KEYS", 0), "Maximum number of keys checked per scrape for check-keys and check-single-keys, the remaining keys are skipped and key_checks_truncated is set, 0 means unlimited")
+		maxCheckedStreams              = flag.Int64("max-checked-streams", getEnvInt64("REDIS_EXPORTER_MAX_CHECKED_STREAMS", 0), "Maximum number of streams checked per scrape for check-streams and check-single-streams, the remaining streams are skipped and stream_checks_truncated is set, 0 means unlimited")
+		maxStreamConsumers             = flag.Int64("max-stream-consumers", getEnvInt64("REDIS_EXPORTER_MAX_STREAM_CONSUMERS", 0), "Maximum number of stream consumers exported per scrape, the remaining consumers are skipped and stream_consumers_truncated is set, 0 means unlimited")
+		checkKeysDumpSize              = flag.Bool("check-keys-dump-size", getEnvBool("REDIS_EXPORTER_CHECK_KEYS_DUMP_SIZE", false), "Export the length of the DUMP serialization of the checked keys as key_dump_size_bytes, works without MEMORY USAGE")
+		checkKeysDumpMaxKeys           = flag.Int64("check-keys-dump-max-keys", getEnvInt64("REDIS_EXPORTER_CHECK_KEYS_DUMP_MAX_KEYS", 100), "Maximum number of keys DUMPed per scrape for check-keys-dump-size, 0 means unlimited")
+		checkKeysDumpSampleRatio       = flag.Float64("check-keys-dump-sample-ratio", getEnvFloat64("REDIS_EXPORTER_CHECK_KEYS_DUMP_SAMPLE_RATIO", 1), "Fraction of the checked keys DUMPed for check-keys-dump-size, the same keys are picked on every scrape")
+		checkKeysHexLabels             = flag.Bool("check-keys-hex-labels", getEnvBool("REDIS_EXPORTER_CHECK_KEYS_HEX_LABELS", false), "Whether to export key and stream names with non-printable characters as hex-encoded label values (0x...), binary names that aren't valid UTF-8 are always hex-encoded")
+		keySampleCount                 = flag.Int64("key-sample-count", getEnvInt64("REDIS_EXPORTER_KEY_SAMPLE_COUNT", 0), "Number of keys picked with RANDOMKEY per database and scrape to export the approximate composition of the keyspace, 0 disables sampling")
+		dbsizeFallbackDbs              = flag.String("dbsize-fallback-dbs", getEnv("REDIS_EXPORTER_DBSIZE_FALLBACK_DBS", ""), "Comma separated list of the databases whose keys are counted with DBSIZE if INFO has no Keyspace section, e.g. because it's restricted")
+		keySampleDbs                   = flag.String("key-sample-dbs", getEnv("REDIS_EXPORTER_KEY_SAMPLE_DBS", "0"), "Comma separated list of the databases sampled with key-sample-count")
+		checkKeysAsMetricNames         = flag.Bool("check-keys-as-metric-names", getEnvBool("REDIS_EXPORTER_CHECK_KEYS_AS_METRIC_NAMES", false), "Export check-keys metrics with the key in the metric name (e.g. key_size_myqueue) instead of the key label")
+		keyMetricNamesFile             = flag.String("key-metric-names-file", getEnv("REDIS_EXPORTER_KEY_METRIC_NAMES_FILE", ""), "Path to a JSON file mapping key names to the metric name suffixes used with check-keys-as-metric-names")
+		probeConfigFile                = flag.String("probe-config-file", getEnv("REDIS_EXPORTER_PROBE_CONFIG_FILE", ""), "Path to a JSON file with read-only probe commands that are run on every scrape, exported as redis_probe_success")
+		waitProbeKey                   = flag.String("wait-probe-key", getEnv("REDIS_EXPORTER_WAIT_PROBE_KEY", ""), "Key of a canary write followed by WAIT on masters to measure replica acknowledgements, disabled if empty, not supported with --is-cluster")
+		waitProbeReplicas              = flag.Int64("wait-probe-replicas", getEnvInt64("REDIS_EXPORTER_WAIT_PROBE_REPLICAS", 1), "Number of replicas the WAIT probe waits for")
+		waitProbeTimeout               = flag.String("wait-probe-timeout", getEnv("REDIS_EXPORTER_WAIT_PROBE_TIMEOUT", "100ms"), "Timeout of the WAIT probe")
 		scriptPath                     = flag.String("script", getEnv("REDIS_EXPORTER_SCRIPT", ""), "Comma separated list of path(s) to Redis Lua script(s) for gathering extra metrics")
+		haReplicaName                  = flag.String("ha.replica-name", getEnv("REDIS_EXPORTER_HA_REPLICA_NAME", ""), "Name of this exporter in an HA pair, added as replica label to the exporter's own metrics only so the redis metrics of both exporters can be deduplicated")
+		leaderElectionKey              = flag.String("leader-election.key", getEnv("REDIS_EXPORTER_LEADER_ELECTION_KEY", ""), "Key of the lock on the redis.addr instance used to elect one of several exporters to run the key collectors, empty disables leader election")
+		leaderElectionTTL              = flag.String("leader-election.ttl", getEnv("REDIS_EXPORTER_LEADER_ELECTION_TTL", "15s"), "How long the leader holds the lock without renewing it")
+		expositionFormat               = flag.String("web.exposition-format", getEnv("REDIS_EXPORTER_WEB_EXPOSITION_FORMAT", ""), "Force the exposition format of the metrics (text, protobuf or openmetrics) instead of negotiating it with the Accept header of the scraper, for debugging scraper compatibility")
+		zone                           = flag.String("zone", getEnv("REDIS_EXPORTER_ZONE", ""), "Availability zone of the redis instance, added as zone label to redis_instance_info (only, join it to other metrics) if the instance doesn't report it in INFO (Valkey 8.0+ availability_zone)")
 		listenAddress                  = flag.String("web.listen-address", getEnv("REDIS_EXPORTER_WEB_LISTEN_ADDRESS", ":9121"), "Address to listen on for web interface and telemetry.")
+		webAllowedCIDRs                = flag.String("web.allowed-cidrs", getEnv("REDIS_EXPORTER_WEB_ALLOWED_CIDRS", ""), "Comma separated list of CIDRs or addresses of the clients allowed to use the web endpoints, e.g. \"10.0.0.0/8,127.0.0.1\", empty allows all clients")
+		grpcHealthListenAddress        = flag.String("grpc-health.listen-address", getEnv("REDIS_EXPORTER_GRPC_HEALTH_LISTEN_ADDRESS", ""), "Address to serve the gRPC health checking service on, e.g. localhost:9123, disabled if empty")
+		webAdminListenAddress          = flag.String("web.admin-listen-address", getEnv("REDIS_EXPORTER_WEB_ADMIN_LISTEN_ADDRESS", ""), "Address to serve the health, pprof, reload, targets and permissions endpoints on instead of web.listen-address, e.g. localhost:9122")
 		metricPath                     = flag.String("web.telemetry-path", getEnv("REDIS_EXPORTER_WEB_TELEMETRY_PATH", "/metrics"), "Path under which to expose metrics.")
+		webReadTimeout                 = flag.String("web.read-timeout", getEnv("REDIS_EXPORTER_WEB_READ_TIMEOUT", "0s"), "Maximum duration for reading an entire request including the body, 0s means no timeout")
+		webReadHeaderTimeout           = flag.String("web.read-header-timeout", getEnv("REDIS_EXPORTER_WEB_READ_HEADER_TIMEOUT", "0s"), "Maximum duration for reading the request headers, 0s falls back to web.read-timeout")
+		webWriteTimeout                = flag.String("web.write-timeout", getEnv("REDIS_EXPORTER_WEB_WRITE_TIMEOUT", "0s"), "Maximum duration before timing out writes of the response, must be longer than a scrape takes, 0s means no timeout")
+		webIdleTimeout                 = flag.String("web.idle-timeout", getEnv("REDIS_EXPORTER_WEB_IDLE_TIMEOUT", "0s"), "Maximum duration to wait for the next request on keep-alive connections, 0s falls back to web.read-timeout")
+		webMaxHeaderBytes              = flag.Int64("web.max-header-bytes", getEnvInt64("REDIS_EXPORTER_WEB_MAX_HEADER_BYTES", 0), "Maximum size of the request headers in bytes, 0 means the Go default of 1MB")
+		webDisableHTTP2                = flag.Bool("web.disable-http2", getEnvBool("REDIS_EXPORTER_WEB_DISABLE_HTTP2", false), "Whether to disable HTTP/2 and only serve HTTP/1.1")
+		landingPageTitle               = flag.String("web.landing-page-title", getEnv("REDIS_EXPORTER_WEB_LANDING_PAGE_TITLE", ""), "Title of the landing page, defaults to \"Redis Exporter <version>\"")
+		disableLandingPage             = flag.Bool("web.disable-landing-page", getEnvBool("REDIS_EXPORTER_WEB_DISABLE_LANDING_PAGE", false), "Whether to disable the HTML landing page, unknown paths return 404 instead")
 		configCommand                  = flag.String("config-command", getEnv("REDIS_EXPORTER_CONFIG_COMMAND", "CONFIG"), "What to use for the CONFIG command, set to \"-\" to skip config metrics extraction")
 		connectionTimeout              = flag.String("connection-timeout", getEnv("REDIS_EXPORTER_CONNECTION_TIMEOUT", "15s"), "Timeout for connection to Redis instance")
+		connectionRetries              = flag.Int64("connection-retries", getEnvInt64("REDIS_EXPORTER_CONNECTION_RETRIES", 0), "Number of times to retry a failed connection to a Redis instance (with exponential backoff, bounded by the connection timeout)")
+		connectionRetryBackoff         = flag.String("connection-retry-backoff", getEnv("REDIS_EXPORTER_CONNECTION_RETRY_BACKOFF", "100ms"), "Initial backoff between connection retries, doubled after every attempt")
+		circuitBreakerThreshold        = flag.Int64("circuit-breaker-threshold", getEnvInt64("REDIS_EXPORTER_CIRCUIT_BREAKER_THRESHOLD", 0), "Number of consecutive failed scrapes after which connection attempts to a target are skipped for the cooldown period, 0 disables the circuit breaker")
+		serveStaleOnError              = flag.String("serve-stale-on-error", getEnv("REDIS_EXPORTER_SERVE_STALE_ON_ERROR", "0s"), "How long the metrics of the last successful scrape of a target are served when a scrape fails, marked with redis_exporter_data_stale=1 while redis_up stays 0, 0s disables it")
+		waitForRedis                   = flag.String("wait-for-redis", getEnv("REDIS_EXPORTER_WAIT_FOR_REDIS", "0s"), "How long to retry loading the TLS client config and connecting to redis.addr at startup before exiting, 0s only checks the TLS client config once")
+		circuitBreakerCooldown         = flag.String("circuit-breaker-cooldown", getEnv("REDIS_EXPORTER_CIRCUIT_BREAKER_COOLDOWN", "30s"), "How long the circuit breaker stays open before the next connection attempt to a failing target")
+		counterStateFile               = flag.String("counter-state-file", getEnv("REDIS_EXPORTER_COUNTER_STATE_FILE", ""), "Path to a JSON file the exporter side counters (scrapes, role changes, events, cluster redirects) are saved to every 30s and on shutdown and restored from on start")
+		tenantsFile                    = flag.String("tenants-file", getEnv("REDIS_EXPORTER_TENANTS_FILE", ""), "Path to a JSON file with tenants, each with its own redis address, namespace, basic auth, checks and collectors, served at /tenants/<name>/metrics")
+		targetsFile                    = flag.String("targets-file", getEnv("REDIS_EXPORTER_TARGETS_FILE", ""), "Path to a JSON file (Prometheus file_sd format) with Redis targets that are collected in the background and served via /scrape")
+		targetsScrapeInterval          = flag.String("targets-scrape-interval", getEnv("REDIS_EXPORTER_TARGETS_SCRAPE_INTERVAL", "30s"), "How often the targets of the targets file are collected")
+		targetsScrapeConcurrency       = flag.Int64("targets-scrape-concurrency", getEnvInt64("REDIS_EXPORTER_TARGETS_SCRAPE_CONCURRENCY", 10), "Number of targets of the targets file that are collected concurrently")
+		targetsScrapeJitter            = flag.String("targets-scrape-jitter", getEnv("REDIS_EXPORTER_TARGETS_SCRAPE_JITTER", "0s"), "Maximum random delay before collecting a target of the targets file, spreads out connections to the Redis instances")
 		tlsClientKeyFile               = flag.String("tls-client-key-file", getEnv("REDIS_EXPORTER_TLS_CLIENT_KEY_FILE", ""), "Name of the client key file (including full path) if the server requires TLS client authentication")
 		tlsClientCertFile              = flag.String("tls-client-cert-file", getEnv("REDIS_EXPORTER_TLS_CLIENT_CERT_FILE", ""), "Name of the client certificate file (including full path) if the server requires TLS client authentication")
 		tlsCaCertFile                  = flag.String("tls-ca-cert-file", getEnv("REDIS_EXPORTER_TLS_CA_CERT_FILE", ""), "Name of the CA certificate file (including full path) if the server requires TLS client authentication")
@@ -181,35 +297,95 @@ func main() {
 		tlsServerCaCertFile            = flag.String("tls-server-ca-cert-file", getEnv("REDIS_EXPORTER_TLS_SERVER_CA_CERT_FILE", ""), "Name of the CA certificate file (including full path) if the web interface and telemetry should require TLS client authentication")
 		tlsServerMinVersion            = flag.String("tls-server-min-version", getEnv("REDIS_EXPORTER_TLS_SERVER_MIN_VERSION", "TLS1.2"), "Minimum TLS version that is acceptable by the web interface and telemetry when using TLS")
 		maxDistinctKeyGroups           = flag.Int64("max-distinct-key-groups", getEnvInt64("REDIS_EXPORTER_MAX_DISTINCT_KEY_GROUPS", 100), "The maximum number of distinct key groups with the most memory utilization to present as distinct metrics per database, the leftover key groups will be aggregated in the 'overflow' bucket")
+		keyGroupsMemorySamples         = flag.Int64("key-groups-memory-samples", getEnvInt64("REDIS_EXPORTER_KEY_GROUPS_MEMORY_SAMPLES", 0), "Number of nested values MEMORY USAGE samples per key of key groups, 0 uses the Redis default, -1 samples all values")
+		keyGroupsMemorySampleRatio     = flag.Float64("key-groups-memory-sample-ratio", getEnvFloat64("REDIS_EXPORTER_KEY_GROUPS_MEMORY_SAMPLE_RATIO", 1), "Fraction of keys of each key group whose memory usage is sampled, the memory usage of the group is extrapolated from the sample, 1 samples every key")
+		keyGroupsMinKeys               = flag.Int64("key-groups-min-keys", getEnvInt64("REDIS_EXPORTER_KEY_GROUPS_MIN_KEYS", 0), "Minimum number of keys of a key group to be reported as a distinct key group, smaller key groups are aggregated in the 'overflow' bucket")
 		isDebug                        = flag.Bool("debug", getEnvBool("REDIS_EXPORTER_DEBUG", false), "Output verbose debug information (sets log level to DEBUG, takes precedence over \"--log-level\")")
-		logLevel                       = flag.String("log-level", getEnv("REDIS_EXPORTER_LOG_LEVEL", "INFO"), "Set log level")
+		logLevel                       = flag.String("log-level", getEnv("REDIS_EXPORTER_LOG_LEVEL", "INFO"), "Set log level, valid options are DEBUG, INFO, WARN and ERROR")
 		logFormat                      = flag.String("log-format", getEnv("REDIS_EXPORTER_LOG_FORMAT", "txt"), "Log format, valid options are txt and json")
-		setClientName                  = flag.Bool("set-client-name", getEnvBool("REDIS_EXPORTER_SET_CLIENT_NAME", true), "Whether to set client name to redis_exporter")
+		logFile                        = flag.String("log-file", getEnv("REDIS_EXPORTER_LOG_FILE", ""), "Path to a file to write logs to instead of stderr")
+		logFileMaxSize                 = flag.Int64("log-file-max-size", getEnvInt64("REDIS_EXPORTER_LOG_FILE_MAX_SIZE", 100), "Maximum size in megabytes of the log file before it gets rotated, 0 disables size based rotation")
+		logFileMaxAge                  = flag.String("log-file-max-age", getEnv("REDIS_EXPORTER_LOG_FILE_MAX_AGE", "0s"), "Maximum age of the log file before it gets rotated, e.g. \"24h\", 0s disables age based rotation")
+		logFileMaxBackups              = flag.Int64("log-file-max-backups", getEnvInt64("REDIS_EXPORTER_LOG_FILE_MAX_BACKUPS", 5), "Number of rotated log files to keep, 0 keeps all of them")
+		setClientName                  = flag.Bool("set-client-name", getEnvBool("REDIS_EXPORTER_SET_CLIENT_NAME", true), "Whether to set client name to redis_exporter and the lib-name and lib-ver of CLIENT SETINFO")
+		isGarnet                       = flag.Bool("is-garnet", getEnvBool("REDIS_EXPORTER_IS_GARNET", false), "Whether to scrape the servers in Garnet compatibility mode, Garnet servers are detected by their INFO otherwise")
+		viaProxy                       = flag.Bool("via-proxy", getEnvBool("REDIS_EXPORTER_VIA_PROXY", false), "Whether the server is scraped through a proxy like Twemproxy or Envoy that rejects admin commands, only PING and the probes are run")
 		isTile38                       = flag.Bool("is-tile38", getEnvBool("REDIS_EXPORTER_IS_TILE38", false), "Whether to scrape Tile38 specific metrics")
 		isCluster                      = flag.Bool("is-cluster", getEnvBool("REDIS_EXPORTER_IS_CLUSTER", false), "Whether this is a redis cluster (Enable this if you need to fetch key level data on a Redis Cluster).")
+		checkKeysSlotRange             = flag.String("check-keys-slot-range", getEnv("REDIS_EXPORTER_CHECK_KEYS_SLOT_RANGE", ""), "Comma separated list of hash slots (e.g. 0-5460) or hash tags (e.g. {user1}) to limit check-keys and count-keys to in cluster mode, to shard key scanning across exporters")
+		clusterMaxRedirects            = flag.Int64("cluster-max-redirects", getEnvInt64("REDIS_EXPORTER_CLUSTER_MAX_REDIRECTS", 10), "Maximum number of MOVED/ASK redirects followed for a command of the key collectors in cluster mode")
+		disableSelect                  = flag.Bool("disable-select", getEnvBool("REDIS_EXPORTER_DISABLE_SELECT", false), "Whether to never send SELECT and restrict key collectors to db0, for proxies and providers that forbid SELECT (detected automatically if SELECT is rejected)")
 		exportClientList               = flag.Bool("export-client-list", getEnvBool("REDIS_EXPORTER_EXPORT_CLIENT_LIST", false), "Whether to scrape Client List specific metrics")
+		clientListMaxClients           = flag.Int64("client-list-max-clients", getEnvInt64("REDIS_EXPORTER_CLIENT_LIST_MAX_CLIENTS", 0), "Maximum number of clients exported when scraping the client list, 0 means no limit")
+		clientListMaxConnectedClients  = flag.Int64("client-list-max-connected-clients", getEnvInt64("REDIS_EXPORTER_CLIENT_LIST_MAX_CONNECTED_CLIENTS", 0), "Don't run CLIENT LIST, whose reply is read as a whole, if the server has more connected clients than this, 0 means no limit")
+		inclClientIdleMetrics          = flag.Bool("include-client-idle-metrics", getEnvBool("REDIS_EXPORTER_INCL_CLIENT_IDLE_METRICS", false), "Whether to export a histogram of the idle time of the connected clients (from CLIENT LIST) without exporting every client")
+		clientIdleThreshold            = flag.String("client-idle-threshold", getEnv("REDIS_EXPORTER_CLIENT_IDLE_THRESHOLD", "1h"), "Clients idle for longer than this are counted in redis_connected_clients_idle_over_threshold, 0s disables the count")
 		exportClientPort               = flag.Bool("export-client-port", getEnvBool("REDIS_EXPORTER_EXPORT_CLIENT_PORT", false), "Whether to include the client's port when exporting the client list. Warning: including the port increases the number of metrics generated and will make your Prometheus server take up more memory")
+		watchdogInterval               = flag.String("watchdog-interval", getEnv("REDIS_EXPORTER_WATCHDOG_INTERVAL", "0s"), "How often the watchdog sends a PING to redis.addr and checks for stuck collections, 0s disables the watchdog")
+		watchdogStuckAfter             = flag.String("watchdog-stuck-after", getEnv("REDIS_EXPORTER_WATCHDOG_STUCK_AFTER", "0s"), "Collections running for longer than this are considered stuck by the watchdog, set it above the scrape timeout of Prometheus and the duration of the key checks, 0s uses 20 times the connection-timeout")
+		watchdogExitOnStuck            = flag.Bool("watchdog-exit-on-stuck", getEnvBool("REDIS_EXPORTER_WATCHDOG_EXIT_ON_STUCK", false), "Whether to exit after the watchdog found a stuck collection so a supervisor can restart the exporter")
 		showVersion                    = flag.Bool("version", false, "Show version information and exit")
 		redisMetricsOnly               = flag.Bool("redis-only-metrics", getEnvBool("REDIS_EXPORTER_REDIS_ONLY_METRICS", false), "Whether to export only Redis metrics (omit Go process+runtime metrics)")
 		inclGoRuntimeMetrics           = flag.Bool("include-go-runtime-metrics", getEnvBool("REDIS_EXPORTER_INCLUDE_GO_RUNTIME_METRICS", false), "Whether to include Go runtime metrics")
 		pingOnConnect                  = flag.Bool("ping-on-connect", getEnvBool("REDIS_EXPORTER_PING_ON_CONNECT", false), "Whether to ping the redis instance after connecting")
 		inclConfigMetrics              = flag.Bool("include-config-metrics", getEnvBool("REDIS_EXPORTER_INCL_CONFIG_METRICS", false), "Whether to include all config settings as metrics")
+		expectedModules                = flag.String("expected-modules", getEnv("REDIS_EXPORTER_EXPECTED_MODULES", ""), "Comma separated list of modules every node must have loaded, optionally with a minimum version, eg: search>=2.10.0,ReJSON, exported as module_missing and module_version_outdated")
 		inclModulesMetrics             = flag.Bool("include-modules-metrics", getEnvBool("REDIS_EXPORTER_INCL_MODULES_METRICS", false), "Whether to collect Redis Modules metrics")
+		inclPubSubShardMetrics         = flag.Bool("include-pubsub-shard-metrics", getEnvBool("REDIS_EXPORTER_INCL_PUBSUB_SHARD_METRICS", false), "Whether to collect the subscribers of the shard channels of sharded pub/sub (Redis 7.0+)")
+		inclPubSubShardChannels        = flag.Bool("include-pubsub-shard-channels", getEnvBool("REDIS_EXPORTER_INCL_PUBSUB_SHARD_CHANNELS", false), "Whether to export the subscribers of each shard channel with include-pubsub-shard-metrics, one series per channel")
+		inclACLLogMetrics              = flag.Bool("include-acl-log-metrics", getEnvBool("REDIS_EXPORTER_INCL_ACL_LOG_METRICS", false), "Whether to export the number of new ACL LOG events (auth failures and permission denials) per username and reason")
+		aclLogCount                    = flag.Int64("acl-log-count", getEnvInt64("REDIS_EXPORTER_ACL_LOG_COUNT", 128), "Number of ACL LOG entries to fetch per scrape")
 		inclSearchIndexesMetrics       = flag.Bool("include-search-indexes-metrics", getEnvBool("REDIS_EXPORTER_INCL_SEARCH_INDEXES_METRICS", false), "Whether to collect Redis Search indexes metrics")
 		checkSearchIndexes             = flag.String("check-search-indexes", getEnv("REDIS_EXPORTER_CHECK_SEARCH_INDEXES", ".*"), "Regex pattern for Redis Search indexes to export metrics from FT.INFO command")
 		disableExportingKeyValues      = flag.Bool("disable-exporting-key-values", getEnvBool("REDIS_EXPORTER_DISABLE_EXPORTING_KEY_VALUES", false), "Whether to disable values of keys stored in redis as labels or not when using check-keys/check-single-key")
+		latencyPercentiles             = flag.String("latency-percentiles", getEnv("REDIS_EXPORTER_LATENCY_PERCENTILES", ""), "Comma separated list of the LATENCYSTATS percentiles to export, e.g. p50,p99,p99.9, empty for all percentiles Redis reports")
+		latencyPercentilesFormat       = flag.String("latency-percentiles-format", getEnv("REDIS_EXPORTER_LATENCY_PERCENTILES_FORMAT", "summary"), "Export the LATENCYSTATS percentiles as a summary per command (summary) or as a gauge per command and percentile (gauges)")
 		excludeLatencyHistogramMetrics = flag.Bool("exclude-latency-histogram-metrics", getEnvBool("REDIS_EXPORTER_EXCLUDE_LATENCY_HISTOGRAM_METRICS", false), "Do not try to collect latency histogram metrics")
+		configMetricsInclude           = flag.String("config-metrics-include", getEnv("REDIS_EXPORTER_CONFIG_METRICS_INCLUDE", ""), "Comma separated list of config parameters to export as metrics, e.g. \"maxmemory,maxmemory-policy,appendonly\"")
+		labelValuesMaxBytes            = flag.Int64("label-values-max-bytes", getEnvInt64("REDIS_EXPORTER_LABEL_VALUES_MAX_BYTES", 0), "Maximum length in bytes of the key, stream, group and consumer names used as label values, longer names are handled according to label-values-too-long, 0 means unlimited")
+		labelValuesTooLong             = flag.String("label-values-too-long", getEnv("REDIS_EXPORTER_LABEL_VALUES_TOO_LONG", "truncate"), "How names longer than label-values-max-bytes are exported, \"truncate\" (with a short hash of the name appended) or \"hash\" (a hash mapped back to the name by redis_label_value_hash_info)")
+		labelValuesStripNonUTF8        = flag.Bool("label-values-strip-non-utf8", getEnvBool("REDIS_EXPORTER_LABEL_VALUES_STRIP_NON_UTF8", false), "Whether to remove invalid UTF-8 from the key, stream, group and consumer names used as label values")
+		dbLabelFormat                  = flag.String("db-label-format", getEnv("REDIS_EXPORTER_DB_LABEL_FORMAT", "prefixed"), "Format of the db label: prefixed (db=\"db0\"), numeric (db=\"0\") or both (db=\"db0\" and db_number=\"0\")")
+		validateSeries                 = flag.Bool("validate-series", getEnvBool("REDIS_EXPORTER_VALIDATE_SERIES", false), "Whether to check every collection for duplicate series, inconsistent labels and invalid label values, e.g. produced by Lua scripts or key names, and drop and log them instead of failing the scrape, always on with the debug log level")
+		maxSeriesPerFamily             = flag.Int64("max-series-per-family", getEnvInt64("REDIS_EXPORTER_MAX_SERIES_PER_FAMILY", 0), "Maximum number of labeled series per metric, additional series are summed into the unlabeled <metric>_overflow metric, 0 means no limit")
+		metricMappingFile              = flag.String("metric-mapping-file", getEnv("REDIS_EXPORTER_METRIC_MAPPING_FILE", ""), "Path to a JSON file to rename metrics, override their help text or force their type")
+		configDriftFile                = flag.String("config-drift-file", getEnv("REDIS_EXPORTER_CONFIG_DRIFT_FILE", ""), "Path to a JSON file with expected config values, differences are exported as redis_config_drift")
 		redactConfigMetrics            = flag.Bool("redact-config-metrics", getEnvBool("REDIS_EXPORTER_REDACT_CONFIG_METRICS", true), "Whether to redact config settings that include potentially sensitive information like passwords")
 		inclSystemMetrics              = flag.Bool("include-system-metrics", getEnvBool("REDIS_EXPORTER_INCL_SYSTEM_METRICS", false), "Whether to include system metrics like e.g. redis_total_system_memory_bytes")
+		inclKernelWarnings             = flag.Bool("include-kernel-warnings", getEnvBool("REDIS_EXPORTER_INCL_KERNEL_WARNINGS", false), "Whether to include the kernel misconfigurations Redis warns about (transparent huge pages, overcommit_memory, somaxconn) as redis_kernel_warning")
+		inclCPUForkMetrics             = flag.Bool("include-cpu-fork-metrics", getEnvBool("REDIS_EXPORTER_INCL_CPU_FORK_METRICS", false), "Whether to include a histogram of the fork durations")
+		inclDerivedMetrics             = flag.Bool("include-derived-metrics", getEnvBool("REDIS_EXPORTER_INCL_DERIVED_METRICS", false), "Whether to include metrics derived from INFO fields like e.g. redis_derived_keyspace_hit_ratio")
+		exportUnknownInfoFields        = flag.Bool("export-unknown-info-fields", getEnvBool("REDIS_EXPORTER_EXPORT_UNKNOWN_INFO_FIELDS", false), "Whether to export the numeric INFO fields the exporter doesn't map, under their sanitized name with a section label")
+		infoFieldsInclude              = flag.String("info-fields-include", getEnv("REDIS_EXPORTER_INFO_FIELDS_INCLUDE", ""), "Comma separated list of globs of the INFO fields or metric names to produce metrics from, e.g. \"used_memory,connected_clients,cmdstat_*\", empty means all")
+		infoFieldsExclude              = flag.String("info-fields-exclude", getEnv("REDIS_EXPORTER_INFO_FIELDS_EXCLUDE", ""), "Comma separated list of globs of the INFO fields or metric names not to produce metrics from, applied after info-fields-include")
 		skipTLSVerification            = flag.Bool("skip-tls-verification", getEnvBool("REDIS_EXPORTER_SKIP_TLS_VERIFICATION", false), "Whether to to skip TLS verification")
 		skipCheckKeysForRoleMaster     = flag.Bool("skip-checkkeys-for-role-master", getEnvBool("REDIS_EXPORTER_SKIP_CHECKKEYS_FOR_ROLE_MASTER", false), "Whether to skip gathering the check-keys metrics (size, val) when the instance is of type master (reduce load on master nodes)")
+		checkPermissions               = flag.Bool("check-permissions", getEnvBool("REDIS_EXPORTER_CHECK_PERMISSIONS", false), "Whether to check at startup that the user can run the commands of the enabled collectors (requires Redis 7.0+) and log the missing permissions")
+		assumeReadonlyReplica          = flag.Bool("assume-readonly-replica", getEnvBool("REDIS_EXPORTER_ASSUME_READONLY_REPLICA", false), "Whether the target is a read-only endpoint: READONLY is sent to cluster replicas and the collectors that write or run scripts or arbitrary commands (WAIT probe, key groups, probes, Lua scripts) are skipped")
 		basicAuthUsername              = flag.String("basic-auth-username", getEnv("REDIS_EXPORTER_BASIC_AUTH_USERNAME", ""), "Username for basic authentication")
 		basicAuthPassword              = flag.String("basic-auth-password", getEnv("REDIS_EXPORTER_BASIC_AUTH_PASSWORD", ""), "Password for basic authentication, conflicts with --basic-auth-hash-password")
 		basicAuthHashPassword          = flag.String("basic-auth-hash-password", getEnv("REDIS_EXPORTER_BASIC_AUTH_HASH_PASSWORD", ""), "Hashed password for basic authentication, bcrypt format, conflicts with --basic-auth-password")
-
+		auditLog                       = flag.Bool("audit-log", getEnvBool("REDIS_EXPORTER_AUDIT_LOG", false), "Whether to log every request to the metrics path and /scrape (client ip, target, duration, outcome)")
+		logLatencyMonitorHint          = flag.Bool("log-latency-monitor-hint", getEnvBool("REDIS_EXPORTER_LOG_LATENCY_MONITOR_HINT", false), "Whether to log a hint once per target if latency monitoring is disabled (latency-monitor-threshold is 0)")
+		allowDebugScrape               = flag.Bool("allow-debug-scrape", getEnvBool("REDIS_EXPORTER_ALLOW_DEBUG_SCRAPE", false), "Whether to allow /scrape?debug=true which logs the scrape at debug level and returns its warnings as headers and comments of the response")
+		tracingOTLPEndpoint            = flag.String("tracing-otlp-endpoint", getEnv("REDIS_EXPORTER_TRACING_OTLP_ENDPOINT", ""), "OTLP/HTTP endpoint to send the traces of the scrapes to, e.g. http://localhost:4318, tracing is disabled if empty")
+		tracingSampleRatio             = flag.Float64("tracing-sample-ratio", getEnvFloat64("REDIS_EXPORTER_TRACING_SAMPLE_RATIO", 1), "Fraction of scrapes that are traced, between 0 and 1")
+		scrapeRateLimit                = flag.Float64("scrape-rate-limit", getEnvFloat64("REDIS_EXPORTER_SCRAPE_RATE_LIMIT", 0), "Maximum number of requests per second to /scrape across all clients, 0 disables the limit")
+		scrapeRateBurst                = flag.Int64("scrape-rate-burst", getEnvInt64("REDIS_EXPORTER_SCRAPE_RATE_BURST", 10), "Number of requests to /scrape across all clients allowed to exceed scrape-rate-limit in a burst")
+		scrapeRateLimitPerClient       = flag.Float64("scrape-rate-limit-per-client", getEnvFloat64("REDIS_EXPORTER_SCRAPE_RATE_LIMIT_PER_CLIENT", 0), "Maximum number of requests per second to /scrape per client IP, 0 disables the limit")
+		scrapeRateBurstPerClient       = flag.Int64("scrape-rate-burst-per-client", getEnvInt64("REDIS_EXPORTER_SCRAPE_RATE_BURST_PER_CLIENT", 5), "Number of requests to /scrape per client IP allowed to exceed scrape-rate-limit-per-client in a burst")
+
+		growthWindow                 = flag.String("growth-window", getEnv("REDIS_EXPORTER_GROWTH_WINDOW", "0s"), "Window of the linear regression of memory_growth_bytes_per_hour and db_keys_growth_per_hour, 0s disables them")
+		events                       = flag.Bool("events", getEnvBool("REDIS_EXPORTER_EVENTS", false), "Whether to record role changes, master link, cluster state and AOF write status transitions, served on /events and exported as events_total")
 		inclMetricsForEmptyDatabases = flag.Bool("include-metrics-for-empty-databases", getEnvBool("REDIS_EXPORTER_INCL_METRICS_FOR_EMPTY_DATABASES", true), "Whether to emit db metrics (like db_keys) for empty databases")
 	)
-	flag.Parse()
+	// print-acl is a subcommand, its flags are the flags of the exporter
+	printACL := len(os.Args) > 1 && os.Args[1] == "print-acl"
+	if printACL {
+		_ = flag.CommandLine.Parse(os.Args[2:])
+	} else {
+		flag.Parse()
+	}
 
 	if *showVersion {
 		log.SetOutput(os.Stdout)
@@ -227,6 +403,21 @@ func main() {
 	if err := setupLogging(*isDebug, *logLevel, *logFormat); err != nil {
 		log.Fatalf("Failed to setup logging: %v", err)
 	}
+	if *logFile != "" {
+		maxAge, err := time.ParseDuration(*logFileMaxAge)
+		if err != nil {
+			log.Fatalf("Couldn't parse log file max age duration, err: %s", err)
+		}
+		f, err := newRotatingFile(*logFile, *logFileMaxSize*1024*1024, maxAge, int(*logFileMaxBackups))
+		if err != nil {
+			log.Fatalf("Couldn't open log file %s, err: %s", *logFile, err)
+		}
+		defer f.Close()
+		log.SetOutput(f)
+	}
+	if *auditLog {
+		exporter.ConfigureAuditLog()
+	}
 	if *isDebug {
 		log.Debugln("Enabling debug output")
 	}
@@ -237,14 +428,179 @@ func main() {
 		log.Fatalf("Couldn't parse connection timeout duration, err: %s", err)
 	}
 
-	passwordMap := make(map[string]string)
+	retryBackoff, err := time.ParseDuration(*connectionRetryBackoff)
+	if err != nil {
+		log.Fatalf("Couldn't parse connection retry backoff duration, err: %s", err)
+	}
+
+	cbCooldown, err := time.ParseDuration(*circuitBreakerCooldown)
+	if err != nil {
+		log.Fatalf("Couldn't parse circuit breaker cooldown duration, err: %s", err)
+	}
+
+	staleWindow, err := time.ParseDuration(*serveStaleOnError)
+	if err != nil {
+		log.Fatalf("Couldn't parse serve stale on error duration, err: %s", err)
+	}
+
+	growthDuration, err := time.ParseDuration(*growthWindow)
+	if err != nil {
+		log.Fatalf("Couldn't parse growth window duration, err: %s", err)
+	}
+
+	waitRedisTimeout, err := time.ParseDuration(*waitForRedis)
+	if err != nil {
+		log.Fatalf("Couldn't parse wait for redis duration, err: %s", err)
+	}
+
+	targetsInterval, err := time.ParseDuration(*targetsScrapeInterval)
+	if err != nil {
+		log.Fatalf("Couldn't parse targets scrape interval duration, err: %s", err)
+	}
+
+	targetsJitter, err := time.ParseDuration(*targetsScrapeJitter)
+	if err != nil {
+		log.Fatalf("Couldn't parse targets scrape jitter duration, err: %s", err)
+	}
+
+	consumerMinIdle, err := time.ParseDuration(*streamsConsumerMinIdle)
+	if err != nil {
+		log.Fatalf("Couldn't parse streams consumer min idle duration, err: %s", err)
+	}
+
+	idleThreshold, err := time.ParseDuration(*clientIdleThreshold)
+	if err != nil {
+		log.Fatalf("Couldn't parse client idle threshold duration, err: %s", err)
+	}
+
+	watchdogEvery, err := time.ParseDuration(*watchdogInterval)
+	if err != nil {
+		log.Fatalf("Couldn't parse watchdog interval duration, err: %s", err)
+	}
+
+	watchdogStuck, err := time.ParseDuration(*watchdogStuckAfter)
+	if err != nil {
+		log.Fatalf("Couldn't parse watchdog stuck after duration, err: %s", err)
+	}
+
+	leaderTTL, err := time.ParseDuration(*leaderElectionTTL)
+	if err != nil {
+		log.Fatalf("Couldn't parse leader election ttl duration, err: %s", err)
+	}
+
+	waitTimeout, err := time.ParseDuration(*waitProbeTimeout)
+	if err != nil {
+		log.Fatalf("Couldn't parse wait probe timeout duration, err: %s", err)
+	}
+
+	readTimeout, err := time.ParseDuration(*webReadTimeout)
+	if err != nil {
+		log.Fatalf("Couldn't parse web read timeout duration, err: %s", err)
+	}
+
+	readHeaderTimeout, err := time.ParseDuration(*webReadHeaderTimeout)
+	if err != nil {
+		log.Fatalf("Couldn't parse web read header timeout duration, err: %s", err)
+	}
+
+	writeTimeout, err := time.ParseDuration(*webWriteTimeout)
+	if err != nil {
+		log.Fatalf("Couldn't parse web write timeout duration, err: %s", err)
+	}
+
+	idleTimeout, err := time.ParseDuration(*webIdleTimeout)
+	if err != nil {
+		log.Fatalf("Couldn't parse web idle timeout duration, err: %s", err)
+	}
+
+	var pwdFileKey []byte
+	switch {
+	case *redisPwdFileKey != "":
+		pwdFileKey, err = exporter.ParsePwdFileKey(*redisPwdFileKey)
+	case *redisPwdFileKeyFile != "":
+		pwdFileKey, err = exporter.LoadPwdFileKey(*redisPwdFileKeyFile)
+	}
+	if err != nil {
+		log.Fatalf("Error loading password file key, err: %s", err)
+	}
+
+	if *encryptPwdFile != "" {
+		if pwdFileKey == nil {
+			log.Fatalf("Encrypting a password file requires --redis.password-file-key or --redis.password-file-key-file")
+		}
+		plaintext, err := os.ReadFile(*encryptPwdFile)
+		if err != nil {
+			log.Fatalf("Error reading password file %s, err: %s", *encryptPwdFile, err)
+		}
+		encrypted, err := exporter.EncryptPwdFile(plaintext, pwdFileKey)
+		if err != nil {
+			log.Fatalf("Error encrypting password file %s, err: %s", *encryptPwdFile, err)
+		}
+		os.Stdout.Write(encrypted)
+		return
+	}
+
+	var passwordEntries map[string]exporter.PasswordEntry
 	if *redisPwd == "" && *redisPwdFile != "" {
-		passwordMap, err = exporter.LoadPwdFile(*redisPwdFile)
+		passwordEntries, err = exporter.LoadPwdFileEntries(*redisPwdFile, pwdFileKey)
 		if err != nil {
 			log.Fatalf("Error loading redis passwords from file %s, err: %s", *redisPwdFile, err)
 		}
 	}
 
+	var expectedConfig map[string]string
+	if *configDriftFile != "" {
+		expectedConfig, err = exporter.LoadExpectedConfigFile(*configDriftFile)
+		if err != nil {
+			log.Fatalf("Error loading expected config from file %s, err: %s", *configDriftFile, err)
+		}
+	}
+
+	var keyMetricNames map[string]string
+	if *keyMetricNamesFile != "" {
+		keyMetricNames, err = exporter.LoadKeyMetricNamesFile(*keyMetricNamesFile)
+		if err != nil {
+			log.Fatalf("Error loading key metric names from file %s, err: %s", *keyMetricNamesFile, err)
+		}
+	}
+
+	subsystemNamespaces, err := exporter.ParseSubsystemNamespaces(*namespaceOverrides)
+	if err != nil {
+		log.Fatalf("Error parsing namespace overrides, err: %s", err)
+	}
+
+	var metricMapping map[string]exporter.MetricMapping
+	if *metricMappingFile != "" {
+		metricMapping, err = exporter.LoadMetricMappingFile(*metricMappingFile)
+		if err != nil {
+			log.Fatalf("Error loading metric mapping from file %s, err: %s", *metricMappingFile, err)
+		}
+	}
+
+	var probes []exporter.Probe
+	if *probeConfigFile != "" {
+		probes, err = exporter.LoadProbeConfigFile(*probeConfigFile)
+		if err != nil {
+			log.Fatalf("Error loading probes from file %s, err: %s", *probeConfigFile, err)
+		}
+	}
+
+	var tenants []exporter.Tenant
+	if *tenantsFile != "" {
+		tenants, err = exporter.LoadTenantsFile(*tenantsFile)
+		if err != nil {
+			log.Fatalf("Error loading tenants from file %s, err: %s", *tenantsFile, err)
+		}
+	}
+
+	var keyGroups []exporter.KeyGroup
+	if *keyGroupsFile != "" {
+		keyGroups, err = exporter.LoadKeyGroupsFile(*keyGroupsFile)
+		if err != nil {
+			log.Fatalf("Error loading key groups from file %s, err: %s", *keyGroupsFile, err)
+		}
+	}
+
 	ls, err := loadScripts(*scriptPath)
 	if err != nil {
 		log.Fatalf("Error loading script files: %s", err)
@@ -252,47 +608,146 @@ func main() {
 
 	registry := createPrometheusRegistry(*redisMetricsOnly, *inclGoRuntimeMetrics)
 
+	var tracerProvider trace.TracerProvider
+	if *tracingOTLPEndpoint != "" && !printACL {
+		tp, err := newTracerProvider(*tracingOTLPEndpoint, *tracingSampleRatio, BuildVersion)
+		if err != nil {
+			log.Fatalf("Error setting up tracing, err: %s", err)
+		}
+		defer tp.Shutdown(context.Background())
+		tracerProvider = tp
+	}
+
 	exp, err := exporter.NewRedisExporter(
 		*redisAddr,
 		exporter.Options{
 			User:                           *redisUser,
 			Password:                       *redisPwd,
-			PasswordMap:                    passwordMap,
+			PasswordEntries:                passwordEntries,
 			Namespace:                      *namespace,
+			SubsystemNamespaces:            subsystemNamespaces,
 			ConfigCommandName:              *configCommand,
 			CheckKeys:                      *checkKeys,
 			CheckSingleKeys:                *checkSingleKeys,
+			CheckKeysFromKey:               *checkKeysFromKey,
+			CheckKeysAPIFile:               *checkKeysAPIFile,
 			CheckKeysBatchSize:             *checkKeysBatchSize,
+			CheckKeysPipelineSize:          *checkKeysPipelineSize,
+			CheckKeysAsMetricNames:         *checkKeysAsMetricNames,
+			CheckKeysAggregate:             *checkKeysAggregate,
+			CheckKeysTopN:                  *checkKeysTopN,
+			CheckKeysSort:                  *checkKeysSort,
+			CheckKeysScanBudget:            *checkKeysScanBudget,
+			MaxCheckedKeys:                 *maxCheckedKeys,
+			MaxCheckedStreams:              *maxCheckedStreams,
+			MaxStreamConsumers:             *maxStreamConsumers,
+			CheckKeysDumpSize:              *checkKeysDumpSize,
+			CheckKeysDumpMaxKeys:           *checkKeysDumpMaxKeys,
+			CheckKeysDumpSampleRatio:       *checkKeysDumpSampleRatio,
+			CheckKeysHexLabels:             *checkKeysHexLabels,
+			KeySampleCount:                 *keySampleCount,
+			KeySampleDbs:                   *keySampleDbs,
+			DbsizeFallbackDbs:              *dbsizeFallbackDbs,
+			KeyMetricNames:                 keyMetricNames,
 			CheckKeyGroups:                 *checkKeyGroups,
+			KeyGroups:                      keyGroups,
 			MaxDistinctKeyGroups:           *maxDistinctKeyGroups,
+			KeyGroupsMemorySamples:         *keyGroupsMemorySamples,
+			KeyGroupsMemorySampleRatio:     *keyGroupsMemorySampleRatio,
+			KeyGroupsMinKeys:               *keyGroupsMinKeys,
 			CheckStreams:                   *checkStreams,
 			CheckSingleStreams:             *checkSingleStreams,
 			StreamsExcludeConsumerMetrics:  *streamsExcludeConsumerMetrics,
+			StreamsConsumerFilter:          *streamsConsumerFilter,
+			StreamsConsumerMinPending:      *streamsConsumerMinPending,
+			StreamsConsumerMinIdle:         consumerMinIdle,
+			StreamsUseXinfoFull:            *streamsUseXinfoFull,
+			StreamsXinfoFullCount:          *streamsXinfoFullCount,
 			CountKeys:                      *countKeys,
 			LuaScript:                      ls,
+			Probes:                         probes,
+			Tenants:                        tenants,
+			CounterStateFile:               *counterStateFile,
+			WaitProbeKey:                   *waitProbeKey,
+			WaitProbeReplicas:              int(*waitProbeReplicas),
+			WaitProbeTimeout:               waitTimeout,
 			InclSystemMetrics:              *inclSystemMetrics,
+			InclDerivedMetrics:             *inclDerivedMetrics,
+			InclCPUForkMetrics:             *inclCPUForkMetrics,
+			InclKernelWarnings:             *inclKernelWarnings,
+			ExportUnknownInfoFields:        *exportUnknownInfoFields,
+			InfoFieldsInclude:              *infoFieldsInclude,
+			InfoFieldsExclude:              *infoFieldsExclude,
 			InclConfigMetrics:              *inclConfigMetrics,
+			ConfigMetricsInclude:           *configMetricsInclude,
 			DisableExportingKeyValues:      *disableExportingKeyValues,
 			ExcludeLatencyHistogramMetrics: *excludeLatencyHistogramMetrics,
+			LatencyPercentiles:             *latencyPercentiles,
+			LatencyPercentilesFormat:       *latencyPercentilesFormat,
 			RedactConfigMetrics:            *redactConfigMetrics,
+			ExpectedConfig:                 expectedConfig,
+			MetricMapping:                  metricMapping,
+			MaxSeriesPerFamily:             int(*maxSeriesPerFamily),
+			ValidateSeries:                 *validateSeries,
+			LabelValuesMaxBytes:            int(*labelValuesMaxBytes),
+			LabelValuesTooLong:             *labelValuesTooLong,
+			LabelValuesStripNonUTF8:        *labelValuesStripNonUTF8,
+			DbLabelFormat:                  *dbLabelFormat,
 			SetClientName:                  *setClientName,
 			IsTile38:                       *isTile38,
+			IsGarnet:                       *isGarnet,
+			ViaProxy:                       *viaProxy,
 			IsCluster:                      *isCluster,
+			ClusterMaxRedirects:            int(*clusterMaxRedirects),
+			CheckKeysSlotRange:             *checkKeysSlotRange,
 			InclModulesMetrics:             *inclModulesMetrics,
+			ExpectedModules:                *expectedModules,
+			InclPubSubShardMetrics:         *inclPubSubShardMetrics,
+			InclPubSubShardChannels:        *inclPubSubShardChannels,
+			InclACLLogMetrics:              *inclACLLogMetrics,
+			ACLLogCount:                    *aclLogCount,
 			InclSearchIndexesMetrics:       *inclSearchIndexesMetrics,
 			CheckSearchIndexes:             *checkSearchIndexes,
 			ExportClientList:               *exportClientList,
+			ClientListMaxClients:           *clientListMaxClients,
+			ClientListMaxConnectedClients:  *clientListMaxConnectedClients,
+			InclClientIdleMetrics:          *inclClientIdleMetrics,
+			ClientIdleThreshold:            idleThreshold,
+			WatchdogInterval:               watchdogEvery,
+			WatchdogStuckAfter:             watchdogStuck,
+			WatchdogExitOnStuck:            *watchdogExitOnStuck,
 			ExportClientsInclPort:          *exportClientPort,
 			SkipCheckKeysForRoleMaster:     *skipCheckKeysForRoleMaster,
+			AssumeReadonlyReplica:          *assumeReadonlyReplica,
 			SkipTLSVerification:            *skipTLSVerification,
 			ClientCertFile:                 *tlsClientCertFile,
 			ClientKeyFile:                  *tlsClientKeyFile,
 			CaCertFile:                     *tlsCaCertFile,
 			ConnectionTimeouts:             to,
+			ConnectionRetries:              int(*connectionRetries),
+			ConnectionRetryBackoff:         retryBackoff,
+			CircuitBreakerThreshold:        int(*circuitBreakerThreshold),
+			CircuitBreakerCooldown:         cbCooldown,
+			ServeStaleOnError:              staleWindow,
+			TargetsFile:                    *targetsFile,
+			TargetsScrapeInterval:          targetsInterval,
+			TargetsScrapeConcurrency:       int(*targetsScrapeConcurrency),
+			TargetsScrapeJitter:            targetsJitter,
+			HAReplicaName:                  *haReplicaName,
+			Zone:                           *zone,
+			LeaderElectionKey:              *leaderElectionKey,
+			LeaderElectionTTL:              leaderTTL,
 			MetricsPath:                    *metricPath,
+			ExpositionFormat:               *expositionFormat,
+			LandingPageTitle:               *landingPageTitle,
+			AdminListenAddress:             *webAdminListenAddress,
+			GRPCHealthListenAddress:        *grpcHealthListenAddress,
+			WebAllowedCIDRs:                *webAllowedCIDRs,
+			DisableLandingPage:             *disableLandingPage,
 			RedisMetricsOnly:               *redisMetricsOnly,
 			PingOnConnect:                  *pingOnConnect,
 			RedisPwdFile:                   *redisPwdFile,
+			RedisPwdFileKey:                pwdFileKey,
 			Registry:                       registry,
 			BuildInfo: exporter.BuildInfo{
 				Version:   BuildVersion,
@@ -302,12 +757,27 @@ func main() {
 			BasicAuthUsername:            *basicAuthUsername,
 			BasicAuthPassword:            *basicAuthPassword,
 			BasicAuthHashPassword:        *basicAuthHashPassword,
+			AuditLog:                     *auditLog,
+			ScrapeRateLimit:              *scrapeRateLimit,
+			ScrapeRateBurst:              int(*scrapeRateBurst),
+			ScrapeRateLimitPerClient:     *scrapeRateLimitPerClient,
+			ScrapeRateBurstPerClient:     int(*scrapeRateBurstPerClient),
 			InclMetricsForEmptyDatabases: *inclMetricsForEmptyDatabases,
+			GrowthWindow:                 growthDuration,
+			Events:                       *events,
+			DisableSelect:                *disableSelect,
+			AllowDebugScrape:             *allowDebugScrape,
+			LogLatencyMonitorHint:        *logLatencyMonitorHint,
+			TracerProvider:               tracerProvider,
 		},
 	)
 	if err != nil {
 		log.Fatal(err)
 	}
+	if printACL {
+		os.Stdout.WriteString(exp.MinimalACL() + "\n")
+		return
+	}
 	// Validate auth parameters
 	if err := validateAuthParams(*basicAuthPassword, *basicAuthHashPassword); err != nil {
 		log.Fatal(err)
@@ -317,48 +787,90 @@ func main() {
 	if err := validateTLSClientConfig(*tlsClientCertFile, *tlsClientKeyFile); err != nil {
 		log.Fatal(err)
 	}
-	_, err = exp.CreateClientTLSConfig()
-	if err != nil {
+	if waitRedisTimeout > 0 {
+		if err := exp.WaitForRedis(context.Background(), waitRedisTimeout); err != nil {
+			log.Fatal(err)
+		}
+	} else if _, err = exp.CreateClientTLSConfig(); err != nil {
 		log.Fatal(err)
 	}
 
+	if *checkPermissions {
+		if err := exp.CheckPermissions(); err != nil {
+			log.Errorf("Couldn't check the permissions of the Redis user, err: %s", err)
+		}
+	}
+
+	schedulerCtx, stopScheduler := context.WithCancel(context.Background())
+	defer stopScheduler()
+	if err := exp.StartTargetScheduler(schedulerCtx); err != nil {
+		log.Fatalf("Error loading targets file %s, err: %s", *targetsFile, err)
+	}
+	exp.StartWatchdog(schedulerCtx)
+	exp.StartCounterStatePersistence(schedulerCtx)
+	if err := exp.StartGRPCHealthServer(schedulerCtx); err != nil {
+		log.Fatalf("Error starting the gRPC health server on %s, err: %s", *grpcHealthListenAddress, err)
+	}
+	if err := exp.StartLeaderElection(schedulerCtx); err != nil {
+		log.Fatalf("Error starting the leader election, err: %s", err)
+	}
+
 	log.Infof("Providing metrics at %s%s", *listenAddress, *metricPath)
 	log.Debugf("Configured redis addr: %#v", *redisAddr)
-	server := &http.Server{
-		Addr:    *listenAddress,
-		Handler: exp,
+	serverOpts := httpServerOptions{
+		ReadTimeout:       readTimeout,
+		ReadHeaderTimeout: readHeaderTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       idleTimeout,
+		MaxHeaderBytes:    int(*webMaxHeaderBytes),
+		DisableHTTP2:      *webDisableHTTP2,
 	}
-	go func() {
-		if *tlsServerCertFile != "" && *tlsServerKeyFile != "" {
-			log.Debugf("Bind as TLS using cert %s and key %s", *tlsServerCertFile, *tlsServerKeyFile)
-
-			tlsConfig, err := exp.CreateServerTLSConfig(*tlsServerCertFile, *tlsServerKeyFile, *tlsServerCaCertFile, *tlsServerMinVersion)
-			if err != nil {
-				log.Fatal(err)
-			}
-			server.TLSConfig = tlsConfig
-			if err := server.ListenAndServeTLS("", ""); err != nil && !errors.Is(err, http.ErrServerClosed) {
-				log.Fatalf("TLS Server error: %v", err)
-			}
-		} else {
-			if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-				log.Fatalf("Server error: %v", err)
+	servers := []*http.Server{newHTTPServer(*listenAddress, exp, serverOpts)}
+	if *webAdminListenAddress != "" {
+		log.Infof("Providing the admin endpoints at %s", *webAdminListenAddress)
+		servers = append(servers, newHTTPServer(*webAdminListenAddress, exp.AdminHandler(), serverOpts))
+	}
+	for _, server := range servers {
+		go func() {
+			if *tlsServerCertFile != "" && *tlsServerKeyFile != "" {
+				log.Debugf("Bind %s as TLS using cert %s and key %s", server.Addr, *tlsServerCertFile, *tlsServerKeyFile)
+
+				tlsConfig, err := exp.CreateServerTLSConfig(*tlsServerCertFile, *tlsServerKeyFile, *tlsServerCaCertFile, *tlsServerMinVersion)
+				if err != nil {
+					log.Fatal(err)
+				}
+				server.TLSConfig = tlsConfig
+				if err := server.ListenAndServeTLS("", ""); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					log.Fatalf("TLS Server error: %v", err)
+				}
+			} else {
+				if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					log.Fatalf("Server error: %v", err)
+				}
 			}
-		}
-	}()
+		}()
+	}
 
 	// graceful shutdown
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	_quit := <-quit
+	signal.Notify(shutdownSignals, syscall.SIGINT, syscall.SIGTERM)
+	_quit := <-shutdownSignals
 	log.Infof("Received %s signal, exiting", _quit.String())
 	// Create a context with a timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Shutdown the HTTP server gracefully
-	if err := server.Shutdown(ctx); err != nil {
-		log.Fatalf("Server shutdown failed: %v", err)
+	// Shutdown the HTTP servers gracefully
+	for _, server := range servers {
+		if err := server.Shutdown(ctx); err != nil {
+			log.Fatalf("Server shutdown failed: %v", err)
+		}
 	}
 	log.Infof("Server shut down gracefully")
+
+	// the other exporter can take over right away instead of waiting for the lock to expire
+	exp.StopLeaderElection()
+
+	if err := exp.SaveCounterState(); err != nil {
+		log.Errorf("Couldn't save the counters to %s, err: %s", *counterStateFile, err)
+	}
 }
@@ -1,10 +1,12 @@
 package main
 
 import (
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -212,6 +214,63 @@ func TestGetEnvInt64(t *testing.T) {
 	}
 }
 
+func TestGetEnvFloat64(t *testing.T) {
+	tests := []struct {
+		name       string
+		key        string
+		defaultVal float64
+		envValue   string
+		setEnv     bool
+		expected   float64
+	}{
+		{
+			name:       "valid float",
+			key:        "TEST_FLOAT_VALID",
+			defaultVal: 1,
+			envValue:   "0.5",
+			setEnv:     true,
+			expected:   0.5,
+		},
+		{
+			name:       "integer value",
+			key:        "TEST_FLOAT_INT",
+			defaultVal: 1,
+			envValue:   "20",
+			setEnv:     true,
+			expected:   20,
+		},
+		{
+			name:       "invalid float returns default",
+			key:        "TEST_FLOAT_INVALID",
+			defaultVal: 2.5,
+			envValue:   "not_a_number",
+			setEnv:     true,
+			expected:   2.5,
+		},
+		{
+			name:       "environment variable does not exist",
+			key:        "NONEXISTENT_FLOAT_VAR",
+			defaultVal: 42,
+			setEnv:     false,
+			expected:   42,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.setEnv {
+				os.Setenv(tt.key, tt.envValue)
+				defer os.Unsetenv(tt.key)
+			}
+
+			result := getEnvFloat64(tt.key, tt.defaultVal)
+			if result != tt.expected {
+				t.Errorf("getEnvFloat64() = %v, expected %v", result, tt.expected)
+			}
+		})
+	}
+}
+
 func TestParseLogLevel(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -546,3 +605,28 @@ func TestValidateAuthParams(t *testing.T) {
 		})
 	}
 }
+
+func TestNewHTTPServer(t *testing.T) {
+	handler := http.NewServeMux()
+
+	server := newHTTPServer(":9121", handler, httpServerOptions{})
+	if server.Addr != ":9121" || server.ReadTimeout != 0 || server.WriteTimeout != 0 || server.MaxHeaderBytes != 0 || server.Protocols != nil {
+		t.Errorf("unexpected default server settings: %#v", server)
+	}
+
+	server = newHTTPServer(":9121", handler, httpServerOptions{
+		ReadTimeout:       5 * time.Second,
+		ReadHeaderTimeout: 2 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       time.Minute,
+		MaxHeaderBytes:    8192,
+		DisableHTTP2:      true,
+	})
+	if server.ReadTimeout != 5*time.Second || server.ReadHeaderTimeout != 2*time.Second ||
+		server.WriteTimeout != 30*time.Second || server.IdleTimeout != time.Minute || server.MaxHeaderBytes != 8192 {
+		t.Errorf("unexpected server settings: %#v", server)
+	}
+	if server.Protocols == nil || !server.Protocols.HTTP1() || server.Protocols.HTTP2() {
+		t.Errorf("expected only HTTP/1 to be enabled, got: %v", server.Protocols)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !windows
+
+package main
+
+import "errors"
+
+// runServiceCommand handles the "service" subcommands, which are only supported on Windows
+func runServiceCommand(args []string) error {
+	return errors.New("running as a service is only supported on Windows")
+}
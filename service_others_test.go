@@ -0,0 +1,11 @@
+//go:build !windows
+
+package main
+
+import "testing"
+
+func TestRunServiceCommand(t *testing.T) {
+	if err := runServiceCommand([]string{"install"}); err == nil {
+		t.Errorf("expected service commands to fail outside of Windows")
+	}
+}
@@ -0,0 +1,183 @@
+//go:build windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/eventlog"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+const serviceName = "redis_exporter"
+
+// runServiceCommand handles the "service" subcommands:
+//
+//	redis_exporter service install [flags]   registers the exporter as an automatically started service
+//	redis_exporter service uninstall         removes the service
+//	redis_exporter service run [flags]       runs the exporter under the service control manager
+func runServiceCommand(args []string) error {
+	if len(args) == 0 {
+		return errors.New("missing service command, valid commands are install, uninstall and run")
+	}
+
+	switch args[0] {
+	case "install":
+		return installService(args[1:])
+	case "uninstall":
+		return uninstallService()
+	case "run":
+		return runService(args[1:])
+	default:
+		return fmt.Errorf("unknown service command %q, valid commands are install, uninstall and run", args[0])
+	}
+}
+
+func installService(flags []string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	exe, err = filepath.Abs(exe)
+	if err != nil {
+		return err
+	}
+
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+
+	if s, err := m.OpenService(serviceName); err == nil {
+		s.Close()
+		return fmt.Errorf("service %s already exists", serviceName)
+	}
+
+	s, err := m.CreateService(serviceName, exe, mgr.Config{
+		DisplayName: "Redis Exporter",
+		Description: "Prometheus exporter for Redis and Valkey metrics",
+		StartType:   mgr.StartAutomatic,
+	}, append([]string{"service", "run"}, flags...)...)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	if err := eventlog.InstallAsEventCreate(serviceName, eventlog.Error|eventlog.Warning|eventlog.Info); err != nil {
+		_ = s.Delete()
+		return fmt.Errorf("couldn't set up event log source: %s", err)
+	}
+
+	log.Infof("Installed service %s", serviceName)
+	return nil
+}
+
+func uninstallService() error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(serviceName)
+	if err != nil {
+		return fmt.Errorf("service %s is not installed", serviceName)
+	}
+	defer s.Close()
+
+	if err := s.Delete(); err != nil {
+		return err
+	}
+	if err := eventlog.Remove(serviceName); err != nil {
+		log.Warnf("Couldn't remove event log source, err: %s", err)
+	}
+
+	log.Infof("Uninstalled service %s", serviceName)
+	return nil
+}
+
+func runService(flags []string) error {
+	isService, err := svc.IsWindowsService()
+	if err != nil {
+		return err
+	}
+	if !isService {
+		return errors.New("\"service run\" must be started by the service control manager, use \"service install\" instead")
+	}
+
+	elog, err := eventlog.Open(serviceName)
+	if err != nil {
+		return err
+	}
+	defer elog.Close()
+	log.AddHook(&eventLogHook{elog: elog})
+
+	// the exporter parses its flags from os.Args
+	os.Args = append([]string{os.Args[0]}, flags...)
+	return svc.Run(serviceName, &exporterService{})
+}
+
+type exporterService struct{}
+
+func (s *exporterService) Execute(args []string, r <-chan svc.ChangeRequest, changes chan<- svc.Status) (bool, uint32) {
+	changes <- svc.Status{State: svc.StartPending}
+
+	done := make(chan struct{})
+	go func() {
+		runExporter()
+		close(done)
+	}()
+
+	changes <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+	for {
+		select {
+		case <-done:
+			return false, 1
+		case c := <-r:
+			switch c.Cmd {
+			case svc.Interrogate:
+				changes <- c.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				changes <- svc.Status{State: svc.StopPending}
+				shutdownSignals <- os.Interrupt
+				select {
+				case <-done:
+				case <-time.After(15 * time.Second):
+				}
+				return false, 0
+			}
+		}
+	}
+}
+
+// eventLogHook writes log entries to the Windows event log
+type eventLogHook struct {
+	elog *eventlog.Log
+}
+
+func (h *eventLogHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+func (h *eventLogHook) Fire(entry *log.Entry) error {
+	msg, err := entry.String()
+	if err != nil {
+		return err
+	}
+
+	switch entry.Level {
+	case log.PanicLevel, log.FatalLevel, log.ErrorLevel:
+		return h.elog.Error(1, msg)
+	case log.WarnLevel:
+		return h.elog.Warning(1, msg)
+	default:
+		return h.elog.Info(1, msg)
+	}
+}